@@ -0,0 +1,346 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API Key认证（面向合作方的接口）：签发的密钥形如 dai_<ID>_<密文>，存储中只保存密文的SHA-256摘要，
+// 明文仅在签发/轮换时返回一次。每个密钥可配置授权范围（scope）与独立的限流规则，
+// 校验通过的密钥身份写入请求级context（FromContext）并记入访问日志，便于审计。
+// HTTP中间件见 http.APIKeyAuth、http.RequireScopes。
+
+var (
+	// ErrInvalidKey 密钥无效（格式错误、不存在或密文不匹配）
+	ErrInvalidKey = errs.Unauthorized.WithMessage("apikey: API Key无效")
+	// ErrKeyExpired 密钥已过期（含轮换后旧密钥的宽限期已过）
+	ErrKeyExpired = errs.Unauthorized.WithMessage("apikey: API Key已过期")
+	// ErrKeyRevoked 密钥已吊销
+	ErrKeyRevoked = errs.Unauthorized.WithMessage("apikey: API Key已吊销")
+	// ErrScopeDenied 密钥未被授予所需的授权范围
+	ErrScopeDenied = errs.Forbidden.WithMessage("apikey: API Key无权访问")
+	// ErrNotFound 密钥不存在（Store.Get返回，管理接口使用）
+	ErrNotFound = errs.NotFound.WithMessage("apikey: API Key不存在")
+)
+
+// Key 密钥记录（不含明文）
+type Key struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`   // 备注名称
+	Owner     string    `json:"owner"`  // 所属方（如合作方ID，写入访问日志的user字段）
+	Scopes    []string  `json:"scopes"` // 授权范围："*"表示全部，"orders:*"表示orders:下的全部范围
+	Rate      int       `json:"rate"`   // 每个周期允许的请求数（0使用Manager的默认规则，<0不限流）
+	Period    int       `json:"period"` // 限流周期（秒，默认1）
+	Hash      string    `json:"hash"`   // 密文的SHA-256摘要（十六进制）
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"` // 过期时间（零值表示不过期）
+	RevokedAt time.Time `json:"revoked_at"` // 吊销时间（零值表示未吊销）
+}
+
+// HasScope 是否被授予指定范围
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		switch {
+		case s == "*" || s == scope:
+			return true
+		case strings.HasSuffix(s, ":*") && strings.HasPrefix(scope, s[:len(s)-1]):
+			return true
+		}
+	}
+	return false
+}
+
+// HasScopes 是否被授予全部指定范围
+func (k *Key) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !k.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Active 在指定时间是否有效（未吊销且未过期）
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt))
+}
+
+// Store 密钥存储
+type Store interface {
+	Get(ctx context.Context, id string) (*Key, error)       // 不存在时返回ErrNotFound
+	Save(ctx context.Context, key *Key) error               // 新增或覆盖
+	List(ctx context.Context, owner string) ([]*Key, error) // 所属方的全部密钥（含已吊销/过期）
+}
+
+// Options 密钥管理参数
+type Options struct {
+	Prefix   string            // 签发的密钥前缀（默认dai，便于密钥扫描工具识别泄露；不能包含下划线）
+	Header   string            // 读取密钥的请求头（默认X-API-Key，供HTTP中间件使用）
+	CacheTTL time.Duration     // 校验结果本地缓存时长（默认30秒，<0不缓存；吊销与轮换在该时长内生效）
+	Limiter  ratelimit.Limiter // 按密钥限流的限流器（默认内存令牌桶，多实例部署使用ratelimit.NewRedisLimiter共享计数）
+	Default  ratelimit.Rule    // 未单独配置限流的密钥使用的规则（Rate<=0不限流）
+}
+
+// Manager 密钥管理器：签发、轮换、吊销与校验
+type Manager struct {
+	store Store
+	opts  Options
+	cache sync.Map // id -> cachedKey
+}
+
+type cachedKey struct {
+	key      *Key // nil表示密钥不存在（避免无效密钥反复查询存储）
+	expireAt time.Time
+}
+
+// NewManager 创建密钥管理器
+func NewManager(store Store, opts Options) *Manager {
+	if opts.Prefix == "" {
+		opts.Prefix = "dai"
+	}
+	if opts.Header == "" {
+		opts.Header = "X-API-Key"
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = 30 * time.Second
+	}
+	if opts.Limiter == nil {
+		opts.Limiter = ratelimit.NewMemoryLimiter()
+	}
+	return &Manager{store: store, opts: opts}
+}
+
+// Options 获取密钥管理参数
+func (m *Manager) Options() Options {
+	return m.opts
+}
+
+// Store 密钥存储（供管理接口查询）
+func (m *Manager) Store() Store {
+	return m.store
+}
+
+// Issue 签发密钥：按key中的Name/Owner/Scopes/Rate/Period/ExpiresAt生成新密钥并保存，返回明文（仅此一次）
+func (m *Manager) Issue(ctx context.Context, key *Key) (string, error) {
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	key.ID = id
+	key.Hash = hashSecret(secret)
+	key.CreatedAt = time.Now()
+	key.RevokedAt = time.Time{}
+	if err := m.store.Save(ctx, key); err != nil {
+		return "", fmt.Errorf("保存API Key失败：%w", err)
+	}
+	return m.opts.Prefix + "_" + id + "_" + secret, nil
+}
+
+// Rotate 轮换密钥：签发与旧密钥属性相同的新密钥，旧密钥在grace后过期（grace<=0时立即吊销），
+// 合作方可在宽限期内平滑切换；返回新密钥明文与记录
+func (m *Manager) Rotate(ctx context.Context, id string, grace time.Duration) (string, *Key, error) {
+	old, err := m.store.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if !old.RevokedAt.IsZero() {
+		return "", nil, ErrKeyRevoked
+	}
+	next := &Key{
+		Name:      old.Name,
+		Owner:     old.Owner,
+		Scopes:    append([]string(nil), old.Scopes...),
+		Rate:      old.Rate,
+		Period:    old.Period,
+		ExpiresAt: old.ExpiresAt,
+	}
+	token, err := m.Issue(ctx, next)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	if grace <= 0 {
+		old.RevokedAt = now
+	} else if deadline := now.Add(grace); old.ExpiresAt.IsZero() || deadline.Before(old.ExpiresAt) {
+		old.ExpiresAt = deadline
+	}
+	if err := m.store.Save(ctx, old); err != nil {
+		return "", nil, fmt.Errorf("更新旧API Key失败：%w", err)
+	}
+	m.cache.Delete(id)
+	return token, next, nil
+}
+
+// Revoke 吊销密钥（其他实例的本地缓存在CacheTTL内失效）
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	key, err := m.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !key.RevokedAt.IsZero() {
+		return nil
+	}
+	key.RevokedAt = time.Now()
+	if err := m.store.Save(ctx, key); err != nil {
+		return err
+	}
+	m.cache.Delete(id)
+	return nil
+}
+
+// Verify 校验密钥明文，返回密钥记录；失败返回ErrInvalidKey/ErrKeyExpired/ErrKeyRevoked，存储故障时返回errs.Unavailable
+func (m *Manager) Verify(ctx context.Context, token string) (*Key, error) {
+	prefix, rest, ok := strings.Cut(token, "_")
+	if !ok || prefix != m.opts.Prefix {
+		return nil, ErrInvalidKey
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrInvalidKey
+	}
+	key, err := m.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalidKey
+	}
+	if !key.RevokedAt.IsZero() {
+		return nil, ErrKeyRevoked
+	}
+	if !key.Active(time.Now()) {
+		return nil, ErrKeyExpired
+	}
+	return key, nil
+}
+
+// lookup 读取密钥记录（优先本地缓存，不存在时返回nil）
+func (m *Manager) lookup(ctx context.Context, id string) (*Key, error) {
+	now := time.Now()
+	if m.opts.CacheTTL > 0 {
+		if v, ok := m.cache.Load(id); ok && now.Before(v.(cachedKey).expireAt) {
+			return v.(cachedKey).key, nil
+		}
+	}
+	key, err := m.store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		key, err = nil, nil
+	}
+	if err != nil {
+		return nil, errs.Unavailable.Wrap(fmt.Errorf("读取API Key失败：%w", err))
+	}
+	if m.opts.CacheTTL > 0 {
+		m.cache.Store(id, cachedKey{key: key, expireAt: now.Add(m.opts.CacheTTL)})
+	}
+	return key, nil
+}
+
+// Allow 按密钥的限流规则判定请求是否放行（限流器出错时放行并记录日志）
+func (m *Manager) Allow(ctx context.Context, key *Key) ratelimit.Result {
+	rule := m.opts.Default
+	if key.Rate > 0 {
+		rule = ratelimit.Rule{Rate: key.Rate, Period: time.Duration(key.Period) * time.Second}
+	} else if key.Rate < 0 {
+		return ratelimit.Result{Allowed: true}
+	}
+	if rule.Rate <= 0 {
+		return ratelimit.Result{Allowed: true}
+	}
+	if rule.Period <= 0 {
+		rule.Period = time.Second
+	}
+	result, err := m.opts.Limiter.Allow(ctx, "apikey:"+key.ID, rule)
+	if err != nil {
+		logger.FromContext(ctx).Warn("API Key限流判定失败，已放行：", err, "key：", key.ID)
+		return ratelimit.Result{Allowed: true, Limit: rule.Rate}
+	}
+	return result
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成API Key失败：%w", err)
+	}
+	return encode(b), nil
+}
+
+var (
+	appManagers sync.Map // appName -> *Manager
+	managerMu   sync.Mutex
+)
+
+// FromAppConfig 按应用配置创建密钥管理器（未启用时返回nil，同一应用多次调用返回同一实例）
+func FromAppConfig(appName string) (*Manager, error) {
+	if m, ok := appManagers.Load(appName); ok {
+		return m.(*Manager), nil
+	}
+	managerMu.Lock()
+	defer managerMu.Unlock()
+	if m, ok := appManagers.Load(appName); ok {
+		return m.(*Manager), nil
+	}
+	cfg := config.GetAppConfig(appName).APIKey
+	if !cfg.Enable {
+		return nil, nil
+	}
+	opts := Options{
+		Prefix:   cfg.Prefix,
+		Header:   cfg.Header,
+		CacheTTL: time.Duration(cfg.CacheTTL) * time.Second,
+		Default:  ratelimit.Rule{Rate: cfg.Rate, Period: time.Duration(cfg.Period) * time.Second},
+	}
+	if strings.Contains(opts.Prefix, "_") {
+		return nil, fmt.Errorf("API Key前缀不能包含下划线：%s", opts.Prefix)
+	}
+	if cfg.RedisDb != "" {
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return nil, err
+		}
+		opts.Limiter = ratelimit.NewRedisLimiter(rdb)
+	}
+	var store Store
+	switch strings.ToLower(cfg.Store) {
+	case "mysql":
+		mysqlStore, err := NewMysqlStore(cfg.MysqlDb, cfg.Table)
+		if err != nil {
+			return nil, err
+		}
+		store = mysqlStore
+	case "redis":
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return nil, err
+		}
+		store = NewRedisStore(rdb)
+	default:
+		return nil, fmt.Errorf("不支持的API Key存储：%s", cfg.Store)
+	}
+	m := NewManager(store, opts)
+	appManagers.Store(appName, m)
+	return m, nil
+}
@@ -0,0 +1,43 @@
+package apikey
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/ratelimit"
+)
+
+// 校验通过后写入上下文参数的key（控制器可通过GetParam获取）
+const (
+	ParamKeyID    = "apikey_id"
+	ParamKeyOwner = "apikey_owner"
+)
+
+type keyCtxKey struct{}
+
+// WithKey 将密钥记录写入context
+func WithKey(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, keyCtxKey{}, key)
+}
+
+// FromContext 从context获取校验通过的密钥记录（未经API Key认证时返回nil）
+func FromContext(ctx context.Context) *Key {
+	if ctx == nil {
+		return nil
+	}
+	key, _ := ctx.Value(keyCtxKey{}).(*Key)
+	return key
+}
+
+// Bind 将校验结果写入请求上下文：参数（ParamKeyID等）、请求级context（FromContext）、
+// 限流与访问日志的用户维度（apikey:<ID>）及请求级日志字段api_key_id/api_key_owner
+func Bind(c netContext.Context, key *Key) {
+	c.SetParam(ParamKeyID, key.ID)
+	c.SetParam(ParamKeyOwner, key.Owner)
+	ctx := ratelimit.WithUser(c.GetContext(), "apikey:"+key.ID)
+	ctx = logger.WithContext(ctx, logger.FromContext(ctx).WithFields(logger.Fields{
+		"api_key_id":    key.ID,
+		"api_key_owner": key.Owner,
+	}))
+	c.SetContext(WithKey(ctx, key))
+}
@@ -0,0 +1,157 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"strconv"
+	"time"
+)
+
+// MysqlStore MySQL密钥存储（表结构见CreateTable，时间字段按UTC读写，与驱动默认的loc一致）
+type MysqlStore struct {
+	dbKey string
+	table string
+}
+
+var _ Store = (*MysqlStore)(nil)
+
+// NewMysqlStore 创建MySQL密钥存储（table为空时默认api_key，会拼接表前缀）
+func NewMysqlStore(dbKey, table string) (*MysqlStore, error) {
+	if table == "" {
+		table = "api_key"
+	}
+	if _, err := mysql.GetMysqlDB(dbKey); err != nil {
+		return nil, err
+	}
+	return &MysqlStore{dbKey: dbKey, table: table}, nil
+}
+
+// CreateTable 创建密钥表（已存在时忽略）
+func (s *MysqlStore) CreateTable(ctx context.Context) error {
+	mdb, err := mysql.GetMysqlDB(s.dbKey)
+	if err != nil {
+		return err
+	}
+	_, err = mdb.Exec(ctx, "CREATE TABLE IF NOT EXISTS `"+mdb.DbPre+s.table+"` ("+
+		"id VARCHAR(32) NOT NULL PRIMARY KEY, "+
+		"name VARCHAR(128) NOT NULL DEFAULT '', "+
+		"owner VARCHAR(128) NOT NULL DEFAULT '', "+
+		"scopes VARCHAR(1024) NOT NULL DEFAULT '[]', "+
+		"rate INT NOT NULL DEFAULT 0, "+
+		"period INT NOT NULL DEFAULT 0, "+
+		"key_hash CHAR(64) NOT NULL, "+
+		"created_at DATETIME NOT NULL, "+
+		"expires_at DATETIME NULL, "+
+		"revoked_at DATETIME NULL, "+
+		"KEY idx_owner (owner)"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='API Key'")
+	return err
+}
+
+// Get 读取密钥记录（不存在时返回ErrNotFound）
+func (s *MysqlStore) Get(ctx context.Context, id string) (*Key, error) {
+	mdb, err := mysql.GetMysqlDB(s.dbKey)
+	if err != nil {
+		return nil, err
+	}
+	mdb.SetTable(s.table).SetWhere("id = ?", id).SetLimit(0, 1).FindAll(ctx)
+	if mdb.Err != nil {
+		return nil, mdb.Err
+	}
+	if len(mdb.Data) == 0 {
+		return nil, ErrNotFound
+	}
+	return keyFromRow(mdb.Data[0])
+}
+
+// Save 新增或覆盖密钥记录
+func (s *MysqlStore) Save(ctx context.Context, key *Key) error {
+	mdb, err := mysql.GetMysqlDB(s.dbKey)
+	if err != nil {
+		return err
+	}
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = mdb.Exec(ctx, "INSERT INTO `"+mdb.DbPre+s.table+"` "+
+		"(id, name, owner, scopes, rate, period, key_hash, created_at, expires_at, revoked_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE name = VALUES(name), owner = VALUES(owner), scopes = VALUES(scopes), rate = VALUES(rate), "+
+		"period = VALUES(period), key_hash = VALUES(key_hash), expires_at = VALUES(expires_at), revoked_at = VALUES(revoked_at)",
+		key.ID, key.Name, key.Owner, string(scopes), key.Rate, key.Period, key.Hash,
+		key.CreatedAt.UTC(), nullTime(key.ExpiresAt), nullTime(key.RevokedAt))
+	return err
+}
+
+// List 所属方的全部密钥（按创建时间升序）
+func (s *MysqlStore) List(ctx context.Context, owner string) ([]*Key, error) {
+	mdb, err := mysql.GetMysqlDB(s.dbKey)
+	if err != nil {
+		return nil, err
+	}
+	mdb.SetTable(s.table).SetWhere("owner = ?", owner).SetOrder("created_at ASC").FindAll(ctx)
+	if mdb.Err != nil {
+		return nil, mdb.Err
+	}
+	keys := make([]*Key, 0, len(mdb.Data))
+	for _, row := range mdb.Data {
+		key, err := keyFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// keyFromRow 查询结果行转换为密钥记录（兼容文本/二进制协议返回的列值类型）
+func keyFromRow(row map[string]interface{}) (*Key, error) {
+	key := &Key{
+		ID:    fmt.Sprint(row["id"]),
+		Name:  fmt.Sprint(row["name"]),
+		Owner: fmt.Sprint(row["owner"]),
+		Hash:  fmt.Sprint(row["key_hash"]),
+	}
+	if scopes, _ := row["scopes"].(string); scopes != "" {
+		if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+			return nil, fmt.Errorf("API Key[%s]授权范围格式错误：%w", key.ID, err)
+		}
+	}
+	key.Rate = int(columnInt(row["rate"]))
+	key.Period = int(columnInt(row["period"]))
+	key.CreatedAt = columnTime(row["created_at"])
+	key.ExpiresAt = columnTime(row["expires_at"])
+	key.RevokedAt = columnTime(row["revoked_at"])
+	return key, nil
+}
+
+func columnInt(v interface{}) int64 {
+	switch val := v.(type) {
+	case int64:
+		return val
+	case string:
+		n, _ := strconv.ParseInt(val, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func columnTime(v interface{}) time.Time {
+	switch val := v.(type) {
+	case time.Time:
+		return val
+	case string:
+		t, _ := time.ParseInLocation(time.DateTime, val, time.UTC)
+		return t
+	}
+	return time.Time{}
+}
+
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,82 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/go-redis/redis"
+)
+
+// redisKeyPrefix Redis键前缀（会再拼接Redis表前缀）
+const redisKeyPrefix = "apikey:"
+
+// RedisStore Redis密钥存储：密钥记录以JSON保存在 apikey:key:<ID>，所属方的密钥ID保存在集合 apikey:owner:<Owner>
+type RedisStore struct {
+	rdb *redisDb.RedisDb
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore 创建Redis密钥存储
+func NewRedisStore(rdb *redisDb.RedisDb) *RedisStore {
+	return &RedisStore{rdb: rdb}
+}
+
+func (s *RedisStore) keyName(id string) string {
+	return s.rdb.DbPre + redisKeyPrefix + "key:" + id
+}
+
+func (s *RedisStore) ownerName(owner string) string {
+	return s.rdb.DbPre + redisKeyPrefix + "owner:" + owner
+}
+
+// Get 读取密钥记录（不存在时返回ErrNotFound）
+func (s *RedisStore) Get(ctx context.Context, id string) (*Key, error) {
+	data, err := s.rdb.WithContext(ctx).Db.Get(s.keyName(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{}
+	if err := json.Unmarshal(data, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Save 保存密钥记录（不设置过期时间，已过期/吊销的记录保留用于审计）
+func (s *RedisStore) Save(ctx context.Context, key *Key) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.rdb.WithContext(ctx).Db.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(s.keyName(key.ID), data, 0)
+		pipe.SAdd(s.ownerName(key.Owner), key.ID)
+		return nil
+	})
+	return err
+}
+
+// List 所属方的全部密钥
+func (s *RedisStore) List(ctx context.Context, owner string) ([]*Key, error) {
+	ids, err := s.rdb.WithContext(ctx).Db.SMembers(s.ownerName(owner)).Result()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(ids))
+	for _, id := range ids {
+		key, err := s.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
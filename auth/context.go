@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"strings"
+)
+
+// 认证成功后写入上下文参数的key（控制器可通过GetParam获取）
+const (
+	ParamUserID    = "auth_user_id"
+	ParamRoles     = "auth_roles" // 逗号分隔
+	ParamSessionID = "auth_session_id"
+)
+
+type claimsKey struct{}
+
+// WithClaims 将令牌声明写入context
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext 从context获取令牌声明（未认证时返回nil）
+func FromContext(ctx context.Context) *Claims {
+	if ctx == nil {
+		return nil
+	}
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// Bind 将认证结果写入请求上下文：参数（ParamUserID等）、请求级context（FromContext）及限流用户维度
+func Bind(c netContext.Context, claims *Claims) {
+	c.SetParam(ParamUserID, claims.UserID)
+	c.SetParam(ParamRoles, strings.Join(claims.Roles, ","))
+	c.SetParam(ParamSessionID, claims.SessionID)
+	ctx := ratelimit.WithUser(c.GetContext(), claims.UserID)
+	c.SetContext(WithClaims(ctx, claims))
+}
+
+// BearerToken 从Authorization头中提取Bearer令牌
+func BearerToken(header string) string {
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
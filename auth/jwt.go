@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 令牌类型
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// refreshKeyPrefix 刷新令牌记录的Redis键前缀（会再拼接Redis表前缀）
+const refreshKeyPrefix = "auth:refresh:"
+
+var (
+	// ErrInvalidToken 令牌无效（签名错误、格式错误、类型不符等）
+	ErrInvalidToken = errs.Unauthorized.WithMessage("auth: 令牌无效")
+	// ErrTokenExpired 令牌已过期
+	ErrTokenExpired = errs.Unauthorized.WithMessage("auth: 令牌已过期")
+	// ErrTokenReused 刷新令牌被重复使用（疑似泄露，所在会话已被吊销）
+	ErrTokenReused = errs.Unauthorized.WithMessage("auth: 刷新令牌已失效")
+)
+
+// Claims 令牌声明
+type Claims struct {
+	UserID    string                 `json:"uid"`
+	Roles     []string               `json:"roles,omitempty"`
+	Extra     map[string]interface{} `json:"ext,omitempty"` // 业务自定义字段
+	TokenType string                 `json:"typ,omitempty"` // access/refresh
+	SessionID string                 `json:"sid,omitempty"` // 会话ID（同一次登录签发的令牌共享，用于刷新令牌轮换与吊销）
+	jwt.RegisteredClaims
+}
+
+// HasRole 是否拥有指定角色
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// JWTConfig JWT参数
+type JWTConfig struct {
+	Algorithm  string        // HS256（默认）/RS256
+	Secret     string        // HS256密钥
+	PrivateKey string        // RS256私钥（PEM内容，仅签发方需要）
+	PublicKey  string        // RS256公钥（PEM内容）
+	Issuer     string        // 签发方（iss，设置后解析时校验）
+	Audience   string        // 接收方（aud，设置后解析时校验）
+	AccessTTL  time.Duration // 访问令牌有效期（默认2小时）
+	RefreshTTL time.Duration // 刷新令牌有效期（默认7天）
+	Leeway     time.Duration // 时钟偏差容忍
+}
+
+// JWT 令牌签发与解析
+type JWT struct {
+	cfg       JWTConfig
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	refresh   *redisDb.RedisDb // 刷新令牌轮换记录（为nil时刷新令牌仅校验签名与有效期）
+}
+
+// NewJWT 创建JWT实例
+func NewJWT(cfg JWTConfig) (*JWT, error) {
+	if cfg.AccessTTL <= 0 {
+		cfg.AccessTTL = 2 * time.Hour
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = 7 * 24 * time.Hour
+	}
+	j := &JWT{cfg: cfg}
+	switch strings.ToUpper(cfg.Algorithm) {
+	case "", "HS256":
+		if cfg.Secret == "" {
+			return nil, errors.New("auth: HS256需要配置secret")
+		}
+		j.method = jwt.SigningMethodHS256
+		j.signKey, j.verifyKey = []byte(cfg.Secret), []byte(cfg.Secret)
+	case "RS256":
+		j.method = jwt.SigningMethodRS256
+		if cfg.PublicKey == "" {
+			return nil, errors.New("auth: RS256需要配置公钥")
+		}
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(cfg.PublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("auth: 解析RS256公钥失败：%w", err)
+		}
+		j.verifyKey = publicKey
+		if cfg.PrivateKey != "" {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(cfg.PrivateKey))
+			if err != nil {
+				return nil, fmt.Errorf("auth: 解析RS256私钥失败：%w", err)
+			}
+			j.signKey = privateKey
+		}
+	default:
+		return nil, fmt.Errorf("auth: 不支持的签名算法：%s", cfg.Algorithm)
+	}
+	return j, nil
+}
+
+// WithRefreshStore 启用刷新令牌轮换：每个会话仅最新签发的刷新令牌有效，旧令牌再次使用时吊销整个会话
+func (j *JWT) WithRefreshStore(rdb *redisDb.RedisDb) *JWT {
+	j.refresh = rdb
+	return j
+}
+
+// Issue 签发访问令牌
+func (j *JWT) Issue(claims Claims) (string, error) {
+	token, _, err := j.sign(claims, TokenTypeAccess, j.cfg.AccessTTL)
+	return token, err
+}
+
+// IssuePair 签发访问令牌与刷新令牌（登录时调用，会开启新会话）
+func (j *JWT) IssuePair(ctx context.Context, claims Claims) (TokenPair, error) {
+	if claims.SessionID == "" {
+		claims.SessionID = uuid.NewString()
+	}
+	pair, refreshClaims, err := j.issuePair(claims)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if j.refresh != nil {
+		key := j.refreshKey(refreshClaims.UserID, refreshClaims.SessionID)
+		if err := j.refresh.WithContext(ctx).Db.Set(key, refreshClaims.ID, j.cfg.RefreshTTL).Err(); err != nil {
+			return TokenPair{}, err
+		}
+	}
+	return pair, nil
+}
+
+// Refresh 使用刷新令牌换发新的令牌对（启用轮换时旧刷新令牌立即失效）
+func (j *JWT) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+	old, err := j.parse(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	claims := Claims{UserID: old.UserID, Roles: old.Roles, Extra: old.Extra, SessionID: old.SessionID}
+	pair, refreshClaims, err := j.issuePair(claims)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if j.refresh != nil {
+		rdb := j.refresh.WithContext(ctx)
+		key := refreshKeyPrefix + old.UserID + ":" + old.SessionID
+		err := rdb.CompareAndRotate(ctx, key, old.ID, refreshClaims.ID, j.cfg.RefreshTTL)
+		if errors.Is(err, redisDb.ErrTokenMismatch) {
+			// 旧令牌被重复使用：吊销整个会话，迫使攻击者与合法用户都重新登录
+			_ = rdb.Db.Del(rdb.DbPre + key).Err()
+			return TokenPair{}, ErrTokenReused
+		}
+		if err != nil {
+			return TokenPair{}, err
+		}
+	}
+	return pair, nil
+}
+
+// Revoke 吊销会话（退出登录），该会话的刷新令牌立即失效；已签发的访问令牌在过期前仍然有效
+func (j *JWT) Revoke(ctx context.Context, userID, sessionID string) error {
+	if j.refresh == nil {
+		return nil
+	}
+	return j.refresh.WithContext(ctx).Db.Del(j.refreshKey(userID, sessionID)).Err()
+}
+
+// Parse 解析并校验访问令牌
+func (j *JWT) Parse(token string) (*Claims, error) {
+	return j.parse(token, TokenTypeAccess)
+}
+
+// ParseRefresh 解析并校验刷新令牌（不校验是否已被轮换）
+func (j *JWT) ParseRefresh(token string) (*Claims, error) {
+	return j.parse(token, TokenTypeRefresh)
+}
+
+func (j *JWT) issuePair(claims Claims) (TokenPair, *Claims, error) {
+	access, accessClaims, err := j.sign(claims, TokenTypeAccess, j.cfg.AccessTTL)
+	if err != nil {
+		return TokenPair{}, nil, err
+	}
+	refresh, refreshClaims, err := j.sign(claims, TokenTypeRefresh, j.cfg.RefreshTTL)
+	if err != nil {
+		return TokenPair{}, nil, err
+	}
+	return TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		AccessExpiresAt:  accessClaims.ExpiresAt.Time,
+		RefreshExpiresAt: refreshClaims.ExpiresAt.Time,
+	}, refreshClaims, nil
+}
+
+// sign 按类型与有效期补齐标准声明并签名
+func (j *JWT) sign(claims Claims, tokenType string, ttl time.Duration) (string, *Claims, error) {
+	if j.signKey == nil {
+		return "", nil, errors.New("auth: 未配置签名私钥，仅可用于校验令牌")
+	}
+	now := time.Now()
+	claims.TokenType = tokenType
+	claims.ID = uuid.NewString()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	if claims.Subject == "" {
+		claims.Subject = claims.UserID
+	}
+	if j.cfg.Issuer != "" {
+		claims.Issuer = j.cfg.Issuer
+	}
+	if j.cfg.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{j.cfg.Audience}
+	}
+	token, err := jwt.NewWithClaims(j.method, &claims).SignedString(j.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &claims, nil
+}
+
+func (j *JWT) parse(token, tokenType string) (*Claims, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods([]string{j.method.Alg()}), jwt.WithLeeway(j.cfg.Leeway)}
+	if j.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(j.cfg.Issuer))
+	}
+	if j.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(j.cfg.Audience))
+	}
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return j.verifyKey, nil
+	}, opts...)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w：%v", ErrInvalidToken, err)
+	}
+	if claims.TokenType != tokenType {
+		return nil, fmt.Errorf("%w：令牌类型不符", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+func (j *JWT) refreshKey(userID, sessionID string) string {
+	return j.refresh.DbPre + refreshKeyPrefix + userID + ":" + sessionID
+}
+
+var (
+	appJWTs sync.Map // appName -> *JWT
+	jwtMu   sync.Mutex
+)
+
+// FromAppConfig 按应用配置创建JWT实例（未配置时返回nil，同一应用多次调用返回同一实例）
+func FromAppConfig(appName string) (*JWT, error) {
+	if j, ok := appJWTs.Load(appName); ok {
+		return j.(*JWT), nil
+	}
+	jwtMu.Lock()
+	defer jwtMu.Unlock()
+	if j, ok := appJWTs.Load(appName); ok {
+		return j.(*JWT), nil
+	}
+	cfg := config.GetAppConfig(appName).JWT
+	if cfg.Secret == "" && cfg.PublicKeyFile == "" {
+		return nil, nil
+	}
+	jwtCfg := JWTConfig{
+		Algorithm:  cfg.Algorithm,
+		Secret:     cfg.Secret,
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		AccessTTL:  time.Duration(cfg.AccessTTL) * time.Second,
+		RefreshTTL: time.Duration(cfg.RefreshTTL) * time.Second,
+		Leeway:     time.Duration(cfg.Leeway) * time.Second,
+	}
+	var err error
+	if jwtCfg.PublicKey, err = readKeyFile(cfg.PublicKeyFile); err != nil {
+		return nil, err
+	}
+	if jwtCfg.PrivateKey, err = readKeyFile(cfg.PrivateKeyFile); err != nil {
+		return nil, err
+	}
+	j, err := NewJWT(jwtCfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RedisDb != "" {
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return nil, err
+		}
+		j.WithRefreshStore(rdb)
+	}
+	appJWTs.Store(appName, j)
+	return j, nil
+}
+
+func readKeyFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("auth: 读取密钥文件失败：%w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"os"
+	"strings"
+	"time"
+)
+
+// 双向TLS（mTLS）：HTTP/WS/gRPC服务器启用SSL时可要求并校验客户端证书（CA证书链+CN/SAN白名单+CRL/OCSP吊销检查），
+// 校验通过的客户端身份由框架写入请求级context，通过ClientIdentityFromContext读取用于授权。
+
+// MTLSOptions 客户端证书校验配置
+type MTLSOptions struct {
+	ClientCAFile string        // 签发客户端证书的CA（PEM，可包含多个证书）
+	Optional     bool          // 为true时客户端可不提供证书（提供时仍须校验通过），默认必须提供
+	AllowedNames []string      // CN/SAN白名单（为空时不限制）：精确匹配，"*.svc.local"匹配子域名，以*结尾时按前缀匹配（如"spiffe://prod/*"）
+	CRLFile      string        // 证书吊销列表（PEM或DER，为空时不检查），文件变更后自动重新加载
+	CRLRefresh   time.Duration // CRL文件变更检查间隔（默认5分钟）
+	OCSP         string        // OCSP在线吊销检查：OCSPSoft/OCSPHard，为空时不检查
+	OCSPTimeout  time.Duration // OCSP查询超时（默认3秒）
+}
+
+// MTLSOptionsFromConfig 由配置构建客户端证书校验配置（未启用时返回nil）
+func MTLSOptionsFromConfig(cfg config.MTLSConfig) *MTLSOptions {
+	if !cfg.Enable {
+		return nil
+	}
+	return &MTLSOptions{
+		ClientCAFile: cfg.ClientCAFile,
+		Optional:     cfg.Optional,
+		AllowedNames: cfg.AllowedNames,
+		CRLFile:      cfg.CRLFile,
+		CRLRefresh:   time.Duration(cfg.CRLRefresh) * time.Second,
+		OCSP:         cfg.OCSP,
+		OCSPTimeout:  time.Duration(cfg.OCSPTimeout) * time.Second,
+	}
+}
+
+// ServerTLSConfig 构建服务端TLS配置（mtls为nil时不校验客户端证书）
+func ServerTLSConfig(certFile, keyFile string, mtls *MTLSOptions) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("SSL enabled but cert/key file path is empty")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SSL cert/key: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if mtls == nil {
+		return tlsConfig, nil
+	}
+	if mtls.ClientCAFile == "" {
+		return nil, errors.New("mTLS enabled but client CA file path is empty")
+	}
+	pem, err := os.ReadFile(mtls.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid certificate in client CA file %s", mtls.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if mtls.Optional {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	revocation, err := newRevocationChecker(mtls)
+	if err != nil {
+		return nil, err
+	}
+	allowed := append([]string(nil), mtls.AllowedNames...)
+	if len(allowed) > 0 || revocation != nil {
+		// 证书链已由ClientCAs校验，此处检查叶子证书的身份是否在白名单中，以及证书链是否已被吊销
+		tlsConfig.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+			if len(chains) == 0 || len(chains[0]) == 0 {
+				return nil
+			}
+			if len(allowed) > 0 {
+				id := identityFromCert(chains[0][0])
+				if !id.matchAny(allowed) {
+					return fmt.Errorf("client certificate %q is not allowed", id.Name())
+				}
+			}
+			if revocation != nil {
+				return revocation.check(chains[0])
+			}
+			return nil
+		}
+	}
+	return tlsConfig, nil
+}
+
+// ClientIdentity 已校验的客户端证书身份
+type ClientIdentity struct {
+	CommonName     string
+	DNSNames       []string
+	URIs           []string // URI SAN（如SPIFFE ID）
+	EmailAddresses []string
+	IPAddresses    []string
+	Organization   []string
+	SerialNumber   string
+	Issuer         string
+	Fingerprint    string // 证书SHA-256指纹（十六进制）
+	Certificate    *x509.Certificate
+}
+
+// Name 身份名称：CN，无CN时依次取第一个URI/DNS/Email SAN
+func (id *ClientIdentity) Name() string {
+	if id.CommonName != "" {
+		return id.CommonName
+	}
+	for _, names := range [][]string{id.URIs, id.DNSNames, id.EmailAddresses} {
+		if len(names) > 0 {
+			return names[0]
+		}
+	}
+	return ""
+}
+
+// Names CN与全部SAN
+func (id *ClientIdentity) Names() []string {
+	names := make([]string, 0, 1+len(id.DNSNames)+len(id.URIs)+len(id.EmailAddresses)+len(id.IPAddresses))
+	if id.CommonName != "" {
+		names = append(names, id.CommonName)
+	}
+	names = append(names, id.DNSNames...)
+	names = append(names, id.URIs...)
+	names = append(names, id.EmailAddresses...)
+	return append(names, id.IPAddresses...)
+}
+
+// Match 身份（CN或任一SAN）是否匹配patterns中的任一项（规则同MTLSOptions.AllowedNames）
+func (id *ClientIdentity) Match(patterns ...string) bool {
+	return id != nil && id.matchAny(patterns)
+}
+
+func (id *ClientIdentity) matchAny(patterns []string) bool {
+	for _, name := range id.Names() {
+		for _, pattern := range patterns {
+			if matchName(pattern, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchName 精确匹配（忽略大小写）；"*.example.com"匹配任意子域名；以*结尾时按前缀匹配
+func matchName(pattern, name string) bool {
+	switch {
+	case strings.HasPrefix(pattern, "*."):
+		suffix := pattern[1:]
+		return len(name) > len(suffix) && strings.EqualFold(name[len(name)-len(suffix):], suffix)
+	case strings.HasSuffix(pattern, "*"):
+		return strings.HasPrefix(name, pattern[:len(pattern)-1])
+	default:
+		return strings.EqualFold(pattern, name)
+	}
+}
+
+// IdentityFromTLS 从TLS连接状态提取已校验的客户端身份（未提供或未校验客户端证书时返回nil）
+func IdentityFromTLS(state *tls.ConnectionState) *ClientIdentity {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return identityFromCert(state.VerifiedChains[0][0])
+}
+
+func identityFromCert(cert *x509.Certificate) *ClientIdentity {
+	sum := sha256.Sum256(cert.Raw)
+	id := &ClientIdentity{
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Organization:   cert.Subject.Organization,
+		SerialNumber:   cert.SerialNumber.String(),
+		Issuer:         cert.Issuer.String(),
+		Fingerprint:    hex.EncodeToString(sum[:]),
+		Certificate:    cert,
+	}
+	for _, u := range cert.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		id.IPAddresses = append(id.IPAddresses, ip.String())
+	}
+	return id
+}
+
+type clientIdentityKey struct{}
+
+// WithClientIdentity 将客户端证书身份写入context（id为nil时原样返回）
+func WithClientIdentity(ctx context.Context, id *ClientIdentity) context.Context {
+	if id == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clientIdentityKey{}, id)
+}
+
+// ClientIdentityFromContext 获取客户端证书身份（未启用mTLS或客户端未提供证书时返回nil）
+func ClientIdentityFromContext(ctx context.Context) *ClientIdentity {
+	if ctx == nil {
+		return nil
+	}
+	id, _ := ctx.Value(clientIdentityKey{}).(*ClientIdentity)
+	return id
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 第三方登录（OAuth2授权码模式）：
+//
+//	Begin    生成state/nonce/PKCE校验码并存入Store（默认Redis），返回跳转到第三方授权页的地址
+//	Complete 回调时校验并消费state（一次性），用授权码换取令牌，校验OIDC的id_token与nonce，获取用户信息
+//
+// 本地账号的关联与登录态的建立（会话/JWT）由http.OAuthCallback完成。
+
+var (
+	// ErrOAuthState state无效、已过期或已被使用（疑似CSRF或重放）
+	ErrOAuthState = errors.New("auth: 登录状态无效或已过期")
+	// ErrOAuthProvider 未注册的第三方登录提供方
+	ErrOAuthProvider = errors.New("auth: 未知的登录提供方")
+	// ErrOAuthDenied 用户拒绝授权或第三方返回错误
+	ErrOAuthDenied = errors.New("auth: 第三方授权失败")
+)
+
+// OAuthToken 第三方令牌
+type OAuthToken struct {
+	AccessToken  string                 `json:"access_token"`
+	TokenType    string                 `json:"token_type,omitempty"`
+	RefreshToken string                 `json:"refresh_token,omitempty"`
+	ExpiresIn    int64                  `json:"expires_in,omitempty"` // 秒
+	Scope        string                 `json:"scope,omitempty"`
+	IDToken      string                 `json:"id_token,omitempty"` // OIDC
+	Raw          map[string]interface{} `json:"-"`                  // 原始响应（如微信的openid/unionid）
+}
+
+// OAuthUser 第三方用户信息（字段按各平台归一化）
+type OAuthUser struct {
+	Provider      string                 `json:"provider"`
+	ID            string                 `json:"id"`                 // 平台内用户唯一标识（OIDC的sub、GitHub的id、微信的openid）
+	UnionID       string                 `json:"union_id,omitempty"` // 跨应用统一标识（微信unionid）
+	Name          string                 `json:"name,omitempty"`
+	Email         string                 `json:"email,omitempty"`
+	EmailVerified bool                   `json:"email_verified,omitempty"`
+	Avatar        string                 `json:"avatar,omitempty"`
+	Raw           map[string]interface{} `json:"raw,omitempty"` // 原始用户信息
+}
+
+// OAuthProvider 第三方登录提供方
+type OAuthProvider interface {
+	// Name 提供方名称（路由与state中使用，如github、wechat、google）
+	Name() string
+	// AuthCodeURL 授权页地址（nonce/codeChallenge由不支持的提供方忽略）
+	AuthCodeURL(state, nonce, codeChallenge string) (string, error)
+	// Exchange 用授权码换取令牌
+	Exchange(ctx context.Context, code, codeVerifier string) (*OAuthToken, error)
+	// UserInfo 获取用户信息（OIDC提供方在此校验id_token及nonce）
+	UserInfo(ctx context.Context, token *OAuthToken, nonce string) (*OAuthUser, error)
+}
+
+// OAuthResult 登录结果
+type OAuthResult struct {
+	Provider string
+	User     *OAuthUser
+	Token    *OAuthToken
+	ReturnTo string // 发起登录时指定的回跳地址
+}
+
+// oauthState 授权请求状态（state为键，回调时一次性消费）
+type oauthState struct {
+	Provider string `json:"p"`
+	Nonce    string `json:"n,omitempty"`
+	Verifier string `json:"v,omitempty"`
+	ReturnTo string `json:"r,omitempty"`
+}
+
+// OAuth 第三方登录管理器
+type OAuth struct {
+	store     redisDb.Store
+	stateTTL  time.Duration
+	mu        sync.RWMutex
+	providers map[string]OAuthProvider
+}
+
+// NewOAuth 创建第三方登录管理器（store保存state，多实例部署须使用Redis；stateTTL默认10分钟）
+func NewOAuth(store redisDb.Store, stateTTL time.Duration) *OAuth {
+	if stateTTL <= 0 {
+		stateTTL = 10 * time.Minute
+	}
+	return &OAuth{store: store, stateTTL: stateTTL, providers: make(map[string]OAuthProvider)}
+}
+
+// Register 注册提供方（同名覆盖）
+func (o *OAuth) Register(providers ...OAuthProvider) *OAuth {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, p := range providers {
+		o.providers[p.Name()] = p
+	}
+	return o
+}
+
+// Provider 获取提供方
+func (o *OAuth) Provider(name string) (OAuthProvider, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	p, ok := o.providers[name]
+	return p, ok
+}
+
+// Providers 已注册的提供方名称
+func (o *OAuth) Providers() []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	names := make([]string, 0, len(o.providers))
+	for name := range o.providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Begin 发起登录，返回第三方授权页地址（returnTo为登录成功后的回跳地址，原样保存在state中）
+func (o *OAuth) Begin(ctx context.Context, provider, returnTo string) (string, error) {
+	p, ok := o.Provider(provider)
+	if !ok {
+		return "", fmt.Errorf("%w：%s", ErrOAuthProvider, provider)
+	}
+	st := oauthState{Provider: provider, Nonce: randomToken(16), Verifier: randomToken(32), ReturnTo: returnTo}
+	state := randomToken(32)
+	data, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	if err := o.store.Set(ctx, state, data, o.stateTTL); err != nil {
+		return "", err
+	}
+	return p.AuthCodeURL(state, st.Nonce, pkceChallenge(st.Verifier))
+}
+
+// Complete 处理回调：校验并消费state，换取令牌并获取用户信息
+func (o *OAuth) Complete(ctx context.Context, provider, state, code string) (*OAuthResult, error) {
+	if state == "" || len(state) > 128 {
+		return nil, ErrOAuthState
+	}
+	data, err := o.store.Get(ctx, state)
+	if errors.Is(err, redisDb.ErrNotFound) {
+		return nil, ErrOAuthState
+	}
+	if err != nil {
+		return nil, err
+	}
+	// state一次性有效，先删除再换取令牌，避免同一授权码被并发重放
+	if err := o.store.Delete(ctx, state); err != nil {
+		return nil, err
+	}
+	var st oauthState
+	if err := json.Unmarshal(data, &st); err != nil || st.Provider != provider {
+		return nil, ErrOAuthState
+	}
+	p, ok := o.Provider(provider)
+	if !ok {
+		return nil, fmt.Errorf("%w：%s", ErrOAuthProvider, provider)
+	}
+	if code == "" {
+		return nil, fmt.Errorf("%w：缺少授权码", ErrOAuthDenied)
+	}
+	token, err := p.Exchange(ctx, code, st.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	user, err := p.UserInfo(ctx, token, st.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	user.Provider = provider
+	return &OAuthResult{Provider: provider, User: user, Token: token, ReturnTo: st.ReturnTo}, nil
+}
+
+// randomToken 生成base64url编码的随机串
+func randomToken(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// pkceChallenge PKCE S256校验码
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+var (
+	appOAuths sync.Map // appName -> *OAuth
+	oauthMu   sync.Mutex
+)
+
+// OAuthFromAppConfig 按应用配置创建第三方登录管理器（未配置提供方时返回nil，同一应用多次调用返回同一实例）
+func OAuthFromAppConfig(appName string) (*OAuth, error) {
+	if o, ok := appOAuths.Load(appName); ok {
+		return o.(*OAuth), nil
+	}
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	if o, ok := appOAuths.Load(appName); ok {
+		return o.(*OAuth), nil
+	}
+	cfg := config.GetAppConfig(appName).OAuth
+	if len(cfg.Providers) == 0 {
+		return nil, nil
+	}
+	rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+	if err != nil {
+		return nil, err
+	}
+	o := NewOAuth(rdb.NewStore("oauth:state:"), time.Duration(cfg.StateTTL)*time.Second)
+	for name, pc := range cfg.Providers {
+		p, err := providerFromConfig(name, pc)
+		if err != nil {
+			return nil, err
+		}
+		o.Register(p)
+	}
+	appOAuths.Store(appName, o)
+	return o, nil
+}
+
+// providerFromConfig 按配置类型创建提供方
+func providerFromConfig(name string, pc config.OAuthProviderConfig) (OAuthProvider, error) {
+	switch strings.ToLower(pc.Type) {
+	case "github":
+		p := NewGitHubProvider(pc.ClientID, pc.ClientSecret, pc.RedirectURL, pc.Scopes...)
+		p.ProviderName = name
+		return p, nil
+	case "wechat":
+		p := NewWeChatProvider(pc.ClientID, pc.ClientSecret, pc.RedirectURL, pc.Scopes...)
+		p.ProviderName = name
+		return p, nil
+	case "oidc":
+		return NewOIDCProvider(name, pc.Issuer, pc.ClientID, pc.ClientSecret, pc.RedirectURL, pc.Scopes...)
+	case "", "oauth2":
+		if pc.AuthURL == "" || pc.TokenURL == "" {
+			return nil, fmt.Errorf("auth: 登录提供方%s缺少auth_url/token_url配置", name)
+		}
+		return &OAuth2Provider{
+			ProviderName: name,
+			ClientID:     pc.ClientID,
+			ClientSecret: pc.ClientSecret,
+			RedirectURL:  pc.RedirectURL,
+			AuthURL:      pc.AuthURL,
+			TokenURL:     pc.TokenURL,
+			UserInfoURL:  pc.UserInfoURL,
+			Scopes:       pc.Scopes,
+			UsePKCE:      pc.UsePKCE,
+		}, nil
+	default:
+		return nil, fmt.Errorf("auth: 不支持的登录提供方类型：%s", pc.Type)
+	}
+}
@@ -0,0 +1,385 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// oauthHTTPClient 默认的第三方接口请求客户端
+var oauthHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OAuth2Provider 通用OAuth2提供方（标准授权码模式，令牌接口使用client_secret_post认证）
+type OAuth2Provider struct {
+	ProviderName string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // 回调地址（须与第三方平台登记的一致）
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string // 为空时UserInfo仅返回令牌信息，由调用方自行获取用户资料
+	Scopes       []string
+	UsePKCE      bool              // 携带PKCE校验码（S256）
+	AuthParams   map[string]string // 授权页附加参数（如prompt、access_type）
+	HTTPClient   *http.Client
+	// MapUser 将用户信息接口的原始响应映射为OAuthUser（默认识别sub/id、name/login、email、picture/avatar_url）
+	MapUser func(raw map[string]interface{}) *OAuthUser
+}
+
+// Name 实现OAuthProvider
+func (p *OAuth2Provider) Name() string {
+	return p.ProviderName
+}
+
+// AuthCodeURL 实现OAuthProvider
+func (p *OAuth2Provider) AuthCodeURL(state, _, codeChallenge string) (string, error) {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	if p.RedirectURL != "" {
+		q.Set("redirect_uri", p.RedirectURL)
+	}
+	if len(p.Scopes) > 0 {
+		q.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	q.Set("state", state)
+	if p.UsePKCE {
+		q.Set("code_challenge", codeChallenge)
+		q.Set("code_challenge_method", "S256")
+	}
+	for k, v := range p.AuthParams {
+		q.Set(k, v)
+	}
+	return appendQuery(p.AuthURL, q)
+}
+
+// Exchange 实现OAuthProvider
+func (p *OAuth2Provider) Exchange(ctx context.Context, code, codeVerifier string) (*OAuthToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+	if p.RedirectURL != "" {
+		form.Set("redirect_uri", p.RedirectURL)
+	}
+	if p.UsePKCE {
+		form.Set("code_verifier", codeVerifier)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	raw, err := doOAuthJSON(p.client(), req)
+	if err != nil {
+		return nil, err
+	}
+	return parseOAuthToken(raw)
+}
+
+// UserInfo 实现OAuthProvider
+func (p *OAuth2Provider) UserInfo(ctx context.Context, token *OAuthToken, _ string) (*OAuthUser, error) {
+	if p.UserInfoURL == "" {
+		return &OAuthUser{Raw: token.Raw}, nil
+	}
+	raw, err := getOAuthJSON(ctx, p.client(), p.UserInfoURL, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if p.MapUser != nil {
+		return p.MapUser(raw), nil
+	}
+	return mapStandardUser(raw), nil
+}
+
+func (p *OAuth2Provider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return oauthHTTPClient
+}
+
+// GitHubProvider GitHub登录（邮箱未公开时通过/user/emails获取已验证的主邮箱，需要user:email权限）
+type GitHubProvider struct {
+	OAuth2Provider
+}
+
+// NewGitHubProvider 创建GitHub提供方（scopes默认read:user、user:email）
+func NewGitHubProvider(clientID, clientSecret, redirectURL string, scopes ...string) *GitHubProvider {
+	if len(scopes) == 0 {
+		scopes = []string{"read:user", "user:email"}
+	}
+	return &GitHubProvider{OAuth2Provider{
+		ProviderName: "github",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		UserInfoURL:  "https://api.github.com/user",
+		Scopes:       scopes,
+		UsePKCE:      true,
+	}}
+}
+
+// UserInfo 实现OAuthProvider
+func (p *GitHubProvider) UserInfo(ctx context.Context, token *OAuthToken, _ string) (*OAuthUser, error) {
+	raw, err := getOAuthJSON(ctx, p.client(), p.UserInfoURL, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	user := &OAuthUser{
+		ID:     jsonString(raw, "id"),
+		Name:   jsonString(raw, "name", "login"),
+		Email:  jsonString(raw, "email"),
+		Avatar: jsonString(raw, "avatar_url"),
+		Raw:    raw,
+	}
+	// GitHub仅返回公开邮箱且不标明是否已验证，统一以/user/emails的主邮箱为准（无权限时保留公开邮箱）
+	emailsURL := strings.TrimSuffix(p.UserInfoURL, "/") + "/emails"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, emailsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&emails); err == nil {
+			for _, e := range emails {
+				if e.Primary && e.Verified {
+					user.Email, user.EmailVerified = e.Email, true
+					break
+				}
+			}
+		}
+	}
+	return user, nil
+}
+
+// WeChatProvider 微信登录：scope为snsapi_login时使用网站应用扫码登录，
+// snsapi_userinfo/snsapi_base时使用公众号网页授权（snsapi_base仅返回openid）
+type WeChatProvider struct {
+	ProviderName string
+	AppID        string
+	AppSecret    string
+	RedirectURL  string
+	Scope        string
+	Lang         string // 用户信息语言（zh_CN默认/zh_TW/en）
+	HTTPClient   *http.Client
+}
+
+// NewWeChatProvider 创建微信提供方（scopes默认snsapi_login，取第一个）
+func NewWeChatProvider(appID, appSecret, redirectURL string, scopes ...string) *WeChatProvider {
+	scope := "snsapi_login"
+	if len(scopes) > 0 && scopes[0] != "" {
+		scope = scopes[0]
+	}
+	return &WeChatProvider{ProviderName: "wechat", AppID: appID, AppSecret: appSecret, RedirectURL: redirectURL, Scope: scope}
+}
+
+// Name 实现OAuthProvider
+func (p *WeChatProvider) Name() string {
+	return p.ProviderName
+}
+
+// AuthCodeURL 实现OAuthProvider（微信不支持PKCE与nonce）
+func (p *WeChatProvider) AuthCodeURL(state, _, _ string) (string, error) {
+	endpoint := "https://open.weixin.qq.com/connect/oauth2/authorize"
+	if p.Scope == "snsapi_login" {
+		endpoint = "https://open.weixin.qq.com/connect/qrconnect"
+	}
+	// 微信校验参数顺序，须按appid、redirect_uri、response_type、scope、state拼接
+	return endpoint + "?appid=" + url.QueryEscape(p.AppID) +
+		"&redirect_uri=" + url.QueryEscape(p.RedirectURL) +
+		"&response_type=code&scope=" + url.QueryEscape(p.Scope) +
+		"&state=" + url.QueryEscape(state) + "#wechat_redirect", nil
+}
+
+// Exchange 实现OAuthProvider
+func (p *WeChatProvider) Exchange(ctx context.Context, code, _ string) (*OAuthToken, error) {
+	q := url.Values{}
+	q.Set("appid", p.AppID)
+	q.Set("secret", p.AppSecret)
+	q.Set("code", code)
+	q.Set("grant_type", "authorization_code")
+	raw, err := getOAuthJSON(ctx, p.client(), "https://api.weixin.qq.com/sns/oauth2/access_token?"+q.Encode(), "")
+	if err != nil {
+		return nil, err
+	}
+	if err := weChatError(raw); err != nil {
+		return nil, err
+	}
+	return parseOAuthToken(raw)
+}
+
+// UserInfo 实现OAuthProvider
+func (p *WeChatProvider) UserInfo(ctx context.Context, token *OAuthToken, _ string) (*OAuthUser, error) {
+	user := &OAuthUser{ID: jsonString(token.Raw, "openid"), UnionID: jsonString(token.Raw, "unionid"), Raw: token.Raw}
+	if p.Scope == "snsapi_base" {
+		return user, nil
+	}
+	lang := p.Lang
+	if lang == "" {
+		lang = "zh_CN"
+	}
+	q := url.Values{}
+	q.Set("access_token", token.AccessToken)
+	q.Set("openid", user.ID)
+	q.Set("lang", lang)
+	raw, err := getOAuthJSON(ctx, p.client(), "https://api.weixin.qq.com/sns/userinfo?"+q.Encode(), "")
+	if err != nil {
+		return nil, err
+	}
+	if err := weChatError(raw); err != nil {
+		return nil, err
+	}
+	user.Name = jsonString(raw, "nickname")
+	user.Avatar = jsonString(raw, "headimgurl")
+	if unionID := jsonString(raw, "unionid"); unionID != "" {
+		user.UnionID = unionID
+	}
+	user.Raw = raw
+	return user, nil
+}
+
+func (p *WeChatProvider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return oauthHTTPClient
+}
+
+// weChatError 微信接口以errcode表示错误（HTTP状态码始终为200）
+func weChatError(raw map[string]interface{}) error {
+	if code := jsonString(raw, "errcode"); code != "" && code != "0" {
+		return fmt.Errorf("%w：微信接口错误%s：%s", ErrOAuthDenied, code, jsonString(raw, "errmsg"))
+	}
+	return nil
+}
+
+// getOAuthJSON GET请求JSON接口（bearer非空时携带Authorization头）
+func getOAuthJSON(ctx context.Context, client *http.Client, endpoint, bearer string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	return doOAuthJSON(client, req)
+}
+
+// doOAuthJSON 发送请求并解析JSON响应（数字保留为json.Number，避免大整数ID丢失精度）
+func doOAuthJSON(client *http.Client, req *http.Request) (map[string]interface{}, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		// 部分平台（如旧版GitHub）忽略Accept头返回表单格式
+		values, perr := url.ParseQuery(string(body))
+		if perr != nil || len(values) == 0 {
+			return nil, fmt.Errorf("auth: 第三方接口响应解析失败（HTTP %d）：%w", resp.StatusCode, err)
+		}
+		for k := range values {
+			raw[k] = values.Get(k)
+		}
+	}
+	if e := jsonString(raw, "error"); e != "" {
+		return nil, fmt.Errorf("%w：%s %s", ErrOAuthDenied, e, jsonString(raw, "error_description"))
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%w：HTTP %d", ErrOAuthDenied, resp.StatusCode)
+	}
+	return raw, nil
+}
+
+// parseOAuthToken 解析令牌响应
+func parseOAuthToken(raw map[string]interface{}) (*OAuthToken, error) {
+	token := &OAuthToken{
+		AccessToken:  jsonString(raw, "access_token"),
+		TokenType:    jsonString(raw, "token_type"),
+		RefreshToken: jsonString(raw, "refresh_token"),
+		Scope:        jsonString(raw, "scope"),
+		IDToken:      jsonString(raw, "id_token"),
+		Raw:          raw,
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("auth: 令牌响应缺少access_token")
+	}
+	token.ExpiresIn, _ = strconv.ParseInt(jsonString(raw, "expires_in"), 10, 64)
+	return token, nil
+}
+
+// mapStandardUser 按常见字段名映射用户信息（兼容OIDC标准声明与多数平台的自定义字段）
+func mapStandardUser(raw map[string]interface{}) *OAuthUser {
+	verified, _ := raw["email_verified"].(bool)
+	return &OAuthUser{
+		ID:            jsonString(raw, "sub", "id", "user_id", "openid"),
+		UnionID:       jsonString(raw, "unionid"),
+		Name:          jsonString(raw, "name", "nickname", "login", "preferred_username"),
+		Email:         jsonString(raw, "email"),
+		EmailVerified: verified,
+		Avatar:        jsonString(raw, "picture", "avatar_url", "avatar"),
+		Raw:           raw,
+	}
+}
+
+// jsonString 按顺序取第一个非空字段并转为字符串
+func jsonString(raw map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := raw[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case json.Number:
+			return v.String()
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// appendQuery 向地址追加查询参数（保留地址中已有的参数）
+func appendQuery(endpoint string, q url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	existing := u.Query()
+	for k, vs := range q {
+		existing[k] = vs
+	}
+	u.RawQuery = existing.Encode()
+	return u.String(), nil
+}
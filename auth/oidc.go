@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval 遇到未知kid时重新拉取JWKS的最小间隔（防止伪造kid触发频繁请求）
+const jwksRefreshInterval = time.Minute
+
+// OIDCProvider 通用OpenID Connect提供方：通过{issuer}/.well-known/openid-configuration自动发现端点，
+// 使用JWKS校验id_token的签名、iss、aud、exp及nonce
+type OIDCProvider struct {
+	OAuth2Provider
+	Issuer string
+
+	mu          sync.Mutex
+	discovered  bool
+	jwksURL     string
+	keys        map[string]interface{} // kid -> 公钥
+	keysFetched time.Time
+}
+
+// NewOIDCProvider 创建OIDC提供方（scopes默认openid、profile、email；端点在首次使用时发现）
+func NewOIDCProvider(name, issuer, clientID, clientSecret, redirectURL string, scopes ...string) (*OIDCProvider, error) {
+	if issuer == "" || clientID == "" {
+		return nil, fmt.Errorf("auth: OIDC提供方%s缺少issuer/client_id配置", name)
+	}
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	return &OIDCProvider{
+		OAuth2Provider: OAuth2Provider{
+			ProviderName: name,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       scopes,
+			UsePKCE:      true,
+		},
+		Issuer: strings.TrimSuffix(issuer, "/"),
+	}, nil
+}
+
+// AuthCodeURL 实现OAuthProvider
+func (p *OIDCProvider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	if err := p.discover(context.Background()); err != nil {
+		return "", err
+	}
+	authURL, err := p.OAuth2Provider.AuthCodeURL(state, nonce, codeChallenge)
+	if err != nil || nonce == "" {
+		return authURL, err
+	}
+	return authURL + "&nonce=" + nonce, nil
+}
+
+// Exchange 实现OAuthProvider
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (*OAuthToken, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+	return p.OAuth2Provider.Exchange(ctx, code, codeVerifier)
+}
+
+// UserInfo 实现OAuthProvider：校验id_token，存在userinfo端点时合并其返回的资料
+func (p *OIDCProvider) UserInfo(ctx context.Context, token *OAuthToken, nonce string) (*OAuthUser, error) {
+	if token.IDToken == "" {
+		return nil, errors.New("auth: OIDC令牌响应缺少id_token")
+	}
+	claims, err := p.VerifyIDToken(ctx, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	user := mapStandardUser(claims)
+	if p.UserInfoURL == "" {
+		return user, nil
+	}
+	raw, err := getOAuthJSON(ctx, p.client(), p.UserInfoURL, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if sub := jsonString(raw, "sub"); sub != user.ID {
+		return nil, fmt.Errorf("%w：userinfo与id_token的sub不一致", ErrInvalidToken)
+	}
+	for k, v := range claims {
+		if _, ok := raw[k]; !ok {
+			raw[k] = v
+		}
+	}
+	return mapStandardUser(raw), nil
+}
+
+// VerifyIDToken 校验id_token并返回声明（nonce为空时不校验nonce）
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, idToken, nonce string) (map[string]interface{}, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w：%v", ErrInvalidToken, err)
+	}
+	if nonce != "" {
+		if got, _ := claims["nonce"].(string); got != nonce {
+			return nil, fmt.Errorf("%w：nonce不匹配", ErrInvalidToken)
+		}
+	}
+	return claims, nil
+}
+
+// discover 拉取发现文档（成功后缓存，已显式配置的端点不被覆盖）
+func (p *OIDCProvider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered {
+		return nil
+	}
+	raw, err := getOAuthJSON(ctx, p.client(), p.Issuer+"/.well-known/openid-configuration", "")
+	if err != nil {
+		return fmt.Errorf("auth: OIDC发现文档获取失败：%w", err)
+	}
+	if iss := jsonString(raw, "issuer"); strings.TrimSuffix(iss, "/") != p.Issuer {
+		return fmt.Errorf("auth: OIDC发现文档issuer不匹配：%s", iss)
+	}
+	if p.AuthURL == "" {
+		p.AuthURL = jsonString(raw, "authorization_endpoint")
+	}
+	if p.TokenURL == "" {
+		p.TokenURL = jsonString(raw, "token_endpoint")
+	}
+	if p.UserInfoURL == "" {
+		p.UserInfoURL = jsonString(raw, "userinfo_endpoint")
+	}
+	p.jwksURL = jsonString(raw, "jwks_uri")
+	if p.AuthURL == "" || p.TokenURL == "" || p.jwksURL == "" {
+		return errors.New("auth: OIDC发现文档缺少必要端点")
+	}
+	p.discovered = true
+	return nil
+}
+
+// key 按kid获取公钥（未命中时限频重新拉取JWKS，以支持密钥轮换）
+func (p *OIDCProvider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("auth: 未知的签名密钥：%s", kid)
+	}
+	keys, err := fetchJWKS(ctx, p.client(), p.jwksURL)
+	p.keysFetched = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("auth: 未知的签名密钥：%s", kid)
+}
+
+// lookupKey 令牌未携带kid且仅有一个密钥时使用该密钥
+func (p *OIDCProvider) lookupKey(kid string) (interface{}, bool) {
+	if k, ok := p.keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// fetchJWKS 拉取并解析JWKS（支持RSA与EC P-256/P-384/P-521签名密钥）
+func fetchJWKS(ctx context.Context, client *http.Client, jwksURL string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth: JWKS获取失败（HTTP %d）", resp.StatusCode)
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("auth: JWKS解析失败：%w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil || len(e) > 4 {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
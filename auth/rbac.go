@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/go-redis/redis"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 基于角色的访问控制（RBAC）：按资源（WS action、HTTP "METHOD /path"或"/path"、gRPC完整方法名）声明所需的角色/权限，
+// 角色拥有的权限由策略中的Roles定义，用户角色取自认证结果（Claims.Roles）。
+// HTTP/WS/gRPC分别通过http.RBAC、websocket.RBAC、grpc.RBACInterceptor接入，须注册在认证中间件之后。
+//
+// 策略可写在应用配置rbac中，也可通过WithLoader从数据库加载（与配置合并，同名规则以加载的为准），
+// 加载结果缓存在进程内与Redis（多实例共享），角色权限变更后调用Invalidate使缓存失效。
+
+// rbacCacheKey 策略缓存的Redis键（会再拼接Redis表前缀）
+const rbacCacheKey = "auth:rbac:policy"
+
+const defaultRBACCacheTTL = time.Minute
+
+var (
+	// ErrPermissionDenied 已认证但不具备访问资源所需的角色/权限
+	ErrPermissionDenied = errs.Forbidden.WithMessage("auth: 权限不足")
+	// ErrUnauthenticated 访问受保护的资源但请求未认证
+	ErrUnauthenticated = errs.Unauthorized.WithMessage("auth: 未认证")
+)
+
+// Rule 资源的访问要求（Roles满足其一且具备全部Permissions；两者均为空时仅要求已认证）
+type Rule struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// Policy 权限策略
+type Policy struct {
+	Rules map[string]Rule     `json:"rules"` // 资源 -> 访问要求（以*结尾时按前缀匹配，如"admin.*"、"/admin/*"、"/pkg.Service/*"，"*"匹配全部）
+	Roles map[string][]string `json:"roles"` // 角色 -> 拥有的权限（"*"表示全部权限）
+}
+
+// PolicyLoader 从数据库等外部来源加载权限策略
+type PolicyLoader func(ctx context.Context) (*Policy, error)
+
+// compiledPolicy 编译后的策略（精确匹配与前缀匹配分开，前缀按长度降序）
+type compiledPolicy struct {
+	exact    map[string]Rule
+	prefixes []string
+	prefixed map[string]Rule
+	roles    map[string]map[string]bool
+}
+
+// RBAC 访问控制器
+type RBAC struct {
+	static *Policy
+	loader PolicyLoader
+	rdb    *redisDb.RedisDb
+	ttl    time.Duration
+
+	mu       sync.RWMutex
+	loadMu   sync.Mutex
+	compiled *compiledPolicy
+	expireAt time.Time // 加载策略的缓存过期时间（未设置loader时不过期）
+}
+
+// NewRBAC 创建访问控制器（policy为静态策略，可为nil）
+func NewRBAC(policy *Policy) *RBAC {
+	if policy == nil {
+		policy = &Policy{}
+	}
+	r := &RBAC{static: policy, ttl: defaultRBACCacheTTL}
+	r.compiled = compilePolicy(policy, nil)
+	return r
+}
+
+// WithLoader 设置策略加载函数与缓存时间（ttl<=0时默认60秒）
+func (r *RBAC) WithLoader(loader PolicyLoader, ttl time.Duration) *RBAC {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loader = loader
+	if ttl > 0 {
+		r.ttl = ttl
+	}
+	r.expireAt = time.Time{}
+	return r
+}
+
+// WithCache 使用Redis缓存加载的策略（多实例共享，减少数据库查询）
+func (r *RBAC) WithCache(rdb *redisDb.RedisDb) *RBAC {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rdb = rdb
+	return r
+}
+
+// Invalidate 清除进程内与Redis中的策略缓存（角色/权限数据变更后调用，其他实例在各自进程内缓存到期后生效）
+func (r *RBAC) Invalidate(ctx context.Context) error {
+	r.mu.Lock()
+	r.expireAt = time.Time{}
+	rdb := r.rdb
+	r.mu.Unlock()
+	if rdb == nil {
+		return nil
+	}
+	return rdb.WithContext(ctx).Db.Del(rdb.DbPre + rbacCacheKey).Err()
+}
+
+// Authorize 校验用户是否可以访问资源：未匹配到规则的资源直接放行，claims为nil时返回ErrUnauthenticated，
+// 权限不足时返回ErrPermissionDenied
+func (r *RBAC) Authorize(ctx context.Context, resource string, claims *Claims) error {
+	return r.AuthorizeAny(ctx, []string{resource}, claims)
+}
+
+// AuthorizeAny 按顺序取第一个匹配到规则的资源进行校验（如HTTP依次尝试"GET /user/1"与"/user/1"）
+func (r *RBAC) AuthorizeAny(ctx context.Context, resources []string, claims *Claims) error {
+	policy, err := r.policy(ctx)
+	if err != nil {
+		return err
+	}
+	for _, resource := range resources {
+		rule, ok := policy.match(resource)
+		if !ok {
+			continue
+		}
+		if claims == nil {
+			return ErrUnauthenticated
+		}
+		if !policy.allow(rule, claims) {
+			return ErrPermissionDenied
+		}
+		return nil
+	}
+	return nil
+}
+
+// HasPermission 用户角色是否拥有指定权限（供控制器内按数据做更细的判断）
+func (r *RBAC) HasPermission(ctx context.Context, claims *Claims, permission string) (bool, error) {
+	if claims == nil {
+		return false, nil
+	}
+	policy, err := r.policy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return policy.hasPermission(claims.Roles, permission), nil
+}
+
+// policy 当前生效的策略（设置了loader时按缓存时间重新加载，加载失败时沿用旧策略并返回错误）
+func (r *RBAC) policy(ctx context.Context) (*compiledPolicy, error) {
+	r.mu.RLock()
+	compiled, loader, fresh := r.compiled, r.loader, time.Now().Before(r.expireAt)
+	r.mu.RUnlock()
+	if loader == nil || fresh {
+		return compiled, nil
+	}
+	r.loadMu.Lock()
+	defer r.loadMu.Unlock()
+	r.mu.RLock()
+	compiled, fresh = r.compiled, time.Now().Before(r.expireAt)
+	r.mu.RUnlock()
+	if fresh {
+		return compiled, nil
+	}
+	loaded, err := r.load(ctx, loader)
+	if err != nil {
+		return compiled, err
+	}
+	compiled = compilePolicy(r.static, loaded)
+	r.mu.Lock()
+	r.compiled, r.expireAt = compiled, time.Now().Add(r.ttl)
+	r.mu.Unlock()
+	return compiled, nil
+}
+
+// load 读取Redis缓存，未命中时调用loader并回写缓存
+func (r *RBAC) load(ctx context.Context, loader PolicyLoader) (*Policy, error) {
+	r.mu.RLock()
+	rdb, ttl := r.rdb, r.ttl
+	r.mu.RUnlock()
+	if rdb != nil {
+		data, err := rdb.WithContext(ctx).Db.Get(rdb.DbPre + rbacCacheKey).Bytes()
+		if err == nil {
+			policy := &Policy{}
+			if json.Unmarshal(data, policy) == nil {
+				return policy, nil
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			// Redis不可用时直接从数据源加载
+			rdb = nil
+		}
+	}
+	policy, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		policy = &Policy{}
+	}
+	if rdb != nil {
+		if data, err := json.Marshal(policy); err == nil {
+			_ = rdb.WithContext(ctx).Db.Set(rdb.DbPre+rbacCacheKey, data, ttl).Err()
+		}
+	}
+	return policy, nil
+}
+
+// compilePolicy 合并静态策略与加载的策略（同名规则/角色以加载的为准）
+func compilePolicy(static, loaded *Policy) *compiledPolicy {
+	c := &compiledPolicy{
+		exact:    make(map[string]Rule),
+		prefixed: make(map[string]Rule),
+		roles:    make(map[string]map[string]bool),
+	}
+	for _, p := range []*Policy{static, loaded} {
+		if p == nil {
+			continue
+		}
+		for resource, rule := range p.Rules {
+			if prefix, ok := strings.CutSuffix(resource, "*"); ok {
+				c.prefixed[prefix] = rule
+			} else {
+				c.exact[resource] = rule
+			}
+		}
+		for role, perms := range p.Roles {
+			set := make(map[string]bool, len(perms))
+			for _, perm := range perms {
+				set[perm] = true
+			}
+			c.roles[role] = set
+		}
+	}
+	for prefix := range c.prefixed {
+		c.prefixes = append(c.prefixes, prefix)
+	}
+	sort.Slice(c.prefixes, func(i, j int) bool { return len(c.prefixes[i]) > len(c.prefixes[j]) })
+	return c
+}
+
+// match 匹配资源的规则（精确匹配优先，其次最长前缀）
+func (c *compiledPolicy) match(resource string) (Rule, bool) {
+	if rule, ok := c.exact[resource]; ok {
+		return rule, true
+	}
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(resource, prefix) {
+			return c.prefixed[prefix], true
+		}
+	}
+	return Rule{}, false
+}
+
+func (c *compiledPolicy) allow(rule Rule, claims *Claims) bool {
+	if len(rule.Roles) > 0 {
+		matched := false
+		for _, role := range rule.Roles {
+			if claims.HasRole(role) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, perm := range rule.Permissions {
+		if !c.hasPermission(claims.Roles, perm) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *compiledPolicy) hasPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		if perms := c.roles[role]; perms[permission] || perms["*"] {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	appRBACs sync.Map // appName -> *RBAC
+	rbacMu   sync.Mutex
+)
+
+// RBACFromAppConfig 按应用配置rbac创建访问控制器（同一应用多次调用返回同一实例；配置了redis_db时启用Redis缓存，
+// 从数据库加载策略需再调用WithLoader）
+func RBACFromAppConfig(appName string) (*RBAC, error) {
+	if r, ok := appRBACs.Load(appName); ok {
+		return r.(*RBAC), nil
+	}
+	rbacMu.Lock()
+	defer rbacMu.Unlock()
+	if r, ok := appRBACs.Load(appName); ok {
+		return r.(*RBAC), nil
+	}
+	cfg := config.GetAppConfig(appName).RBAC
+	policy := &Policy{Rules: make(map[string]Rule, len(cfg.Rules)), Roles: cfg.Roles}
+	for resource, rule := range cfg.Rules {
+		policy.Rules[resource] = Rule{Roles: rule.Roles, Permissions: rule.Permissions}
+	}
+	r := NewRBAC(policy)
+	if cfg.CacheTTL > 0 {
+		r.ttl = time.Duration(cfg.CacheTTL) * time.Second
+	}
+	if cfg.RedisDb != "" {
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return nil, err
+		}
+		r.WithCache(rdb)
+	}
+	appRBACs.Store(appName, r)
+	return r, nil
+}
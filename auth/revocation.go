@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"golang.org/x/crypto/ocsp"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 客户端证书吊销检查（mTLS握手阶段执行）：
+//   - CRL：从本地文件加载（由运维或证书系统定期下发），按签发者匹配证书链中的每一级证书，文件变更后自动重新加载
+//   - OCSP：向叶子证书AIA中的OCSP地址查询，响应按NextUpdate缓存；soft模式下查询失败放行，hard模式下拒绝
+//
+// 明确吊销的证书在两种模式下都会被拒绝。
+
+// OCSP检查模式
+const (
+	OCSPSoft = "soft" // 查询失败或状态未知时放行（仅拒绝明确吊销的证书）
+	OCSPHard = "hard" // 查询失败、状态未知或证书未提供OCSP地址时拒绝
+)
+
+const (
+	defaultCRLRefresh   = 5 * time.Minute
+	defaultOCSPTimeout  = 3 * time.Second
+	defaultOCSPCache    = time.Hour // 响应未提供NextUpdate时的缓存时长
+	maxOCSPResponse     = 1 << 20
+	maxOCSPCacheEntries = 10000
+)
+
+// ErrCertRevoked 客户端证书已被吊销
+var ErrCertRevoked = errors.New("client certificate has been revoked")
+
+// revocationChecker 证书吊销检查
+type revocationChecker struct {
+	crl  *crlStore
+	ocsp *ocspChecker
+}
+
+// newRevocationChecker 按mTLS配置创建吊销检查（未配置CRL与OCSP时返回nil）
+func newRevocationChecker(opts *MTLSOptions) (*revocationChecker, error) {
+	checker := &revocationChecker{}
+	if opts.CRLFile != "" {
+		refresh := opts.CRLRefresh
+		if refresh <= 0 {
+			refresh = defaultCRLRefresh
+		}
+		store := &crlStore{file: opts.CRLFile, refresh: refresh}
+		if err := store.load(); err != nil {
+			return nil, err
+		}
+		checker.crl = store
+	}
+	switch strings.ToLower(opts.OCSP) {
+	case "":
+	case OCSPSoft, OCSPHard:
+		timeout := opts.OCSPTimeout
+		if timeout <= 0 {
+			timeout = defaultOCSPTimeout
+		}
+		checker.ocsp = &ocspChecker{
+			hard:   strings.EqualFold(opts.OCSP, OCSPHard),
+			client: &http.Client{Timeout: timeout},
+			cache:  make(map[string]ocspCacheEntry),
+		}
+	default:
+		return nil, fmt.Errorf("invalid mTLS ocsp mode %q (expected soft or hard)", opts.OCSP)
+	}
+	if checker.crl == nil && checker.ocsp == nil {
+		return nil, nil
+	}
+	return checker, nil
+}
+
+// check 检查已校验的证书链（chain[0]为叶子证书，最后一级为根CA）
+func (r *revocationChecker) check(chain []*x509.Certificate) error {
+	if r.crl != nil {
+		for i := 0; i+1 < len(chain); i++ {
+			if err := r.crl.check(chain[i], chain[i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	if r.ocsp != nil && len(chain) > 1 {
+		return r.ocsp.check(chain[0], chain[1])
+	}
+	return nil
+}
+
+// crlStore 本地CRL文件
+type crlStore struct {
+	file    string
+	refresh time.Duration
+
+	mu      sync.RWMutex
+	lists   []*crlEntry
+	modTime time.Time
+	checked time.Time
+}
+
+// crlEntry 一个签发者的CRL
+type crlEntry struct {
+	list     *x509.RevocationList
+	revoked  map[string]struct{} // 序列号（十进制）
+	verified sync.Map            // 签发者证书（DER） -> 签名校验结果（nil或error）
+}
+
+// load 加载CRL文件（PEM中的X509 CRL块，或单个DER编码的CRL）
+func (s *crlStore) load() error {
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return fmt.Errorf("failed to read CRL file: %w", err)
+	}
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return fmt.Errorf("failed to read CRL file: %w", err)
+	}
+	var ders [][]byte
+	if bytes.Contains(data, []byte("-----BEGIN")) {
+		for rest := data; ; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type == "X509 CRL" {
+				ders = append(ders, block.Bytes)
+			}
+		}
+	} else {
+		ders = append(ders, data)
+	}
+	if len(ders) == 0 {
+		return fmt.Errorf("no valid CRL in file %s", s.file)
+	}
+	lists := make([]*crlEntry, 0, len(ders))
+	for _, der := range ders {
+		list, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return fmt.Errorf("failed to parse CRL file %s: %w", s.file, err)
+		}
+		if !list.NextUpdate.IsZero() && time.Now().After(list.NextUpdate) {
+			logger.Warn("CRL已过期（仍按现有内容检查），签发者：", list.Issuer.String(), "，NextUpdate：", list.NextUpdate)
+		}
+		entry := &crlEntry{list: list, revoked: make(map[string]struct{}, len(list.RevokedCertificateEntries))}
+		for _, revoked := range list.RevokedCertificateEntries {
+			entry.revoked[revoked.SerialNumber.String()] = struct{}{}
+		}
+		lists = append(lists, entry)
+	}
+	s.mu.Lock()
+	s.lists, s.modTime, s.checked = lists, info.ModTime(), time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// reloadIfChanged 超过检查间隔时按文件修改时间重新加载（加载失败时保留原内容）
+func (s *crlStore) reloadIfChanged() {
+	s.mu.Lock()
+	if time.Since(s.checked) < s.refresh {
+		s.mu.Unlock()
+		return
+	}
+	s.checked = time.Now()
+	modTime := s.modTime
+	s.mu.Unlock()
+	info, err := os.Stat(s.file)
+	if err != nil || info.ModTime().Equal(modTime) {
+		return
+	}
+	if err := s.load(); err != nil {
+		logger.Error("CRL重新加载失败，继续使用原内容：", err)
+		return
+	}
+	logger.Info("CRL已重新加载：", s.file)
+}
+
+// check 检查证书是否被issuer签发的CRL吊销（CRL签名须由issuer签发，签名无效的CRL视为检查失败）
+func (s *crlStore) check(cert, issuer *x509.Certificate) error {
+	s.reloadIfChanged()
+	s.mu.RLock()
+	lists := s.lists
+	s.mu.RUnlock()
+	for _, entry := range lists {
+		if !bytes.Equal(entry.list.RawIssuer, cert.RawIssuer) {
+			continue
+		}
+		if err := entry.verify(issuer); err != nil {
+			return err
+		}
+		if _, ok := entry.revoked[cert.SerialNumber.String()]; ok {
+			return fmt.Errorf("%w: serial %s", ErrCertRevoked, cert.SerialNumber.String())
+		}
+	}
+	return nil
+}
+
+// verify 校验CRL由issuer签发（按签发者缓存结果）
+func (e *crlEntry) verify(issuer *x509.Certificate) error {
+	key := string(issuer.Raw)
+	if v, ok := e.verified.Load(key); ok {
+		err, _ := v.(error)
+		return err
+	}
+	err := e.list.CheckSignatureFrom(issuer)
+	if err != nil {
+		err = fmt.Errorf("invalid CRL signature for issuer %q: %w", issuer.Subject.String(), err)
+		e.verified.Store(key, err)
+		return err
+	}
+	e.verified.Store(key, nil)
+	return nil
+}
+
+// ocspChecker OCSP在线吊销检查
+type ocspChecker struct {
+	hard   bool
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]ocspCacheEntry
+}
+
+type ocspCacheEntry struct {
+	status  int
+	expires time.Time
+}
+
+// check 查询叶子证书的吊销状态
+func (o *ocspChecker) check(cert, issuer *x509.Certificate) error {
+	key := string(issuer.RawSubject) + "/" + cert.SerialNumber.String()
+	o.mu.Lock()
+	entry, ok := o.cache[key]
+	o.mu.Unlock()
+	if !ok || time.Now().After(entry.expires) {
+		resp, err := o.query(cert, issuer)
+		if err != nil {
+			if o.hard {
+				return fmt.Errorf("OCSP check failed: %w", err)
+			}
+			logger.Warn("OCSP查询失败（soft模式，放行）：", cert.Subject.CommonName, err)
+			return nil
+		}
+		entry = ocspCacheEntry{status: resp.Status, expires: resp.NextUpdate}
+		if entry.expires.IsZero() {
+			entry.expires = time.Now().Add(defaultOCSPCache)
+		}
+		o.mu.Lock()
+		if len(o.cache) >= maxOCSPCacheEntries {
+			o.pruneLocked()
+		}
+		o.cache[key] = entry
+		o.mu.Unlock()
+	}
+	switch entry.status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return fmt.Errorf("%w: serial %s", ErrCertRevoked, cert.SerialNumber.String())
+	default:
+		if o.hard {
+			return errors.New("OCSP status of client certificate is unknown")
+		}
+		return nil
+	}
+}
+
+// pruneLocked 清理过期的缓存（仍超过上限时清空）
+func (o *ocspChecker) pruneLocked() {
+	now := time.Now()
+	for key, entry := range o.cache {
+		if now.After(entry.expires) {
+			delete(o.cache, key)
+		}
+	}
+	if len(o.cache) >= maxOCSPCacheEntries {
+		o.cache = make(map[string]ocspCacheEntry)
+	}
+}
+
+// query 依次向证书中的OCSP地址查询（响应须由issuer或其授权的签名证书签发）
+func (o *ocspChecker) query(cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	if len(cert.OCSPServer) == 0 {
+		return nil, errors.New("certificate has no OCSP server")
+	}
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, server := range cert.OCSPServer {
+		res, err := o.client.Post(server, "application/ocsp-request", bytes.NewReader(req))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(res.Body, maxOCSPResponse))
+		_ = res.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if res.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("OCSP responder %s returned %d", server, res.StatusCode)
+			continue
+		}
+		resp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,310 @@
+package base
+
+import (
+	"fmt"
+	"github.com/dfpopp/go-dai/grpc"
+	"github.com/dfpopp/go-dai/http"
+	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/websocket"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 控制器自动注册：扫描控制器结构体，按路由声明或方法命名约定批量注册HTTP/WS/gRPC路由，替代逐条的手工注册。
+// 处理方法签名须为func(netContext.Context)。
+//
+// 1. 路由声明：以空白字段声明路由，route/ws/grpc标签分别指定HTTP路由、WS action与gRPC方法，handler指定处理方法
+//
+//	type UserController struct {
+//		*base.BaseController
+//		svc *service.UserService
+//
+//		_ base.Route `route:"GET /user/:id" ws:"user.get" handler:"GetUser"`
+//		_ base.Route `route:"POST /user" handler:"CreateUser"`
+//		_ base.Route `grpc:"/user.UserService/GetUser" handler:"GetUser"`
+//	}
+//
+// 2. 命名约定：控制器实现RoutePrefix时，未被路由声明引用的处理方法按方法名注册（prefix为"/user"时）：
+//   - HTTP：以Get/Post/Put/Patch/Delete开头的方法，如GetInfo -> GET /user/info，PostLoginSms -> POST /user/login-sms，Get -> GET /user
+//   - WS：全部处理方法，action为"前缀.方法名首字母小写"，如GetInfo -> user.getInfo
+//
+// 每个请求基于注册的控制器浅拷贝出新实例（服务等依赖字段共享），嵌入的BaseController替换为新实例，
+// 再按协议调用Init（HTTP/gRPC）或WsInit（WS）注入上下文，避免并发请求共用同一控制器的上下文字段。
+// 控制器可实现HTTPMiddlewares/WSMiddlewares/GRPCMiddlewares为其全部路由附加中间件。
+
+// Route 路由声明标记（用于控制器中的空白字段）
+type Route struct{}
+
+// RoutePrefixer 启用命名约定注册的控制器（返回路由前缀，可为空）
+type RoutePrefixer interface {
+	RoutePrefix() string
+}
+
+// HTTPMiddlewareProvider 控制器级HTTP中间件
+type HTTPMiddlewareProvider interface {
+	HTTPMiddlewares() []http.MiddlewareFunc
+}
+
+// WSMiddlewareProvider 控制器级WS中间件
+type WSMiddlewareProvider interface {
+	WSMiddlewares() []websocket.MiddlewareFunc
+}
+
+// GRPCMiddlewareProvider 控制器级gRPC中间件
+type GRPCMiddlewareProvider interface {
+	GRPCMiddlewares() []grpc.MiddlewareFunc
+}
+
+// 路由协议（对应路由声明的标签名）
+const (
+	routeKindHTTP = "route"
+	routeKindWS   = "ws"
+	routeKindGRPC = "grpc"
+)
+
+var (
+	handlerType  = reflect.TypeOf((*func(netContext.Context))(nil)).Elem()
+	baseCtrlType = reflect.TypeOf(BaseController{})
+	baseCtrlPtr  = reflect.TypeOf(&BaseController{})
+	httpVerbs    = []string{"Get", "Post", "Put", "Patch", "Delete"}
+)
+
+// controllerRoute 扫描得到的路由
+type controllerRoute struct {
+	verb   string // HTTP方法（仅HTTP）
+	target string // HTTP路径/WS action/gRPC方法
+	method int    // 处理方法在指针类型上的索引
+}
+
+// controllerMeta 控制器模板（每个请求由其复制出新实例）
+type controllerMeta struct {
+	tpl       reflect.Value // 注册的控制器（指向结构体的指针）
+	baseIndex []int         // 嵌入的BaseController字段（为nil时未嵌入）
+	basePtr   bool          // 嵌入的是*BaseController
+	userField string        // 模板中BaseController的UserIDField
+	conns     *sync.Map     // 各实例共享的用户-连接映射
+}
+
+// RegisterHTTPControllers 自动注册控制器的HTTP路由
+func RegisterHTTPControllers(server *http.Server, controllers ...interface{}) error {
+	for _, ctrl := range controllers {
+		meta, routes, err := scanController(ctrl, routeKindHTTP)
+		if err != nil {
+			return err
+		}
+		var middlewares []http.MiddlewareFunc
+		if p, ok := ctrl.(HTTPMiddlewareProvider); ok {
+			middlewares = p.HTTPMiddlewares()
+		}
+		for _, route := range routes {
+			server.Handle(route.verb, route.target, http.ToHTTPHandler(meta.handler(route.method, false)), middlewares...)
+		}
+	}
+	return nil
+}
+
+// RegisterWSControllers 自动注册控制器的WS路由
+func RegisterWSControllers(server *websocket.Server, controllers ...interface{}) error {
+	for _, ctrl := range controllers {
+		meta, routes, err := scanController(ctrl, routeKindWS)
+		if err != nil {
+			return err
+		}
+		var middlewares []websocket.MiddlewareFunc
+		if p, ok := ctrl.(WSMiddlewareProvider); ok {
+			middlewares = p.WSMiddlewares()
+		}
+		for _, route := range routes {
+			server.Register(route.target, websocket.ToWSHandler(meta.handler(route.method, true)), middlewares...)
+		}
+	}
+	return nil
+}
+
+// RegisterGRPCControllers 自动注册控制器的gRPC路由（仅支持路由声明，处理器在该方法的gRPC服务实现之前执行）
+func RegisterGRPCControllers(server *grpc.Server, controllers ...interface{}) error {
+	for _, ctrl := range controllers {
+		meta, routes, err := scanController(ctrl, routeKindGRPC)
+		if err != nil {
+			return err
+		}
+		var middlewares []grpc.MiddlewareFunc
+		if p, ok := ctrl.(GRPCMiddlewareProvider); ok {
+			middlewares = p.GRPCMiddlewares()
+		}
+		for _, route := range routes {
+			server.Register(route.target, grpc.ToGRPCHandler(meta.handler(route.method, false)), middlewares...)
+		}
+	}
+	return nil
+}
+
+// scanController 扫描控制器的路由声明与命名约定
+func scanController(ctrl interface{}, kind string) (*controllerMeta, []controllerRoute, error) {
+	val := reflect.ValueOf(ctrl)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("控制器须为非nil的结构体指针：%T", ctrl)
+	}
+	ptrType, structType := val.Type(), val.Elem().Type()
+	meta := &controllerMeta{tpl: val}
+	if field, ok := structType.FieldByName(baseCtrlType.Name()); ok && field.Anonymous {
+		switch field.Type {
+		case baseCtrlType:
+			meta.baseIndex = field.Index
+			tplBase := val.Elem().FieldByIndex(field.Index).Addr().Interface().(*BaseController)
+			meta.userField, meta.conns = tplBase.UserIDField, tplBase.connMap()
+		case baseCtrlPtr:
+			meta.baseIndex, meta.basePtr = field.Index, true
+			meta.conns = new(sync.Map)
+			if tplBase, _ := val.Elem().FieldByIndex(field.Index).Interface().(*BaseController); tplBase != nil {
+				meta.userField, meta.conns = tplBase.UserIDField, tplBase.connMap()
+			}
+		}
+	}
+
+	var routes []controllerRoute
+	declared := make(map[string]bool)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Type != reflect.TypeOf(Route{}) {
+			continue
+		}
+		target := strings.TrimSpace(field.Tag.Get(kind))
+		name := field.Tag.Get("handler")
+		if name == "" {
+			if target != "" {
+				return nil, nil, fmt.Errorf("%s的路由声明%q缺少handler", structType.Name(), target)
+			}
+			continue
+		}
+		method, ok := ptrType.MethodByName(name)
+		if !ok || val.Method(method.Index).Type() != handlerType {
+			return nil, nil, fmt.Errorf("%s.%s不存在或签名不是func(netContext.Context)", structType.Name(), name)
+		}
+		declared[name] = true
+		if target == "" {
+			continue
+		}
+		route := controllerRoute{target: target, method: method.Index}
+		if kind == routeKindHTTP {
+			verb, path, ok := strings.Cut(target, " ")
+			path = strings.TrimSpace(path)
+			if !ok || path == "" {
+				return nil, nil, fmt.Errorf("%s.%s的HTTP路由格式应为\"METHOD /path\"：%q", structType.Name(), name, target)
+			}
+			route.verb, route.target = strings.ToUpper(verb), path
+		}
+		routes = append(routes, route)
+	}
+
+	// 命名约定（gRPC方法名须包含服务全名，不支持约定注册）
+	prefixer, ok := ctrl.(RoutePrefixer)
+	if !ok || kind == routeKindGRPC {
+		return meta, routes, nil
+	}
+	prefix := prefixer.RoutePrefix()
+	for i := 0; i < ptrType.NumMethod(); i++ {
+		method := ptrType.Method(i)
+		if declared[method.Name] || val.Method(i).Type() != handlerType {
+			continue
+		}
+		// 跳过初始化方法与BaseController的方法
+		if _, promoted := baseCtrlPtr.MethodByName(method.Name); promoted || method.Name == "Init" || method.Name == "WsInit" {
+			continue
+		}
+		route := controllerRoute{method: i}
+		if kind == routeKindHTTP {
+			verb, rest := splitVerb(method.Name)
+			if verb == "" {
+				continue
+			}
+			route.verb, route.target = strings.ToUpper(verb), joinPath(prefix, kebabCase(rest))
+		} else {
+			route.target = lowerFirst(method.Name)
+			if p := strings.ReplaceAll(strings.Trim(prefix, "/"), "/", "."); p != "" {
+				route.target = p + "." + route.target
+			}
+		}
+		routes = append(routes, route)
+	}
+	return meta, routes, nil
+}
+
+// handler 生成按请求创建控制器实例的处理函数
+func (m *controllerMeta) handler(method int, ws bool) func(netContext.Context) {
+	return func(ctx netContext.Context) {
+		inst := m.newInstance()
+		if ws {
+			if c, ok := inst.Interface().(interface{ WsInit(netContext.Context) }); ok {
+				c.WsInit(ctx)
+			}
+		} else if c, ok := inst.Interface().(interface{ Init(netContext.Context) }); ok {
+			c.Init(ctx)
+		}
+		inst.Method(method).Interface().(func(netContext.Context))(ctx)
+	}
+}
+
+// newInstance 浅拷贝模板并替换嵌入的BaseController
+func (m *controllerMeta) newInstance() reflect.Value {
+	inst := reflect.New(m.tpl.Elem().Type())
+	inst.Elem().Set(m.tpl.Elem())
+	if m.baseIndex != nil {
+		ctrl := &BaseController{UserIDField: m.userField, userConns: m.conns}
+		field := inst.Elem().FieldByIndex(m.baseIndex)
+		if m.basePtr {
+			field.Set(reflect.ValueOf(ctrl))
+		} else {
+			field.Set(reflect.ValueOf(ctrl).Elem())
+		}
+	}
+	return inst
+}
+
+// splitVerb 拆分方法名中的HTTP方法前缀（如PostLogin -> Post, Login）
+func splitVerb(name string) (string, string) {
+	for _, verb := range httpVerbs {
+		rest, ok := strings.CutPrefix(name, verb)
+		if ok && (rest == "" || unicode.IsUpper(rune(rest[0]))) {
+			return verb, rest
+		}
+	}
+	return "", ""
+}
+
+// kebabCase 驼峰转短横线（LoginSms -> login-sms）
+func kebabCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// lowerFirst 首字母小写
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// joinPath 拼接路由前缀与路径
+func joinPath(prefix, path string) string {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if path == "" {
+		return prefix
+	}
+	if prefix == "/" {
+		return prefix + path
+	}
+	return prefix + "/" + path
+}
@@ -1,6 +1,26 @@
 package base
 
-
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/apikey"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/db/cursor"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/dfpopp/go-dai/grpc"
+	"github.com/dfpopp/go-dai/http"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/validate"
+	"github.com/dfpopp/go-dai/websocket"
+	"github.com/google/uuid"
+	"slices"
+	"sync"
+	"time"
+)
 
 // BaseController 框架根控制器基类
 type BaseController struct {
@@ -9,6 +29,7 @@ type BaseController struct {
 	cachedConnID string                 // 缓存当前连接ID，避免重复断言
 	UserIDField  string                 // 用户ID在连接属性中的存储键（默认"user_id"）
 	userConnMap  sync.Map               //维护用户ID -> 连接ID列表的映射（无需应用层额外维护）
+	userConns    *sync.Map              // 自动注册的控制器按请求创建实例时共享的用户-连接映射（为nil时使用userConnMap）
 	log          logger.Logger          // 日志实例
 }
 
@@ -19,7 +40,7 @@ func (c *BaseController) Init(ctx netContext.Context) {
 		return
 	}
 	c.Ctx = ctx
-	c.log = logger.GetLogger()
+	c.log = logger.FromContext(ctx.GetContext())
 	// 兼容WS和HTTP的路径/action打印
 	if c.log.GetEnv() != "prod" {
 		if httpCtx, ok := ctx.(*http.Context); ok {
@@ -39,7 +60,7 @@ func (c *BaseController) WsInit(ctx netContext.Context) {
 		return
 	}
 	c.Ctx = ctx
-	c.log = logger.GetLogger()
+	c.log = logger.FromContext(ctx.GetContext())
 	// 新增：初始化连接管理器和用户ID字段
 	c.connManager = websocket.GetGlobalConnManager()
 	if c.UserIDField == "" {
@@ -79,6 +100,14 @@ func (c *BaseController) initCachedConnID() {
 	}
 }
 
+// connMap 用户ID -> 连接ID列表的映射
+func (c *BaseController) connMap() *sync.Map {
+	if c.userConns != nil {
+		return c.userConns
+	}
+	return &c.userConnMap
+}
+
 // GetConnID 获取当前连接ID（应用层直接调用）
 func (c *BaseController) GetConnID() string {
 	if c == nil {
@@ -109,7 +138,13 @@ func (c *BaseController) BindUserID(userID string) error {
 	}
 	// 存储用户ID到连接属性
 	c.connManager.SetConnAttr(connID, c.UserIDField, userID)
-	connIDsObj, exists := c.userConnMap.Load(userID)
+	// 启用集群时同步写入Redis用户索引，供其他节点SendToUser
+	if cluster := c.connManager.Cluster(); cluster != nil {
+		if err := cluster.BindUser(c.Ctx.GetContext(), connID, userID); err != nil {
+			c.log.Error("集群用户索引写入失败", "userID", userID, "connID", connID, "error", err)
+		}
+	}
+	connIDsObj, exists := c.connMap().Load(userID)
 	var connIDs []string
 	if exists {
 		connIDs, _ = connIDsObj.([]string)
@@ -122,10 +157,16 @@ func (c *BaseController) BindUserID(userID string) error {
 		}
 	}
 	connIDs = append(connIDs, connID)
-	c.userConnMap.Store(userID, connIDs)
+	c.connMap().Store(userID, connIDs)
 	if c.log.GetEnv() != "prod" {
 		c.LogInfo("用户ID与连接绑定成功", "userID", userID, "connID", connID)
 	}
+	// 补发到期时用户不在线的定时消息
+	if n, err := c.connManager.DeliverOffline(c.Ctx.GetContext(), userID, connID); err != nil {
+		c.log.Warn("补发离线定时消息失败", "userID", userID, "connID", connID, "error", err)
+	} else if n > 0 && c.log.GetEnv() != "prod" {
+		c.LogInfo("补发离线定时消息成功", "userID", userID, "connID", connID, "count", n)
+	}
 	return nil
 }
 
@@ -145,10 +186,13 @@ func (c *BaseController) UnbindUserID(userID string) error {
 	// 1. 移除连接属性中的用户ID
 	if c.connManager != nil {
 		c.connManager.SetConnAttr(connID, c.UserIDField, "")
+		if cluster := c.connManager.Cluster(); cluster != nil {
+			_ = cluster.UnbindUser(c.Ctx.GetContext(), connID, userID)
+		}
 	}
 
 	// 2. 维护用户-连接映射，移除当前连接
-	connIDsObj, exists := c.userConnMap.Load(userID)
+	connIDsObj, exists := c.connMap().Load(userID)
 	if !exists {
 		return nil
 	}
@@ -163,9 +207,9 @@ func (c *BaseController) UnbindUserID(userID string) error {
 		}
 	}
 	if len(newConnIDs) == 0 {
-		c.userConnMap.Delete(userID)
+		c.connMap().Delete(userID)
 	} else {
-		c.userConnMap.Store(userID, newConnIDs)
+		c.connMap().Store(userID, newConnIDs)
 	}
 	if c.log.GetEnv() != "prod" {
 		c.LogInfo("用户ID与连接解绑成功", "userID", userID, "connID", connID)
@@ -183,7 +227,7 @@ func (c *BaseController) GetUserConnIDs(userID string) ([]string, error) {
 	}
 
 	// 1. 从用户-连接映射中获取连接列表
-	connIDsObj, exists := c.userConnMap.Load(userID)
+	connIDsObj, exists := c.connMap().Load(userID)
 	if !exists {
 		return []string{}, nil
 	}
@@ -207,9 +251,24 @@ func (c *BaseController) GetUserConnIDs(userID string) ([]string, error) {
 	// 3. 更新有效连接映射（避免无效数据堆积）
 	if len(validConnIDs) != len(connIDs) {
 		if len(validConnIDs) == 0 {
-			c.userConnMap.Delete(userID)
+			c.connMap().Delete(userID)
 		} else {
-			c.userConnMap.Store(userID, validConnIDs)
+			c.connMap().Store(userID, validConnIDs)
+		}
+	}
+
+	// 4. 启用集群时合并其他节点上的连接
+	if c.connManager != nil {
+		if cluster := c.connManager.Cluster(); cluster != nil {
+			remoteConnIDs, err := cluster.UserConnIDs(c.Ctx.GetContext(), userID)
+			if err != nil {
+				c.log.Warn("查询集群用户连接失败，仅返回本节点连接", "userID", userID, "error", err)
+			}
+			for _, connID := range remoteConnIDs {
+				if !slices.Contains(validConnIDs, connID) {
+					validConnIDs = append(validConnIDs, connID)
+				}
+			}
 		}
 	}
 	if c.log.GetEnv() != "prod" {
@@ -327,6 +386,60 @@ func (c *BaseController) SendToUser(targetUserID string, msgAction string, msgDa
 	return nil
 }
 
+// SendToUserWithAck 以需确认模式给指定用户发送关键消息（支付结果、安全告警等）：用户任一连接回复确认帧即视为送达，
+// 超时未确认时按opts重发（每次重发前重新获取用户在线连接），投递结果通过返回的Delivery或opts.OnResult获取
+func (c *BaseController) SendToUserWithAck(targetUserID string, msgAction string, msgData interface{}, opts websocket.AckOptions) (*websocket.Delivery, error) {
+	if c == nil {
+		return nil, errors.New("BaseController 未初始化（指针为nil），无法发送消息")
+	}
+	if targetUserID == "" || msgAction == "" {
+		return nil, errors.New("目标用户ID和消息动作不能为空")
+	}
+	if c.connManager == nil {
+		return nil, errors.New("连接管理器未初始化，无法发送消息")
+	}
+	delivery := c.connManager.SendWithAck(func() ([]string, error) {
+		return c.GetUserConnIDs(targetUserID)
+	}, msgAction, msgData, opts)
+	if c.log.GetEnv() != "prod" {
+		c.LogInfo("给指定用户发送需确认消息", "targetUserID", targetUserID, "msgAction", msgAction, "msgID", delivery.MsgID)
+	}
+	return delivery, nil
+}
+
+// SendToUserAt 在deliverAt给指定用户发送消息（需配置websocket.delay.enable）：到期时发送到用户当时的在线连接，
+// 用户不在线时进入离线队列，用户再次BindUserID时补发；返回消息ID（即消息的request_id），可用于CancelScheduled
+func (c *BaseController) SendToUserAt(targetUserID string, msgAction string, msgData interface{}, deliverAt time.Time) (string, error) {
+	if c == nil {
+		return "", errors.New("BaseController 未初始化（指针为nil），无法发送消息")
+	}
+	if targetUserID == "" || msgAction == "" {
+		return "", errors.New("目标用户ID和消息动作不能为空")
+	}
+	msgID, err := websocket.GetGlobalConnManager().SendToUserAt(c.Ctx.GetContext(), targetUserID, msgAction, msgData, deliverAt)
+	if err != nil {
+		c.log.Error("定时消息写入失败", "targetUserID", targetUserID, "msgAction", msgAction, "error", err)
+		return "", err
+	}
+	if c.log.GetEnv() != "prod" {
+		c.LogInfo("定时消息写入成功", "targetUserID", targetUserID, "msgAction", msgAction, "msgID", msgID, "deliverAt", deliverAt)
+	}
+	return msgID, nil
+}
+
+// SendToUserAfter 在delay之后给指定用户发送消息（见SendToUserAt）
+func (c *BaseController) SendToUserAfter(targetUserID string, msgAction string, msgData interface{}, delay time.Duration) (string, error) {
+	return c.SendToUserAt(targetUserID, msgAction, msgData, time.Now().Add(delay))
+}
+
+// CancelScheduled 取消尚未投递的定时消息（已投递或不存在时返回false）
+func (c *BaseController) CancelScheduled(msgID string) (bool, error) {
+	if c == nil {
+		return false, errors.New("BaseController 未初始化（指针为nil），无法取消消息")
+	}
+	return websocket.GetGlobalConnManager().CancelScheduled(c.Ctx.GetContext(), msgID)
+}
+
 // SendToUsers 给指定多个用户批量发送消息（自动获取每个用户的在线连接，应用层直接调用）
 func (c *BaseController) SendToUsers(targetUserIDs []string, msgAction string, msgData interface{}) error {
 	if c == nil {
@@ -372,6 +485,74 @@ func (c *BaseController) SendToUsers(targetUserIDs []string, msgAction string, m
 	return nil
 }
 
+// JoinRoom 当前连接加入房间（断开时自动离开，应用层直接调用）
+func (c *BaseController) JoinRoom(room string) error {
+	if c == nil {
+		return errors.New("BaseController 未初始化（指针为nil），无法加入房间")
+	}
+	connID := c.GetConnID()
+	if connID == "" {
+		return errors.New("非WebSocket连接，无法加入房间")
+	}
+	if c.connManager == nil {
+		return errors.New("连接管理器未初始化，无法加入房间")
+	}
+	return c.connManager.JoinRoom(connID, room)
+}
+
+// LeaveRoom 当前连接离开房间（应用层直接调用）
+func (c *BaseController) LeaveRoom(room string) error {
+	if c == nil {
+		return errors.New("BaseController 未初始化（指针为nil），无法离开房间")
+	}
+	connID := c.GetConnID()
+	if connID == "" {
+		return errors.New("非WebSocket连接，无法离开房间")
+	}
+	if c.connManager == nil {
+		return errors.New("连接管理器未初始化，无法离开房间")
+	}
+	c.connManager.LeaveRoom(connID, room)
+	return nil
+}
+
+// SendToRoom 给房间内的连接群发消息（excludeSelf为true时不发给当前连接，应用层直接调用）
+func (c *BaseController) SendToRoom(room string, msgAction string, msgData interface{}, excludeSelf bool) error {
+	if c == nil {
+		return errors.New("BaseController 未初始化（指针为nil），无法发送消息")
+	}
+	if room == "" || msgAction == "" {
+		return errors.New("房间名和消息动作不能为空")
+	}
+	if c.connManager == nil {
+		return errors.New("连接管理器未初始化，无法发送消息")
+	}
+	msgStr := c.buildStandardMsg(msgAction, msgData)
+	if msgStr == "" {
+		return errors.New("消息组装失败，无法发送")
+	}
+	var exclude []string
+	if excludeSelf {
+		exclude = append(exclude, c.GetConnID())
+	}
+	sent := c.connManager.BroadcastToRoom(room, msgStr, exclude...)
+	if c.log.GetEnv() != "prod" {
+		c.LogInfo("给房间群发消息成功", "room", room, "connCount", sent, "msgAction", msgAction)
+	}
+	return nil
+}
+
+// RoomHistory 查询房间beforeSeq之前的最近limit条历史消息（按序号升序，beforeSeq为0时为最近的消息；需启用websocket.history）
+func (c *BaseController) RoomHistory(room string, limit int, beforeSeq int64) ([]websocket.HistoryMessage, error) {
+	if c == nil {
+		return nil, errors.New("BaseController 未初始化（指针为nil），无法查询房间历史")
+	}
+	if c.connManager == nil {
+		return nil, errors.New("连接管理器未初始化，无法查询房间历史")
+	}
+	return c.connManager.History(c.Ctx.GetContext(), room, limit, beforeSeq)
+}
+
 // Success 统一成功响应（JSON格式）
 func (c *BaseController) Success(data interface{}, msg ...string) {
 	if c == nil {
@@ -411,7 +592,33 @@ func (c *BaseController) DataSuccess(data interface{}, count int64) {
 	})
 }
 
-// Error 统一失败响应（JSON格式）
+// CursorSuccess 游标分页成功响应（data为nil时使用page.Data；next_cursor/prev_cursor为空表示没有下一页/上一页）
+func (c *BaseController) CursorSuccess(data interface{}, page *cursor.Page) {
+	if c == nil {
+		c.LogError("BaseController 未初始化（指针为nil），无法执行CursorSuccess响应")
+		return
+	}
+	if c.Ctx == nil {
+		c.LogError("调用框架BaseController.CursorSuccess 之前未设置上下文")
+		return
+	}
+	if page == nil {
+		page = &cursor.Page{}
+	}
+	if data == nil {
+		data = page.Data
+	}
+	c.Ctx.JSON(200, map[string]interface{}{
+		"code":        200,
+		"msg":         "操作成功",
+		"data":        data,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+		"has_more":    page.HasMore,
+	})
+}
+
+// Error 统一失败响应（JSON格式，HTTP状态码固定为200；msg为空时使用errs登记的错误码消息）
 func (c *BaseController) Error(code int, msg string) {
 	if c == nil {
 		c.LogError("BaseController 未初始化（指针为nil），无法执行Error响应")
@@ -421,6 +628,11 @@ func (c *BaseController) Error(code int, msg string) {
 		c.LogError("调用框架BaseController.Error 之前未设置上下文")
 		return
 	}
+	if msg == "" {
+		if e, ok := errs.Lookup(code); ok {
+			msg = e.Message
+		}
+	}
 	c.Ctx.JSON(200, map[string]interface{}{
 		"code": code,
 		"msg":  msg,
@@ -432,6 +644,49 @@ func (c *BaseController) Error(code int, msg string) {
 	}
 }
 
+// Fail 按框架统一错误响应：err经errs.From转换，HTTP按登记的状态码返回{"code","msg","data"}，
+// gRPC按登记的状态码返回，WS推送同样的响应体；服务端错误（5xx）记录错误日志（含原因），其余在非生产环境记录警告。
+// 参数校验错误（validate.Errors）与参数绑定错误按请求语言生成提示，逐字段提示放在data.errors中
+//
+//	if err := userService.Create(ctx, req); err != nil {
+//		c.Fail(err)
+//		return
+//	}
+func (c *BaseController) Fail(err error) {
+	if c == nil {
+		c.LogError("BaseController 未初始化（指针为nil），无法执行Fail响应")
+		return
+	}
+	if c.Ctx == nil {
+		c.LogError("调用框架BaseController.Fail 之前未设置上下文")
+		return
+	}
+	var invalid validate.Errors
+	if !errors.As(err, &invalid) {
+		invalid = validate.FromBindError(err)
+	}
+	if len(invalid) > 0 {
+		invalid.Localize(c.locale())
+		body := invalid.Body()
+		c.Ctx.JSON(errs.InvalidArgument.HTTPStatus, body)
+		if c.log.GetEnv() != "prod" {
+			c.LogWarn("接口响应失败：", "code=", body["code"], "msg=", body["msg"], "path=", c.Ctx.GetRequestInfo().GetPath(), "cause=", err)
+		}
+		return
+	}
+	e := errs.From(err)
+	if e == nil {
+		e = errs.Internal
+	}
+	c.Ctx.JSON(e.HTTPStatus, e.Body())
+	path := c.Ctx.GetRequestInfo().GetPath()
+	if e.IsServerError() {
+		c.LogError("接口响应失败：", "code=", e.Code, "msg=", e.Message, "path=", path, "cause=", err)
+	} else if c.log.GetEnv() != "prod" {
+		c.LogWarn("接口响应失败：", "code=", e.Code, "msg=", e.Message, "path=", path, "cause=", err)
+	}
+}
+
 // RespText 统一响应（字符串格式）
 func (c *BaseController) RespText(msg string) {
 	if c == nil {
@@ -513,6 +768,69 @@ func (c *BaseController) BindJSON(v interface{}) error {
 	return c.Ctx.BindJSON(v)
 }
 
+// Bind 按query/form/json标签合并绑定查询参数、表单与JSON请求体到结构体（类型转换规则见netContext.Bind）
+func (c *BaseController) Bind(v interface{}) error {
+	if c == nil {
+		return errors.New("BaseController 未初始化（指针为nil），无法绑定参数")
+	}
+	if c.Ctx == nil {
+		return errors.New("调用框架BaseController.Bind 之前未设置上下文")
+	}
+	return c.Ctx.Bind(v)
+}
+
+// Validate 按validate标签校验请求参数，提示按当前请求语言生成（未通过时返回validate.Errors，可直接交给Fail响应）
+//
+//	if err := c.Bind(&req); err != nil {
+//		c.Fail(err)
+//		return
+//	}
+//	if err := c.Validate(&req); err != nil {
+//		c.Fail(err) // {"code":400,"msg":"手机号不能为空","data":{"errors":[{"field":"mobile","rule":"required","message":"手机号不能为空"}]}}
+//		return
+//	}
+func (c *BaseController) Validate(v interface{}) error {
+	if c == nil {
+		return errors.New("BaseController 未初始化（指针为nil），无法校验参数")
+	}
+	return validate.StructLocale(c.locale(), v)
+}
+
+// locale 当前请求的语言（HTTP/WS/gRPC按请求协商，其他协议取context中的语言）
+func (c *BaseController) locale() string {
+	if c.Ctx == nil {
+		return i18n.DefaultLocale()
+	}
+	if l, ok := c.Ctx.(interface{ Locale() string }); ok {
+		return l.Locale()
+	}
+	return i18n.FromContext(c.Ctx.GetContext())
+}
+
+// GetContext 获取请求级context（携带链路追踪信息，传给Service/Model的DB操作）
+func (c *BaseController) GetContext() context.Context {
+	if c == nil || c.Ctx == nil {
+		return context.Background()
+	}
+	return c.Ctx.GetContext()
+}
+
+// RequestCache 请求级缓存（请求结束后自动丢弃；泛型读取使用netContext.GetOrCompute(c.GetContext(), key, fn)），
+// 在后台任务等未挂载缓存的context中为nil
+func (c *BaseController) RequestCache() *netContext.RequestCache {
+	return netContext.RequestCacheFrom(c.GetContext())
+}
+
+// ClientIdentity 已校验的客户端证书身份（服务器启用mtls且客户端提供了证书时有效，否则为nil）
+func (c *BaseController) ClientIdentity() *auth.ClientIdentity {
+	return auth.ClientIdentityFromContext(c.GetContext())
+}
+
+// APIKey 已校验的API Key（经http.APIKeyAuth认证时有效，否则为nil）
+func (c *BaseController) APIKey() *apikey.Key {
+	return apikey.FromContext(c.GetContext())
+}
+
 // LogInfo 记录服务层信息日志
 func (c *BaseController) LogInfo(content ...interface{}) {
 	c.log.Info(content...)
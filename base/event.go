@@ -0,0 +1,194 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/websocket"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// 进程内事件总线：模块间按事件类型（Go类型）发布/订阅，发布方无需依赖订阅方。
+//
+//	type UserRegistered struct{ UserID int64 }
+//	base.Subscribe(func(ctx context.Context, e UserRegistered) error { return sendWelcomeMail(ctx, e.UserID) }, base.Async())
+//	_ = base.Publish(ctx, UserRegistered{UserID: 1})
+//
+// 同步订阅者在发布方协程内按订阅顺序执行，错误合并后返回给发布方；
+// 异步订阅者在独立协程中执行，错误仅记录日志。订阅者panic会被捕获，不影响发布方及其他订阅者。
+
+// EventHandler 事件处理函数
+type EventHandler[T any] func(ctx context.Context, event T) error
+
+// EventBus 事件总线
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   map[reflect.Type][]*subscription
+	nextID atomic.Uint64
+}
+
+// subscription 订阅记录
+type subscription struct {
+	id      uint64
+	name    string
+	async   bool
+	handler func(ctx context.Context, event interface{}) error
+}
+
+// Subscription 订阅句柄（用于取消订阅）
+type Subscription struct {
+	bus *EventBus
+	typ reflect.Type
+	id  uint64
+}
+
+// SubscribeOption 订阅选项
+type SubscribeOption func(*subscription)
+
+// Async 异步投递（独立协程执行，不阻塞发布方）
+func Async() SubscribeOption {
+	return func(s *subscription) {
+		s.async = true
+	}
+}
+
+// WithSubscriberName 指定订阅者名称（用于日志定位）
+func WithSubscriberName(name string) SubscribeOption {
+	return func(s *subscription) {
+		s.name = name
+	}
+}
+
+var defaultEventBus = NewEventBus()
+
+// NewEventBus 创建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[reflect.Type][]*subscription)}
+}
+
+// DefaultEventBus 获取全局事件总线
+func DefaultEventBus() *EventBus {
+	return defaultEventBus
+}
+
+// Subscribe 在全局事件总线上订阅类型为T的事件
+func Subscribe[T any](handler EventHandler[T], opts ...SubscribeOption) *Subscription {
+	return SubscribeOn(defaultEventBus, handler, opts...)
+}
+
+// Publish 在全局事件总线上发布事件
+func Publish[T any](ctx context.Context, event T) error {
+	return PublishOn(defaultEventBus, ctx, event)
+}
+
+// SubscribeOn 在指定事件总线上订阅类型为T的事件
+func SubscribeOn[T any](bus *EventBus, handler EventHandler[T], opts ...SubscribeOption) *Subscription {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	sub := &subscription{
+		id:   bus.nextID.Add(1),
+		name: typ.String(),
+		handler: func(ctx context.Context, event interface{}) error {
+			return handler(ctx, event.(T))
+		},
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	// 写时复制，发布时无需持锁遍历
+	subs := make([]*subscription, 0, len(bus.subs[typ])+1)
+	subs = append(subs, bus.subs[typ]...)
+	bus.subs[typ] = append(subs, sub)
+	return &Subscription{bus: bus, typ: typ, id: sub.id}
+}
+
+// PublishOn 在指定事件总线上发布事件，返回同步订阅者的错误（多个错误合并）
+func PublishOn[T any](bus *EventBus, ctx context.Context, event T) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	bus.mu.RLock()
+	subs := bus.subs[typ]
+	bus.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if sub.async {
+			// 脱离发布方的取消信号，保留请求ID等上下文值
+			go func(sub *subscription) {
+				if err := sub.deliver(context.WithoutCancel(ctx), event); err != nil {
+					logger.FromContext(ctx).Error("异步事件处理失败：", sub.name, " Err：", err)
+				}
+			}(sub)
+			continue
+		}
+		if err := sub.deliver(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HasSubscribers 判断类型为T的事件是否存在订阅者
+func HasSubscribers[T any](bus *EventBus) bool {
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	return len(bus.subs[reflect.TypeOf((*T)(nil)).Elem()]) > 0
+}
+
+// deliver 执行订阅者（捕获panic并转换为错误）
+func (s *subscription) deliver(ctx context.Context, event interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(ctx).Error("事件订阅者panic：", s.name, " ", r, "\n", string(debug.Stack()))
+			err = fmt.Errorf("事件订阅者[%s] panic: %v", s.name, r)
+		}
+	}()
+	return s.handler(ctx, event)
+}
+
+// Unsubscribe 取消订阅（重复调用无副作用）
+func (s *Subscription) Unsubscribe() {
+	if s == nil || s.bus == nil {
+		return
+	}
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	old := s.bus.subs[s.typ]
+	subs := make([]*subscription, 0, len(old))
+	for _, sub := range old {
+		if sub.id != s.id {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		delete(s.bus.subs, s.typ)
+		return
+	}
+	s.bus.subs[s.typ] = subs
+}
+
+// connEventBridge 将WS连接事件转发到全局事件总线
+type connEventBridge struct{}
+
+func (connEventBridge) OnConnEvent(event websocket.ConnEvent) {
+	if err := Publish(context.Background(), event); err != nil {
+		logger.Warn("WS连接事件处理失败：", event.EventType, " Err：", err)
+	}
+}
+
+var bridgeOnce sync.Once
+
+// BridgeConnEvents 将WS连接上下线及房间事件（websocket.ConnEvent）转发到全局事件总线，
+// 之后即可通过 base.Subscribe(func(ctx context.Context, e websocket.ConnEvent) error {...}) 订阅（框架启动WS服务时自动调用）
+func BridgeConnEvents() {
+	bridgeOnce.Do(func() {
+		websocket.GetGlobalConnManager().GetEventBus().Subscribe("base.eventbus", connEventBridge{})
+	})
+}
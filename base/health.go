@@ -0,0 +1,221 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db"
+	"github.com/dfpopp/go-dai/http"
+	nethttp "net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 健康自检：应用模块实现Checkable并通过RegisterCheck注册（如“支付网关可达”），
+// 就绪端点并发执行内置的数据库检查与注册的自检项，返回带每项耗时与状态的结构化报告。
+//
+//	base.RegisterCheck(base.CheckFunc("payment-gateway", func(ctx context.Context) error {
+//		return payment.Ping(ctx)
+//	}))
+
+// Checkable 健康自检项
+type Checkable interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// 检查状态
+const (
+	CheckStatusUp   = "up"
+	CheckStatusDown = "down"
+)
+
+const defaultHealthTimeout = 5 * time.Second
+
+// CheckResult 单项检查结果
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency int64  `json:"latency_ms"` // 耗时（毫秒）
+	Error   string `json:"error,omitempty"`
+}
+
+// HealthReport 健康检查报告（任一检查项失败时整体状态为down）
+type HealthReport struct {
+	Status   string        `json:"status"`
+	Time     int64         `json:"time"`        // 检查时间（Unix秒）
+	Duration int64         `json:"duration_ms"` // 总耗时（毫秒）
+	Checks   []CheckResult `json:"checks"`
+}
+
+// Up 是否全部检查通过
+func (r HealthReport) Up() bool {
+	return r.Status == CheckStatusUp
+}
+
+var (
+	checksMu sync.RWMutex
+	checks   []Checkable
+)
+
+// RegisterCheck 注册健康自检项（同名检查项后注册的覆盖先注册的）
+func RegisterCheck(items ...Checkable) {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		replaced := false
+		for i, c := range checks {
+			if c.Name() == item.Name() {
+				checks[i], replaced = item, true
+				break
+			}
+		}
+		if !replaced {
+			checks = append(checks, item)
+		}
+	}
+}
+
+// UnregisterCheck 注销健康自检项
+func UnregisterCheck(name string) {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	for i, c := range checks {
+		if c.Name() == name {
+			checks = append(checks[:i], checks[i+1:]...)
+			return
+		}
+	}
+}
+
+// funcCheck 函数形式的自检项
+type funcCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c funcCheck) Name() string                    { return c.name }
+func (c funcCheck) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// CheckFunc 将函数包装为自检项
+func CheckFunc(name string, fn func(ctx context.Context) error) Checkable {
+	return funcCheck{name: name, fn: fn}
+}
+
+// CheckHealth 并发执行内置数据库检查（预热状态与各连接的Ping，名称为"db.类型.连接key"）与注册的自检项，
+// ctx到期时未完成的检查项记为失败
+func CheckHealth(ctx context.Context) HealthReport {
+	start := time.Now()
+	items := builtinChecks()
+	checksMu.RLock()
+	items = append(items, checks...)
+	checksMu.RUnlock()
+
+	results := make([]CheckResult, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item Checkable) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, item)
+		}(i, item)
+	}
+	wg.Wait()
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	report := HealthReport{
+		Status:   CheckStatusUp,
+		Time:     start.Unix(),
+		Duration: time.Since(start).Milliseconds(),
+		Checks:   results,
+	}
+	for _, result := range results {
+		if result.Status != CheckStatusUp {
+			report.Status = CheckStatusDown
+			break
+		}
+	}
+	return report
+}
+
+// runCheck 执行单个检查项（panic与ctx到期均记为失败，到期后不再等待检查函数返回）
+func runCheck(ctx context.Context, item Checkable) CheckResult {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- item.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := CheckResult{Name: item.Name(), Status: CheckStatusUp, Latency: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = CheckStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// builtinChecks 内置的数据库检查项
+func builtinChecks() []Checkable {
+	items := []Checkable{CheckFunc("db.warmup", func(ctx context.Context) error {
+		if !db.Ready() {
+			return errors.New("database connection pools are warming up")
+		}
+		return nil
+	})}
+	for name, ping := range db.Pingers() {
+		items = append(items, CheckFunc("db."+name, ping))
+	}
+	return items
+}
+
+// RegisterHealthRoutes 按应用配置在HTTP服务上注册存活与就绪端点（未启用时不注册）：
+// 存活端点固定返回200；就绪端点返回HealthReport，全部通过时200，否则503
+func RegisterHealthRoutes(srv *http.Server, appName string) {
+	cfg := config.GetAppConfig(appName).Health
+	if !cfg.Enable {
+		return
+	}
+	livePath, readyPath := cfg.LivePath, cfg.ReadyPath
+	if livePath == "" {
+		livePath = "/healthz"
+	}
+	if readyPath == "" {
+		readyPath = "/readyz"
+	}
+	timeout := defaultHealthTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	srv.GET(livePath, func(c *http.Context) {
+		c.JSON(nethttp.StatusOK, map[string]interface{}{"status": CheckStatusUp})
+	})
+	srv.GET(readyPath, func(c *http.Context) {
+		ctx, cancel := context.WithTimeout(c.GetContext(), timeout)
+		defer cancel()
+		report := CheckHealth(ctx)
+		code := nethttp.StatusOK
+		if !report.Up() {
+			code = nethttp.StatusServiceUnavailable
+		}
+		c.Writer.Header().Set("Content-Type", "application/json;charset=utf-8")
+		c.Writer.Header().Set("Cache-Control", "no-store")
+		c.Writer.WriteHeader(code)
+		_ = json.NewEncoder(c.Writer).Encode(report)
+	})
+}
@@ -3,6 +3,7 @@ package base
 import (
 	"github.com/dfpopp/go-dai/grpc"
 	"github.com/dfpopp/go-dai/http"
+	"github.com/dfpopp/go-dai/mqtt"
 	"github.com/dfpopp/go-dai/websocket"
 )
 
@@ -13,6 +14,11 @@ type BaseRouter interface {
 	RegisterGRPCRoutes(server *grpc.Server)    // 注册gRPC路由
 }
 
+// MQTTRouter MQTT路由注册（可选接口：启用mqtt服务时，应用路由实现该接口即可注册主题路由）
+type MQTTRouter interface {
+	RegisterMQTTRoutes(server *mqtt.Server) // 注册MQTT主题路由
+}
+
 // DefaultBaseRouter 默认路由实现（应用层可嵌入复用）
 type DefaultBaseRouter struct{}
 
@@ -24,3 +30,6 @@ func (r *DefaultBaseRouter) RegisterWSRoutes(server *websocket.Server) {}
 
 // RegisterGRPCRoutes 默认gRPC路由注册（空实现，应用层重写）
 func (r *DefaultBaseRouter) RegisterGRPCRoutes(server *grpc.Server) {}
+
+// RegisterMQTTRoutes 默认MQTT路由注册（空实现，应用层重写）
+func (r *DefaultBaseRouter) RegisterMQTTRoutes(server *mqtt.Server) {}
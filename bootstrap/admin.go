@@ -0,0 +1,357 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/go-redis/redis"
+	"net"
+	nethttp "net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 运行时管理接口：在独立地址上在线调整日志级别（全局/按模块）、功能开关、维护/只读模式。
+// 覆盖项保存在Redis哈希中（配置admin.redis_db），修改后经Pub/Sub通知所有实例重新加载，重启后自动恢复；
+// 访问控制与诊断端口一致（IP白名单+令牌，令牌通过请求头X-Admin-Token传递）。
+//
+//	curl -X PUT -H "X-Admin-Token: xxx" -d '{"module":"db/mysql","level":"debug"}' http://127.0.0.1:6061/admin/log-level
+//	curl -X PUT -H "X-Admin-Token: xxx" -d '{"enabled":true}' http://127.0.0.1:6061/admin/features/new_checkout
+//	curl -X PUT -H "X-Admin-Token: xxx" -d '{"enabled":true}' http://127.0.0.1:6061/admin/maintenance
+
+const (
+	defaultAdminAddr   = "127.0.0.1:6061"
+	defaultAdminPrefix = "admin:"
+	adminReloadPeriod  = 30 * time.Second // 定期全量加载，弥补Pub/Sub断线期间丢失的通知
+
+	adminFieldLog         = "log:"
+	adminFieldFeature     = "feature:"
+	adminFieldMaintenance = "maintenance"
+	adminFieldReadOnly    = "read_only"
+)
+
+// Admin 运行时管理接口
+type Admin struct {
+	cfg    config.AdminConfig
+	rdb    *redisDb.RedisDb // 为空时覆盖项仅保存在当前进程
+	pubsub *redis.PubSub
+	srv    *nethttp.Server
+	mu     sync.Mutex // 串行化未配置Redis时的读改写
+	stop   chan struct{}
+	done   sync.WaitGroup
+	once   sync.Once
+}
+
+// StartAdmin 按应用配置启动运行时管理接口（未启用时返回nil）：先从Redis恢复覆盖项，再订阅变更通知并开始监听
+func StartAdmin(appName string) (*Admin, error) {
+	cfg := config.GetAppConfig(appName).Admin
+	if !cfg.Enable {
+		return nil, nil
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = defaultAdminAddr
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultAdminPrefix
+	}
+	a := &Admin{cfg: cfg, stop: make(chan struct{})}
+	if cfg.RedisDb != "" {
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return nil, err
+		}
+		a.rdb = rdb
+		if err := a.Reload(); err != nil {
+			return nil, err
+		}
+		a.pubsub = rdb.Db.Subscribe(a.key("changed"))
+		if _, err := a.pubsub.Receive(); err != nil {
+			_ = a.pubsub.Close()
+			return nil, errors.New("管理接口变更通知订阅失败：" + err.Error())
+		}
+		a.done.Add(1)
+		go a.receiveLoop()
+	} else {
+		logger.Warn("管理接口未配置redis_db，运行时覆盖项仅对当前实例生效且重启后丢失")
+	}
+	handler, err := a.Handler()
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	lis, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	a.srv = &nethttp.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := a.srv.Serve(lis); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			logger.Error("管理接口异常退出：", err)
+		}
+	}()
+	logger.Info("管理接口已启动，监听地址：", lis.Addr().String())
+	return a, nil
+}
+
+// Close 停止监听与变更订阅（已生效的覆盖项保持不变，可重复调用）
+func (a *Admin) Close() {
+	a.once.Do(func() {
+		close(a.stop)
+		if a.srv != nil {
+			_ = a.srv.Close()
+		}
+		if a.pubsub != nil {
+			_ = a.pubsub.Close()
+		}
+		a.done.Wait()
+	})
+}
+
+// Reload 从Redis全量加载覆盖项并生效
+func (a *Admin) Reload() error {
+	if a.rdb == nil {
+		return nil
+	}
+	fields, err := a.rdb.Db.HGetAll(a.key("overrides")).Result()
+	if err != nil {
+		return errors.New("加载运行时覆盖项失败：" + err.Error())
+	}
+	val := config.RuntimeOverrides{LogLevels: map[string]string{}, Features: map[string]bool{}}
+	for field, raw := range fields {
+		switch {
+		case strings.HasPrefix(field, adminFieldLog):
+			val.LogLevels[strings.TrimPrefix(field, adminFieldLog)] = raw
+		case strings.HasPrefix(field, adminFieldFeature):
+			val.Features[strings.TrimPrefix(field, adminFieldFeature)] = raw == "1"
+		case field == adminFieldMaintenance:
+			val.Maintenance = raw == "1"
+		case field == adminFieldReadOnly:
+			val.ReadOnly = raw == "1"
+		}
+	}
+	config.SetRuntime(val)
+	return nil
+}
+
+// SetLogLevel 覆盖日志级别（module为空或*时覆盖全局级别）
+func (a *Admin) SetLogLevel(module, level string) error {
+	module = normalizeLogModule(module)
+	level = strings.ToLower(strings.TrimSpace(level))
+	if logger.ParseLevel(level).String() != level && level != "warning" {
+		return errors.New("无效的日志级别：" + level)
+	}
+	return a.update(adminFieldLog+module, level, func(val *config.RuntimeOverrides) {
+		val.LogLevels[module] = level
+	})
+}
+
+// ResetLogLevel 撤销日志级别覆盖（全局级别恢复为配置文件中的级别）
+func (a *Admin) ResetLogLevel(module string) error {
+	module = normalizeLogModule(module)
+	return a.update(adminFieldLog+module, "", func(val *config.RuntimeOverrides) {
+		delete(val.LogLevels, module)
+	})
+}
+
+// SetFeature 覆盖功能开关
+func (a *Admin) SetFeature(name string, enabled bool) error {
+	if name == "" {
+		return errors.New("功能开关名称不能为空")
+	}
+	return a.update(adminFieldFeature+name, flagValue(enabled), func(val *config.RuntimeOverrides) {
+		val.Features[name] = enabled
+	})
+}
+
+// ResetFeature 撤销功能开关覆盖（恢复为应用配置features中的值）
+func (a *Admin) ResetFeature(name string) error {
+	return a.update(adminFieldFeature+name, "", func(val *config.RuntimeOverrides) {
+		delete(val.Features, name)
+	})
+}
+
+// SetMaintenance 切换维护模式
+func (a *Admin) SetMaintenance(enabled bool) error {
+	return a.update(adminFieldMaintenance, flagValue(enabled), func(val *config.RuntimeOverrides) {
+		val.Maintenance = enabled
+	})
+}
+
+// SetReadOnly 切换只读模式
+func (a *Admin) SetReadOnly(enabled bool) error {
+	return a.update(adminFieldReadOnly, flagValue(enabled), func(val *config.RuntimeOverrides) {
+		val.ReadOnly = enabled
+	})
+}
+
+// update 写入单个覆盖项（value为空表示删除）：配置了Redis时按字段写入哈希并通知所有实例，否则直接修改当前进程
+func (a *Admin) update(field, value string, apply func(val *config.RuntimeOverrides)) error {
+	if a.rdb == nil {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		val := config.GetRuntime()
+		apply(&val)
+		config.SetRuntime(val)
+		return nil
+	}
+	var err error
+	if value == "" {
+		err = a.rdb.Db.HDel(a.key("overrides"), field).Err()
+	} else {
+		err = a.rdb.Db.HSet(a.key("overrides"), field, value).Err()
+	}
+	if err != nil {
+		return err
+	}
+	if err := a.Reload(); err != nil {
+		return err
+	}
+	if err := a.rdb.Db.Publish(a.key("changed"), field).Err(); err != nil {
+		logger.Warn("管理接口变更通知发布失败（其他实例将在定期加载时同步）：", err)
+	}
+	return nil
+}
+
+// receiveLoop 收到变更通知或定期全量加载覆盖项
+func (a *Admin) receiveLoop() {
+	defer a.done.Done()
+	ticker := time.NewTicker(adminReloadPeriod)
+	defer ticker.Stop()
+	ch := a.pubsub.Channel()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+		}
+		if err := a.Reload(); err != nil {
+			logger.Error(err)
+		}
+	}
+}
+
+// Handler 管理接口处理器（可挂载到自定义的管理端口）
+func (a *Admin) Handler() (nethttp.Handler, error) {
+	allow, err := parseAllowIPs(a.cfg.AllowIPs)
+	if err != nil {
+		return nil, err
+	}
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("GET /admin/runtime", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		adminRespond(w, nil)
+	})
+	mux.HandleFunc("PUT /admin/log-level", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		var body struct {
+			Module string `json:"module"`
+			Level  string `json:"level"`
+		}
+		if adminDecode(w, r, &body) {
+			adminRespond(w, a.audit(r, "log-level", body.Module, body.Level, a.SetLogLevel(body.Module, body.Level)))
+		}
+	})
+	mux.HandleFunc("DELETE /admin/log-level", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		module := r.URL.Query().Get("module")
+		adminRespond(w, a.audit(r, "log-level", module, "reset", a.ResetLogLevel(module)))
+	})
+	mux.HandleFunc("PUT /admin/features/{name}", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		var body struct {
+			Enabled bool `json:"enabled"`
+		}
+		if adminDecode(w, r, &body) {
+			name := r.PathValue("name")
+			adminRespond(w, a.audit(r, "feature", name, flagValue(body.Enabled), a.SetFeature(name, body.Enabled)))
+		}
+	})
+	mux.HandleFunc("DELETE /admin/features/{name}", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		name := r.PathValue("name")
+		adminRespond(w, a.audit(r, "feature", name, "reset", a.ResetFeature(name)))
+	})
+	mux.HandleFunc("PUT /admin/maintenance", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		var body struct {
+			Enabled bool `json:"enabled"`
+		}
+		if adminDecode(w, r, &body) {
+			adminRespond(w, a.audit(r, "maintenance", "", flagValue(body.Enabled), a.SetMaintenance(body.Enabled)))
+		}
+	})
+	mux.HandleFunc("PUT /admin/read-only", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		var body struct {
+			Enabled bool `json:"enabled"`
+		}
+		if adminDecode(w, r, &body) {
+			adminRespond(w, a.audit(r, "read-only", "", flagValue(body.Enabled), a.SetReadOnly(body.Enabled)))
+		}
+	})
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if !debugAllowed(r, "X-Admin-Token", a.cfg.Token, allow) {
+			nethttp.Error(w, nethttp.StatusText(nethttp.StatusForbidden), nethttp.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+// audit 记录修改操作（含来源地址），原样返回err
+func (a *Admin) audit(r *nethttp.Request, item, target, value string, err error) error {
+	if err != nil {
+		logger.Warn("管理接口修改失败：", item, target, value, r.RemoteAddr, err)
+		return err
+	}
+	logger.Warn("管理接口修改运行时配置：", item, target, value, "来源：", r.RemoteAddr)
+	return nil
+}
+
+func (a *Admin) key(suffix string) string {
+	return a.rdb.DbPre + a.cfg.Prefix + suffix
+}
+
+// adminDecode 解析JSON请求体，失败时直接响应400
+func adminDecode(w nethttp.ResponseWriter, r *nethttp.Request, v interface{}) bool {
+	if err := json.NewDecoder(nethttp.MaxBytesReader(w, r.Body, 1<<16)).Decode(v); err != nil {
+		adminJSON(w, nethttp.StatusBadRequest, "请求参数错误："+err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// adminRespond 返回修改结果与当前生效的覆盖项
+func adminRespond(w nethttp.ResponseWriter, err error) {
+	if err != nil {
+		adminJSON(w, nethttp.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	adminJSON(w, nethttp.StatusOK, "success", config.GetRuntime())
+}
+
+func adminJSON(w nethttp.ResponseWriter, code int, msg string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "msg": msg, "data": data})
+}
+
+// normalizeLogModule 模块名规范化（空表示全局）
+func normalizeLogModule(module string) string {
+	module = strings.Trim(strings.TrimSpace(module), "/")
+	if module == "" {
+		return config.GlobalLogModule
+	}
+	return module
+}
+
+func flagValue(enabled bool) string {
+	if enabled {
+		return "1"
+	}
+	return "0"
+}
@@ -1,21 +1,33 @@
 package bootstrap
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"github.com/dfpopp/go-dai/base"
 	"github.com/dfpopp/go-dai/config"
 	"github.com/dfpopp/go-dai/db"
+	"github.com/dfpopp/go-dai/db/chaos"
+	"github.com/dfpopp/go-dai/discovery"
 	"github.com/dfpopp/go-dai/grpc"
 	"github.com/dfpopp/go-dai/http"
+	"github.com/dfpopp/go-dai/httpclient"
 	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/mq"
+	"github.com/dfpopp/go-dai/mqtt"
+	"github.com/dfpopp/go-dai/queue"
+	"github.com/dfpopp/go-dai/scheduler"
+	"github.com/dfpopp/go-dai/schema"
+	"github.com/dfpopp/go-dai/tracing"
 	"github.com/dfpopp/go-dai/websocket"
+	"net"
+	nethttp "net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
 	"sync"
 	"syscall"
+	"time"
 )
 
 // ServiceType 服务类型枚举
@@ -25,6 +37,7 @@ const (
 	ServiceTypeHTTP ServiceType = "http"
 	ServiceTypeWS   ServiceType = "ws"
 	ServiceTypeGRPC ServiceType = "grpc"
+	ServiceTypeMQTT ServiceType = "mqtt"
 )
 
 // BootConfig 统一启动配置结构体
@@ -35,16 +48,61 @@ type BootConfig struct {
 	CustomConfigPaths  []string        // 自定义配置文件路径（可选）
 	EnableServices     []ServiceType   // 需要启动的服务类型
 	Router             base.BaseRouter // 应用路由实例
+	GracefulRestart    bool            // 是否启用平滑重启（收到SIGUSR2时fork新进程并继承HTTP/WS/MQTT监听FD，仅类Unix系统）
+	GracefulTimeout    int             // 平滑重启/停机时等待连接排空的超时（秒，默认30）
+	WatchConfig        bool            // 是否监听配置文件变更并热更新（订阅方式见config.OnAppConfigChange）
+	ConfigSource       config.Source   // 远程配置源（etcd/Consul/Nacos，可选；设置后配置路径作为配置源中的键）
+	ConfigCacheDir     string          // 远程配置本地缓存目录（配置中心不可用时回退使用）
+	// Servers 附加服务（可选，同一进程内在其他端口运行HTTP/WS/gRPC服务，各自使用独立的路由与中间件；不注册到服务发现）
+	Servers []ServerSpec
+	// Jobs 注册定时任务（可选，设置后Boot/BootCron按scheduler配置创建并启动调度器，停机时等待执行中的任务完成）
+	Jobs func(s *scheduler.Scheduler) error
+	// Workers 注册任务队列的处理函数（可选，设置后Boot/BootCron按queue配置开始消费，停机时等待处理中的消息完成）
+	Workers func(q *queue.Queue) error
+	// Consumers 注册消息中间件（Kafka/RabbitMQ）的处理函数（可选，设置后Boot/BootCron开始消费，停机时等待处理中的消息完成并确认/提交位点）
+	Consumers func(c *mq.Consumer) error
+	// OnBeforeStart 启动前钩子（数据库等依赖已初始化、服务尚未启动，返回错误时中止启动）
+	OnBeforeStart []Hook
+	// OnAfterStart 启动后钩子（全部服务已监听端口并完成服务注册，返回错误时停机并由Boot返回该错误）
+	OnAfterStart []Hook
+	// OnShutdown 停机钩子（服务已排空、定时任务/队列已停止，数据库等连接关闭之前执行；错误只记录并汇总到Wait的返回值）
+	OnShutdown []Hook
 }
 
 // BootContext 启动上下文（存储已启动的服务）
 type BootContext struct {
-	HTTPServer *http.Server
-	WSServer   *websocket.Server
-	GRPCServer *grpc.Server
+	HTTPServer  *http.Server
+	WSServer    *websocket.Server
+	GRPCServer  *grpc.Server
+	MQTTServer  *mqtt.Server
+	DebugServer *nethttp.Server // 诊断端口（配置debug.enable时启动）
+	Admin       *Admin          // 运行时管理接口（配置admin.enable时启动）
+	// Scheduler 定时任务调度器（设置BootConfig.Jobs时启动）
+	Scheduler *scheduler.Scheduler
+	// Queue 任务队列（设置BootConfig.Workers时开始消费）
+	Queue *queue.Queue
+	// Consumer 消息中间件消费者（设置BootConfig.Consumers时开始消费）
+	Consumer *mq.Consumer
+	// HTTPServers/WSServers/GRPCServers 附加服务（BootConfig.Servers，key为服务名）
+	HTTPServers map[string]*http.Server
+	WSServers   map[string]*websocket.Server
+	GRPCServers map[string]*grpc.Server
+
+	cfg      *BootConfig
+	dbTypes  []string // 已初始化的数据库类型（停机时关闭）
+	once     sync.Once
+	stopping chan struct{} // 开始停机时关闭
+	done     chan struct{} // 停机完成时关闭
+	mu       sync.Mutex
+	runErr   error // 服务异常退出的错误
+	err      error // 停机结果
+}
+
+func newBootContext(cfg *BootConfig) *BootContext {
+	return &BootContext{cfg: cfg, stopping: make(chan struct{}), done: make(chan struct{})}
 }
 
-// Boot 统一服务启动入口
+// Boot 统一服务启动入口：完成初始化并启动服务后立即返回，main中调用BootContext.Wait阻塞至停机完成
 func Boot(cfg *BootConfig) (*BootContext, error) {
 	appPath := ""
 	_, entryFile, _, ok := runtime.Caller(1)
@@ -57,6 +115,9 @@ func Boot(cfg *BootConfig) (*BootContext, error) {
 	}
 
 	// 2. 加载配置
+	if cfg.ConfigSource != nil {
+		config.SetSource(cfg.ConfigSource, cfg.ConfigCacheDir)
+	}
 	if err := config.LoadAppConfig(cfg.AppConfigPath, cfg.AppName); err != nil {
 		return nil, fmt.Errorf("加载应用配置失败: %v", err)
 	}
@@ -67,8 +128,24 @@ func Boot(cfg *BootConfig) (*BootContext, error) {
 	if err := logger.InitLogger(cfg.AppName, appPath); err != nil {
 		return nil, fmt.Errorf("初始化日志失败: %v", err)
 	}
+	// 初始化链路追踪（需早于数据库初始化，以便注册数据库埋点）
+	tracing.Init(cfg.AppName)
 
-	// 4. 初始化数据库
+	// 配置热更新（校验失败时保留原配置并记录错误日志）
+	if cfg.WatchConfig {
+		if err := config.StartWatch(func(filePath string, err error) {
+			logger.Error("配置热更新失败：", filePath, err)
+		}); err != nil {
+			logger.Warn("配置文件监听启动失败：", err)
+		}
+	}
+
+	// 4. 初始化数据库（连接由停机流程在服务排空后关闭，不注册独立的退出信号钩子）
+	bootCtx := newBootContext(cfg)
+	fail := func(err error) (*BootContext, error) {
+		_ = bootCtx.Shutdown(context.Background())
+		return nil, err
+	}
 	startDb := make([]string, 0)
 	if len(config.DbConfig.MySQL) > 0 {
 		startDb = append(startDb, "mysql")
@@ -83,89 +160,108 @@ func Boot(cfg *BootConfig) (*BootContext, error) {
 		startDb = append(startDb, "es")
 	}
 	if len(startDb) > 0 {
-		db.StartDb(startDb)
+		db.InitDb(startDb)
+		bootCtx.dbTypes = startDb
+		// 连接池预热完成后再启动服务（就绪门槛），避免发布后首批请求承担建连耗时
+		if err := warmupDb(cfg.AppName, startDb); err != nil {
+			return fail(err)
+		}
+		// 数据库故障注入（配置db_chaos，仅非生产环境），在预热完成后启用
+		chaos.Init(cfg.AppName)
+	}
+	// 初始化服务发现（配置discovery，需早于gRPC客户端以解析discovery:///目标）
+	if err := discovery.Init(cfg.AppName); err != nil {
+		return fail(err)
+	}
+	// 初始化服务间调用的gRPC客户端（配置grpc.clients）
+	if err := grpc.InitClients(cfg.AppName); err != nil {
+		return fail(err)
+	}
+	// 初始化第三方HTTP接口客户端（配置http_clients）
+	if err := httpclient.InitClients(cfg.AppName); err != nil {
+		return fail(err)
+	}
+	// 初始化载荷结构注册中心（配置schema）
+	if err := schema.InitRegistry(cfg.AppName); err != nil {
+		return fail(err)
+	}
+	// 初始化消息中间件连接（数据库配置mq）
+	if len(config.DbConfig.MQ) > 0 {
+		if err := mq.InitMQ(); err != nil {
+			return fail(err)
+		}
+	}
+	// 启动前钩子（数据迁移、缓存预热等，失败时中止启动）
+	if err := bootCtx.runHooks("OnBeforeStart", cfg.OnBeforeStart, true); err != nil {
+		return fail(err)
+	}
+	// 5. 初始化并启动服务（平滑重启拉起的子进程复用父进程的监听器）
+	var inherited map[ServiceType]net.Listener
+	if cfg.GracefulRestart {
+		inherited = inheritedListeners()
+	}
+	var err error
+	if bootCtx.Scheduler, err = startScheduler(cfg); err != nil {
+		return fail(err)
+	}
+	if bootCtx.Queue, err = startQueue(cfg); err != nil {
+		return fail(err)
+	}
+	if bootCtx.Consumer, err = startConsumer(cfg); err != nil {
+		return fail(err)
+	}
+	if srv, err := StartDebugServer(cfg.AppName); err != nil {
+		logger.Error("诊断端口启动失败：", err)
+	} else {
+		bootCtx.DebugServer = srv
+	}
+	if admin, err := StartAdmin(cfg.AppName); err != nil {
+		logger.Error("管理接口启动失败：", err)
+	} else {
+		bootCtx.Admin = admin
 	}
-	// 5. 初始化并启动服务
-	bootCtx := &BootContext{}
-	var wg sync.WaitGroup
 
 	for _, serviceType := range cfg.EnableServices {
-		wg.Add(1)
+		spec := ServerSpec{Type: serviceType, AppName: cfg.AppName, Router: cfg.Router}
 		switch serviceType {
 		case ServiceTypeHTTP:
-			// 初始化HTTP服务
-			bootCtx.HTTPServer = http.NewServer(cfg.AppName)
-			//bootCtx.HTTPServer.Use(http.CORS(), http.Recovery())
-			// 注册路由
-			cfg.Router.RegisterHTTPRoutes(bootCtx.HTTPServer)
-			// 异步启动
-			go func() {
-				defer wg.Done()
-				if err := bootCtx.HTTPServer.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-					logger.Error(fmt.Errorf("HTTP服务启动失败: %v", err))
-				}
-			}()
-			logger.Info("HTTP服务已初始化，监听地址：", bootCtx.HTTPServer.Config().Addr)
-			break
+			bootCtx.HTTPServer, err = bootCtx.startHTTP(spec, inherited)
 		case ServiceTypeWS:
-			// 初始化WebSocket服务
-			bootCtx.WSServer = websocket.NewServer(cfg.AppName)
-			// 注册路由
-			cfg.Router.RegisterWSRoutes(bootCtx.WSServer)
-			// 异步启动
-			go func() {
-				defer wg.Done()
-				if err := bootCtx.WSServer.Run(); err != nil && !errors.Is(err, websocket.ErrServerClosed) {
-					logger.Error(fmt.Errorf("WebSocket服务启动失败: %v", err))
-				}
-			}()
-			logger.Info("WebSocket服务已初始化，监听地址：", bootCtx.WSServer.Config().Addr)
-			break
+			bootCtx.WSServer, err = bootCtx.startWS(spec, inherited)
 		case ServiceTypeGRPC:
-			// 初始化gRPC服务
-			bootCtx.GRPCServer = grpc.NewServer(cfg.AppName)
-			// 注册路由
-			cfg.Router.RegisterGRPCRoutes(bootCtx.GRPCServer)
-			// 异步启动
-			go func() {
-				defer wg.Done()
-				if err := bootCtx.GRPCServer.Run(); err != nil {
-					logger.Error(fmt.Errorf("gRPC服务启动失败: %v", err))
-				}
-			}()
-			logger.Info("gRPC服务已初始化，监听地址：", bootCtx.GRPCServer.Config().Addr)
-			break
+			bootCtx.GRPCServer, err = bootCtx.startGRPC(spec, inherited)
+		case ServiceTypeMQTT:
+			bootCtx.MQTTServer, err = bootCtx.startMQTT(spec, inherited)
 		default:
-			return nil, fmt.Errorf("未知服务类型: %s", serviceType)
+			err = fmt.Errorf("未知服务类型: %s", serviceType)
+		}
+		if err != nil {
+			return fail(err)
 		}
 	}
+	// 附加服务（同一进程内的其他端口，各自使用独立的路由与中间件）
+	if err := bootCtx.startExtraServers(cfg.Servers, inherited); err != nil {
+		return fail(err)
+	}
 
-	// 6. 优雅停机监听
-	go func() {
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-		<-quit
+	// 注册服务实例到注册中心（配置discovery）
+	registerServices(bootCtx)
 
-		logger.Info("应用开始优雅停机...")
-		// 停止HTTP服务
-		if bootCtx.HTTPServer != nil {
-			_ = bootCtx.HTTPServer.Stop()
-		}
-		// 停止WebSocket服务
-		if bootCtx.WSServer != nil {
-			_ = bootCtx.WSServer.Stop()
-		}
-		// 停止gRPC服务
-		//if bootCtx.GRPCServer != nil {
-		//	bootCtx.GRPCServer.Stop()
-		//}
-		logger.Info("应用已完成停机")
-	}()
+	// 6. 平滑重启监听
+	if cfg.GracefulRestart {
+		go watchGracefulRestart(bootCtx)
+	}
+
+	// 7. 优雅停机监听
+	go bootCtx.watchSignal()
 
-	// 等待所有服务启动完成
-	wg.Wait()
+	// 启动后钩子（服务已监听端口，失败时停机并返回错误）
+	if err := bootCtx.runHooks("OnAfterStart", cfg.OnAfterStart, true); err != nil {
+		return fail(err)
+	}
 	return bootCtx, nil
 }
+
 func BootCron(cfg *BootConfig) error {
 	appPath := ""
 	_, entryFile, _, ok := runtime.Caller(1)
@@ -178,6 +274,9 @@ func BootCron(cfg *BootConfig) error {
 	}
 
 	// 2. 加载配置
+	if cfg.ConfigSource != nil {
+		config.SetSource(cfg.ConfigSource, cfg.ConfigCacheDir)
+	}
 	if err := config.LoadAppConfig(cfg.AppConfigPath, cfg.AppName); err != nil {
 		return fmt.Errorf("加载应用配置失败: %v", err)
 	}
@@ -188,6 +287,8 @@ func BootCron(cfg *BootConfig) error {
 	if err := logger.InitLogger(cfg.AppName, appPath); err != nil {
 		return fmt.Errorf("初始化日志失败: %v", err)
 	}
+	// 初始化链路追踪（需早于数据库初始化，以便注册数据库埋点）
+	tracing.Init(cfg.AppName)
 
 	// 4. 初始化数据库
 	startDb := make([]string, 0)
@@ -205,6 +306,43 @@ func BootCron(cfg *BootConfig) error {
 	}
 	if len(startDb) > 0 {
 		db.StartDb(startDb)
+		if err := warmupDb(cfg.AppName, startDb); err != nil {
+			return err
+		}
+		chaos.Init(cfg.AppName)
+	}
+	if err := discovery.Init(cfg.AppName); err != nil {
+		return err
+	}
+	if err := grpc.InitClients(cfg.AppName); err != nil {
+		return err
+	}
+	if err := httpclient.InitClients(cfg.AppName); err != nil {
+		return err
+	}
+	if err := schema.InitRegistry(cfg.AppName); err != nil {
+		return err
+	}
+	if len(config.DbConfig.MQ) > 0 {
+		if err := mq.InitMQ(); err != nil {
+			return err
+		}
+	}
+	// 5. 启动定时任务调度
+	sched, err := startScheduler(cfg)
+	if err != nil {
+		return err
+	}
+	q, err := startQueue(cfg)
+	if err != nil {
+		stopScheduler(sched, cfg.GracefulTimeout)
+		return err
+	}
+	consumer, err := startConsumer(cfg)
+	if err != nil {
+		stopScheduler(sched, cfg.GracefulTimeout)
+		stopQueue(q, cfg.GracefulTimeout)
+		return err
 	}
 	// 6. 优雅停机监听
 	go func() {
@@ -212,7 +350,178 @@ func BootCron(cfg *BootConfig) error {
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 		logger.Info("应用开始优雅停机...")
+		stopScheduler(sched, cfg.GracefulTimeout)
+		stopQueue(q, cfg.GracefulTimeout)
+		stopConsumer(consumer, cfg.GracefulTimeout)
+		_ = mq.CloseMQ()
+		_ = grpc.CloseClients()
+		httpclient.CloseClients()
 		logger.Info("应用已完成停机")
 	}()
 	return nil
 }
+
+// registerServices 将已启动的服务注册到注册中心（未启用服务发现或配置discovery.disable时跳过）
+func registerServices(bootCtx *BootContext) {
+	if !discovery.Enabled() {
+		return
+	}
+	endpoints := make(map[string]string)
+	if bootCtx.HTTPServer != nil {
+		endpoints[discovery.ProtocolHTTP] = bootCtx.HTTPServer.Config().Addr
+	}
+	if bootCtx.WSServer != nil {
+		endpoints[discovery.ProtocolWS] = bootCtx.WSServer.Config().Addr
+	}
+	if bootCtx.GRPCServer != nil {
+		endpoints[discovery.ProtocolGRPC] = bootCtx.GRPCServer.Config().Addr
+	}
+	if bootCtx.MQTTServer != nil {
+		endpoints[discovery.ProtocolMQTT] = bootCtx.MQTTServer.Config().Addr
+	}
+	for protocol, addr := range endpoints {
+		ins, err := discovery.NewInstance(protocol, addr)
+		if err == nil {
+			err = discovery.Register(ins)
+		}
+		if err != nil {
+			logger.Error("服务注册失败：", protocol, "错误：", err)
+		}
+	}
+}
+
+// deregisterServices 注销本进程注册的全部服务实例
+func deregisterServices() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := discovery.DeregisterAll(ctx); err != nil {
+		logger.Error("服务注销失败：", err)
+	}
+}
+
+// startScheduler 按BootConfig.Jobs注册并启动定时任务（未设置Jobs或配置scheduler.disable时返回nil）
+func startScheduler(cfg *BootConfig) (*scheduler.Scheduler, error) {
+	if cfg.Jobs == nil {
+		return nil, nil
+	}
+	if config.GetAppConfig(cfg.AppName).Scheduler.Disable {
+		logger.Info("当前实例已停用定时任务")
+		return nil, nil
+	}
+	sched, err := scheduler.FromAppConfig(cfg.AppName)
+	if err != nil {
+		return nil, fmt.Errorf("初始化定时任务失败: %v", err)
+	}
+	if err := cfg.Jobs(sched); err != nil {
+		return nil, fmt.Errorf("注册定时任务失败: %v", err)
+	}
+	sched.Start()
+	return sched, nil
+}
+
+// stopScheduler 停止定时任务调度并等待执行中的任务完成（超时后取消任务的ctx）
+func stopScheduler(sched *scheduler.Scheduler, timeout int) {
+	if sched == nil {
+		return
+	}
+	if timeout <= 0 {
+		timeout = defaultGracefulTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	if err := sched.Stop(ctx); err != nil {
+		logger.Warn("定时任务未在停机超时内完成，已取消：", err)
+	}
+}
+
+// startQueue 按BootConfig.Workers注册处理函数并开始消费任务队列（未设置Workers或配置queue.disable时返回nil）
+func startQueue(cfg *BootConfig) (*queue.Queue, error) {
+	if cfg.Workers == nil {
+		return nil, nil
+	}
+	if config.GetAppConfig(cfg.AppName).Queue.Disable {
+		logger.Info("当前实例已停用任务队列消费")
+		return nil, nil
+	}
+	q, err := queue.FromAppConfig(cfg.AppName)
+	if err != nil {
+		return nil, fmt.Errorf("初始化任务队列失败: %v", err)
+	}
+	if err := cfg.Workers(q); err != nil {
+		return nil, fmt.Errorf("注册任务队列处理函数失败: %v", err)
+	}
+	if err := q.Start(); err != nil {
+		return nil, fmt.Errorf("启动任务队列消费失败: %v", err)
+	}
+	return q, nil
+}
+
+// stopQueue 停止消费任务队列并等待处理中的消息完成（超时后取消处理函数的ctx）
+func stopQueue(q *queue.Queue, timeout int) {
+	if q == nil {
+		return
+	}
+	if timeout <= 0 {
+		timeout = defaultGracefulTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		logger.Warn("任务队列未在停机超时内处理完成，已取消：", err)
+	}
+}
+
+// startConsumer 按BootConfig.Consumers注册处理函数并开始消费消息中间件（未设置Consumers时返回nil）
+func startConsumer(cfg *BootConfig) (*mq.Consumer, error) {
+	if cfg.Consumers == nil {
+		return nil, nil
+	}
+	c := mq.FromAppConfig(cfg.AppName)
+	if err := cfg.Consumers(c); err != nil {
+		return nil, fmt.Errorf("注册消息处理函数失败: %v", err)
+	}
+	if err := c.Start(); err != nil {
+		return nil, fmt.Errorf("启动消息中间件消费失败: %v", err)
+	}
+	return c, nil
+}
+
+// stopConsumer 停止消费消息中间件并等待处理中的消息完成（超时后取消处理函数的ctx）
+func stopConsumer(c *mq.Consumer, timeout int) {
+	if c == nil {
+		return
+	}
+	if timeout <= 0 {
+		timeout = defaultGracefulTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	if err := c.Stop(ctx); err != nil {
+		logger.Warn("消息中间件消费未在停机超时内处理完成，已取消：", err)
+	}
+}
+
+// warmupDb 按应用配置预热数据库连接池（未启用时直接返回；失败时按required决定中止启动还是放行）
+func warmupDb(appName string, startDb []string) error {
+	cfg := config.GetAppConfig(appName).DbWarmup
+	if !cfg.Enable {
+		return nil
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	result, err := db.Warmup(ctx, startDb, cfg.Conns)
+	if err != nil {
+		if cfg.Required {
+			return fmt.Errorf("数据库连接池预热失败: %v", err)
+		}
+		logger.Warn("数据库连接池预热未完成，继续启动：", err)
+		db.MarkReady()
+		return nil
+	}
+	logger.Info("数据库连接池预热完成，耗时：", result.Elapsed, "，连接数：", result.Conns)
+	return nil
+}
@@ -0,0 +1,193 @@
+package bootstrap
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/logger"
+	"net"
+	nethttp "net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"strings"
+	"time"
+)
+
+// 诊断端口：在独立地址上暴露pprof、expvar、协程堆栈与GC统计，便于在生产环境直接采样分析，
+// 无需重新发布带埋点的版本。访问控制基于IP白名单与令牌，客户端IP仅取TCP连接地址（不信任X-Forwarded-For）。
+//
+//	go tool pprof "http://127.0.0.1:6060/debug/pprof/profile?seconds=30&token=xxx"
+//	curl -H "X-Debug-Token: xxx" http://127.0.0.1:6060/debug/goroutines
+
+const defaultDebugAddr = "127.0.0.1:6060"
+
+// StartDebugServer 按应用配置启动诊断端口（未启用时返回nil）
+func StartDebugServer(appName string) (*nethttp.Server, error) {
+	cfg := config.GetAppConfig(appName).Debug
+	if !cfg.Enable {
+		return nil, nil
+	}
+	handler, err := DebugHandler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	addr := cfg.Addr
+	if addr == "" {
+		addr = defaultDebugAddr
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &nethttp.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			logger.Error("诊断端口异常退出：", err)
+		}
+	}()
+	logger.Info("诊断端口已启动，监听地址：", lis.Addr().String())
+	return srv, nil
+}
+
+// DebugHandler 诊断接口处理器（可挂载到自定义的管理端口）
+func DebugHandler(cfg config.DebugConfig) (nethttp.Handler, error) {
+	allow, err := parseAllowIPs(cfg.AllowIPs)
+	if err != nil {
+		return nil, err
+	}
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/goroutines", goroutineDump)
+	mux.HandleFunc("/debug/gc", gcStats)
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if !debugAllowed(r, "X-Debug-Token", cfg.Token, allow) {
+			nethttp.Error(w, nethttp.StatusText(nethttp.StatusForbidden), nethttp.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+// debugAllowed 校验访问权限：配置了白名单时IP须命中，配置了令牌时令牌须一致（从tokenHeader请求头或查询参数token读取），均未配置时仅允许本机
+func debugAllowed(r *nethttp.Request, tokenHeader, token string, allow []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if len(allow) == 0 && token == "" {
+		return ip.IsLoopback()
+	}
+	if len(allow) > 0 {
+		matched := false
+		for _, n := range allow {
+			if n.Contains(ip) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if token != "" {
+		got := r.Header.Get(tokenHeader)
+		if got == "" {
+			got = r.URL.Query().Get("token")
+		}
+		return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+	}
+	return true
+}
+
+// parseAllowIPs 解析IP/CIDR白名单
+func parseAllowIPs(items []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil && ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, errors.New("诊断端口白名单格式错误：" + item)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// goroutineDump 输出全部协程堆栈（文本格式，与panic时的堆栈格式一致）
+func goroutineDump(w nethttp.ResponseWriter, _ *nethttp.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// gcStats 输出内存与GC统计
+func gcStats(w nethttp.ResponseWriter, _ *nethttp.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	gc := debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	debug.ReadGCStats(&gc)
+	recent := gc.Pause
+	if len(recent) > 10 {
+		recent = recent[:10]
+	}
+	pauses := make([]string, len(recent))
+	for i, p := range recent {
+		pauses[i] = p.String()
+	}
+	quantiles := make([]string, len(gc.PauseQuantiles))
+	for i, q := range gc.PauseQuantiles {
+		quantiles[i] = q.String()
+	}
+	data := map[string]interface{}{
+		"goroutines":      runtime.NumGoroutine(),
+		"num_cpu":         runtime.NumCPU(),
+		"gomaxprocs":      runtime.GOMAXPROCS(0),
+		"go_version":      runtime.Version(),
+		"heap_alloc":      mem.HeapAlloc,
+		"heap_inuse":      mem.HeapInuse,
+		"heap_idle":       mem.HeapIdle,
+		"heap_released":   mem.HeapReleased,
+		"heap_objects":    mem.HeapObjects,
+		"stack_inuse":     mem.StackInuse,
+		"sys":             mem.Sys,
+		"total_alloc":     mem.TotalAlloc,
+		"mallocs":         mem.Mallocs,
+		"frees":           mem.Frees,
+		"next_gc":         mem.NextGC,
+		"num_gc":          gc.NumGC,
+		"num_forced_gc":   mem.NumForcedGC,
+		"gc_cpu_fraction": mem.GCCPUFraction,
+		"last_gc":         gc.LastGC,
+		"pause_total":     gc.PauseTotal.String(),
+		"recent_pauses":   pauses,
+		"pause_quantiles": quantiles,                // 最小值、25%、50%、75%、最大值
+		"memory_limit":    debug.SetMemoryLimit(-1), // 传负数仅读取当前GOMEMLIMIT
+	}
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	_ = json.NewEncoder(w).Encode(data)
+}
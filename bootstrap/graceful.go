@@ -0,0 +1,159 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/websocket"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envInheritFds 父进程传递给子进程的监听FD列表（格式：http:3,ws:4）
+const envInheritFds = "DAI_INHERIT_FDS"
+
+// defaultGracefulTimeout 平滑重启时旧进程等待连接排空的默认超时（秒）
+const defaultGracefulTimeout = 30
+
+// inheritedListeners 解析从父进程继承的监听器（key为服务类型，非平滑重启启动时返回空map）
+func inheritedListeners() map[ServiceType]net.Listener {
+	listeners := make(map[ServiceType]net.Listener)
+	val := os.Getenv(envInheritFds)
+	if val == "" {
+		return listeners
+	}
+	// 避免子进程再次fork时误用旧值
+	_ = os.Unsetenv(envInheritFds)
+	for _, item := range strings.Split(val, ",") {
+		kv := strings.SplitN(item, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		fd, err := strconv.Atoi(kv[1])
+		if err != nil {
+			logger.Warn("继承的监听FD格式错误：", item)
+			continue
+		}
+		file := os.NewFile(uintptr(fd), kv[0])
+		lis, err := net.FileListener(file)
+		// FileListener会复制FD，原文件可直接关闭
+		_ = file.Close()
+		if err != nil {
+			logger.Warn("恢复继承的监听器失败：", item, err)
+			continue
+		}
+		listeners[ServiceType(kv[0])] = lis
+		logger.Info("已继承父进程监听器：", kv[0], lis.Addr().String())
+	}
+	return listeners
+}
+
+// forkChild 携带HTTP/WS/MQTT/gRPC监听FD启动新进程
+func forkChild(bootCtx *BootContext) error {
+	files := make([]*os.File, 0)
+	fds := make([]string, 0)
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	addListener := func(serviceType ServiceType, lis net.Listener) error {
+		if lis == nil {
+			return nil
+		}
+		tcpLis, ok := lis.(*net.TCPListener)
+		if !ok {
+			return fmt.Errorf("%s监听器不支持FD继承", serviceType)
+		}
+		file, err := tcpLis.File()
+		if err != nil {
+			return fmt.Errorf("获取%s监听FD失败: %w", serviceType, err)
+		}
+		// ExtraFiles中第i个文件在子进程中的FD为3+i
+		fds = append(fds, fmt.Sprintf("%s:%d", serviceType, 3+len(files)))
+		files = append(files, file)
+		return nil
+	}
+	if bootCtx.HTTPServer != nil {
+		if err := addListener(ServiceTypeHTTP, bootCtx.HTTPServer.Listener()); err != nil {
+			return err
+		}
+	}
+	if bootCtx.WSServer != nil {
+		if err := addListener(ServiceTypeWS, bootCtx.WSServer.Listener()); err != nil {
+			return err
+		}
+	}
+	if bootCtx.MQTTServer != nil {
+		if err := addListener(ServiceTypeMQTT, bootCtx.MQTTServer.Listener()); err != nil {
+			return err
+		}
+	}
+	if bootCtx.GRPCServer != nil {
+		if err := addListener(ServiceTypeGRPC, bootCtx.GRPCServer.Listener()); err != nil {
+			return err
+		}
+	}
+	// 附加服务以"类型.服务名"标识
+	for name, srv := range bootCtx.HTTPServers {
+		if err := addListener(ServiceType(string(ServiceTypeHTTP)+"."+name), srv.Listener()); err != nil {
+			return err
+		}
+	}
+	for name, srv := range bootCtx.WSServers {
+		if err := addListener(ServiceType(string(ServiceTypeWS)+"."+name), srv.Listener()); err != nil {
+			return err
+		}
+	}
+	for name, srv := range bootCtx.GRPCServers {
+		if err := addListener(ServiceType(string(ServiceTypeGRPC)+"."+name), srv.Listener()); err != nil {
+			return err
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("没有可继承的监听器")
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取可执行文件路径失败: %w", err)
+	}
+	cmd := exec.Command(execPath, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), envInheritFds+"="+strings.Join(fds, ","))
+	cmd.ExtraFiles = files
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动新进程失败: %w", err)
+	}
+	logger.Info("新进程已启动，PID：", cmd.Process.Pid)
+	return nil
+}
+
+// gracefulRestart 启动新进程接管监听，当前进程停止接收新请求并等待存量连接排空（排空后执行停机钩子并关闭连接，Wait返回）
+func gracefulRestart(bootCtx *BootContext) {
+	if err := forkChild(bootCtx); err != nil {
+		logger.Error(fmt.Errorf("平滑重启失败: %v", err))
+		return
+	}
+	bootCtx.once.Do(func() {
+		bootCtx.stop(true)
+	})
+}
+
+// drainWS 通知WS客户端重连并等待连接排空（超时后关闭剩余连接）
+func drainWS(server *websocket.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	result, err := server.Drain(ctx, websocket.DrainOptions{CloseRemaining: true})
+	if err != nil {
+		logger.Warn("WS连接排空超时，已通知：", result.Notified, "剩余：", result.Remaining, "强制关闭：", result.Closed)
+		return
+	}
+	logger.Info("WS连接排空完成，已通知：", result.Notified)
+}
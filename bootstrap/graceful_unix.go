@@ -0,0 +1,18 @@
+//go:build !windows
+
+package bootstrap
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchGracefulRestart 监听SIGUSR2信号触发平滑重启
+func watchGracefulRestart(bootCtx *BootContext) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR2)
+	<-sigCh
+	signal.Stop(sigCh)
+	gracefulRestart(bootCtx)
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package bootstrap
+
+import (
+	"github.com/dfpopp/go-dai/logger"
+)
+
+// watchGracefulRestart Windows不支持SIGUSR2及FD继承，仅提示
+func watchGracefulRestart(bootCtx *BootContext) {
+	logger.Warn("当前系统不支持平滑重启，已忽略GracefulRestart配置")
+}
@@ -0,0 +1,252 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/db"
+	"github.com/dfpopp/go-dai/discovery"
+	"github.com/dfpopp/go-dai/grpc"
+	"github.com/dfpopp/go-dai/httpclient"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/mq"
+	"github.com/dfpopp/go-dai/websocket"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// 生命周期：Boot完成初始化并启动服务后立即返回，main中调用BootContext.Wait等待停机完成。
+// 启动顺序：配置/日志 -> 数据库/服务发现/gRPC客户端/消息中间件 -> OnBeforeStart -> 定时任务/队列/消费者 -> 监听端口与服务 -> 服务注册 -> OnAfterStart
+// 停机顺序与依赖相反：服务注销 -> 排空并停止HTTP/WS/MQTT/gRPC -> 定时任务/队列/消费者 -> OnShutdown -> 消息中间件/gRPC客户端 -> 数据库
+
+// HookFunc 生命周期钩子函数（OnBeforeStart执行时服务尚未创建，bootCtx中的服务字段为nil）
+type HookFunc func(ctx context.Context, bootCtx *BootContext) error
+
+// Hook 生命周期钩子
+type Hook struct {
+	Name  string // 钩子名称（用于日志）
+	Order int    // 同一阶段内按Order升序执行，相同时按注册顺序
+	Fn    HookFunc
+	// Timeout 单个钩子的执行超时（秒，默认为BootConfig.GracefulTimeout），到期时取消ctx
+	Timeout int
+}
+
+// runHooks 按顺序执行钩子（stopOnError为true时遇到错误立即返回，否则执行全部并合并错误）
+func (b *BootContext) runHooks(phase string, hooks []Hook, stopOnError bool) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	sorted := make([]Hook, len(hooks))
+	copy(sorted, hooks)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+	var errs []error
+	for _, hook := range sorted {
+		if hook.Fn == nil {
+			continue
+		}
+		timeout := hook.Timeout
+		if timeout <= 0 {
+			timeout = b.gracefulTimeout()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+		start := time.Now()
+		err := callHook(ctx, hook.Fn, b)
+		cancel()
+		if err != nil {
+			err = fmt.Errorf("%s钩子[%s]执行失败: %w", phase, hook.Name, err)
+			if stopOnError {
+				return err
+			}
+			logger.Error(err)
+			errs = append(errs, err)
+			continue
+		}
+		logger.Info(phase, "钩子执行完成：", hook.Name, "，耗时：", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// callHook 执行钩子（panic转为错误，避免中断启动/停机流程）
+func callHook(ctx context.Context, fn HookFunc, bootCtx *BootContext) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx, bootCtx)
+}
+
+// Wait 阻塞直至停机完成（收到SIGINT/SIGTERM、调用Shutdown、服务异常退出或平滑重启排空完成），返回停机过程中的错误
+func (b *BootContext) Wait() error {
+	<-b.done
+	return b.err
+}
+
+// Done 停机完成时关闭的通道
+func (b *BootContext) Done() <-chan struct{} {
+	return b.done
+}
+
+// Shutdown 主动停机（可重复调用，只执行一次）：按依赖相反的顺序停止服务并关闭连接，等待停机完成或ctx到期
+func (b *BootContext) Shutdown(ctx context.Context) error {
+	b.once.Do(func() {
+		go b.stop(false)
+	})
+	select {
+	case <-b.done:
+		return b.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// watchSignal 收到SIGINT/SIGTERM时停机
+func (b *BootContext) watchSignal() {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case <-quit:
+		_ = b.Shutdown(context.Background())
+	case <-b.done:
+	}
+}
+
+// serve 异步运行服务（非主动停止导致的退出视为故障，记录错误并停机）
+func (b *BootContext) serve(name string, run func() error, closedErr error) {
+	go func() {
+		err := run()
+		if err == nil || (closedErr != nil && errors.Is(err, closedErr)) {
+			return
+		}
+		select {
+		case <-b.stopping:
+			return
+		default:
+		}
+		err = fmt.Errorf("%s服务异常退出: %w", name, err)
+		logger.Error(err)
+		b.mu.Lock()
+		b.runErr = errors.Join(b.runErr, err)
+		b.mu.Unlock()
+		_ = b.Shutdown(context.Background())
+	}()
+}
+
+// stop 停机流程（restart为true时为平滑重启：新进程已接管监听，服务实例只停止续约不注销）
+func (b *BootContext) stop(restart bool) {
+	close(b.stopping)
+	if restart {
+		logger.Info("旧进程开始排空连接...")
+	} else {
+		logger.Info("应用开始优雅停机...")
+	}
+	b.stopServices(restart)
+	timeout := b.gracefulTimeout()
+	stopScheduler(b.Scheduler, timeout)
+	stopQueue(b.Queue, timeout)
+	stopConsumer(b.Consumer, timeout)
+	var errs []error
+	if b.cfg != nil {
+		if err := b.runHooks("OnShutdown", b.cfg.OnShutdown, false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	b.closeResources()
+	if b.DebugServer != nil {
+		_ = b.DebugServer.Close()
+	}
+	if b.Admin != nil {
+		b.Admin.Close()
+	}
+	b.mu.Lock()
+	b.err = errors.Join(append([]error{b.runErr}, errs...)...)
+	b.mu.Unlock()
+	if restart {
+		logger.Info("旧进程已完成排空")
+	} else {
+		logger.Info("应用已完成停机")
+	}
+	close(b.done)
+}
+
+// stopServices 注销服务实例，排空并停止HTTP/WS/MQTT/gRPC服务
+func (b *BootContext) stopServices(restart bool) {
+	if restart {
+		// 新进程以相同的实例标识重新注册，旧进程只停止续约不注销
+		discovery.StopKeepAlive()
+	} else {
+		// 先从注册中心注销，调用方不再路由到本实例
+		deregisterServices()
+	}
+	timeout := time.Duration(b.gracefulTimeout()) * time.Second
+	if b.HTTPServer != nil {
+		_ = b.HTTPServer.Stop()
+	}
+	for _, srv := range b.HTTPServers {
+		_ = srv.Stop()
+	}
+	// 排空并停止WebSocket服务（先拒绝新连接并通知客户端重连到其他实例）
+	wsServers := make([]*websocket.Server, 0, len(b.WSServers)+1)
+	if b.WSServer != nil {
+		wsServers = append(wsServers, b.WSServer)
+	}
+	for _, srv := range b.WSServers {
+		wsServers = append(wsServers, srv)
+	}
+	for _, srv := range wsServers {
+		if restart {
+			_ = srv.CloseListener()
+		}
+		drainWS(srv, timeout)
+		_ = srv.Stop()
+	}
+	// 停止MQTT服务（处理中的消息完成并应答后断开）
+	if b.MQTTServer != nil {
+		_ = b.MQTTServer.Stop()
+	}
+	// 停止gRPC服务（等待进行中的调用完成）
+	if b.GRPCServer != nil {
+		b.GRPCServer.Stop()
+	}
+	for _, srv := range b.GRPCServers {
+		srv.Stop()
+	}
+}
+
+// closeResources 关闭消息中间件、gRPC客户端与数据库连接（服务与后台任务停止之后，保证处理中的请求仍可访问数据库）
+func (b *BootContext) closeResources() {
+	_ = mq.CloseMQ()
+	_ = grpc.CloseClients()
+	httpclient.CloseClients()
+	if len(b.dbTypes) > 0 {
+		if err := db.CloseDb(b.dbTypes); err != nil {
+			logger.Error("数据库连接关闭失败：", err)
+		} else {
+			logger.Info("数据库连接已关闭")
+		}
+	}
+}
+
+func (b *BootContext) gracefulTimeout() int {
+	if b.cfg == nil || b.cfg.GracefulTimeout <= 0 {
+		return defaultGracefulTimeout
+	}
+	return b.cfg.GracefulTimeout
+}
+
+// listen 创建服务监听器（平滑重启时复用继承的监听器），启动阶段即可发现端口占用
+func listen(serviceType ServiceType, addr string, inherited map[ServiceType]net.Listener) (net.Listener, error) {
+	if lis, ok := inherited[serviceType]; ok {
+		return lis, nil
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s服务监听失败: %w", serviceType, err)
+	}
+	return lis, nil
+}
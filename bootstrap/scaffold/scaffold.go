@@ -0,0 +1,145 @@
+// Package scaffold 生成可直接运行的应用骨架（配置文件、实现base.BaseRouter的路由、HTTP+WS示例控制器/服务/模型、
+// WS连接监听器、Dockerfile），入口通过bootstrap.Boot启动。命令行入口见cmd/godai。
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// ErrDirNotEmpty 目标目录已存在且非空（设置Force覆盖同名文件）
+var ErrDirNotEmpty = errors.New("scaffold: 目标目录非空")
+
+// Options 骨架生成参数
+type Options struct {
+	Dir     string // 目标目录
+	Module  string // Go模块路径（默认取目标目录名）
+	App     string // 应用名（配置文件中的应用键、app/下的目录名，默认api）
+	Version string // go.mod中go-dai的版本（为空时不写require，由go mod tidy解析最新版本）
+	Force   bool   // 目标目录非空时仍然生成（覆盖同名文件）
+}
+
+// templateData 模板数据
+type templateData struct {
+	Module  string
+	App     string
+	Name    string // 项目名（目标目录名，用于Docker镜像与二进制文件名）
+	Version string
+}
+
+// file 模板文件与生成路径（生成路径中的{{.App}}按应用名替换）
+type file struct {
+	tmpl   string
+	target string
+}
+
+var files = []file{
+	{"go.mod.tmpl", "go.mod"},
+	{"main.go.tmpl", "main.go"},
+	{"app.json.tmpl", "config/app.json"},
+	{"database.json.tmpl", "config/database.json"},
+	{"router.go.tmpl", "app/{{.App}}/router/router.go"},
+	{"controller.go.tmpl", "app/{{.App}}/controller/user.go"},
+	{"service.go.tmpl", "app/{{.App}}/service/user.go"},
+	{"model.go.tmpl", "app/{{.App}}/model/user.go"},
+	{"listener.go.tmpl", "app/{{.App}}/listener/conn.go"},
+	{"Dockerfile.tmpl", "Dockerfile"},
+	{"dockerignore.tmpl", ".dockerignore"},
+	{"gitignore.tmpl", ".gitignore"},
+}
+
+var appNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Generate 在opts.Dir下生成应用骨架，返回生成的文件（相对路径）
+func Generate(opts Options) ([]string, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("scaffold: 未指定目标目录")
+	}
+	abs, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	data := templateData{
+		Module:  strings.TrimSpace(opts.Module),
+		App:     opts.App,
+		Name:    filepath.Base(abs),
+		Version: strings.TrimSpace(opts.Version),
+	}
+	if data.Module == "" {
+		data.Module = data.Name
+	}
+	if data.App == "" {
+		data.App = "api"
+	}
+	if !appNamePattern.MatchString(data.App) {
+		return nil, fmt.Errorf("scaffold: 应用名%q不合法（须为小写字母开头的字母、数字或下划线）", data.App)
+	}
+	if strings.ContainsAny(data.Module, " \t\"'`") {
+		return nil, fmt.Errorf("scaffold: 模块路径%q不合法", data.Module)
+	}
+	if !opts.Force {
+		if entries, err := os.ReadDir(abs); err == nil && len(entries) > 0 {
+			return nil, fmt.Errorf("%w：%s", ErrDirNotEmpty, abs)
+		}
+	}
+
+	rendered := make(map[string][]byte, len(files))
+	generated := make([]string, 0, len(files))
+	for _, f := range files {
+		target, err := render(f.target, f.target, data)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(templateFS, path.Join("templates", f.tmpl))
+		if err != nil {
+			return nil, err
+		}
+		out, err := render(f.tmpl, string(content), data)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(string(target), ".go") {
+			if out, err = format.Source(out); err != nil {
+				return nil, fmt.Errorf("scaffold: 格式化%s失败：%w", target, err)
+			}
+		}
+		rendered[string(target)] = out
+		generated = append(generated, string(target))
+	}
+	// 全部渲染成功后再写入，避免生成一半的骨架
+	for _, target := range generated {
+		dst := filepath.Join(abs, filepath.FromSlash(target))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(dst, rendered[target], 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return generated, nil
+}
+
+func render(name, text string, data templateData) ([]byte, error) {
+	tpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: 解析模板%s失败：%w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("scaffold: 渲染模板%s失败：%w", name, err)
+	}
+	return buf.Bytes(), nil
+}
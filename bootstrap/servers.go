@@ -0,0 +1,191 @@
+package bootstrap
+
+import (
+	"fmt"
+	"github.com/dfpopp/go-dai/base"
+	"github.com/dfpopp/go-dai/grpc"
+	"github.com/dfpopp/go-dai/http"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/mqtt"
+	"github.com/dfpopp/go-dai/tracing"
+	"github.com/dfpopp/go-dai/websocket"
+	"net"
+	"strings"
+)
+
+// ServerSpec 附加服务：同一进程内在其他端口运行HTTP/WS/gRPC服务（如对外API :8080、管理后台 :8081、监控指标 :9100），
+// 每个服务使用独立的服务器实例、路由与中间件栈
+type ServerSpec struct {
+	Name    string          // 服务名（同类型内唯一，用于日志、BootContext.HTTPServers等查找及平滑重启的监听器继承）
+	Type    ServiceType     // 服务类型（http/ws/grpc）
+	Addr    string          // 监听地址（为空时使用AppName对应配置中的地址）
+	AppName string          // 读取服务配置（超时、SSL、限流等）的应用名，默认为BootConfig.AppName，其他应用名需已通过config.LoadAppConfig加载
+	Router  base.BaseRouter // 路由实例（为nil时使用BootConfig.Router），按服务类型调用RegisterHTTPRoutes/RegisterWSRoutes/RegisterGRPCRoutes
+}
+
+// listenerKey 平滑重启时监听器的继承标识（主服务为服务类型，附加服务为"类型.服务名"）
+func (spec ServerSpec) listenerKey() ServiceType {
+	if spec.Name == "" {
+		return spec.Type
+	}
+	return ServiceType(string(spec.Type) + "." + spec.Name)
+}
+
+// label 日志中的服务名称
+func (spec ServerSpec) label(kind string) string {
+	if spec.Name == "" {
+		return kind
+	}
+	return kind + "[" + spec.Name + "]"
+}
+
+// startExtraServers 启动附加服务
+func (b *BootContext) startExtraServers(specs []ServerSpec, inherited map[ServiceType]net.Listener) error {
+	seen := make(map[ServiceType]bool, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" || strings.ContainsAny(spec.Name, ".:,") {
+			return fmt.Errorf("附加服务名无效（不能为空或包含.:,）：%q", spec.Name)
+		}
+		if seen[spec.listenerKey()] {
+			return fmt.Errorf("附加服务名重复：%s %s", spec.Type, spec.Name)
+		}
+		seen[spec.listenerKey()] = true
+		if spec.AppName == "" {
+			spec.AppName = b.cfg.AppName
+		}
+		if spec.Router == nil {
+			spec.Router = b.cfg.Router
+		}
+		switch spec.Type {
+		case ServiceTypeHTTP:
+			srv, err := b.startHTTP(spec, inherited)
+			if err != nil {
+				return err
+			}
+			if b.HTTPServers == nil {
+				b.HTTPServers = make(map[string]*http.Server)
+			}
+			b.HTTPServers[spec.Name] = srv
+		case ServiceTypeWS:
+			srv, err := b.startWS(spec, inherited)
+			if err != nil {
+				return err
+			}
+			if b.WSServers == nil {
+				b.WSServers = make(map[string]*websocket.Server)
+			}
+			b.WSServers[spec.Name] = srv
+		case ServiceTypeGRPC:
+			srv, err := b.startGRPC(spec, inherited)
+			if err != nil {
+				return err
+			}
+			if b.GRPCServers == nil {
+				b.GRPCServers = make(map[string]*grpc.Server)
+			}
+			b.GRPCServers[spec.Name] = srv
+		default:
+			return fmt.Errorf("附加服务[%s]的类型不支持：%s", spec.Name, spec.Type)
+		}
+	}
+	return nil
+}
+
+// startHTTP 创建并异步启动HTTP服务
+func (b *BootContext) startHTTP(spec ServerSpec, inherited map[ServiceType]net.Listener) (*http.Server, error) {
+	srv := http.NewServer(spec.AppName)
+	//srv.Use(http.CORS(), http.Recovery())
+	if spec.Addr != "" {
+		srv.Config().Addr = spec.Addr
+	}
+	lis, err := listen(spec.listenerKey(), srv.Config().Addr, inherited)
+	if err != nil {
+		return nil, err
+	}
+	srv.SetListener(lis)
+	srv.Use(http.RequestID())
+	// 启用管理接口时拦截维护/只读模式下的请求
+	if b.Admin != nil {
+		srv.Use(http.Maintenance())
+	}
+	if tracing.Enabled() {
+		srv.Use(http.Tracing())
+	}
+	// 存活/就绪端点（配置health.enable时）
+	base.RegisterHealthRoutes(srv, spec.AppName)
+	// 注册路由
+	spec.Router.RegisterHTTPRoutes(srv)
+	// 异步启动
+	b.serve(spec.label("HTTP"), srv.Run, http.ErrServerClosed)
+	logger.Info(spec.label("HTTP"), "服务已初始化，监听地址：", srv.Config().Addr)
+	return srv, nil
+}
+
+// startWS 创建并异步启动WebSocket服务
+func (b *BootContext) startWS(spec ServerSpec, inherited map[ServiceType]net.Listener) (*websocket.Server, error) {
+	srv := websocket.NewServer(spec.AppName)
+	if spec.Addr != "" {
+		srv.Config().Addr = spec.Addr
+	}
+	// 连接上下线事件同步转发到全局事件总线
+	base.BridgeConnEvents()
+	lis, err := listen(spec.listenerKey(), srv.Config().Addr, inherited)
+	if err != nil {
+		return nil, err
+	}
+	srv.SetListener(lis)
+	srv.Use(websocket.RequestID())
+	if tracing.Enabled() {
+		srv.Use(websocket.Tracing())
+	}
+	// 注册路由
+	spec.Router.RegisterWSRoutes(srv)
+	// 异步启动
+	b.serve(spec.label("WebSocket"), srv.Run, websocket.ErrServerClosed)
+	logger.Info(spec.label("WebSocket"), "服务已初始化，监听地址：", srv.Config().Addr)
+	return srv, nil
+}
+
+// startGRPC 创建并异步启动gRPC服务
+func (b *BootContext) startGRPC(spec ServerSpec, inherited map[ServiceType]net.Listener) (*grpc.Server, error) {
+	srv := grpc.NewServer(spec.AppName)
+	if spec.Addr != "" {
+		srv.Config().Addr = spec.Addr
+	}
+	lis, err := listen(spec.listenerKey(), srv.Config().Addr, inherited)
+	if err != nil {
+		return nil, err
+	}
+	srv.SetListener(lis)
+	// 注册路由
+	spec.Router.RegisterGRPCRoutes(srv)
+	// 异步启动
+	b.serve(spec.label("gRPC"), srv.Run, nil)
+	logger.Info(spec.label("gRPC"), "服务已初始化，监听地址：", srv.Config().Addr)
+	return srv, nil
+}
+
+// startMQTT 创建并异步启动MQTT服务
+func (b *BootContext) startMQTT(spec ServerSpec, inherited map[ServiceType]net.Listener) (*mqtt.Server, error) {
+	srv := mqtt.NewServer(spec.AppName)
+	if spec.Addr != "" {
+		srv.Config().Addr = spec.Addr
+	}
+	lis, err := listen(spec.listenerKey(), srv.Config().Addr, inherited)
+	if err != nil {
+		return nil, err
+	}
+	srv.SetListener(lis)
+	srv.Use(mqtt.RequestID())
+	if tracing.Enabled() {
+		srv.Use(mqtt.Tracing())
+	}
+	// 注册路由（应用路由实现base.MQTTRouter时）
+	if router, ok := spec.Router.(base.MQTTRouter); ok {
+		router.RegisterMQTTRoutes(srv)
+	}
+	// 异步启动
+	b.serve(spec.label("MQTT"), srv.Run, mqtt.ErrServerClosed)
+	logger.Info(spec.label("MQTT"), "服务已初始化，监听地址：", srv.Config().Addr)
+	return srv, nil
+}
@@ -0,0 +1,199 @@
+// datadiff 迁移校验：对两个数据源执行同一逻辑查询，比较行数并按主键抽样逐字段比对，输出差异报告
+//
+// 用法：
+//
+//	go run github.com/dfpopp/go-dai/cmd/datadiff -config ./config/database.json \
+//		-a mysql:default/user -a-where "status = 1" \
+//		-b es:search/user -b-key id -b-where '{"term":{"status":1}}' \
+//		-sample 500 -both -ignore updated_at -map nickname=nick_name
+//
+// 数据源格式为 类型:连接标识/表名，类型支持mysql、mongodb、es；-where对MySQL为SQL条件，对MongoDB为扩展JSON过滤条件，对ES为DSL中的query部分。
+// 退出码：0一致，2存在差异，1执行失败。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/datadiff"
+	"github.com/dfpopp/go-dai/db/elasticSearch"
+	"github.com/dfpopp/go-dai/db/mongoDb"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// sourceFlags 单个数据源的命令行参数
+type sourceFlags struct {
+	spec  string
+	key   string
+	where string
+}
+
+func main() {
+	var a, b sourceFlags
+	configPath := flag.String("config", "./config/database.json", "数据库配置文件路径")
+	flag.StringVar(&a.spec, "a", "", "数据源A（如 mysql:default/user）")
+	flag.StringVar(&a.key, "a-key", "", "数据源A的主键字段（默认MySQL为id，MongoDB/ES为_id）")
+	flag.StringVar(&a.where, "a-where", "", "数据源A的查询条件")
+	flag.StringVar(&b.spec, "b", "", "数据源B（如 es:default/user）")
+	flag.StringVar(&b.key, "b-key", "", "数据源B的主键字段")
+	flag.StringVar(&b.where, "b-where", "", "数据源B的查询条件")
+	sample := flag.Int("sample", datadiff.DefaultSampleSize, "抽样主键数")
+	both := flag.Bool("both", false, "同时从B抽样，检查B中多出的数据")
+	fields := flag.String("fields", "", "只比较这些字段（按A的字段名，逗号分隔）")
+	ignore := flag.String("ignore", "", "忽略的字段（逗号分隔，以.*结尾时忽略整个子文档）")
+	fieldMap := flag.String("map", "", "字段改名映射（A字段=B字段，逗号分隔）")
+	strict := flag.Bool("strict", false, "严格比较（不做数值/时间归一）")
+	tz := flag.String("tz", "", "不带时区的时间按该时区解析（如 Asia/Shanghai，默认本机时区）")
+	format := flag.String("format", "text", "输出格式：text/json")
+	out := flag.String("out", "", "输出文件路径（为空时输出到标准输出）")
+	timeout := flag.Duration("timeout", 5*time.Minute, "整体超时")
+	flag.Parse()
+
+	opts := datadiff.Options{
+		SampleSize:    *sample,
+		Bidirectional: *both,
+		Fields:        splitList(*fields),
+		Ignore:        splitList(*ignore),
+		FieldMap:      map[string]string{},
+		Strict:        *strict,
+	}
+	for _, pair := range splitList(*fieldMap) {
+		if from, to, ok := strings.Cut(pair, "="); ok {
+			opts.FieldMap[strings.TrimSpace(from)] = strings.TrimSpace(to)
+		}
+	}
+	if *tz != "" {
+		loc, err := time.LoadLocation(*tz)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "datadiff: 时区无效：", err)
+			os.Exit(1)
+		}
+		opts.Location = loc
+	}
+	ok, err := run(*configPath, a, b, opts, *format, *out, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "datadiff:", err)
+		os.Exit(1)
+	}
+	if !ok {
+		os.Exit(2)
+	}
+}
+
+func run(configPath string, a, b sourceFlags, opts datadiff.Options, format, out string, timeout time.Duration) (bool, error) {
+	if a.spec == "" || b.spec == "" {
+		return false, fmt.Errorf("请通过 -a 与 -b 指定两个数据源")
+	}
+	if err := config.LoadDatabaseConfig(configPath); err != nil {
+		return false, fmt.Errorf("加载数据库配置失败：%w", err)
+	}
+	srcA, err := parseSource(a)
+	if err != nil {
+		return false, err
+	}
+	srcB, err := parseSource(b)
+	if err != nil {
+		return false, err
+	}
+	defer initBackends(srcA, srcB)()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	report, err := datadiff.Compare(ctx, srcA, srcB, opts)
+	if err != nil {
+		return false, err
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			return false, err
+		}
+		defer file.Close()
+		w = file
+	}
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return report.OK(), enc.Encode(report)
+	}
+	return report.OK(), report.WriteText(w)
+}
+
+// parseSource 解析 类型:连接标识/表名
+func parseSource(f sourceFlags) (datadiff.Source, error) {
+	kind, rest, ok := strings.Cut(f.spec, ":")
+	dbKey, table, ok2 := strings.Cut(rest, "/")
+	if !ok || !ok2 || dbKey == "" || table == "" {
+		return nil, fmt.Errorf("数据源格式错误（应为 类型:连接标识/表名）：%s", f.spec)
+	}
+	switch kind {
+	case "mysql":
+		return &datadiff.MySQLSource{DbKey: dbKey, Table: table, Key: f.key, Where: f.where}, nil
+	case "mongodb", "mongo":
+		src := &datadiff.MongoSource{DbKey: dbKey, Collection: table, Key: f.key}
+		if f.where != "" {
+			if err := bson.UnmarshalExtJSON([]byte(f.where), false, &src.Filter); err != nil {
+				return nil, fmt.Errorf("MongoDB查询条件解析失败：%w", err)
+			}
+		}
+		return src, nil
+	case "es":
+		src := &datadiff.ESSource{DbKey: dbKey, Index: table, Key: f.key}
+		if f.where != "" {
+			if err := json.Unmarshal([]byte(f.where), &src.Query); err != nil {
+				return nil, fmt.Errorf("ES查询条件解析失败：%w", err)
+			}
+		}
+		return src, nil
+	}
+	return nil, fmt.Errorf("不支持的数据源类型：%s", kind)
+}
+
+// initBackends 只初始化用到的数据库连接池，返回关闭函数
+func initBackends(sources ...datadiff.Source) func() {
+	closers := map[string]func() error{}
+	for _, src := range sources {
+		switch src.(type) {
+		case *datadiff.MySQLSource:
+			if closers["mysql"] == nil {
+				mysql.InitMySQL()
+				closers["mysql"] = mysql.CloseMysql
+			}
+		case *datadiff.MongoSource:
+			if closers["mongodb"] == nil {
+				mongoDb.InitMongoDB()
+				closers["mongodb"] = mongoDb.CloseMongoDb
+			}
+		case *datadiff.ESSource:
+			if closers["es"] == nil {
+				elasticSearch.InitEs()
+				closers["es"] = elasticSearch.CloseES
+			}
+		}
+	}
+	return func() {
+		for _, closeFn := range closers {
+			_ = closeFn()
+		}
+	}
+}
+
+// splitList 解析逗号分隔的列表
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
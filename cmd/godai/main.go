@@ -0,0 +1,80 @@
+// godai 框架命令行工具
+//
+// 用法：
+//
+//	go run github.com/dfpopp/go-dai/cmd/godai new [-module github.com/acme/demo] [-app api] [-version v1.2.0] [-force] ./demo
+//
+// new 生成可直接运行的应用骨架：config/app.json与database.json、实现base.BaseRouter的路由、
+// HTTP+WS共用的示例控制器/服务/模型、WS连接监听器、Dockerfile，入口main.go通过bootstrap.Boot启动。
+// 生成后执行 cd demo && go mod tidy && go run . 即可访问 http://127.0.0.1:8080/user/1 与 ws://127.0.0.1:8081/ws。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dfpopp/go-dai/bootstrap/scaffold"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "new":
+		err = runNew(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "godai: 未知命令%q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "godai:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法：godai <命令> [参数]
+
+命令：
+  new   生成应用骨架（godai new -h 查看参数）`)
+}
+
+func runNew(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	module := fs.String("module", "", "Go模块路径（默认取目录名）")
+	app := fs.String("app", "api", "应用名（配置文件中的应用键、app/下的目录名）")
+	version := fs.String("version", "", "go.mod中go-dai的版本（为空时由go mod tidy解析最新版本）")
+	force := fs.Bool("force", false, "目标目录非空时仍然生成（覆盖同名文件）")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法：godai new [参数] <目录>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	dir := fs.Arg(0)
+	generated, err := scaffold.Generate(scaffold.Options{
+		Dir:     dir,
+		Module:  *module,
+		App:     *app,
+		Version: *version,
+		Force:   *force,
+	})
+	if err != nil {
+		return err
+	}
+	for _, file := range generated {
+		fmt.Println("  create", file)
+	}
+	fmt.Printf("\n应用骨架已生成，运行：\n  cd %s\n  go mod tidy\n  go run .\n", dir)
+	return nil
+}
@@ -0,0 +1,102 @@
+// schemadoc 根据数据库配置生成数据字典（MySQL表结构 + MongoDB抽样字段），输出Markdown或HTML
+//
+// 用法：
+//
+//	go run github.com/dfpopp/go-dai/cmd/schemadoc -config ./config/database.json -mysql default -mongo default -format html -out docs/schema.html
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/mongoDb"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"github.com/dfpopp/go-dai/db/schemadoc"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func main() {
+	configPath := flag.String("config", "./config/database.json", "数据库配置文件路径")
+	mysqlKeys := flag.String("mysql", "", "MySQL连接标识，多个以逗号分隔，*表示全部")
+	mongoKeys := flag.String("mongo", "", "MongoDB连接标识，多个以逗号分隔，*表示全部")
+	sampleSize := flag.Int("sample", schemadoc.DefaultSampleSize, "MongoDB每个集合抽样文档数")
+	format := flag.String("format", schemadoc.FormatMarkdown, "输出格式：md/html")
+	title := flag.String("title", "数据字典", "文档标题")
+	out := flag.String("out", "", "输出文件路径（为空时输出到标准输出）")
+	timeout := flag.Duration("timeout", 2*time.Minute, "整体超时")
+	flag.Parse()
+
+	if err := run(*configPath, *mysqlKeys, *mongoKeys, *sampleSize, *format, *title, *out, *timeout); err != nil {
+		fmt.Fprintln(os.Stderr, "schemadoc:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, mysqlKeys, mongoKeys string, sampleSize int, format, title, out string, timeout time.Duration) error {
+	if err := config.LoadDatabaseConfig(configPath); err != nil {
+		return fmt.Errorf("加载数据库配置失败：%w", err)
+	}
+	opts := schemadoc.Options{
+		Title:      title,
+		MySQL:      resolveKeys(mysqlKeys, mapKeys(config.GetMysqlConfig())),
+		Mongo:      resolveKeys(mongoKeys, mapKeys(config.GetMongodbConfig())),
+		SampleSize: sampleSize,
+	}
+	if len(opts.MySQL) == 0 && len(opts.Mongo) == 0 {
+		return fmt.Errorf("请通过 -mysql 或 -mongo 指定至少一个连接")
+	}
+	if len(opts.MySQL) > 0 {
+		mysql.InitMySQL()
+		defer mysql.CloseMysql()
+	}
+	if len(opts.Mongo) > 0 {
+		mongoDb.InitMongoDB()
+		defer mongoDb.CloseMongoDb()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	schema, err := schemadoc.Generate(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return schemadoc.Render(w, schema, format)
+}
+
+// resolveKeys 解析逗号分隔的连接标识（*表示配置中的全部连接）
+func resolveKeys(value string, all []string) []string {
+	if value == "*" {
+		return all
+	}
+	keys := make([]string, 0)
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func mapKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+// tablegen 根据MySQL表结构生成表名常量与字段结构体，传给SetTable/SetField的表名、字段名在编译期检查
+//
+// 用法：
+//
+//	go run github.com/dfpopp/go-dai/cmd/tablegen -config ./config/database.json -mysql default -pkg model -out app/model/tables_gen.go
+//
+// 生成代码示例：db.SetTable(model.User.Table).SetField(model.User.Col.All()).SetWhere(model.User.Col.Name+" = ?", name)
+// 可在项目中添加 //go:generate 指令，表结构变更后执行 go generate 重新生成。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"github.com/dfpopp/go-dai/db/schemadoc"
+	"github.com/dfpopp/go-dai/db/tablegen"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	configPath := flag.String("config", "./config/database.json", "数据库配置文件路径")
+	dbKey := flag.String("mysql", "default", "MySQL连接标识")
+	pkg := flag.String("pkg", "", "生成代码的包名（默认取输出目录名，输出到标准输出时为model）")
+	tables := flag.String("tables", "", "只生成这些表（不含前缀，逗号分隔，支持通配符如 user_*）")
+	out := flag.String("out", "", "输出文件路径（为空时输出到标准输出）")
+	timeout := flag.Duration("timeout", time.Minute, "整体超时")
+	flag.Parse()
+
+	if err := run(*configPath, *dbKey, *pkg, *tables, *out, *timeout); err != nil {
+		fmt.Fprintln(os.Stderr, "tablegen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, dbKey, pkg, tables, out string, timeout time.Duration) error {
+	if err := config.LoadDatabaseConfig(configPath); err != nil {
+		return fmt.Errorf("加载数据库配置失败：%w", err)
+	}
+	cfg, ok := config.GetMysqlConfig()[dbKey]
+	if !ok {
+		return fmt.Errorf("MySQL连接不存在：%s", dbKey)
+	}
+	mysql.InitMySQL()
+	defer mysql.CloseMysql()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	schema, err := schemadoc.InspectMySQL(ctx, dbKey)
+	if err != nil {
+		return err
+	}
+	if pkg == "" && out != "" {
+		if abs, err := filepath.Abs(out); err == nil {
+			pkg = strings.ReplaceAll(filepath.Base(filepath.Dir(abs)), "-", "_")
+		}
+	}
+	src, err := tablegen.Generate(schema, tablegen.Options{
+		Package: pkg,
+		Prefix:  cfg.Pre,
+		Tables:  splitList(tables),
+		Source:  "mysql:" + dbKey,
+	})
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
+
+// splitList 解析逗号分隔的列表
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
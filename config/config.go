@@ -1,9 +1,6 @@
 package config
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
 	"sync"
 )
 
@@ -13,52 +10,506 @@ type AppConfig struct {
 	HTTP      HTTPConfig      `json:"http"`
 	WebSocket WebSocketConfig `json:"websocket"`
 	GRPC      GRPCConfig      `json:"grpc"`
+	MQTT      MQTTConfig      `json:"mqtt"`
 	Logger    LoggerConfig    `json:"logger"`
+	Tracing   TracingConfig   `json:"tracing"`
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	JWT       JWTConfig       `json:"jwt"`
+	Session   SessionConfig   `json:"session"`
+	OAuth     OAuthConfig     `json:"oauth"`
+	Debug     DebugConfig     `json:"debug"`
+	Health    HealthConfig    `json:"health"`
+	DbWarmup  DbWarmupConfig  `json:"db_warmup"`
+	DbChaos   DbChaosConfig   `json:"db_chaos"`
+	Admin     AdminConfig     `json:"admin"`
+	Schema    SchemaConfig    `json:"schema"`
+	APIKey    APIKeyConfig    `json:"api_key"`
+	RBAC      RBACConfig      `json:"rbac"`
+	Scheduler SchedulerConfig `json:"scheduler"`
+	Queue     QueueConfig     `json:"queue"`
+	Discovery DiscoveryConfig `json:"discovery"`
+	Features  map[string]bool `json:"features"` // 功能开关默认值（可由管理接口在线覆盖，读取见FeatureEnabled）
+
+	// HTTPClients 调用第三方HTTP接口的命名客户端（通过httpclient.GetClient获取）
+	HTTPClients map[string]HTTPClientConfig `json:"http_clients"`
 }
 
 // HTTPConfig HTTP配置
 type HTTPConfig struct {
-	Addr           string `json:"addr"`
-	ReadTimeout    int    `json:"read_timeout"`
-	WriteTimeout   int    `json:"write_timeout"`
-	MaxHeaderBytes int    `json:"max_header_bytes"`
-	SSL            bool   `json:"ssl"`
-	SSLCertFile    string `json:"ssl_cert_file"`
-	SSLKeyFile     string `json:"ssl_key_file"`
+	Addr              string                    `json:"addr"`
+	ReadTimeout       int                       `json:"read_timeout"`
+	ReadHeaderTimeout int                       `json:"read_header_timeout"` // 读取请求头超时（秒，默认与ReadTimeout一致）
+	WriteTimeout      int                       `json:"write_timeout"`
+	IdleTimeout       int                       `json:"idle_timeout"`     // Keep-Alive空闲连接超时（秒，默认与ReadTimeout一致）
+	ShutdownTimeout   int                       `json:"shutdown_timeout"` // 停机时等待处理中请求完成的超时（秒，默认30）
+	DetachTimeout     int                       `json:"detach_timeout"`   // c.Detach()/c.Go()后台任务context的最长存活时间（秒，默认30）
+	MaxHeaderBytes    int                       `json:"max_header_bytes"`
+	SSL               bool                      `json:"ssl"`
+	SSLCertFile       string                    `json:"ssl_cert_file"`
+	SSLKeyFile        string                    `json:"ssl_key_file"`
+	CORS              CORSConfig                `json:"cors"`            // 跨域配置（未配置时允许所有来源）
+	MaxBodySize       int64                     `json:"max_body_size"`   // 请求体上限（字节，0表示不启用BodyLimit中间件）
+	HandlerTimeout    int                       `json:"handler_timeout"` // 处理器超时（秒，0表示不启用Timeout中间件）
+	RouteLimits       map[string]HTTPRouteLimit `json:"route_limits"`    // 按路径单独配置请求体上限与超时（以*结尾时按前缀匹配）
+	AccessLog         AccessLogConfig           `json:"access_log"`      // 访问日志
+	MTLS              MTLSConfig                `json:"mtls"`            // 客户端证书校验（ssl为true时生效）
+	Template          HTTPTemplateConfig        `json:"template"`        // HTML模板（配置dir后启用c.HTML）
+}
+
+// HTTPTemplateConfig HTML模板配置
+type HTTPTemplateConfig struct {
+	Dir      string `json:"dir"`      // 模板根目录
+	Ext      string `json:"ext"`      // 模板扩展名（默认.html）
+	Layout   string `json:"layout"`   // 默认布局文件（相对dir，如layouts/base.html）
+	Partials string `json:"partials"` // 公共片段目录（相对dir，默认partials）
+	Reload   bool   `json:"reload"`   // 每次渲染重新解析模板（env为dev时自动开启）
+}
+
+// AccessLogConfig HTTP访问日志配置
+type AccessLogConfig struct {
+	Enable        bool               `json:"enable"`
+	Format        string             `json:"format"`         // combined（默认）/json
+	SampleRate    float64            `json:"sample_rate"`    // 采样率（0~1，默认1）
+	Routes        map[string]float64 `json:"routes"`         // 按路径单独配置采样率（0表示不记录）
+	SlowThreshold int                `json:"slow_threshold"` // 慢请求阈值（毫秒，超过时不受采样限制）
+	SampleErrors  bool               `json:"sample_errors"`  // 错误请求也参与采样（默认总是记录）
+}
+
+// HTTPRouteLimit 单个路径的请求体上限与超时（0表示沿用全局配置，-1表示该路径不限制）
+type HTTPRouteLimit struct {
+	MaxBodySize int64 `json:"max_body_size"` // 字节
+	Timeout     int   `json:"timeout"`       // 秒
+}
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	AllowOrigins     []string `json:"allow_origins"`     // 允许的来源（支持 * 与子域通配 https://*.example.com）
+	AllowMethods     []string `json:"allow_methods"`     // 允许的方法（为空使用默认列表）
+	AllowHeaders     []string `json:"allow_headers"`     // 允许的请求头（为空时回显预检请求头）
+	ExposeHeaders    []string `json:"expose_headers"`    // 允许前端读取的响应头
+	AllowCredentials bool     `json:"allow_credentials"` // 是否允许携带凭证
+	MaxAge           int      `json:"max_age"`           // 预检结果缓存时长（秒）
 }
 
 // WebSocketConfig WebSocket服务器配置
 type WebSocketConfig struct {
-	Addr             string `json:"addr"`              // 监听地址（ip:port）
-	ReadTimeout      int    `json:"read_timeout"`      // 读超时（秒）
-	WriteTimeout     int    `json:"write_timeout"`     // 写超时（秒）
-	Path             string `json:"path"`              // WebSocket监听路径（如：/ws）
-	Origin           string `json:"origin"`            // 允许的来源（* 表示允许所有）
-	HandshakeTimeout int    `json:"handshake_timeout"` // 握手超时（秒）
-	MaxMessageSize   int64  `json:"max_message_size"`  // 最大消息大小（字节，默认1MB）
-	MaxConnections   int32  `json:"max_connections"`   // 最大连接数（默认1000）
-	SSL              bool   `json:"ssl"`               //是否启用SSL/TLS（启用后为WSS，禁用为WS）
-	SSLCertFile      string `json:"ssl_cert_file"`     //SSL证书路径（如：./cert/server.crt）
-	SSLKeyFile       string `json:"ssl_key_file"`      //SSL密钥路径（如：./cert/server.key）
+	Addr                 string          `json:"addr"`                  // 监听地址（ip:port）
+	ReadTimeout          int             `json:"read_timeout"`          // 读超时（秒）
+	WriteTimeout         int             `json:"write_timeout"`         // 写超时（秒）
+	Path                 string          `json:"path"`                  // WebSocket监听路径（如：/ws）
+	Origin               string          `json:"origin"`                // 允许的来源（* 表示允许所有，多个以逗号分隔，支持子域通配 https://*.example.com）
+	HandshakeTimeout     int             `json:"handshake_timeout"`     // 握手超时（秒）
+	MaxMessageSize       int64           `json:"max_message_size"`      // 最大消息大小（字节，默认1MB）
+	MaxConnections       int32           `json:"max_connections"`       // 最大连接数（默认1000）
+	SSL                  bool            `json:"ssl"`                   //是否启用SSL/TLS（启用后为WSS，禁用为WS）
+	SSLCertFile          string          `json:"ssl_cert_file"`         //SSL证书路径（如：./cert/server.crt）
+	SSLKeyFile           string          `json:"ssl_key_file"`          //SSL密钥路径（如：./cert/server.key）
+	SendQueueSize        int             `json:"send_queue_size"`       // 每个连接的发送队列长度（默认256）
+	SlowPolicy           string          `json:"slow_policy"`           // 发送队列已满时的策略：close（断开慢连接，默认）/drop（丢弃消息）
+	PingInterval         int             `json:"ping_interval"`         // 服务端ping间隔（秒，默认30，-1表示不发送）
+	PongTimeout          int             `json:"pong_timeout"`          // 等待pong的超时（秒，默认10）
+	IdleTimeout          int             `json:"idle_timeout"`          // 无业务消息的空闲断开时长（秒，0表示不限制）
+	Compression          bool            `json:"compression"`           // 是否启用permessage-deflate压缩（客户端提议时）
+	CompressionThreshold int             `json:"compression_threshold"` // 压缩阈值（字节，默认1024）
+	CompressionLevel     int             `json:"compression_level"`     // 压缩级别（1-9，默认1）
+	StreamChunkSize      int             `json:"stream_chunk_size"`     // WriteMessageStream分片大小（字节，默认32KB）
+	ShutdownTimeout      int             `json:"shutdown_timeout"`      // 停止时等待客户端响应关闭帧的时长（秒，默认10）
+	Cluster              WSClusterConfig `json:"cluster"`               // 多节点连接注册与消息中继（基于Redis）
+	MTLS                 MTLSConfig      `json:"mtls"`                  // 客户端证书校验（ssl为true时生效）
+	Delay                WSDelayConfig   `json:"delay"`                 // 定时/延迟消息（SendToUserAt/SendToUserAfter）
+	History              WSHistoryConfig `json:"history"`               // 房间消息历史（新成员补发与_history拉取）
+}
+
+// MTLSConfig 双向TLS配置（HTTP/WS/gRPC共用）：要求并校验客户端证书
+type MTLSConfig struct {
+	Enable       bool     `json:"enable"`
+	ClientCAFile string   `json:"client_ca_file"` // 签发客户端证书的CA（PEM，可包含多个证书）
+	Optional     bool     `json:"optional"`       // 为true时客户端可不提供证书（提供时仍须校验通过）
+	AllowedNames []string `json:"allowed_names"`  // CN/SAN白名单（为空时不限制），支持"*.svc.local"与以*结尾的前缀匹配
+	CRLFile      string   `json:"crl_file"`       // 证书吊销列表（PEM或DER，可包含多个CA的CRL，为空时不检查）
+	CRLRefresh   int      `json:"crl_refresh"`    // CRL文件变更检查间隔（秒，默认300）
+	OCSP         string   `json:"ocsp"`           // OCSP在线吊销检查：为空不检查；soft（查询失败时放行）；hard（查询失败时拒绝）
+	OCSPTimeout  int      `json:"ocsp_timeout"`   // OCSP查询超时（秒，默认3）
+}
+
+// WSClusterConfig WS多节点中继配置
+type WSClusterConfig struct {
+	Enable  bool   `json:"enable"`
+	RedisDb string `json:"redis_db"` // Redis连接标识
+	NodeID  string `json:"node_id"`  // 节点ID（默认主机名-进程号-随机串）
+	Prefix  string `json:"prefix"`   // 键与频道前缀（默认ws:）
+	TTL     int    `json:"ttl"`      // 连接注册有效期（秒，默认60）
+}
+
+// WSDelayConfig WS定时/延迟消息配置
+type WSDelayConfig struct {
+	Enable       bool   `json:"enable"`
+	RedisDb      string `json:"redis_db"`      // Redis连接标识（为空时使用进程内队列，重启后未到期的消息丢失）
+	Prefix       string `json:"prefix"`        // 键前缀（默认ws:）
+	PollInterval int    `json:"poll_interval"` // 到期检查间隔（毫秒，默认1000）
+	Batch        int    `json:"batch"`         // 每次最多取出的到期消息数（默认100）
+	OfflineTTL   int    `json:"offline_ttl"`   // 到期时用户不在线的消息在离线队列中的保留时长（秒，默认7天，-1表示直接丢弃）
+	OfflineMax   int    `json:"offline_max"`   // 每个用户离线队列的最大长度（默认100，超出时丢弃最早的）
+}
+
+// WSHistoryConfig WS房间消息历史配置
+type WSHistoryConfig struct {
+	Enable  bool   `json:"enable"`
+	RedisDb string `json:"redis_db"` // Redis连接标识（为空时使用进程内环形缓冲区，多节点不共享、重启后丢失）
+	Prefix  string `json:"prefix"`   // 键前缀（默认ws:）
+	Size    int    `json:"size"`     // 每个房间保留的消息条数（默认100）
+	Replay  int    `json:"replay"`   // 新成员加入房间时补发的最近消息条数（默认0，不补发）
+	TTL     int    `json:"ttl"`      // 房间无新消息后历史的保留时长（秒，默认7天，仅Redis）
+}
+
+// MQTTConfig MQTT服务配置（面向IoT设备的内置broker，客户端发布的消息按主题路由到处理器）
+type MQTTConfig struct {
+	Addr            string     `json:"addr"`             // 监听地址（ip:port，默认:1883）
+	SSL             bool       `json:"ssl"`              // 是否启用TLS（MQTTS）
+	SSLCertFile     string     `json:"ssl_cert_file"`    // TLS证书路径
+	SSLKeyFile      string     `json:"ssl_key_file"`     // TLS密钥路径
+	MTLS            MTLSConfig `json:"mtls"`             // 客户端证书校验（ssl为true时生效）
+	MaxConnections  int32      `json:"max_connections"`  // 最大连接数（默认10000）
+	MaxMessageSize  int        `json:"max_message_size"` // 最大报文大小（字节，默认256KB）
+	ConnectTimeout  int        `json:"connect_timeout"`  // 建连后等待CONNECT报文的超时（秒，默认10）
+	WriteTimeout    int        `json:"write_timeout"`    // 写超时（秒，默认10）
+	SendQueueSize   int        `json:"send_queue_size"`  // 每个客户端的发送队列长度（默认256，已满时断开）
+	MaxInflight     int        `json:"max_inflight"`     // 每个客户端未确认的QoS1下行消息上限（默认1000，超出时断开）
+	ReplyTopic      string     `json:"reply_topic"`      // 处理器回复的主题模板（默认{topic}/reply，支持{topic}、{client_id}，-表示不回复）
+	ShutdownTimeout int        `json:"shutdown_timeout"` // 停止时等待处理中消息完成的时长（秒，默认10）
 }
 
 // GRPCConfig gRPC配置
 type GRPCConfig struct {
-	Addr                 string `json:"addr"`
-	MaxRecvMsgSize       int    `json:"max_recv_msg_size"`
-	MaxSendMsgSize       int    `json:"max_send_msg_size"`
-	KeepaliveTime        int    `json:"keepalive_time"`         // 新增：保活时间（秒）
-	KeepaliveTimeout     int    `json:"keepalive_timeout"`      // 新增：保活超时（秒）
-	MaxConcurrentStreams uint32 `json:"max_concurrent_streams"` // 新增：最大并发流数
-	Timeout              int    `json:"timeout"`
-	SSL                  bool   `json:"ssl"`
-	SSLCertFile          string `json:"ssl_cert_file"`
-	SSLKeyFile           string `json:"ssl_key_file"`
+	Addr                 string          `json:"addr"`
+	MaxRecvMsgSize       int             `json:"max_recv_msg_size"`
+	MaxSendMsgSize       int             `json:"max_send_msg_size"`
+	KeepaliveTime        int             `json:"keepalive_time"`         // 新增：保活时间（秒）
+	KeepaliveTimeout     int             `json:"keepalive_timeout"`      // 新增：保活超时（秒）
+	MaxConcurrentStreams uint32          `json:"max_concurrent_streams"` // 新增：最大并发流数
+	Timeout              int             `json:"timeout"`
+	SSL                  bool            `json:"ssl"`
+	SSLCertFile          string          `json:"ssl_cert_file"`
+	SSLKeyFile           string          `json:"ssl_key_file"`
+	Quota                GRPCQuotaConfig `json:"quota"`           // 按调用方身份的配额（基于Redis）
+	Reflection           *bool           `json:"reflection"`      // 是否注册反射服务（未配置时非prod环境开启）
+	MTLS                 MTLSConfig      `json:"mtls"`            // 客户端证书校验（ssl为true时生效）
+	MaxConnections       int             `json:"max_connections"` // 最大同时连接数（0不限制）
+	// 连接寿命（秒，0不限制）：到期以GOAWAY关闭，客户端重连后重新均衡到各节点
+	MaxConnectionIdle     int `json:"max_connection_idle"`
+	MaxConnectionAge      int `json:"max_connection_age"`
+	MaxConnectionAgeGrace int `json:"max_connection_age_grace"`
+	// 客户端保活策略：允许的ping最小间隔（秒，默认300）及是否允许无活跃调用时ping
+	KeepaliveMinTime             int  `json:"keepalive_min_time"`
+	KeepalivePermitWithoutStream bool `json:"keepalive_permit_without_stream"`
+	// Clients 服务间调用的命名客户端（grpc.GetClient按名称获取）
+	Clients map[string]GRPCClientConfig `json:"clients"`
+}
+
+// HTTPClientConfig HTTP客户端配置
+type HTTPClientConfig struct {
+	BaseURL               string            `json:"base_url"`                   // 基础地址（请求使用相对路径时拼接）
+	Headers               map[string]string `json:"headers"`                    // 默认请求头（如鉴权、User-Agent）
+	Timeout               int               `json:"timeout"`                    // 单次调用超时（毫秒，默认10000，含重试；调用方context的截止时间更早时以其为准）
+	MaxRetries            int               `json:"max_retries"`                // 幂等请求最大重试次数（默认0不重试）
+	RetryBackoff          int               `json:"retry_backoff"`              // 首次重试间隔（毫秒，默认100，按指数退避并加随机抖动）
+	RetryStatus           []int             `json:"retry_status"`               // 可重试的响应状态码（默认429/502/503/504）
+	MaxBodySize           int64             `json:"max_body_size"`              // 响应体上限（字节，默认10MB）
+	MaxIdleConnNum        int               `json:"max_idle_conn_num"`          // 最大空闲连接数（默认100）
+	MaxIdleConnNumPerHost int               `json:"max_idle_conn_num_per_host"` // 每个主机最大空闲连接数（默认10）
+	MaxConnNumPerHost     int               `json:"max_conn_num_per_host"`      // 每个主机最大连接数（默认0不限制）
+	IdleConnTimeout       int               `json:"idle_conn_timeout"`          // 空闲连接超时（秒，默认90）
+	DialTimeout           int               `json:"dial_timeout"`               // 建立连接超时（秒，默认5）
+	ResponseHeaderTimeout int               `json:"response_header_timeout"`    // 等待响应头超时（秒，默认0不单独限制）
+	SSLCAFile             string            `json:"ssl_ca_file"`                // 校验服务端证书的CA（为空时使用系统根证书）
+	// 客户端证书（对方要求双向TLS时提供）
+	SSLCertFile string `json:"ssl_cert_file"`
+	SSLKeyFile  string `json:"ssl_key_file"`
+}
+
+// GRPCClientConfig gRPC客户端配置
+type GRPCClientConfig struct {
+	Target           string   `json:"target"`            // 目标地址（host:port，或dns:///host:port等gRPC解析格式；启用服务发现时可用discovery:///服务名）
+	PoolSize         int      `json:"pool_size"`         // 连接数（默认1，多连接轮询使用，分摊单连接的并发流上限）
+	Timeout          int      `json:"timeout"`           // 单次调用超时（毫秒，默认5000；调用方context的截止时间更早时以其为准）
+	MaxRetries       int      `json:"max_retries"`       // 一元调用最大重试次数（默认0不重试）
+	RetryBackoff     int      `json:"retry_backoff"`     // 首次重试间隔（毫秒，默认100，按指数退避并加随机抖动）
+	RetryCodes       []string `json:"retry_codes"`       // 可重试的状态码（默认UNAVAILABLE）
+	MaxRecvMsgSize   int      `json:"max_recv_msg_size"` // 最大接收消息大小
+	MaxSendMsgSize   int      `json:"max_send_msg_size"` // 最大发送消息大小
+	KeepaliveTime    int      `json:"keepalive_time"`    // 空闲保活ping间隔（秒，0为不发送）
+	KeepaliveTimeout int      `json:"keepalive_timeout"` // 保活ping超时（秒，默认20）
+	SSL              bool     `json:"ssl"`               // 是否使用TLS
+	SSLCAFile        string   `json:"ssl_ca_file"`       // 校验服务端证书的CA（为空时使用系统根证书）
+	SSLServerName    string   `json:"ssl_server_name"`   // 校验证书的服务端名称（默认取目标地址的主机名）
+	// 客户端证书（服务端启用mtls时提供）
+	SSLCertFile string `json:"ssl_cert_file"`
+	SSLKeyFile  string `json:"ssl_key_file"`
+}
+
+// GRPCQuotaConfig gRPC调用配额配置
+type GRPCQuotaConfig struct {
+	Enable          bool                            `json:"enable"`
+	RedisDb         string                          `json:"redis_db"`         // 保存计数的Redis连接key
+	Window          int                             `json:"window"`           // 滚动窗口（秒，默认3600）
+	MaxRequests     int64                           `json:"max_requests"`     // 窗口内最大请求数（0表示不限制）
+	MaxBytes        int64                           `json:"max_bytes"`        // 窗口内最大流量字节数（0表示不限制）
+	IdentityKeys    []string                        `json:"identity_keys"`    // 读取身份的元数据key（默认 x-api-key、x-user-id）
+	RequireIdentity bool                            `json:"require_identity"` // 无身份的请求是否拒绝
+	Overrides       map[string]GRPCQuotaLimitConfig `json:"overrides"`        // 按身份单独配置
+}
+
+// GRPCQuotaLimitConfig 单个身份的配额
+type GRPCQuotaLimitConfig struct {
+	MaxRequests int64 `json:"max_requests"`
+	MaxBytes    int64 `json:"max_bytes"`
+}
+
+// TracingConfig 链路追踪配置（OpenTelemetry）
+type TracingConfig struct {
+	Enable           bool   `json:"enable"`            // 是否启用链路追踪
+	ServiceName      string `json:"service_name"`      // 服务名（默认取应用名）
+	CaptureStatement bool   `json:"capture_statement"` // 是否在数据库span中记录语句摘要
+}
+
+// DebugConfig 诊断端口配置（pprof、expvar、协程与GC信息，独立监听，生产环境请仅绑定内网地址）
+type DebugConfig struct {
+	Enable   bool     `json:"enable"`    // 是否启用
+	Addr     string   `json:"addr"`      // 监听地址（默认127.0.0.1:6060）
+	Token    string   `json:"token"`     // 访问令牌（请求头X-Debug-Token或查询参数token）
+	AllowIPs []string `json:"allow_ips"` // 允许访问的IP/CIDR（与Token均未配置时仅允许本机访问）
+}
+
+// HealthConfig 健康检查端点配置（注册在HTTP服务上，供负载均衡器与Kubernetes探针使用）
+type HealthConfig struct {
+	Enable    bool   `json:"enable"`     // 是否启用
+	LivePath  string `json:"live_path"`  // 存活检查路径（默认/healthz，只要进程可响应即返回200）
+	ReadyPath string `json:"ready_path"` // 就绪检查路径（默认/readyz，汇总数据库与应用注册的自检项）
+	Timeout   int    `json:"timeout"`    // 就绪检查的超时（秒，默认5），超时未完成的检查项视为失败
+}
+
+// AdminConfig 运行时管理接口配置（在线调整日志级别、功能开关、维护/只读模式，独立监听，生产环境请仅绑定内网地址）
+type AdminConfig struct {
+	Enable   bool     `json:"enable"`    // 是否启用
+	Addr     string   `json:"addr"`      // 监听地址（默认127.0.0.1:6061）
+	Token    string   `json:"token"`     // 访问令牌（请求头X-Admin-Token）
+	AllowIPs []string `json:"allow_ips"` // 允许访问的IP/CIDR（与Token均未配置时仅允许本机访问）
+	RedisDb  string   `json:"redis_db"`  // 持久化覆盖项并在实例间同步的Redis连接key（为空时仅对当前实例生效且重启后丢失）
+	Prefix   string   `json:"prefix"`    // Redis键前缀（默认admin:）
+}
+
+// SchemaConfig 载荷结构注册中心配置（gRPC强类型方法与消息队列共用）
+type SchemaConfig struct {
+	Enable        bool              `json:"enable"`        // 是否启用（schema.Default()返回全局注册中心）
+	Store         string            `json:"store"`         // 存储：redis（多服务共享，默认）/memory（单进程）
+	RedisDb       string            `json:"redis_db"`      // store为redis时使用的Redis连接key
+	Prefix        string            `json:"prefix"`        // Redis键前缀（默认schema:）
+	Compatibility string            `json:"compatibility"` // 默认兼容性策略：BACKWARD（默认）/FORWARD/FULL/NONE
+	Transitive    bool              `json:"transitive"`    // 与全部历史版本比对（默认仅比对最新版本）
+	CacheTTL      int               `json:"cache_ttl"`     // 最新版本的本地缓存时长（秒，默认30）
+	Subjects      map[string]string `json:"subjects"`      // 按subject覆盖兼容性策略
+}
+
+// RateLimitConfig 限流配置（HTTP/WS/gRPC共用）
+type RateLimitConfig struct {
+	Enable  bool                     `json:"enable"`   // 是否启用
+	Store   string                   `json:"store"`    // 存储：memory（单实例令牌桶，默认）/redis（集群滑动窗口）
+	RedisDb string                   `json:"redis_db"` // store为redis时使用的Redis连接key
+	Key     string                   `json:"key"`      // 限流维度：ip/route/user，可用逗号组合（如 user,route，默认ip）
+	Rate    int                      `json:"rate"`     // 每个周期允许的请求数
+	Period  int                      `json:"period"`   // 周期（秒，默认1）
+	Burst   int                      `json:"burst"`    // 令牌桶容量（仅memory，默认等于rate）
+	Routes  map[string]RateLimitRule `json:"routes"`   // 按HTTP路径/WS action/gRPC方法单独配置（按路由独立计数）
+
+	// 按IP限流默认取连接的对端IP；部署在反向代理之后时列出代理地址（IP或CIDR），仅对这些对端采信X-Forwarded-For/X-Real-IP
+	TrustedProxies []string `json:"trusted_proxies"`
+	// 内存令牌桶最多保留的计数键（默认100000，超出时淘汰最久未访问的键），避免大量不同IP/用户耗尽内存
+	MaxKeys int `json:"max_keys"`
+}
+
+// RateLimitRule 单条限流规则
+type RateLimitRule struct {
+	Rate   int `json:"rate"`
+	Period int `json:"period"`
+	Burst  int `json:"burst"`
+}
+
+// APIKeyConfig API Key认证配置（面向合作方的接口）
+type APIKeyConfig struct {
+	Enable   bool   `json:"enable"`    // 是否启用
+	Store    string `json:"store"`     // 存储：mysql/redis（必填）
+	MysqlDb  string `json:"mysql_db"`  // store为mysql时使用的MySQL连接key
+	Table    string `json:"table"`     // store为mysql时的表名（默认api_key，会拼接表前缀）
+	RedisDb  string `json:"redis_db"`  // store为redis时使用的Redis连接key；同时作为按密钥限流的共享计数（为空时使用内存令牌桶）
+	Header   string `json:"header"`    // 读取密钥的请求头（默认X-API-Key）
+	Prefix   string `json:"prefix"`    // 签发的密钥前缀（默认dai）
+	CacheTTL int    `json:"cache_ttl"` // 校验结果本地缓存时长（秒，默认30，-1不缓存）
+	Rate     int    `json:"rate"`      // 未单独配置限流的密钥每个周期允许的请求数（0不限流）
+	Period   int    `json:"period"`    // 限流周期（秒，默认1）
+}
+
+// SchedulerConfig 定时任务配置（任务在代码中通过BootConfig.Jobs注册）
+type SchedulerConfig struct {
+	Disable  bool                          `json:"disable"`  // 当前实例不执行定时任务
+	RedisDb  string                        `json:"redis_db"` // 分布式锁使用的Redis连接key（为空时不加锁，每个实例都执行）
+	LockTTL  int                           `json:"lock_ttl"` // 锁过期时长（秒，默认60，任务执行期间自动续期）
+	Timezone string                        `json:"timezone"` // cron表达式的时区（如Asia/Shanghai，默认本地时区）
+	Jobs     map[string]SchedulerJobConfig `json:"jobs"`     // 按任务名覆盖代码中的配置
+}
+
+// SchedulerJobConfig 单个定时任务的配置覆盖
+type SchedulerJobConfig struct {
+	Spec    string `json:"spec"`    // 替换执行时间（cron表达式或@every）
+	Disable bool   `json:"disable"` // 停用该任务
+}
+
+// QueueConfig 任务队列配置（基于Redis Stream）
+type QueueConfig struct {
+	RedisDb     string                     `json:"redis_db"`    // Redis连接key
+	Prefix      string                     `json:"prefix"`      // 键前缀（默认queue:）
+	Group       string                     `json:"group"`       // 消费组（默认应用名，同一消费组的实例分摊消息）
+	Concurrency int                        `json:"concurrency"` // 每个队列的并发处理数（默认10）
+	MaxRetries  int                        `json:"max_retries"` // 失败重试次数（默认3，-1不重试），用尽后进入死信队列
+	Backoff     int                        `json:"backoff"`     // 首次重试的等待时长（毫秒，默认1000，之后每次翻倍）
+	Timeout     int                        `json:"timeout"`     // 单次处理超时（秒，默认60）
+	ClaimIdle   int                        `json:"claim_idle"`  // 已投递但未确认的消息超过该时长（秒，默认300）由其他消费者接管（消费者崩溃恢复）
+	MaxLen      int64                      `json:"max_len"`     // 队列最大长度（近似裁剪，0不限制）
+	Disable     bool                       `json:"disable"`     // 当前实例不消费（仍可投递）
+	Queues      map[string]QueueItemConfig `json:"queues"`      // 按队列名覆盖并发、重试与超时
+}
+
+// QueueItemConfig 单个队列的配置覆盖（0表示沿用全局配置）
+type QueueItemConfig struct {
+	Concurrency int `json:"concurrency"`
+	MaxRetries  int `json:"max_retries"`
+	Timeout     int `json:"timeout"`
+}
+
+// DiscoveryConfig 服务注册与发现配置
+type DiscoveryConfig struct {
+	Enable        bool              `json:"enable"`
+	Driver        string            `json:"driver"`         // etcd/consul
+	Endpoints     []string          `json:"endpoints"`      // etcd节点地址或Consul地址（如 http://127.0.0.1:2379）
+	Username      string            `json:"username"`       // etcd认证用户名
+	Password      string            `json:"password"`       // etcd认证密码
+	Token         string            `json:"token"`          // Consul ACL Token
+	Datacenter    string            `json:"datacenter"`     // Consul数据中心
+	Prefix        string            `json:"prefix"`         // etcd键前缀（默认/go-dai/services）
+	TTL           int               `json:"ttl"`            // 注册有效期（秒，默认15，每TTL/3续约一次）
+	ServiceName   string            `json:"service_name"`   // 注册的服务名（默认应用名）
+	AdvertiseHost string            `json:"advertise_host"` // 注册的主机地址（默认取监听地址的主机，为空时自动探测本机IP）
+	Tags          []string          `json:"tags"`           // 实例标签
+	Metadata      map[string]string `json:"metadata"`       // 实例元数据（如版本、机房）
+	Disable       bool              `json:"disable"`        // 当前实例不注册（仍可发现其他服务）
+}
+
+// JWTConfig JWT认证配置
+type JWTConfig struct {
+	Algorithm      string `json:"algorithm"`        // HS256（默认）/RS256
+	Secret         string `json:"secret"`           // HS256密钥
+	PrivateKeyFile string `json:"private_key_file"` // RS256私钥文件（仅签发方需要）
+	PublicKeyFile  string `json:"public_key_file"`  // RS256公钥文件
+	Issuer         string `json:"issuer"`
+	Audience       string `json:"audience"`
+	AccessTTL      int    `json:"access_ttl"`  // 访问令牌有效期（秒，默认7200）
+	RefreshTTL     int    `json:"refresh_ttl"` // 刷新令牌有效期（秒，默认7天）
+	Leeway         int    `json:"leeway"`      // 时钟偏差容忍（秒）
+	RedisDb        string `json:"redis_db"`    // 刷新令牌轮换记录使用的Redis连接key（为空时不启用轮换）
+}
+
+// RBACConfig 基于角色的访问控制配置（HTTP/WS/gRPC权限中间件共用）
+type RBACConfig struct {
+	Rules    map[string]RBACRule `json:"rules"`     // 资源（WS action、HTTP "METHOD /path"或"/path"、gRPC完整方法名，以*结尾时按前缀匹配）-> 访问要求
+	Roles    map[string][]string `json:"roles"`     // 角色 -> 拥有的权限（"*"表示全部权限）
+	RedisDb  string              `json:"redis_db"`  // 缓存从数据库加载的策略使用的Redis连接key（为空时仅进程内缓存）
+	CacheTTL int                 `json:"cache_ttl"` // 加载策略的缓存时间（秒，默认60）
+}
+
+// RBACRule 资源的访问要求（roles满足其一且具备全部permissions，两者均为空时仅要求已认证）
+type RBACRule struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// OAuthConfig 第三方登录配置
+type OAuthConfig struct {
+	RedisDb   string                         `json:"redis_db"`  // 保存state/nonce的Redis连接key
+	StateTTL  int                            `json:"state_ttl"` // 登录状态有效期（秒，默认600）
+	Providers map[string]OAuthProviderConfig `json:"providers"` // 提供方名称 -> 配置（名称用于登录/回调路由）
+}
+
+// OAuthProviderConfig 第三方登录提供方配置
+type OAuthProviderConfig struct {
+	Type         string   `json:"type"`          // oidc/github/wechat/oauth2（默认）
+	ClientID     string   `json:"client_id"`     // 微信为appid
+	ClientSecret string   `json:"client_secret"` // 微信为secret
+	RedirectURL  string   `json:"redirect_url"`  // 回调地址
+	Scopes       []string `json:"scopes"`        // 授权范围（微信取第一个：snsapi_login/snsapi_userinfo/snsapi_base）
+	Issuer       string   `json:"issuer"`        // OIDC签发方（用于自动发现端点）
+	AuthURL      string   `json:"auth_url"`      // oauth2类型必填
+	TokenURL     string   `json:"token_url"`     // oauth2类型必填
+	UserInfoURL  string   `json:"user_info_url"`
+	UsePKCE      bool     `json:"use_pkce"` // oauth2类型是否携带PKCE校验码
+}
+
+// DbWarmupConfig 数据库连接池预热配置（启动时在服务开始接收流量前预先建立连接）
+type DbWarmupConfig struct {
+	Enable   bool `json:"enable"`
+	Timeout  int  `json:"timeout"`  // 预热超时（秒，默认10）
+	Conns    int  `json:"conns"`    // 每个连接池预热的连接数（0表示按各连接池的空闲连接配置）
+	Required bool `json:"required"` // 预热失败时中止启动（默认仅记录日志后继续启动）
+}
+
+// DbChaosConfig 数据库故障注入配置（仅非生产环境生效，用于测试应用的重试/降级逻辑）
+type DbChaosConfig struct {
+	Enable bool                         `json:"enable"`
+	Seed   int64                        `json:"seed"`  // 随机种子（非0时注入序列可复现）
+	Rules  map[string]DbChaosRuleConfig `json:"rules"` // 按“类型.连接key”配置（如mysql.default），依次回退到“类型.*”与“*”
+}
+
+// DbChaosRuleConfig 单个连接的故障注入规则（概率取值0~1）
+type DbChaosRuleConfig struct {
+	LatencyRate     float64 `json:"latency_rate"`      // 注入延迟的概率
+	LatencyMin      int     `json:"latency_min"`       // 最小延迟（毫秒）
+	LatencyMax      int     `json:"latency_max"`       // 最大延迟（毫秒，默认等于latency_min）
+	ErrorRate       float64 `json:"error_rate"`        // 注入连接错误的概率
+	BulkFailureRate float64 `json:"bulk_failure_rate"` // 批量写入部分失败的概率（仅写入前一部分，其余返回失败）
+}
+
+// SessionConfig 服务端会话配置（HTTP）
+type SessionConfig struct {
+	Enable          bool   `json:"enable"`            // 是否启用
+	Store           string `json:"store"`             // 存储：memory（单实例，默认）/redis
+	RedisDb         string `json:"redis_db"`          // store为redis时使用的Redis连接key
+	CookieName      string `json:"cookie_name"`       // Cookie名（默认go_dai_session）
+	Path            string `json:"path"`              // Cookie路径（默认/）
+	Domain          string `json:"domain"`            // Cookie域
+	MaxAge          int    `json:"max_age"`           // 会话有效期（秒，默认86400）
+	Secure          bool   `json:"secure"`            // 仅HTTPS传输
+	SameSite        string `json:"same_site"`         // lax（默认）/strict/none
+	Rolling         bool   `json:"rolling"`           // 每次访问都刷新有效期
+	DisableHttpOnly bool   `json:"disable_http_only"` // 允许脚本读取会话Cookie
 }
 
 // LoggerConfig 日志配置
 type LoggerConfig struct {
-	Path string `json:"path"`
+	Path     string `json:"path"`
+	Level    string `json:"level"`    // 最低输出级别：debug/info/warn/error/fatal（默认info）
+	Format   string `json:"format"`   // 输出格式：text/json（默认text）
+	MaxSize  int    `json:"max_size"` // 单个日志文件最大体积（MB，0表示仅按天轮转）
+	MaxAge   int    `json:"max_age"`  // 日志保留天数（0表示不清理）
+	Compress bool   `json:"compress"` // 是否gzip压缩已轮转的日志文件
+	// Buffer 请求级日志缓冲：请求内的debug/info日志先缓冲，请求出错或超过耗时阈值时才输出
+	Buffer LogBufferConfig `json:"buffer"`
+}
+
+// LogBufferConfig 请求级日志缓冲配置
+type LogBufferConfig struct {
+	Enable        bool   `json:"enable"`
+	Level         string `json:"level"`          // 缓冲的最低级别（默认debug，低于全局级别的日志同样缓冲，出错时一并输出）
+	MaxLines      int    `json:"max_lines"`      // 每个请求最多缓冲的行数（默认200，超出时丢弃最早的）
+	SlowThreshold int    `json:"slow_threshold"` // 慢请求阈值（毫秒，超过时输出缓冲，0表示不按耗时输出）
+	Summary       bool   `json:"summary"`        // 丢弃缓冲时输出一行摘要（缓冲行数与耗时）
 }
 
 // GlobalAppConfig 应用全局配置
@@ -73,6 +524,23 @@ type DatabaseConfig struct {
 	Mongodb map[string]MongodbConfig `json:"mongodb"`
 	Redis   map[string]RedisConfig   `json:"redis"`
 	Es      map[string]EsConfig      `json:"es"`
+	MQ      map[string]MQConfig      `json:"mq"` // 消息中间件（Kafka/RabbitMQ）
+	// FieldCrypt 敏感字段加密（MySQL/MongoDB写入前自动加密、读取后解密）
+	FieldCrypt FieldCryptConfig `json:"field_crypt"`
+}
+
+// FieldCryptConfig 敏感字段加密配置（密钥建议通过远程配置源或环境变量占位符下发，勿提交到代码仓库）
+type FieldCryptConfig struct {
+	Keys     map[string]string            `json:"keys"`      // 密钥版本（如"1"）-> base64编码的AES密钥（16/24/32字节）
+	Current  string                       `json:"current"`   // 加密使用的密钥版本（默认取最大版本），旧版本保留用于解密
+	IndexKey string                       `json:"index_key"` // 盲索引HMAC密钥（base64，变更后需重建盲索引）
+	Tables   map[string][]FieldCryptField `json:"tables"`    // 表/集合名（不含前缀）-> 加密字段
+}
+
+// FieldCryptField 加密字段
+type FieldCryptField struct {
+	Name       string `json:"name"`
+	BlindIndex string `json:"blind_index"` // 盲索引列名（为空时不生成，无法按该字段等值查询）
 }
 
 // MySQLConfig MySQL连接配置
@@ -103,6 +571,8 @@ type MongodbConfig struct {
 	MinPoolSize     uint64 `json:"min_pool_size"`      // 最小空闲连接数
 	MaxConnIdleTime int    `json:"max_conn_idle_time"` // 空闲连接 多少秒后关闭
 	Timeout         int    `json:"timeout"`            // 连接超时时间(秒)
+	// AllowUnsafeStages 允许聚合管道使用服务端JavaScript（$function/$accumulator/$where），默认禁止
+	AllowUnsafeStages bool `json:"allow_unsafe_stages"`
 }
 
 // RedisConfig redis连接配置
@@ -126,23 +596,57 @@ type RedisConfig struct {
 
 // EsConfig ES连接配置
 type EsConfig struct {
-	Host                  string `json:"host"`
-	Port                  string `json:"port"`
-	User                  string `json:"user"`
-	Pwd                   string `json:"pwd"`
-	Pre                   string `json:"pre"`
-	GzipStatus            bool   `json:"gzip_status"`
-	EnableTLS             bool   // 是否开启HTTPS
-	InsecureTLS           bool   // 跳过TLS证书验证（测试环境用）
-	MaxIdleConnNum        int    `json:"max_idle_conn_num"`          // 全局最大空闲连接
-	MaxIdleConnNumPerHost int    `json:"max_idle_conn_num_per_host"` // 每个主机最大空闲连接
-	IdleConnTimeout       int    `json:"idle_conn_timeout"`          //空闲连接超时释放(秒)
-	MaxConnNumPerHost     int    `json:"max_conn_num_per_host"`      //每个主机最大并发连接（限制并发）
-	Timeout               int    `json:"timeout"`                    // 连接建立超时（TCP握手）
-	KeepAlive             int    `json:"keep_alive"`                 // 长连接保活
-	ResponseHeaderTimeout int    `json:"response_header_timeout"`    //响应头超时
-	TLSHandshakeTimeout   int    `json:"tls_handshake_timeout"`      // TLS握手超时
+	Host                  string        `json:"host"`
+	Port                  string        `json:"port"`
+	User                  string        `json:"user"`
+	Pwd                   string        `json:"pwd"`
+	Pre                   string        `json:"pre"`
+	GzipStatus            bool          `json:"gzip_status"`
+	EnableTLS             bool          // 是否开启HTTPS
+	InsecureTLS           bool          // 跳过TLS证书验证（测试环境用）
+	MaxIdleConnNum        int           `json:"max_idle_conn_num"`          // 全局最大空闲连接
+	MaxIdleConnNumPerHost int           `json:"max_idle_conn_num_per_host"` // 每个主机最大空闲连接
+	IdleConnTimeout       int           `json:"idle_conn_timeout"`          //空闲连接超时释放(秒)
+	MaxConnNumPerHost     int           `json:"max_conn_num_per_host"`      //每个主机最大并发连接（限制并发）
+	Timeout               int           `json:"timeout"`                    // 连接建立超时（TCP握手）
+	KeepAlive             int           `json:"keep_alive"`                 // 长连接保活
+	ResponseHeaderTimeout int           `json:"response_header_timeout"`    //响应头超时
+	TLSHandshakeTimeout   int           `json:"tls_handshake_timeout"`      // TLS握手超时
+	Cache                 EsCacheConfig `json:"cache"`                      // 查询结果缓存
+}
+
+// EsCacheConfig ES查询结果缓存配置（缓存存放在Redis，查询通过SetCache开启）
+type EsCacheConfig struct {
+	RedisDb         string `json:"redis_db"`         // 缓存使用的Redis连接key（为空时不启用缓存）
+	TTL             int    `json:"ttl"`              // 缓存有效期（秒，默认60）
+	MaxSize         int    `json:"max_size"`         // 单条缓存结果的最大字节数（默认1MB，超出时不缓存）
+	MaxEntries      int    `json:"max_entries"`      // 每个索引最多缓存的查询数（默认1000，写入后重新计数）
+	InvalidateDelay int    `json:"invalidate_delay"` // 写入后再次失效的延迟（毫秒，默认1000，应不小于索引的refresh_interval）
+}
+
+// MQConfig 消息中间件连接配置（driver为kafka或rabbitmq）
+type MQConfig struct {
+	Driver       string   `json:"driver"`        // kafka/rabbitmq
+	Addrs        []string `json:"addrs"`         // 服务地址列表（host:port，Kafka为引导broker，RabbitMQ依次尝试）
+	User         string   `json:"user"`          // 用户名（Kafka配置后使用SASL/PLAIN认证）
+	Pwd          string   `json:"pwd"`           // 密码
+	Vhost        string   `json:"vhost"`         // RabbitMQ虚拟主机（默认/）
+	Pre          string   `json:"pre"`           // topic/队列名前缀
+	TLS          bool     `json:"tls"`           // 是否使用TLS连接
+	ClientID     string   `json:"client_id"`     // 客户端标识（默认go-dai）
+	Timeout      int      `json:"timeout"`       // 连接与请求超时（秒，默认10）
+	PoolSize     int      `json:"pool_size"`     // RabbitMQ发布通道数（默认CPU核数；Kafka的连接由驱动管理）
+	Heartbeat    int      `json:"heartbeat"`     // 心跳间隔（秒，Kafka消费组默认3，RabbitMQ默认10）
+	Acks         int      `json:"acks"`          // Kafka写入确认（-1所有副本（默认），1仅leader）
+	Compression  string   `json:"compression"`   // Kafka发布压缩（none（默认）/gzip/snappy/lz4/zstd）
+	Exchange     string   `json:"exchange"`      // RabbitMQ交换机（为空时使用默认交换机，topic即队列名）
+	ExchangeType string   `json:"exchange_type"` // RabbitMQ交换机类型（默认topic）
+	Group        string   `json:"group"`         // 默认消费组（为空时使用应用名）
+	Offset       string   `json:"offset"`        // Kafka消费组无已提交位点时的起始位置（latest（默认）/earliest）
+	Session      int      `json:"session"`       // Kafka消费组会话超时（秒，默认30）
+	Commit       int      `json:"commit"`        // Kafka位点提交间隔（毫秒，默认1000）
 }
+
 type PostLoadHook func() error
 
 var (
@@ -151,6 +655,10 @@ var (
 	appConfigOnce      sync.Once
 	databaseConfigOnce sync.Once
 	postLoadHooks      []PostLoadHook // 存储用户注册的钩子函数
+	configMu           sync.RWMutex   // 保护配置热更新时的并发读写
+	appConfigPath      string         // 已加载的应用配置文件路径（热更新时重新读取）
+	appConfigNames     []string       // 已加载的应用名
+	databaseConfigPath string         // 已加载的数据库配置文件路径
 )
 
 // RegisterPostLoadHook 注册配置加载后的钩子函数
@@ -163,26 +671,30 @@ func RegisterPostLoadHook(hook PostLoadHook) {
 func LoadAppConfig(filePath string, appNames ...string) error {
 	var err error
 	appConfigOnce.Do(func() {
-		// 读取配置文件
-		data, readErr := os.ReadFile(filepath.Clean(filePath))
-		if readErr != nil {
-			err = readErr
-			return
-		}
-
-		// 解析配置
+		// 读取并解析配置（支持JSON/YAML/TOML、环境变量插值与覆盖）
 		var cfgMap map[string]*AppConfig
-		if unmarshalErr := json.Unmarshal(data, &cfgMap); unmarshalErr != nil {
-			err = unmarshalErr
+		if decodeErr := decodeConfigFile(filePath, &cfgMap); decodeErr != nil {
+			err = decodeErr
 			return
 		}
 
+		for _, appName := range appNames {
+			if cfg, ok := cfgMap[appName]; ok {
+				if err = validateAppConfig(appName, cfg); err != nil {
+					return
+				}
+			}
+		}
 		// 加载指定应用配置
+		configMu.Lock()
 		for _, appName := range appNames {
 			if cfg, ok := cfgMap[appName]; ok {
 				appConfigMap[appName] = cfg
 			}
 		}
+		appConfigPath = filePath
+		appConfigNames = appNames
+		configMu.Unlock()
 		// 执行配置加载后钩子（新增核心逻辑）
 		if len(postLoadHooks) > 0 {
 			for _, hook := range postLoadHooks {
@@ -199,49 +711,55 @@ func LoadAppConfig(filePath string, appNames ...string) error {
 func LoadDatabaseConfig(filePath string) error {
 	var err error
 	databaseConfigOnce.Do(func() {
-		data, readErr := os.ReadFile(filepath.Clean(filePath))
-		if readErr != nil {
-			err = readErr
-			return
-		}
-
 		var cfg DatabaseConfig
-		if unmarshalErr := json.Unmarshal(data, &cfg); unmarshalErr != nil {
-			err = unmarshalErr
+		if decodeErr := decodeConfigFile(filePath, &cfg); decodeErr != nil {
+			err = decodeErr
 			return
 		}
 
+		configMu.Lock()
 		DbConfig = &cfg
+		databaseConfigPath = filePath
+		configMu.Unlock()
 	})
 	return err
 }
 
 // GetAppConfig 获取应用配置
 func GetAppConfig(appName string) *AppConfig {
+	configMu.RLock()
+	defer configMu.RUnlock()
 	return appConfigMap[appName]
 }
 
 // GetDatabaseConfig 获取数据库配置
 func GetDatabaseConfig() *DatabaseConfig {
+	configMu.RLock()
+	defer configMu.RUnlock()
 	return DbConfig
 }
 
 // GetMysqlConfig 获取mysql数据库配置
 func GetMysqlConfig() map[string]MySQLConfig {
-	return DbConfig.MySQL
+	return GetDatabaseConfig().MySQL
 }
 
 // GetEsConfig 获取mysql数据库配置
 func GetEsConfig() map[string]EsConfig {
-	return DbConfig.Es
+	return GetDatabaseConfig().Es
 }
 
 // GetMongodbConfig 获取数据库配置
 func GetMongodbConfig() map[string]MongodbConfig {
-	return DbConfig.Mongodb
+	return GetDatabaseConfig().Mongodb
 }
 
 // GetRedisConfig 获取mysql数据库配置
 func GetRedisConfig() map[string]RedisConfig {
-	return DbConfig.Redis
+	return GetDatabaseConfig().Redis
+}
+
+// GetMQConfig 获取消息中间件配置
+func GetMQConfig() map[string]MQConfig {
+	return GetDatabaseConfig().MQ
 }
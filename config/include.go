@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IncludesKey 配置文件顶层引用其他配置片段的键，如 "includes": ["conf.d/redis.json", "conf.d/es.yaml"]。
+// 合并规则：
+// 1. 片段按列表顺序合并，靠后的覆盖靠前的；当前文件自身的配置项优先于其引用的全部片段；
+// 2. 对象按键深度合并，数组与标量整体替换；
+// 3. 片段可继续引用其他片段，循环引用时加载失败；同一片段被多个文件引用时各自合并一次；
+// 4. 路径相对于当前文件所在目录（支持${VAR}环境变量），本地文件支持通配符（如conf.d/*.json，按文件名排序，无匹配时忽略）；
+// 5. 片段按自身扩展名解析格式，合并完成后再统一进行环境变量插值与DAI_覆盖。
+const IncludesKey = "includes"
+
+var (
+	includeMu     sync.RWMutex
+	includedFiles = make(map[string][]string) // 主配置路径 -> 已合并的片段路径（热更新时一并监听）
+)
+
+// loadWithIncludes 读取配置文件并递归合并其引用的片段，chain为当前引用链（用于检测循环），files收集合并过的片段路径
+func loadWithIncludes(filePath string, chain []string, files *[]string) (interface{}, error) {
+	id := includeID(filePath)
+	for i, item := range chain {
+		if item == id {
+			return nil, fmt.Errorf("配置文件循环引用：%s", strings.Join(append(chain[i:], id), " -> "))
+		}
+	}
+	data, err := readConfigData(filePath)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := parseConfigData(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := raw.(map[string]interface{})
+	if !ok {
+		return raw, nil
+	}
+	includes, err := includeList(filePath, node[IncludesKey])
+	if err != nil {
+		return nil, err
+	}
+	delete(node, IncludesKey)
+	if len(includes) == 0 {
+		return node, nil
+	}
+	chain = append(chain, id)
+	merged := make(map[string]interface{})
+	for _, include := range includes {
+		fragment, err := loadWithIncludes(include, chain, files)
+		if err != nil {
+			return nil, err
+		}
+		fragmentNode, ok := fragment.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("配置片段顶层必须为对象（%s）", include)
+		}
+		*files = append(*files, include)
+		mergeConfig(merged, fragmentNode)
+	}
+	mergeConfig(merged, node)
+	return merged, nil
+}
+
+// includeList 解析includes的值（字符串或字符串数组）为片段路径列表
+func includeList(filePath string, value interface{}) ([]string, error) {
+	var items []string
+	switch val := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		items = []string{val}
+	case []interface{}:
+		for _, item := range val {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s必须为字符串数组（%s）", IncludesKey, filePath)
+			}
+			items = append(items, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s必须为字符串数组（%s）", IncludesKey, filePath)
+	}
+	remote := GetSource() != nil
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(expandEnv(item))
+		if item == "" {
+			continue
+		}
+		// 远程配置源中的键按"/"分隔，本地文件按系统路径处理
+		if remote {
+			if !strings.HasPrefix(item, "/") {
+				item = path.Join(path.Dir(filePath), item)
+			}
+			paths = append(paths, item)
+			continue
+		}
+		if !filepath.IsAbs(item) {
+			item = filepath.Join(filepath.Dir(filePath), item)
+		}
+		if !strings.ContainsAny(item, "*?[") {
+			paths = append(paths, item)
+			continue
+		}
+		matches, err := filepath.Glob(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s通配符无效（%s）: %w", IncludesKey, filePath, err)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// includeID 用于循环检测的路径标识
+func includeID(filePath string) string {
+	if GetSource() != nil {
+		return path.Clean(filePath)
+	}
+	return absPath(filePath)
+}
+
+// mergeConfig 将src深度合并到dst（src优先）
+func mergeConfig(dst, src map[string]interface{}) {
+	for key, val := range src {
+		srcNode, srcIsMap := val.(map[string]interface{})
+		dstNode, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeConfig(dstNode, srcNode)
+			continue
+		}
+		dst[key] = val
+	}
+}
+
+// setIncludedFiles 记录主配置合并过的片段
+func setIncludedFiles(filePath string, files []string) {
+	includeMu.Lock()
+	defer includeMu.Unlock()
+	includedFiles[filePath] = files
+}
+
+// IncludedFiles 返回已加载的配置文件合并过的片段路径（按合并顺序，可能重复）
+func IncludedFiles(filePath string) []string {
+	includeMu.RLock()
+	defer includeMu.RUnlock()
+	return append([]string(nil), includedFiles[filePath]...)
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix 环境变量覆盖前缀：DAI_<路径>，路径各段为配置键的大写形式，以下划线连接。
+// 如 DAI_MYSQL_DEFAULT_PWD 覆盖数据库配置 mysql.default.pwd，DAI_API_LOGGER_LEVEL 覆盖应用api的logger.level。
+// 配置文件中缺失的项按配置结构体补建（密钥可不写入文件）；map类型的键（应用名、数据库实例名等）须已存在于配置文件中
+const EnvPrefix = "DAI_"
+
+// decodeConfigFile 读取配置文件并解码到v：
+// 1. 按扩展名识别格式（.yaml/.yml、.toml，其余按JSON解析），结构体字段统一使用json标签；
+// 2. 合并顶层includes引用的配置片段（规则见IncludesKey）；
+// 3. 字符串值中的 ${VAR} / ${VAR:-默认值} 替换为环境变量；
+// 4. 配置项可被 DAI_ 前缀的环境变量覆盖或补建（密钥等无需提交到配置文件）。
+// 设置了远程配置源（SetSource）时filePath为配置源中的键，否则为本地文件路径。
+func decodeConfigFile(filePath string, v interface{}) error {
+	var files []string
+	raw, err := loadWithIncludes(filePath, nil, &files)
+	if err != nil {
+		return err
+	}
+	setIncludedFiles(filePath, files)
+	raw = interpolate(raw)
+	applyEnvOverrides(raw, os.Environ(), reflect.TypeOf(v))
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalized, v)
+}
+
+// parseConfigData 按扩展名解析配置内容
+func parseConfigData(filePath string, data []byte) (interface{}, error) {
+	var raw interface{}
+	var err error
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		var tree map[string]interface{}
+		err = toml.Unmarshal(data, &tree)
+		raw = tree
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析配置文件失败（%s）: %w", filePath, err)
+	}
+	return raw, nil
+}
+
+// interpolate 递归替换字符串中的环境变量引用
+func interpolate(value interface{}) interface{} {
+	switch val := value.(type) {
+	case string:
+		return expandEnv(val)
+	case map[string]interface{}:
+		for key, item := range val {
+			val[key] = interpolate(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = interpolate(item)
+		}
+	}
+	return value
+}
+
+// expandEnv 替换 ${VAR} 与 ${VAR:-默认值}（未设置或为空时使用默认值）；不处理无花括号的$VAR，避免误伤密码中的$字符
+func expandEnv(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	var builder strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			break
+		}
+		builder.WriteString(s[:start])
+		expr := s[start+2 : start+end]
+		name, def, hasDefault := strings.Cut(expr, ":-")
+		if val := os.Getenv(name); val != "" || !hasDefault {
+			builder.WriteString(val)
+		} else {
+			builder.WriteString(def)
+		}
+		s = s[start+end+1:]
+	}
+	builder.WriteString(s)
+	return builder.String()
+}
+
+// applyEnvOverrides 使用DAI_前缀环境变量覆盖配置项（值按原配置项类型转换）；
+// 配置文件中不存在的项按目标类型target的json标签补建（值按字段类型转换）
+func applyEnvOverrides(raw interface{}, environ []string, target reflect.Type) {
+	root, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, kv := range environ {
+		name, value, found := strings.Cut(kv, "=")
+		if !found || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(name, EnvPrefix), "_")
+		if !setByEnvPath(root, segments, value) && target != nil {
+			createByEnvPath(root, target, segments, value)
+		}
+	}
+}
+
+// setByEnvPath 按下划线分段匹配配置路径；配置键本身含下划线（如max_open_conns）时尝试合并相邻分段
+func setByEnvPath(node map[string]interface{}, segments []string, value string) bool {
+	for n := 1; n <= len(segments); n++ {
+		want := strings.Join(segments[:n], "_")
+		for key, child := range node {
+			if !strings.EqualFold(key, want) {
+				continue
+			}
+			if n == len(segments) {
+				if converted, ok := convertEnvValue(child, value); ok {
+					node[key] = converted
+					return true
+				}
+				continue
+			}
+			if childMap, ok := child.(map[string]interface{}); ok && setByEnvPath(childMap, segments[n:], value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// convertEnvValue 按原值类型转换环境变量（对象/数组不支持覆盖）
+func convertEnvValue(origin interface{}, value string) (interface{}, bool) {
+	switch origin.(type) {
+	case map[string]interface{}, []interface{}:
+		return nil, false
+	case bool:
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	case json.Number:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(value), true
+	case int, int64, uint64, float64:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+	return value, true
+}
+
+// createByEnvPath 按目标类型补建配置文件中缺失的配置项（缺失的中间对象一并创建）。
+// 结构体按json标签匹配分段；map的键由配置文件决定，只进入已存在的键，不凭环境变量新建
+func createByEnvPath(node map[string]interface{}, t reflect.Type, segments []string, value string) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		for n := 1; n < len(segments); n++ {
+			want := strings.Join(segments[:n], "_")
+			for key, child := range node {
+				childMap, ok := child.(map[string]interface{})
+				if ok && strings.EqualFold(key, want) && createByEnvPath(childMap, t.Elem(), segments[n:], value) {
+					return true
+				}
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := jsonFieldName(field)
+			n := strings.Count(name, "_") + 1
+			if name == "" || n > len(segments) || !strings.EqualFold(strings.Join(segments[:n], "_"), name) {
+				continue
+			}
+			// 字段名可能是其他字段的前缀（如ssl与ssl_cert_file），匹配失败时继续尝试其他字段
+			key, child, exists := lookupKey(node, name)
+			if n == len(segments) {
+				// 已存在的叶子项由setByEnvPath处理，走到这里说明值与原类型不匹配，不覆盖
+				if exists {
+					continue
+				}
+				if converted, ok := convertEnvField(field.Type, value); ok {
+					node[key] = converted
+					return true
+				}
+				continue
+			}
+			if exists {
+				if childMap, ok := child.(map[string]interface{}); ok && createByEnvPath(childMap, field.Type, segments[n:], value) {
+					return true
+				}
+				continue
+			}
+			childMap := make(map[string]interface{})
+			if createByEnvPath(childMap, field.Type, segments[n:], value) {
+				node[key] = childMap
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonFieldName 结构体字段的JSON名称（忽略的字段返回空）
+func jsonFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// lookupKey 按不区分大小写查找配置键（未找到时返回name本身）
+func lookupKey(node map[string]interface{}, name string) (string, interface{}, bool) {
+	for key, child := range node {
+		if strings.EqualFold(key, name) {
+			return key, child, true
+		}
+	}
+	return name, nil, false
+}
+
+// convertEnvField 按结构体字段类型转换环境变量（仅支持字符串、布尔与数值）
+func convertEnvField(t reflect.Type, value string) (interface{}, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return value, true
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(value), true
+	}
+	return nil, false
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// decodeWithEnv 按给定环境变量应用覆盖后解码到v
+func decodeWithEnv(t *testing.T, data string, environ []string, v interface{}) {
+	t.Helper()
+	raw, err := parseConfigData("app.json", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyEnvOverrides(raw, environ, reflect.TypeOf(v))
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(normalized, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnvOverrideCreatesMissingKeys(t *testing.T) {
+	var cfg DatabaseConfig
+	decodeWithEnv(t, `{"mysql":{"default":{"host":"127.0.0.1","max_open_conn_num":10}}}`, []string{
+		"DAI_MYSQL_DEFAULT_PWD=secret",           // 文件中缺失的密钥
+		"DAI_MYSQL_DEFAULT_MAX_IDLE_CONN_NUM=5",  // 缺失的数值项（键含下划线）
+		"DAI_MYSQL_DEFAULT_MAX_OPEN_CONN_NUM=20", // 已存在的项按原逻辑覆盖
+		"DAI_MYSQL_OTHER_PWD=x",                  // map键不存在时不新建实例
+		"DAI_MYSQL_DEFAULT_UNKNOWN=x",            // 结构体中不存在的字段
+	}, &cfg)
+	got := cfg.MySQL["default"]
+	if got.Pwd != "secret" || got.MaxIdleConnNum != 5 || got.MaxOpenConnNum != 20 || got.Host != "127.0.0.1" {
+		t.Fatalf("覆盖结果不符合预期：%+v", got)
+	}
+	if _, ok := cfg.MySQL["other"]; ok {
+		t.Fatal("不应凭环境变量新建数据库实例")
+	}
+}
+
+func TestEnvOverrideCreatesNestedSections(t *testing.T) {
+	var cfg map[string]*AppConfig
+	decodeWithEnv(t, `{"api":{"http":{"ssl":true}}}`, []string{
+		"DAI_API_LOGGER_LEVEL=warn",                // 缺失的中间对象一并创建
+		"DAI_API_HTTP_SSL_CERT_FILE=/etc/cert.pem", // 字段名以已存在的布尔项ssl为前缀
+		"DAI_API_LOGGER_BUFFER_ENABLE=notabool",    // 类型不匹配时忽略
+	}, &cfg)
+	api := cfg["api"]
+	if api.Logger.Level != "warn" {
+		t.Fatalf("logger.level=%q，期望warn", api.Logger.Level)
+	}
+	if !api.HTTP.SSL || api.HTTP.SSLCertFile != "/etc/cert.pem" {
+		t.Fatalf("http配置不符合预期：ssl=%v ssl_cert_file=%q", api.HTTP.SSL, api.HTTP.SSLCertFile)
+	}
+}
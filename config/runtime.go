@@ -0,0 +1,97 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// 运行时覆盖项：由管理接口（bootstrap.StartAdmin）在线调整，优先于配置文件，
+// 持久化在Redis中，重启后恢复并同步到所有实例。业务代码通过FeatureEnabled、InMaintenance、IsReadOnly读取。
+
+// GlobalLogModule 全局日志级别在RuntimeOverrides.LogLevels中的键
+const GlobalLogModule = "*"
+
+// RuntimeOverrides 运行时覆盖项
+type RuntimeOverrides struct {
+	LogLevels   map[string]string `json:"log_levels"`  // 按模块的日志级别（键为包路径或其末尾若干段，如 websocket、db/mysql；*为全局）
+	Features    map[string]bool   `json:"features"`    // 功能开关覆盖（优先于应用配置features）
+	Maintenance bool              `json:"maintenance"` // 维护模式（HTTP请求统一返回503）
+	ReadOnly    bool              `json:"read_only"`   // 只读模式（拒绝GET/HEAD/OPTIONS以外的HTTP请求）
+}
+
+// RuntimeSubscriber 运行时覆盖项变更订阅函数
+type RuntimeSubscriber func(oldVal, newVal RuntimeOverrides)
+
+var (
+	runtimeMu          sync.Mutex
+	runtimeOverrides   atomic.Pointer[RuntimeOverrides]
+	runtimeSubscribers []RuntimeSubscriber
+)
+
+// GetRuntime 获取当前运行时覆盖项（副本，修改不影响生效值）
+func GetRuntime() RuntimeOverrides {
+	if cur := runtimeOverrides.Load(); cur != nil {
+		return cur.clone()
+	}
+	return RuntimeOverrides{}
+}
+
+// SetRuntime 整体替换运行时覆盖项并通知订阅者
+func SetRuntime(val RuntimeOverrides) {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	oldVal := GetRuntime()
+	newVal := val.clone()
+	runtimeOverrides.Store(&newVal)
+	for _, fn := range runtimeSubscribers {
+		fn(oldVal, newVal.clone())
+	}
+}
+
+// OnRuntimeChange 订阅运行时覆盖项变更
+func OnRuntimeChange(fn RuntimeSubscriber) {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	runtimeSubscribers = append(runtimeSubscribers, fn)
+}
+
+// FeatureEnabled 功能开关是否开启（运行时覆盖 > 应用配置features > 关闭）
+func FeatureEnabled(appName, name string) bool {
+	if cur := runtimeOverrides.Load(); cur != nil {
+		if enabled, ok := cur.Features[name]; ok {
+			return enabled
+		}
+	}
+	if cfg := GetAppConfig(appName); cfg != nil {
+		return cfg.Features[name]
+	}
+	return false
+}
+
+// InMaintenance 是否处于维护模式
+func InMaintenance() bool {
+	cur := runtimeOverrides.Load()
+	return cur != nil && cur.Maintenance
+}
+
+// IsReadOnly 是否处于只读模式
+func IsReadOnly() bool {
+	cur := runtimeOverrides.Load()
+	return cur != nil && cur.ReadOnly
+}
+
+func (o RuntimeOverrides) clone() RuntimeOverrides {
+	out := RuntimeOverrides{
+		LogLevels:   make(map[string]string, len(o.LogLevels)),
+		Features:    make(map[string]bool, len(o.Features)),
+		Maintenance: o.Maintenance,
+		ReadOnly:    o.ReadOnly,
+	}
+	for key, val := range o.LogLevels {
+		out.LogLevels[key] = val
+	}
+	for key, val := range o.Features {
+		out.Features[key] = val
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ConsulSource Consul KV配置源（基于HTTP API，变更订阅使用阻塞查询）
+type ConsulSource struct {
+	Address    string       // Consul地址（如 http://127.0.0.1:8500）
+	Token      string       // ACL Token（可选）
+	Datacenter string       // 数据中心（可选）
+	Client     *http.Client // 自定义HTTP客户端，为nil时使用默认客户端
+}
+
+// NewConsulSource 创建Consul配置源
+func NewConsulSource(address, token string) *ConsulSource {
+	return &ConsulSource{Address: address, Token: token}
+}
+
+func (s *ConsulSource) Name() string {
+	return "consul"
+}
+
+// Fetch 读取KV原始值
+func (s *ConsulSource) Fetch(ctx context.Context, key string) ([]byte, error) {
+	data, _, err := s.get(ctx, key, "")
+	return data, err
+}
+
+// Watch 以阻塞查询等待X-Consul-Index变化，索引变化即视为配置变更
+func (s *ConsulSource) Watch(ctx context.Context, key string, onChange func()) error {
+	_, index, err := s.get(ctx, key, "")
+	if err != nil {
+		return err
+	}
+	for {
+		_, newIndex, getErr := s.get(ctx, key, index)
+		if getErr != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return getErr
+		}
+		if newIndex != index {
+			index = newIndex
+			onChange()
+		}
+	}
+}
+
+// get 读取KV，index非空时发起阻塞查询（最长等待5分钟）
+func (s *ConsulSource) get(ctx context.Context, key, index string) ([]byte, string, error) {
+	query := url.Values{}
+	query.Set("raw", "")
+	if s.Datacenter != "" {
+		query.Set("dc", s.Datacenter)
+	}
+	if index != "" {
+		query.Set("index", index)
+		query.Set("wait", "5m")
+	}
+	reqURL := strings.TrimRight(s.Address, "/") + "/v1/kv/" + strings.TrimPrefix(key, "/") + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.Token != "" {
+		req.Header.Set("X-Consul-Token", s.Token)
+	}
+	res, err := sourceHTTPClient(s.Client).Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, res.Header.Get("X-Consul-Index"), fmt.Errorf("consul配置不存在：%s", key)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("consul请求失败：%d %s", res.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, res.Header.Get("X-Consul-Index"), nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"github.com/dfpopp/go-dai/internal/etcd"
+	"net/http"
+	"sync"
+)
+
+// EtcdSource etcd v3配置源（通过etcd内置的v3 HTTP/JSON网关访问，无需引入etcd客户端依赖）
+type EtcdSource struct {
+	Endpoints []string     // 节点地址（如 http://127.0.0.1:2379），按顺序尝试
+	Username  string       // 启用认证时的用户名
+	Password  string       // 启用认证时的密码
+	Client    *http.Client // 自定义HTTP客户端（如需TLS），为nil时使用默认客户端
+
+	once   sync.Once
+	client *etcd.Client
+}
+
+// NewEtcdSource 创建etcd配置源
+func NewEtcdSource(endpoints []string, username, password string) *EtcdSource {
+	return &EtcdSource{Endpoints: endpoints, Username: username, Password: password}
+}
+
+func (s *EtcdSource) Name() string {
+	return "etcd"
+}
+
+// Fetch 读取key的最新值
+func (s *EtcdSource) Fetch(ctx context.Context, key string) ([]byte, error) {
+	kvs, err := s.gateway().Range(ctx, key, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(kvs) == 0 {
+		return nil, fmt.Errorf("etcd配置不存在：%s", key)
+	}
+	return kvs[0].Value, nil
+}
+
+// Watch 通过/v3/watch流式接口监听key变更
+func (s *EtcdSource) Watch(ctx context.Context, key string, onChange func()) error {
+	return s.gateway().Watch(ctx, key, "", onChange)
+}
+
+// gateway 首次使用时按字段创建网关客户端（认证token在客户端内缓存）
+func (s *EtcdSource) gateway() *etcd.Client {
+	s.once.Do(func() {
+		s.client = &etcd.Client{Endpoints: s.Endpoints, Username: s.Username, Password: s.Password, HTTP: s.Client}
+	})
+	return s.client
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const nacosLongPollTimeout = 30 * time.Second // Nacos长轮询超时
+
+// NacosSource Nacos配置源（基于v1 Open API，key对应dataId，变更订阅使用长轮询监听接口）
+type NacosSource struct {
+	Address   string       // Nacos地址（如 http://127.0.0.1:8848）
+	Namespace string       // 命名空间ID（tenant，可选）
+	Group     string       // 配置分组（默认DEFAULT_GROUP）
+	Username  string       // 启用鉴权时的用户名
+	Password  string       // 启用鉴权时的密码
+	Client    *http.Client // 自定义HTTP客户端，为nil时使用默认客户端
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpire time.Time
+}
+
+// NewNacosSource 创建Nacos配置源
+func NewNacosSource(address, namespace, group string) *NacosSource {
+	return &NacosSource{Address: address, Namespace: namespace, Group: group}
+}
+
+func (s *NacosSource) Name() string {
+	return "nacos"
+}
+
+func (s *NacosSource) group() string {
+	if s.Group == "" {
+		return "DEFAULT_GROUP"
+	}
+	return s.Group
+}
+
+// Fetch 读取dataId对应的配置内容
+func (s *NacosSource) Fetch(ctx context.Context, key string) ([]byte, error) {
+	query := url.Values{}
+	query.Set("dataId", key)
+	query.Set("group", s.group())
+	if s.Namespace != "" {
+		query.Set("tenant", s.Namespace)
+	}
+	if err := s.withToken(ctx, query); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint("/nacos/v1/cs/configs")+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := sourceHTTPClient(s.Client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return data, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("nacos配置不存在：%s", key)
+	}
+	return nil, fmt.Errorf("nacos请求失败：%d %s", res.StatusCode, strings.TrimSpace(string(data)))
+}
+
+// Watch 长轮询监听：提交当前内容的MD5，服务端在内容变化或超时后返回，返回非空即表示已变更
+func (s *NacosSource) Watch(ctx context.Context, key string, onChange func()) error {
+	data, err := s.Fetch(ctx, key)
+	if err != nil {
+		return err
+	}
+	for {
+		sum := md5.Sum(data)
+		listening := key + "\x02" + s.group() + "\x02" + hex.EncodeToString(sum[:])
+		if s.Namespace != "" {
+			listening += "\x02" + s.Namespace
+		}
+		listening += "\x01"
+		form := url.Values{}
+		form.Set("Listening-Configs", listening)
+		query := url.Values{}
+		if err = s.withToken(ctx, query); err != nil {
+			return err
+		}
+		reqURL := s.endpoint("/nacos/v1/cs/configs/listener")
+		if len(query) > 0 {
+			reqURL += "?" + query.Encode()
+		}
+		req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, strings.NewReader(form.Encode()))
+		if reqErr != nil {
+			return reqErr
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Long-Pulling-Timeout", fmt.Sprint(nacosLongPollTimeout.Milliseconds()))
+		res, doErr := sourceHTTPClient(s.Client).Do(req)
+		if doErr != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return doErr
+		}
+		changed, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("nacos监听失败：%d %s", res.StatusCode, strings.TrimSpace(string(changed)))
+		}
+		if strings.TrimSpace(string(changed)) == "" {
+			continue
+		}
+		if data, err = s.Fetch(ctx, key); err != nil {
+			return err
+		}
+		onChange()
+	}
+}
+
+func (s *NacosSource) endpoint(path string) string {
+	return strings.TrimRight(s.Address, "/") + path
+}
+
+// withToken 启用鉴权时登录并附加accessToken参数（按服务端返回的有效期缓存）
+func (s *NacosSource) withToken(ctx context.Context, query url.Values) error {
+	if s.Username == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken == "" || time.Now().After(s.tokenExpire) {
+		form := url.Values{}
+		form.Set("username", s.Username)
+		form.Set("password", s.Password)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint("/nacos/v1/auth/login"), strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res, err := sourceHTTPClient(s.Client).Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		var resp struct {
+			AccessToken string `json:"accessToken"`
+			TokenTtl    int64  `json:"tokenTtl"`
+		}
+		if err = json.NewDecoder(res.Body).Decode(&resp); err != nil {
+			return err
+		}
+		if resp.AccessToken == "" {
+			return errors.New("nacos登录失败")
+		}
+		s.accessToken = resp.AccessToken
+		// 提前10%过期，避免临界时刻token失效
+		s.tokenExpire = time.Now().Add(time.Duration(resp.TokenTtl) * time.Second * 9 / 10)
+	}
+	query.Set("accessToken", s.accessToken)
+	return nil
+}
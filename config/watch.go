@@ -0,0 +1,248 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/function"
+	"github.com/fsnotify/fsnotify"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 配置热更新：监听已加载的应用配置/数据库配置文件，变更后重新解析、校验，
+// 校验通过才替换内存中的配置并通知订阅者；解析或校验失败时保留原配置。
+// 注意：已建立的连接池、监听端口等不会因配置变更自动重建，订阅者只应处理可在线调整的项（如日志级别、限流阈值）。
+
+const watchDebounce = 300 * time.Millisecond // 合并编辑器保存时产生的连续写事件
+
+// AppConfigSubscriber 应用配置变更订阅函数
+type AppConfigSubscriber func(appName string, oldCfg, newCfg *AppConfig)
+
+// DatabaseConfigSubscriber 数据库配置变更订阅函数
+type DatabaseConfigSubscriber func(oldCfg, newCfg *DatabaseConfig)
+
+var (
+	subscriberMu        sync.RWMutex
+	appSubscribers      []AppConfigSubscriber
+	databaseSubscribers []DatabaseConfigSubscriber
+	watcherMu           sync.Mutex
+	watcher             *fsnotify.Watcher
+	sourceWatchCancel   context.CancelFunc // 远程配置源订阅的取消函数
+)
+
+// OnAppConfigChange 订阅应用配置变更
+func OnAppConfigChange(fn AppConfigSubscriber) {
+	subscriberMu.Lock()
+	defer subscriberMu.Unlock()
+	appSubscribers = append(appSubscribers, fn)
+}
+
+// OnDatabaseConfigChange 订阅数据库配置变更
+func OnDatabaseConfigChange(fn DatabaseConfigSubscriber) {
+	subscriberMu.Lock()
+	defer subscriberMu.Unlock()
+	databaseSubscribers = append(databaseSubscribers, fn)
+}
+
+// validateAppConfig 校验应用配置
+func validateAppConfig(appName string, cfg *AppConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("应用[%s]配置不存在", appName)
+	}
+	switch cfg.Env {
+	case "", "dev", "prod", "test":
+	default:
+		return fmt.Errorf("应用[%s]运行环境env无效：%s", appName, cfg.Env)
+	}
+	// 允许凭证的跨域配置须显式列出来源，否则任意网站都可携带用户Cookie跨域访问接口
+	if cfg.HTTP.CORS.AllowCredentials && function.IsWildcardOrigins(cfg.HTTP.CORS.AllowOrigins) {
+		return fmt.Errorf("应用[%s]跨域配置无效：http.cors.allow_credentials为true时allow_origins须显式列出来源，不能为空或包含*", appName)
+	}
+	return nil
+}
+
+// ReloadAppConfig 重新读取应用配置文件（校验失败时保留原配置并返回错误）
+func ReloadAppConfig() error {
+	configMu.RLock()
+	filePath, appNames := appConfigPath, appConfigNames
+	configMu.RUnlock()
+	if filePath == "" {
+		return errors.New("应用配置未加载")
+	}
+	var cfgMap map[string]*AppConfig
+	if err := decodeConfigFile(filePath, &cfgMap); err != nil {
+		return err
+	}
+	for _, appName := range appNames {
+		if err := validateAppConfig(appName, cfgMap[appName]); err != nil {
+			return err
+		}
+	}
+	oldMap := make(map[string]*AppConfig, len(appNames))
+	configMu.Lock()
+	for _, appName := range appNames {
+		oldMap[appName] = appConfigMap[appName]
+		appConfigMap[appName] = cfgMap[appName]
+	}
+	configMu.Unlock()
+
+	subscriberMu.RLock()
+	subscribers := append([]AppConfigSubscriber(nil), appSubscribers...)
+	subscriberMu.RUnlock()
+	for _, appName := range appNames {
+		for _, fn := range subscribers {
+			fn(appName, oldMap[appName], cfgMap[appName])
+		}
+	}
+	return nil
+}
+
+// ReloadDatabaseConfig 重新读取数据库配置文件（解析失败时保留原配置并返回错误）
+func ReloadDatabaseConfig() error {
+	configMu.RLock()
+	filePath := databaseConfigPath
+	configMu.RUnlock()
+	if filePath == "" {
+		return errors.New("数据库配置未加载")
+	}
+	var cfg DatabaseConfig
+	if err := decodeConfigFile(filePath, &cfg); err != nil {
+		return err
+	}
+	configMu.Lock()
+	oldCfg := DbConfig
+	DbConfig = &cfg
+	configMu.Unlock()
+
+	subscriberMu.RLock()
+	subscribers := append([]DatabaseConfigSubscriber(nil), databaseSubscribers...)
+	subscriberMu.RUnlock()
+	for _, fn := range subscribers {
+		fn(oldCfg, &cfg)
+	}
+	return nil
+}
+
+// StartWatch 开始监听已加载的配置文件（需在LoadAppConfig/LoadDatabaseConfig之后调用；设置了远程配置源时订阅配置源变更），
+// onError接收重新加载失败的错误（可为nil）
+func StartWatch(onError func(filePath string, err error)) error {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+	if watcher != nil || sourceWatchCancel != nil {
+		return nil
+	}
+	configMu.RLock()
+	keys := make(map[string]func() error)
+	if appConfigPath != "" {
+		keys[appConfigPath] = ReloadAppConfig
+	}
+	if databaseConfigPath != "" {
+		keys[databaseConfigPath] = ReloadDatabaseConfig
+	}
+	configMu.RUnlock()
+	if len(keys) == 0 {
+		return errors.New("未加载任何配置文件，无法监听")
+	}
+	// includes引用的片段变更时重新加载主配置（启动后新增的引用需重启才会监听）
+	fragments := make(map[string]func() error)
+	for filePath, reload := range keys {
+		for _, include := range IncludedFiles(filePath) {
+			fragments[include] = reload
+		}
+	}
+	for include, reload := range fragments {
+		if _, ok := keys[include]; !ok {
+			keys[include] = reload
+		}
+	}
+	if src := GetSource(); src != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		sourceWatchCancel = cancel
+		for key, reload := range keys {
+			go watchSource(ctx, src, key, reload, onError)
+		}
+		return nil
+	}
+	reloaders := make(map[string]func() error, len(keys))
+	for filePath, reload := range keys {
+		reloaders[absPath(filePath)] = reload
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// 监听所在目录而非文件本身：编辑器常以"写临时文件+重命名"方式保存，直接监听文件会丢失后续事件
+	dirs := make(map[string]struct{})
+	for filePath := range reloaders {
+		dirs[filepath.Dir(filePath)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err = w.Add(dir); err != nil {
+			_ = w.Close()
+			return err
+		}
+	}
+	watcher = w
+
+	go func() {
+		timers := make(map[string]*time.Timer)
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+					continue
+				}
+				filePath := absPath(event.Name)
+				reload, ok := reloaders[filePath]
+				if !ok {
+					continue
+				}
+				if timer, exists := timers[filePath]; exists {
+					timer.Stop()
+				}
+				timers[filePath] = time.AfterFunc(watchDebounce, func() {
+					if reloadErr := reload(); reloadErr != nil && onError != nil {
+						onError(filePath, reloadErr)
+					}
+				})
+			case watchErr, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				if onError != nil {
+					onError("", watchErr)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// StopWatch 停止监听配置文件
+func StopWatch() error {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+	if sourceWatchCancel != nil {
+		sourceWatchCancel()
+		sourceWatchCancel = nil
+	}
+	if watcher == nil {
+		return nil
+	}
+	err := watcher.Close()
+	watcher = nil
+	return err
+}
+
+func absPath(filePath string) string {
+	if abs, err := filepath.Abs(filePath); err == nil {
+		return abs
+	}
+	return filepath.Clean(filePath)
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAppConfigCORSCredentials(t *testing.T) {
+	cfg := &AppConfig{}
+	cfg.HTTP.CORS.AllowCredentials = true
+	if err := validateAppConfig("api", cfg); err == nil || !strings.Contains(err.Error(), "allow_origins") {
+		t.Fatalf("未列出来源时应拒绝：%v", err)
+	}
+	cfg.HTTP.CORS.AllowOrigins = []string{"https://a.com", "*"}
+	if err := validateAppConfig("api", cfg); err == nil {
+		t.Fatal("包含*时应拒绝")
+	}
+	cfg.HTTP.CORS.AllowOrigins = []string{"https://a.com"}
+	if err := validateAppConfig("api", cfg); err != nil {
+		t.Fatal(err)
+	}
+}
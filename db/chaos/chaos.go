@@ -0,0 +1,193 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/dfpopp/go-dai/logger"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 数据库故障注入（混沌测试）：按“类型.连接key”配置延迟、连接错误与批量写入部分失败的概率，
+// 让应用在不改动真实基础设施的情况下验证重试/降级逻辑。各数据库模块始终接入注入点，
+// 未启用时仅有一次原子读取的开销；生产环境（env=prod）拒绝启用。
+//
+// 注入点：MySQL查询/写入、MongoDB各操作、Redis连接读写（经go-redis自身的重试）、ES HTTP请求；
+// 批量写入部分失败：MySQL InsertAll、MongoDB InsertAll、ES _bulk请求。
+
+// ErrInjected 注入的故障（errors.Is可区分注入故障与真实故障）
+var ErrInjected = errors.New("chaos: 注入的数据库故障")
+
+// Rule 故障注入规则（概率取值0~1）
+type Rule struct {
+	LatencyRate     float64       // 注入延迟的概率
+	LatencyMin      time.Duration // 最小延迟
+	LatencyMax      time.Duration // 最大延迟（延迟在[LatencyMin, LatencyMax]内随机）
+	ErrorRate       float64       // 注入连接错误的概率
+	BulkFailureRate float64       // 批量写入部分失败的概率
+}
+
+// Options 故障注入配置
+type Options struct {
+	Seed  int64           // 随机种子（非0时注入序列可复现）
+	Rules map[string]Rule // 按“类型.连接key”配置（类型：mysql/mongodb/redis/es），依次回退到“类型.*”与“*”
+}
+
+// Stats 已注入的故障次数
+type Stats struct {
+	Latency      int64
+	Errors       int64
+	BulkFailures int64
+}
+
+type injector struct {
+	rules map[string]Rule
+	mu    sync.Mutex
+	rng   *rand.Rand
+}
+
+var (
+	active       atomic.Pointer[injector]
+	latencyCount atomic.Int64
+	errorCount   atomic.Int64
+	bulkCount    atomic.Int64
+)
+
+// Init 按应用配置（db_chaos节点）启用故障注入，由bootstrap在连接池预热完成后调用；生产环境拒绝启用并记录错误日志
+func Init(appName string) {
+	appCfg := config.GetAppConfig(appName)
+	if appCfg == nil || !appCfg.DbChaos.Enable {
+		return
+	}
+	if appCfg.Env == "prod" {
+		logger.Error("数据库故障注入不能在生产环境启用，已忽略db_chaos配置")
+		return
+	}
+	opts := Options{Seed: appCfg.DbChaos.Seed, Rules: make(map[string]Rule, len(appCfg.DbChaos.Rules))}
+	for key, r := range appCfg.DbChaos.Rules {
+		opts.Rules[key] = Rule{
+			LatencyRate:     r.LatencyRate,
+			LatencyMin:      time.Duration(r.LatencyMin) * time.Millisecond,
+			LatencyMax:      time.Duration(r.LatencyMax) * time.Millisecond,
+			ErrorRate:       r.ErrorRate,
+			BulkFailureRate: r.BulkFailureRate,
+		}
+	}
+	Enable(opts)
+	logger.Warn("数据库故障注入已启用（仅用于测试环境）：", appCfg.DbChaos.Rules)
+}
+
+// Enable 启用故障注入（替换已有规则，供测试代码直接调用）
+func Enable(opts Options) {
+	inj := &injector{rules: opts.Rules}
+	if opts.Seed != 0 {
+		inj.rng = rand.New(rand.NewPCG(uint64(opts.Seed), uint64(opts.Seed)))
+	}
+	active.Store(inj)
+}
+
+// Disable 停用故障注入
+func Disable() {
+	active.Store(nil)
+}
+
+// Enabled 是否已启用故障注入
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// GetStats 已注入的故障次数（进程启动以来）
+func GetStats() Stats {
+	return Stats{Latency: latencyCount.Load(), Errors: errorCount.Load(), BulkFailures: bulkCount.Load()}
+}
+
+// rule 查找连接对应的规则
+func (inj *injector) rule(dbType, dbKey string) (Rule, bool) {
+	for _, key := range []string{dbType + "." + dbKey, dbType + ".*", "*"} {
+		if r, ok := inj.rules[key]; ok {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// float64 [0,1)随机数（配置了种子时使用独立随机源，保证序列可复现）
+func (inj *injector) float64() float64 {
+	if inj.rng == nil {
+		return rand.Float64()
+	}
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	return inj.rng.Float64()
+}
+
+func (inj *injector) hit(rate float64) bool {
+	return rate > 0 && inj.float64() < rate
+}
+
+// Inject 数据库操作前调用：按规则注入延迟（等待期间ctx取消时返回ctx错误），并按概率返回注入的连接错误
+// （errs.Unavailable，错误链包含ErrInjected）；未启用或未命中时返回nil
+func Inject(ctx context.Context, dbType, dbKey string) error {
+	inj := active.Load()
+	if inj == nil {
+		return nil
+	}
+	r, ok := inj.rule(dbType, dbKey)
+	if !ok {
+		return nil
+	}
+	if err := inj.delay(ctx, r); err != nil {
+		return err
+	}
+	if inj.hit(r.ErrorRate) {
+		errorCount.Add(1)
+		logger.Debug("chaos: 注入连接错误：", dbType, ".", dbKey)
+		return errs.Unavailable.Wrap(fmt.Errorf("%s[%s]: %w", dbType, dbKey, ErrInjected))
+	}
+	return nil
+}
+
+// Bulk 批量写入前调用：按概率注入部分失败，返回应实际写入的条数keep（0<=keep<n）与注入的错误；
+// 未命中时返回n与nil。调用方仅写入前keep条，并在写入成功后返回该错误
+func Bulk(dbType, dbKey string, n int) (int, error) {
+	inj := active.Load()
+	if inj == nil || n <= 0 {
+		return n, nil
+	}
+	r, ok := inj.rule(dbType, dbKey)
+	if !ok || !inj.hit(r.BulkFailureRate) {
+		return n, nil
+	}
+	keep := int(inj.float64() * float64(n))
+	bulkCount.Add(1)
+	logger.Debug("chaos: 注入批量写入部分失败：", dbType, ".", dbKey, " ", keep, "/", n)
+	return keep, errs.Unavailable.Wrap(fmt.Errorf("%s[%s]: 批量写入部分失败（成功%d条，失败%d条）: %w", dbType, dbKey, keep, n-keep, ErrInjected))
+}
+
+// delay 按规则注入延迟
+func (inj *injector) delay(ctx context.Context, r Rule) error {
+	if !inj.hit(r.LatencyRate) {
+		return nil
+	}
+	d := r.LatencyMin
+	if r.LatencyMax > r.LatencyMin {
+		d += time.Duration(inj.float64() * float64(r.LatencyMax-r.LatencyMin))
+	}
+	if d <= 0 {
+		return nil
+	}
+	latencyCount.Add(1)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
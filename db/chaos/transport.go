@@ -0,0 +1,180 @@
+package chaos
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Conn 包装数据库连接：每次写入（发送命令）前按规则注入延迟与连接错误，
+// 注入错误时关闭连接，由驱动按真实的网络故障处理（丢弃连接、按配置重试）
+func Conn(dbType, dbKey string, c net.Conn) net.Conn {
+	return &chaosConn{Conn: c, dbType: dbType, dbKey: dbKey}
+}
+
+type chaosConn struct {
+	net.Conn
+	dbType string
+	dbKey  string
+}
+
+func (c *chaosConn) Write(b []byte) (int, error) {
+	if err := Inject(context.Background(), c.dbType, c.dbKey); err != nil {
+		_ = c.Conn.Close()
+		return 0, &net.OpError{Op: "write", Net: "tcp", Addr: c.RemoteAddr(), Err: err}
+	}
+	return c.Conn.Write(b)
+}
+
+// RoundTripper 包装HTTP型数据库（ES）的传输层：请求前注入延迟与连接错误；
+// _bulk请求按概率只转发前一部分操作，其余操作在响应中标记为失败（status 503，errors为true）
+func RoundTripper(dbType, dbKey string, next http.RoundTripper) http.RoundTripper {
+	return &chaosTransport{next: next, dbType: dbType, dbKey: dbKey}
+}
+
+type chaosTransport struct {
+	next   http.RoundTripper
+	dbType string
+	dbKey  string
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !Enabled() {
+		return t.next.RoundTrip(req)
+	}
+	if err := Inject(req.Context(), t.dbType, t.dbKey); err != nil {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: err}
+	}
+	if req.Body == nil || !strings.HasSuffix(req.URL.Path, "/_bulk") {
+		return t.next.RoundTrip(req)
+	}
+	return t.bulk(req)
+}
+
+// bulkAction _bulk请求中的一个操作（操作行 + 可选的文档行）
+type bulkAction struct {
+	name  string // index/create/update/delete
+	index string
+	id    string
+	lines [][]byte
+}
+
+// bulk 按注入结果截断_bulk请求，并在响应中补齐被截断操作的失败项
+func (t *chaosTransport) bulk(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	actions := parseBulk(body)
+	keep, injected := Bulk(t.dbType, t.dbKey, len(actions))
+	if injected == nil {
+		return t.next.RoundTrip(withBody(req, body))
+	}
+	var kept bytes.Buffer
+	for _, a := range actions[:keep] {
+		for _, line := range a.lines {
+			kept.Write(line)
+			kept.WriteByte('\n')
+		}
+	}
+	items := make([]interface{}, 0, len(actions))
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+		Request:    req,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+	if keep > 0 {
+		fwd := withBody(req, kept.Bytes())
+		fwd.Header.Del("Accept-Encoding") // 需要解析并合并响应，不接受压缩
+		real, err := t.next.RoundTrip(fwd)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(real.Body)
+		_ = real.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var parsed struct {
+			Items []interface{} `json:"items"`
+		}
+		// 非200响应无法合并，原样返回
+		if real.StatusCode != http.StatusOK || real.Header.Get("Content-Encoding") != "" || json.Unmarshal(data, &parsed) != nil {
+			real.Body = io.NopCloser(bytes.NewReader(data))
+			return real, nil
+		}
+		items = append(items, parsed.Items...)
+		resp.Header = real.Header.Clone()
+	}
+	for _, a := range actions[keep:] {
+		items = append(items, map[string]interface{}{
+			a.name: map[string]interface{}{
+				"_index": a.index,
+				"_id":    a.id,
+				"status": http.StatusServiceUnavailable,
+				"error": map[string]interface{}{
+					"type":   "chaos_injected_exception",
+					"reason": injected.Error(),
+				},
+			},
+		})
+	}
+	data, err := json.Marshal(map[string]interface{}{"took": 0, "errors": true, "items": items})
+	if err != nil {
+		return nil, err
+	}
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = int64(len(data))
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// parseBulk 解析_bulk请求体（NDJSON：操作行，除delete外紧跟一行文档）
+func parseBulk(body []byte) []bulkAction {
+	var actions []bulkAction
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		line = append([]byte(nil), line...)
+		if n := len(actions); n > 0 && actions[n-1].name != "delete" && len(actions[n-1].lines) == 1 {
+			actions[n-1].lines = append(actions[n-1].lines, line) // 上一个操作的文档行
+			continue
+		}
+		var meta map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		_ = json.Unmarshal(line, &meta)
+		a := bulkAction{name: "index", lines: [][]byte{line}}
+		for name, m := range meta {
+			a.name, a.index, a.id = name, m.Index, m.ID
+		}
+		actions = append(actions, a)
+	}
+	return actions
+}
+
+func withBody(req *http.Request, body []byte) *http.Request {
+	r := req.Clone(req.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return r
+}
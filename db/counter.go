@@ -0,0 +1,365 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/go-redis/redis"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 高频计数落库：浏览数、点赞数等计数先在Redis中累加（请求不直接写MySQL），由定时任务周期性调用Flush将增量批量写入MySQL。
+// 每次Flush将当前累加的Hash原子地切换为一个带批次ID的快照，MySQL事务中先写入批次流水（counter_flush_log）再累加计数：
+//   - 事务失败时快照保留，下次Flush重试同一批次
+//   - 提交后删除快照前进程退出时，下次Flush发现流水已存在，只清理快照而不重复累加
+//
+// 因此每个快照批次恰好写入一次；多个实例同时Flush时通过Redis锁只有一个实例执行，其余直接返回。
+
+const (
+	counterKeyPrefix        = "counter:"         // Redis键前缀（会再拼接Redis表前缀）
+	defaultCounterBatchSize = 500                // 单条SQL默认最多包含的行数
+	defaultCounterLockTTL   = 5 * time.Minute    // Flush锁默认过期时长
+	defaultCounterRetention = 7 * 24 * time.Hour // 批次流水默认保留时长
+	defaultCounterLedger    = "counter_flush_log"
+)
+
+// ErrCounterColumn 计数字段未在CounterOptions.Columns中声明
+var ErrCounterColumn = errors.New("计数字段未声明")
+
+var counterIdentRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// counterSnapshotScript 切换快照：已有未完成的快照时返回其批次ID（重试），否则将累加Hash重命名为新批次的快照
+var counterSnapshotScript = redis.NewScript(`local id = redis.call('GET', KEYS[1])
+if id then return id end
+if redis.call('EXISTS', KEYS[2]) == 0 then return false end
+redis.call('RENAME', KEYS[2], KEYS[3])
+redis.call('SET', KEYS[1], ARGV[1])
+return ARGV[1]`)
+
+// CounterOptions 计数器配置
+type CounterOptions struct {
+	Name      string   // 计数器名称（Redis键与批次流水中区分不同计数器，同一名称的计数器须配置一致）
+	RedisDb   string   // 累加计数的Redis连接
+	MysqlDb   string   // 落库的MySQL连接
+	Table     string   // 落库表名（不含前缀）
+	KeyColumn string   // 行标识字段（如id）
+	Columns   []string // 允许累加的计数字段（如view_count、like_count）
+	// Upsert 为true时使用 INSERT ... ON DUPLICATE KEY UPDATE（行不存在时插入，要求KeyColumn为主键或唯一键，其他字段有默认值）；
+	// 默认只累加已存在的行（UPDATE ... CASE WHEN），不存在的行的增量被丢弃
+	Upsert      bool
+	BatchSize   int           // 单条SQL最多包含的行数（默认500）
+	LedgerTable string        // 批次流水表名（不含前缀，默认counter_flush_log，可用CreateLedgerTable创建）
+	Retention   time.Duration // 批次流水保留时长（默认7天，Flush时清理过期流水）
+	LockTTL     time.Duration // Flush锁过期时长（默认5分钟，应大于单次Flush的最长耗时）
+}
+
+// Counter Redis累加、批量落库的计数器
+type Counter struct {
+	opts    CounterOptions
+	redis   *redisDb.RedisDb
+	columns map[string]bool
+}
+
+// FlushResult 单次Flush的结果
+type FlushResult struct {
+	BatchID   string // 批次ID（无待落库的增量时为空）
+	Rows      int    // 写入的增量条数（行×字段）
+	Duplicate bool   // 批次此前已落库（本次仅清理快照）
+	Skipped   bool   // 其他实例正在Flush，本次跳过
+}
+
+// NewCounter 创建计数器
+func NewCounter(opts CounterOptions) (*Counter, error) {
+	if opts.Name == "" || strings.ContainsAny(opts.Name, ":{}") {
+		return nil, errors.New("计数器名称不能为空，且不能包含 : { }")
+	}
+	if opts.LedgerTable == "" {
+		opts.LedgerTable = defaultCounterLedger
+	}
+	for _, ident := range append([]string{opts.Table, opts.KeyColumn, opts.LedgerTable}, opts.Columns...) {
+		if !counterIdentRegexp.MatchString(ident) {
+			return nil, fmt.Errorf("计数器表名或字段名非法：%q", ident)
+		}
+	}
+	if len(opts.Columns) == 0 {
+		return nil, errors.New("未声明计数字段")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultCounterBatchSize
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = defaultCounterRetention
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = defaultCounterLockTTL
+	}
+	rdb, err := redisDb.GetRedisDB(opts.RedisDb)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mysql.GetMysqlDB(opts.MysqlDb); err != nil {
+		return nil, err
+	}
+	c := &Counter{opts: opts, redis: rdb, columns: make(map[string]bool, len(opts.Columns))}
+	for _, column := range opts.Columns {
+		c.columns[column] = true
+	}
+	return c, nil
+}
+
+// key 计数器的Redis键（live：累加中，pending：未完成快照的批次ID，batch:<id>：快照，lock：Flush锁）
+func (c *Counter) key(suffix string) string {
+	return c.redis.DbPre + counterKeyPrefix + c.opts.Name + ":" + suffix
+}
+
+// counterField Hash字段：字段名与行标识以冒号连接（行标识可包含冒号）
+func counterField(column, id string) string {
+	return column + ":" + id
+}
+
+// Incr 累加计数（只写Redis）
+func (c *Counter) Incr(ctx context.Context, column string, id string, delta int64) error {
+	if !c.columns[column] {
+		return fmt.Errorf("%w：%s", ErrCounterColumn, column)
+	}
+	if delta == 0 {
+		return nil
+	}
+	return c.redis.WithContext(ctx).Db.HIncrBy(c.key("live"), counterField(column, id), delta).Err()
+}
+
+// Pending 尚未落库的增量（累加中与未完成快照之和），展示时与MySQL中的值相加即为最新计数
+func (c *Counter) Pending(ctx context.Context, column string, id string) (int64, error) {
+	if !c.columns[column] {
+		return 0, fmt.Errorf("%w：%s", ErrCounterColumn, column)
+	}
+	client := c.redis.WithContext(ctx).Db
+	field := counterField(column, id)
+	total, err := hashInt(client.HGet(c.key("live"), field))
+	if err != nil {
+		return 0, err
+	}
+	batchID, err := client.Get(c.key("pending")).Result()
+	if errors.Is(err, redis.Nil) {
+		return total, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	pending, err := hashInt(client.HGet(c.key("batch:"+batchID), field))
+	return total + pending, err
+}
+
+// hashInt 解析HGET结果（字段不存在时为0）
+func hashInt(cmd *redis.StringCmd) (int64, error) {
+	n, err := cmd.Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Flush 将累加的增量批量写入MySQL（由定时任务周期调用，见Run）
+func (c *Counter) Flush(ctx context.Context) (FlushResult, error) {
+	var result FlushResult
+	client := c.redis.WithContext(ctx).Db
+	token := counterToken()
+	locked, err := client.SetNX(c.key("lock"), token, c.opts.LockTTL).Result()
+	if err != nil {
+		return result, fmt.Errorf("获取计数器Flush锁失败：%w", err)
+	}
+	if !locked {
+		result.Skipped = true
+		return result, nil
+	}
+	defer func() {
+		_, _ = c.redis.CompareAndDelete(context.WithoutCancel(ctx), counterKeyPrefix+c.opts.Name+":lock", token)
+	}()
+
+	newID := c.opts.Name + "-" + strconv.FormatInt(time.Now().UnixMilli(), 10) + "-" + token[:8]
+	reply, err := counterSnapshotScript.Run(client, []string{c.key("pending"), c.key("live"), c.key("batch:" + newID)}, newID).Result()
+	if errors.Is(err, redis.Nil) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("切换计数快照失败：%w", err)
+	}
+	result.BatchID, _ = reply.(string)
+	if result.BatchID == "" {
+		return result, errors.New("切换计数快照失败：返回值格式错误")
+	}
+	batchKey := c.key("batch:" + result.BatchID)
+	deltas, err := c.readSnapshot(ctx, batchKey)
+	if err != nil {
+		return result, err
+	}
+	applied, err := c.apply(ctx, result.BatchID, deltas)
+	if err != nil {
+		return result, err
+	}
+	result.Duplicate = !applied
+	if applied {
+		for _, rows := range deltas {
+			result.Rows += len(rows)
+		}
+	}
+	// 落库成功（或此前已落库）后清理快照，失败时下次Flush会发现流水已存在并再次清理
+	if err := client.Del(batchKey, c.key("pending")).Err(); err != nil {
+		logger.Warn("计数快照清理失败 [", result.BatchID, "]：", err)
+	}
+	return result, nil
+}
+
+// readSnapshot 读取快照（按字段分组，HSCAN可能返回重复字段，按字段去重）
+func (c *Counter) readSnapshot(ctx context.Context, batchKey string) (map[string]map[string]int64, error) {
+	client := c.redis.WithContext(ctx).Db
+	deltas := map[string]map[string]int64{}
+	var cursor uint64
+	for {
+		items, next, err := client.HScan(batchKey, cursor, "", int64(c.opts.BatchSize)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("读取计数快照失败：%w", err)
+		}
+		for i := 0; i+1 < len(items); i += 2 {
+			column, id, ok := strings.Cut(items[i], ":")
+			delta, err := strconv.ParseInt(items[i+1], 10, 64)
+			if !ok || err != nil || delta == 0 || !c.columns[column] {
+				continue
+			}
+			if deltas[column] == nil {
+				deltas[column] = map[string]int64{}
+			}
+			deltas[column][id] = delta
+		}
+		if cursor = next; cursor == 0 {
+			return deltas, nil
+		}
+	}
+}
+
+// apply 在一个事务中写入批次流水并累加计数，批次流水已存在时返回false（不重复累加）
+func (c *Counter) apply(ctx context.Context, batchID string, deltas map[string]map[string]int64) (bool, error) {
+	mdb, err := mysql.GetMysqlDB(c.opts.MysqlDb)
+	if err != nil {
+		return false, err
+	}
+	if err := mdb.ToBegin(); err != nil {
+		return false, err
+	}
+	ledger := "`" + mdb.DbPre + c.opts.LedgerTable + "`"
+	// 并发事务写入同一批次时，后者等待前者提交后插入被忽略
+	inserted, err := mdb.Exec(ctx, "INSERT IGNORE INTO "+ledger+" (batch_id, name, created_at) VALUES (?, ?, ?)", batchID, c.opts.Name, time.Now())
+	if err != nil || inserted == 0 {
+		_ = mdb.Rollback()
+		return false, err
+	}
+	table := "`" + mdb.DbPre + c.opts.Table + "`"
+	columns := make([]string, 0, len(deltas))
+	for column := range deltas {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		ids := make([]string, 0, len(deltas[column]))
+		for id := range deltas[column] {
+			ids = append(ids, id)
+		}
+		// 按行标识排序，多个计数器/事务按相同顺序加锁，避免死锁
+		sort.Strings(ids)
+		for start := 0; start < len(ids); start += c.opts.BatchSize {
+			chunk := ids[start:min(start+c.opts.BatchSize, len(ids))]
+			sqlStr, args := c.buildSQL(table, column, chunk, deltas[column])
+			if _, err := mdb.Exec(ctx, sqlStr, args...); err != nil {
+				_ = mdb.Rollback()
+				return false, err
+			}
+		}
+	}
+	if err := mdb.Commit(); err != nil {
+		return false, fmt.Errorf("计数落库提交失败：%w", err)
+	}
+	// 清理过期流水（失败不影响本次结果）
+	if _, err := mdb.Exec(ctx, "DELETE FROM "+ledger+" WHERE name = ? AND created_at < ?", c.opts.Name, time.Now().Add(-c.opts.Retention)); err != nil {
+		logger.Warn("计数批次流水清理失败：", err)
+	}
+	return true, nil
+}
+
+// buildSQL 构建累加语句
+func (c *Counter) buildSQL(table, column string, ids []string, deltas map[string]int64) (string, []interface{}) {
+	key, col := "`"+c.opts.KeyColumn+"`", "`"+column+"`"
+	args := make([]interface{}, 0, len(ids)*3)
+	if c.opts.Upsert {
+		placeholders := make([]string, len(ids))
+		for i, id := range ids {
+			placeholders[i] = "(?, ?)"
+			args = append(args, id, deltas[id])
+		}
+		return "INSERT INTO " + table + " (" + key + ", " + col + ") VALUES " + strings.Join(placeholders, ", ") +
+			" ON DUPLICATE KEY UPDATE " + col + " = " + col + " + VALUES(" + col + ")", args
+	}
+	var b strings.Builder
+	b.WriteString("UPDATE " + table + " SET " + col + " = " + col + " + CASE " + key)
+	for _, id := range ids {
+		b.WriteString(" WHEN ? THEN ?")
+		args = append(args, id, deltas[id])
+	}
+	b.WriteString(" ELSE 0 END WHERE " + key + " IN (?" + strings.Repeat(", ?", len(ids)-1) + ")")
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return b.String(), args
+}
+
+// CreateLedgerTable 创建批次流水表（已存在时忽略）
+func (c *Counter) CreateLedgerTable(ctx context.Context) error {
+	mdb, err := mysql.GetMysqlDB(c.opts.MysqlDb)
+	if err != nil {
+		return err
+	}
+	_, err = mdb.Exec(ctx, "CREATE TABLE IF NOT EXISTS `"+mdb.DbPre+c.opts.LedgerTable+"` ("+
+		"batch_id VARCHAR(191) NOT NULL PRIMARY KEY, "+
+		"name VARCHAR(128) NOT NULL, "+
+		"created_at DATETIME NOT NULL, "+
+		"KEY idx_name_created (name, created_at)"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='计数落库批次流水'")
+	return err
+}
+
+// Run 按interval周期执行Flush直至ctx取消（取消时再执行一次Flush，尽量落库剩余增量）
+func (c *Counter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	flush := func(ctx context.Context) {
+		if _, err := c.Flush(ctx); err != nil {
+			logger.Error("计数器[", c.opts.Name, "]落库失败：", err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			final, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.opts.LockTTL)
+			flush(final)
+			cancel()
+			return
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// counterToken 随机令牌（Flush锁与批次ID）
+func counterToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,359 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/errs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"hash/crc32"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 游标分页（keyset/search_after）：列表接口按排序键定位下一页，替代大数据量下的offset分页。
+// 游标为不透明字符串（base64编码的排序键值+翻页方向），MySQL、MongoDB、ES的SetCursor/FindPage共用同一格式；
+// 游标未签名，仅作为查询参数化条件的取值使用，不应承载权限信息
+
+const (
+	defaultLimit = 20
+	maxLimit     = 1000
+)
+
+// ErrInvalidCursor 游标格式错误或与排序字段不匹配（BaseController.Fail响应400）
+var ErrInvalidCursor = errs.InvalidArgument.WithMessage("cursor: 游标无效")
+
+// Field 排序字段（最后一个字段须唯一，如主键，保证翻页稳定）
+type Field struct {
+	Name string // 字段名（MySQL可带表别名，如o.id）
+	Desc bool   // 是否降序
+}
+
+// Asc 升序字段
+func Asc(name string) Field {
+	return Field{Name: name}
+}
+
+// Desc 降序字段
+func Desc(name string) Field {
+	return Field{Name: name, Desc: true}
+}
+
+// Direction 翻页方向
+type Direction string
+
+const (
+	Next Direction = "next" // 下一页
+	Prev Direction = "prev" // 上一页
+)
+
+// Cursor 解码后的游标
+type Cursor struct {
+	Values    []interface{} // 边界记录的排序键值（与排序字段一一对应）
+	Direction Direction
+}
+
+// payload 游标编码内容
+type payload struct {
+	Values []string `json:"v"`
+	Dir    string   `json:"d"`
+	Sign   string   `json:"s"` // 排序字段签名（排序变化后旧游标失效）
+}
+
+// Encode 编码游标
+func Encode(fields []Field, values []interface{}, dir Direction) (string, error) {
+	if len(values) != len(fields) {
+		return "", fmt.Errorf("cursor: 排序键值数量（%d）与排序字段数量（%d）不一致", len(values), len(fields))
+	}
+	p := payload{Values: make([]string, 0, len(values)), Dir: "n", Sign: sign(fields)}
+	if dir == Prev {
+		p.Dir = "p"
+	}
+	for i, v := range values {
+		s, err := encodeValue(v)
+		if err != nil {
+			return "", fmt.Errorf("cursor: 排序字段[%s]%w", fields[i].Name, err)
+		}
+		p.Values = append(p.Values, s)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decode 解码游标（校验与排序字段一致）
+func Decode(token string, fields []Field) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(token, "="))
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if p.Sign != sign(fields) || len(p.Values) != len(fields) {
+		return nil, ErrInvalidCursor
+	}
+	c := &Cursor{Values: make([]interface{}, 0, len(p.Values)), Direction: Next}
+	if p.Dir == "p" {
+		c.Direction = Prev
+	}
+	for _, s := range p.Values {
+		v, err := decodeValue(s)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		c.Values = append(c.Values, v)
+	}
+	return c, nil
+}
+
+// sign 排序字段签名
+func sign(fields []Field) string {
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f.Name)
+		if f.Desc {
+			b.WriteString(":desc,")
+		} else {
+			b.WriteString(":asc,")
+		}
+	}
+	return strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(b.String()))), 36)
+}
+
+// encodeValue 按类型编码排序键值（类型前缀保证解码后与数据库中的类型一致）
+func encodeValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", errors.New("值为空（排序字段不能为NULL）")
+	case string:
+		return "s:" + val, nil
+	case []byte:
+		return "s:" + string(val), nil
+	case bool:
+		return "b:" + strconv.FormatBool(val), nil
+	case int:
+		return "i:" + strconv.FormatInt(int64(val), 10), nil
+	case int8:
+		return "i:" + strconv.FormatInt(int64(val), 10), nil
+	case int16:
+		return "i:" + strconv.FormatInt(int64(val), 10), nil
+	case int32:
+		return "i:" + strconv.FormatInt(int64(val), 10), nil
+	case int64:
+		return "i:" + strconv.FormatInt(val, 10), nil
+	case uint:
+		return "u:" + strconv.FormatUint(uint64(val), 10), nil
+	case uint8:
+		return "u:" + strconv.FormatUint(uint64(val), 10), nil
+	case uint16:
+		return "u:" + strconv.FormatUint(uint64(val), 10), nil
+	case uint32:
+		return "u:" + strconv.FormatUint(uint64(val), 10), nil
+	case uint64:
+		return "u:" + strconv.FormatUint(val, 10), nil
+	case float32:
+		return "f:" + strconv.FormatFloat(float64(val), 'g', -1, 32), nil
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return "", errors.New("值不是有效数字")
+		}
+		return "f:" + strconv.FormatFloat(val, 'g', -1, 64), nil
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "i:" + val.String(), nil
+		}
+		return "f:" + val.String(), nil
+	case time.Time:
+		return "t:" + val.Format(time.RFC3339Nano), nil
+	case primitive.DateTime:
+		return "d:" + strconv.FormatInt(int64(val), 10), nil
+	case primitive.ObjectID:
+		return "o:" + val.Hex(), nil
+	}
+	return "", fmt.Errorf("值类型[%T]不支持", v)
+}
+
+// decodeValue 解码排序键值
+func decodeValue(s string) (interface{}, error) {
+	if len(s) < 2 || s[1] != ':' {
+		return nil, ErrInvalidCursor
+	}
+	val := s[2:]
+	switch s[0] {
+	case 's':
+		return val, nil
+	case 'b':
+		return strconv.ParseBool(val)
+	case 'i':
+		return strconv.ParseInt(val, 10, 64)
+	case 'u':
+		return strconv.ParseUint(val, 10, 64)
+	case 'f':
+		return strconv.ParseFloat(val, 64)
+	case 't':
+		return time.Parse(time.RFC3339Nano, val)
+	case 'd':
+		ms, err := strconv.ParseInt(val, 10, 64)
+		return primitive.DateTime(ms), err
+	case 'o':
+		return primitive.ObjectIDFromHex(val)
+	}
+	return nil, ErrInvalidCursor
+}
+
+// Query 一次游标分页查询（驱动的SetCursor创建，统一处理翻页方向与结果拆分）
+type Query struct {
+	Fields []Field
+	Cursor *Cursor // 首页为nil
+	Limit  int64   // 每页条数
+}
+
+// NewQuery 解析游标（token为上一页返回的next_cursor/prev_cursor，首页为空；limit<=0时为20，最大1000）
+func NewQuery(token string, limit int64, fields []Field) (*Query, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("cursor: 未指定排序字段")
+	}
+	for _, f := range fields {
+		if f.Name == "" {
+			return nil, errors.New("cursor: 排序字段名为空")
+		}
+	}
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	q := &Query{Fields: fields, Limit: limit}
+	if token != "" {
+		c, err := Decode(token, fields)
+		if err != nil {
+			return nil, err
+		}
+		q.Cursor = c
+	}
+	return q, nil
+}
+
+// Reverse 是否向前翻页（查询时排序反转，结果再倒序）
+func (q *Query) Reverse() bool {
+	return q.Cursor != nil && q.Cursor.Direction == Prev
+}
+
+// Desc 查询时第i个排序字段是否降序（已考虑翻页方向）
+func (q *Query) Desc(i int) bool {
+	return q.Fields[i].Desc != q.Reverse()
+}
+
+// Fetch 查询条数（多取一条判断是否还有数据）
+func (q *Query) Fetch() int64 {
+	return q.Limit + 1
+}
+
+// Page 游标分页结果
+type Page struct {
+	Data       []map[string]interface{} `json:"data"`
+	NextCursor string                   `json:"next_cursor"` // 下一页游标（没有下一页时为空）
+	PrevCursor string                   `json:"prev_cursor"` // 上一页游标（首页为空）
+	HasMore    bool                     `json:"has_more"`    // 是否还有下一页
+}
+
+// Page 将查询结果拆分为一页（rows按查询顺序；keys提取记录的排序键值，为nil时按排序字段名读取）
+func (q *Query) Page(rows []map[string]interface{}, keys func(row map[string]interface{}) []interface{}) (*Page, error) {
+	if keys == nil {
+		keys = q.keysOf
+	}
+	extra := int64(len(rows)) > q.Limit
+	if extra {
+		rows = rows[:q.Limit]
+	}
+	page := &Page{Data: rows}
+	if page.Data == nil {
+		page.Data = make([]map[string]interface{}, 0)
+	}
+	reverse := q.Reverse()
+	if reverse {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	// 向后翻页：多取到的记录表示还有下一页，带游标时可返回上一页；向前翻页：从后一页返回，下一页必然存在
+	hasNext, hasPrev := extra, q.Cursor != nil
+	if reverse {
+		hasNext, hasPrev = true, extra
+	}
+	if len(rows) == 0 {
+		if reverse && q.Cursor != nil {
+			// 前面已没有数据：下一页从原游标位置继续
+			token, err := Encode(q.Fields, q.Cursor.Values, Next)
+			if err != nil {
+				return nil, err
+			}
+			page.NextCursor, page.HasMore = token, true
+		}
+		return page, nil
+	}
+	var err error
+	if hasNext {
+		if page.NextCursor, err = Encode(q.Fields, keys(rows[len(rows)-1]), Next); err != nil {
+			return nil, err
+		}
+		page.HasMore = true
+	}
+	if hasPrev {
+		if page.PrevCursor, err = Encode(q.Fields, keys(rows[0]), Prev); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// keysOf 按排序字段名读取记录的排序键值
+func (q *Query) keysOf(row map[string]interface{}) []interface{} {
+	values := make([]interface{}, len(q.Fields))
+	for i, f := range q.Fields {
+		values[i] = lookup(row, f.Name)
+	}
+	return values
+}
+
+// lookup 读取字段值：先按完整名称，再按去掉表别名后的列名（o.id -> id），最后按嵌套文档路径
+func lookup(row map[string]interface{}, name string) interface{} {
+	if v, ok := row[name]; ok {
+		return v
+	}
+	idx := strings.LastIndex(name, ".")
+	if idx < 0 {
+		return nil
+	}
+	if v, ok := row[name[idx+1:]]; ok {
+		return v
+	}
+	var cur interface{} = row
+	for _, part := range strings.Split(name, ".") {
+		switch doc := cur.(type) {
+		case map[string]interface{}:
+			cur = doc[part]
+		case primitive.M:
+			cur = doc[part]
+		case primitive.D:
+			cur = nil
+			for _, e := range doc {
+				if e.Key == part {
+					cur = e.Value
+					break
+				}
+			}
+		default:
+			return nil
+		}
+	}
+	return cur
+}
@@ -0,0 +1,450 @@
+package datadiff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 迁移校验：对两个数据源（如旧MySQL表与新ES索引、迁移前后的两个库）执行同一逻辑查询，
+// 比较总行数，并按主键抽样逐字段比对，输出差异报告，用于切换前确认数据一致。
+// 默认宽松比较：数值按数值比较（"12.50"与12.5相等）、时间字符串统一到同一时区、缺失字段与null视为相等、
+// 嵌套文档按"."展开为字段路径；Strict模式下按值的JSON表示逐字比较。
+
+// 默认值
+const (
+	DefaultSampleSize    = 100 // 默认抽样数
+	DefaultMaxMismatches = 200 // 报告中保留的字段差异明细上限
+	fetchBatchSize       = 500 // 按主键批量读取的批大小
+)
+
+// Doc 按"."展开后的文档（字段路径 -> 值）
+type Doc map[string]interface{}
+
+// Source 比对数据源
+type Source interface {
+	// Name 数据源描述（用于报告）
+	Name() string
+	// KeyField 主键字段名（比对时不作为普通字段比较）
+	KeyField() string
+	// Count 满足查询条件的总行数
+	Count(ctx context.Context) (int64, error)
+	// SampleKeys 随机抽取n个满足查询条件的主键
+	SampleKeys(ctx context.Context, n int) ([]interface{}, error)
+	// Fetch 按主键读取文档，返回 KeyString(主键) -> 文档（不存在的主键不出现在结果中）
+	Fetch(ctx context.Context, keys []interface{}) (map[string]Doc, error)
+}
+
+// Options 比对选项
+type Options struct {
+	SampleSize    int               // 抽样数（默认100）
+	Bidirectional bool              // 同时从B抽样，检查B中多出（A中缺失）的数据
+	Fields        []string          // 只比较这些字段（按A的字段名，为空时比较两侧字段的并集）
+	Ignore        []string          // 忽略的字段（按A的字段名，以.*结尾时忽略该路径下的全部子字段）
+	FieldMap      map[string]string // 字段改名映射（A字段名 -> B字段名）
+	Strict        bool              // 严格模式（不做数值/时间归一，缺失与null视为不同）
+	Location      *time.Location    // 不带时区的时间字符串按该时区解析（默认time.Local）
+	MaxMismatches int               // 报告中保留的字段差异明细上限（默认200，超出只计数）
+}
+
+// Mismatch 单个字段差异
+type Mismatch struct {
+	Key      string `json:"key"`
+	Field    string `json:"field"`
+	A        string `json:"a"`
+	B        string `json:"b"`
+	AMissing bool   `json:"a_missing,omitempty"`
+	BMissing bool   `json:"b_missing,omitempty"`
+}
+
+// Report 比对报告
+type Report struct {
+	A              string         `json:"a"`
+	B              string         `json:"b"`
+	CountA         int64          `json:"count_a"`
+	CountB         int64          `json:"count_b"`
+	Sampled        int            `json:"sampled"`         // 实际比对的主键数
+	MissingInB     []string       `json:"missing_in_b"`    // 从A抽样、B中不存在的主键
+	MissingInA     []string       `json:"missing_in_a"`    // 从B抽样、A中不存在的主键（Bidirectional）
+	MismatchedKeys int            `json:"mismatched_keys"` // 存在字段差异的主键数
+	MismatchCount  int            `json:"mismatch_count"`  // 字段差异总数
+	Mismatches     []Mismatch     `json:"mismatches"`      // 字段差异明细（最多MaxMismatches条）
+	FieldStats     map[string]int `json:"field_stats"`     // 各字段差异次数
+	Elapsed        time.Duration  `json:"elapsed"`
+}
+
+// OK 两侧是否一致（行数相等且抽样无差异）
+func (r *Report) OK() bool {
+	return r.CountA == r.CountB && len(r.MissingInB) == 0 && len(r.MissingInA) == 0 && r.MismatchCount == 0
+}
+
+// Compare 比对两个数据源
+func Compare(ctx context.Context, a, b Source, opts Options) (*Report, error) {
+	start := time.Now()
+	if opts.SampleSize <= 0 {
+		opts.SampleSize = DefaultSampleSize
+	}
+	if opts.MaxMismatches <= 0 {
+		opts.MaxMismatches = DefaultMaxMismatches
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	report := &Report{A: a.Name(), B: b.Name(), MissingInB: []string{}, MissingInA: []string{}, Mismatches: []Mismatch{}, FieldStats: map[string]int{}}
+	var err error
+	if report.CountA, err = a.Count(ctx); err != nil {
+		return nil, fmt.Errorf("统计[%s]行数失败：%w", a.Name(), err)
+	}
+	if report.CountB, err = b.Count(ctx); err != nil {
+		return nil, fmt.Errorf("统计[%s]行数失败：%w", b.Name(), err)
+	}
+	c := &comparer{opts: opts, report: report, seen: map[string]struct{}{}, reverse: map[string]string{}}
+	for field, mapped := range opts.FieldMap {
+		c.reverse[mapped] = field
+	}
+	if err = c.run(ctx, a, b, false); err != nil {
+		return nil, err
+	}
+	if opts.Bidirectional {
+		if err = c.run(ctx, b, a, true); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(report.MissingInB)
+	sort.Strings(report.MissingInA)
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// comparer 单次比对的状态
+type comparer struct {
+	opts    Options
+	report  *Report
+	seen    map[string]struct{} // 已比对的主键（双向抽样时去重）
+	reverse map[string]string   // B字段名 -> A字段名
+}
+
+// run 从from抽样并与to比对（reversed为true时from为B）
+func (c *comparer) run(ctx context.Context, from, to Source, reversed bool) error {
+	keys, err := from.SampleKeys(ctx, c.opts.SampleSize)
+	if err != nil {
+		return fmt.Errorf("从[%s]抽样失败：%w", from.Name(), err)
+	}
+	for start := 0; start < len(keys); start += fetchBatchSize {
+		batch := keys[start:min(start+fetchBatchSize, len(keys))]
+		fromDocs, err := from.Fetch(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("读取[%s]失败：%w", from.Name(), err)
+		}
+		toDocs, err := to.Fetch(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("读取[%s]失败：%w", to.Name(), err)
+		}
+		for _, key := range batch {
+			ks := KeyString(key)
+			if _, ok := c.seen[ks]; ok {
+				continue
+			}
+			c.seen[ks] = struct{}{}
+			fromDoc, ok := fromDocs[ks]
+			if !ok {
+				continue // 抽样后被删除
+			}
+			toDoc, ok := toDocs[ks]
+			c.report.Sampled++
+			if !ok {
+				if reversed {
+					c.report.MissingInA = append(c.report.MissingInA, ks)
+				} else {
+					c.report.MissingInB = append(c.report.MissingInB, ks)
+				}
+				continue
+			}
+			if reversed {
+				c.compareDoc(ks, toDoc, fromDoc, to.KeyField(), from.KeyField())
+			} else {
+				c.compareDoc(ks, fromDoc, toDoc, from.KeyField(), to.KeyField())
+			}
+		}
+	}
+	return nil
+}
+
+// compareDoc 逐字段比较同一主键的两份文档
+func (c *comparer) compareDoc(key string, docA, docB Doc, keyA, keyB string) {
+	fields := c.opts.Fields
+	if len(fields) == 0 {
+		set := make(map[string]struct{}, len(docA))
+		for field := range docA {
+			if field != keyA {
+				set[field] = struct{}{}
+			}
+		}
+		for field := range docB {
+			if field == keyB {
+				continue
+			}
+			if original, ok := c.reverse[field]; ok {
+				field = original
+			}
+			set[field] = struct{}{}
+		}
+		fields = make([]string, 0, len(set))
+		for field := range set {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	}
+	mismatched := false
+	for _, field := range fields {
+		if c.ignored(field) {
+			continue
+		}
+		fieldB := field
+		if mapped, ok := c.opts.FieldMap[field]; ok {
+			fieldB = mapped
+		}
+		valA, okA := docA[field]
+		valB, okB := docB[fieldB]
+		if c.equal(valA, okA, valB, okB) {
+			continue
+		}
+		mismatched = true
+		c.report.MismatchCount++
+		c.report.FieldStats[field]++
+		if len(c.report.Mismatches) < c.opts.MaxMismatches {
+			c.report.Mismatches = append(c.report.Mismatches, Mismatch{
+				Key: key, Field: field,
+				A: displayValue(valA, okA), B: displayValue(valB, okB),
+				AMissing: !okA, BMissing: !okB,
+			})
+		}
+	}
+	if mismatched {
+		c.report.MismatchedKeys++
+	}
+}
+
+// ignored 字段是否被忽略
+func (c *comparer) ignored(field string) bool {
+	for _, pattern := range c.opts.Ignore {
+		if pattern == field {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, ".*"); ok && strings.HasPrefix(field, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// equal 比较两个字段值
+func (c *comparer) equal(valA interface{}, okA bool, valB interface{}, okB bool) bool {
+	if c.opts.Strict {
+		if okA != okB {
+			return false
+		}
+		return canonical(valA) == canonical(valB)
+	}
+	return normalize(valA, c.opts.Location) == normalize(valB, c.opts.Location)
+}
+
+// KeyString 主键的字符串形式（不同数据源间统一，如int64(1)、"1"、float64(1)均为"1"）
+func KeyString(key interface{}) string {
+	switch v := key.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case primitive.ObjectID:
+		return v.Hex()
+	case json.Number:
+		return numberString(v.String())
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(key)
+}
+
+// normalize 宽松比较使用的规范形式
+func normalize(val interface{}, loc *time.Location) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return normalizeString(string(v), loc)
+	case string:
+		return normalizeString(v, loc)
+	case json.Number:
+		return numberString(v.String())
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case primitive.DateTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.Decimal128:
+		return numberString(v.String())
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case float32, float64:
+		return KeyString(v)
+	}
+	return canonical(val)
+}
+
+// normalizeString 字符串规范化：数值字符串统一格式，时间字符串统一到UTC
+func normalizeString(s string, loc *time.Location) string {
+	if s == "" {
+		return ""
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return numberString(s)
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return s
+}
+
+// numberString 数值字符串规范化（非数值原样返回）
+func numberString(s string) string {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return s
+}
+
+// canonical 值的JSON表示（严格比较与复合值使用）
+func canonical(val interface{}) string {
+	switch v := val.(type) {
+	case []byte:
+		val = string(v)
+	case time.Time:
+		val = v.UTC().Format(time.RFC3339Nano)
+	case primitive.DateTime:
+		val = v.Time().UTC().Format(time.RFC3339Nano)
+	case primitive.ObjectID:
+		val = v.Hex()
+	case primitive.Decimal128:
+		val = v.String()
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(data)
+}
+
+// displayValue 报告中展示的值
+func displayValue(val interface{}, ok bool) string {
+	if !ok {
+		return "<缺失>"
+	}
+	if val == nil {
+		return "null"
+	}
+	s := canonical(val)
+	if len(s) > 200 {
+		s = s[:200] + "..."
+	}
+	return s
+}
+
+// flatten 将嵌套文档按"."展开（数组保持为整体值）
+func flatten(prefix string, in map[string]interface{}, out Doc) {
+	for key, val := range in {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		switch v := val.(type) {
+		case map[string]interface{}:
+			flatten(path, v, out)
+		case primitive.M:
+			flatten(path, v, out)
+		case primitive.D:
+			flatten(path, v.Map(), out)
+		default:
+			out[path] = val
+		}
+	}
+}
+
+// WriteText 输出文本格式报告
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	status := "一致"
+	if !r.OK() {
+		status = "存在差异"
+	}
+	fmt.Fprintf(&b, "数据比对：%s\n  A：%s\n  B：%s\n\n", status, r.A, r.B)
+	fmt.Fprintf(&b, "行数：A=%d B=%d 差值=%d\n", r.CountA, r.CountB, r.CountA-r.CountB)
+	fmt.Fprintf(&b, "抽样：%d 个主键，%d 个存在字段差异，共 %d 处\n", r.Sampled, r.MismatchedKeys, r.MismatchCount)
+	writeKeys(&b, "B中缺失", r.MissingInB)
+	writeKeys(&b, "A中缺失", r.MissingInA)
+	if len(r.FieldStats) > 0 {
+		fields := make([]string, 0, len(r.FieldStats))
+		for field := range r.FieldStats {
+			fields = append(fields, field)
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			if r.FieldStats[fields[i]] != r.FieldStats[fields[j]] {
+				return r.FieldStats[fields[i]] > r.FieldStats[fields[j]]
+			}
+			return fields[i] < fields[j]
+		})
+		b.WriteString("\n差异字段：\n")
+		for _, field := range fields {
+			fmt.Fprintf(&b, "  %-30s %d\n", field, r.FieldStats[field])
+		}
+	}
+	if len(r.Mismatches) > 0 {
+		b.WriteString("\n差异明细：\n")
+		for _, m := range r.Mismatches {
+			fmt.Fprintf(&b, "  [%s] %s\n    A: %s\n    B: %s\n", m.Key, m.Field, m.A, m.B)
+		}
+		if r.MismatchCount > len(r.Mismatches) {
+			fmt.Fprintf(&b, "  ……另有 %d 处未列出\n", r.MismatchCount-len(r.Mismatches))
+		}
+	}
+	fmt.Fprintf(&b, "\n耗时：%s\n", r.Elapsed.Round(time.Millisecond))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeKeys 输出缺失主键（最多列出20个）
+func writeKeys(b *strings.Builder, title string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	shown := keys
+	if len(shown) > 20 {
+		shown = shown[:20]
+	}
+	fmt.Fprintf(b, "%s：%d 个（%s", title, len(keys), strings.Join(shown, ", "))
+	if len(keys) > len(shown) {
+		b.WriteString(", ...")
+	}
+	b.WriteString("）\n")
+}
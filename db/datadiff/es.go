@@ -0,0 +1,165 @@
+package datadiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/elasticSearch"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// ESSource Elasticsearch索引数据源
+type ESSource struct {
+	DbKey string                 // 连接标识
+	Index string                 // 索引名（不含前缀，自动拼接连接配置的前缀）
+	Key   string                 // 主键字段（默认_id，即文档ID）
+	Query map[string]interface{} // 查询条件（DSL中query部分，为空时匹配全部）
+}
+
+// Name 数据源描述
+func (s *ESSource) Name() string {
+	name := fmt.Sprintf("es:%s/%s", s.DbKey, s.Index)
+	if len(s.Query) > 0 {
+		name += " " + canonical(s.Query)
+	}
+	return name
+}
+
+// KeyField 主键字段名
+func (s *ESSource) KeyField() string {
+	if s.Key == "" {
+		return "_id"
+	}
+	return s.Key
+}
+
+// Count 满足条件的文档数
+func (s *ESSource) Count(ctx context.Context) (int64, error) {
+	var result struct {
+		Count int64 `json:"count"`
+	}
+	err := s.do(ctx, func(esdb *elasticSearch.ESDb, body *bytes.Reader) esapi.Request {
+		return esapi.CountRequest{Index: esdb.Index, Body: body}
+	}, map[string]interface{}{"query": s.query()}, &result)
+	return result.Count, err
+}
+
+// SampleKeys 使用random_score随机抽取主键
+func (s *ESSource) SampleKeys(ctx context.Context, n int) ([]interface{}, error) {
+	body := map[string]interface{}{
+		"size": n,
+		"query": map[string]interface{}{
+			"function_score": map[string]interface{}{
+				"query":        s.query(),
+				"random_score": map[string]interface{}{},
+			},
+		},
+	}
+	if s.KeyField() == "_id" {
+		body["_source"] = false
+	} else {
+		body["_source"] = []string{s.KeyField()}
+	}
+	docs, err := s.search(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		if k, ok := doc[s.KeyField()]; ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// Fetch 按主键读取满足查询条件的文档（_id使用ids查询，其他字段使用terms查询）
+func (s *ESSource) Fetch(ctx context.Context, keys []interface{}) (map[string]Doc, error) {
+	result := make(map[string]Doc, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, KeyString(k))
+	}
+	var query map[string]interface{}
+	if s.KeyField() == "_id" {
+		query = map[string]interface{}{"ids": map[string]interface{}{"values": values}}
+	} else {
+		query = map[string]interface{}{"terms": map[string]interface{}{s.KeyField(): values}}
+	}
+	query = map[string]interface{}{"bool": map[string]interface{}{"filter": []interface{}{s.query(), query}}}
+	docs, err := s.search(ctx, map[string]interface{}{"size": len(keys), "query": query})
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		result[KeyString(doc[s.KeyField()])] = doc
+	}
+	return result, nil
+}
+
+// search 执行查询，返回展开后的文档（包含_id）
+func (s *ESSource) search(ctx context.Context, body map[string]interface{}) ([]Doc, error) {
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID     string                 `json:"_id"`
+				Source map[string]interface{} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	err := s.do(ctx, func(esdb *elasticSearch.ESDb, body *bytes.Reader) esapi.Request {
+		return esapi.SearchRequest{Index: esdb.Index, Body: body}
+	}, body, &result)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]Doc, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		doc := make(Doc, len(hit.Source)+1)
+		flatten("", hit.Source, doc)
+		doc["_id"] = hit.ID
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// do 发送请求并解析响应（数值保留为json.Number，避免大整数精度丢失）
+func (s *ESSource) do(ctx context.Context, build func(esdb *elasticSearch.ESDb, body *bytes.Reader) esapi.Request, body map[string]interface{}, out interface{}) error {
+	esdb, err := elasticSearch.GetEsDB(s.DbKey)
+	if err != nil {
+		return err
+	}
+	if esdb.SetIndex(s.Index); esdb.Err != nil {
+		return esdb.Err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	res, err := build(esdb, bytes.NewReader(data)).Do(ctx, esdb.Client)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	raw, err := elasticSearch.DeZip(esdb.GzipStatus, res)
+	if err != nil {
+		return err
+	}
+	if res.IsError() {
+		return fmt.Errorf("ES请求失败：%s", string(raw))
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(out)
+}
+
+func (s *ESSource) query() map[string]interface{} {
+	if len(s.Query) == 0 {
+		return map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+	return s.Query
+}
@@ -0,0 +1,129 @@
+package datadiff
+
+import (
+	"context"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/mongoDb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strconv"
+)
+
+// MongoSource MongoDB集合数据源
+type MongoSource struct {
+	DbKey      string // 连接标识
+	Collection string // 集合名（不含前缀，自动拼接连接配置的表前缀）
+	Key        string // 主键字段（默认_id）
+	Filter     bson.M // 查询条件
+}
+
+// Name 数据源描述
+func (s *MongoSource) Name() string {
+	name := fmt.Sprintf("mongodb:%s/%s", s.DbKey, s.Collection)
+	if len(s.Filter) > 0 {
+		name += " " + canonical(s.Filter)
+	}
+	return name
+}
+
+// KeyField 主键字段名
+func (s *MongoSource) KeyField() string {
+	if s.Key == "" {
+		return "_id"
+	}
+	return s.Key
+}
+
+// Count 满足条件的文档数
+func (s *MongoSource) Count(ctx context.Context) (int64, error) {
+	coll, err := s.collection()
+	if err != nil {
+		return 0, err
+	}
+	return coll.CountDocuments(ctx, s.filter())
+}
+
+// SampleKeys 使用$sample随机抽取主键
+func (s *MongoSource) SampleKeys(ctx context.Context, n int) ([]interface{}, error) {
+	coll, err := s.collection()
+	if err != nil {
+		return nil, err
+	}
+	cur, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: s.filter()}},
+		{{Key: "$sample", Value: bson.M{"size": n}}},
+		{{Key: "$project", Value: bson.M{s.KeyField(): 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	keys := make([]interface{}, 0, n)
+	for cur.Next(ctx) {
+		var doc bson.M
+		if err = cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if k, ok := doc[s.KeyField()]; ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys, cur.Err()
+}
+
+// Fetch 按主键读取满足查询条件的文档（主键来自其他数据源时同时按字符串、数值、ObjectID形式匹配）
+func (s *MongoSource) Fetch(ctx context.Context, keys []interface{}) (map[string]Doc, error) {
+	docs := make(map[string]Doc, len(keys))
+	if len(keys) == 0 {
+		return docs, nil
+	}
+	coll, err := s.collection()
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]interface{}, 0, len(keys)*2)
+	for _, k := range keys {
+		candidates = append(candidates, k)
+		ks := KeyString(k)
+		if _, isString := k.(string); !isString {
+			candidates = append(candidates, ks)
+		}
+		if i, err := strconv.ParseInt(ks, 10, 64); err == nil {
+			candidates = append(candidates, i, int32(i))
+		}
+		if oid, err := primitive.ObjectIDFromHex(ks); err == nil {
+			candidates = append(candidates, oid)
+		}
+	}
+	cur, err := coll.Find(ctx, bson.M{"$and": bson.A{s.filter(), bson.M{s.KeyField(): bson.M{"$in": candidates}}}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var raw bson.M
+		if err = cur.Decode(&raw); err != nil {
+			return nil, err
+		}
+		doc := make(Doc, len(raw))
+		flatten("", raw, doc)
+		docs[KeyString(doc[s.KeyField()])] = doc
+	}
+	return docs, cur.Err()
+}
+
+func (s *MongoSource) collection() (*mongo.Collection, error) {
+	mdb, err := mongoDb.GetMongoDB(s.DbKey)
+	if err != nil {
+		return nil, err
+	}
+	return mdb.Db.Collection(mdb.DbPre + s.Collection), nil
+}
+
+func (s *MongoSource) filter() bson.M {
+	if s.Filter == nil {
+		return bson.M{}
+	}
+	return s.Filter
+}
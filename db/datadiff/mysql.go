@@ -0,0 +1,173 @@
+package datadiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+)
+
+var validIdentifier = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// MySQLSource MySQL表数据源
+type MySQLSource struct {
+	DbKey string        // 连接标识
+	Table string        // 表名（不含前缀，自动拼接连接配置的表前缀）
+	Key   string        // 主键字段（默认id）
+	Where string        // 查询条件（不含WHERE，如 status = ?）
+	Args  []interface{} // 查询条件参数
+}
+
+// Name 数据源描述
+func (s *MySQLSource) Name() string {
+	name := fmt.Sprintf("mysql:%s/%s", s.DbKey, s.Table)
+	if s.Where != "" {
+		name += " WHERE " + s.Where
+	}
+	return name
+}
+
+// KeyField 主键字段名
+func (s *MySQLSource) KeyField() string {
+	if s.Key == "" {
+		return "id"
+	}
+	return s.Key
+}
+
+// Count 满足条件的总行数
+func (s *MySQLSource) Count(ctx context.Context) (int64, error) {
+	mdb, table, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = mdb.Db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+s.where(""), s.Args...).Scan(&count)
+	return count, err
+}
+
+// SampleKeys 随机抽取主键：数值主键按主键区间随机定位（走索引），其他类型使用ORDER BY RAND()
+func (s *MySQLSource) SampleKeys(ctx context.Context, n int) ([]interface{}, error) {
+	mdb, table, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	key := "`" + s.KeyField() + "`"
+	var lo, hi sql.NullInt64
+	err = mdb.Db.QueryRowContext(ctx, "SELECT MIN("+key+"), MAX("+key+") FROM "+table+s.where(""), s.Args...).Scan(&lo, &hi)
+	if err != nil || !lo.Valid || !hi.Valid || hi.Int64-lo.Int64+1 <= int64(n)*2 {
+		// 非数值主键或数据量较小
+		return s.queryKeys(ctx, mdb.Db, "SELECT "+key+" FROM "+table+s.where("")+" ORDER BY RAND() LIMIT ?", append(append([]interface{}{}, s.Args...), n)...)
+	}
+	keys := make([]interface{}, 0, n)
+	seen := make(map[string]struct{}, n)
+	query := "SELECT " + key + " FROM " + table + s.where(key+" >= ?") + " ORDER BY " + key + " LIMIT 1"
+	for attempt := 0; len(keys) < n && attempt < n*3; attempt++ {
+		pivot := lo.Int64 + rand.Int64N(hi.Int64-lo.Int64+1)
+		found, err := s.queryKeys(ctx, mdb.Db, query, append(append([]interface{}{}, s.Args...), pivot)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range found {
+			if _, ok := seen[KeyString(k)]; !ok {
+				seen[KeyString(k)] = struct{}{}
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Fetch 按主键读取满足查询条件的行
+func (s *MySQLSource) Fetch(ctx context.Context, keys []interface{}) (map[string]Doc, error) {
+	docs := make(map[string]Doc, len(keys))
+	if len(keys) == 0 {
+		return docs, nil
+	}
+	mdb, table, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	args := append(make([]interface{}, 0, len(s.Args)+len(keys)), s.Args...)
+	for _, k := range keys {
+		args = append(args, KeyString(k))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+	rows, err := mdb.Db.QueryContext(ctx, "SELECT * FROM "+table+s.where("`"+s.KeyField()+"` IN ("+placeholders+")"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		doc := make(Doc, len(cols))
+		for i, col := range cols {
+			if b, ok := vals[i].([]byte); ok {
+				vals[i] = string(b)
+			}
+			doc[col] = vals[i]
+		}
+		docs[KeyString(doc[s.KeyField()])] = doc
+	}
+	return docs, rows.Err()
+}
+
+// open 获取连接并校验表名、主键名
+func (s *MySQLSource) open() (*mysql.MysqlDb, string, error) {
+	if !validIdentifier.MatchString(s.Table) || !validIdentifier.MatchString(s.KeyField()) {
+		return nil, "", fmt.Errorf("表名或主键字段非法：%s.%s", s.Table, s.KeyField())
+	}
+	mdb, err := mysql.GetMysqlDB(s.DbKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return mdb, "`" + mdb.DbPre + s.Table + "`", nil
+}
+
+// where 拼接查询条件与附加条件
+func (s *MySQLSource) where(extra string) string {
+	switch {
+	case s.Where != "" && extra != "":
+		return " WHERE (" + s.Where + ") AND " + extra
+	case s.Where != "":
+		return " WHERE " + s.Where
+	case extra != "":
+		return " WHERE " + extra
+	}
+	return ""
+}
+
+// queryKeys 执行只返回主键列的查询
+func (s *MySQLSource) queryKeys(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]interface{}, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]interface{}, 0)
+	for rows.Next() {
+		var k interface{}
+		if err = rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		if b, ok := k.([]byte); ok {
+			k = string(b)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
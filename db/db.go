@@ -1,21 +1,36 @@
 package db
 
 import (
+	"errors"
 	"fmt"
+	"github.com/dfpopp/go-dai/config"
 	"github.com/dfpopp/go-dai/db/elasticSearch"
+	"github.com/dfpopp/go-dai/db/fieldcrypt"
 	"github.com/dfpopp/go-dai/db/mongoDb"
 	"github.com/dfpopp/go-dai/db/mysql"
 	"github.com/dfpopp/go-dai/db/redisDb"
 	"github.com/dfpopp/go-dai/function"
+	"github.com/dfpopp/go-dai/logger"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 )
 
+// StartDb 初始化数据库连接并注册退出信号钩子（收到SIGINT/SIGTERM/SIGQUIT时关闭连接并退出进程）；
+// 由bootstrap.Boot统一停机时使用InitDb，连接在服务排空后由CloseDb关闭
 func StartDb(dbTypeList []string) {
 	// 注册服务退出信号，触发 所有数据库连接关闭（优雅退出）
 	registerShutdownHook(dbTypeList)
+	InitDb(dbTypeList)
+}
+
+// InitDb 初始化数据库连接（不注册退出信号钩子）
+func InitDb(dbTypeList []string) {
+	// 敏感字段加密（配置field_crypt时），须在连接初始化前完成
+	if err := fieldcrypt.InitFromConfig(config.GetDatabaseConfig().FieldCrypt); err != nil {
+		logger.Error("敏感字段加密配置无效：", err)
+	}
 	for _, dbType := range dbTypeList {
 		switch dbType {
 		case "mysql":
@@ -32,6 +47,41 @@ func StartDb(dbTypeList []string) {
 	}
 }
 
+// CloseDb 并行关闭数据库连接，返回各类型关闭失败的合并错误
+func CloseDb(dbTypeList []string) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, dbType := range dbTypeList {
+		var closeFn func() error
+		switch dbType {
+		case "mysql":
+			closeFn = mysql.CloseMysql
+		case "mongodb":
+			closeFn = mongoDb.CloseMongoDb
+		case "redis":
+			closeFn = redisDb.CloseRedis
+		case "es":
+			closeFn = elasticSearch.CloseES
+		default:
+			continue
+		}
+		wg.Add(1)
+		go func(dbType string, closeFn func() error) {
+			defer wg.Done()
+			if err := closeFn(); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s 连接关闭失败: %w", dbType, err))
+				mu.Unlock()
+			}
+		}(dbType, closeFn)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
 // 注册服务退出钩子（监听信号，自动关闭 mysql 连接）
 func registerShutdownHook(dbTypeList []string) {
 	sigCh := make(chan os.Signal, 1)
@@ -0,0 +1,226 @@
+package elasticSearch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 查询结果缓存：FindAll/FindCount按 索引+规范化DSL 的指纹将结果缓存到Redis，吸收热点重复搜索。
+// 失效按索引代数（generation）实现：框架内的写操作（Insert/Update/Delete/Bulk等）递增相关索引的代数，
+// 旧代数的缓存不再命中并随TTL过期；代数在查询前读取，查询期间发生的写入不会使旧结果以新代数写回。
+// 注意：通配符索引、别名查询不会因具体索引的写入而失效；框架外的写入需调用InvalidateCache。
+
+const (
+	cacheNamespace         = "es_cache:"      // Redis键命名空间（会再拼接Redis表前缀）
+	defaultCacheTTL        = 60 * time.Second // 缓存默认有效期
+	defaultCacheMaxSize    = 1 << 20          // 单条缓存结果默认最大字节数
+	defaultCacheMaxEntries = 1000             // 每个索引默认最多缓存的查询数
+	defaultInvalidateDelay = time.Second      // 写入后再次失效的默认延迟（ES默认refresh_interval为1秒）
+	cacheRedisTimeout      = time.Second      // 延迟失效等后台Redis操作的超时
+)
+
+var (
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+)
+
+// QueryCacheStat 查询缓存命中统计（进程内累计）
+type QueryCacheStat struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// QueryCacheStats 返回查询缓存的命中统计
+func QueryCacheStats() QueryCacheStat {
+	return QueryCacheStat{Hits: cacheHits.Load(), Misses: cacheMisses.Load()}
+}
+
+// queryCache 连接级的缓存配置
+type queryCache struct {
+	redisDbKey      string
+	ttl             time.Duration
+	maxSize         int
+	maxEntries      int64
+	invalidateDelay time.Duration
+}
+
+// cacheLookup 一次查询的缓存定位结果
+type cacheLookup struct {
+	store    *redisDb.KVStore
+	key      string // 结果键（含索引代数）
+	countKey string // 当前代数下已缓存查询数的计数键
+	data     []byte // 命中时的缓存内容
+}
+
+// newQueryCache 按配置创建缓存（未配置Redis连接时返回nil，即不启用）
+func newQueryCache(cfg config.EsCacheConfig) *queryCache {
+	if cfg.RedisDb == "" {
+		return nil
+	}
+	c := &queryCache{
+		redisDbKey:      cfg.RedisDb,
+		ttl:             time.Duration(cfg.TTL) * time.Second,
+		maxSize:         cfg.MaxSize,
+		maxEntries:      int64(cfg.MaxEntries),
+		invalidateDelay: time.Duration(cfg.InvalidateDelay) * time.Millisecond,
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultCacheTTL
+	}
+	if c.maxSize <= 0 {
+		c.maxSize = defaultCacheMaxSize
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = defaultCacheMaxEntries
+	}
+	if c.invalidateDelay <= 0 {
+		c.invalidateDelay = defaultInvalidateDelay
+	}
+	return c
+}
+
+// SetCache 为本次FindAll/FindCount开启结果缓存（ttl<=0时使用配置的有效期）。
+// 连接未配置cache.redis_db时忽略，查询照常直连ES
+func (db *ESDb) SetCache(ttl time.Duration) *ESDb {
+	if db.Err != nil || db.cache == nil {
+		return db
+	}
+	if ttl <= 0 {
+		ttl = db.cache.ttl
+	}
+	db.cacheTTL = ttl
+	return db
+}
+
+// InvalidateCache 使当前索引（SetIndex）的查询缓存失效，用于框架之外写入ES的场景（如Logstash同步）
+func (db *ESDb) InvalidateCache(ctx context.Context) error {
+	defer db.clearData(false)
+	if db.Err != nil {
+		return db.Err
+	}
+	if db.cache == nil {
+		return nil
+	}
+	if len(db.Index) == 0 {
+		return errors.New("未指定索引")
+	}
+	return db.cache.bump(ctx, db.Index)
+}
+
+// cacheGet 查找缓存（未开启缓存或Redis异常时返回nil，由调用方直连ES）
+func (db *ESDb) cacheGet(ctx context.Context, op string, dsl []byte) *cacheLookup {
+	if db.cache == nil || db.cacheTTL <= 0 {
+		return nil
+	}
+	store, err := redisDb.GetStore(db.cache.redisDbKey, cacheNamespace)
+	if err != nil {
+		logger.Warn("ES查询缓存不可用：", err)
+		return nil
+	}
+	indexes := append([]string(nil), db.Index...)
+	sort.Strings(indexes)
+	gens := make([]string, len(indexes))
+	for i, index := range indexes {
+		gen, err := store.Get(ctx, "gen:"+index)
+		if err != nil && !errors.Is(err, redisDb.ErrNotFound) {
+			logger.Warn("ES查询缓存读取索引代数失败：", err)
+			return nil
+		}
+		gens[i] = "0"
+		if len(gen) > 0 {
+			gens[i] = string(gen)
+		}
+	}
+	scope := strings.Join(indexes, ",") + "@" + strings.Join(gens, ",")
+	lookup := &cacheLookup{store: store, key: "q:" + fingerprint(op, scope, dsl), countKey: "n:" + scope}
+	data, err := store.Get(ctx, lookup.key)
+	switch {
+	case err == nil:
+		cacheHits.Add(1)
+		lookup.data = data
+	case errors.Is(err, redisDb.ErrNotFound):
+		cacheMisses.Add(1)
+	default:
+		logger.Warn("ES查询缓存读取失败：", err)
+		return nil
+	}
+	return lookup
+}
+
+// cacheSet 写入查询结果（超过单条大小上限或当前代数缓存数已满时跳过）
+func (db *ESDb) cacheSet(ctx context.Context, lookup *cacheLookup, data []byte) {
+	if lookup == nil || len(data) > db.cache.maxSize {
+		return
+	}
+	count, err := lookup.store.IncrBy(ctx, lookup.countKey, 1, db.cache.ttl)
+	if err != nil || count > db.cache.maxEntries {
+		return
+	}
+	if err := lookup.store.Set(ctx, lookup.key, data, db.cacheTTL); err != nil {
+		logger.Warn("ES查询缓存写入失败：", err)
+	}
+}
+
+// invalidateCache 写操作后使相关索引的缓存失效：立即递增代数，并在refresh间隔后再递增一次，
+// 避免写入尚未refresh可见时的查询结果以新代数被缓存
+func (db *ESDb) invalidateCache(indexes []string) {
+	if db.cache == nil || len(indexes) == 0 {
+		return
+	}
+	c := db.cache
+	indexes = append([]string(nil), indexes...)
+	bump := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cacheRedisTimeout)
+		defer cancel()
+		if err := c.bump(ctx, indexes); err != nil {
+			logger.Warn("ES查询缓存失效失败 [索引：", strings.Join(indexes, ","), "]：", err)
+		}
+	}
+	bump()
+	time.AfterFunc(c.invalidateDelay, bump)
+}
+
+// bump 递增索引代数
+func (c *queryCache) bump(ctx context.Context, indexes []string) error {
+	store, err := redisDb.GetStore(c.redisDbKey, cacheNamespace)
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if _, err := store.IncrBy(ctx, "gen:"+index, 1, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fingerprint 查询指纹：DSL经JSON往返规范化（对象键排序、数值格式统一）后与操作类型、索引代数一起哈希
+func fingerprint(op, scope string, dsl []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(dsl, &v); err == nil {
+		if normalized, err := json.Marshal(v); err == nil {
+			dsl = normalized
+		}
+	}
+	h := sha256.New()
+	h.Write([]byte(op + "\x00" + scope + "\x00"))
+	h.Write(dsl)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseCachedCount 解析缓存的计数结果
+func parseCachedCount(data []byte) (int64, bool) {
+	count, err := strconv.ParseInt(string(data), 10, 64)
+	return count, err == nil
+}
@@ -0,0 +1,69 @@
+package elasticSearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/cursor"
+)
+
+// sortKey 命中记录的排序值（FindPage期间暂存在文档中，返回前移除）
+const sortKey = "_sort"
+
+// SetCursor 设置游标分页（search_after）：token为上一页返回的next_cursor/prev_cursor（首页为空），
+// fields为排序字段（最后一个须唯一，如keyword类型的业务主键），会覆盖SetSort与SetLimit，配合FindPage使用
+func (db *ESDb) SetCursor(token string, limit int64, fields ...cursor.Field) *ESDb {
+	if db.Err != nil {
+		return db
+	}
+	for _, f := range fields {
+		if !validIdentifierRegex.MatchString(f.Name) {
+			db.Err = fmt.Errorf("排序字段[%s]非法", f.Name)
+			return db
+		}
+	}
+	q, err := cursor.NewQuery(token, limit, fields)
+	if err != nil {
+		db.Err = err
+		return db
+	}
+	db.page = q
+	return db
+}
+
+// FindPage 执行游标分页查询，返回当前页数据与上一页/下一页游标（TotalCount为总匹配数）
+func (db *ESDb) FindPage(ctx context.Context) (*cursor.Page, error) {
+	defer db.clearData(false)
+	if db.Err != nil {
+		return nil, db.Err
+	}
+	q := db.page
+	if q == nil {
+		return nil, errors.New("未设置游标分页（请先调用SetCursor）")
+	}
+	db.Sort = make([]string, len(q.Fields))
+	for i, f := range q.Fields {
+		if q.Desc(i) {
+			db.Sort[i] = f.Name + ":desc"
+		} else {
+			db.Sort[i] = f.Name + ":asc"
+		}
+	}
+	db.From = 0
+	db.Size = q.Fetch()
+	db.FindAll(ctx)
+	if db.Err != nil {
+		return nil, db.Err
+	}
+	page, err := q.Page(db.Data, func(row map[string]interface{}) []interface{} {
+		values, _ := row[sortKey].([]interface{})
+		return values
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range page.Data {
+		delete(row, sortKey)
+	}
+	return page, nil
+}
@@ -8,15 +8,19 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/chaos"
 	"github.com/dfpopp/go-dai/function"
 	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/tracing"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.opentelemetry.io/otel"
 	"io"
 	"net"
 	"net/http"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,17 +41,17 @@ const (
 func InitEs() {
 	cfgMap := config.GetEsConfig()
 	for dbKey, cfg := range cfgMap {
-		client, transport, err := connect(cfg)
+		client, transport, err := connect(dbKey, cfg)
 		if err != nil {
 			logger.Error(fmt.Sprintf("ES连接初始化失败（%s）: %v", dbKey, err))
 		} else {
-			multiESPool.Store(dbKey, DbObj{Client: client, Transport: transport, Pre: cfg.Pre, GzipStatus: cfg.GzipStatus})
+			multiESPool.Store(dbKey, DbObj{Client: client, Transport: transport, Pre: cfg.Pre, GzipStatus: cfg.GzipStatus, cache: newQueryCache(cfg.Cache)})
 		}
 	}
 }
 
 // connect 建立MongoDB连接
-func connect(cfg config.EsConfig) (*elasticsearch.Client, *http.Transport, error) {
+func connect(dbKey string, cfg config.EsConfig) (*elasticsearch.Client, *http.Transport, error) {
 	// 默认配置
 	if cfg.Host == "" {
 		cfg.Host = "localhost"
@@ -118,8 +122,8 @@ func connect(cfg config.EsConfig) (*elasticsearch.Client, *http.Transport, error
 		Addresses: []string{address},
 		Username:  cfg.User,
 		Password:  cfg.Pwd,
-		// 自定义HTTP客户端（包含连接池+超时）
-		Transport: transport,
+		// 自定义HTTP客户端（包含连接池+超时，经故障注入层包装，仅测试环境启用db_chaos时生效）
+		Transport: chaos.RoundTripper("es", dbKey, transport),
 		// 请求头配置
 		Header: header,
 		// 重试配置（可选，根据业务调整）
@@ -127,6 +131,10 @@ func connect(cfg config.EsConfig) (*elasticsearch.Client, *http.Transport, error
 		RetryBackoff:  func(i int) time.Duration { return time.Duration(i) * 100 * time.Millisecond }, // 退避策略
 		MaxRetries:    3,                                                                              // 最大重试次数
 	}
+	// 链路追踪：使用官方客户端内置的OpenTelemetry埋点
+	if tracing.Enabled() {
+		esCfg.Instrumentation = elasticsearch.NewOpenTelemetryInstrumentation(otel.GetTracerProvider(), tracing.CaptureStatement())
+	}
 
 	// 6. 创建ES客户端
 	client, err := elasticsearch.NewClient(esCfg)
@@ -185,6 +193,7 @@ func GetEsDB(dbKey string) (*ESDb, error) {
 		BulkActions:   nil,
 		Data:          nil,
 		Err:           nil,
+		cache:         dbObj.cache,
 	}, nil
 }
 func (db *ESDb) SetIndex(tables string) *ESDb {
@@ -623,6 +632,10 @@ func (db *ESDb) FindAll(ctx context.Context) *ESDb {
 	// 分页
 	queryDSL["from"] = db.From
 	queryDSL["size"] = db.Size
+	// 游标分页：从游标记录之后继续
+	if db.page != nil && db.page.Cursor != nil {
+		queryDSL["search_after"] = db.page.Cursor.Values
+	}
 	// 高亮
 	if len(db.Highlight) > 0 {
 		queryDSL["highlight"] = db.Highlight
@@ -638,6 +651,14 @@ func (db *ESDb) FindAll(ctx context.Context) *ESDb {
 		db.Err = fmt.Errorf("序列化查询DSL失败：%w", err)
 		return db
 	}
+	cached := db.cacheGet(ctx, "search", queryBytes)
+	if cached != nil && cached.data != nil {
+		var result map[string]interface{}
+		if err := json.Unmarshal(cached.data, &result); err == nil {
+			db.fillSearchResult(result)
+			return db
+		}
+	}
 	// 3. 执行查询
 	req := esapi.SearchRequest{
 		Index:  db.Index,
@@ -652,7 +673,7 @@ func (db *ESDb) FindAll(ctx context.Context) *ESDb {
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			logger.Error("ES查询关闭body失败 [索引：%s]，错误：%v", strings.Join(db.Index, ","), err)
+			logger.Errorf("ES查询关闭body失败 [索引：%s]，错误：%v", strings.Join(db.Index, ","), err)
 		}
 	}(res.Body)
 	body, err := DeZip(db.GzipStatus, res)
@@ -671,28 +692,36 @@ func (db *ESDb) FindAll(ctx context.Context) *ESDb {
 		db.Err = fmt.Errorf("ES查询错误：%s", result["error"].(map[string]interface{})["reason"])
 		return db
 	}
-	// 6. 提取文档数据
+	// 6. 提取文档数据与聚合结果
+	db.fillSearchResult(result)
+	if db.Err == nil {
+		db.cacheSet(ctx, cached, body)
+	}
+	return db
+}
+
+// fillSearchResult 从_search响应中提取文档（Data）、总匹配数（TotalCount）与聚合结果（AggsData）
+func (db *ESDb) fillSearchResult(result map[string]interface{}) {
 	hitsVal, ok := result["hits"]
 	if !ok {
 		db.Err = errors.New("ES响应无hits字段")
-		return db
+		return
 	}
 	hitsMap, ok := hitsVal.(map[string]interface{})
 	if !ok {
 		db.Err = errors.New("ES响应hits字段类型错误")
-		return db
+		return
 	}
 	hitsList, ok := hitsMap["hits"].([]interface{})
 	if !ok {
 		db.Err = errors.New("ES响应hits.hits字段类型错误")
-		return db
+		return
 	}
-	// 新增：提取总匹配数（聚合场景常用）
+	// 提取总匹配数（聚合场景常用）
 	if totalVal, ok := hitsMap["total"]; ok {
 		totalMap, ok := totalVal.(map[string]interface{})
 		if ok {
 			if totalCount, ok := totalMap["value"].(float64); ok {
-				// 可新增 TotalCount 字段到 ESDb 结构体，存储总匹配数
 				db.TotalCount = int64(totalCount)
 			}
 		}
@@ -702,7 +731,7 @@ func (db *ESDb) FindAll(ctx context.Context) *ESDb {
 		hitMap, ok := hit.(map[string]interface{})
 		if !ok {
 			db.Err = fmt.Errorf("文档数据类型错误：%T", hit)
-			return db
+			return
 		}
 		doc := make(map[string]interface{})
 		// 文档元数据
@@ -728,22 +757,23 @@ func (db *ESDb) FindAll(ctx context.Context) *ESDb {
 		if highlight, ok := hitMap["highlight"].(map[string]interface{}); ok {
 			doc["_highlight"] = highlight
 		}
+		// 排序值（游标分页生成游标用）
+		if db.page != nil {
+			doc[sortKey] = hitMap["sort"]
+		}
 		data = append(data, doc)
 	}
 	db.Data = data
-	// 7. 聚合结果（如果有）
-	aggsVal, hasAggs := result["aggregations"]
-	if hasAggs {
-		if aggs, ok := aggsVal.(map[string]interface{}); ok {
-			db.AggsData = aggs
-		} else {
+	// 聚合结果（无 aggregations 字段时不报错，仅置空 AggsData）
+	db.AggsData = nil
+	if aggsVal, hasAggs := result["aggregations"]; hasAggs {
+		aggs, ok := aggsVal.(map[string]interface{})
+		if !ok {
 			db.Err = errors.New("ES响应aggregations字段类型错误")
-			return db
+			return
 		}
+		db.AggsData = aggs
 	}
-	// 无 aggregations 字段时不报错，仅置空 AggsData
-	db.AggsData = nil
-	return db
 }
 
 // FindCount 统计文档数量（对标MySQL的FindCount）
@@ -772,6 +802,12 @@ func (db *ESDb) FindCount(ctx context.Context) (int64, error) {
 	if err != nil {
 		return 0, fmt.Errorf("序列化计数DSL失败：%w", err)
 	}
+	cached := db.cacheGet(ctx, "count", countBytes)
+	if cached != nil && cached.data != nil {
+		if count, ok := parseCachedCount(cached.data); ok {
+			return count, nil
+		}
+	}
 
 	// 执行计数请求
 	req := esapi.CountRequest{
@@ -808,8 +844,10 @@ func (db *ESDb) FindCount(ctx context.Context) (int64, error) {
 		if !ok {
 			return 0, fmt.Errorf("count字段类型错误（预期float64/int64）：%T", countVal)
 		}
+		db.cacheSet(ctx, cached, []byte(strconv.FormatInt(countInt, 10)))
 		return countInt, nil
 	}
+	db.cacheSet(ctx, cached, []byte(strconv.FormatInt(int64(countFloat), 10)))
 	return int64(countFloat), nil
 }
 
@@ -885,6 +923,7 @@ func (db *ESDb) GetById(ctx context.Context, id string) (string, error) {
 // Insert 新增单文档
 func (db *ESDb) Insert(ctx context.Context, id string, data map[string]interface{}) (string, error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	if db.Err != nil {
 		return "", db.Err
 	}
@@ -948,6 +987,7 @@ func (db *ESDb) Insert(ctx context.Context, id string, data map[string]interface
 // 返回：新增数、更新数、错误
 func (db *ESDb) InsertAll(ctx context.Context, dataList []map[string]interface{}) (insertCount int64, updateCount int64, err error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	// 链式错误传递
 	if db.Err != nil {
 		return 0, 0, db.Err
@@ -1036,7 +1076,7 @@ func (db *ESDb) InsertAll(ctx context.Context, dataList []map[string]interface{}
 		// 处理失败项
 		if item.Index.Error.Type != "" {
 			failCount++
-			logger.Error("ES文档[%s]操作失败：%s-%s", item.Index.ID, item.Index.Error.Type, item.Index.Error.Reason)
+			logger.Errorf("ES文档[%s]操作失败：%s-%s", item.Index.ID, item.Index.Error.Type, item.Index.Error.Reason)
 			continue
 		}
 		// 统计新增/更新
@@ -1059,6 +1099,7 @@ func (db *ESDb) InsertAll(ctx context.Context, dataList []map[string]interface{}
 // UpdateById 按文档ID更新单文档
 func (db *ESDb) UpdateById(ctx context.Context, id string, data map[string]interface{}) (bool, error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	if db.Err != nil {
 		return false, db.Err
 	}
@@ -1122,6 +1163,7 @@ func (db *ESDb) UpdateById(ctx context.Context, id string, data map[string]inter
 // 返回：成功覆盖数、失败ID及原因、错误
 func (db *ESDb) UpdateByFull(ctx context.Context, dataList []map[string]interface{}) (successCount int64, failMap map[string]string, err error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	// 链式错误传递
 	if db.Err != nil {
 		return 0, nil, db.Err
@@ -1218,7 +1260,7 @@ func (db *ESDb) UpdateByFull(ctx context.Context, dataList []map[string]interfac
 		// 处理失败项
 		if item.Index.Error.Type != "" {
 			failMap[docID] = fmt.Sprintf("%s：%s", item.Index.Error.Type, item.Index.Error.Reason)
-			logger.Error("ES文档[%s]全量覆盖失败：%v", docID, failMap[docID])
+			logger.Errorf("ES文档[%s]全量覆盖失败：%v", docID, failMap[docID])
 			continue
 		}
 		// 成功覆盖（result为"updated"）
@@ -1227,7 +1269,7 @@ func (db *ESDb) UpdateByFull(ctx context.Context, dataList []map[string]interfac
 		} else if item.Index.Result == "created" {
 			// 文档不存在时会新增，视为“覆盖失败”（按需调整）
 			failMap[docID] = "文档不存在，已新增（非预期覆盖）"
-			logger.Error("ES文档[%s]全量覆盖失败：文档不存在，已新增", docID)
+			logger.Errorf("ES文档[%s]全量覆盖失败：文档不存在，已新增", docID)
 		}
 	}
 
@@ -1249,6 +1291,7 @@ func (db *ESDb) UpdateByFull(ctx context.Context, dataList []map[string]interfac
 // 返回：成功更新数、失败ID及原因、错误
 func (db *ESDb) UpdateByPartial(ctx context.Context, dataList []map[string]interface{}) (successCount int64, failMap map[string]string, err error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	// 链式错误传递
 	if db.Err != nil {
 		return 0, nil, db.Err
@@ -1365,7 +1408,7 @@ func (db *ESDb) UpdateByPartial(ctx context.Context, dataList []map[string]inter
 		// 处理失败项
 		if item.Update.Error.Type != "" {
 			failMap[docID] = fmt.Sprintf("%s：%s", item.Update.Error.Type, item.Update.Error.Reason)
-			logger.Error("ES文档[%s]部分更新失败：%v", docID, failMap[docID])
+			logger.Errorf("ES文档[%s]部分更新失败：%v", docID, failMap[docID])
 			continue
 		}
 		// 成功更新（result为"updated"）
@@ -1374,10 +1417,10 @@ func (db *ESDb) UpdateByPartial(ctx context.Context, dataList []map[string]inter
 		} else if item.Update.Result == "noop" {
 			// 无更新（字段值未变化），视为成功或失败按需调整
 			failMap[docID] = "无字段更新（值未变化）"
-			logger.Error("ES文档[%s]部分更新无操作：字段值未变化", docID)
+			logger.Errorf("ES文档[%s]部分更新无操作：字段值未变化", docID)
 		} else if item.Update.Result == "not_found" {
 			failMap[docID] = "文档不存在"
-			logger.Error("ES文档[%s]部分更新失败：文档不存在", docID)
+			logger.Errorf("ES文档[%s]部分更新失败：文档不存在", docID)
 		}
 	}
 
@@ -1400,6 +1443,7 @@ func (db *ESDb) UpdateByPartial(ctx context.Context, dataList []map[string]inter
 // 返回：成功更新数、失败数、错误
 func (db *ESDb) Update(ctx context.Context, updateDoc map[string]interface{}) (updatedCount int64, failCount int64, err error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	// 链式错误传递
 	if db.Err != nil {
 		return 0, 0, db.Err
@@ -1486,7 +1530,7 @@ func (db *ESDb) Update(ctx context.Context, updateDoc map[string]interface{}) (u
 		failReason := make([]string, 0, failCount)
 		for _, f := range resp.Failures {
 			failReason = append(failReason, fmt.Sprintf("索引[%s]：%s-%s", f.Index, f.Type, f.Reason))
-			logger.Error("ES条件更新失败：%v", failReason[len(failReason)-1])
+			logger.Errorf("ES条件更新失败：%v", failReason[len(failReason)-1])
 		}
 		err = fmt.Errorf("条件更新部分失败：成功更新[%d]（含无变化[%d]），失败[%d]，失败原因：%v",
 			updatedCount, resp.Noops, failCount, strings.Join(failReason, "; "))
@@ -1498,6 +1542,7 @@ func (db *ESDb) Update(ctx context.Context, updateDoc map[string]interface{}) (u
 // DeleteById 删除单文档
 func (db *ESDb) DeleteById(ctx context.Context, id string) (bool, error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	if db.Err != nil {
 		return false, db.Err
 	}
@@ -1546,6 +1591,7 @@ func (db *ESDb) DeleteById(ctx context.Context, id string) (bool, error) {
 // 返回：成功删除数、失败ID及原因、错误
 func (db *ESDb) DeleteByIDs(ctx context.Context, ids []string) (successCount int64, failMap map[string]string, err error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	// 链式错误传递
 	if db.Err != nil {
 		return 0, nil, db.Err
@@ -1635,7 +1681,7 @@ func (db *ESDb) DeleteByIDs(ctx context.Context, ids []string) (successCount int
 		// 处理失败项
 		if item.Delete.Error.Type != "" {
 			failMap[docID] = fmt.Sprintf("%s：%s", item.Delete.Error.Type, item.Delete.Error.Reason)
-			logger.Error("ES文档[%s]删除失败：%v", docID, failMap[docID])
+			logger.Errorf("ES文档[%s]删除失败：%v", docID, failMap[docID])
 			continue
 		}
 		// 成功删除（result为"deleted"，不存在则为"not_found"）
@@ -1643,7 +1689,7 @@ func (db *ESDb) DeleteByIDs(ctx context.Context, ids []string) (successCount int
 			successCount++
 		} else if item.Delete.Result == "not_found" {
 			failMap[docID] = "文档不存在"
-			logger.Error("ES文档[%s]删除失败：文档不存在", docID)
+			logger.Errorf("ES文档[%s]删除失败：文档不存在", docID)
 		}
 	}
 
@@ -1664,6 +1710,7 @@ func (db *ESDb) DeleteByIDs(ctx context.Context, ids []string) (successCount int
 // 返回：成功删除数、失败数、错误
 func (db *ESDb) Delete(ctx context.Context) (deletedCount int64, failCount int64, err error) {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	// 链式错误传递
 	if db.Err != nil {
 		return 0, 0, db.Err
@@ -1751,7 +1798,7 @@ func (db *ESDb) Delete(ctx context.Context) (deletedCount int64, failCount int64
 		failReason := make([]string, 0, failCount)
 		for _, f := range resp.Failures {
 			failReason = append(failReason, fmt.Sprintf("索引[%s]：%s-%s", f.Index, f.Type, f.Reason))
-			logger.Error("ES条件删除失败：%v", failReason[len(failReason)-1])
+			logger.Errorf("ES条件删除失败：%v", failReason[len(failReason)-1])
 		}
 		err = fmt.Errorf("条件删除部分失败：成功删除[%d]，失败[%d]，失败原因：%v",
 			deletedCount, failCount, strings.Join(failReason, "; "))
@@ -1798,6 +1845,7 @@ func (db *ESDb) AddBulkInsert(data map[string]interface{}) *ESDb {
 		return db
 	}
 	db.BulkActions = append(db.BulkActions, string(metaBytes), string(dataBytes))
+	db.addBulkIndex()
 	return db
 }
 
@@ -1832,6 +1880,7 @@ func (db *ESDb) AddBulkUpdate(data map[string]interface{}) *ESDb {
 		return db
 	}
 	db.BulkActions = append(db.BulkActions, string(metaBytes), string(updateBytes))
+	db.addBulkIndex()
 	return db
 }
 
@@ -1860,12 +1909,24 @@ func (db *ESDb) AddBulkDelete() *ESDb {
 		return db
 	}
 	db.BulkActions = append(db.BulkActions, string(metaBytes))
+	db.addBulkIndex()
 	return db
 }
 
+// addBulkIndex 记录批量操作涉及的索引
+func (db *ESDb) addBulkIndex() {
+	for _, index := range db.bulkIndex {
+		if index == db.Index[0] {
+			return
+		}
+	}
+	db.bulkIndex = append(db.bulkIndex, db.Index[0])
+}
+
 // Commit 提交批量操作（对标MySQL的Commit）
 func (db *ESDb) Commit(ctx context.Context) (int64, error) {
 	defer db.clearData(true)
+	defer db.invalidateCache(db.bulkIndex)
 	if db.Err != nil {
 		return 0, db.Err
 	}
@@ -1999,6 +2060,7 @@ func (db *ESDb) CreateIndex(ctx context.Context, mapping map[string]interface{})
 // 返回：错误信息
 func (db *ESDb) DeleteIndex(ctx context.Context) error {
 	defer db.clearData(false)
+	defer db.invalidateCache(db.Index)
 	// 链式错误传递
 	if db.Err != nil {
 		return db.Err
@@ -2062,7 +2124,7 @@ func (db *ESDb) DeleteIndex(ctx context.Context) error {
 				errorReason = reason
 				// 忽略"索引不存在"的错误
 				if strings.Contains(errorReason, "no such index") {
-					logger.Error("索引[%s]不存在，无需删除", strings.Join(db.Index, ","))
+					logger.Errorf("索引[%s]不存在，无需删除", strings.Join(db.Index, ","))
 					return nil
 				}
 			}
@@ -2253,8 +2315,11 @@ func (db *ESDb) clearData(isClearTx bool) {
 	db.AggsData = nil
 	db.TotalCount = int64(0)
 	db.Err = nil
+	db.cacheTTL = 0
+	db.page = nil
 	if isClearTx {
 		db.BulkActions = nil
+		db.bulkIndex = nil
 	}
 }
 
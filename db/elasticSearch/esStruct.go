@@ -1,8 +1,10 @@
 package elasticSearch
 
 import (
+	"github.com/dfpopp/go-dai/db/cursor"
 	"github.com/elastic/go-elasticsearch/v8"
 	"net/http"
+	"time"
 )
 
 // BoolClauseType 定义Bool子句类型（约束合法的bool子句）
@@ -30,12 +32,17 @@ type ESDb struct {
 	AggsData      map[string]interface{} // 新增：专存聚合结果
 	TotalCount    int64
 	Err           error
+	cache         *queryCache   // 查询结果缓存（未配置时为nil）
+	cacheTTL      time.Duration // 本次查询的缓存有效期（SetCache设置，0表示不缓存）
+	bulkIndex     []string      // 批量操作涉及的索引（提交后使其缓存失效）
+	page          *cursor.Query // 游标分页（SetCursor设置）
 }
 type DbObj struct {
 	Client     *elasticsearch.Client // 复用全局数据库连接池
 	Transport  *http.Transport
 	Pre        string
 	GzipStatus bool //响应内容是否开启gzip压缩
+	cache      *queryCache
 }
 
 // HighlightOption 定义高亮配置的可选参数
@@ -0,0 +1,215 @@
+package elasticSearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"io"
+	"strconv"
+	"time"
+)
+
+// IndexStat 索引统计（来自_stats接口，数值为累计值，速率需用两次采样通过Rate计算）
+type IndexStat struct {
+	Index             string `json:"index"`
+	DocsCount         int64  `json:"docs_count"`           // 文档数（主分片）
+	DocsDeleted       int64  `json:"docs_deleted"`         // 已删除未合并的文档数（主分片）
+	StoreSizeBytes    int64  `json:"store_size_bytes"`     // 总存储大小（含副本）
+	PriStoreSizeBytes int64  `json:"pri_store_size_bytes"` // 主分片存储大小
+	SegmentsCount     int64  `json:"segments_count"`       // 段数量（含副本）
+	SearchQueryTotal  int64  `json:"search_query_total"`   // 累计查询次数
+	SearchQueryTimeMs int64  `json:"search_query_time_ms"` // 累计查询耗时（毫秒）
+	IndexingTotal     int64  `json:"indexing_total"`       // 累计写入次数
+	IndexingTimeMs    int64  `json:"indexing_time_ms"`     // 累计写入耗时（毫秒）
+}
+
+// IndexRate 索引速率（两次采样之间的差值换算）
+type IndexRate struct {
+	SearchPerSec     float64 `json:"search_per_sec"`     // 每秒查询次数
+	SearchAvgLatency float64 `json:"search_avg_latency"` // 平均查询耗时（毫秒）
+	IndexPerSec      float64 `json:"index_per_sec"`      // 每秒写入次数
+	IndexAvgLatency  float64 `json:"index_avg_latency"`  // 平均写入耗时（毫秒）
+}
+
+// Rate 计算当前采样相对上一次采样(prev)在elapsed时间内的速率
+func (s IndexStat) Rate(prev IndexStat, elapsed time.Duration) IndexRate {
+	var rate IndexRate
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		return rate
+	}
+	searchDelta := s.SearchQueryTotal - prev.SearchQueryTotal
+	indexDelta := s.IndexingTotal - prev.IndexingTotal
+	rate.SearchPerSec = float64(searchDelta) / seconds
+	rate.IndexPerSec = float64(indexDelta) / seconds
+	if searchDelta > 0 {
+		rate.SearchAvgLatency = float64(s.SearchQueryTimeMs-prev.SearchQueryTimeMs) / float64(searchDelta)
+	}
+	if indexDelta > 0 {
+		rate.IndexAvgLatency = float64(s.IndexingTimeMs-prev.IndexingTimeMs) / float64(indexDelta)
+	}
+	return rate
+}
+
+// CatIndex _cat/indices单行结果
+type CatIndex struct {
+	Health            string `json:"health"`
+	Status            string `json:"status"`
+	Index             string `json:"index"`
+	UUID              string `json:"uuid"`
+	Primaries         int64  `json:"primaries"`
+	Replicas          int64  `json:"replicas"`
+	DocsCount         int64  `json:"docs_count"`
+	DocsDeleted       int64  `json:"docs_deleted"`
+	StoreSizeBytes    int64  `json:"store_size_bytes"`
+	PriStoreSizeBytes int64  `json:"pri_store_size_bytes"`
+}
+
+// IndexStats 获取索引统计（已SetIndex时统计指定索引，否则统计全部索引），返回 索引名 -> 统计
+func (db *ESDb) IndexStats(ctx context.Context) (map[string]IndexStat, error) {
+	defer db.clearData(false)
+	if db.Err != nil {
+		return nil, db.Err
+	}
+	if db.Client == nil {
+		return nil, errors.New("ES客户端未初始化")
+	}
+	req := esapi.IndicesStatsRequest{
+		Index:  db.Index,
+		Metric: []string{"docs", "store", "segments", "search", "indexing"},
+	}
+	res, err := req.Do(ctx, db.Client)
+	if err != nil {
+		return nil, fmt.Errorf("获取索引统计失败：%w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logger.Error("ES获取索引统计时关闭body失败 Err：" + err.Error())
+		}
+	}(res.Body)
+	body, err := DeZip(db.GzipStatus, res)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败：%v", err)
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("获取索引统计失败，状态码：%d，响应：%s", res.StatusCode, string(body))
+	}
+	type statGroup struct {
+		Docs struct {
+			Count   int64 `json:"count"`
+			Deleted int64 `json:"deleted"`
+		} `json:"docs"`
+		Store struct {
+			SizeInBytes int64 `json:"size_in_bytes"`
+		} `json:"store"`
+		Segments struct {
+			Count int64 `json:"count"`
+		} `json:"segments"`
+		Search struct {
+			QueryTotal        int64 `json:"query_total"`
+			QueryTimeInMillis int64 `json:"query_time_in_millis"`
+		} `json:"search"`
+		Indexing struct {
+			IndexTotal        int64 `json:"index_total"`
+			IndexTimeInMillis int64 `json:"index_time_in_millis"`
+		} `json:"indexing"`
+	}
+	var result struct {
+		Indices map[string]struct {
+			Primaries statGroup `json:"primaries"`
+			Total     statGroup `json:"total"`
+		} `json:"indices"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析索引统计失败：%v", err)
+	}
+	stats := make(map[string]IndexStat, len(result.Indices))
+	for name, item := range result.Indices {
+		stats[name] = IndexStat{
+			Index:             name,
+			DocsCount:         item.Primaries.Docs.Count,
+			DocsDeleted:       item.Primaries.Docs.Deleted,
+			StoreSizeBytes:    item.Total.Store.SizeInBytes,
+			PriStoreSizeBytes: item.Primaries.Store.SizeInBytes,
+			SegmentsCount:     item.Total.Segments.Count,
+			SearchQueryTotal:  item.Total.Search.QueryTotal,
+			SearchQueryTimeMs: item.Total.Search.QueryTimeInMillis,
+			IndexingTotal:     item.Total.Indexing.IndexTotal,
+			IndexingTimeMs:    item.Total.Indexing.IndexTimeInMillis,
+		}
+	}
+	return stats, nil
+}
+
+// CatIndices 按通配模式列出索引（如 "log-*"，自动拼接索引前缀；为空时列出全部），存储大小以字节返回
+func (db *ESDb) CatIndices(ctx context.Context, pattern string) ([]CatIndex, error) {
+	defer db.clearData(false)
+	if db.Err != nil {
+		return nil, db.Err
+	}
+	if db.Client == nil {
+		return nil, errors.New("ES客户端未初始化")
+	}
+	req := esapi.CatIndicesRequest{
+		Format: "json",
+		Bytes:  "b",
+	}
+	if pattern != "" {
+		req.Index = []string{db.DbPre + pattern}
+	}
+	res, err := req.Do(ctx, db.Client)
+	if err != nil {
+		return nil, fmt.Errorf("获取索引列表失败：%w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logger.Error("ES获取索引列表时关闭body失败 Err：" + err.Error())
+		}
+	}(res.Body)
+	body, err := DeZip(db.GzipStatus, res)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败：%v", err)
+	}
+	if res.StatusCode == 404 {
+		return []CatIndex{}, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("获取索引列表失败，状态码：%d，响应：%s", res.StatusCode, string(body))
+	}
+	// _cat接口的数值字段以字符串返回（关闭的索引部分字段为null）
+	var rows []map[string]*string
+	if err = json.Unmarshal(body, &rows); err != nil {
+		return nil, fmt.Errorf("解析索引列表失败：%v", err)
+	}
+	str := func(row map[string]*string, key string) string {
+		if val := row[key]; val != nil {
+			return *val
+		}
+		return ""
+	}
+	num := func(row map[string]*string, key string) int64 {
+		n, _ := strconv.ParseInt(str(row, key), 10, 64)
+		return n
+	}
+	indices := make([]CatIndex, 0, len(rows))
+	for _, row := range rows {
+		indices = append(indices, CatIndex{
+			Health:            str(row, "health"),
+			Status:            str(row, "status"),
+			Index:             str(row, "index"),
+			UUID:              str(row, "uuid"),
+			Primaries:         num(row, "pri"),
+			Replicas:          num(row, "rep"),
+			DocsCount:         num(row, "docs.count"),
+			DocsDeleted:       num(row, "docs.deleted"),
+			StoreSizeBytes:    num(row, "store.size"),
+			PriStoreSizeBytes: num(row, "pri.store.size"),
+		})
+	}
+	return indices, nil
+}
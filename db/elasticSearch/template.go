@@ -0,0 +1,162 @@
+package elasticSearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// 搜索模板：复杂查询以mustache模板形式存储在ES集群中（经评审后由运维/发布流程写入），
+// 业务代码只传模板ID与参数，参数由ES按JSON转义后填入，避免拼接DSL带来的注入风险与多服务间的重复代码。
+// 模板ID自动拼接表前缀，与索引名一致按前缀隔离。
+
+var validTemplateIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-.]+$`)
+
+// PutSearchTemplate 创建或覆盖搜索模板（source为mustache模板，可为DSL对象或模板字符串，
+// 如 {"query":{"match":{"title":"{{keyword}}"}},"size":"{{size}}{{^size}}10{{/size}}"}）
+func (db *ESDb) PutSearchTemplate(ctx context.Context, templateID string, source interface{}) error {
+	id, err := db.templateID(templateID)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"script": map[string]interface{}{"lang": "mustache", "source": source},
+	})
+	if err != nil {
+		return fmt.Errorf("序列化搜索模板失败：%w", err)
+	}
+	req := esapi.PutScriptRequest{ScriptID: id, Body: bytes.NewReader(body)}
+	_, err = db.doTemplateRequest(ctx, req, "保存搜索模板", id)
+	return err
+}
+
+// DeleteSearchTemplate 删除搜索模板（模板不存在时视为成功）
+func (db *ESDb) DeleteSearchTemplate(ctx context.Context, templateID string) error {
+	id, err := db.templateID(templateID)
+	if err != nil {
+		return err
+	}
+	_, err = db.doTemplateRequest(ctx, esapi.DeleteScriptRequest{ScriptID: id}, "删除搜索模板", id)
+	if errors.Is(err, errTemplateNotFound) {
+		return nil
+	}
+	return err
+}
+
+// RenderSearchTemplate 渲染搜索模板，返回最终DSL（用于调试模板与参数，不执行查询）
+func (db *ESDb) RenderSearchTemplate(ctx context.Context, templateID string, params map[string]interface{}) (string, error) {
+	id, err := db.templateID(templateID)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]interface{}{"params": templateParams(params)})
+	if err != nil {
+		return "", fmt.Errorf("序列化模板参数失败：%w", err)
+	}
+	resp, err := db.doTemplateRequest(ctx, esapi.RenderSearchTemplateRequest{TemplateID: id, Body: bytes.NewReader(body)}, "渲染搜索模板", id)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		TemplateOutput json.RawMessage `json:"template_output"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", fmt.Errorf("解析渲染结果失败：%w", err)
+	}
+	return string(result.TemplateOutput), nil
+}
+
+// SearchByTemplate 按搜索模板查询（需先SetIndex），结果与FindAll一致写入Data/TotalCount/AggsData，
+// 可继续链式调用ToString/GetData
+func (db *ESDb) SearchByTemplate(ctx context.Context, templateID string, params map[string]interface{}) *ESDb {
+	if db.Err != nil {
+		return db
+	}
+	if db.Client == nil {
+		db.Err = errors.New("ES客户端未初始化")
+		return db
+	}
+	if len(db.Index) == 0 {
+		db.Err = errors.New("未指定索引")
+		return db
+	}
+	id, err := db.templateID(templateID)
+	if err != nil {
+		db.Err = err
+		return db
+	}
+	body, err := json.Marshal(map[string]interface{}{"id": id, "params": templateParams(params)})
+	if err != nil {
+		db.Err = fmt.Errorf("序列化模板参数失败：%w", err)
+		return db
+	}
+	resp, err := db.doTemplateRequest(ctx, esapi.SearchTemplateRequest{Index: db.Index, Body: bytes.NewReader(body)}, "模板查询", id)
+	if err != nil {
+		db.Err = err
+		return db
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		db.Err = fmt.Errorf("解析查询结果失败：%w", err)
+		return db
+	}
+	db.fillSearchResult(result)
+	return db
+}
+
+var errTemplateNotFound = errors.New("搜索模板不存在")
+
+// templateID 校验模板ID并拼接表前缀
+func (db *ESDb) templateID(templateID string) (string, error) {
+	if db.Err != nil {
+		return "", db.Err
+	}
+	if !validTemplateIDRegex.MatchString(templateID) {
+		return "", fmt.Errorf("搜索模板ID[%s]非法，仅支持字母、数字、下划线、连字符与点", templateID)
+	}
+	return db.DbPre + templateID, nil
+}
+
+// doTemplateRequest 执行模板相关请求并返回响应体（404返回errTemplateNotFound）
+func (db *ESDb) doTemplateRequest(ctx context.Context, req esapi.Request, action, id string) ([]byte, error) {
+	if db.Client == nil {
+		return nil, errors.New("ES客户端未初始化")
+	}
+	res, err := req.Do(ctx, db.Client)
+	if err != nil {
+		return nil, fmt.Errorf("%s失败：%w", action, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logger.Error("ES"+action+"时关闭body失败 [模板："+id+"] Err：", err)
+		}
+	}(res.Body)
+	body, err := DeZip(db.GzipStatus, res)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败：%v", err)
+	}
+	if res.StatusCode == http.StatusNotFound && !strings.Contains(string(body), "index_not_found_exception") {
+		return nil, fmt.Errorf("%w：%s", errTemplateNotFound, id)
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("%s失败，状态码：%d，响应：%s", action, res.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// templateParams 参数为空时传空对象（mustache对缺失参数渲染为空）
+func templateParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return map[string]interface{}{}
+	}
+	return params
+}
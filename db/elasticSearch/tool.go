@@ -115,7 +115,7 @@ func DeZip(isGzip bool, response *esapi.Response) ([]byte, error) {
 	// 读取成功后，关闭gzipReader（仅清理其内部缓冲区，不影响底层Reader）
 	if gzReader != nil {
 		if err := gzReader.Close(); err != nil {
-			logger.Error("关闭gzip reader失败（不影响数据）：%v", err)
+			logger.Errorf("关闭gzip reader失败（不影响数据）：%v", err)
 		}
 	}
 
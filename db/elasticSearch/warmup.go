@@ -0,0 +1,85 @@
+package elasticSearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Warmup 预热连接池：并发发送conns个Ping请求（<=0时取每个主机的最大空闲连接数，最多32个），
+// 请求结束后连接保留在Transport的空闲池中。返回各连接池成功的请求数。
+func Warmup(ctx context.Context, conns int) (map[string]int, error) {
+	result := make(map[string]int)
+	var errs []error
+	multiESPool.Range(func(key, value interface{}) bool {
+		dbObj := value.(DbObj)
+		n := conns
+		if n <= 0 {
+			n = min(dbObj.Transport.MaxIdleConnsPerHost, 32)
+		}
+		if n <= 0 {
+			n = 1
+		}
+		var (
+			mu    sync.Mutex
+			wg    sync.WaitGroup
+			ok    int
+			first error
+		)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := dbObj.Client.Ping(dbObj.Client.Ping.WithContext(ctx))
+				if err == nil {
+					// 读完响应体连接才会放回空闲池
+					_, _ = io.Copy(io.Discard, res.Body)
+					_ = res.Body.Close()
+					if res.IsError() {
+						err = errors.New(res.Status())
+					}
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if first == nil {
+						first = err
+					}
+					return
+				}
+				ok++
+			}()
+		}
+		wg.Wait()
+		result[key.(string)] = ok
+		if first != nil {
+			errs = append(errs, fmt.Errorf("ES[%v]预热失败：%w", key, first))
+		}
+		return true
+	})
+	return result, errors.Join(errs...)
+}
+
+// Pingers 各连接的可用性检查函数（供健康检查使用），key为连接key
+func Pingers() map[string]func(context.Context) error {
+	result := make(map[string]func(context.Context) error)
+	multiESPool.Range(func(key, value interface{}) bool {
+		client := value.(DbObj).Client
+		result[key.(string)] = func(ctx context.Context) error {
+			res, err := client.Ping(client.Ping.WithContext(ctx))
+			if err != nil {
+				return err
+			}
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+			if res.IsError() {
+				return errors.New(res.Status())
+			}
+			return nil
+		}
+		return true
+	})
+	return result
+}
@@ -0,0 +1,369 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 敏感字段加密（静态数据加密）：登记的表/集合字段（如phone、id_card）在MySQL/MongoDB写入前自动加密，读取后自动解密。
+//   - 加密：AES-GCM，密文格式为"enc:v{版本}:"+base64(nonce+密文)，附加数据绑定"表.字段"，密文无法挪用到其他字段；
+//     密钥按版本管理，新数据使用当前版本加密，旧版本密钥保留用于解密，轮换后可用Reencrypt逐步重写
+//   - 盲索引：字段配置了BlindIndex列时，写入同时生成HMAC-SHA256盲索引，等值查询改为按盲索引列匹配
+//     （mysql.SetWhereBlind / mongoDb.SetWhereBlind 或 BlindIndex）；盲索引密钥变更后需重建索引
+//   - 未带密文前缀的值按明文原样返回，便于存量数据逐步迁移
+//
+// 表名为不含前缀的逻辑表名（MongoDB为集合名）。
+
+const (
+	prefix        = "enc:v"
+	blindIndexLen = 16 // 盲索引取HMAC前16字节（hex编码后32个字符）
+)
+
+var (
+	// ErrNoKey 未配置加密密钥
+	ErrNoKey = errors.New("fieldcrypt: encryption key not configured")
+	// ErrNoIndexKey 未配置盲索引密钥
+	ErrNoIndexKey = errors.New("fieldcrypt: blind index key not configured")
+	// ErrNoBlindIndex 字段未配置盲索引列
+	ErrNoBlindIndex = errors.New("fieldcrypt: field has no blind index")
+)
+
+// Field 加密字段
+type Field struct {
+	Name       string              // 字段名
+	BlindIndex string              // 盲索引列名（为空时不生成，无法按该字段等值查询）
+	Normalize  func(string) string // 计算盲索引前的规范化（默认去除首尾空白），如手机号去掉区号、邮箱转小写
+}
+
+// keyring 版本化密钥
+type keyring struct {
+	current  uint32
+	aeads    map[uint32]cipher.AEAD
+	indexKey []byte
+}
+
+var (
+	mu     sync.RWMutex
+	keys   *keyring
+	tables = make(map[string]map[string]Field) // 表名 -> 字段名 -> 字段
+)
+
+// SetKeys 设置加密密钥（版本 -> 16/24/32字节AES密钥）与盲索引密钥，current为加密使用的版本（为0时取最大版本）
+func SetKeys(current uint32, encKeys map[uint32][]byte, indexKey []byte) error {
+	if len(encKeys) == 0 {
+		return ErrNoKey
+	}
+	ring := &keyring{current: current, aeads: make(map[uint32]cipher.AEAD, len(encKeys))}
+	for version, key := range encKeys {
+		if version == 0 {
+			return errors.New("fieldcrypt: key version must be greater than 0")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("fieldcrypt: invalid key v%d: %w", version, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("fieldcrypt: invalid key v%d: %w", version, err)
+		}
+		ring.aeads[version] = aead
+		if current == 0 && version > ring.current {
+			ring.current = version
+		}
+	}
+	if _, ok := ring.aeads[ring.current]; !ok {
+		return fmt.Errorf("fieldcrypt: current key v%d not found", ring.current)
+	}
+	if len(indexKey) > 0 {
+		ring.indexKey = append([]byte(nil), indexKey...)
+	}
+	mu.Lock()
+	keys = ring
+	mu.Unlock()
+	return nil
+}
+
+// Register 登记表的加密字段（同名字段覆盖）
+func Register(table string, fields ...Field) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered, ok := tables[table]
+	if !ok {
+		registered = make(map[string]Field, len(fields))
+		tables[table] = registered
+	}
+	for _, field := range fields {
+		if field.Name != "" {
+			registered[field.Name] = field
+		}
+	}
+}
+
+// InitFromConfig 按数据库配置field_crypt设置密钥并登记加密字段（未配置密钥时跳过）
+func InitFromConfig(cfg config.FieldCryptConfig) error {
+	if len(cfg.Keys) == 0 {
+		return nil
+	}
+	encKeys := make(map[uint32][]byte, len(cfg.Keys))
+	for v, k := range cfg.Keys {
+		version, err := strconv.ParseUint(strings.TrimPrefix(v, "v"), 10, 32)
+		if err != nil {
+			return fmt.Errorf("fieldcrypt: invalid key version %q", v)
+		}
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			return fmt.Errorf("fieldcrypt: key v%d is not valid base64", version)
+		}
+		encKeys[uint32(version)] = key
+	}
+	var current uint64
+	if cfg.Current != "" {
+		var err error
+		if current, err = strconv.ParseUint(strings.TrimPrefix(cfg.Current, "v"), 10, 32); err != nil {
+			return fmt.Errorf("fieldcrypt: invalid current version %q", cfg.Current)
+		}
+	}
+	var indexKey []byte
+	if cfg.IndexKey != "" {
+		var err error
+		if indexKey, err = base64.StdEncoding.DecodeString(cfg.IndexKey); err != nil {
+			return errors.New("fieldcrypt: index_key is not valid base64")
+		}
+	}
+	if err := SetKeys(uint32(current), encKeys, indexKey); err != nil {
+		return err
+	}
+	for table, fields := range cfg.Tables {
+		for _, field := range fields {
+			Register(table, Field{Name: field.Name, BlindIndex: field.BlindIndex})
+		}
+	}
+	return nil
+}
+
+// Fields 表的加密字段（未登记时返回nil）
+func Fields(table string) map[string]Field {
+	mu.RLock()
+	defer mu.RUnlock()
+	return tables[table]
+}
+
+// Enabled 表是否登记了加密字段
+func Enabled(table string) bool {
+	return len(Fields(table)) > 0
+}
+
+func currentKeys() (*keyring, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if keys == nil {
+		return nil, ErrNoKey
+	}
+	return keys, nil
+}
+
+// IsEncrypted 值是否为密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt 使用当前版本密钥加密字段值
+func Encrypt(table, field, plaintext string) (string, error) {
+	ring, err := currentKeys()
+	if err != nil {
+		return "", err
+	}
+	aead := ring.aeads[ring.current]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), aad(table, field))
+	return prefix + strconv.FormatUint(uint64(ring.current), 10) + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密字段值（非密文原样返回）
+func Decrypt(table, field, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	version, payload, ok := strings.Cut(value[len(prefix):], ":")
+	if !ok {
+		return "", fmt.Errorf("fieldcrypt: malformed ciphertext in %s.%s", table, field)
+	}
+	v, err := strconv.ParseUint(version, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("fieldcrypt: malformed ciphertext in %s.%s", table, field)
+	}
+	ring, err := currentKeys()
+	if err != nil {
+		return "", err
+	}
+	aead, ok := ring.aeads[uint32(v)]
+	if !ok {
+		return "", fmt.Errorf("fieldcrypt: key v%d not found for %s.%s", v, table, field)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("fieldcrypt: malformed ciphertext in %s.%s", table, field)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad(table, field))
+	if err != nil {
+		return "", fmt.Errorf("fieldcrypt: failed to decrypt %s.%s: %w", table, field, err)
+	}
+	return string(plain), nil
+}
+
+// Reencrypt 将旧版本密钥（或明文）的值用当前版本重新加密，已是当前版本时changed为false（密钥轮换后批量重写使用）
+func Reencrypt(table, field, value string) (result string, changed bool, err error) {
+	ring, err := currentKeys()
+	if err != nil {
+		return "", false, err
+	}
+	if strings.HasPrefix(value, prefix+strconv.FormatUint(uint64(ring.current), 10)+":") {
+		return value, false, nil
+	}
+	plain, err := Decrypt(table, field, value)
+	if err != nil {
+		return "", false, err
+	}
+	result, err = Encrypt(table, field, plain)
+	return result, err == nil, err
+}
+
+// BlindIndex 计算字段值的盲索引，返回盲索引列名与索引值（用于等值查询）
+func BlindIndex(table, field string, value interface{}) (column, index string, err error) {
+	f, ok := Fields(table)[field]
+	if !ok || f.BlindIndex == "" {
+		return "", "", fmt.Errorf("%w: %s.%s", ErrNoBlindIndex, table, field)
+	}
+	index, err = blindIndex(table, f, toString(value))
+	return f.BlindIndex, index, err
+}
+
+func blindIndex(table string, field Field, value string) (string, error) {
+	ring, err := currentKeys()
+	if err != nil {
+		return "", err
+	}
+	if len(ring.indexKey) == 0 {
+		return "", ErrNoIndexKey
+	}
+	if field.Normalize != nil {
+		value = field.Normalize(value)
+	} else {
+		value = strings.TrimSpace(value)
+	}
+	mac := hmac.New(sha256.New, ring.indexKey)
+	mac.Write(aad(table, field.Name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:blindIndexLen]), nil
+}
+
+// EncryptRow 加密行数据中登记的字段并补充盲索引列，返回新的map（未登记加密字段时原样返回；nil值不加密，盲索引列同步置nil）
+func EncryptRow(table string, row map[string]interface{}) (map[string]interface{}, error) {
+	fields := Fields(table)
+	if len(fields) == 0 || len(row) == 0 {
+		return row, nil
+	}
+	result := make(map[string]interface{}, len(row)+len(fields))
+	for key, val := range row {
+		result[key] = val
+	}
+	for name, field := range fields {
+		val, ok := row[name]
+		if !ok {
+			continue
+		}
+		if val == nil {
+			// 置空字段时同步清空盲索引，批量写入时各行字段保持一致
+			if field.BlindIndex != "" {
+				result[field.BlindIndex] = nil
+			}
+			continue
+		}
+		plain := toString(val)
+		if IsEncrypted(plain) {
+			// 已加密的值（如原样写回查询结果）不重复加密
+			continue
+		}
+		enc, err := Encrypt(table, name, plain)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = enc
+		if field.BlindIndex != "" {
+			index, err := blindIndex(table, field, plain)
+			if err != nil {
+				return nil, err
+			}
+			result[field.BlindIndex] = index
+		}
+	}
+	return result, nil
+}
+
+// DecryptRow 原地解密行数据中登记的字段
+func DecryptRow(table string, row map[string]interface{}) error {
+	fields := Fields(table)
+	if len(fields) == 0 {
+		return nil
+	}
+	return decryptRow(table, fields, row)
+}
+
+// DecryptRows 原地解密多行数据
+func DecryptRows(table string, rows []map[string]interface{}) error {
+	fields := Fields(table)
+	if len(fields) == 0 {
+		return nil
+	}
+	for _, row := range rows {
+		if err := decryptRow(table, fields, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decryptRow(table string, fields map[string]Field, row map[string]interface{}) error {
+	for name := range fields {
+		val, ok := row[name].(string)
+		if !ok || !IsEncrypted(val) {
+			continue
+		}
+		plain, err := Decrypt(table, name, val)
+		if err != nil {
+			return err
+		}
+		row[name] = plain
+	}
+	return nil
+}
+
+func aad(table, field string) []byte {
+	return []byte(table + "." + field)
+}
+
+func toString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/db/elasticSearch"
+	"github.com/dfpopp/go-dai/db/mongoDb"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"github.com/dfpopp/go-dai/db/redisDb"
+)
+
+// Pingers 已初始化的全部数据库连接的可用性检查函数（供健康检查使用），key为"数据库类型.连接key"
+func Pingers() map[string]func(context.Context) error {
+	result := make(map[string]func(context.Context) error)
+	for dbType, pingers := range map[string]func() map[string]func(context.Context) error{
+		"mysql":   mysql.Pingers,
+		"mongodb": mongoDb.Pingers,
+		"redis":   redisDb.Pingers,
+		"es":      elasticSearch.Pingers,
+	} {
+		for key, ping := range pingers() {
+			result[dbType+"."+key] = ping
+		}
+	}
+	return result
+}
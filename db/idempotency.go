@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/elasticSearch"
+	"github.com/dfpopp/go-dai/db/mongoDb"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"time"
+)
+
+// 幂等写入：调用方为一次批量写操作指定幂等键（如MQ消息ID），框架在Redis中记录执行状态，
+// 重复调用（如消息重投）直接返回首次执行的结果，不再写库。
+// 执行失败时会释放幂等键，允许调用方重试。占位值带有本次执行的唯一标识，
+// 释放与写入结果时比对该标识，执行超过占位时长后不会误删或覆盖其他实例的占位与结果。
+
+const (
+	defaultIdempotencyTTL     = 24 * time.Hour  // 完成记录默认保留时长
+	defaultIdempotencyLockTTL = 5 * time.Minute // 执行中占位默认过期时长（防止进程崩溃导致键永久占用）
+	idempotencyKeyPrefix      = "idempotency:"  // Redis键前缀（会再拼接Redis表前缀）
+	idempotencyAcquireTries   = 3               // 占位在读取前恰好过期或被释放时重新抢占的次数
+)
+
+// idempotencyCommitScript 仅当占位仍属于本次执行时写入完成记录
+var idempotencyCommitScript = redis.NewScript(`if redis.call('GET', KEYS[1]) == ARGV[1] then
+  redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+  return 1
+end
+return 0`)
+
+// ErrIdempotencyPending 相同幂等键的操作正在执行中（调用方可稍后重试）
+var ErrIdempotencyPending = errors.New("相同幂等键的操作正在执行中")
+
+// ErrIdempotencyLockLost 执行耗时超过占位时长，占位已过期或被其他实例抢占，本次结果未记录（应调大SetLockTTL）
+var ErrIdempotencyLockLost = errors.New("幂等键占位已过期，执行结果未记录")
+
+// Idempotency 幂等执行器
+type Idempotency struct {
+	redis   *redisDb.RedisDb
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// idempotencyRecord Redis中保存的执行记录
+type idempotencyRecord struct {
+	Done   bool            `json:"done"`
+	Owner  string          `json:"owner,omitempty"` // 执行中占位的唯一标识
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// NewIdempotency 创建幂等执行器（redisDbKey为记录执行状态的Redis连接，ttl<=0时默认保留24小时）
+func NewIdempotency(redisDbKey string, ttl time.Duration) (*Idempotency, error) {
+	rdb, err := redisDb.GetRedisDB(redisDbKey)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return &Idempotency{redis: rdb, ttl: ttl, lockTTL: defaultIdempotencyLockTTL}, nil
+}
+
+// SetLockTTL 设置执行中占位的过期时长（应大于单次写操作的最长耗时）
+func (i *Idempotency) SetLockTTL(lockTTL time.Duration) *Idempotency {
+	if lockTTL > 0 {
+		i.lockTTL = lockTTL
+	}
+	return i
+}
+
+// Do 以幂等方式执行fn：首次调用执行fn并记录结果；重复调用将首次结果解码到result并返回duplicate=true。
+// result须为指针，fn的返回值会以JSON序列化保存。
+func (i *Idempotency) Do(ctx context.Context, key string, result interface{}, fn func(ctx context.Context) (interface{}, error)) (duplicate bool, err error) {
+	if key == "" {
+		return false, errors.New("幂等键不能为空")
+	}
+	client := i.redis.WithContext(ctx).Db
+	redisKey := i.redis.DbPre + idempotencyKeyPrefix + key
+	pendingBytes, _ := json.Marshal(idempotencyRecord{Owner: uuid.NewString()})
+	pending := string(pendingBytes)
+	acquired := false
+	for attempt := 0; attempt < idempotencyAcquireTries; attempt++ {
+		acquired, err = client.SetNX(redisKey, pending, i.lockTTL).Result()
+		if err != nil {
+			return false, fmt.Errorf("写入幂等键失败：%w", err)
+		}
+		if acquired {
+			break
+		}
+		data, getErr := client.Get(redisKey).Bytes()
+		if errors.Is(getErr, redis.Nil) {
+			// 占位恰好过期或被释放，重新抢占
+			continue
+		}
+		if getErr != nil {
+			return false, fmt.Errorf("读取幂等键失败：%w", getErr)
+		}
+		var record idempotencyRecord
+		if jsonErr := json.Unmarshal(data, &record); jsonErr != nil {
+			return false, fmt.Errorf("解析幂等记录失败：%w", jsonErr)
+		}
+		if !record.Done {
+			return false, ErrIdempotencyPending
+		}
+		if result != nil && len(record.Result) > 0 {
+			if jsonErr := json.Unmarshal(record.Result, result); jsonErr != nil {
+				return true, fmt.Errorf("解析幂等结果失败：%w", jsonErr)
+			}
+		}
+		return true, nil
+	}
+	if !acquired {
+		// 多次抢占均与其他实例的写入、释放交错，按执行中处理
+		return false, ErrIdempotencyPending
+	}
+
+	value, err := fn(ctx)
+	if err != nil {
+		// 执行失败释放幂等键（仅释放本次的占位），允许重试
+		_, _ = i.redis.CompareAndDelete(ctx, idempotencyKeyPrefix+key, pending)
+		return false, err
+	}
+	resultBytes, err := json.Marshal(value)
+	if err != nil {
+		_, _ = i.redis.CompareAndDelete(ctx, idempotencyKeyPrefix+key, pending)
+		return false, fmt.Errorf("序列化幂等结果失败：%w", err)
+	}
+	recordBytes, _ := json.Marshal(idempotencyRecord{Done: true, Result: resultBytes})
+	committed, setErr := idempotencyCommitScript.Run(client, []string{redisKey}, pending, recordBytes, i.ttl.Milliseconds()).Int64()
+	if setErr != nil {
+		return false, fmt.Errorf("记录幂等结果失败：%w", setErr)
+	}
+	if committed == 0 {
+		return false, ErrIdempotencyLockLost
+	}
+	if result != nil {
+		_ = json.Unmarshal(resultBytes, result)
+	}
+	return false, nil
+}
+
+// Forget 删除幂等记录（如需人工重放某条消息）
+func (i *Idempotency) Forget(ctx context.Context, key string) error {
+	return i.redis.WithContext(ctx).Db.Del(i.redis.DbPre + idempotencyKeyPrefix + key).Err()
+}
+
+// MysqlInsertAll 幂等批量插入MySQL，返回插入行数；重复调用返回首次插入行数
+func (i *Idempotency) MysqlInsertAll(ctx context.Context, key string, db *mysql.MysqlDb, dataList []map[string]interface{}) (num int64, duplicate bool, err error) {
+	duplicate, err = i.Do(ctx, key, &num, func(ctx context.Context) (interface{}, error) {
+		return db.InsertAll(ctx, dataList)
+	})
+	return num, duplicate, err
+}
+
+// MongoInsertAll 幂等批量插入MongoDB，返回插入的文档ID列表；重复调用返回首次插入的ID列表（ObjectID以十六进制字符串返回）
+func (i *Idempotency) MongoInsertAll(ctx context.Context, key string, db *mongoDb.Db, docs []interface{}) (ids []interface{}, duplicate bool, err error) {
+	duplicate, err = i.Do(ctx, key, &ids, func(ctx context.Context) (interface{}, error) {
+		return db.InsertAll(ctx, docs)
+	})
+	return ids, duplicate, err
+}
+
+// EsInsertAll 幂等批量写入ES，返回新增数与更新数；重复调用返回首次执行的统计
+func (i *Idempotency) EsInsertAll(ctx context.Context, key string, db *elasticSearch.ESDb, dataList []map[string]interface{}) (insertCount int64, updateCount int64, duplicate bool, err error) {
+	var counts [2]int64
+	duplicate, err = i.Do(ctx, key, &counts, func(ctx context.Context) (interface{}, error) {
+		insert, update, insertErr := db.InsertAll(ctx, dataList)
+		return [2]int64{insert, update}, insertErr
+	})
+	return counts[0], counts[1], duplicate, err
+}
+
+// EsCommit 幂等提交ES批量操作（AddBulkInsert/AddBulkUpdate/AddBulkDelete后调用），返回成功条数
+func (i *Idempotency) EsCommit(ctx context.Context, key string, db *elasticSearch.ESDb) (num int64, duplicate bool, err error) {
+	duplicate, err = i.Do(ctx, key, &num, func(ctx context.Context) (interface{}, error) {
+		return db.Commit(ctx)
+	})
+	return num, duplicate, err
+}
@@ -0,0 +1,79 @@
+package mongoDb
+
+import (
+	"context"
+	"errors"
+	"github.com/dfpopp/go-dai/db/cursor"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SetCursor 设置游标分页：token为上一页返回的next_cursor/prev_cursor（首页为空），
+// fields为排序字段（最后一个须唯一，通常为_id），会覆盖SetSort、SetSkip与SetLimit，配合FindPage使用
+func (m *Db) SetCursor(token string, limit int64, fields ...cursor.Field) *Db {
+	if m.Err != nil {
+		return m
+	}
+	q, err := cursor.NewQuery(token, limit, fields)
+	if err != nil {
+		m.Err = err
+		return m
+	}
+	m.page = q
+	return m
+}
+
+// FindPage 执行游标分页查询（sort + 排序键范围条件），返回当前页数据与上一页/下一页游标
+func (m *Db) FindPage(ctx context.Context) (*cursor.Page, error) {
+	defer m.clearData(false)
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	q := m.page
+	if q == nil {
+		return nil, errors.New("未设置游标分页（请先调用SetCursor）")
+	}
+	sort := make(bson.D, len(q.Fields))
+	for i, f := range q.Fields {
+		order := 1
+		if q.Desc(i) {
+			order = -1
+		}
+		sort[i] = bson.E{f.Name, order}
+	}
+	m.SetSort(sort)
+	m.SetSkip(0)
+	m.SetLimit(q.Fetch())
+	if q.Cursor != nil {
+		if len(m.Filter) == 0 {
+			m.Filter = keysetFilter(q)
+		} else {
+			m.Filter = bson.D{{"$and", bson.A{m.Filter, keysetFilter(q)}}}
+		}
+	}
+	m.FindAll(ctx)
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return q.Page(m.Data, nil)
+}
+
+// keysetFilter 生成游标之后的记录条件：{$or: [{a: {$lt: v1}}, {a: v1, _id: {$lt: v2}}]}
+func keysetFilter(q *cursor.Query) bson.D {
+	ors := make(bson.A, 0, len(q.Fields))
+	for i, f := range q.Fields {
+		cond := make(bson.D, 0, i+1)
+		for j := 0; j < i; j++ {
+			cond = append(cond, bson.E{q.Fields[j].Name, q.Cursor.Values[j]})
+		}
+		op := "$gt"
+		if q.Desc(i) {
+			op = "$lt"
+		}
+		cond = append(cond, bson.E{f.Name, bson.D{{op, q.Cursor.Values[i]}}})
+		ors = append(ors, cond)
+	}
+	if len(ors) == 1 {
+		return ors[0].(bson.D)
+	}
+	return bson.D{{"$or", ors}}
+}
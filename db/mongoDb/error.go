@@ -0,0 +1,24 @@
+package mongoDb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/errs"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// wrapError 为驱动错误加上操作描述，并归类为框架统一错误（唯一键冲突->Conflict，超时->Timeout，
+// 网络错误->Unavailable），原始错误保留为原因；无法归类的仅加描述
+func wrapError(op string, err error) error {
+	wrapped := fmt.Errorf("%s: %w", op, err)
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		return errs.Conflict.WithMessage("数据已存在").Wrap(wrapped)
+	case errors.Is(err, context.DeadlineExceeded), mongo.IsTimeout(err):
+		return errs.Timeout.Wrap(wrapped)
+	case mongo.IsNetworkError(err):
+		return errs.Unavailable.Wrap(wrapped)
+	}
+	return wrapped
+}
@@ -0,0 +1,141 @@
+package mongoDb
+
+import (
+	"github.com/dfpopp/go-dai/db/fieldcrypt"
+	"go.mongodb.org/mongo-driver/bson"
+	"sort"
+	"strings"
+)
+
+// 敏感字段加密：按fieldcrypt登记的集合字段（集合名不含前缀），Insert/InsertAll写入前自动加密并填充盲索引字段，
+// Update/UpdateOne加密$set/$setOnInsert中的登记字段，FindAll/Find/Aggregate读取后自动解密（仅顶层字段）。
+// 加密字段不能直接用于查询条件（密文每次不同），等值查询请使用SetWhereBlind。
+
+// logicalCollection 去掉前缀的集合名
+func (m *Db) logicalCollection() string {
+	return strings.TrimPrefix(m.Collection, m.DbPre)
+}
+
+// encryptDoc 返回加密登记字段后的文档（支持map/bson.M/bson.D，结构体等其他类型先转为bson.D；集合未登记时原样返回）
+func (m *Db) encryptDoc(doc interface{}) (interface{}, error) {
+	table := m.logicalCollection()
+	if !fieldcrypt.Enabled(table) {
+		return doc, nil
+	}
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		return fieldcrypt.EncryptRow(table, d)
+	case bson.M:
+		enc, err := fieldcrypt.EncryptRow(table, d)
+		return bson.M(enc), err
+	case bson.D:
+		return encryptD(table, d)
+	default:
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var converted bson.D
+		if err := bson.Unmarshal(raw, &converted); err != nil {
+			return nil, err
+		}
+		return encryptD(table, converted)
+	}
+}
+
+// encryptD 加密bson.D中的登记字段（保持字段顺序，盲索引字段已存在时覆盖，否则追加到末尾）
+func encryptD(table string, d bson.D) (bson.D, error) {
+	fields := fieldcrypt.Fields(table)
+	partial := make(map[string]interface{}, len(fields))
+	for _, e := range d {
+		if _, ok := fields[e.Key]; ok {
+			partial[e.Key] = e.Value
+		}
+	}
+	if len(partial) == 0 {
+		return d, nil
+	}
+	enc, err := fieldcrypt.EncryptRow(table, partial)
+	if err != nil {
+		return nil, err
+	}
+	result := make(bson.D, 0, len(d)+len(enc))
+	for _, e := range d {
+		if val, ok := enc[e.Key]; ok {
+			e.Value = val
+			delete(enc, e.Key)
+		}
+		result = append(result, e)
+	}
+	keys := make([]string, 0, len(enc))
+	for key := range enc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		result = append(result, bson.E{Key: key, Value: enc[key]})
+	}
+	return result, nil
+}
+
+// encryptUpdate 加密更新文档中$set/$setOnInsert的登记字段（支持bson.D/bson.M/map，其他形式如聚合管道原样返回）
+func (m *Db) encryptUpdate(update interface{}) (interface{}, error) {
+	if !fieldcrypt.Enabled(m.logicalCollection()) {
+		return update, nil
+	}
+	isSetOp := func(key string) bool { return key == "$set" || key == "$setOnInsert" }
+	switch u := update.(type) {
+	case bson.D:
+		result := make(bson.D, len(u))
+		for i, e := range u {
+			if isSetOp(e.Key) {
+				enc, err := m.encryptDoc(e.Value)
+				if err != nil {
+					return nil, err
+				}
+				e.Value = enc
+			}
+			result[i] = e
+		}
+		return result, nil
+	case bson.M:
+		return m.encryptUpdateMap(u, isSetOp)
+	case map[string]interface{}:
+		return m.encryptUpdateMap(u, isSetOp)
+	}
+	return update, nil
+}
+
+func (m *Db) encryptUpdateMap(u map[string]interface{}, isSetOp func(string) bool) (bson.M, error) {
+	result := make(bson.M, len(u))
+	for key, val := range u {
+		if isSetOp(key) {
+			enc, err := m.encryptDoc(val)
+			if err != nil {
+				return nil, err
+			}
+			val = enc
+		}
+		result[key] = val
+	}
+	return result, nil
+}
+
+// decryptRows 原地解密查询结果中的登记字段
+func (m *Db) decryptRows(rows []map[string]interface{}) error {
+	return fieldcrypt.DecryptRows(m.logicalCollection(), rows)
+}
+
+// SetWhereBlind 按加密字段的盲索引追加等值条件（如SetWhereBlind("phone", "13800000000")追加{phone_bidx: 索引值}）
+func (m *Db) SetWhereBlind(field string, value interface{}) *Db {
+	if m.Err != nil {
+		return m
+	}
+	column, index, err := fieldcrypt.BlindIndex(m.logicalCollection(), field, value)
+	if err != nil {
+		m.Err = err
+		return m
+	}
+	m.Filter = append(m.Filter, bson.E{Key: column, Value: index})
+	return m
+}
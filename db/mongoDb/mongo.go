@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/chaos"
+	"github.com/dfpopp/go-dai/db/cursor"
 	"github.com/dfpopp/go-dai/function"
 	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/tracing"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -39,11 +42,16 @@ type Db struct {
 	Projection    bson.D                     // 字段投影（只返回指定字段）
 	Data          []map[string]interface{}   // 查询结果
 	Err           error                      // 错误存储
+	allowUnsafe   bool                       // 允许聚合管道使用服务端脚本（连接配置allow_unsafe_stages）
+	dbKey         string                     // 连接标识（故障注入按连接匹配规则）
+	page          *cursor.Query              // 游标分页（SetCursor设置）
 }
 type DbObj struct {
-	Client *mongo.Client
-	DbName string
-	Pre    string
+	Client      *mongo.Client
+	DbName      string
+	Pre         string
+	minPool     int  // 最小连接数（预热目标）
+	allowUnsafe bool // 允许聚合管道使用服务端脚本
 }
 
 var multiClientPool sync.Map
@@ -56,7 +64,11 @@ func InitMongoDB() {
 		if err != nil {
 			logger.Error(fmt.Sprintf("MongoDB连接初始化失败（%s）: %v", dbKey, err))
 		} else {
-			multiClientPool.Store(dbKey, DbObj{Client: client, DbName: cfg.Dbname, Pre: cfg.Pre})
+			minPool := int(cfg.MinPoolSize)
+			if minPool == 0 {
+				minPool = runtime.NumCPU() // 与connect中的默认值一致
+			}
+			multiClientPool.Store(dbKey, DbObj{Client: client, DbName: cfg.Dbname, Pre: cfg.Pre, minPool: minPool, allowUnsafe: cfg.AllowUnsafeStages})
 		}
 	}
 }
@@ -97,6 +109,9 @@ func connect(cfg config.MongodbConfig) (*mongo.Client, error) {
 	clientOpts.SetMinPoolSize(cfg.MinPoolSize)
 	clientOpts.SetMaxConnIdleTime(time.Duration(cfg.MaxConnIdleTime) * time.Second)
 	clientOpts.SetConnectTimeout(time.Duration(cfg.Timeout) * time.Second)
+	if tracing.Enabled() {
+		clientOpts.SetMonitor(newTraceMonitor())
+	}
 	// 建立连接
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
 	defer cancel()
@@ -144,6 +159,8 @@ func GetMongoDB(dbKey string) (*Db, error) {
 		Projection:    nil,
 		Data:          nil,
 		Err:           nil,
+		allowUnsafe:   dbObj.allowUnsafe,
+		dbKey:         dbKey,
 	}, nil
 }
 func (m *Db) SetDbName(dbName string) *Db {
@@ -346,13 +363,17 @@ func (m *Db) FindAll(ctx context.Context) *Db {
 	coll := m.Db.Collection(m.Collection)
 	// 获取绑定事务的上下文
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return m
+	}
 	// 执行查询
 	if m.Filter == nil {
 		m.Filter = bson.D{}
 	}
 	cursor, err := coll.Find(txCtx, m.Filter, m.FindOptions)
 	if err != nil {
-		m.Err = fmt.Errorf("查询失败: %v", err)
+		m.Err = wrapError("查询失败", err)
 		return m
 	}
 	if cursor == nil {
@@ -361,7 +382,7 @@ func (m *Db) FindAll(ctx context.Context) *Db {
 	defer func(cursor *mongo.Cursor, ctx context.Context) {
 		closeErr := cursor.Close(ctx)
 		if closeErr != nil {
-			logger.Error("mongoDb 关闭结果集失败: %v", closeErr)
+			logger.Errorf("mongoDb 关闭结果集失败: %v", closeErr)
 		}
 	}(cursor, txCtx)
 	// 解析结果
@@ -369,14 +390,19 @@ func (m *Db) FindAll(ctx context.Context) *Db {
 	for cursor.Next(txCtx) {
 		var doc map[string]interface{}
 		if err := cursor.Decode(&doc); err != nil {
-			m.Err = fmt.Errorf("解析文档失败: %v", err)
+			m.Err = wrapError("解析文档失败", err)
 			return m
 		}
 		result = append(result, doc)
 	}
 	// 检查游标错误
 	if err := cursor.Err(); err != nil {
-		m.Err = fmt.Errorf("游标遍历失败: %v", err)
+		m.Err = wrapError("游标遍历失败", err)
+		return m
+	}
+	// 敏感字段解密（未登记时不做处理）
+	if err := m.decryptRows(result); err != nil {
+		m.Err = err
 		return m
 	}
 	m.Data = result
@@ -394,12 +420,16 @@ func (m *Db) FindCount(ctx context.Context) (int64, error) {
 	}
 	coll := m.Db.Collection(m.Collection)
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return 0, m.Err
+	}
 	if m.Filter == nil {
 		m.Filter = bson.D{}
 	}
 	count, err := coll.CountDocuments(txCtx, m.Filter)
 	if err != nil {
-		m.Err = fmt.Errorf("计数失败: %v", err)
+		m.Err = wrapError("计数失败", err)
 		return 0, m.Err
 	}
 	return count, nil
@@ -433,11 +463,21 @@ func (m *Db) Aggregate(ctx context.Context) *Db {
 		m.Err = errors.New("聚合管道不能为空")
 		return m
 	}
+	if !m.allowUnsafe {
+		if err := CheckUnsafeStages(m.AggregatePipe); err != nil {
+			m.Err = err
+			return m
+		}
+	}
 	coll := m.Db.Collection(m.Collection)
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return m
+	}
 	cursor, err := coll.Aggregate(txCtx, m.AggregatePipe)
 	if err != nil {
-		m.Err = fmt.Errorf("聚合查询失败: %v", err)
+		m.Err = wrapError("聚合查询失败", err)
 		return m
 	}
 	if cursor == nil {
@@ -446,21 +486,25 @@ func (m *Db) Aggregate(ctx context.Context) *Db {
 	defer func(cursor *mongo.Cursor, ctx context.Context) {
 		closeErr := cursor.Close(ctx)
 		if closeErr != nil {
-			logger.Error("mongoDb 关闭结果集失败: %v", closeErr)
+			logger.Errorf("mongoDb 关闭结果集失败: %v", closeErr)
 		}
 	}(cursor, txCtx)
 	var result []map[string]interface{}
 	for cursor.Next(txCtx) {
 		var doc map[string]interface{}
 		if err := cursor.Decode(&doc); err != nil {
-			m.Err = fmt.Errorf("解析聚合结果失败: %v", err)
+			m.Err = wrapError("解析聚合结果失败", err)
 			return m
 		}
 		result = append(result, doc)
 	}
 
 	if err := cursor.Err(); err != nil {
-		m.Err = fmt.Errorf("聚合游标遍历失败: %v", err)
+		m.Err = wrapError("聚合游标遍历失败", err)
+		return m
+	}
+	if err := m.decryptRows(result); err != nil {
+		m.Err = err
 		return m
 	}
 	m.Data = result
@@ -479,11 +523,21 @@ func (m *Db) Insert(ctx context.Context, doc interface{}) (primitive.ObjectID, e
 	if doc == nil {
 		return primitive.NilObjectID, errors.New("插入文档不能为空")
 	}
+	// 敏感字段加密（未登记时不做处理）
+	doc, err := m.encryptDoc(doc)
+	if err != nil {
+		m.Err = err
+		return primitive.NilObjectID, m.Err
+	}
 	coll := m.Db.Collection(m.Collection)
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return primitive.NilObjectID, m.Err
+	}
 	res, err := coll.InsertOne(txCtx, doc)
 	if err != nil {
-		m.Err = fmt.Errorf("插入失败: %v", err)
+		m.Err = wrapError("插入失败", err)
 		return primitive.NilObjectID, m.Err
 	}
 	// 转换为ObjectID
@@ -507,15 +561,40 @@ func (m *Db) InsertAll(ctx context.Context, docs []interface{}) ([]interface{},
 	if len(docs) == 0 {
 		return nil, errors.New("批量插入文档不能为空")
 	}
+	// 敏感字段加密（未登记时不做处理）
+	encDocs := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		enc, err := m.encryptDoc(doc)
+		if err != nil {
+			m.Err = err
+			return nil, m.Err
+		}
+		encDocs[i] = enc
+	}
+	docs = encDocs
 
 	coll := m.Db.Collection(m.Collection)
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return nil, m.Err
+	}
 
-	res, err := coll.InsertMany(txCtx, docs, m.InsertOptions)
+	// 故障注入（仅测试环境启用）：只写入前keep条，写入完成后返回部分失败
+	keep, chaosErr := chaos.Bulk("mongodb", m.dbKey, len(docs))
+	if keep == 0 && chaosErr != nil {
+		m.Err = chaosErr
+		return nil, m.Err
+	}
+	res, err := coll.InsertMany(txCtx, docs[:keep], m.InsertOptions)
 	if err != nil {
-		m.Err = fmt.Errorf("批量插入失败: %v", err)
+		m.Err = wrapError("批量插入失败", err)
 		return nil, m.Err
 	}
+	if chaosErr != nil {
+		m.Err = chaosErr
+		return res.InsertedIDs, m.Err
+	}
 	return res.InsertedIDs, nil
 }
 
@@ -534,13 +613,23 @@ func (m *Db) Update(ctx context.Context, update interface{}) (int64, error) {
 	if len(m.Filter) == 0 {
 		return 0, errors.New("查询条件不能为空（防止全表更新）")
 	}
+	// 敏感字段加密（未登记时不做处理）
+	update, err := m.encryptUpdate(update)
+	if err != nil {
+		m.Err = err
+		return 0, m.Err
+	}
 
 	coll := m.Db.Collection(m.Collection)
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return 0, m.Err
+	}
 	// 构造更新操作（$set）
 	res, err := coll.UpdateMany(txCtx, m.Filter, update, m.UpdateOptions)
 	if err != nil {
-		m.Err = fmt.Errorf("更新失败: %v", err)
+		m.Err = wrapError("更新失败", err)
 		return 0, m.Err
 	}
 	return res.ModifiedCount, nil
@@ -558,11 +647,20 @@ func (m *Db) UpdateOne(ctx context.Context, update interface{}) (int64, error) {
 	if update == nil {
 		return 0, errors.New("数据条件不能为空")
 	}
+	update, err := m.encryptUpdate(update)
+	if err != nil {
+		m.Err = err
+		return 0, m.Err
+	}
 	coll := m.Db.Collection(m.Collection)
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return 0, m.Err
+	}
 	res, err := coll.UpdateOne(txCtx, m.Filter, update, m.UpdateOptions)
 	if err != nil {
-		m.Err = fmt.Errorf("更新单条失败: %v", err)
+		m.Err = wrapError("更新单条失败", err)
 		return 0, m.Err
 	}
 	return res.ModifiedCount, nil
@@ -583,11 +681,15 @@ func (m *Db) Delete(ctx context.Context) (int64, error) {
 
 	coll := m.Db.Collection(m.Collection)
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return 0, m.Err
+	}
 
 	// 核心修正：删除操作通过事务上下文传递会话，而非SetSession
 	res, err := coll.DeleteMany(txCtx, m.Filter, m.DeleteOptions)
 	if err != nil {
-		m.Err = fmt.Errorf("删除失败: %v", err)
+		m.Err = wrapError("删除失败", err)
 		return 0, m.Err
 	}
 	return res.DeletedCount, nil
@@ -608,10 +710,14 @@ func (m *Db) DeleteOne(ctx context.Context) (int64, error) {
 
 	coll := m.Db.Collection(m.Collection)
 	txCtx := m.getTxContext(ctx)
+	if err := chaos.Inject(txCtx, "mongodb", m.dbKey); err != nil {
+		m.Err = err
+		return 0, m.Err
+	}
 
 	res, err := coll.DeleteOne(txCtx, m.Filter, m.DeleteOptions)
 	if err != nil {
-		m.Err = fmt.Errorf("删除单条失败: %v", err)
+		m.Err = wrapError("删除单条失败", err)
 		return 0, m.Err
 	}
 	return res.DeletedCount, nil
@@ -649,6 +755,7 @@ func (m *Db) clearData(isClearTx bool) {
 	m.Projection = nil
 	m.Data = nil
 	m.Err = nil
+	m.page = nil
 	if isClearTx {
 		m.TxSession = nil
 	}
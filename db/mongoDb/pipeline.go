@@ -1,6 +1,8 @@
 package mongoDb
 
 import (
+	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -69,7 +71,8 @@ func (b *PipelineBuilder) Limit(limit int64) *PipelineBuilder {
 	return b
 }
 
-// AppendStage 追加自定义管道阶段（如$lookup/$unwind等）
+// AppendStage 追加自定义管道阶段（如$lookup/$unwind等）；
+// 包含$function/$accumulator/$where等服务端脚本的阶段仅在连接配置allow_unsafe_stages开启时可执行
 // stage: 自定义阶段，如bson.D{{"$lookup", bson.D{{"from", "table"}, {"localField", "id"}, {"foreignField", "fid"}, {"as", "data"}}}}
 func (b *PipelineBuilder) AppendStage(stage bson.D) *PipelineBuilder {
 	if len(stage) > 0 {
@@ -78,6 +81,15 @@ func (b *PipelineBuilder) AppendStage(stage bson.D) *PipelineBuilder {
 	return b
 }
 
+// AddFields 添加$addFields阶段（新增/覆盖字段）
+// fields: 字段表达式，如bson.D{{"total", bson.D{{"$add", bson.A{"$price", "$fee"}}}}}
+func (b *PipelineBuilder) AddFields(fields bson.D) *PipelineBuilder {
+	if len(fields) > 0 {
+		b.pipeline = append(b.pipeline, bson.D{{"$addFields", fields}})
+	}
+	return b
+}
+
 // Build 生成最终的聚合管道
 func (b *PipelineBuilder) Build() mongo.Pipeline {
 	return b.pipeline
@@ -95,3 +107,91 @@ func BuildAggPipeline(where bson.D, group bson.D, sort bson.D, project interface
 		Limit(limit).
 		Build()
 }
+
+// ===================== 服务端脚本（$function/$accumulator） =====================
+
+// ErrUnsafeStage 聚合管道包含服务端脚本，而连接未开启allow_unsafe_stages
+var ErrUnsafeStage = errors.New("聚合管道包含服务端脚本操作符")
+
+// unsafeOperators 在服务端执行JavaScript的操作符
+var unsafeOperators = map[string]bool{"$function": true, "$accumulator": true, "$where": true}
+
+// AccumulatorSpec $accumulator自定义累加器（各函数体为JavaScript源码）
+type AccumulatorSpec struct {
+	Init           string        // 初始化状态，如 function() { return {count: 0, sum: 0} }
+	InitArgs       []interface{} // Init的参数（可选）
+	Accumulate     string        // 累加单个文档，如 function(state, v) { return {count: state.count + 1, sum: state.sum + v} }
+	AccumulateArgs []interface{} // Accumulate除state外的参数，如 []interface{}{"$amount"}
+	Merge          string        // 合并两个状态（分片/落盘时调用）
+	Finalize       string        // 生成最终结果（可选）
+}
+
+// Function 构建$function表达式（在$addFields/$project/$match的$expr等位置使用）
+//
+//	NewPipelineBuilder().AddFields(bson.D{{"score", Function("function(a, b) { return a * 0.7 + b * 0.3 }", "$views", "$likes")}})
+func Function(body string, args ...interface{}) bson.D {
+	if args == nil {
+		args = []interface{}{}
+	}
+	return bson.D{{"$function", bson.D{{"body", body}, {"args", bson.A(args)}, {"lang", "js"}}}}
+}
+
+// Accumulator 构建$accumulator表达式（在$group等累加位置使用）
+//
+//	NewPipelineBuilder().Group(bson.D{{"_id", "$shop"}, {"avg", Accumulator(spec)}})
+func Accumulator(spec AccumulatorSpec) bson.D {
+	acc := bson.D{{"init", spec.Init}}
+	if len(spec.InitArgs) > 0 {
+		acc = append(acc, bson.E{"initArgs", bson.A(spec.InitArgs)})
+	}
+	accumulateArgs := spec.AccumulateArgs
+	if accumulateArgs == nil {
+		accumulateArgs = []interface{}{}
+	}
+	acc = append(acc, bson.E{"accumulate", spec.Accumulate}, bson.E{"accumulateArgs", bson.A(accumulateArgs)}, bson.E{"merge", spec.Merge})
+	if spec.Finalize != "" {
+		acc = append(acc, bson.E{"finalize", spec.Finalize})
+	}
+	acc = append(acc, bson.E{"lang", "js"})
+	return bson.D{{"$accumulator", acc}}
+}
+
+// CheckUnsafeStages 检查聚合管道是否包含服务端脚本操作符，包含时返回ErrUnsafeStage（错误信息注明操作符与阶段序号）
+func CheckUnsafeStages(pipeline mongo.Pipeline) error {
+	for i, stage := range pipeline {
+		raw, err := bson.Marshal(stage)
+		if err != nil {
+			return fmt.Errorf("聚合管道第%d个阶段序列化失败：%w", i+1, err)
+		}
+		if op := findUnsafeOperator(raw); op != "" {
+			return fmt.Errorf("%w %s（第%d个阶段），需在MongoDB连接配置中开启allow_unsafe_stages", ErrUnsafeStage, op, i+1)
+		}
+	}
+	return nil
+}
+
+// findUnsafeOperator 递归查找文档/数组中的服务端脚本操作符
+func findUnsafeOperator(raw bson.Raw) string {
+	elements, err := raw.Elements()
+	if err != nil {
+		return ""
+	}
+	for _, element := range elements {
+		if unsafeOperators[element.Key()] {
+			return element.Key()
+		}
+		value := element.Value()
+		var nested bson.Raw
+		if doc, ok := value.DocumentOK(); ok {
+			nested = bson.Raw(doc)
+		} else if arr, ok := value.ArrayOK(); ok {
+			nested = bson.Raw(arr)
+		}
+		if nested != nil {
+			if op := findUnsafeOperator(nested); op != "" {
+				return op
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,387 @@
+package mongoDb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 集合统计与索引使用情况：封装$collStats、$indexStats，并结合慢查询分析器（system.profile）
+// 给出未使用索引与高频全表扫描（COLLSCAN）的优化建议。
+
+// CollectionStats 集合统计信息（字节数均为未压缩/存储引擎上报值）
+type CollectionStats struct {
+	Collection     string           `json:"collection"`
+	Count          int64            `json:"count"`            // 文档数
+	Size           int64            `json:"size"`             // 数据大小
+	AvgObjSize     int64            `json:"avg_obj_size"`     // 平均文档大小
+	StorageSize    int64            `json:"storage_size"`     // 占用存储空间
+	TotalIndexSize int64            `json:"total_index_size"` // 索引总大小
+	IndexSizes     map[string]int64 `json:"index_sizes"`      // 各索引大小
+	NIndexes       int64            `json:"nindexes"`         // 索引数
+	Capped         bool             `json:"capped"`           // 是否固定集合
+}
+
+// IndexUsage 单个索引的使用情况（统计自mongod启动或索引创建以来，按节点独立计数）
+type IndexUsage struct {
+	Name  string    `json:"name"`
+	Key   bson.D    `json:"key"`
+	Ops   int64     `json:"ops"`   // 被查询使用的次数
+	Since time.Time `json:"since"` // 开始统计的时间
+	Host  string    `json:"host"`
+}
+
+// CollScanPattern 全表扫描的查询模式（按操作类型与过滤字段归类）
+type CollScanPattern struct {
+	Namespace    string   `json:"namespace"`
+	Op           string   `json:"op"`            // query/update/remove/command等
+	Fields       []string `json:"fields"`        // 过滤条件的顶层字段（已排序）
+	Count        int      `json:"count"`         // 采样中出现的次数
+	AvgMillis    int64    `json:"avg_millis"`    // 平均耗时
+	DocsExamined int64    `json:"docs_examined"` // 平均扫描文档数
+	Example      bson.M   `json:"example"`       // 一条示例命令
+}
+
+// AdvisorOptions 索引建议参数
+type AdvisorOptions struct {
+	UnusedMinAge  time.Duration // 索引统计时长超过该值且使用次数为0才判定为未使用（默认24小时，避免刚重启或刚建的索引被误判）
+	ProfileWindow time.Duration // 分析最近多久的慢查询记录（默认24小时）
+	ProfileSample int64         // 最多采样的慢查询记录数（默认1000）
+	MinCollScans  int           // 同一模式出现多少次以上才提示（默认5）
+}
+
+// IndexAdvice 索引优化建议
+type IndexAdvice struct {
+	Collection    string            `json:"collection"`
+	UnusedIndexes []IndexUsage      `json:"unused_indexes"` // 可考虑删除的索引
+	CollScans     []CollScanPattern `json:"coll_scans"`     // 可考虑建索引的查询
+	ProfilerEmpty bool              `json:"profiler_empty"` // 未采集到慢查询记录（分析器可能未开启，见SetProfiling）
+}
+
+// CollectionStats 获取当前集合的统计信息（需先SetTable）
+func (m *Db) CollectionStats(ctx context.Context) (CollectionStats, error) {
+	defer m.clearData(false)
+	if m.Err != nil {
+		return CollectionStats{}, m.Err
+	}
+	if m.Collection == "" {
+		return CollectionStats{}, errors.New("未指定集合名")
+	}
+	return collectionStats(ctx, m.Db, m.Collection)
+}
+
+// IndexUsageReport 获取当前集合各索引的使用情况（需先SetTable，副本集仅返回所连节点的统计）
+func (m *Db) IndexUsageReport(ctx context.Context) ([]IndexUsage, error) {
+	defer m.clearData(false)
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if m.Collection == "" {
+		return nil, errors.New("未指定集合名")
+	}
+	return indexUsage(ctx, m.Db, m.Collection)
+}
+
+// AdviseIndexes 分析当前集合的索引使用情况与慢查询记录，给出优化建议（需先SetTable）
+func (m *Db) AdviseIndexes(ctx context.Context, opts AdvisorOptions) (IndexAdvice, error) {
+	defer m.clearData(false)
+	if m.Err != nil {
+		return IndexAdvice{}, m.Err
+	}
+	if m.Collection == "" {
+		return IndexAdvice{}, errors.New("未指定集合名")
+	}
+	usages, err := indexUsage(ctx, m.Db, m.Collection)
+	if err != nil {
+		return IndexAdvice{}, err
+	}
+	return adviseIndexes(ctx, m.Db, m.Collection, usages, opts)
+}
+
+// SetProfiling 设置当前库的分析器级别（0关闭，1记录慢于slowMs的操作，2记录全部操作）
+func (m *Db) SetProfiling(ctx context.Context, level int, slowMs int) error {
+	cmd := bson.D{{Key: "profile", Value: level}}
+	if slowMs > 0 {
+		cmd = append(cmd, bson.E{Key: "slowms", Value: slowMs})
+	}
+	if err := m.Db.RunCommand(ctx, cmd).Err(); err != nil {
+		return fmt.Errorf("设置分析器失败: %v", err)
+	}
+	return nil
+}
+
+func collectionStats(ctx context.Context, db *mongo.Database, coll string) (CollectionStats, error) {
+	stats := CollectionStats{Collection: coll}
+	pipeline := mongo.Pipeline{{{Key: "$collStats", Value: bson.D{{Key: "storageStats", Value: bson.D{}}}}}}
+	cursor, err := db.Collection(coll).Aggregate(ctx, pipeline)
+	if err != nil {
+		return stats, fmt.Errorf("获取集合统计失败: %v", err)
+	}
+	defer cursor.Close(ctx)
+	// 分片集合每个分片返回一条，累加即为整体统计
+	for cursor.Next(ctx) {
+		var doc struct {
+			StorageStats bson.M `bson:"storageStats"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return stats, fmt.Errorf("解析集合统计失败: %v", err)
+		}
+		s := doc.StorageStats
+		stats.Count += toInt64(s["count"])
+		stats.Size += toInt64(s["size"])
+		stats.StorageSize += toInt64(s["storageSize"])
+		stats.TotalIndexSize += toInt64(s["totalIndexSize"])
+		stats.NIndexes = toInt64(s["nindexes"])
+		stats.Capped, _ = s["capped"].(bool)
+		if sizes, ok := s["indexSizes"].(bson.M); ok {
+			if stats.IndexSizes == nil {
+				stats.IndexSizes = make(map[string]int64, len(sizes))
+			}
+			for name, size := range sizes {
+				stats.IndexSizes[name] += toInt64(size)
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return stats, fmt.Errorf("集合统计游标遍历失败: %v", err)
+	}
+	if stats.Count > 0 {
+		stats.AvgObjSize = stats.Size / stats.Count
+	}
+	return stats, nil
+}
+
+func indexUsage(ctx context.Context, db *mongo.Database, coll string) ([]IndexUsage, error) {
+	pipeline := mongo.Pipeline{{{Key: "$indexStats", Value: bson.D{}}}}
+	cursor, err := db.Collection(coll).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("获取索引统计失败: %v", err)
+	}
+	defer cursor.Close(ctx)
+	var result []IndexUsage
+	for cursor.Next(ctx) {
+		var doc struct {
+			Name     string `bson:"name"`
+			Key      bson.D `bson:"key"`
+			Host     string `bson:"host"`
+			Accesses struct {
+				Ops   interface{} `bson:"ops"`
+				Since time.Time   `bson:"since"`
+			} `bson:"accesses"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("解析索引统计失败: %v", err)
+		}
+		result = append(result, IndexUsage{
+			Name:  doc.Name,
+			Key:   doc.Key,
+			Ops:   toInt64(doc.Accesses.Ops),
+			Since: doc.Accesses.Since,
+			Host:  doc.Host,
+		})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("索引统计游标遍历失败: %v", err)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func adviseIndexes(ctx context.Context, db *mongo.Database, coll string, usages []IndexUsage, opts AdvisorOptions) (IndexAdvice, error) {
+	if opts.UnusedMinAge <= 0 {
+		opts.UnusedMinAge = 24 * time.Hour
+	}
+	if opts.ProfileWindow <= 0 {
+		opts.ProfileWindow = 24 * time.Hour
+	}
+	if opts.ProfileSample <= 0 {
+		opts.ProfileSample = 1000
+	}
+	if opts.MinCollScans <= 0 {
+		opts.MinCollScans = 5
+	}
+	advice := IndexAdvice{Collection: coll, UnusedIndexes: []IndexUsage{}, CollScans: []CollScanPattern{}}
+	for _, usage := range usages {
+		if usage.Name != "_id_" && usage.Ops == 0 && time.Since(usage.Since) >= opts.UnusedMinAge {
+			advice.UnusedIndexes = append(advice.UnusedIndexes, usage)
+		}
+	}
+	patterns, sampled, err := collScanPatterns(ctx, db, coll, opts)
+	if err != nil {
+		return advice, err
+	}
+	advice.ProfilerEmpty = sampled == 0
+	for _, p := range patterns {
+		if p.Count >= opts.MinCollScans {
+			advice.CollScans = append(advice.CollScans, p)
+		}
+	}
+	return advice, nil
+}
+
+// collScanPatterns 采样分析器记录中的全表扫描并按模式归类，返回模式列表与采样的记录数
+func collScanPatterns(ctx context.Context, db *mongo.Database, coll string, opts AdvisorOptions) ([]CollScanPattern, int, error) {
+	ns := db.Name() + "." + coll
+	filter := bson.D{
+		{Key: "ns", Value: ns},
+		{Key: "planSummary", Value: "COLLSCAN"},
+		{Key: "ts", Value: bson.D{{Key: "$gte", Value: time.Now().Add(-opts.ProfileWindow)}}},
+	}
+	findOpts := options.Find().SetSort(bson.D{{Key: "ts", Value: -1}}).SetLimit(opts.ProfileSample).
+		SetProjection(bson.D{{Key: "op", Value: 1}, {Key: "command", Value: 1}, {Key: "millis", Value: 1}, {Key: "docsExamined", Value: 1}})
+	cursor, err := db.Collection("system.profile").Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取分析器记录失败: %v", err)
+	}
+	defer cursor.Close(ctx)
+	type acc struct {
+		pattern      CollScanPattern
+		millis, docs int64
+	}
+	groups := make(map[string]*acc)
+	sampled := 0
+	for cursor.Next(ctx) {
+		var doc struct {
+			Op           string      `bson:"op"`
+			Command      bson.M      `bson:"command"`
+			Millis       interface{} `bson:"millis"`
+			DocsExamined interface{} `bson:"docsExamined"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, sampled, fmt.Errorf("解析分析器记录失败: %v", err)
+		}
+		sampled++
+		fields := filterFields(doc.Command)
+		key := doc.Op + "|" + strings.Join(fields, ",")
+		g, ok := groups[key]
+		if !ok {
+			g = &acc{pattern: CollScanPattern{Namespace: ns, Op: doc.Op, Fields: fields, Example: doc.Command}}
+			groups[key] = g
+		}
+		g.pattern.Count++
+		g.millis += toInt64(doc.Millis)
+		g.docs += toInt64(doc.DocsExamined)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, sampled, fmt.Errorf("分析器记录游标遍历失败: %v", err)
+	}
+	patterns := make([]CollScanPattern, 0, len(groups))
+	for _, g := range groups {
+		g.pattern.AvgMillis = g.millis / int64(g.pattern.Count)
+		g.pattern.DocsExamined = g.docs / int64(g.pattern.Count)
+		patterns = append(patterns, g.pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].Count > patterns[j].Count })
+	return patterns, sampled, nil
+}
+
+// filterFields 提取命令中过滤条件的顶层字段（find/count/delete的filter，update的q，aggregate首个$match）
+func filterFields(cmd bson.M) []string {
+	var filter interface{}
+	switch {
+	case cmd["filter"] != nil:
+		filter = cmd["filter"]
+	case cmd["query"] != nil:
+		filter = cmd["query"]
+	case cmd["q"] != nil:
+		filter = cmd["q"]
+	case cmd["pipeline"] != nil:
+		if stages, ok := cmd["pipeline"].(bson.A); ok && len(stages) > 0 {
+			if stage, ok := stages[0].(bson.M); ok {
+				filter = stage["$match"]
+			}
+		}
+	}
+	fields := []string{}
+	if m, ok := filter.(bson.M); ok {
+		for key := range m {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// StatsHandler 集合统计管理接口（标准net/http处理器，需由调用方挂载并自行做好鉴权）：
+// GET ?collection=xxx 返回该集合（逻辑名，自动拼接表前缀）的统计、索引使用情况与优化建议，
+// 不传collection时返回库中所有带表前缀集合的统计与建议
+func StatsHandler(dbKey string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := statsReport(r.Context(), dbKey, r.URL.Query().Get("collection"))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 500, "msg": err.Error(), "data": nil})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "msg": "success", "data": data})
+	}
+}
+
+// statsReport 汇总集合统计、索引使用情况与优化建议
+func statsReport(ctx context.Context, dbKey, collection string) ([]map[string]interface{}, error) {
+	m, err := GetMongoDB(dbKey)
+	if err != nil {
+		return nil, err
+	}
+	var colls []string
+	if collection != "" {
+		colls = []string{m.DbPre + collection}
+	} else {
+		names, err := m.Db.ListCollectionNames(ctx, bson.D{{Key: "type", Value: "collection"}})
+		if err != nil {
+			return nil, fmt.Errorf("获取集合列表失败: %v", err)
+		}
+		for _, name := range names {
+			if strings.HasPrefix(name, m.DbPre) && !strings.HasPrefix(name, "system.") {
+				colls = append(colls, name)
+			}
+		}
+		sort.Strings(colls)
+	}
+	report := make([]map[string]interface{}, 0, len(colls))
+	for _, coll := range colls {
+		stats, err := collectionStats(ctx, m.Db, coll)
+		if err != nil {
+			return nil, err
+		}
+		usages, err := indexUsage(ctx, m.Db, coll)
+		if err != nil {
+			return nil, err
+		}
+		advice, err := adviseIndexes(ctx, m.Db, coll, usages, AdvisorOptions{})
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, map[string]interface{}{"stats": stats, "indexes": usages, "advice": advice})
+	}
+	return report, nil
+}
+
+// toInt64 兼容int32/int64/double等数值类型
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	case int:
+		return int64(n)
+	}
+	return 0
+}
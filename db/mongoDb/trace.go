@@ -0,0 +1,36 @@
+package mongoDb
+
+import (
+	"context"
+	"errors"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+)
+
+// newTraceMonitor 构建MongoDB命令监听器（启用链路追踪时为每条命令开启span，语句摘要为“命令 库.集合”）
+func newTraceMonitor() *event.CommandMonitor {
+	var spans sync.Map // key: RequestID, value: trace.Span
+	endSpan := func(requestID int64, err error) {
+		if val, ok := spans.LoadAndDelete(requestID); ok {
+			tracing.End(val.(trace.Span), err)
+		}
+	}
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			statement := evt.CommandName + " " + evt.DatabaseName
+			if col, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+				statement += "." + col
+			}
+			_, span := tracing.StartDbSpan(ctx, "mongodb", evt.CommandName, statement)
+			spans.Store(evt.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			endSpan(evt.RequestID, nil)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			endSpan(evt.RequestID, errors.New(evt.Failure))
+		},
+	}
+}
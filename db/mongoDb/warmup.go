@@ -0,0 +1,68 @@
+package mongoDb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"sync"
+)
+
+// Warmup 预热连接池：并发执行conns次Ping（<=0时取MinPoolSize），促使驱动提前建立连接。
+// 驱动另会在后台将连接数维持在MinPoolSize以上，因此返回的是成功的Ping次数而非精确的连接数。
+func Warmup(ctx context.Context, conns int) (map[string]int, error) {
+	result := make(map[string]int)
+	var errs []error
+	multiClientPool.Range(func(key, value interface{}) bool {
+		dbObj := value.(DbObj)
+		n := conns
+		if n <= 0 {
+			n = dbObj.minPool
+		}
+		if n <= 0 {
+			n = 1
+		}
+		var (
+			mu    sync.Mutex
+			wg    sync.WaitGroup
+			ok    int
+			first error
+		)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := dbObj.Client.Ping(ctx, readpref.Primary())
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if first == nil {
+						first = err
+					}
+					return
+				}
+				ok++
+			}()
+		}
+		wg.Wait()
+		result[key.(string)] = ok
+		if first != nil {
+			errs = append(errs, fmt.Errorf("MongoDB[%v]预热失败：%w", key, first))
+		}
+		return true
+	})
+	return result, errors.Join(errs...)
+}
+
+// Pingers 各连接的可用性检查函数（供健康检查使用），key为连接key
+func Pingers() map[string]func(context.Context) error {
+	result := make(map[string]func(context.Context) error)
+	multiClientPool.Range(func(key, value interface{}) bool {
+		client := value.(DbObj).Client
+		result[key.(string)] = func(ctx context.Context) error {
+			return client.Ping(ctx, readpref.Primary())
+		}
+		return true
+	})
+	return result
+}
@@ -0,0 +1,151 @@
+package mysql
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// 写入约定（需显式开启）：Insert/InsertAll/Update时自动填充创建/更新时间，并为缺失字段补充按表注册的默认值。
+// 调用方显式传入的字段始终优先，不会被覆盖。
+
+// 时间格式
+const (
+	TimeFormatDatetime  = "datetime"  // 字符串 2006-01-02 15:04:05（默认）
+	TimeFormatUnix      = "unix"      // 秒级时间戳
+	TimeFormatUnixMilli = "unixmilli" // 毫秒级时间戳
+)
+
+// TimestampConfig 自动时间字段配置
+type TimestampConfig struct {
+	CreatedAtColumn string   // 创建时间字段名（默认created_at，"-"表示不填充）
+	UpdatedAtColumn string   // 更新时间字段名（默认updated_at，"-"表示不填充）
+	TimeFormat      string   // 时间格式：datetime/unix/unixmilli，也可直接填写Go时间布局（如 2006-01-02）
+	Tables          []string // 生效的表名（不含前缀），为空时对所有表生效
+}
+
+// DefaultValueFunc 动态默认值（每次写入时计算）
+type DefaultValueFunc func() interface{}
+
+var (
+	conventionMu  sync.RWMutex
+	timestampCfg  *TimestampConfig
+	tableDefaults = make(map[string]map[string]interface{})
+)
+
+// EnableTimestamps 开启自动时间字段填充
+func EnableTimestamps(cfg TimestampConfig) {
+	if cfg.CreatedAtColumn == "" {
+		cfg.CreatedAtColumn = "created_at"
+	}
+	if cfg.UpdatedAtColumn == "" {
+		cfg.UpdatedAtColumn = "updated_at"
+	}
+	if cfg.TimeFormat == "" {
+		cfg.TimeFormat = TimeFormatDatetime
+	}
+	conventionMu.Lock()
+	defer conventionMu.Unlock()
+	timestampCfg = &cfg
+}
+
+// DisableTimestamps 关闭自动时间字段填充
+func DisableTimestamps() {
+	conventionMu.Lock()
+	defer conventionMu.Unlock()
+	timestampCfg = nil
+}
+
+// RegisterDefaults 注册表的字段默认值（表名不含前缀；值为DefaultValueFunc时每次写入动态计算），Insert/InsertAll时为缺失字段补充
+func RegisterDefaults(table string, defaults map[string]interface{}) {
+	conventionMu.Lock()
+	defer conventionMu.Unlock()
+	merged := make(map[string]interface{}, len(defaults))
+	for key, val := range tableDefaults[table] {
+		merged[key] = val
+	}
+	for key, val := range defaults {
+		merged[key] = val
+	}
+	tableDefaults[table] = merged
+}
+
+// formatTime 按配置格式输出时间值
+func (c *TimestampConfig) formatTime(now time.Time) interface{} {
+	switch c.TimeFormat {
+	case TimeFormatDatetime:
+		return now.Format("2006-01-02 15:04:05")
+	case TimeFormatUnix:
+		return now.Unix()
+	case TimeFormatUnixMilli:
+		return now.UnixMilli()
+	}
+	return now.Format(c.TimeFormat)
+}
+
+// appliesTo 判断时间字段配置是否对表生效
+func (c *TimestampConfig) appliesTo(table string) bool {
+	if len(c.Tables) == 0 {
+		return true
+	}
+	for _, t := range c.Tables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// logicalTable 去掉前缀的表名
+func (db *MysqlDb) logicalTable() string {
+	return strings.TrimPrefix(db.Table, db.DbPre)
+}
+
+// applyInsertConvention 返回补充默认值与时间字段后的数据副本（未开启任何约定时原样返回）
+func (db *MysqlDb) applyInsertConvention(data map[string]interface{}, now time.Time) map[string]interface{} {
+	conventionMu.RLock()
+	cfg := timestampCfg
+	defaults := tableDefaults[db.logicalTable()]
+	conventionMu.RUnlock()
+	useTimestamps := cfg != nil && cfg.appliesTo(db.logicalTable())
+	if !useTimestamps && len(defaults) == 0 {
+		return data
+	}
+	result := make(map[string]interface{}, len(data)+len(defaults)+2)
+	for key, val := range defaults {
+		if fn, ok := val.(DefaultValueFunc); ok {
+			val = fn()
+		}
+		result[key] = val
+	}
+	for key, val := range data {
+		result[key] = val
+	}
+	if useTimestamps {
+		for _, column := range []string{cfg.CreatedAtColumn, cfg.UpdatedAtColumn} {
+			if _, ok := data[column]; !ok && column != "-" {
+				result[column] = cfg.formatTime(now)
+			}
+		}
+	}
+	return result
+}
+
+// applyUpdateConvention 返回补充更新时间后的数据副本（未开启或调用方已指定时原样返回）
+func (db *MysqlDb) applyUpdateConvention(data map[string]interface{}, now time.Time) map[string]interface{} {
+	conventionMu.RLock()
+	cfg := timestampCfg
+	conventionMu.RUnlock()
+	if cfg == nil || cfg.UpdatedAtColumn == "-" || !cfg.appliesTo(db.logicalTable()) {
+		return data
+	}
+	if _, ok := data[cfg.UpdatedAtColumn]; ok {
+		return data
+	}
+	result := make(map[string]interface{}, len(data)+1)
+	for key, val := range data {
+		result[key] = val
+	}
+	result[cfg.UpdatedAtColumn] = cfg.formatTime(now)
+	return result
+}
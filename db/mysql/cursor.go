@@ -0,0 +1,88 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/cursor"
+	"strconv"
+	"strings"
+)
+
+// SetCursor 设置游标分页（keyset）：token为上一页返回的next_cursor/prev_cursor（首页为空），
+// fields为排序字段（最后一个须唯一，如主键；排序字段不能为NULL），会覆盖SetOrder与SetLimit，配合FindPage使用
+func (db *MysqlDb) SetCursor(token string, limit int64, fields ...cursor.Field) *MysqlDb {
+	if db.Err != nil {
+		return db
+	}
+	for _, f := range fields {
+		if !isValidTable(f.Name) {
+			db.Err = fmt.Errorf("排序字段[%s]包含非法字符，存在注入风险", f.Name)
+			return db
+		}
+	}
+	q, err := cursor.NewQuery(token, limit, fields)
+	if err != nil {
+		db.Err = err
+		return db
+	}
+	db.page = q
+	return db
+}
+
+// FindPage 执行游标分页查询，返回当前页数据与上一页/下一页游标
+func (db *MysqlDb) FindPage(ctx context.Context) (*cursor.Page, error) {
+	defer db.clearData(false)
+	if db.Err != nil {
+		return nil, db.Err
+	}
+	q := db.page
+	if q == nil {
+		return nil, errors.New("未设置游标分页（请先调用SetCursor）")
+	}
+	orders := make([]string, len(q.Fields))
+	for i, f := range q.Fields {
+		if q.Desc(i) {
+			orders[i] = f.Name + " DESC"
+		} else {
+			orders[i] = f.Name + " ASC"
+		}
+	}
+	db.Order = strings.Join(orders, ", ")
+	db.Limit = strconv.FormatInt(q.Fetch(), 10)
+	if q.Cursor != nil {
+		tpl, args := keysetWhere(q)
+		db.WhereTemplates = append(db.WhereTemplates, tpl)
+		db.WhereArgs = append(db.WhereArgs, args...)
+	}
+	db.FindAll(ctx)
+	if db.Err != nil {
+		return nil, db.Err
+	}
+	return q.Page(db.Data, nil)
+}
+
+// keysetWhere 生成游标之后的记录条件：(a < ? OR (a = ? AND id < ?))
+func keysetWhere(q *cursor.Query) (string, []interface{}) {
+	ors := make([]string, 0, len(q.Fields))
+	args := make([]interface{}, 0, len(q.Fields)*(len(q.Fields)+1)/2)
+	for i, f := range q.Fields {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, q.Fields[j].Name+" = ?")
+			args = append(args, q.Cursor.Values[j])
+		}
+		op := " > ?"
+		if q.Desc(i) {
+			op = " < ?"
+		}
+		ands = append(ands, f.Name+op)
+		args = append(args, q.Cursor.Values[i])
+		if len(ands) == 1 {
+			ors = append(ors, ands[0])
+		} else {
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
@@ -0,0 +1,373 @@
+package mysql
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// 逻辑备份：以INSERT语句导出表结构与数据（输出格式与mysqldump兼容，可直接用mysql客户端导入），
+// 以及对应的Import导入，适合小规模部署配合定时任务做周期备份，无需依赖mysqldump命令。
+
+// ExportOptions 导出参数
+type ExportOptions struct {
+	Consistent bool              // 在单个可重复读只读事务中导出，保证多表数据一致（仅InnoDB表）
+	NoCreate   bool              // 不导出建表语句
+	DropTable  bool              // 建表前输出DROP TABLE IF EXISTS
+	NoData     bool              // 仅导出表结构
+	BatchRows  int               // 每条INSERT语句包含的行数（默认500）
+	Where      map[string]string // 按表过滤导出的数据（键为逻辑表名，值为WHERE条件，不含WHERE关键字）
+}
+
+// ImportOptions 导入参数
+type ImportOptions struct {
+	ContinueOnError bool // 单条语句失败时继续执行（默认遇错即停）
+}
+
+// ExportResult 导出统计
+type ExportResult struct {
+	Tables int   // 导出的表数
+	Rows   int64 // 导出的数据行数
+}
+
+// queryer 连接池与事务共用的查询接口
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Export 导出指定表（逻辑表名，自动拼接表前缀；为空时导出当前库中带表前缀的全部数据表）
+func (db *MysqlDb) Export(ctx context.Context, tables []string, w io.Writer, opts ExportOptions) (ExportResult, error) {
+	var result ExportResult
+	if db.Db == nil {
+		return result, errors.New("数据库连接未初始化")
+	}
+	if opts.BatchRows <= 0 {
+		opts.BatchRows = 500
+	}
+	var q queryer = db.Db
+	if opts.Consistent {
+		tx, err := db.Db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		if err != nil {
+			return result, fmt.Errorf("开启导出事务失败：%w", err)
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+	physical, err := db.exportTables(ctx, q, tables)
+	if err != nil {
+		return result, err
+	}
+
+	bw := bufio.NewWriterSize(w, 64*1024)
+	fmt.Fprintf(bw, "-- go-dai logical dump\n-- Dump time: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	bw.WriteString("SET NAMES utf8mb4;\nSET FOREIGN_KEY_CHECKS=0;\nSET UNIQUE_CHECKS=0;\n\n")
+	for _, table := range physical {
+		if !isValidTable(table) {
+			return result, fmt.Errorf("表名[%s]不合法", table)
+		}
+		if !opts.NoCreate {
+			if err := dumpCreateTable(ctx, q, bw, table, opts.DropTable); err != nil {
+				return result, err
+			}
+		}
+		if !opts.NoData {
+			where := opts.Where[strings.TrimPrefix(table, db.DbPre)]
+			rows, err := dumpTableData(ctx, q, bw, table, where, opts.BatchRows)
+			if err != nil {
+				return result, err
+			}
+			result.Rows += rows
+		}
+		result.Tables++
+	}
+	bw.WriteString("SET UNIQUE_CHECKS=1;\nSET FOREIGN_KEY_CHECKS=1;\n")
+	return result, bw.Flush()
+}
+
+// exportTables 解析需要导出的物理表名
+func (db *MysqlDb) exportTables(ctx context.Context, q queryer, tables []string) ([]string, error) {
+	if len(tables) > 0 {
+		physical := make([]string, 0, len(tables))
+		for _, table := range tables {
+			physical = append(physical, db.DbPre+table)
+		}
+		return physical, nil
+	}
+	rows, err := q.QueryContext(ctx, "SHOW FULL TABLES WHERE Table_type = 'BASE TABLE'")
+	if err != nil {
+		return nil, fmt.Errorf("获取表列表失败：%w", err)
+	}
+	defer rows.Close()
+	var physical []string
+	for rows.Next() {
+		var name, tableType string
+		if err := rows.Scan(&name, &tableType); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(name, db.DbPre) {
+			physical = append(physical, name)
+		}
+	}
+	return physical, rows.Err()
+}
+
+// dumpCreateTable 输出建表语句
+func dumpCreateTable(ctx context.Context, q queryer, w *bufio.Writer, table string, drop bool) error {
+	var name, createSQL string
+	if err := q.QueryRowContext(ctx, "SHOW CREATE TABLE `"+table+"`").Scan(&name, &createSQL); err != nil {
+		return fmt.Errorf("获取表[%s]结构失败：%w", table, err)
+	}
+	fmt.Fprintf(w, "--\n-- Table structure for table `%s`\n--\n\n", table)
+	if drop {
+		fmt.Fprintf(w, "DROP TABLE IF EXISTS `%s`;\n", table)
+	}
+	w.WriteString(createSQL)
+	w.WriteString(";\n\n")
+	return nil
+}
+
+// dumpTableData 以多行INSERT语句输出表数据，返回行数
+func dumpTableData(ctx context.Context, q queryer, w *bufio.Writer, table, where string, batchRows int) (int64, error) {
+	query := "SELECT * FROM `" + table + "`"
+	if where != "" {
+		if !isValidWhere(where) {
+			return 0, fmt.Errorf("表[%s]的导出条件不合法", table)
+		}
+		query += " WHERE " + where
+	}
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("导出表[%s]数据失败：%w", table, err)
+	}
+	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	columns := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = "`" + ct.Name() + "`"
+	}
+	insertPrefix := "INSERT INTO `" + table + "` (" + strings.Join(columns, ", ") + ") VALUES\n"
+
+	values := make([]sql.RawBytes, len(columnTypes))
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	fmt.Fprintf(w, "--\n-- Dumping data for table `%s`\n--\n\n", table)
+	var total int64
+	inBatch := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return total, err
+		}
+		if inBatch == 0 {
+			w.WriteString(insertPrefix)
+		} else {
+			w.WriteString(",\n")
+		}
+		w.WriteByte('(')
+		for i, value := range values {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			writeSQLValue(w, value, columnTypes[i].DatabaseTypeName())
+		}
+		w.WriteByte(')')
+		total++
+		if inBatch++; inBatch >= batchRows {
+			w.WriteString(";\n")
+			inBatch = 0
+		}
+	}
+	if inBatch > 0 {
+		w.WriteString(";\n")
+	}
+	w.WriteString("\n")
+	return total, rows.Err()
+}
+
+// writeSQLValue 按列类型输出SQL字面量（数值原样输出，二进制输出为十六进制，其余按字符串转义）
+func writeSQLValue(w *bufio.Writer, value sql.RawBytes, dbType string) {
+	if value == nil {
+		w.WriteString("NULL")
+		return
+	}
+	switch dbType {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "UNSIGNED TINYINT", "UNSIGNED SMALLINT",
+		"UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT", "DECIMAL", "FLOAT", "DOUBLE", "YEAR":
+		w.Write(value)
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		if len(value) == 0 {
+			w.WriteString("''")
+			return
+		}
+		w.WriteString("0x")
+		w.WriteString(hex.EncodeToString(value))
+	default:
+		w.WriteByte('\'')
+		for _, b := range value {
+			switch b {
+			case 0:
+				w.WriteString(`\0`)
+			case '\n':
+				w.WriteString(`\n`)
+			case '\r':
+				w.WriteString(`\r`)
+			case '\\':
+				w.WriteString(`\\`)
+			case '\'':
+				w.WriteString(`\'`)
+			case '"':
+				w.WriteString(`\"`)
+			case 0x1a:
+				w.WriteString(`\Z`)
+			default:
+				w.WriteByte(b)
+			}
+		}
+		w.WriteByte('\'')
+	}
+}
+
+// Import 执行SQL脚本（如Export的输出），返回成功执行的语句数。
+// 语句在同一连接上依次执行以保证SET等会话变量生效；DDL会隐式提交，因此不包裹事务。
+func (db *MysqlDb) Import(ctx context.Context, r io.Reader, opts ImportOptions) (int64, error) {
+	if db.Db == nil {
+		return 0, errors.New("数据库连接未初始化")
+	}
+	conn, err := db.Db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	var (
+		executed int64
+		errs     []error
+	)
+	err = splitStatements(r, func(stmt string) error {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			err = fmt.Errorf("执行语句失败：%w（%s）", err, abbreviate(stmt, 120))
+			if !opts.ContinueOnError {
+				return err
+			}
+			errs = append(errs, err)
+			return nil
+		}
+		executed++
+		return nil
+	})
+	if err != nil {
+		return executed, err
+	}
+	return executed, errors.Join(errs...)
+}
+
+// splitStatements 按分号切分SQL脚本（忽略引号内的分号与注释）
+func splitStatements(r io.Reader, fn func(stmt string) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var (
+		stmt    strings.Builder
+		quote   byte // 当前所在的引号（'、"、`），0表示不在引号内
+		escaped bool
+	)
+	flush := func() error {
+		s := strings.TrimSpace(stmt.String())
+		stmt.Reset()
+		if s == "" {
+			return nil
+		}
+		return fn(s)
+	}
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+		if quote != 0 {
+			stmt.WriteByte(b)
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\' && quote != '`':
+				escaped = true
+			case b == quote:
+				quote = 0
+			}
+			continue
+		}
+		switch b {
+		case '\'', '"', '`':
+			quote = b
+			stmt.WriteByte(b)
+		case ';':
+			if err := flush(); err != nil {
+				return err
+			}
+		case '#':
+			if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+				return err
+			}
+			stmt.WriteByte('\n')
+		case '-':
+			// "-- " 行注释
+			next, _ := br.Peek(2)
+			if len(next) == 2 && next[0] == '-' && (next[1] == ' ' || next[1] == '\t' || next[1] == '\n') {
+				if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+					return err
+				}
+				stmt.WriteByte('\n')
+				continue
+			}
+			stmt.WriteByte(b)
+		case '/':
+			// 块注释（保留/*!...*/可执行注释）
+			next, _ := br.Peek(2)
+			if len(next) >= 1 && next[0] == '*' && !(len(next) == 2 && next[1] == '!') {
+				if err := skipBlockComment(br); err != nil {
+					return err
+				}
+				stmt.WriteByte(' ')
+				continue
+			}
+			stmt.WriteByte(b)
+		default:
+			stmt.WriteByte(b)
+		}
+	}
+}
+
+// skipBlockComment 跳过块注释（当前位置为"/*"的"*"之前）
+func skipBlockComment(br *bufio.Reader) error {
+	_, _ = br.ReadByte() // '*'
+	prev := byte(0)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		if prev == '*' && b == '/' {
+			return nil
+		}
+		prev = b
+	}
+}
+
+// abbreviate 截断过长的语句用于错误信息
+func abbreviate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
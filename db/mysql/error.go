@@ -0,0 +1,36 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/dfpopp/go-dai/errs"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+)
+
+// MySQL错误号
+const (
+	errDupEntry        = 1062 // 唯一键冲突
+	errLockWaitTimeout = 1205 // 锁等待超时
+	errLockDeadlock    = 1213 // 死锁
+)
+
+// wrapError 将驱动错误归类为框架统一错误（唯一键冲突->Conflict，死锁/锁等待超时->Conflict，
+// 超时->Timeout，连接失效->Unavailable），原始错误保留为原因；无法归类的原样返回
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var myErr *mysqlDriver.MySQLError
+	switch {
+	case errors.As(err, &myErr) && myErr.Number == errDupEntry:
+		return errs.Conflict.WithMessage("数据已存在").Wrap(err)
+	case errors.As(err, &myErr) && (myErr.Number == errLockDeadlock || myErr.Number == errLockWaitTimeout):
+		return errs.Conflict.WithMessage("数据正被其他操作修改，请重试").Wrap(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return errs.Timeout.Wrap(err)
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysqlDriver.ErrInvalidConn):
+		return errs.Unavailable.Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package mysql
+
+import (
+	"fmt"
+	"github.com/dfpopp/go-dai/db/fieldcrypt"
+)
+
+// 敏感字段加密：按fieldcrypt登记的表字段，Insert/InsertAll/Update(map)写入前自动加密并填充盲索引列，
+// FindAll/Find/游标读取后自动解密。UpdateBySet/SetInc/Exec等原生SQL不做处理，加密字段须通过Update(map)更新。
+// 加密字段不能直接用于WHERE条件（密文每次不同），等值查询请使用SetWhereBlind。
+
+// encryptRow 返回加密登记字段后的数据副本（表未登记加密字段时原样返回）
+func (db *MysqlDb) encryptRow(data map[string]interface{}) (map[string]interface{}, error) {
+	return fieldcrypt.EncryptRow(db.logicalTable(), data)
+}
+
+// decryptRows 原地解密查询结果中的登记字段
+func (db *MysqlDb) decryptRows(rows []map[string]interface{}) error {
+	return fieldcrypt.DecryptRows(db.logicalTable(), rows)
+}
+
+// SetWhereBlind 按加密字段的盲索引进行等值查询（如SetWhereBlind("phone", "13800000000")转为`phone_bidx` = ?）
+func (db *MysqlDb) SetWhereBlind(field string, value interface{}) *MysqlDb {
+	if db.Err != nil {
+		return db
+	}
+	column, index, err := fieldcrypt.BlindIndex(db.logicalTable(), field, value)
+	if err != nil {
+		db.Err = err
+		return db
+	}
+	if !isValidField(column) {
+		db.Err = fmt.Errorf("盲索引字段[%s]包含非法字符，存在注入风险", column)
+		return db
+	}
+	db.WhereTemplates = append(db.WhereTemplates, fmt.Sprintf("`%s` = ?", column))
+	db.WhereArgs = append(db.WhereArgs, index)
+	return db
+}
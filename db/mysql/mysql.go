@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/chaos"
+	"github.com/dfpopp/go-dai/db/cursor"
 	"github.com/dfpopp/go-dai/function"
 	"github.com/dfpopp/go-dai/logger"
 	"math"
@@ -37,10 +39,13 @@ type MysqlDb struct {
 	Limit          string
 	Data           []map[string]interface{}
 	Err            error
+	dbKey          string        // 连接标识（故障注入按连接匹配规则）
+	page           *cursor.Query // 游标分页（SetCursor设置）
 }
 type DbObj struct {
-	Db  *sql.DB // 复用全局数据库连接池
-	Pre string
+	Db   *sql.DB // 复用全局数据库连接池
+	Pre  string
+	idle int // 最大空闲连接数（预热目标）
 }
 
 // InitMySQL 初始化MySQL连接池
@@ -73,7 +78,7 @@ func InitMySQL() {
 			if err := db.Ping(); err != nil {
 				logger.Error("MySQL Ping失败: " + err.Error())
 			}
-			multiDBPool.Store(dbKey, DbObj{Db: db, Pre: cfg.Pre})
+			multiDBPool.Store(dbKey, DbObj{Db: db, Pre: cfg.Pre, idle: cfg.MaxIdleConnNum})
 		}
 	}
 }
@@ -102,6 +107,7 @@ func GetMysqlDB(dbKey string) (*MysqlDb, error) {
 		Limit:          "",
 		Data:           nil,
 		Err:            nil,
+		dbKey:          dbKey,
 	}, nil
 }
 func (db *MysqlDb) ToBegin() error {
@@ -325,20 +331,16 @@ func (db *MysqlDb) FindAll(ctx context.Context) *MysqlDb {
 	}
 	var rows *sql.Rows
 	var err error
-	if db.Tx != nil {
-		rows, err = db.Tx.QueryContext(ctx, sqlStr, db.WhereArgs...)
-	} else {
-		rows, err = db.Db.QueryContext(ctx, sqlStr, db.WhereArgs...)
-	}
+	rows, err = db.queryContext(ctx, sqlStr, db.WhereArgs...)
 	if err != nil {
-		db.Err = fmt.Errorf("SQL语句:%s，values:%s,查询失败，失败原因[%s]", sqlStr, function.Json_encode(db.WhereArgs), err.Error())
+		db.Err = fmt.Errorf("SQL语句:%s，values:%s,查询失败，失败原因[%w]", sqlStr, function.Json_encode(db.WhereArgs), err)
 		return db
 	}
 	// 确保结果集关闭
 	defer func() {
 		if rows != nil {
 			if closeErr := rows.Close(); closeErr != nil {
-				logger.Error("关闭结果集失败: %v", closeErr)
+				logger.Errorf("关闭结果集失败: %v", closeErr)
 			}
 		}
 	}()
@@ -376,6 +378,11 @@ func (db *MysqlDb) FindAll(ctx context.Context) *MysqlDb {
 		db.Err = fmt.Errorf("遍历结果集失败: %w", err)
 		return db
 	}
+	// 敏感字段解密（未登记时不做处理）
+	if err := db.decryptRows(result); err != nil {
+		db.Err = err
+		return db
+	}
 	db.Data = result
 	return db
 }
@@ -458,6 +465,13 @@ func (db *MysqlDb) Insert(ctx context.Context, data map[string]interface{}) (int
 			return 0, errors.New("表名包含非法字符，存在注入风险")
 		}
 	}
+	// 写入约定：补充默认值与创建/更新时间（未开启时不做处理）
+	data = db.applyInsertConvention(data, time.Now())
+	// 敏感字段加密（未登记时不做处理）
+	data, err := db.encryptRow(data)
+	if err != nil {
+		return 0, err
+	}
 	var (
 		fields       []string      // 存储字段名
 		placeholders []string      // 存储参数占位符?
@@ -476,12 +490,7 @@ func (db *MysqlDb) Insert(ctx context.Context, data map[string]interface{}) (int
 
 	// 执行SQL
 	var result sql.Result
-	var err error
-	if db.Tx != nil {
-		result, err = db.Tx.ExecContext(ctx, sqlStr, values...)
-	} else {
-		result, err = db.Db.ExecContext(ctx, sqlStr, values...)
-	}
+	result, err = db.execContext(ctx, sqlStr, values...)
 	if err != nil {
 		return 0, fmt.Errorf("执行插入SQL失败，SQL：%s，values:%s,错误：%w", sqlStr, function.Json_encode(values), err)
 	}
@@ -508,6 +517,23 @@ func (db *MysqlDb) InsertAll(ctx context.Context, dataList []map[string]interfac
 			return 0, errors.New("表名包含非法字符，存在注入风险")
 		}
 	}
+	// 写入约定：每条数据补充默认值与创建/更新时间（同一批次使用相同时间）
+	now := time.Now()
+	conventionList := make([]map[string]interface{}, len(dataList))
+	for i, data := range dataList {
+		data, err := db.encryptRow(db.applyInsertConvention(data, now))
+		if err != nil {
+			return 0, err
+		}
+		conventionList[i] = data
+	}
+	dataList = conventionList
+	// 故障注入（仅测试环境启用）：只写入前keep条，写入完成后返回部分失败
+	keep, chaosErr := chaos.Bulk("mysql", db.dbKey, len(dataList))
+	if keep == 0 && chaosErr != nil {
+		return 0, chaosErr
+	}
+	dataList = dataList[:keep]
 	// 提取第一条数据的字段作为批量插入的统一字段（确保字段一致）
 	firstData := dataList[0]
 	if len(firstData) == 0 {
@@ -564,11 +590,7 @@ func (db *MysqlDb) InsertAll(ctx context.Context, dataList []map[string]interfac
 	// 核心修正：提前声明result和err，解决作用域问题
 	var result sql.Result
 	var err error
-	if db.Tx != nil {
-		result, err = db.Tx.ExecContext(ctx, sqlStr, allValues...)
-	} else {
-		result, err = db.Db.ExecContext(ctx, sqlStr, allValues...)
-	}
+	result, err = db.execContext(ctx, sqlStr, allValues...)
 	if err != nil {
 		return 0, fmt.Errorf("执行批量SQL失败，SQL：%s，values:%s,错误：%w", sqlStr, function.Json_encode(allValues), err)
 	}
@@ -577,6 +599,9 @@ func (db *MysqlDb) InsertAll(ctx context.Context, dataList []map[string]interfac
 	if err != nil {
 		return 0, fmt.Errorf("获取受影响行数失败：%w", err)
 	}
+	if chaosErr != nil {
+		return rowsAffected, chaosErr
+	}
 	return rowsAffected, nil
 }
 func (db *MysqlDb) Update(ctx context.Context, data map[string]interface{}) (int64, error) {
@@ -595,6 +620,13 @@ func (db *MysqlDb) Update(ctx context.Context, data map[string]interface{}) (int
 			return 0, errors.New("表名包含非法字符，存在注入风险")
 		}
 	}
+	// 写入约定：自动填充更新时间（未开启时不做处理）
+	data = db.applyUpdateConvention(data, time.Now())
+	// 敏感字段加密（未登记时不做处理）
+	data, err := db.encryptRow(data)
+	if err != nil {
+		return 0, err
+	}
 	// 2. 构建SET子句：参数化赋值（如 `name`=?, `age`=?）
 	var (
 		setClauses []string      // SET子句的片段
@@ -620,12 +652,7 @@ func (db *MysqlDb) Update(ctx context.Context, data map[string]interface{}) (int
 	}
 	// 5. 执行SQL并处理错误
 	var result sql.Result
-	var err error
-	if db.Tx != nil {
-		result, err = db.Tx.ExecContext(ctx, sqlStr, values...)
-	} else {
-		result, err = db.Db.ExecContext(ctx, sqlStr, values...)
-	}
+	result, err = db.execContext(ctx, sqlStr, values...)
 	if err != nil {
 		// 包装错误，保留原始错误链和SQL信息（便于调试）
 		return 0, fmt.Errorf("执行更新SQL失败，SQL：%s，values:%s,错误：%w", sqlStr, function.Json_encode(values), err)
@@ -666,11 +693,7 @@ func (db *MysqlDb) UpdateBySet(ctx context.Context, setTpl string, values ...int
 	// 5. 执行SQL并处理错误
 	var result sql.Result
 	var err error
-	if db.Tx != nil {
-		result, err = db.Tx.ExecContext(ctx, sqlStr, values...)
-	} else {
-		result, err = db.Db.ExecContext(ctx, sqlStr, values...)
-	}
+	result, err = db.execContext(ctx, sqlStr, values...)
 	if err != nil {
 		// 包装错误，保留原始错误链和SQL信息（便于调试）
 		return 0, fmt.Errorf("执行更新SQL失败，SQL：%s，values:%s,错误：%w", sqlStr, function.Json_encode(values), err)
@@ -724,11 +747,7 @@ func (db *MysqlDb) SetInc(ctx context.Context, tpl string, step ...int) (int64,
 	// 5. 执行SQL并处理错误
 	var result sql.Result
 	var err error
-	if db.Tx != nil {
-		result, err = db.Tx.ExecContext(ctx, sqlStr, values...)
-	} else {
-		result, err = db.Db.ExecContext(ctx, sqlStr, values...)
-	}
+	result, err = db.execContext(ctx, sqlStr, values...)
 	if err != nil {
 		// 包装错误，保留原始错误链和SQL信息（便于调试）
 		return 0, fmt.Errorf("执行更新SQL失败，SQL：%s，values:%s,错误：%w", sqlStr, function.Json_encode(values), err)
@@ -770,11 +789,7 @@ func (db *MysqlDb) Delete(ctx context.Context) (int64, error) {
 	}
 	var result sql.Result
 	var err error
-	if db.Tx != nil {
-		result, err = db.Tx.ExecContext(ctx, sqlStr, db.WhereArgs...)
-	} else {
-		result, err = db.Db.ExecContext(ctx, sqlStr, db.WhereArgs...)
-	}
+	result, err = db.execContext(ctx, sqlStr, db.WhereArgs...)
 	if err != nil {
 		// 包装错误，保留原始错误链和SQL信息（便于调试）
 		return 0, fmt.Errorf("执行更新SQL失败，SQL：%s，values:%s,错误：%w", sqlStr, function.Json_encode(db.WhereArgs), err)
@@ -799,11 +814,7 @@ func (db *MysqlDb) Exec(ctx context.Context, sqlStr string, values ...interface{
 	// 5. 执行SQL并处理错误
 	var result sql.Result
 	var err error
-	if db.Tx != nil {
-		result, err = db.Tx.ExecContext(ctx, sqlStr, values...)
-	} else {
-		result, err = db.Db.ExecContext(ctx, sqlStr, values...)
-	}
+	result, err = db.execContext(ctx, sqlStr, values...)
 	if err != nil {
 		// 包装错误，保留原始错误链和SQL信息（便于调试）
 		return 0, fmt.Errorf("执行Exec的SQL失败，SQL：%s,values:%s,，错误：%w", sqlStr, function.Json_encode(values), err)
@@ -933,6 +944,7 @@ func (db *MysqlDb) clearData(isClearTx bool) {
 	db.RelationList = nil
 	db.Limit = ""
 	db.Err = nil
+	db.page = nil
 	if isClearTx {
 		db.Tx = nil
 	}
@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/dfpopp/go-dai/db/chaos"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+// queryContext 执行查询（自动选择事务/连接池，启用链路追踪时记录span，驱动错误按wrapError归类）
+func (db *MysqlDb) queryContext(ctx context.Context, sqlStr string, args ...interface{}) (rows *sql.Rows, err error) {
+	if tracing.Enabled() {
+		var span trace.Span
+		ctx, span = tracing.StartDbSpan(ctx, "mysql", sqlOperation(sqlStr), sqlStr)
+		defer func() {
+			tracing.End(span, err)
+		}()
+	}
+	if err = chaos.Inject(ctx, "mysql", db.dbKey); err != nil {
+		return nil, err
+	}
+	if db.Tx != nil {
+		rows, err = db.Tx.QueryContext(ctx, sqlStr, args...)
+	} else {
+		rows, err = db.Db.QueryContext(ctx, sqlStr, args...)
+	}
+	return rows, wrapError(err)
+}
+
+// execContext 执行写操作（自动选择事务/连接池，启用链路追踪时记录span，驱动错误按wrapError归类）
+func (db *MysqlDb) execContext(ctx context.Context, sqlStr string, args ...interface{}) (result sql.Result, err error) {
+	if tracing.Enabled() {
+		var span trace.Span
+		ctx, span = tracing.StartDbSpan(ctx, "mysql", sqlOperation(sqlStr), sqlStr)
+		defer func() {
+			tracing.End(span, err)
+		}()
+	}
+	if err = chaos.Inject(ctx, "mysql", db.dbKey); err != nil {
+		return nil, err
+	}
+	if db.Tx != nil {
+		result, err = db.Tx.ExecContext(ctx, sqlStr, args...)
+	} else {
+		result, err = db.Db.ExecContext(ctx, sqlStr, args...)
+	}
+	return result, wrapError(err)
+}
+
+// sqlOperation 取SQL首个关键字作为操作名（SELECT/INSERT/UPDATE...）
+func sqlOperation(sqlStr string) string {
+	return strings.ToUpper(strings.SplitN(strings.TrimSpace(sqlStr), " ", 2)[0])
+}
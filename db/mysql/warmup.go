@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Warmup 预热连接池：同时占用conns个连接（<=0时取各连接池的最大空闲连接数）并逐个Ping，
+// 全部归还后即留在空闲池中，避免发布后首批请求承担建连耗时。返回各连接池实际预热的连接数。
+func Warmup(ctx context.Context, conns int) (map[string]int, error) {
+	result := make(map[string]int)
+	var errs []error
+	multiDBPool.Range(func(key, value interface{}) bool {
+		dbObj := value.(DbObj)
+		n := conns
+		if n <= 0 {
+			n = dbObj.idle
+		}
+		warmed, err := warmupPool(ctx, dbObj.Db, n)
+		result[key.(string)] = warmed
+		if err != nil {
+			errs = append(errs, fmt.Errorf("MySQL[%v]预热失败：%w", key, err))
+		}
+		return true
+	})
+	return result, errors.Join(errs...)
+}
+
+func warmupPool(ctx context.Context, db *sql.DB, n int) (int, error) {
+	if n <= 0 {
+		n = 1
+	}
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		held  = make([]*sql.Conn, 0, n)
+		first error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err == nil {
+				if err = conn.PingContext(ctx); err != nil {
+					_ = conn.Close()
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if first == nil {
+					first = err
+				}
+				return
+			}
+			held = append(held, conn)
+		}()
+	}
+	wg.Wait()
+	// 同时持有后再统一归还，确保建立的是n个不同的物理连接
+	for _, conn := range held {
+		_ = conn.Close()
+	}
+	return len(held), first
+}
+
+// Pingers 各连接池的可用性检查函数（供健康检查使用），key为连接key
+func Pingers() map[string]func(context.Context) error {
+	result := make(map[string]func(context.Context) error)
+	multiDBPool.Range(func(key, value interface{}) bool {
+		db := value.(DbObj).Db
+		result[key.(string)] = db.PingContext
+		return true
+	})
+	return result
+}
@@ -0,0 +1,136 @@
+package redisDb
+
+import (
+	"context"
+	"errors"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+// 通用存储适配接口：供第三方中间件（会话、限流、OAuth state等）复用框架的Redis连接池与键前缀，
+// 无需各自建立Redis连接。
+
+// ErrNotFound 键不存在
+var ErrNotFound = errs.NotFound.WithMessage("redis key not found")
+
+// Store 键值存储接口（会话存储等）
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)                        // 键不存在时返回ErrNotFound
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error // ttl<=0表示不过期
+	Delete(ctx context.Context, keys ...string) error                           // 删除键（不存在时忽略）
+	TTL(ctx context.Context, key string) (time.Duration, error)                 // 剩余有效期（不过期返回-1，键不存在返回ErrNotFound）
+	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)    // 刷新有效期，键不存在时返回false
+}
+
+// Cache 缓存接口（在Store基础上增加原子操作，供限流器、分布式锁等使用）
+type Cache interface {
+	Store
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)  // 键不存在时写入
+	IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) // 原子增加，首次创建时设置有效期
+}
+
+// KVStore 基于RedisDb的Store/Cache实现（键自动拼接表前缀与命名空间）
+type KVStore struct {
+	rdb       *RedisDb
+	namespace string
+}
+
+var _ Cache = (*KVStore)(nil)
+
+// NewStore 创建适配器，namespace用于隔离不同用途的键（如 "session:"、"ratelimit:"）
+func (r *RedisDb) NewStore(namespace string) *KVStore {
+	return &KVStore{rdb: r, namespace: namespace}
+}
+
+// GetStore 按连接标识获取适配器
+func GetStore(dbKey string, namespace string) (*KVStore, error) {
+	rdb, err := GetRedisDB(dbKey)
+	if err != nil {
+		return nil, err
+	}
+	return rdb.NewStore(namespace), nil
+}
+
+// Key 返回实际写入Redis的完整键名
+func (s *KVStore) Key(key string) string {
+	return s.rdb.DbPre + s.namespace + key
+}
+
+func (s *KVStore) client(ctx context.Context) *redis.Client {
+	if ctx == nil {
+		return s.rdb.Db
+	}
+	return s.rdb.WithContext(ctx).Db
+}
+
+func (s *KVStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.client(ctx).Get(s.Key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *KVStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.client(ctx).Set(s.Key(key), value, ttl).Err()
+}
+
+func (s *KVStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = s.Key(key)
+	}
+	return s.client(ctx).Del(fullKeys...).Err()
+}
+
+func (s *KVStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client(ctx).TTL(s.Key(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Redis返回-2表示键不存在，-1表示未设置过期时间（go-redis v6按秒换算为Duration）
+	switch ttl {
+	case -2 * time.Second, -2:
+		return 0, ErrNotFound
+	case -1 * time.Second, -1:
+		return -1, nil
+	}
+	return ttl, nil
+}
+
+func (s *KVStore) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.client(ctx).Expire(s.Key(key), ttl).Result()
+}
+
+func (s *KVStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.client(ctx).SetNX(s.Key(key), value, ttl).Result()
+}
+
+// IncrBy 原子增加计数（首次创建时设置有效期，已存在的键保留原有效期，适合固定窗口限流）
+func (s *KVStore) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	fullKey := s.Key(key)
+	client := s.client(ctx)
+	pipe := client.TxPipeline()
+	incr := pipe.IncrBy(fullKey, delta)
+	pttl := pipe.PTTL(fullKey)
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+	// 键无过期时间（新建或此前未设置）时补充有效期
+	if ttl > 0 && pttl.Val() < 0 {
+		if err := client.Expire(fullKey, ttl).Err(); err != nil {
+			return incr.Val(), err
+		}
+	}
+	return incr.Val(), nil
+}
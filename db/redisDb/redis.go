@@ -1,9 +1,14 @@
 package redisDb
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/chaos"
+	"github.com/dfpopp/go-dai/tracing"
 	"github.com/go-redis/redis"
+	"net"
 	"os"
 	"os/signal"
 	"runtime"
@@ -82,6 +87,16 @@ func InitRedis() {
 			MinRetryBackoff: time.Duration(cfg.MinRetryBackoff) * time.Millisecond, // 最小重试间隔
 			MaxRetryBackoff: time.Duration(cfg.MaxRetryBackoff) * time.Second,      // 最大重试间隔
 		}
+		// 自定义拨号：连接经故障注入层包装（仅测试环境启用db_chaos时生效）
+		dialer := &net.Dialer{Timeout: redisOpts.DialTimeout, KeepAlive: 5 * time.Minute}
+		addr, key := redisOpts.Addr, dbKey
+		redisOpts.Dialer = func() (net.Conn, error) {
+			conn, err := dialer.Dial("tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			return chaos.Conn("redis", key, conn), nil
+		}
 		// 创建客户端
 		db := redis.NewClient(redisOpts)
 		// 关键：测试连接有效性（捕获认证失败、网络不通等错误）
@@ -147,3 +162,30 @@ func CloseRedis() error {
 	})
 	return err
 }
+
+// WithContext 返回绑定请求context的Redis实例（启用链路追踪时每条命令记录span，语句摘要仅含命令名与首个key）
+func (r *RedisDb) WithContext(ctx context.Context) *RedisDb {
+	client := r.Db.WithContext(ctx)
+	if tracing.Enabled() {
+		client.WrapProcess(func(oldProcess func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+			return func(cmd redis.Cmder) error {
+				statement := cmd.Name()
+				if args := cmd.Args(); len(args) > 1 {
+					statement += " " + fmt.Sprint(args[1])
+				}
+				_, span := tracing.StartDbSpan(ctx, "redis", cmd.Name(), statement)
+				err := oldProcess(cmd)
+				if errors.Is(err, redis.Nil) {
+					tracing.End(span, nil)
+				} else {
+					tracing.End(span, err)
+				}
+				return err
+			}
+		})
+	}
+	return &RedisDb{
+		Db:    client,
+		DbPre: r.DbPre,
+	}
+}
@@ -0,0 +1,170 @@
+package redisDb
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 原子读取/令牌轮换：GETDEL/GETEX优先使用原生命令（Redis 6.2+），服务端不支持时自动降级为Lua脚本；
+// 令牌轮换与比较删除始终通过Lua脚本保证原子性，用于refresh token、一次性验证码等场景。
+// 以下方法的key均自动拼接表前缀（DbPre）。
+
+// ErrTokenMismatch 当前令牌与期望值不一致（已被轮换、已使用或不存在）
+var ErrTokenMismatch = errors.New("redis token mismatch")
+
+var (
+	getDelScript = redis.NewScript(`local v = redis.call('GET', KEYS[1])
+if v then redis.call('DEL', KEYS[1]) end
+return v`)
+	getExScript = redis.NewScript(`local v = redis.call('GET', KEYS[1])
+if v then
+  local ttl = tonumber(ARGV[1])
+  if ttl > 0 then redis.call('PEXPIRE', KEYS[1], ttl) elseif ttl < 0 then redis.call('PERSIST', KEYS[1]) end
+end
+return v`)
+	rotateScript = redis.NewScript(`local old = redis.call('GET', KEYS[1])
+if ARGV[3] == '1' and old ~= ARGV[2] then return {0, old or false} end
+if tonumber(ARGV[4]) > 0 then redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[4]) else redis.call('SET', KEYS[1], ARGV[1]) end
+return {1, old or false}`)
+	compareDelScript = redis.NewScript(`if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`)
+)
+
+// unsupportedCommands 记录不支持原生命令的Redis实例（key：地址/库号/命令）
+var unsupportedCommands sync.Map
+
+// GetDel 读取并删除key（键不存在时返回ErrNotFound）
+func (r *RedisDb) GetDel(ctx context.Context, key string) (string, error) {
+	client := r.WithContext(ctx).Db
+	fullKey := r.DbPre + key
+	val, err := r.tryNative(client, "GETDEL", func() (interface{}, error) {
+		return client.Do("GETDEL", fullKey).Result()
+	})
+	if isUnknownCommand(err) {
+		val, err = getDelScript.Run(client, []string{fullKey}).Result()
+	}
+	return stringReply(val, err)
+}
+
+// GetEx 读取key并调整有效期：ttl>0设置新有效期，ttl<0移除有效期，ttl==0保持不变（键不存在时返回ErrNotFound）
+func (r *RedisDb) GetEx(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	client := r.WithContext(ctx).Db
+	fullKey := r.DbPre + key
+	if ttl == 0 {
+		return stringReply(client.Get(fullKey).Result())
+	}
+	val, err := r.tryNative(client, "GETEX", func() (interface{}, error) {
+		if ttl < 0 {
+			return client.Do("GETEX", fullKey, "PERSIST").Result()
+		}
+		return client.Do("GETEX", fullKey, "PX", ttl.Milliseconds()).Result()
+	})
+	if isUnknownCommand(err) {
+		val, err = getExScript.Run(client, []string{fullKey}, ttlArg(ttl)).Result()
+	}
+	return stringReply(val, err)
+}
+
+// RotateToken 原子地写入新令牌并返回旧值（旧值不存在时返回空字符串），ttl<=0表示不过期
+func (r *RedisDb) RotateToken(ctx context.Context, key, newVal string, ttl time.Duration) (string, error) {
+	old, _, err := r.rotate(ctx, key, newVal, "", false, ttl)
+	return old, err
+}
+
+// CompareAndRotate 仅当当前令牌等于expected时替换为newVal（refresh token防重放），不一致时返回ErrTokenMismatch
+func (r *RedisDb) CompareAndRotate(ctx context.Context, key, expected, newVal string, ttl time.Duration) error {
+	_, swapped, err := r.rotate(ctx, key, newVal, expected, true, ttl)
+	if err != nil {
+		return err
+	}
+	if !swapped {
+		return ErrTokenMismatch
+	}
+	return nil
+}
+
+// CompareAndDelete 仅当当前值等于expected时删除（一次性验证码校验），返回是否删除成功
+func (r *RedisDb) CompareAndDelete(ctx context.Context, key, expected string) (bool, error) {
+	n, err := compareDelScript.Run(r.WithContext(ctx).Db, []string{r.DbPre + key}, expected).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// rotate 执行令牌轮换脚本，返回旧值与是否已替换
+func (r *RedisDb) rotate(ctx context.Context, key, newVal, expected string, compare bool, ttl time.Duration) (string, bool, error) {
+	compareFlag := "0"
+	if compare {
+		compareFlag = "1"
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	res, err := rotateScript.Run(r.WithContext(ctx).Db, []string{r.DbPre + key}, newVal, expected, compareFlag, ttlArg(ttl)).Result()
+	if err != nil {
+		return "", false, err
+	}
+	items, ok := res.([]interface{})
+	if !ok || len(items) == 0 {
+		return "", false, errors.New("redis token rotate: unexpected reply")
+	}
+	swapped, _ := items[0].(int64)
+	old := ""
+	if len(items) > 1 {
+		old, _ = items[1].(string)
+	}
+	return old, swapped == 1, nil
+}
+
+// tryNative 执行原生命令（已确认不支持时直接返回unknown command错误，避免重复探测）
+func (r *RedisDb) tryNative(client *redis.Client, command string, fn func() (interface{}, error)) (interface{}, error) {
+	opts := client.Options()
+	cacheKey := opts.Addr + "/" + strconv.Itoa(opts.DB) + "/" + command
+	if _, unsupported := unsupportedCommands.Load(cacheKey); unsupported {
+		return nil, errUnknownCommand
+	}
+	val, err := fn()
+	if isUnknownCommand(err) {
+		unsupportedCommands.Store(cacheKey, true)
+	}
+	return val, err
+}
+
+var errUnknownCommand = errors.New("ERR unknown command")
+
+func isUnknownCommand(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "unknown command")
+}
+
+// ttlArg 转换为Lua脚本使用的毫秒参数（<0表示移除有效期）
+func ttlArg(ttl time.Duration) int64 {
+	if ttl < 0 {
+		return -1
+	}
+	return ttl.Milliseconds()
+}
+
+// stringReply 转换字符串回复（redis.Nil转换为ErrNotFound）
+func stringReply(val interface{}, err error) (string, error) {
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case nil:
+		return "", ErrNotFound
+	}
+	return "", errors.New("redis: unexpected reply type")
+}
@@ -0,0 +1,53 @@
+package redisDb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Warmup 预热连接池：连接池按MinIdleConns在后台建立空闲连接，这里Ping校验后等待连接数达到目标
+// （conns<=0或超过MinIdleConns时取MinIdleConns，连接池只维持这么多空闲连接）或ctx到期。返回各连接池当前的连接数。
+func Warmup(ctx context.Context, conns int) (map[string]int, error) {
+	result := make(map[string]int)
+	var errs []error
+	multiDBPool.Range(func(key, value interface{}) bool {
+		client := value.(DbObj).Db
+		n := client.Options().MinIdleConns
+		if conns > 0 && conns < n {
+			n = conns
+		}
+		if err := client.WithContext(ctx).Ping().Err(); err != nil {
+			errs = append(errs, fmt.Errorf("Redis[%v]预热失败：%w", key, err))
+			return true
+		}
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for int(client.PoolStats().TotalConns) < n {
+			select {
+			case <-ctx.Done():
+				errs = append(errs, fmt.Errorf("Redis[%v]预热未完成（%d/%d）：%w", key, client.PoolStats().TotalConns, n, ctx.Err()))
+				result[key.(string)] = int(client.PoolStats().TotalConns)
+				return true
+			case <-ticker.C:
+			}
+		}
+		result[key.(string)] = int(client.PoolStats().TotalConns)
+		return true
+	})
+	return result, errors.Join(errs...)
+}
+
+// Pingers 各连接池的可用性检查函数（供健康检查使用），key为连接key
+func Pingers() map[string]func(context.Context) error {
+	result := make(map[string]func(context.Context) error)
+	multiDBPool.Range(func(key, value interface{}) bool {
+		client := value.(DbObj).Db
+		result[key.(string)] = func(ctx context.Context) error {
+			return client.WithContext(ctx).Ping().Err()
+		}
+		return true
+	})
+	return result
+}
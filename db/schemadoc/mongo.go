@@ -0,0 +1,196 @@
+package schemadoc
+
+import (
+	"context"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/mongoDb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSampleSize Mongo每个集合默认抽样文档数
+const DefaultSampleSize = 100
+
+// maxFieldDepth 嵌套文档展开的最大层级
+const maxFieldDepth = 4
+
+// fieldStat 字段抽样统计
+type fieldStat struct {
+	count int
+	types map[string]int
+}
+
+// InspectMongo 抽样读取MongoDB连接下各集合的字段结构（嵌套字段以"."连接，数组元素类型记为array<T>），
+// sampleSize<=0时使用DefaultSampleSize，仅包含带表前缀的集合
+func InspectMongo(ctx context.Context, dbKey string, sampleSize int) (*Schema, error) {
+	mdb, err := mongoDb.GetMongoDB(dbKey)
+	if err != nil {
+		return nil, err
+	}
+	if sampleSize <= 0 {
+		sampleSize = DefaultSampleSize
+	}
+	names, err := mdb.Db.ListCollectionNames(ctx, bson.D{{Key: "type", Value: "collection"}})
+	if err != nil {
+		return nil, fmt.Errorf("读取MongoDB集合列表失败：%w", err)
+	}
+	sort.Strings(names)
+	schema := NewSchema(dbKey)
+	for _, name := range names {
+		if strings.HasPrefix(name, "system.") || (mdb.DbPre != "" && !strings.HasPrefix(name, mdb.DbPre)) {
+			continue
+		}
+		table, err := inspectCollection(ctx, mdb.Db.Collection(name), sampleSize)
+		if err != nil {
+			return nil, fmt.Errorf("抽样集合[%s]失败：%w", name, err)
+		}
+		table.DbKey = dbKey
+		schema.Tables = append(schema.Tables, table)
+	}
+	return schema, nil
+}
+
+// inspectCollection 抽样单个集合并汇总字段类型
+func inspectCollection(ctx context.Context, coll *mongo.Collection, sampleSize int) (Table, error) {
+	table := Table{Source: SourceMongo, Name: coll.Name()}
+	if count, err := coll.EstimatedDocumentCount(ctx); err == nil {
+		table.Rows = count
+	}
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{{{Key: "$sample", Value: bson.D{{Key: "size", Value: sampleSize}}}}})
+	if err != nil {
+		return table, err
+	}
+	defer cursor.Close(ctx)
+	stats := make(map[string]*fieldStat)
+	for cursor.Next(ctx) {
+		var doc bson.D
+		if err = cursor.Decode(&doc); err != nil {
+			return table, err
+		}
+		table.Sampled++
+		seen := make(map[string]bool)
+		collectFields(doc, "", 0, stats, seen)
+	}
+	if err = cursor.Err(); err != nil {
+		return table, err
+	}
+	paths := make([]string, 0, len(stats))
+	for path := range stats {
+		paths = append(paths, path)
+	}
+	// _id排首位，其余按路径排序（父字段紧邻其子字段）
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i] == "_id" || paths[j] == "_id" {
+			return paths[i] == "_id"
+		}
+		return paths[i] < paths[j]
+	})
+	for _, path := range paths {
+		stat := stats[path]
+		col := Column{
+			Name:     path,
+			Type:     joinTypes(stat.types),
+			Nullable: stat.count < table.Sampled || stat.types["null"] > 0,
+			Coverage: float64(stat.count) / float64(table.Sampled),
+		}
+		if path == "_id" {
+			col.Key = "PRI"
+		}
+		table.Columns = append(table.Columns, col)
+	}
+	return table, nil
+}
+
+// collectFields 递归统计文档字段（同一文档内每个路径只计数一次）
+func collectFields(doc bson.D, prefix string, depth int, stats map[string]*fieldStat, seen map[string]bool) {
+	for _, elem := range doc {
+		path := elem.Key
+		if prefix != "" {
+			path = prefix + "." + elem.Key
+		}
+		stat, ok := stats[path]
+		if !ok {
+			stat = &fieldStat{types: make(map[string]int)}
+			stats[path] = stat
+		}
+		if !seen[path] {
+			seen[path] = true
+			stat.count++
+		}
+		stat.types[bsonTypeName(elem.Value)]++
+		if sub, ok := elem.Value.(bson.D); ok && depth+1 < maxFieldDepth {
+			collectFields(sub, path, depth+1, stats, seen)
+		}
+	}
+}
+
+// bsonTypeName 返回值对应的BSON类型名
+func bsonTypeName(v interface{}) string {
+	switch val := v.(type) {
+	case nil, primitive.Null:
+		return "null"
+	case string:
+		return "string"
+	case int32:
+		return "int"
+	case int64:
+		return "long"
+	case float64:
+		return "double"
+	case bool:
+		return "bool"
+	case primitive.ObjectID:
+		return "objectId"
+	case primitive.DateTime, time.Time:
+		return "date"
+	case primitive.Decimal128:
+		return "decimal"
+	case primitive.Binary:
+		return "binData"
+	case primitive.Timestamp:
+		return "timestamp"
+	case primitive.Regex:
+		return "regex"
+	case bson.D, bson.M:
+		return "object"
+	case bson.A:
+		return "array<" + arrayElemType(val) + ">"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// arrayElemType 数组元素类型（多种类型以"|"分隔，空数组为unknown）
+func arrayElemType(arr bson.A) string {
+	types := make(map[string]int)
+	for _, item := range arr {
+		types[bsonTypeName(item)]++
+	}
+	if len(types) == 0 {
+		return "unknown"
+	}
+	return joinTypes(types)
+}
+
+// joinTypes 按出现次数降序拼接类型名（null不参与，仅全为null时输出null）
+func joinTypes(types map[string]int) string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		if name != "null" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "null"
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if types[names[i]] != types[names[j]] {
+			return types[names[i]] > types[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,96 @@
+package schemadoc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"strings"
+)
+
+// InspectMySQL 读取MySQL连接当前库的表结构与外键（基于information_schema），仅包含带表前缀的表
+func InspectMySQL(ctx context.Context, dbKey string) (*Schema, error) {
+	mdb, err := mysql.GetMysqlDB(dbKey)
+	if err != nil {
+		return nil, err
+	}
+	schema := NewSchema(dbKey)
+	tables, err := mysqlTables(ctx, mdb.Db, mdb.DbPre)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(tables))
+	for i := range tables {
+		tables[i].DbKey = dbKey
+		index[tables[i].Name] = i
+	}
+	rows, err := mdb.Db.QueryContext(ctx, `SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, ORDINAL_POSITION`)
+	if err != nil {
+		return nil, fmt.Errorf("读取MySQL字段信息失败：%w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			tableName, nullable string
+			col                 Column
+			def                 sql.NullString
+		)
+		if err = rows.Scan(&tableName, &col.Name, &col.Type, &nullable, &col.Key, &def, &col.Extra, &col.Comment); err != nil {
+			return nil, fmt.Errorf("读取MySQL字段信息失败：%w", err)
+		}
+		i, ok := index[tableName]
+		if !ok {
+			continue
+		}
+		col.Nullable = nullable == "YES"
+		col.Coverage = 1
+		if def.Valid {
+			col.Default = &def.String
+		}
+		tables[i].Columns = append(tables[i].Columns, col)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	schema.Tables = tables
+
+	fkRows, err := mdb.Db.QueryContext(ctx, `SELECT TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("读取MySQL外键信息失败：%w", err)
+	}
+	defer fkRows.Close()
+	for fkRows.Next() {
+		var rel Relation
+		if err = fkRows.Scan(&rel.FromTable, &rel.FromColumn, &rel.ToTable, &rel.ToColumn); err != nil {
+			return nil, fmt.Errorf("读取MySQL外键信息失败：%w", err)
+		}
+		if _, ok := index[rel.FromTable]; ok {
+			schema.Relations = append(schema.Relations, rel)
+		}
+	}
+	return schema, fkRows.Err()
+}
+
+// mysqlTables 读取表名、注释与估算行数（配置了表前缀时只保留带前缀的表）
+func mysqlTables(ctx context.Context, db *sql.DB, pre string) ([]Table, error) {
+	rows, err := db.QueryContext(ctx, `SELECT TABLE_NAME, TABLE_COMMENT, IFNULL(TABLE_ROWS, 0)
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME`)
+	if err != nil {
+		return nil, fmt.Errorf("读取MySQL表信息失败：%w", err)
+	}
+	defer rows.Close()
+	tables := make([]Table, 0)
+	for rows.Next() {
+		table := Table{Source: SourceMySQL}
+		if err = rows.Scan(&table.Name, &table.Comment, &table.Rows); err != nil {
+			return nil, fmt.Errorf("读取MySQL表信息失败：%w", err)
+		}
+		if pre != "" && !strings.HasPrefix(table.Name, pre) {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,156 @@
+package schemadoc
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// 输出格式
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+)
+
+// Render 按格式输出数据字典（md/html）
+func Render(w io.Writer, schema *Schema, format string) error {
+	switch format {
+	case "", FormatMarkdown, "markdown":
+		return RenderMarkdown(w, schema)
+	case FormatHTML:
+		return RenderHTML(w, schema)
+	}
+	return fmt.Errorf("不支持的输出格式：%s", format)
+}
+
+// RenderMarkdown 输出Markdown格式数据字典
+func RenderMarkdown(w io.Writer, schema *Schema) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", mdEscape(schema.Title))
+	fmt.Fprintf(&b, "> 生成时间：%s\n\n", schema.GeneratedAt.Format("2006-01-02 15:04:05"))
+	b.WriteString("## 目录\n\n")
+	for _, table := range schema.Tables {
+		fmt.Fprintf(&b, "- [%s](#%s)（%s/%s）%s\n", table.Name, anchor(table), table.Source, table.DbKey, mdEscape(table.Comment))
+	}
+	if len(schema.Relations) > 0 {
+		b.WriteString("\n## 关系\n\n| 字段 | 引用 | 来源 |\n| --- | --- | --- |\n")
+		for _, rel := range schema.Relations {
+			fmt.Fprintf(&b, "| %s.%s | %s.%s | %s |\n", rel.FromTable, rel.FromColumn, rel.ToTable, rel.ToColumn, relationKind(rel))
+		}
+	}
+	for _, table := range schema.Tables {
+		fmt.Fprintf(&b, "\n<a id=\"%s\"></a>\n\n## %s\n\n", anchor(table), table.Name)
+		if table.Comment != "" {
+			fmt.Fprintf(&b, "%s\n\n", mdEscape(table.Comment))
+		}
+		fmt.Fprintf(&b, "- 数据源：%s（%s）\n- 行数（估算）：%d\n", table.Source, table.DbKey, table.Rows)
+		if table.Source == SourceMongo {
+			fmt.Fprintf(&b, "- 抽样文档数：%d\n", table.Sampled)
+			b.WriteString("\n| 字段 | 类型 | 可空 | 出现率 | 键 | 引用 |\n| --- | --- | --- | --- | --- | --- |\n")
+			for _, col := range table.Columns {
+				fmt.Fprintf(&b, "| %s | %s | %s | %.0f%% | %s | %s |\n", col.Name, mdEscape(col.Type), yesNo(col.Nullable),
+					col.Coverage*100, col.Key, schema.Reference(table.Name, col.Name))
+			}
+			continue
+		}
+		b.WriteString("\n| 字段 | 类型 | 可空 | 默认值 | 键 | 附加 | 注释 | 引用 |\n| --- | --- | --- | --- | --- | --- | --- | --- |\n")
+		for _, col := range table.Columns {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n", col.Name, mdEscape(col.Type), yesNo(col.Nullable),
+				mdEscape(defaultText(col.Default)), col.Key, col.Extra, mdEscape(col.Comment), schema.Reference(table.Name, col.Name))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var htmlTpl = template.Must(template.New("schema").Funcs(template.FuncMap{
+	"anchor":       anchor,
+	"yesNo":        yesNo,
+	"defaultText":  defaultText,
+	"relationKind": relationKind,
+	"percent":      func(v float64) string { return fmt.Sprintf("%.0f%%", v*100) },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body{font-family:-apple-system,"Segoe UI",sans-serif;margin:2em;color:#222}
+table{border-collapse:collapse;margin:0.5em 0 2em;font-size:14px}
+th,td{border:1px solid #ddd;padding:4px 8px;text-align:left}
+th{background:#f5f5f5}
+.meta{color:#666;font-size:13px}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">生成时间：{{.GeneratedAt.Format "2006-01-02 15:04:05"}}</p>
+<h2>目录</h2>
+<ul>{{range .Tables}}<li><a href="#{{anchor .}}">{{.Name}}</a>（{{.Source}}/{{.DbKey}}）{{.Comment}}</li>{{end}}</ul>
+{{if .Relations}}<h2>关系</h2>
+<table><tr><th>字段</th><th>引用</th><th>来源</th></tr>
+{{range .Relations}}<tr><td>{{.FromTable}}.{{.FromColumn}}</td><td>{{.ToTable}}.{{.ToColumn}}</td><td>{{relationKind .}}</td></tr>
+{{end}}</table>{{end}}
+{{$schema := .}}{{range $table := .Tables}}
+<h2 id="{{anchor $table}}">{{$table.Name}}</h2>
+{{if $table.Comment}}<p>{{$table.Comment}}</p>{{end}}
+<p class="meta">数据源：{{$table.Source}}（{{$table.DbKey}}） 行数（估算）：{{$table.Rows}}{{if eq $table.Source "mongodb"}} 抽样文档数：{{$table.Sampled}}{{end}}</p>
+{{if eq $table.Source "mongodb"}}<table><tr><th>字段</th><th>类型</th><th>可空</th><th>出现率</th><th>键</th><th>引用</th></tr>
+{{range $table.Columns}}<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{yesNo .Nullable}}</td><td>{{percent .Coverage}}</td><td>{{.Key}}</td><td>{{$schema.Reference $table.Name .Name}}</td></tr>
+{{end}}</table>{{else}}<table><tr><th>字段</th><th>类型</th><th>可空</th><th>默认值</th><th>键</th><th>附加</th><th>注释</th><th>引用</th></tr>
+{{range $table.Columns}}<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{yesNo .Nullable}}</td><td>{{defaultText .Default}}</td><td>{{.Key}}</td><td>{{.Extra}}</td><td>{{.Comment}}</td><td>{{$schema.Reference $table.Name .Name}}</td></tr>
+{{end}}</table>{{end}}{{end}}
+</body>
+</html>
+`))
+
+// RenderHTML 输出HTML格式数据字典
+func RenderHTML(w io.Writer, schema *Schema) error {
+	return htmlTpl.Execute(w, schema)
+}
+
+// Reference 返回字段引用的目标（table.column），无引用时返回空
+func (s *Schema) Reference(table, column string) string {
+	for _, rel := range s.Relations {
+		if rel.FromTable == table && rel.FromColumn == column {
+			return rel.ToTable + "." + rel.ToColumn
+		}
+	}
+	return ""
+}
+
+// anchor 生成表的锚点ID
+func anchor(table Table) string {
+	return strings.ToLower(table.Source + "-" + table.DbKey + "-" + table.Name)
+}
+
+func relationKind(rel Relation) string {
+	if rel.Inferred {
+		return "命名推断"
+	}
+	return "外键"
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "是"
+	}
+	return "否"
+}
+
+func defaultText(def *string) string {
+	if def == nil {
+		return ""
+	}
+	if *def == "" {
+		return "''"
+	}
+	return *def
+}
+
+// mdEscape 转义Markdown表格中的特殊字符
+func mdEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", " "), "\n", " ")
+}
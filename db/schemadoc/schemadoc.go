@@ -0,0 +1,233 @@
+package schemadoc
+
+import (
+	"context"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 数据字典生成：从已配置的MySQL/MongoDB连接中读取表结构（Mongo按抽样文档推断字段类型），
+// 并根据命名约定推断表间关系，输出Markdown/HTML文档，供团队保持数据模型文档与线上结构同步。
+
+// 数据源类型
+const (
+	SourceMySQL = "mysql"
+	SourceMongo = "mongodb"
+)
+
+// Column 字段信息
+type Column struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`     // MySQL为列类型，Mongo为抽样得到的BSON类型（多种类型以"|"分隔）
+	Nullable bool    `json:"nullable"` // MySQL为可空，Mongo为部分文档缺失该字段
+	Key      string  `json:"key"`      // MySQL索引类型（PRI/UNI/MUL）
+	Default  *string `json:"default"`  // 默认值
+	Extra    string  `json:"extra"`    // 附加信息（如auto_increment）
+	Comment  string  `json:"comment"`  // 字段注释
+	Coverage float64 `json:"coverage"` // Mongo字段出现比例（0~1），MySQL恒为1
+}
+
+// Table 表/集合信息
+type Table struct {
+	Source  string   `json:"source"`  // 数据源类型（mysql/mongodb）
+	DbKey   string   `json:"db_key"`  // 连接标识
+	Name    string   `json:"name"`    // 完整表名（含前缀）
+	Comment string   `json:"comment"` // 表注释
+	Rows    int64    `json:"rows"`    // 行数（MySQL为估算值，Mongo为估算文档数）
+	Sampled int      `json:"sampled"` // Mongo抽样文档数
+	Columns []Column `json:"columns"`
+}
+
+// Relation 表间关系（From.Column 引用 To.Column）
+type Relation struct {
+	FromTable  string `json:"from_table"`
+	FromColumn string `json:"from_column"`
+	ToTable    string `json:"to_table"`
+	ToColumn   string `json:"to_column"`
+	Inferred   bool   `json:"inferred"` // true为按命名约定推断，false为数据库声明的外键
+}
+
+// Schema 数据字典
+type Schema struct {
+	Title       string     `json:"title"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Tables      []Table    `json:"tables"`
+	Relations   []Relation `json:"relations"`
+}
+
+// NewSchema 创建空数据字典
+func NewSchema(title string) *Schema {
+	return &Schema{Title: title, GeneratedAt: time.Now()}
+}
+
+// Merge 合并其他数据源的结果
+func (s *Schema) Merge(other *Schema) {
+	if other == nil {
+		return
+	}
+	s.Tables = append(s.Tables, other.Tables...)
+	s.Relations = append(s.Relations, other.Relations...)
+}
+
+// table 按名称查找表（同名时优先同一数据源）
+func (s *Schema) table(name, source string) *Table {
+	var found *Table
+	for i := range s.Tables {
+		if s.Tables[i].Name != name {
+			continue
+		}
+		if s.Tables[i].Source == source {
+			return &s.Tables[i]
+		}
+		if found == nil {
+			found = &s.Tables[i]
+		}
+	}
+	return found
+}
+
+// hasColumn 判断表是否包含字段
+func (t *Table) hasColumn(name string) bool {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// InferRelations 按命名约定推断关系并追加到Relations（已声明外键的字段不重复推断）：
+//
+//	user_id / userId / user_uid -> user、users、前缀+user 等表的 id / _id 字段
+//
+// prefixes为各数据源的表前缀（如 "t_"），用于匹配带前缀的表名。
+func (s *Schema) InferRelations(prefixes ...string) {
+	declared := make(map[string]bool, len(s.Relations))
+	for _, rel := range s.Relations {
+		declared[rel.FromTable+"."+rel.FromColumn] = true
+	}
+	prefixes = append([]string{""}, prefixes...)
+	for _, table := range s.Tables {
+		for _, col := range table.Columns {
+			if declared[table.Name+"."+col.Name] {
+				continue
+			}
+			base := referenceBase(col.Name)
+			if base == "" {
+				continue
+			}
+			if target, toColumn := s.resolveTarget(base, table, prefixes); target != nil {
+				s.Relations = append(s.Relations, Relation{
+					FromTable:  table.Name,
+					FromColumn: col.Name,
+					ToTable:    target.Name,
+					ToColumn:   toColumn,
+					Inferred:   true,
+				})
+			}
+		}
+	}
+	sort.SliceStable(s.Relations, func(i, j int) bool {
+		if s.Relations[i].FromTable != s.Relations[j].FromTable {
+			return s.Relations[i].FromTable < s.Relations[j].FromTable
+		}
+		return s.Relations[i].FromColumn < s.Relations[j].FromColumn
+	})
+}
+
+// resolveTarget 按候选表名查找被引用的表（不引用自身）
+func (s *Schema) resolveTarget(base string, from Table, prefixes []string) (*Table, string) {
+	candidates := []string{base, base + "s", base + "es"}
+	if strings.HasSuffix(base, "y") {
+		candidates = append(candidates, strings.TrimSuffix(base, "y")+"ies")
+	}
+	for _, prefix := range prefixes {
+		for _, name := range candidates {
+			target := s.table(prefix+name, from.Source)
+			if target == nil || target.Name == from.Name {
+				continue
+			}
+			for _, pk := range []string{"id", "_id"} {
+				if target.hasColumn(pk) {
+					return target, pk
+				}
+			}
+		}
+	}
+	return nil, ""
+}
+
+// referenceBase 提取引用字段对应的实体名（user_id/userId/user_uid -> user），非引用字段返回空
+func referenceBase(column string) string {
+	lower := strings.ToLower(column)
+	for _, suffix := range []string{"_id", "_uid"} {
+		if strings.HasSuffix(lower, suffix) && len(lower) > len(suffix) {
+			return lower[:len(lower)-len(suffix)]
+		}
+	}
+	// 驼峰命名：userId / userID
+	if n := len(column); n > 2 && (strings.HasSuffix(column, "Id") || strings.HasSuffix(column, "ID")) {
+		if r := rune(column[n-3]); unicode.IsLower(r) || unicode.IsDigit(r) {
+			return camelToSnake(column[:n-2])
+		}
+	}
+	return ""
+}
+
+// camelToSnake 驼峰转下划线（orderItem -> order_item）
+func camelToSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Options 生成参数
+type Options struct {
+	Title      string   // 文档标题（默认"数据字典"）
+	MySQL      []string // 需要读取的MySQL连接标识
+	Mongo      []string // 需要读取的MongoDB连接标识
+	SampleSize int      // Mongo每个集合抽样文档数
+}
+
+// Generate 读取配置的各连接结构、推断关系并返回数据字典（连接池需已初始化）
+func Generate(ctx context.Context, opts Options) (*Schema, error) {
+	if opts.Title == "" {
+		opts.Title = "数据字典"
+	}
+	schema := NewSchema(opts.Title)
+	prefixes := make([]string, 0)
+	for _, dbKey := range opts.MySQL {
+		part, err := InspectMySQL(ctx, dbKey)
+		if err != nil {
+			return nil, fmt.Errorf("读取MySQL[%s]结构失败：%w", dbKey, err)
+		}
+		schema.Merge(part)
+		if cfg, ok := config.GetMysqlConfig()[dbKey]; ok && cfg.Pre != "" {
+			prefixes = append(prefixes, cfg.Pre)
+		}
+	}
+	for _, dbKey := range opts.Mongo {
+		part, err := InspectMongo(ctx, dbKey, opts.SampleSize)
+		if err != nil {
+			return nil, fmt.Errorf("读取MongoDB[%s]结构失败：%w", dbKey, err)
+		}
+		schema.Merge(part)
+		if cfg, ok := config.GetMongodbConfig()[dbKey]; ok && cfg.Pre != "" {
+			prefixes = append(prefixes, cfg.Pre)
+		}
+	}
+	schema.InferRelations(prefixes...)
+	return schema, nil
+}
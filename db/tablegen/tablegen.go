@@ -0,0 +1,206 @@
+package tablegen
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dfpopp/go-dai/db/schemadoc"
+	"go/format"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// 表结构代码生成：根据MySQL表结构生成表名常量与字段结构体（如 User.Table、User.Col.Name），
+// 传给SetTable/SetField/SetWhere的表名、字段名在编译期检查，表或字段改名后重新生成即可由编译器定位全部引用。
+
+// Options 生成参数
+type Options struct {
+	Package string   // 生成代码的包名（默认model）
+	Prefix  string   // 表前缀（生成的表名去除该前缀，与SetTable自动拼接前缀的行为一致）
+	Tables  []string // 只生成这些表（不含前缀，支持path.Match通配符，为空时生成全部）
+	Source  string   // 来源描述（写入文件头注释，如 mysql:default）
+}
+
+// commonInitialisms 转换Go标识符时保持全大写的缩写
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "GUID": true,
+	"HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "QPS": true, "RAM": true,
+	"RPC": true, "SKU": true, "SLA": true, "SMS": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true, "URL": true, "UTF8": true,
+	"VIP": true, "XML": true,
+}
+
+type genColumn struct {
+	Name    string // 字段名
+	Ident   string // Go字段名
+	Comment string
+}
+
+type genTable struct {
+	Name    string // 表名（不含前缀）
+	Ident   string // Go变量名
+	TypeID  string // 字段结构体类型名
+	Comment string
+	Columns []genColumn
+}
+
+// Generate 根据表结构生成Go源码（已gofmt）
+func Generate(schema *schemadoc.Schema, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "model"
+	}
+	tables := make([]genTable, 0, len(schema.Tables))
+	used := map[string]bool{}
+	for _, table := range schema.Tables {
+		name := strings.TrimPrefix(table.Name, opts.Prefix)
+		if !matchTables(name, opts.Tables) || len(table.Columns) == 0 {
+			continue
+		}
+		t := genTable{Name: name, Comment: oneLine(table.Comment)}
+		// 变量名与表名常量（Table+变量名）均不能与其他表重名
+		t.Ident = exportedIdent(name)
+		for i := 2; used[t.Ident] || used["Table"+t.Ident]; i++ {
+			t.Ident = fmt.Sprintf("%s%d", exportedIdent(name), i)
+		}
+		used[t.Ident], used["Table"+t.Ident] = true, true
+		t.TypeID = uniqueIdent(lowerFirst(t.Ident)+"Columns", used)
+		colUsed := map[string]bool{"As": true, "All": true}
+		for _, col := range table.Columns {
+			comment := col.Type
+			if c := oneLine(col.Comment); c != "" {
+				comment = c + " " + col.Type
+			}
+			t.Columns = append(t.Columns, genColumn{Name: col.Name, Ident: uniqueIdent(exportedIdent(col.Name), colUsed), Comment: comment})
+		}
+		tables = append(tables, t)
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("没有匹配的表")
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, map[string]interface{}{"Package": opts.Package, "Source": opts.Source, "Tables": tables}); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成代码失败：%w", err)
+	}
+	return src, nil
+}
+
+// matchTables 表名是否在生成范围内
+func matchTables(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// exportedIdent 将表名/字段名转换为导出的Go标识符（user_login_log → UserLoginLog，user_id → UserID）
+func exportedIdent(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	ident := b.String()
+	if ident == "" || !unicode.IsLetter([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// uniqueIdent 转换后重名时追加序号
+func uniqueIdent(ident string, used map[string]bool) string {
+	candidate := ident
+	for i := 2; used[candidate] || token.IsKeyword(candidate); i++ {
+		candidate = fmt.Sprintf("%s%d", ident, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// lowerFirst 首字母小写，开头的缩写整体小写（IPBlacklist → ipBlacklist）
+func lowerFirst(s string) string {
+	runes := []rune(s)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) {
+		n--
+	}
+	for i := 0; i < max(n, 1); i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// oneLine 注释压缩为单行
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var fileTemplate = template.Must(template.New("tablegen").Funcs(template.FuncMap{"quote": func(s string) string { return fmt.Sprintf("%q", s) }}).Parse(`// Code generated by go-dai tablegen. DO NOT EDIT.
+{{- if .Source}}
+// 来源：{{.Source}}
+{{- end}}
+
+package {{.Package}}
+
+// 表名（不含前缀，用于SetTable/SetJoin）
+const (
+{{- range .Tables}}
+	Table{{.Ident}} = {{quote .Name}}{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+)
+{{range .Tables}}
+// {{.Ident}} {{if .Comment}}{{.Comment}}（{{.Name}}）{{else}}{{.Name}}表{{end}}
+var {{.Ident}} = struct {
+	Table string // 表名（不含前缀）
+	Col   {{.TypeID}}
+}{
+	Table: Table{{.Ident}},
+	Col: {{.TypeID}}{
+{{- range .Columns}}
+		{{.Ident}}: {{quote .Name}},
+{{- end}}
+	},
+}
+
+// {{.TypeID}} {{.Name}}表字段
+type {{.TypeID}} struct {
+{{- range .Columns}}
+	{{.Ident}} string // {{.Comment}}
+{{- end}}
+}
+
+// As 返回带表别名限定的字段（如 {{.Ident}}.Col.As("t").{{(index .Columns 0).Ident}} 为 t.{{(index .Columns 0).Name}}），用于联表查询
+func (c {{.TypeID}}) As(alias string) {{.TypeID}} {
+	return {{.TypeID}}{
+{{- range .Columns}}
+		{{.Ident}}: alias + "." + c.{{.Ident}},
+{{- end}}
+	}
+}
+
+// All 全部字段（逗号分隔，用于SetField）
+func (c {{.TypeID}}) All() string {
+	return {{range $i, $c := .Columns}}{{if $i}} + "," + {{end}}c.{{$c.Ident}}{{end}}
+}
+{{end}}`))
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/dfpopp/go-dai/db/elasticSearch"
+	"github.com/dfpopp/go-dai/db/mongoDb"
+	"github.com/dfpopp/go-dai/db/mysql"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/function"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// warming 预热进行中（零值表示就绪，未启用预热时Ready始终为true）
+var warming atomic.Bool
+
+// WarmupResult 预热结果
+type WarmupResult struct {
+	Conns   map[string]map[string]int // 数据库类型 -> 连接key -> 预热的连接数
+	Elapsed time.Duration
+}
+
+// Warmup 并发预热各类数据库连接池（conns<=0时各连接池使用自身的空闲连接目标），
+// 预热期间Ready返回false，全部成功后恢复就绪；失败时保持未就绪，由调用方决定中止启动或调用MarkReady放行
+func Warmup(ctx context.Context, dbTypeList []string, conns int) (WarmupResult, error) {
+	warming.Store(true)
+	start := time.Now()
+	warmers := map[string]func(context.Context, int) (map[string]int, error){
+		"mysql":   mysql.Warmup,
+		"mongodb": mongoDb.Warmup,
+		"redis":   redisDb.Warmup,
+		"es":      elasticSearch.Warmup,
+	}
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	result := WarmupResult{Conns: make(map[string]map[string]int)}
+	for dbType, warm := range warmers {
+		if !function.InArray(dbType, dbTypeList) {
+			continue
+		}
+		wg.Add(1)
+		go func(dbType string, warm func(context.Context, int) (map[string]int, error)) {
+			defer wg.Done()
+			stats, err := warm(ctx, conns)
+			mu.Lock()
+			defer mu.Unlock()
+			result.Conns[dbType] = stats
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}(dbType, warm)
+	}
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+	err := errors.Join(errs...)
+	if err == nil {
+		warming.Store(false)
+	}
+	return result, err
+}
+
+// Ready 数据库连接池是否就绪（供就绪探针使用）
+func Ready() bool {
+	return !warming.Load()
+}
+
+// MarkReady 标记为就绪（预热失败但允许继续启动时调用）
+func MarkReady() {
+	warming.Store(false)
+}
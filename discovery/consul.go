@@ -0,0 +1,213 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsulRegistry Consul注册中心：通过本机Agent注册服务并附带TTL健康检查，续约即上报检查通过；
+// 检查失败超过1分钟后Consul自动注销实例。协议写入标签（protocol=grpc）与元数据，查询时只返回检查通过的实例
+type ConsulRegistry struct {
+	Address    string       // Consul地址（如 http://127.0.0.1:8500）
+	Token      string       // ACL Token（可选）
+	Datacenter string       // 数据中心（可选）
+	Client     *http.Client // 自定义HTTP客户端，为nil时使用默认客户端
+}
+
+// NewConsulRegistry 创建Consul注册中心
+func NewConsulRegistry(address, token string) *ConsulRegistry {
+	return &ConsulRegistry{Address: address, Token: token}
+}
+
+func (r *ConsulRegistry) Name() string {
+	return "consul"
+}
+
+// Register 注册服务与TTL健康检查，注册后立即上报一次检查通过
+func (r *ConsulRegistry) Register(ctx context.Context, ins *Instance, ttl time.Duration) error {
+	host, portStr, err := net.SplitHostPort(ins.Addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+	meta := map[string]string{"protocol": ins.Protocol}
+	for k, v := range ins.Metadata {
+		meta[k] = v
+	}
+	body := map[string]interface{}{
+		"ID":      ins.ID,
+		"Name":    ins.Service,
+		"Address": host,
+		"Port":    port,
+		"Tags":    append([]string{"protocol=" + ins.Protocol}, ins.Tags...),
+		"Meta":    meta,
+		"Check": map[string]string{
+			"CheckID":                        checkID(ins),
+			"TTL":                            ttl.String(),
+			"DeregisterCriticalServiceAfter": "1m",
+		},
+	}
+	if err := r.do(ctx, http.MethodPut, "/v1/agent/service/register", nil, body, nil); err != nil {
+		return err
+	}
+	return r.KeepAlive(ctx, ins)
+}
+
+// KeepAlive 上报TTL检查通过
+func (r *ConsulRegistry) KeepAlive(ctx context.Context, ins *Instance) error {
+	return r.do(ctx, http.MethodPut, "/v1/agent/check/pass/"+url.PathEscape(checkID(ins)), nil, nil, nil)
+}
+
+// Deregister 注销服务
+func (r *ConsulRegistry) Deregister(ctx context.Context, ins *Instance) error {
+	return r.do(ctx, http.MethodPut, "/v1/agent/service/deregister/"+url.PathEscape(ins.ID), nil, nil, nil)
+}
+
+// Resolve 查询检查通过的实例
+func (r *ConsulRegistry) Resolve(ctx context.Context, service, protocol string) ([]*Instance, error) {
+	list, _, err := r.health(ctx, service, protocol, "")
+	return list, err
+}
+
+// Watch 以阻塞查询等待X-Consul-Index变化，索引变化即视为实例变化
+func (r *ConsulRegistry) Watch(ctx context.Context, service string, onChange func()) error {
+	_, index, err := r.health(ctx, service, "", "")
+	if err != nil {
+		return err
+	}
+	for {
+		_, newIndex, getErr := r.health(ctx, service, "", index)
+		if getErr != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return getErr
+		}
+		if newIndex != index {
+			index = newIndex
+			onChange()
+		}
+	}
+}
+
+// health 查询服务健康实例，index非空时发起阻塞查询（最长等待5分钟）
+func (r *ConsulRegistry) health(ctx context.Context, service, protocol, index string) ([]*Instance, string, error) {
+	query := url.Values{}
+	query.Set("passing", "1")
+	if protocol != "" {
+		query.Set("tag", "protocol="+protocol)
+	}
+	if index != "" {
+		query.Set("index", index)
+		query.Set("wait", "5m")
+	}
+	var entries []struct {
+		Service struct {
+			ID      string
+			Service string
+			Address string
+			Port    int
+			Tags    []string
+			Meta    map[string]string
+		}
+		Node struct {
+			Address string
+		}
+	}
+	var header http.Header
+	if err := r.do(ctx, http.MethodGet, "/v1/health/service/"+url.PathEscape(service), query, nil, &entries, &header); err != nil {
+		return nil, "", err
+	}
+	list := make([]*Instance, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		ins := &Instance{
+			ID:       e.Service.ID,
+			Service:  e.Service.Service,
+			Protocol: e.Service.Meta["protocol"],
+			Addr:     net.JoinHostPort(host, strconv.Itoa(e.Service.Port)),
+			Metadata: map[string]string{},
+		}
+		for _, tag := range e.Service.Tags {
+			if !strings.HasPrefix(tag, "protocol=") {
+				ins.Tags = append(ins.Tags, tag)
+			}
+		}
+		for k, v := range e.Service.Meta {
+			if k != "protocol" {
+				ins.Metadata[k] = v
+			}
+		}
+		list = append(list, ins)
+	}
+	return list, header.Get("X-Consul-Index"), nil
+}
+
+// do 发起请求（body非nil时以JSON发送，out非nil时解析JSON响应，header非nil时返回响应头）
+func (r *ConsulRegistry) do(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}, header ...*http.Header) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	if r.Datacenter != "" {
+		query.Set("dc", r.Datacenter)
+	}
+	reqURL := strings.TrimRight(r.Address, "/") + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reader)
+	if err != nil {
+		return err
+	}
+	if r.Token != "" {
+		req.Header.Set("X-Consul-Token", r.Token)
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("consul请求失败（%s）：%d %s", path, res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if len(header) > 0 && header[0] != nil {
+		*header[0] = res.Header
+	}
+	if out != nil {
+		return json.NewDecoder(res.Body).Decode(out)
+	}
+	return nil
+}
+
+// checkID 实例的TTL检查标识
+func checkID(ins *Instance) string {
+	return "service:" + ins.ID
+}
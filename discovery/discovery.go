@@ -0,0 +1,308 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/logger"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 服务注册与发现：Boot启动服务后将HTTP/gRPC/WS/MQTT端点注册到etcd或Consul（按TTL定期续约，停机时注销），
+// gRPC客户端的目标地址配置为discovery:///服务名即可按注册中心的实例列表负载均衡，实例变化时自动更新。
+
+const (
+	defaultTTL    = 15 * time.Second
+	defaultPrefix = "/go-dai/services"
+	opTimeout     = 10 * time.Second // 单次注册/续约/注销的超时
+)
+
+// 协议类型
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+	ProtocolWS   = "ws"
+	ProtocolMQTT = "mqtt"
+)
+
+// ErrNotInitialized 服务发现未初始化（未配置discovery.enable）
+var ErrNotInitialized = errors.New("discovery: 服务发现未初始化")
+
+// Instance 服务实例
+type Instance struct {
+	ID       string            `json:"id"`       // 实例标识（服务名-协议-地址）
+	Service  string            `json:"service"`  // 服务名
+	Protocol string            `json:"protocol"` // http/grpc/ws/mqtt
+	Addr     string            `json:"addr"`     // host:port
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Registry 注册中心接口（内置etcd/Consul实现，也可自行实现后通过SetRegistry设置）
+type Registry interface {
+	// Name 注册中心名称（用于日志）
+	Name() string
+	// Register 注册实例（ttl内未续约时注册中心自动摘除）
+	Register(ctx context.Context, ins *Instance, ttl time.Duration) error
+	// KeepAlive 续约；返回错误时由调用方重新注册
+	KeepAlive(ctx context.Context, ins *Instance) error
+	// Deregister 注销实例
+	Deregister(ctx context.Context, ins *Instance) error
+	// Resolve 查询服务的健康实例（protocol为空时返回全部协议）
+	Resolve(ctx context.Context, service, protocol string) ([]*Instance, error)
+	// Watch 阻塞监听服务实例变化，变化时调用onChange，ctx取消时返回
+	Watch(ctx context.Context, service string, onChange func()) error
+}
+
+var (
+	mu         sync.RWMutex
+	registry   Registry
+	appCfg     config.DiscoveryConfig
+	registered = map[string]*registration{}
+)
+
+// registration 已注册的实例及其续约协程
+type registration struct {
+	ins    *Instance
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Init 按应用配置discovery创建注册中心并注册gRPC解析器（未启用时返回nil，需在grpc.InitClients之前调用）
+func Init(appName string) error {
+	appConfig := config.GetAppConfig(appName)
+	if appConfig == nil {
+		return fmt.Errorf("应用配置不存在：%s", appName)
+	}
+	cfg := appConfig.Discovery
+	if !cfg.Enable {
+		return nil
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = appName
+	}
+	if len(cfg.Endpoints) == 0 {
+		return errors.New("discovery: 未配置注册中心地址")
+	}
+	var reg Registry
+	switch strings.ToLower(cfg.Driver) {
+	case "etcd":
+		reg = NewEtcdRegistry(cfg.Endpoints, cfg.Username, cfg.Password, cfg.Prefix)
+	case "consul":
+		consul := NewConsulRegistry(cfg.Endpoints[0], cfg.Token)
+		consul.Datacenter = cfg.Datacenter
+		reg = consul
+	default:
+		return fmt.Errorf("discovery: 不支持的注册中心类型：%s", cfg.Driver)
+	}
+	mu.Lock()
+	appCfg = cfg
+	mu.Unlock()
+	SetRegistry(reg)
+	return nil
+}
+
+// SetRegistry 设置注册中心（自定义实现时使用），同时注册gRPC解析器（discovery:///服务名）
+func SetRegistry(reg Registry) {
+	mu.Lock()
+	registry = reg
+	mu.Unlock()
+	registerResolver()
+}
+
+// GetRegistry 获取当前注册中心（未初始化时返回nil）
+func GetRegistry() Registry {
+	mu.RLock()
+	defer mu.RUnlock()
+	return registry
+}
+
+// Enabled 当前实例是否需要注册（已初始化且未配置discovery.disable）
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return registry != nil && !appCfg.Disable
+}
+
+// NewInstance 按配置的服务名、标签与元数据构建实例（listenAddr为服务监听地址，如:8080）
+func NewInstance(protocol, listenAddr string) (*Instance, error) {
+	mu.RLock()
+	cfg := appCfg
+	mu.RUnlock()
+	addr, err := AdvertiseAddr(listenAddr, cfg.AdvertiseHost)
+	if err != nil {
+		return nil, err
+	}
+	ins := &Instance{
+		ID:       cfg.ServiceName + "-" + protocol + "-" + addr,
+		Service:  cfg.ServiceName,
+		Protocol: protocol,
+		Addr:     addr,
+		Tags:     cfg.Tags,
+		Metadata: map[string]string{},
+	}
+	for k, v := range cfg.Metadata {
+		ins.Metadata[k] = v
+	}
+	return ins, nil
+}
+
+// Register 注册实例并按TTL/3定期续约（续约失败时重新注册），直至Deregister/DeregisterAll
+func Register(ins *Instance) error {
+	mu.Lock()
+	reg, ttl := registry, ttlOf(appCfg)
+	if reg == nil {
+		mu.Unlock()
+		return ErrNotInitialized
+	}
+	if _, ok := registered[ins.ID]; ok {
+		mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &registration{ins: ins, cancel: cancel, done: make(chan struct{})}
+	registered[ins.ID] = r
+	mu.Unlock()
+
+	opCtx, opCancel := context.WithTimeout(ctx, opTimeout)
+	err := reg.Register(opCtx, ins, ttl)
+	opCancel()
+	if err != nil {
+		mu.Lock()
+		delete(registered, ins.ID)
+		mu.Unlock()
+		cancel()
+		return fmt.Errorf("discovery: 注册实例[%s]失败：%w", ins.ID, err)
+	}
+	logger.Info("服务实例已注册到", reg.Name(), "：", ins.ID)
+	go keepAlive(ctx, reg, r, ttl)
+	return nil
+}
+
+// keepAlive 续约循环（续约失败时重新注册，注册中心短暂不可用恢复后实例自动重新上线）
+func keepAlive(ctx context.Context, reg Registry, r *registration, ttl time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		opCtx, cancel := context.WithTimeout(ctx, opTimeout)
+		err := reg.KeepAlive(opCtx, r.ins)
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("服务实例续约失败，重新注册：", r.ins.ID, "错误：", err)
+			err = reg.Register(opCtx, r.ins, ttl)
+			if err != nil && ctx.Err() == nil {
+				logger.Warn("服务实例重新注册失败：", r.ins.ID, "错误：", err)
+			}
+		}
+		cancel()
+	}
+}
+
+// Deregister 停止续约并注销实例
+func Deregister(ctx context.Context, ins *Instance) error {
+	mu.Lock()
+	reg := registry
+	r, ok := registered[ins.ID]
+	delete(registered, ins.ID)
+	mu.Unlock()
+	if reg == nil {
+		return ErrNotInitialized
+	}
+	if ok {
+		r.cancel()
+		<-r.done
+	}
+	return reg.Deregister(ctx, ins)
+}
+
+// DeregisterAll 注销本进程注册的全部实例（停机时调用，先于停止服务，避免调用方继续路由到本实例）
+func DeregisterAll(ctx context.Context) error {
+	var errs []error
+	for _, ins := range Registered() {
+		if err := Deregister(ctx, ins); err != nil {
+			errs = append(errs, fmt.Errorf("注销实例[%s]失败：%w", ins.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StopKeepAlive 停止全部续约但不注销（平滑重启时新进程以相同实例标识重新注册，旧进程不应注销）
+func StopKeepAlive() {
+	mu.Lock()
+	all := registered
+	registered = map[string]*registration{}
+	mu.Unlock()
+	for _, r := range all {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// Registered 本进程已注册的实例
+func Registered() []*Instance {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]*Instance, 0, len(registered))
+	for _, r := range registered {
+		list = append(list, r.ins)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Resolve 查询服务的健康实例（protocol为空时返回全部协议）
+func Resolve(ctx context.Context, service, protocol string) ([]*Instance, error) {
+	reg := GetRegistry()
+	if reg == nil {
+		return nil, ErrNotInitialized
+	}
+	return reg.Resolve(ctx, service, protocol)
+}
+
+func ttlOf(cfg config.DiscoveryConfig) time.Duration {
+	if cfg.TTL <= 0 {
+		return defaultTTL
+	}
+	return time.Duration(cfg.TTL) * time.Second
+}
+
+// AdvertiseAddr 注册地址：host非空时替换监听地址的主机，监听地址未指定主机（:8080、0.0.0.0:8080）时自动探测本机IP
+func AdvertiseAddr(listenAddr, host string) (string, error) {
+	h, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return "", fmt.Errorf("discovery: 监听地址格式错误：%s", listenAddr)
+	}
+	if host == "" {
+		host = h
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		if host, err = localIP(); err != nil {
+			return "", err
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// localIP 本机第一个非回环IPv4地址
+func localIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", errors.New("discovery: 未找到本机IP，请配置advertise_host")
+}
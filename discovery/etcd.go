@@ -0,0 +1,148 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/internal/etcd"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EtcdRegistry etcd注册中心：实例以JSON写入{prefix}/{服务名}/{实例标识}并绑定租约，续约即租约KeepAlive，
+// 进程异常退出时租约到期自动摘除（通过etcd v3 HTTP/JSON网关访问，与config.EtcdSource共用网关客户端）
+type EtcdRegistry struct {
+	Prefix string
+
+	client *etcd.Client
+	mu     sync.Mutex
+	leases map[string]string // 实例标识 -> 租约ID
+}
+
+// NewEtcdRegistry 创建etcd注册中心（prefix为空时使用/go-dai/services）
+func NewEtcdRegistry(endpoints []string, username, password, prefix string) *EtcdRegistry {
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	return &EtcdRegistry{
+		Prefix: strings.TrimRight(prefix, "/"),
+		client: &etcd.Client{Endpoints: endpoints, Username: username, Password: password},
+		leases: map[string]string{},
+	}
+}
+
+func (r *EtcdRegistry) Name() string {
+	return "etcd"
+}
+
+// Register 申请租约并写入实例
+func (r *EtcdRegistry) Register(ctx context.Context, ins *Instance, ttl time.Duration) error {
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	if err := r.client.Call(ctx, "/v3/lease/grant", map[string]interface{}{"TTL": int64(ttl / time.Second)}, &grant); err != nil {
+		return err
+	}
+	if grant.ID == "" {
+		return errors.New("etcd租约申请失败")
+	}
+	value, err := json.Marshal(ins)
+	if err != nil {
+		return err
+	}
+	put := map[string]interface{}{
+		"key":   etcd.Encode(r.key(ins.Service, ins.ID)),
+		"value": etcd.Encode(string(value)),
+		"lease": grant.ID,
+	}
+	if err := r.client.Call(ctx, "/v3/kv/put", put, &struct{}{}); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	old := r.leases[ins.ID]
+	r.leases[ins.ID] = grant.ID
+	r.mu.Unlock()
+	if old != "" && old != grant.ID {
+		// 重新注册后撤销旧租约（键已绑定新租约，不受影响）
+		_ = r.client.Call(ctx, "/v3/lease/revoke", map[string]string{"ID": old}, &struct{}{})
+	}
+	return nil
+}
+
+// KeepAlive 续约租约（租约已过期时返回错误）
+func (r *EtcdRegistry) KeepAlive(ctx context.Context, ins *Instance) error {
+	r.mu.Lock()
+	lease := r.leases[ins.ID]
+	r.mu.Unlock()
+	if lease == "" {
+		return errors.New("实例未注册")
+	}
+	res, err := r.client.Do(ctx, "/v3/lease/keepalive", map[string]string{"ID": lease})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var msg struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&msg); err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return errors.New("etcd续约失败：" + msg.Error.Message)
+	}
+	if ttl, _ := strconv.ParseInt(msg.Result.TTL, 10, 64); ttl <= 0 {
+		return errors.New("etcd租约已过期")
+	}
+	return nil
+}
+
+// Deregister 撤销租约（绑定的键随之删除）
+func (r *EtcdRegistry) Deregister(ctx context.Context, ins *Instance) error {
+	r.mu.Lock()
+	lease := r.leases[ins.ID]
+	delete(r.leases, ins.ID)
+	r.mu.Unlock()
+	if lease == "" {
+		return nil
+	}
+	return r.client.Call(ctx, "/v3/lease/revoke", map[string]string{"ID": lease}, &struct{}{})
+}
+
+// Resolve 按前缀读取服务的全部实例
+func (r *EtcdRegistry) Resolve(ctx context.Context, service, protocol string) ([]*Instance, error) {
+	prefix := r.key(service, "")
+	kvs, err := r.client.Range(ctx, prefix, etcd.PrefixEnd(prefix))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Instance, 0, len(kvs))
+	for _, kv := range kvs {
+		var ins Instance
+		if err := json.Unmarshal(kv.Value, &ins); err != nil {
+			continue
+		}
+		if protocol == "" || ins.Protocol == protocol {
+			list = append(list, &ins)
+		}
+	}
+	return list, nil
+}
+
+// Watch 通过/v3/watch监听服务前缀的变化
+func (r *EtcdRegistry) Watch(ctx context.Context, service string, onChange func()) error {
+	prefix := r.key(service, "")
+	return r.client.Watch(ctx, prefix, etcd.PrefixEnd(prefix), onChange)
+}
+
+// key 实例键（id为空时为服务前缀，以/结尾）
+func (r *EtcdRegistry) key(service, id string) string {
+	return r.Prefix + "/" + service + "/" + id
+}
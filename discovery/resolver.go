@@ -0,0 +1,144 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"google.golang.org/grpc/resolver"
+	"sync"
+	"time"
+)
+
+// Scheme gRPC解析器协议：客户端目标地址配置为discovery:///服务名（grpc.clients.xxx.target）
+const Scheme = "discovery"
+
+const (
+	watchRetryDelay     = 5 * time.Second
+	roundRobinSvcConfig = `{"loadBalancingConfig":[{"round_robin":{}}]}`
+)
+
+var resolverOnce sync.Once
+
+// registerResolver 注册gRPC解析器（需早于创建使用discovery:///目标的客户端）
+func registerResolver() {
+	resolverOnce.Do(func() {
+		resolver.Register(&resolverBuilder{})
+	})
+}
+
+// resolverBuilder 按注册中心解析gRPC目标
+type resolverBuilder struct{}
+
+func (b *resolverBuilder) Scheme() string {
+	return Scheme
+}
+
+// Build 创建解析器：立即解析一次，随后监听实例变化（负载均衡策略为round_robin）
+func (b *resolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	reg := GetRegistry()
+	if reg == nil {
+		return nil, ErrNotInitialized
+	}
+	service := target.Endpoint()
+	if service == "" {
+		return nil, fmt.Errorf("discovery: 目标地址缺少服务名：%s", target.URL.String())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &grpcResolver{
+		registry: reg,
+		service:  service,
+		cc:       cc,
+		ctx:      ctx,
+		cancel:   cancel,
+		trigger:  make(chan struct{}, 1),
+	}
+	r.wg.Add(2)
+	go r.resolveLoop()
+	go r.watchLoop()
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	return r, nil
+}
+
+type grpcResolver struct {
+	registry Registry
+	service  string
+	cc       resolver.ClientConn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	trigger  chan struct{}
+	wg       sync.WaitGroup
+}
+
+// ResolveNow 触发重新解析（gRPC在连接失败时调用）
+func (r *grpcResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Close 停止解析与监听
+func (r *grpcResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// resolveLoop 串行处理解析请求
+func (r *grpcResolver) resolveLoop() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.trigger:
+		}
+		r.resolve()
+	}
+}
+
+// resolve 查询gRPC实例并更新连接的地址列表
+func (r *grpcResolver) resolve() {
+	ctx, cancel := context.WithTimeout(r.ctx, opTimeout)
+	defer cancel()
+	list, err := r.registry.Resolve(ctx, r.service, ProtocolGRPC)
+	if err != nil {
+		if r.ctx.Err() == nil {
+			r.cc.ReportError(fmt.Errorf("discovery: 解析服务[%s]失败：%w", r.service, err))
+		}
+		return
+	}
+	if len(list) == 0 {
+		r.cc.ReportError(fmt.Errorf("discovery: 服务[%s]没有可用实例", r.service))
+		return
+	}
+	addrs := make([]resolver.Address, 0, len(list))
+	for _, ins := range list {
+		addrs = append(addrs, resolver.Address{Addr: ins.Addr})
+	}
+	_ = r.cc.UpdateState(resolver.State{
+		Addresses:     addrs,
+		ServiceConfig: r.cc.ParseServiceConfig(roundRobinSvcConfig),
+	})
+}
+
+// watchLoop 监听实例变化（监听异常时按固定间隔重试，重试前重新解析一次以免遗漏变化）
+func (r *grpcResolver) watchLoop() {
+	defer r.wg.Done()
+	for r.ctx.Err() == nil {
+		err := r.registry.Watch(r.ctx, r.service, func() {
+			r.ResolveNow(resolver.ResolveNowOptions{})
+		})
+		if r.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warn("服务发现监听失败：", r.service, "错误：", err)
+		}
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(watchRetryDelay):
+		}
+		r.ResolveNow(resolver.ResolveNowOptions{})
+	}
+}
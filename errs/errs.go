@@ -0,0 +1,178 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+)
+
+// 框架统一错误：业务错误码 + 对外消息 + 底层原因 + HTTP/gRPC状态映射。
+// 应用在启动时通过Register登记业务错误码，处理器/服务层返回登记的错误（或其派生），
+// 由BaseController.Fail、gRPC处理器与拦截器统一转换为JSON响应、gRPC状态与日志：
+//
+//	var ErrBalanceNotEnough = errs.Register(10001, "余额不足", errs.WithHTTPStatus(http.StatusPaymentRequired))
+//
+//	if balance < amount {
+//		return ErrBalanceNotEnough.WithMessagef("余额不足，还差%d分", amount-balance)
+//	}
+//	if err := orderModel.Insert(ctx, order); err != nil {
+//		return errs.Internal.Wrap(err) // 客户端收到“服务器内部错误”，日志记录原因
+//	}
+
+// Domain gRPC状态详情（ErrorInfo）中的错误域，Reason为业务错误码
+const Domain = "go-dai"
+
+// Error 框架统一错误
+type Error struct {
+	Code       int        // 业务错误码（框架内置错误码与HTTP状态码一致）
+	Message    string     // 对外消息（响应给客户端）
+	HTTPStatus int        // HTTP状态码
+	GRPCCode   codes.Code // gRPC状态码
+	Cause      error      // 底层原因（仅记录日志，不响应给客户端）
+	parent     *Error     // 派生来源（errors.Is沿来源链匹配登记的错误）
+}
+
+// Error 错误描述（含底层原因，用于日志）
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+// Unwrap 底层原因
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is 派生错误与其来源匹配：errors.Is(ErrBalanceNotEnough.Wrap(err), ErrBalanceNotEnough)为true
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	for p := e; p != nil; p = p.parent {
+		if p == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap 派生携带底层原因的错误（错误码与消息不变）
+func (e *Error) Wrap(cause error) *Error {
+	d := e.derive()
+	d.Cause = cause
+	return d
+}
+
+// WithMessage 派生替换对外消息的错误
+func (e *Error) WithMessage(msg string) *Error {
+	d := e.derive()
+	d.Message = msg
+	return d
+}
+
+// WithMessagef 派生替换对外消息的错误（格式化）
+func (e *Error) WithMessagef(format string, args ...interface{}) *Error {
+	return e.WithMessage(fmt.Sprintf(format, args...))
+}
+
+func (e *Error) derive() *Error {
+	d := *e
+	d.parent = e
+	return &d
+}
+
+// Body 统一JSON响应体：{"code":错误码,"msg":对外消息,"data":null}
+func (e *Error) Body() map[string]interface{} {
+	return map[string]interface{}{
+		"code": e.Code,
+		"msg":  e.Message,
+		"data": nil,
+	}
+}
+
+// GRPCStatus gRPC状态（状态消息为对外消息，业务错误码写入ErrorInfo详情），
+// 处理器直接返回*Error时gRPC按此转换
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.GRPCCode, e.Message)
+	if withDetails, err := st.WithDetails(&errdetails.ErrorInfo{Reason: strconv.Itoa(e.Code), Domain: Domain}); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// IsServerError 是否为服务端错误（HTTP状态码>=500，日志按错误级别记录）
+func (e *Error) IsServerError() bool {
+	return e.HTTPStatus >= 500
+}
+
+// From 将任意错误转换为*Error（nil返回nil）：
+//   - 错误链中含*Error时沿用其错误码与消息（外层包装信息保留在原因中）
+//   - gRPC状态错误按ErrorInfo详情还原业务错误码，无详情时按状态码映射内置错误
+//   - context超时/取消映射为Timeout/Canceled，其余映射为Internal（对外隐藏原因）
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		if error(e) == err {
+			return e
+		}
+		d := e.derive()
+		d.Cause = err
+		return d
+	}
+	if st, ok := status.FromError(err); ok {
+		return fromStatus(st, err)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout.Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return Canceled.Wrap(err)
+	}
+	return Internal.Wrap(err)
+}
+
+// fromStatus gRPC状态错误（如调用下游服务返回的错误）转换为*Error
+func fromStatus(st *status.Status, err error) *Error {
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != Domain {
+			continue
+		}
+		if code, convErr := strconv.Atoi(info.Reason); convErr == nil {
+			e := New(code, st.Message())
+			e.GRPCCode = st.Code()
+			e.Cause = err
+			return e
+		}
+	}
+	base := Internal
+	for _, b := range builtins {
+		if b.GRPCCode == st.Code() {
+			base = b
+			break
+		}
+	}
+	d := base.Wrap(err)
+	if st.Code() != codes.Unknown && st.Message() != "" {
+		d.Message = st.Message()
+	}
+	return d
+}
+
+// Code 错误对应的业务错误码（nil返回0）
+func Code(err error) int {
+	if e := From(err); e != nil {
+		return e.Code
+	}
+	return 0
+}
@@ -0,0 +1,160 @@
+package errs
+
+import (
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// 框架内置错误（错误码与HTTP状态码一致，应用的业务错误码应避开100~599）
+var (
+	InvalidArgument = mustBuiltin(http.StatusBadRequest, "请求参数错误", codes.InvalidArgument)
+	Unauthorized    = mustBuiltin(http.StatusUnauthorized, "未认证或认证已失效", codes.Unauthenticated)
+	Forbidden       = mustBuiltin(http.StatusForbidden, "无权访问", codes.PermissionDenied)
+	NotFound        = mustBuiltin(http.StatusNotFound, "资源不存在", codes.NotFound)
+	Conflict        = mustBuiltin(http.StatusConflict, "数据冲突", codes.AlreadyExists)
+	TooManyRequests = mustBuiltin(http.StatusTooManyRequests, "请求过于频繁", codes.ResourceExhausted)
+	RequestTooLarge = mustBuiltin(http.StatusRequestEntityTooLarge, "请求内容过大", codes.ResourceExhausted)
+	Canceled        = mustBuiltin(499, "请求已取消", codes.Canceled)
+	Internal        = mustBuiltin(http.StatusInternalServerError, "服务器内部错误", codes.Internal)
+	NotImplemented  = mustBuiltin(http.StatusNotImplemented, "功能未实现", codes.Unimplemented)
+	Unavailable     = mustBuiltin(http.StatusServiceUnavailable, "服务暂不可用", codes.Unavailable)
+	Timeout         = mustBuiltin(http.StatusGatewayTimeout, "请求超时", codes.DeadlineExceeded)
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[int]*Error)
+	builtins   []*Error // 按登记顺序，gRPC状态码还原内置错误时使用
+)
+
+// Option 登记错误码的可选配置
+type Option func(o *options)
+
+type options struct {
+	httpStatus int
+	grpcCode   *codes.Code
+}
+
+// WithHTTPStatus 指定HTTP状态码（默认：错误码为HTTP状态码时取错误码，否则为400）
+func WithHTTPStatus(code int) Option {
+	return func(o *options) {
+		o.httpStatus = code
+	}
+}
+
+// WithGRPCCode 指定gRPC状态码（默认按HTTP状态码映射）
+func WithGRPCCode(code codes.Code) Option {
+	return func(o *options) {
+		o.grpcCode = &code
+	}
+}
+
+// Register 登记错误码并返回对应错误（在包级变量或init中调用，错误码重复登记时panic）
+func Register(code int, message string, opts ...Option) *Error {
+	o := options{httpStatus: defaultHTTPStatus(code)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	e := &Error{Code: code, Message: message, HTTPStatus: o.httpStatus, GRPCCode: GRPCCodeFromHTTP(o.httpStatus)}
+	if o.grpcCode != nil {
+		e.GRPCCode = *o.grpcCode
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if exist, ok := registry[code]; ok {
+		panic(fmt.Sprintf("errs: 错误码%d重复登记（已登记为“%s”）", code, exist.Message))
+	}
+	registry[code] = e
+	return e
+}
+
+func mustBuiltin(code int, message string, grpcCode codes.Code) *Error {
+	e := Register(code, message, WithHTTPStatus(code), WithGRPCCode(grpcCode))
+	builtins = append(builtins, e)
+	return e
+}
+
+// Lookup 查找已登记的错误码
+func Lookup(code int) (*Error, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	e, ok := registry[code]
+	return e, ok
+}
+
+// Registered 全部已登记的错误（按错误码升序，可用于生成错误码文档）
+func Registered() []*Error {
+	registryMu.RLock()
+	list := make([]*Error, 0, len(registry))
+	for _, e := range registry {
+		list = append(list, e)
+	}
+	registryMu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// New 按错误码构建错误：已登记时派生登记的错误（message为空时沿用登记的消息），
+// 未登记时按默认规则映射HTTP/gRPC状态码（未登记的业务错误码映射为HTTP 400、gRPC Unknown）
+func New(code int, message string) *Error {
+	if e, ok := Lookup(code); ok {
+		if message == "" {
+			return e.derive()
+		}
+		return e.WithMessage(message)
+	}
+	e := &Error{Code: code, Message: message, HTTPStatus: defaultHTTPStatus(code), GRPCCode: codes.Unknown}
+	if isHTTPStatus(code) {
+		e.GRPCCode = GRPCCodeFromHTTP(code)
+	}
+	return e
+}
+
+// GRPCCodeFromHTTP HTTP状态码映射为gRPC状态码
+func GRPCCodeFromHTTP(code int) codes.Code {
+	switch code {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusInternalServerError:
+		return codes.Internal
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
+
+func defaultHTTPStatus(code int) int {
+	if isHTTPStatus(code) {
+		return code
+	}
+	return http.StatusBadRequest
+}
+
+func isHTTPStatus(code int) bool {
+	return code >= 100 && code <= 599
+}
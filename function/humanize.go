@@ -0,0 +1,208 @@
+package function
+
+import (
+	"fmt"
+	"github.com/dfpopp/go-dai/i18n"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 时长/相对时间的人性化展示与工作日计算（管理后台、通知文案常用）。
+// 文案取自i18n（见i18n.MsgDuration*），locale为空时使用i18n默认语言，可注册其他语言的同名key扩展。
+
+const (
+	oneDay   = 24 * time.Hour
+	oneMonth = 30 * oneDay
+	oneYear  = 365 * oneDay
+)
+
+// durationUnits 时长单位（从大到小）
+var durationUnits = []struct {
+	size time.Duration
+	key  string
+}{
+	{oneDay, i18n.MsgDurationDay},
+	{time.Hour, i18n.MsgDurationHour},
+	{time.Minute, i18n.MsgDurationMinute},
+	{time.Second, i18n.MsgDurationSecond},
+}
+
+// HumanizeDuration 时长转为可读文案，最多保留两个相邻的最大单位（负数按绝对值处理）
+// 示例：HumanizeDuration(90*time.Minute) → "1小时30分钟"；HumanizeDuration(26*time.Hour, "en") → "1 day 2 hours"；
+// HumanizeDuration(1500*time.Millisecond) → "1秒"；HumanizeDuration(300*time.Millisecond) → "300毫秒"
+func HumanizeDuration(d time.Duration, locale ...string) string {
+	loc := pickLocale(locale)
+	if d < 0 {
+		d = -d
+	}
+	if d < time.Second {
+		return unitText(loc, i18n.MsgDurationMillisecond, d.Milliseconds())
+	}
+	parts := make([]string, 0, 2)
+	for i, unit := range durationUnits {
+		n := d / unit.size
+		if n == 0 {
+			continue
+		}
+		parts = append(parts, unitText(loc, unit.key, int64(n)))
+		// 第二个单位须与第一个相邻（如1天零5分钟只显示1天）
+		if i+1 < len(durationUnits) {
+			next := durationUnits[i+1]
+			if m := (d % unit.size) / next.size; m > 0 {
+				parts = append(parts, unitText(loc, next.key, int64(m)))
+			}
+		}
+		break
+	}
+	return strings.Join(parts, i18n.T(loc, i18n.MsgDurationSeparator))
+}
+
+// RelativeTime 相对当前时间的描述，如"3分钟前"/"3 minutes ago"、"2天后"/"in 2 days"（10秒以内为"刚刚"）
+func RelativeTime(t time.Time, locale ...string) string {
+	return RelativeTimeFrom(t, time.Now(), locale...)
+}
+
+// RelativeTimeFrom 相对指定时间now的描述（只取最大单位，30天以上按月、365天以上按年计）
+func RelativeTimeFrom(t, now time.Time, locale ...string) string {
+	loc := pickLocale(locale)
+	diff := now.Sub(t)
+	future := diff < 0
+	if future {
+		diff = -diff
+	}
+	if diff < 10*time.Second {
+		return i18n.T(loc, i18n.MsgTimeJustNow)
+	}
+	var text string
+	switch {
+	case diff >= oneYear:
+		text = unitText(loc, i18n.MsgDurationYear, int64(diff/oneYear))
+	case diff >= oneMonth:
+		text = unitText(loc, i18n.MsgDurationMonth, int64(diff/oneMonth))
+	case diff >= oneDay:
+		text = unitText(loc, i18n.MsgDurationDay, int64(diff/oneDay))
+	case diff >= time.Hour:
+		text = unitText(loc, i18n.MsgDurationHour, int64(diff/time.Hour))
+	case diff >= time.Minute:
+		text = unitText(loc, i18n.MsgDurationMinute, int64(diff/time.Minute))
+	default:
+		text = unitText(loc, i18n.MsgDurationSecond, int64(diff/time.Second))
+	}
+	if future {
+		return i18n.T(loc, i18n.MsgTimeLater, text)
+	}
+	return i18n.T(loc, i18n.MsgTimeAgo, text)
+}
+
+// unitText 单位文案（数量为1且注册了单数形式时使用"key.one"）
+func unitText(locale, key string, n int64) string {
+	if n == 1 && i18n.Has(locale, key+".one") {
+		key += ".one"
+	}
+	return i18n.T(locale, key, n)
+}
+
+func pickLocale(locale []string) string {
+	if len(locale) > 0 && locale[0] != "" {
+		return locale[0]
+	}
+	return i18n.DefaultLocale()
+}
+
+// -------------------------- 工作日计算 --------------------------
+
+const dateLayout = "2006-01-02"
+
+var (
+	calendarMu sync.RWMutex
+	holidays   = make(map[string]bool) // 法定节假日（周一至周五也不上班）
+	workdays   = make(map[string]bool) // 调休上班日（周末也上班）
+)
+
+// SetHolidays 登记法定节假日（日期格式2006-01-02，按各地每年公布的安排登记），与调休上班日冲突时以后登记的为准
+func SetHolidays(dates ...string) error {
+	return setCalendar(holidays, workdays, dates)
+}
+
+// SetWorkdays 登记调休上班日（落在周末但需上班的日期，格式2006-01-02）
+func SetWorkdays(dates ...string) error {
+	return setCalendar(workdays, holidays, dates)
+}
+
+// ClearWorkCalendar 清空登记的节假日与调休上班日
+func ClearWorkCalendar() {
+	calendarMu.Lock()
+	defer calendarMu.Unlock()
+	clear(holidays)
+	clear(workdays)
+}
+
+func setCalendar(target, other map[string]bool, dates []string) error {
+	for _, date := range dates {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return fmt.Errorf("日期格式错误（应为2006-01-02）：%s", date)
+		}
+	}
+	calendarMu.Lock()
+	defer calendarMu.Unlock()
+	for _, date := range dates {
+		target[date] = true
+		delete(other, date)
+	}
+	return nil
+}
+
+// IsWorkday 是否为工作日（周一至周五且非节假日，或登记的调休上班日；按t所在时区的日期判断）
+func IsWorkday(t time.Time) bool {
+	date := t.Format(dateLayout)
+	calendarMu.RLock()
+	defer calendarMu.RUnlock()
+	if workdays[date] {
+		return true
+	}
+	if holidays[date] {
+		return false
+	}
+	weekday := t.Weekday()
+	return weekday != time.Saturday && weekday != time.Sunday
+}
+
+// AddWorkdays 从t起跳过非工作日前进n个工作日（n为负数时后退），保留t的时分秒
+// 示例：周五 AddWorkdays(t, 1) → 下周一；AddWorkdays(t, 0) → t本身
+func AddWorkdays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if IsWorkday(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// NextWorkday t之后（不含当天）的第一个工作日
+func NextWorkday(t time.Time) time.Time {
+	return AddWorkdays(t, 1)
+}
+
+// WorkdaysBetween 统计[start, end)区间内的工作日天数（按日期计，end早于start时返回负数）
+func WorkdaysBetween(start, end time.Time) int {
+	sign := 1
+	if end.Before(start) {
+		start, end, sign = end, start, -1
+	}
+	from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	end = end.In(start.Location())
+	to := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, start.Location())
+	count := 0
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		if IsWorkday(d) {
+			count++
+		}
+	}
+	return sign * count
+}
@@ -0,0 +1,62 @@
+package function
+
+import "strings"
+
+// MatchOrigin 判断请求来源是否匹配允许列表（HTTP CORS与WS握手共用）
+// 支持的规则："*"（允许所有）、完整来源（如 https://a.com）、子域通配（如 https://*.a.com，不匹配a.com本身）
+// 比较时忽略大小写与末尾的"/"
+func MatchOrigin(origin string, patterns []string) bool {
+	origin = normalizeOrigin(origin)
+	if origin == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = normalizeOrigin(pattern)
+		switch {
+		case pattern == "":
+			continue
+		case pattern == "*":
+			return true
+		case pattern == origin:
+			return true
+		case strings.Contains(pattern, "*."):
+			prefix, suffix, _ := strings.Cut(pattern, "*")
+			// 通配部分至少包含一个字符，且不能跨越协议/端口边界
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				host := origin[len(prefix) : len(origin)-len(suffix)]
+				if !strings.ContainsAny(host, "/:") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// IsWildcardOrigins 允许列表是否放行任意来源（为空或包含"*"）
+func IsWildcardOrigins(patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitOrigins 将逗号分隔的来源配置拆分为列表
+func SplitOrigins(s string) []string {
+	var origins []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			origins = append(origins, item)
+		}
+	}
+	return origins
+}
+
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+}
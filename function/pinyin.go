@@ -0,0 +1,252 @@
+package function
+
+import (
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"strings"
+	"unicode"
+)
+
+// 拼音转换：GB2312一级汉字（3755个常用字）按拼音排序，根据GBK编码落在哪个音节区间即可得到无声调拼音；
+// 带声调拼音依赖内置常用字表（含多音字的常用读音），未收录的字回退为无声调拼音
+
+// pinyinCode GB2312一级汉字音节起始编码（编码值为 高字节*256+低字节-65536）
+type pinyinCode struct {
+	code int
+	py   string
+}
+
+// gb2312Level1End GB2312一级汉字最后一个字（座 0xD7F9）的编码
+const gb2312Level1End = -10247
+
+var pinyinCodeTable = []pinyinCode{
+	{-20319, "a"}, {-20317, "ai"}, {-20304, "an"}, {-20295, "ang"}, {-20292, "ao"}, {-20283, "ba"},
+	{-20265, "bai"}, {-20257, "ban"}, {-20242, "bang"}, {-20230, "bao"}, {-20051, "bei"}, {-20036, "ben"},
+	{-20032, "beng"}, {-20026, "bi"}, {-20002, "bian"}, {-19990, "biao"}, {-19986, "bie"}, {-19982, "bin"},
+	{-19976, "bing"}, {-19805, "bo"}, {-19784, "bu"}, {-19775, "ca"}, {-19774, "cai"}, {-19763, "can"},
+	{-19756, "cang"}, {-19751, "cao"}, {-19746, "ce"}, {-19741, "ceng"}, {-19739, "cha"}, {-19728, "chai"},
+	{-19725, "chan"}, {-19715, "chang"}, {-19540, "chao"}, {-19531, "che"}, {-19525, "chen"}, {-19515, "cheng"},
+	{-19500, "chi"}, {-19484, "chong"}, {-19479, "chou"}, {-19467, "chu"}, {-19289, "chuai"}, {-19288, "chuan"},
+	{-19281, "chuang"}, {-19275, "chui"}, {-19270, "chun"}, {-19263, "chuo"}, {-19261, "ci"}, {-19249, "cong"},
+	{-19243, "cou"}, {-19242, "cu"}, {-19238, "cuan"}, {-19235, "cui"}, {-19227, "cun"}, {-19224, "cuo"},
+	{-19218, "da"}, {-19212, "dai"}, {-19038, "dan"}, {-19023, "dang"}, {-19018, "dao"}, {-19006, "de"},
+	{-19003, "deng"}, {-18996, "di"}, {-18977, "dian"}, {-18961, "diao"}, {-18952, "die"}, {-18783, "ding"},
+	{-18774, "diu"}, {-18773, "dong"}, {-18763, "dou"}, {-18756, "du"}, {-18741, "duan"}, {-18735, "dui"},
+	{-18731, "dun"}, {-18722, "duo"}, {-18710, "e"}, {-18697, "en"}, {-18696, "er"}, {-18526, "fa"},
+	{-18518, "fan"}, {-18501, "fang"}, {-18490, "fei"}, {-18478, "fen"}, {-18463, "feng"}, {-18448, "fo"},
+	{-18447, "fou"}, {-18446, "fu"}, {-18239, "ga"}, {-18237, "gai"}, {-18231, "gan"}, {-18220, "gang"},
+	{-18211, "gao"}, {-18201, "ge"}, {-18184, "gei"}, {-18183, "gen"}, {-18181, "geng"}, {-18012, "gong"},
+	{-17997, "gou"}, {-17988, "gu"}, {-17970, "gua"}, {-17964, "guai"}, {-17961, "guan"}, {-17950, "guang"},
+	{-17947, "gui"}, {-17931, "gun"}, {-17928, "guo"}, {-17922, "ha"}, {-17759, "hai"}, {-17752, "han"},
+	{-17733, "hang"}, {-17730, "hao"}, {-17721, "he"}, {-17703, "hei"}, {-17701, "hen"}, {-17697, "heng"},
+	{-17692, "hong"}, {-17683, "hou"}, {-17676, "hu"}, {-17496, "hua"}, {-17487, "huai"}, {-17482, "huan"},
+	{-17468, "huang"}, {-17454, "hui"}, {-17433, "hun"}, {-17427, "huo"}, {-17417, "ji"}, {-17202, "jia"},
+	{-17185, "jian"}, {-16983, "jiang"}, {-16970, "jiao"}, {-16942, "jie"}, {-16915, "jin"}, {-16733, "jing"},
+	{-16708, "jiong"}, {-16706, "jiu"}, {-16689, "ju"}, {-16664, "juan"}, {-16657, "jue"}, {-16647, "jun"},
+	{-16474, "ka"}, {-16470, "kai"}, {-16465, "kan"}, {-16459, "kang"}, {-16452, "kao"}, {-16448, "ke"},
+	{-16433, "ken"}, {-16429, "keng"}, {-16427, "kong"}, {-16423, "kou"}, {-16419, "ku"}, {-16412, "kua"},
+	{-16407, "kuai"}, {-16403, "kuan"}, {-16401, "kuang"}, {-16393, "kui"}, {-16220, "kun"}, {-16216, "kuo"},
+	{-16212, "la"}, {-16205, "lai"}, {-16202, "lan"}, {-16187, "lang"}, {-16180, "lao"}, {-16171, "le"},
+	{-16169, "lei"}, {-16158, "leng"}, {-16155, "li"}, {-15959, "lia"}, {-15958, "lian"}, {-15944, "liang"},
+	{-15933, "liao"}, {-15920, "lie"}, {-15915, "lin"}, {-15903, "ling"}, {-15889, "liu"}, {-15878, "long"},
+	{-15707, "lou"}, {-15701, "lu"}, {-15681, "lv"}, {-15667, "luan"}, {-15661, "lue"}, {-15659, "lun"},
+	{-15652, "luo"}, {-15640, "ma"}, {-15631, "mai"}, {-15625, "man"}, {-15454, "mang"}, {-15448, "mao"},
+	{-15436, "me"}, {-15435, "mei"}, {-15419, "men"}, {-15416, "meng"}, {-15408, "mi"}, {-15394, "mian"},
+	{-15385, "miao"}, {-15377, "mie"}, {-15375, "min"}, {-15369, "ming"}, {-15363, "miu"}, {-15362, "mo"},
+	{-15183, "mou"}, {-15180, "mu"}, {-15165, "na"}, {-15158, "nai"}, {-15153, "nan"}, {-15150, "nang"},
+	{-15149, "nao"}, {-15144, "ne"}, {-15143, "nei"}, {-15141, "nen"}, {-15140, "neng"}, {-15139, "ni"},
+	{-15128, "nian"}, {-15121, "niang"}, {-15119, "niao"}, {-15117, "nie"}, {-15110, "nin"}, {-15109, "ning"},
+	{-14941, "niu"}, {-14937, "nong"}, {-14933, "nu"}, {-14930, "nv"}, {-14929, "nuan"}, {-14928, "nue"},
+	{-14926, "nuo"}, {-14922, "o"}, {-14921, "ou"}, {-14914, "pa"}, {-14908, "pai"}, {-14902, "pan"},
+	{-14894, "pang"}, {-14889, "pao"}, {-14882, "pei"}, {-14873, "pen"}, {-14871, "peng"}, {-14857, "pi"},
+	{-14678, "pian"}, {-14674, "piao"}, {-14670, "pie"}, {-14668, "pin"}, {-14663, "ping"}, {-14654, "po"},
+	{-14645, "pu"}, {-14630, "qi"}, {-14594, "qia"}, {-14429, "qian"}, {-14407, "qiang"}, {-14399, "qiao"},
+	{-14384, "qie"}, {-14379, "qin"}, {-14368, "qing"}, {-14355, "qiong"}, {-14353, "qiu"}, {-14345, "qu"},
+	{-14170, "quan"}, {-14159, "que"}, {-14151, "qun"}, {-14149, "ran"}, {-14145, "rang"}, {-14140, "rao"},
+	{-14137, "re"}, {-14135, "ren"}, {-14125, "reng"}, {-14123, "ri"}, {-14122, "rong"}, {-14112, "rou"},
+	{-14109, "ru"}, {-14099, "ruan"}, {-14097, "rui"}, {-14094, "run"}, {-14092, "ruo"}, {-14090, "sa"},
+	{-14087, "sai"}, {-14083, "san"}, {-13917, "sang"}, {-13914, "sao"}, {-13910, "se"}, {-13907, "sen"},
+	{-13906, "seng"}, {-13905, "sha"}, {-13896, "shai"}, {-13894, "shan"}, {-13878, "shang"}, {-13870, "shao"},
+	{-13859, "she"}, {-13847, "shen"}, {-13831, "sheng"}, {-13658, "shi"}, {-13611, "shou"}, {-13601, "shu"},
+	{-13406, "shua"}, {-13404, "shuai"}, {-13400, "shuan"}, {-13398, "shuang"}, {-13395, "shui"}, {-13391, "shun"},
+	{-13387, "shuo"}, {-13383, "si"}, {-13367, "song"}, {-13359, "sou"}, {-13356, "su"}, {-13343, "suan"},
+	{-13340, "sui"}, {-13329, "sun"}, {-13326, "suo"}, {-13318, "ta"}, {-13147, "tai"}, {-13138, "tan"},
+	{-13120, "tang"}, {-13107, "tao"}, {-13096, "te"}, {-13095, "teng"}, {-13091, "ti"}, {-13076, "tian"},
+	{-13068, "tiao"}, {-13063, "tie"}, {-13060, "ting"}, {-12888, "tong"}, {-12875, "tou"}, {-12871, "tu"},
+	{-12860, "tuan"}, {-12858, "tui"}, {-12852, "tun"}, {-12849, "tuo"}, {-12838, "wa"}, {-12831, "wai"},
+	{-12829, "wan"}, {-12812, "wang"}, {-12802, "wei"}, {-12607, "wen"}, {-12597, "weng"}, {-12594, "wo"},
+	{-12585, "wu"}, {-12556, "xi"}, {-12359, "xia"}, {-12346, "xian"}, {-12320, "xiang"}, {-12300, "xiao"},
+	{-12120, "xie"}, {-12099, "xin"}, {-12089, "xing"}, {-12074, "xiong"}, {-12067, "xiu"}, {-12058, "xu"},
+	{-12039, "xuan"}, {-11867, "xue"}, {-11861, "xun"}, {-11847, "ya"}, {-11831, "yan"}, {-11798, "yang"},
+	{-11781, "yao"}, {-11604, "ye"}, {-11589, "yi"}, {-11536, "yin"}, {-11358, "ying"}, {-11340, "yo"},
+	{-11339, "yong"}, {-11324, "you"}, {-11303, "yu"}, {-11097, "yuan"}, {-11077, "yue"}, {-11067, "yun"},
+	{-11055, "za"}, {-11052, "zai"}, {-11045, "zan"}, {-11041, "zang"}, {-11038, "zao"}, {-11024, "ze"},
+	{-11020, "zei"}, {-11019, "zen"}, {-11018, "zeng"}, {-11014, "zha"}, {-10838, "zhai"}, {-10832, "zhan"},
+	{-10815, "zhang"}, {-10800, "zhao"}, {-10790, "zhe"}, {-10780, "zhen"}, {-10764, "zheng"}, {-10587, "zhi"},
+	{-10544, "zhong"}, {-10533, "zhou"}, {-10519, "zhu"}, {-10331, "zhua"}, {-10329, "zhuai"}, {-10328, "zhuan"},
+	{-10322, "zhuang"}, {-10315, "zhui"}, {-10309, "zhun"}, {-10307, "zhuo"}, {-10296, "zi"}, {-10281, "zong"},
+	{-10274, "zou"}, {-10270, "zu"}, {-10262, "zuan"}, {-10260, "zui"}, {-10256, "zun"}, {-10254, "zuo"},
+}
+
+// pinyinToneTable 常用字带声调拼音（数字表示声调，5为轻声，ü记作v）
+var pinyinToneTable = map[rune]string{
+	'的': "de5", '一': "yi1", '是': "shi4", '不': "bu4", '了': "le5", '在': "zai4", '人': "ren2", '有': "you3",
+	'我': "wo3", '他': "ta1", '这': "zhe4", '个': "ge4", '们': "men5", '中': "zhong1", '来': "lai2", '上': "shang4",
+	'大': "da4", '为': "wei4", '和': "he2", '国': "guo2", '地': "di4", '到': "dao4", '以': "yi3", '说': "shuo1",
+	'时': "shi2", '要': "yao4", '就': "jiu4", '出': "chu1", '会': "hui4", '可': "ke3", '也': "ye3", '你': "ni3",
+	'对': "dui4", '生': "sheng1", '能': "neng2", '而': "er2", '子': "zi3", '那': "na4", '得': "de2", '于': "yu2",
+	'着': "zhe5", '下': "xia4", '自': "zi4", '之': "zhi1", '年': "nian2", '过': "guo4", '发': "fa1", '后': "hou4",
+	'作': "zuo4", '里': "li3", '用': "yong4", '道': "dao4", '行': "xing2", '所': "suo3", '然': "ran2", '家': "jia1",
+	'种': "zhong3", '事': "shi4", '成': "cheng2", '方': "fang1", '多': "duo1", '经': "jing1", '么': "me5", '去': "qu4",
+	'法': "fa3", '学': "xue2", '如': "ru2", '都': "dou1", '同': "tong2", '现': "xian4", '当': "dang1", '没': "mei2",
+	'动': "dong4", '面': "mian4", '起': "qi3", '看': "kan4", '定': "ding4", '天': "tian1", '分': "fen1", '还': "hai2",
+	'进': "jin4", '好': "hao3", '小': "xiao3", '部': "bu4", '其': "qi2", '些': "xie1", '主': "zhu3", '样': "yang4",
+	'理': "li3", '心': "xin1", '她': "ta1", '本': "ben3", '前': "qian2", '开': "kai1", '但': "dan4", '因': "yin1",
+	'只': "zhi3", '从': "cong2", '想': "xiang3", '实': "shi2", '日': "ri4", '军': "jun1", '者': "zhe3", '意': "yi4",
+	'无': "wu2", '力': "li4", '它': "ta1", '与': "yu3", '长': "chang2", '把': "ba3", '机': "ji1", '十': "shi2",
+	'民': "min2", '第': "di4", '公': "gong1", '此': "ci3", '已': "yi3", '工': "gong1", '使': "shi3", '情': "qing2",
+	'明': "ming2", '性': "xing4", '知': "zhi1", '全': "quan2", '三': "san1", '又': "you4", '关': "guan1", '点': "dian3",
+	'正': "zheng4", '业': "ye4", '外': "wai4", '将': "jiang1", '两': "liang3", '高': "gao1", '间': "jian1", '由': "you2",
+	'问': "wen4", '很': "hen3", '最': "zui4", '重': "zhong4", '并': "bing4", '物': "wu4", '手': "shou3", '应': "ying1",
+	'战': "zhan4", '向': "xiang4", '头': "tou2", '文': "wen2", '体': "ti3", '政': "zheng4", '美': "mei3", '相': "xiang1",
+	'见': "jian4", '被': "bei4", '利': "li4", '什': "shen2", '二': "er4", '等': "deng3", '产': "chan3", '或': "huo4",
+	'新': "xin1", '己': "ji3", '制': "zhi4", '身': "shen1", '果': "guo3", '加': "jia1", '西': "xi1", '斯': "si1",
+	'月': "yue4", '话': "hua4", '合': "he2", '回': "hui2", '特': "te4", '代': "dai4", '内': "nei4", '信': "xin4",
+	'表': "biao3", '化': "hua4", '老': "lao3", '给': "gei3", '世': "shi4", '位': "wei4", '次': "ci4", '度': "du4",
+	'门': "men2", '任': "ren4", '常': "chang2", '先': "xian1", '海': "hai3", '通': "tong1", '教': "jiao4", '儿': "er2",
+	'原': "yuan2", '东': "dong1", '声': "sheng1", '提': "ti2", '立': "li4", '及': "ji2", '比': "bi3", '员': "yuan2",
+	'解': "jie3", '水': "shui3", '名': "ming2", '真': "zhen1", '论': "lun4", '处': "chu4", '走': "zou3", '义': "yi4",
+	'各': "ge4", '入': "ru4", '几': "ji3", '口': "kou3", '认': "ren4", '条': "tiao2", '平': "ping2", '系': "xi4",
+	'气': "qi4", '题': "ti2", '活': "huo2", '尔': "er3", '更': "geng4", '别': "bie2", '打': "da3", '女': "nv3",
+	'变': "bian4", '四': "si4", '神': "shen2", '总': "zong3", '何': "he2", '电': "dian4", '数': "shu4", '安': "an1",
+	'少': "shao3", '报': "bao4", '才': "cai2", '结': "jie2", '反': "fan3", '受': "shou4", '目': "mu4", '太': "tai4",
+	'量': "liang4", '再': "zai4", '感': "gan3", '建': "jian4", '务': "wu4", '做': "zuo4", '接': "jie1", '必': "bi4",
+	'场': "chang3", '件': "jian4", '计': "ji4", '管': "guan3", '期': "qi1", '市': "shi4", '直': "zhi2", '德': "de2",
+	'资': "zi1", '命': "ming4", '山': "shan1", '金': "jin1", '指': "zhi3", '克': "ke4", '许': "xu3", '统': "tong3",
+	'区': "qu1", '保': "bao3", '至': "zhi4", '队': "dui4", '形': "xing2", '社': "she4", '便': "bian4", '空': "kong1",
+	'决': "jue2", '治': "zhi4", '展': "zhan3", '马': "ma3", '科': "ke1", '司': "si1", '五': "wu3", '基': "ji1",
+	'眼': "yan3", '书': "shu1", '非': "fei1", '则': "ze2", '听': "ting1", '白': "bai2", '却': "que4", '界': "jie4",
+	'达': "da2", '光': "guang1", '放': "fang4", '强': "qiang2", '即': "ji2", '像': "xiang4", '难': "nan2", '且': "qie3",
+	'权': "quan2", '思': "si1", '王': "wang2", '象': "xiang4", '完': "wan2", '设': "she4", '式': "shi4", '色': "se4",
+	'路': "lu4", '记': "ji4", '南': "nan2", '品': "pin3", '住': "zhu4", '告': "gao4", '类': "lei4", '求': "qiu2",
+	'据': "ju4", '程': "cheng2", '北': "bei3", '边': "bian1", '死': "si3", '张': "zhang1", '该': "gai1", '交': "jiao1",
+	'规': "gui1", '万': "wan4", '取': "qu3", '拉': "la1", '格': "ge2", '望': "wang4", '觉': "jue2", '术': "shu4",
+	'领': "ling3", '共': "gong4", '确': "que4", '传': "chuan2", '师': "shi1", '观': "guan1", '清': "qing1", '今': "jin1",
+	'切': "qie1", '院': "yuan4", '让': "rang4", '识': "shi2", '候': "hou4", '带': "dai4", '导': "dao3", '争': "zheng1",
+	'运': "yun4", '笑': "xiao4", '飞': "fei1", '风': "feng1", '步': "bu4", '改': "gai3", '收': "shou1", '根': "gen1",
+	'干': "gan4", '造': "zao4", '言': "yan2", '联': "lian2", '持': "chi2", '组': "zu3", '每': "mei3", '济': "ji4",
+	'车': "che1", '亲': "qin1", '极': "ji2", '林': "lin2", '服': "fu2", '快': "kuai4", '办': "ban4", '议': "yi4",
+	'往': "wang3", '元': "yuan2", '英': "ying1", '士': "shi4", '证': "zheng4", '近': "jin4", '失': "shi1", '转': "zhuan3",
+	'夫': "fu1", '令': "ling4", '准': "zhun3", '布': "bu4", '始': "shi3", '怎': "zen3", '呢': "ne5", '病': "bing4",
+	'京': "jing1", '城': "cheng2", '省': "sheng3", '县': "xian4", '村': "cun1", '街': "jie1", '号': "hao4", '楼': "lou2",
+	'室': "shi4", '店': "dian4", '商': "shang1", '价': "jia4", '钱': "qian2", '买': "mai3", '卖': "mai4", '吃': "chi1",
+	'喝': "he1", '穿': "chuan1", '睡': "shui4", '爱': "ai4", '喜': "xi3", '欢': "huan1", '乐': "le4", '红': "hong2",
+	'黄': "huang2", '蓝': "lan2", '绿': "lv4", '黑': "hei1", '春': "chun1", '夏': "xia4", '秋': "qiu1", '冬': "dong1",
+	'雨': "yu3", '雪': "xue3", '云': "yun2", '花': "hua1", '草': "cao3", '树': "shu4", '木': "mu4", '火': "huo3",
+	'土': "tu3", '石': "shi2", '田': "tian2", '河': "he2", '江': "jiang1", '湖': "hu2", '星': "xing1", '李': "li3",
+	'刘': "liu2", '陈': "chen2", '杨': "yang2", '赵': "zhao4", '吴': "wu2", '周': "zhou1", '徐': "xu2", '孙': "sun1",
+	'朱': "zhu1", '胡': "hu2", '郭': "guo1", '罗': "luo2", '郑': "zheng4", '梁': "liang2", '谢': "xie4", '宋': "song4",
+	'唐': "tang2", '韩': "han2", '冯': "feng2", '邓': "deng4", '曹': "cao2", '彭': "peng2", '曾': "zeng1", '肖': "xiao1",
+	'董': "dong3", '袁': "yuan2", '潘': "pan1", '蒋': "jiang3", '蔡': "cai4", '余': "yu2", '杜': "du4", '叶': "ye4",
+	'苏': "su1", '魏': "wei4", '吕': "lv3", '丁': "ding1", '沈': "shen3", '姚': "yao2", '卢': "lu2", '姜': "jiang1",
+	'崔': "cui1", '钟': "zhong1", '谭': "tan2", '陆': "lu4", '汪': "wang1", '范': "fan4", '廖': "liao4", '贾': "jia3",
+	'韦': "wei2", '付': "fu4", '邹': "zou1", '孟': "meng4", '熊': "xiong2", '秦': "qin2", '邱': "qiu1", '尹': "yin3",
+	'薛': "xue1", '闫': "yan2", '段': "duan4", '雷': "lei2", '侯': "hou2", '龙': "long2", '史': "shi3", '陶': "tao2",
+	'黎': "li2", '贺': "he4", '顾': "gu4", '毛': "mao2", '郝': "hao3", '龚': "gong1", '邵': "shao4", '严': "yan2",
+	'覃': "qin2", '武': "wu3", '戴': "dai4", '莫': "mo4", '孔': "kong3", '汤': "tang1", '拼': "pin1", '音': "yin1",
+	'汉': "han4", '字': "zi4", '语': "yu3", '网': "wang3", '站': "zhan4", '页': "ye4", '首': "shou3", '章': "zhang1",
+	'标': "biao1", '闻': "wen2", '单': "dan1", '订': "ding4", '户': "hu4", '登': "deng1", '录': "lu4", '码': "ma3",
+	'密': "mi4", '支': "zhi1", '款': "kuan3", '退': "tui4", '货': "huo4", '购': "gou4", '型': "xing2", '状': "zhuang4",
+	'态': "tai4", '审': "shen3", '核': "he2", '删': "shan1", '除': "chu2", '查': "cha2", '询': "xun2", '列': "lie4",
+	'详': "xiang2",
+}
+
+// toneMarks 各元音对应的1-4声标注
+var toneMarks = map[rune][4]rune{
+	'a': {'ā', 'á', 'ǎ', 'à'},
+	'e': {'ē', 'é', 'ě', 'è'},
+	'i': {'ī', 'í', 'ǐ', 'ì'},
+	'o': {'ō', 'ó', 'ǒ', 'ò'},
+	'u': {'ū', 'ú', 'ǔ', 'ù'},
+	'v': {'ǖ', 'ǘ', 'ǚ', 'ǜ'},
+}
+
+// ChineseToPinyin 汉字转拼音（汉字之间以空格分隔，非汉字原样保留）
+// withTone为true时输出声调符号（如“中国”→“zhōng guó”），否则输出无声调拼音（如“zhong guo”，ü记作v）
+func ChineseToPinyin(str string, withTone bool) string {
+	parts := make([]string, 0)
+	var other strings.Builder
+	flushOther := func() {
+		if other.Len() > 0 {
+			if s := strings.TrimSpace(other.String()); s != "" {
+				parts = append(parts, s)
+			}
+			other.Reset()
+		}
+	}
+	for _, r := range str {
+		if !unicode.Is(unicode.Han, r) {
+			other.WriteRune(r)
+			continue
+		}
+		py := runeToPinyin(r, withTone)
+		if py == "" {
+			// 无法识别的生僻字原样保留
+			other.WriteRune(r)
+			continue
+		}
+		flushOther()
+		parts = append(parts, py)
+	}
+	flushOther()
+	return strings.Join(parts, " ")
+}
+
+// runeToPinyin 单个汉字转拼音，无法识别时返回空字符串
+func runeToPinyin(r rune, withTone bool) string {
+	if toned, ok := pinyinToneTable[r]; ok {
+		if withTone {
+			return markTone(toned)
+		}
+		return toned[:len(toned)-1]
+	}
+	encoded, err := simplifiedchinese.GBK.NewEncoder().String(string(r))
+	if err != nil || len(encoded) != 2 {
+		return ""
+	}
+	code := int(encoded[0])*256 + int(encoded[1]) - 65536
+	if code < pinyinCodeTable[0].code || code > gb2312Level1End {
+		return ""
+	}
+	// 二分查找最后一个起始编码<=code的音节
+	low, high := 0, len(pinyinCodeTable)-1
+	for low < high {
+		mid := (low + high + 1) / 2
+		if pinyinCodeTable[mid].code <= code {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return pinyinCodeTable[low].py
+}
+
+// markTone 将数字声调拼音（如 zhong1）转换为声调符号拼音（zhōng）
+func markTone(toned string) string {
+	tone := int(toned[len(toned)-1] - '0')
+	syllable := []rune(toned[:len(toned)-1])
+	// 标调规则：有a/e标a/e，ou标o，其余标在最后一个元音上（iu/ui标后者）
+	pos := -1
+	for i, r := range syllable {
+		if r == 'a' || r == 'e' || (r == 'o' && i+1 < len(syllable) && syllable[i+1] == 'u') {
+			pos = i
+			break
+		}
+		if _, ok := toneMarks[r]; ok {
+			pos = i
+		}
+	}
+	if pos >= 0 && tone >= 1 && tone <= 4 {
+		syllable[pos] = toneMarks[syllable[pos]][tone-1]
+	}
+	return strings.ReplaceAll(string(syllable), "v", "ü")
+}
@@ -0,0 +1,184 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Backoff 退避策略：返回第attempt次（从1开始）失败后、下一次重试前的等待时长
+type Backoff interface {
+	Next(attempt int) time.Duration
+}
+
+// BackoffFunc 函数形式的退避策略
+type BackoffFunc func(attempt int) time.Duration
+
+// Next 实现Backoff
+func (f BackoffFunc) Next(attempt int) time.Duration {
+	return f(attempt)
+}
+
+// ConstantBackoff 固定间隔
+func ConstantBackoff(d time.Duration) Backoff {
+	return BackoffFunc(func(int) time.Duration { return d })
+}
+
+// ExponentialBackoff 指数退避：Initial * Multiplier^(attempt-1)，不超过Max，并叠加±Jitter比例的随机抖动（避免多实例同时重试）
+type ExponentialBackoff struct {
+	Initial    time.Duration // 首次等待（默认100ms）
+	Max        time.Duration // 最长等待（默认10s）
+	Multiplier float64       // 增长倍数（默认2）
+	Jitter     float64       // 抖动比例（0~1，如0.2表示在±20%范围内随机）
+}
+
+// DefaultBackoff 默认退避策略（100ms起，翻倍增长，最长10s，±20%抖动）
+var DefaultBackoff = ExponentialBackoff{Initial: 100 * time.Millisecond, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2}
+
+// Next 实现Backoff
+func (b ExponentialBackoff) Next(attempt int) time.Duration {
+	initial, maxWait, multiplier := b.Initial, b.Max, b.Multiplier
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	if maxWait <= 0 {
+		maxWait = 10 * time.Second
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	wait := math.Min(float64(initial)*math.Pow(multiplier, float64(attempt-1)), float64(maxWait))
+	if jitter := math.Min(b.Jitter, 1); jitter > 0 {
+		wait *= 1 - jitter + rand.Float64()*2*jitter
+		wait = math.Min(wait, float64(maxWait))
+	}
+	return time.Duration(wait)
+}
+
+// RetryOption 重试选项
+type RetryOption func(*retryConfig)
+
+type retryConfig struct {
+	retryIf func(error) bool
+	onRetry func(attempt int, err error, wait time.Duration)
+}
+
+// RetryIf 仅当predicate返回true时重试（如只重试网络错误、死锁、限流），默认除Permanent外的错误都重试
+func RetryIf(predicate func(error) bool) RetryOption {
+	return func(c *retryConfig) { c.retryIf = predicate }
+}
+
+// OnRetry 每次失败且即将重试时回调（用于记录日志、统计重试次数）
+func OnRetry(fn func(attempt int, err error, wait time.Duration)) RetryOption {
+	return func(c *retryConfig) { c.onRetry = fn }
+}
+
+// permanentError 不再重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装不应重试的错误（如参数错误、鉴权失败），Retry遇到后立即返回
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// RetryError 重试失败的汇总错误（Errors按尝试顺序保存每次的错误，支持errors.Is/As逐个匹配）
+type RetryError struct {
+	Attempts int     // 实际尝试次数
+	Errors   []error // 每次尝试的错误（context取消时最后一项为ctx.Err()）
+}
+
+func (e *RetryError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("尝试%d次后失败：%v", e.Attempts, e.Errors[0])
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("尝试%d次后失败，最后一次错误：%v（全部错误：%s）", e.Attempts, e.Last(), strings.Join(msgs, "; "))
+}
+
+// Unwrap 返回全部错误（errors.Is/As会逐个匹配）
+func (e *RetryError) Unwrap() []error {
+	return e.Errors
+}
+
+// Last 最后一次错误
+func (e *RetryError) Last() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors[len(e.Errors)-1]
+}
+
+// Retry 执行fn，失败时按backoff退避后重试，最多尝试attempts次（<=0按1次处理；backoff为nil时使用DefaultBackoff）。
+// 成功返回nil；fn返回Permanent错误、RetryIf判定不重试、次数用尽或ctx取消时返回*RetryError。
+func Retry(ctx context.Context, attempts int, backoff Backoff, fn func(ctx context.Context) error, opts ...RetryOption) error {
+	_, err := RetryValue(ctx, attempts, backoff, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts...)
+	return err
+}
+
+// RetryValue 带返回值的Retry
+func RetryValue[T any](ctx context.Context, attempts int, backoff Backoff, fn func(ctx context.Context) (T, error), opts ...RetryOption) (T, error) {
+	var cfg retryConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if attempts <= 0 {
+		attempts = 1
+	}
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	var (
+		zero T
+		errs []error
+	)
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return zero, &RetryError{Attempts: attempt - 1, Errors: append(errs, err)}
+		}
+		value, err := fn(ctx)
+		if err == nil {
+			return value, nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return zero, &RetryError{Attempts: attempt, Errors: append(errs, perm.err)}
+		}
+		errs = append(errs, err)
+		if attempt >= attempts || (cfg.retryIf != nil && !cfg.retryIf(err)) {
+			return zero, &RetryError{Attempts: attempt, Errors: errs}
+		}
+		wait := backoff.Next(attempt)
+		if cfg.onRetry != nil {
+			cfg.onRetry(attempt, err, wait)
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return zero, &RetryError{Attempts: attempt, Errors: append(errs, ctx.Err())}
+			case <-timer.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package function
+
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// defaultSlugMaxLen Slug默认最大长度
+const defaultSlugMaxLen = 80
+
+// Slugify 将标题转换为URL友好的slug（汉字转无声调拼音、去除重音符号、转小写、以-分隔）
+// maxLen 可选，限制slug最大长度（默认80），截断时保留完整单词
+// 示例：Slugify("Go语言 入门教程!") → "go-yu-yan-ru-men-jiao-cheng"；Slugify("Café Déjà Vu") → "cafe-deja-vu"
+func Slugify(title string, maxLen ...int) string {
+	limit := defaultSlugMaxLen
+	if len(maxLen) > 0 && maxLen[0] > 0 {
+		limit = maxLen[0]
+	}
+	// 1. 汉字转拼音（拼音之间以空格分隔）
+	str := ChineseToPinyin(title, false)
+	// 2. 去除重音符号（é→e、ü→u）
+	if plain, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), str); err == nil {
+		str = plain
+	}
+	// 3. 仅保留小写字母和数字，其余字符统一替换为-（连续的只保留一个）
+	var builder strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(str) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			builder.WriteByte('-')
+			lastDash = true
+		}
+	}
+	slug := strings.Trim(builder.String(), "-")
+	// 4. 长度截断（优先在-处截断，避免切断单词）
+	if len(slug) > limit {
+		slug = slug[:limit]
+		if idx := strings.LastIndex(slug, "-"); idx > 0 {
+			slug = slug[:idx]
+		}
+		slug = strings.Trim(slug, "-")
+	}
+	return slug
+}
@@ -0,0 +1,175 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// JWTAuthInterceptor JWT认证拦截器：校验元数据authorization: Bearer <token>，
+// 通过后将声明写入context（处理器中通过auth.FromContext获取），失败返回Unauthenticated。
+// publicMethods为无需认证的完整方法名（如/pkg.Service/Login）
+func JWTAuthInterceptor(j *auth.JWT, publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := publicMethodSet(publicMethods)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, j)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// JWTAuthStreamInterceptor 流式方法的JWT认证拦截器（在建立流时校验一次，规则与JWTAuthInterceptor一致）
+func JWTAuthStreamInterceptor(j *auth.JWT, publicMethods ...string) grpc.StreamServerInterceptor {
+	public := publicMethodSet(publicMethods)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if public[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), j)
+		if err != nil {
+			return err
+		}
+		return handler(srv, WrapServerStream(ss, ctx))
+	}
+}
+
+// authenticate 校验元数据中的令牌，返回写入声明的context
+func authenticate(ctx context.Context, j *auth.JWT) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := ""
+	if values := md.Get("authorization"); len(values) > 0 {
+		token = auth.BearerToken(values[0])
+	}
+	claims, err := j.Parse(token)
+	if err != nil {
+		msg := i18n.MsgAuthFailed
+		if errors.Is(err, auth.ErrTokenExpired) {
+			msg = i18n.MsgTokenExpired
+		}
+		return ctx, status.Error(codes.Unauthenticated, i18n.T(metadataLocale(md), msg))
+	}
+	return auth.WithClaims(ratelimit.WithUser(ctx, claims.UserID), claims), nil
+}
+
+func publicMethodSet(methods []string) map[string]bool {
+	public := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		public[method] = true
+	}
+	return public
+}
+
+// ClientCertInterceptor mTLS客户端身份授权拦截器：rules的key为完整方法名（/pkg.Service/Method）、
+// 服务通配（/pkg.Service/*）或全局默认（*），按此优先级选取规则，value为允许的CN/SAN（规则同auth.ClientIdentity.Match）；
+// 未匹配到规则的方法直接放行，客户端未提供证书时返回Unauthenticated，身份不在允许列表中时返回PermissionDenied
+func ClientCertInterceptor(rules map[string][]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorizeClientCert(ctx, rules, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ClientCertStreamInterceptor 流式方法的mTLS客户端身份授权拦截器（在建立流时校验一次，规则与ClientCertInterceptor一致）
+func ClientCertStreamInterceptor(rules map[string][]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorizeClientCert(ss.Context(), rules, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// authorizeClientCert 按方法规则校验对端证书身份
+func authorizeClientCert(ctx context.Context, rules map[string][]string, fullMethod string) error {
+	allowed, ok := rules[fullMethod]
+	if !ok {
+		if idx := strings.LastIndex(fullMethod, "/"); idx > 0 {
+			allowed, ok = rules[fullMethod[:idx]+"/*"]
+		}
+	}
+	if !ok {
+		if allowed, ok = rules["*"]; !ok {
+			return nil
+		}
+	}
+	id := auth.ClientIdentityFromContext(ctx)
+	if id == nil {
+		p, _ := peer.FromContext(ctx)
+		id = peerIdentity(p)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if id == nil {
+		return status.Error(codes.Unauthenticated, i18n.T(metadataLocale(md), i18n.MsgAuthFailed))
+	}
+	if !id.Match(allowed...) {
+		return status.Error(codes.PermissionDenied, i18n.T(metadataLocale(md), i18n.MsgForbidden))
+	}
+	return nil
+}
+
+// peerIdentity 对端已校验的客户端证书身份（未启用mTLS或客户端未提供证书时返回nil）
+func peerIdentity(p *peer.Peer) *auth.ClientIdentity {
+	if p == nil {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return auth.IdentityFromTLS(&info.State)
+}
+
+// RBACInterceptor 权限拦截器：按完整方法名匹配规则，校验已认证用户的角色/权限（规则见auth.RBAC），
+// 须位于JWTAuthInterceptor之后；未匹配到规则的方法直接放行，未认证返回Unauthenticated，权限不足返回PermissionDenied
+func RBACInterceptor(r *auth.RBAC) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorizeRBAC(ctx, r, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RBACStreamInterceptor 流式方法的权限拦截器（在建立流时校验一次，规则与RBACInterceptor一致）
+func RBACStreamInterceptor(r *auth.RBAC) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorizeRBAC(ss.Context(), r, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// authorizeRBAC 按方法规则校验context中的认证声明
+func authorizeRBAC(ctx context.Context, r *auth.RBAC, fullMethod string) error {
+	err := r.Authorize(ctx, fullMethod, auth.FromContext(ctx))
+	if err == nil {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, i18n.T(metadataLocale(md), i18n.MsgAuthFailed))
+	case errors.Is(err, auth.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, i18n.T(metadataLocale(md), i18n.MsgForbidden))
+	}
+	logger.Error("gRPC权限策略加载失败：", err)
+	return status.Error(codes.Internal, i18n.T(metadataLocale(md), i18n.MsgInternalError))
+}
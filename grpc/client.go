@@ -0,0 +1,382 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/function"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 服务间调用客户端：按配置grpc.clients创建命名客户端并复用连接，调用时统一处理
+// 超时（调用方context无更早截止时间时使用配置值）、可重试状态码的退避重试、
+// 请求ID（x-request-id）与链路信息（traceparent）向下游透传，服务间调用遵循同一套模式。
+
+const (
+	defaultClientTimeout      = 5 * time.Second
+	defaultClientRetryBackoff = 100 * time.Millisecond
+	defaultKeepaliveTimeout   = 20 * time.Second
+)
+
+// ErrClientNotFound 客户端未配置
+var ErrClientNotFound = errors.New("gRPC客户端未配置")
+
+var (
+	clientsMu sync.RWMutex
+	clients   = map[string]*Client{}
+)
+
+// Client 命名gRPC客户端：实现grpc.ClientConnInterface，可直接传给protoc生成的NewXxxClient，
+// 也可通过Call按方法名发起强类型调用
+type Client struct {
+	name       string
+	conns      []*grpc.ClientConn
+	next       atomic.Uint64
+	timeout    time.Duration
+	maxRetries int
+	backoff    function.Backoff
+	retryCodes map[codes.Code]bool
+}
+
+// InitClients 按应用配置grpc.clients初始化全部命名客户端（连接在首次调用时建立）
+func InitClients(appName string) error {
+	appCfg := config.GetAppConfig(appName)
+	if appCfg == nil {
+		return fmt.Errorf("应用配置不存在：%s", appName)
+	}
+	for name, cfg := range appCfg.GRPC.Clients {
+		client, err := NewClient(name, cfg)
+		if err != nil {
+			return fmt.Errorf("gRPC客户端[%s]初始化失败：%w", name, err)
+		}
+		clientsMu.Lock()
+		old := clients[name]
+		clients[name] = client
+		clientsMu.Unlock()
+		if old != nil {
+			_ = old.Close()
+		}
+	}
+	return nil
+}
+
+// GetClient 获取命名客户端（需先调用InitClients）
+func GetClient(name string) (*Client, error) {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	client, ok := clients[name]
+	if !ok {
+		return nil, fmt.Errorf("%w：%s", ErrClientNotFound, name)
+	}
+	return client, nil
+}
+
+// CloseClients 关闭全部命名客户端
+func CloseClients() error {
+	clientsMu.Lock()
+	all := clients
+	clients = map[string]*Client{}
+	clientsMu.Unlock()
+	var errs []error
+	for _, client := range all {
+		if err := client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewClient 按配置创建客户端（不注册到命名客户端表，适用于动态目标）
+func NewClient(name string, cfg config.GRPCClientConfig) (*Client, error) {
+	if cfg.Target == "" {
+		return nil, errors.New("未配置目标地址")
+	}
+	opts, err := clientDialOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		name:       name,
+		timeout:    time.Duration(cfg.Timeout) * time.Millisecond,
+		maxRetries: cfg.MaxRetries,
+		retryCodes: map[codes.Code]bool{},
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultClientTimeout
+	}
+	initial := time.Duration(cfg.RetryBackoff) * time.Millisecond
+	if initial <= 0 {
+		initial = defaultClientRetryBackoff
+	}
+	c.backoff = function.ExponentialBackoff{Initial: initial, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2}
+	retryCodes := cfg.RetryCodes
+	if len(retryCodes) == 0 {
+		retryCodes = []string{"UNAVAILABLE"}
+	}
+	for _, name := range retryCodes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(strings.TrimSpace(name))))); err != nil {
+			return nil, fmt.Errorf("可重试状态码无效：%s", name)
+		}
+		c.retryCodes[code] = true
+	}
+	poolSize := cfg.PoolSize
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	for i := 0; i < poolSize; i++ {
+		conn, err := grpc.NewClient(cfg.Target, opts...)
+		if err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+		c.conns = append(c.conns, conn)
+	}
+	return c, nil
+}
+
+// clientDialOptions 构建连接选项（消息大小、保活、TLS）
+func clientDialOptions(cfg config.GRPCClientConfig) ([]grpc.DialOption, error) {
+	var opts []grpc.DialOption
+	var callOpts []grpc.CallOption
+	if cfg.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(cfg.MaxRecvMsgSize))
+	}
+	if cfg.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(cfg.MaxSendMsgSize))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	if cfg.KeepaliveTime > 0 {
+		timeout := time.Duration(cfg.KeepaliveTimeout) * time.Second
+		if timeout <= 0 {
+			timeout = defaultKeepaliveTimeout
+		}
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    time.Duration(cfg.KeepaliveTime) * time.Second,
+			Timeout: timeout,
+		}))
+	}
+	if !cfg.SSL {
+		return append(opts, grpc.WithTransportCredentials(insecure.NewCredentials())), nil
+	}
+	tlsCfg := &tls.Config{ServerName: cfg.SSLServerName, MinVersion: tls.VersionTLS12}
+	if tlsCfg.ServerName == "" {
+		tlsCfg.ServerName = targetHost(cfg.Target)
+	}
+	if cfg.SSLCAFile != "" {
+		pem, err := os.ReadFile(cfg.SSLCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败：%w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("CA证书格式错误：" + cfg.SSLCAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.SSLCertFile != "" || cfg.SSLKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.SSLCertFile, cfg.SSLKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败：%w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg))), nil
+}
+
+// targetHost 从目标地址中取主机名（dns:///host:port → host）
+func targetHost(target string) string {
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		target = target[i+1:]
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return target
+}
+
+// Name 客户端名称
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Conn 轮询返回连接池中的一个连接（直接使用时不经过超时、重试与透传处理）
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conns[(c.next.Add(1)-1)%uint64(len(c.conns))]
+}
+
+// Close 关闭全部连接
+func (c *Client) Close() error {
+	var errs []error
+	for _, conn := range c.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Invoke 发起一元调用（实现grpc.ClientConnInterface）：应用超时，透传请求ID与链路信息，
+// 按可重试状态码退避重试（每次重试轮询使用下一个连接）
+func (c *Client) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) (err error) {
+	timeout, retries := c.timeout, c.maxRetries
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case callTimeoutOption:
+			timeout = o.timeout
+		case callRetriesOption:
+			retries = o.retries
+		}
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if tracing.Enabled() {
+		var span trace.Span
+		ctx, span = tracing.StartClientSpan(ctx, method, attribute.String("rpc.method", method), attribute.String("rpc.client", c.name))
+		defer func() {
+			tracing.End(span, err)
+		}()
+	}
+	ctx = outgoingContext(ctx)
+
+	var last error
+	retryErr := function.Retry(ctx, retries+1, c.backoff, func(ctx context.Context) error {
+		last = c.Conn().Invoke(ctx, method, args, reply, opts...)
+		return last
+	}, function.RetryIf(func(err error) bool {
+		return c.retryCodes[status.Code(err)]
+	}), function.OnRetry(func(attempt int, err error, wait time.Duration) {
+		logger.FromContext(ctx).Warn("gRPC调用失败，", wait, "后重试 [", c.name, " ", method, " 第", attempt, "次]：", err)
+	}))
+	if retryErr == nil {
+		return nil
+	}
+	if last == nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return last
+}
+
+// NewStream 发起流式调用（实现grpc.ClientConnInterface）：透传请求ID与链路信息，
+// 流的生命周期由调用方context控制（不应用配置的超时与CallTimeout，也不重试）
+func (c *Client) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if tracing.Enabled() {
+		var span trace.Span
+		ctx, span = tracing.StartClientSpan(ctx, method, attribute.String("rpc.method", method), attribute.String("rpc.client", c.name),
+			attribute.Bool("rpc.client_streaming", desc.ClientStreams), attribute.Bool("rpc.server_streaming", desc.ServerStreams))
+		stream, err := c.Conn().NewStream(outgoingContext(ctx), desc, method, opts...)
+		if err != nil {
+			tracing.End(span, err)
+			return nil, err
+		}
+		go func() {
+			<-stream.Context().Done()
+			tracing.End(span, nil)
+		}()
+		return stream, nil
+	}
+	return c.Conn().NewStream(outgoingContext(ctx), desc, method, opts...)
+}
+
+// outgoingContext 将请求ID与链路信息写入出站元数据（调用方已设置的x-request-id保持不变）
+func outgoingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" && len(md.Get(logger.RequestIDHeader)) == 0 {
+		md.Set(logger.RequestIDHeader, requestID)
+	}
+	if tracing.Enabled() {
+		tracing.Inject(ctx, metadataCarrier(md))
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// FromNetContext 取框架请求上下文（HTTP/WS/gRPC）的context用于下游调用：携带请求ID与链路信息，
+// 并随上游请求取消
+func FromNetContext(c netContext.Context) context.Context {
+	if c == nil || c.GetContext() == nil {
+		return context.Background()
+	}
+	return c.GetContext()
+}
+
+// callTimeoutOption 单次调用超时
+type callTimeoutOption struct {
+	grpc.EmptyCallOption
+	timeout time.Duration
+}
+
+// CallTimeout 覆盖本次一元调用的超时（<=0表示不设置超时，仅受调用方context约束）
+func CallTimeout(timeout time.Duration) grpc.CallOption {
+	return callTimeoutOption{timeout: timeout}
+}
+
+// callRetriesOption 单次调用重试次数
+type callRetriesOption struct {
+	grpc.EmptyCallOption
+	retries int
+}
+
+// CallRetries 覆盖本次调用的最大重试次数（非幂等调用可传0关闭重试）
+func CallRetries(retries int) grpc.CallOption {
+	return callRetriesOption{retries: retries}
+}
+
+// Call 按方法名发起强类型一元调用，无需proto生成代码：protobuf消息原样收发，
+// 其他类型经google.protobuf.Struct按json标签转换（与RegisterTyped注册的方法对应）
+//
+//	resp, err := grpc.Call[GetUserReq, GetUserResp](ctx, client, "/user.UserService/GetUser", &GetUserReq{ID: 1})
+func Call[TReq, TResp any](ctx context.Context, c *Client, method string, req *TReq, opts ...grpc.CallOption) (*TResp, error) {
+	in, err := encodeTyped(req)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(TResp)
+	if m, ok := interface{}(resp).(proto.Message); ok {
+		if err := c.Invoke(ctx, method, in, m, opts...); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+	out := new(structpb.Struct)
+	if err := c.Invoke(ctx, method, in, out, opts...); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(out.AsMap())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, fmt.Errorf("响应格式错误：%w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,255 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	daiGrpc "github.com/dfpopp/go-dai/grpc"
+	"github.com/dfpopp/go-dai/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// gRPC子系统一致性校验：元数据传递、超时处理、错误码映射、流式调用、健康检查与优雅停机，作为演进时的回归基线
+// （中间件顺序、短路与响应合并见server_test.go）
+
+const (
+	methodChat      = "/dai.test.Streaming/Chat" // 流式方法注册为独立服务（与RegisterService注册的服务名不能重复）
+	sleepDuration   = 300 * time.Millisecond
+	shortDeadline   = 50 * time.Millisecond
+	requestIDSample = "conformance-request-id"
+)
+
+// sampleMethods 示例服务的一元方法
+var sampleMethods = map[string]structMethod{
+	// Echo 返回服务端看到的元数据、请求ID与截止时间
+	"Echo": func(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		resp := map[string]interface{}{
+			"request_id": logger.RequestIDFromContext(ctx),
+			"x-sample":   strings.Join(md.Get("x-sample"), ","),
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			resp["deadline_ms"] = float64(time.Until(deadline).Milliseconds())
+		}
+		return structpb.NewStruct(resp)
+	},
+	// Sleep 等待固定时长或请求取消
+	"Sleep": func(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+		select {
+		case <-time.After(sleepDuration):
+			return structpb.NewStruct(map[string]interface{}{"slept": true})
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	},
+	// Fail 按请求中的code字段返回对应错误（code为0时返回非status错误）
+	"Fail": func(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+		code := codes.Code(req.GetFields()["code"].GetNumberValue())
+		if code == codes.OK {
+			return nil, errors.New("plain error")
+		}
+		return nil, status.Error(code, "sample failure")
+	},
+}
+
+// denyInterceptor 模拟认证等附加拦截器：元数据x-deny存在时拒绝请求
+func denyInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("x-deny")) > 0 {
+		return nil, status.Error(codes.PermissionDenied, "denied")
+	}
+	return handler(ctx, req)
+}
+
+// startSampleServer 启动注册了示例服务、双向流方法与附加拦截器的服务
+func startSampleServer(t *testing.T) (*daiGrpc.Server, *grpc.ClientConn, *tracer) {
+	t.Helper()
+	tr := &tracer{}
+	server, conn := startServer(t, &daiGrpc.ServerConfig{
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{denyInterceptor},
+	}, sampleMethods, func(s *daiGrpc.Server) {
+		s.Use(tr.middleware("global"))
+		daiGrpc.RegisterBidiStream(s, methodChat, func(ctx context.Context, stream *daiGrpc.TypedStream[structpb.Struct, structpb.Struct]) error {
+			tr.record("handler")
+			for seq := 1; ; seq++ {
+				in, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				out, err := structpb.NewStruct(map[string]interface{}{
+					"seq":        float64(seq),
+					"text":       in.GetFields()["text"].GetStringValue(),
+					"request_id": logger.RequestIDFromContext(ctx),
+				})
+				if err != nil {
+					return err
+				}
+				if err := stream.Send(out); err != nil {
+					return err
+				}
+			}
+		}, tr.middleware("route"))
+	})
+	return server, conn, tr
+}
+
+// 上游元数据可见，x-request-id沿用上游值并回写到响应头
+func TestConformanceMetadata(t *testing.T) {
+	_, conn, _ := startSampleServer(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-sample", "value", strings.ToLower(logger.RequestIDHeader), requestIDSample)
+	var header metadata.MD
+	resp, err := invoke(ctx, conn, fullMethod("Echo"), nil, grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := resp.GetFields()
+	if got := fields["x-sample"].GetStringValue(); got != "value" {
+		t.Fatalf("服务端收到x-sample=%q", got)
+	}
+	if got := fields["request_id"].GetStringValue(); got != requestIDSample {
+		t.Fatalf("服务端请求ID为%q，期望沿用上游值", got)
+	}
+	if got := header.Get(logger.RequestIDHeader); len(got) == 0 || got[0] != requestIDSample {
+		t.Fatalf("响应头x-request-id为%v", got)
+	}
+}
+
+// 客户端截止时间传递到服务端，超时后返回DeadlineExceeded
+func TestConformanceDeadline(t *testing.T) {
+	_, conn, _ := startSampleServer(t)
+	echoCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	resp, err := invoke(echoCtx, conn, fullMethod("Echo"), nil)
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.GetFields()["deadline_ms"]; !ok {
+		t.Fatal("服务端context未携带客户端截止时间")
+	}
+	sleepCtx, cancel := context.WithTimeout(context.Background(), shortDeadline)
+	defer cancel()
+	if _, err := invoke(sleepCtx, conn, fullMethod("Sleep"), nil); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("超时调用返回%v，期望DeadlineExceeded", err)
+	}
+}
+
+// 处理器返回的status错误码原样透传，非status错误映射为Unknown，附加拦截器的拒绝原样返回
+func TestConformanceErrorMapping(t *testing.T) {
+	_, conn, _ := startSampleServer(t)
+	ctx := context.Background()
+	for _, code := range []codes.Code{codes.InvalidArgument, codes.NotFound, codes.PermissionDenied, codes.Internal} {
+		_, err := invoke(ctx, conn, fullMethod("Fail"), map[string]interface{}{"code": float64(code)})
+		if status.Code(err) != code {
+			t.Fatalf("期望%v，实际%v", code, err)
+		}
+	}
+	if _, err := invoke(ctx, conn, fullMethod("Fail"), map[string]interface{}{"code": float64(codes.OK)}); status.Code(err) != codes.Unknown {
+		t.Fatalf("非status错误期望Unknown，实际%v", err)
+	}
+	denyCtx := metadata.AppendToOutgoingContext(ctx, "x-deny", "1")
+	if _, err := invoke(denyCtx, conn, fullMethod("Echo"), nil); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("附加拦截器拒绝期望PermissionDenied，实际%v", err)
+	}
+}
+
+// 双向流经过框架中间件，消息按序往返，请求ID沿用上游值
+func TestConformanceBidiStreaming(t *testing.T) {
+	_, conn, tr := startSampleServer(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), strings.ToLower(logger.RequestIDHeader), requestIDSample)
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, methodChat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range []string{"a", "b", "c"} {
+		in, err := structpb.NewStruct(map[string]interface{}{"text": text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.SendMsg(in); err != nil {
+			t.Fatal(err)
+		}
+		out := new(structpb.Struct)
+		if err := stream.RecvMsg(out); err != nil {
+			t.Fatal(err)
+		}
+		fields := out.GetFields()
+		if int(fields["seq"].GetNumberValue()) != i+1 || fields["text"].GetStringValue() != text {
+			t.Fatalf("第%d条回显为%v", i+1, out.AsMap())
+		}
+		if got := fields["request_id"].GetStringValue(); got != requestIDSample {
+			t.Fatalf("流处理器请求ID为%q，期望沿用上游值", got)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.RecvMsg(new(structpb.Struct)); !errors.Is(err, io.EOF) {
+		t.Fatalf("流结束时返回%v，期望io.EOF", err)
+	}
+	if want, got := "global:before route:before handler route:after global:after", tr.take(); got != want {
+		t.Fatalf("执行顺序为[%s]，期望[%s]", got, want)
+	}
+}
+
+// 标准健康检查：整体与已注册服务为SERVING，未知服务返回NotFound，状态可切换，且不经过附加拦截器
+func TestConformanceHealth(t *testing.T) {
+	server, conn, _ := startSampleServer(t)
+	client := healthpb.NewHealthClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-deny", "1")
+	check := func(service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN, err
+		}
+		return resp.GetStatus(), nil
+	}
+	for _, service := range []string{"", testService} {
+		st, err := check(service)
+		if err != nil {
+			t.Fatalf("查询%q健康状态失败：%v", service, err)
+		}
+		if st != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("%q健康状态应为SERVING，实际为%s", service, st)
+		}
+	}
+	if _, err := check("dai.test.Unknown"); status.Code(err) != codes.NotFound {
+		t.Fatalf("未知服务应返回NotFound，实际为%v", err)
+	}
+	server.SetServingStatus(testService, false)
+	if st, err := check(testService); err != nil || st != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("切换后健康状态应为NOT_SERVING，实际为%s（%v）", st, err)
+	}
+}
+
+// 停机时在途请求正常完成，停机后新请求失败
+func TestConformanceGracefulShutdown(t *testing.T) {
+	server, conn, _ := startSampleServer(t)
+	ctx := context.Background()
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := invoke(ctx, conn, fullMethod("Sleep"), nil)
+		inflight <- err
+	}()
+	time.Sleep(sleepDuration / 3)
+	server.Stop()
+	if err := <-inflight; err != nil {
+		t.Fatalf("在途请求未正常完成：%v", err)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := invoke(callCtx, conn, fullMethod("Echo"), nil); err == nil {
+		t.Fatal("停机后新请求仍然成功")
+	}
+}
@@ -1,6 +1,7 @@
 package grpc
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/dfpopp/go-dai/netContext"
 	"google.golang.org/grpc/metadata"
@@ -21,6 +22,8 @@ type Context struct {
 	params   map[string]string      // 自定义参数（对齐HTTP/WS）
 	rawData  []byte                 // 原始请求数据（对齐HTTP Body/WS消息）
 	respData map[string]interface{} // 响应数据
+	ctx      context.Context        // gRPC请求context
+	rpc      func()                 // 执行该方法的gRPC处理器（由拦截器设置，路由处理器之后调用）
 }
 
 // NewContext 创建gRPC上下文实例
@@ -97,6 +100,19 @@ func (c *Context) GetRequestInfo() netContext.RequestInfo {
 	return c
 }
 
+// GetContext 获取gRPC请求context
+func (c *Context) GetContext() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// SetContext 替换请求context（拦截器注入链路信息等）
+func (c *Context) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
 func (c *Context) JSON(code int, data map[string]interface{}) {
 	c.respData = data
 }
@@ -180,6 +196,26 @@ func (c *Context) BindJSON(v interface{}) error {
 	return json.Unmarshal(c.rawData, v)
 }
 
+// Bind 合并绑定元数据中的查询参数（x-grpc-query-*）与请求数据到结构体（请求数据为JSON对象时按json标签，
+// 为key=value&...时按form标签；SetParam设置的参数按query标签绑定）
+func (c *Context) Bind(v interface{}) error {
+	query := url.Values{}
+	for key, vals := range c.MD {
+		if name, ok := strings.CutPrefix(key, "x-grpc-query-"); ok {
+			query[name] = vals
+		}
+	}
+	for key, val := range c.params {
+		query.Set(key, val)
+	}
+	return netContext.Bind(v, netContext.PayloadSources(query, c.rawData))
+}
+
+// Locale 按元数据accept-language协商的语言（与gRPC拦截器错误文案一致）
+func (c *Context) Locale() string {
+	return metadataLocale(c.MD)
+}
+
 func (c *Context) SetParam(key, value string) {
 	c.params[key] = value
 }
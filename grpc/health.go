@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"strings"
+)
+
+// 标准健康检查（grpc.health.v1.Health）：服务器创建时自动注册，整体状态（服务名为空）为SERVING，
+// 通过RegisterService/RegisterTyped/RegisterStream注册的服务在启动时置为SERVING，Stop时全部置为NOT_SERVING。
+// 负载均衡器与探针（如grpc_health_probe、Kubernetes gRPC探针）可直接查询，健康检查请求不经过附加拦截器（认证、限流、配额）。
+
+// healthServicePrefix 健康检查服务的方法前缀
+var healthServicePrefix = "/" + healthpb.Health_ServiceDesc.ServiceName + "/"
+
+// Health 标准健康检查服务（需要更细粒度的控制时直接使用）
+func (s *Server) Health() *health.Server {
+	return s.health
+}
+
+// SetServingStatus 设置服务的健康状态（service为完整服务名，如user.UserService；空字符串表示整体状态）
+func (s *Server) SetServingStatus(service string, serving bool) {
+	st := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		st = healthpb.HealthCheckResponse_SERVING
+	}
+	s.health.SetServingStatus(service, st)
+}
+
+// isHealthMethod 是否为健康检查方法
+func isHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, healthServicePrefix)
+}
+
+// chainUnaryInterceptors 按顺序组合一元拦截器
+func chainUnaryInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, final grpc.UnaryHandler) (interface{}, error) {
+		handler := final
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
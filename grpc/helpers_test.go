@@ -0,0 +1,83 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	daiGrpc "github.com/dfpopp/go-dai/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// testService 测试服务名（请求/响应均为structpb.Struct，无需proto生成代码）
+const testService = "dai.test.Sample"
+
+// structMethod 测试服务的一元方法
+type structMethod func(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+
+// startServer 在本地随机端口启动框架gRPC服务，注册testService的方法，返回已连接的客户端（测试结束时停止服务）
+func startServer(t *testing.T, cfg *daiGrpc.ServerConfig, methods map[string]structMethod, setup func(s *daiGrpc.Server)) (*daiGrpc.Server, *grpc.ClientConn) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Addr = lis.Addr().String()
+	server := daiGrpc.NewServerWithConfig(cfg)
+	server.SetListener(lis)
+	if setup != nil {
+		setup(server)
+	}
+	desc := &grpc.ServiceDesc{ServiceName: testService, HandlerType: (*interface{})(nil)}
+	for name, fn := range methods {
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{MethodName: name, Handler: unaryHandler(fullMethod(name), fn)})
+	}
+	server.RegisterService(desc, struct{}{})
+	go func() {
+		_ = server.Run()
+	}()
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		server.Stop()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+	return server, conn
+}
+
+func fullMethod(name string) string {
+	return "/" + testService + "/" + name
+}
+
+// unaryHandler 将业务函数适配为grpc.MethodDesc处理器（经过服务端拦截器）
+func unaryHandler(method string, fn structMethod) func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(structpb.Struct)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return fn(ctx, req.(*structpb.Struct))
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: method}, handler)
+	}
+}
+
+// invoke 以structpb.Struct调用方法
+func invoke(ctx context.Context, conn *grpc.ClientConn, method string, req map[string]interface{}, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	in, err := structpb.NewStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	out := new(structpb.Struct)
+	err = conn.Invoke(ctx, method, in, out, opts...)
+	return out, err
+}
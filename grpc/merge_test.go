@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"testing"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestMergeResponseRepeatedFieldReplaced(t *testing.T) {
+	resp := &descriptorpb.FileDescriptorProto{Name: proto.String("rpc.proto"), Dependency: []string{"a.proto"}}
+	merged := mergeResponse(resp, map[string]interface{}{"code": 200, "msg": "success", "data": map[string]interface{}{
+		"name": "frame.proto", "package": "frame", "dependency": []string{"x.proto", "y.proto"},
+	}}).(*descriptorpb.FileDescriptorProto)
+	if merged.GetName() != "rpc.proto" {
+		t.Fatalf("name=%q，期望以gRPC处理器的值为准", merged.GetName())
+	}
+	if merged.GetPackage() != "frame" {
+		t.Fatalf("package=%q，期望补充框架数据", merged.GetPackage())
+	}
+	if deps := merged.GetDependency(); len(deps) != 1 || deps[0] != "a.proto" {
+		t.Fatalf("dependency=%v，期望gRPC处理器的列表整体替换框架数据（不追加）", deps)
+	}
+	// 处理器未设置的repeated字段由框架数据填充
+	merged = mergeResponse(&descriptorpb.FileDescriptorProto{}, map[string]interface{}{
+		"data": map[string]interface{}{"dependency": []string{"x.proto"}},
+	}).(*descriptorpb.FileDescriptorProto)
+	if deps := merged.GetDependency(); len(deps) != 1 || deps[0] != "x.proto" {
+		t.Fatalf("dependency=%v，期望框架数据", deps)
+	}
+}
+
+func TestMergeResponseMapFieldReplaced(t *testing.T) {
+	resp := &healthpb.HealthListResponse{Statuses: map[string]*healthpb.HealthCheckResponse{
+		"a": {Status: healthpb.HealthCheckResponse_SERVING},
+	}}
+	merged := mergeResponse(resp, map[string]interface{}{"data": map[string]interface{}{
+		"statuses": map[string]interface{}{
+			"a": map[string]interface{}{"status": "NOT_SERVING"},
+			"b": map[string]interface{}{"status": "NOT_SERVING"},
+		},
+	}}).(*healthpb.HealthListResponse)
+	statuses := merged.GetStatuses()
+	if len(statuses) != 1 || statuses["a"].GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("statuses=%v，期望gRPC处理器的map整体替换框架数据（不按key合并）", statuses)
+	}
+	if len(resp.GetStatuses()) != 1 {
+		t.Fatal("合并不应修改原响应")
+	}
+}
+
+func TestMergeResponseNonProto(t *testing.T) {
+	resp := map[string]string{"k": "v"}
+	if got := mergeResponse(resp, map[string]interface{}{"data": map[string]interface{}{"k": "x"}}); got.(map[string]string)["k"] != "v" {
+		t.Fatal("非protobuf响应应原样返回")
+	}
+}
@@ -0,0 +1,255 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/go-redis/redis"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 调用配额：按调用方身份（元数据中的API Key/用户ID）统计滚动窗口内的请求次数与流量字节数，
+// 计数保存在Redis中（多实例共享），超出配额时返回RESOURCE_EXHAUSTED。
+// 窗口被切分为若干时间片，每个身份对应一个Hash（字段 r:<片号> 为请求数，b:<片号> 为字节数）。
+
+const (
+	quotaKeyPrefix    = "grpc_quota:" // Redis键前缀（会再拼接Redis表前缀）
+	defaultQuotaSlots = 60            // 窗口默认切分的时间片数
+)
+
+// QuotaLimit 单个身份的配额（<=0表示该项不限制）
+type QuotaLimit struct {
+	MaxRequests int64 `json:"max_requests"` // 窗口内最大请求数
+	MaxBytes    int64 `json:"max_bytes"`    // 窗口内最大流量（请求+响应的protobuf字节数）
+}
+
+// QuotaUsage 身份在当前窗口内的用量
+type QuotaUsage struct {
+	Identity string        `json:"identity"`
+	Requests int64         `json:"requests"`
+	Bytes    int64         `json:"bytes"`
+	Limit    QuotaLimit    `json:"limit"`
+	Window   time.Duration `json:"window"`
+}
+
+// QuotaOptions 配额参数
+type QuotaOptions struct {
+	Window          time.Duration         // 滚动窗口（默认1小时）
+	Slots           int                   // 窗口切分的时间片数（默认60，越大越精确）
+	Default         QuotaLimit            // 默认配额
+	Overrides       map[string]QuotaLimit // 按身份单独配置（如付费等级不同的API Key）
+	IdentityKeys    []string              // 读取身份的元数据key（按顺序取第一个非空值，默认 x-api-key、x-user-id）
+	RequireIdentity bool                  // 无法识别身份时是否拒绝（默认放行且不计量）
+}
+
+// Quota 调用配额管理器
+type Quota struct {
+	rdb  *redisDb.RedisDb
+	opts QuotaOptions
+}
+
+var (
+	quotaCheckScript = redis.NewScript(`local slot = tonumber(ARGV[1])
+local minSlot = slot - tonumber(ARGV[2]) + 1
+local fields = redis.call('HGETALL', KEYS[1])
+local reqs, bytes = 0, 0
+for i = 1, #fields, 2 do
+  local s = tonumber(string.sub(fields[i], 3))
+  if s == nil or s < minSlot then
+    redis.call('HDEL', KEYS[1], fields[i])
+  elseif string.sub(fields[i], 1, 1) == 'r' then
+    reqs = reqs + tonumber(fields[i + 1])
+  else
+    bytes = bytes + tonumber(fields[i + 1])
+  end
+end
+if ARGV[7] ~= '1' then return {1, reqs, bytes} end
+local maxReq, maxBytes, size = tonumber(ARGV[3]), tonumber(ARGV[4]), tonumber(ARGV[5])
+if (maxReq > 0 and reqs + 1 > maxReq) or (maxBytes > 0 and bytes + size > maxBytes) then
+  return {0, reqs, bytes}
+end
+redis.call('HINCRBY', KEYS[1], 'r:' .. slot, 1)
+if size > 0 then redis.call('HINCRBY', KEYS[1], 'b:' .. slot, size) end
+redis.call('PEXPIRE', KEYS[1], ARGV[6])
+return {1, reqs + 1, bytes + size}`)
+	quotaAddBytesScript = redis.NewScript(`redis.call('HINCRBY', KEYS[1], 'b:' .. ARGV[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1`)
+)
+
+// NewQuota 创建调用配额管理器
+func NewQuota(rdb *redisDb.RedisDb, opts QuotaOptions) *Quota {
+	if opts.Window <= 0 {
+		opts.Window = time.Hour
+	}
+	if opts.Slots <= 0 {
+		opts.Slots = defaultQuotaSlots
+	}
+	if len(opts.IdentityKeys) == 0 {
+		opts.IdentityKeys = []string{"x-api-key", "x-user-id"}
+	}
+	return &Quota{rdb: rdb, opts: opts}
+}
+
+// Interceptor 配额拦截器（可加入ServerConfig.UnaryInterceptors）
+func (q *Quota) Interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		identity := q.identity(md)
+		if identity == "" {
+			if q.opts.RequireIdentity {
+				return nil, status.Error(codes.Unauthenticated, "missing client identity")
+			}
+			return handler(ctx, req)
+		}
+		allowed, usage, err := q.consume(ctx, identity, messageSize(req))
+		if err != nil {
+			logger.FromContext(ctx).Warn("gRPC配额计量失败，已放行：", err, "身份：", identity)
+			return handler(ctx, req)
+		}
+		if !allowed {
+			return nil, status.Errorf(codes.ResourceExhausted, "quota exceeded: %d requests, %d bytes used in last %s", usage.Requests, usage.Bytes, q.opts.Window)
+		}
+		resp, err := handler(ctx, req)
+		if size := messageSize(resp); err == nil && size > 0 {
+			if addErr := q.addBytes(ctx, identity, size); addErr != nil {
+				logger.FromContext(ctx).Warn("gRPC配额记录响应流量失败：", addErr, "身份：", identity)
+			}
+		}
+		return resp, err
+	}
+}
+
+// Usage 查询身份在当前窗口内的用量
+func (q *Quota) Usage(ctx context.Context, identity string) (QuotaUsage, error) {
+	res, err := quotaCheckScript.Run(q.rdb.WithContext(ctx).Db, []string{q.key(identity)},
+		q.slot(), q.opts.Slots, 0, 0, 0, q.opts.Window.Milliseconds(), "0").Result()
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+	_, usage := q.parseResult(identity, res)
+	return usage, nil
+}
+
+// Reset 清空身份的用量（如付费充值后）
+func (q *Quota) Reset(ctx context.Context, identity string) error {
+	return q.rdb.WithContext(ctx).Db.Del(q.key(identity)).Err()
+}
+
+// Limit 获取身份适用的配额
+func (q *Quota) Limit(identity string) QuotaLimit {
+	if limit, ok := q.opts.Overrides[identity]; ok {
+		return limit
+	}
+	return q.opts.Default
+}
+
+// AdminHandler 用量管理接口（标准net/http处理器，需由调用方挂载并自行做好鉴权）：
+// GET ?identity=xxx 查询用量，DELETE ?identity=xxx 清空用量
+func (q *Quota) AdminHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := r.URL.Query().Get("identity")
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+		if identity == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 400, "msg": "缺少identity参数", "data": nil})
+			return
+		}
+		var (
+			data interface{}
+			err  error
+		)
+		switch r.Method {
+		case http.MethodGet:
+			data, err = q.Usage(r.Context(), identity)
+		case http.MethodDelete:
+			err = q.Reset(r.Context(), identity)
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 500, "msg": err.Error(), "data": nil})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "msg": "success", "data": data})
+	}
+}
+
+// consume 校验配额并记录本次请求
+func (q *Quota) consume(ctx context.Context, identity string, size int64) (bool, QuotaUsage, error) {
+	limit := q.Limit(identity)
+	res, err := quotaCheckScript.Run(q.rdb.WithContext(ctx).Db, []string{q.key(identity)},
+		q.slot(), q.opts.Slots, limit.MaxRequests, limit.MaxBytes, size, q.opts.Window.Milliseconds(), "1").Result()
+	if err != nil {
+		return false, QuotaUsage{}, err
+	}
+	allowed, usage := q.parseResult(identity, res)
+	return allowed, usage, nil
+}
+
+// addBytes 记录响应流量
+func (q *Quota) addBytes(ctx context.Context, identity string, size int64) error {
+	return quotaAddBytesScript.Run(q.rdb.WithContext(ctx).Db, []string{q.key(identity)},
+		q.slot(), size, q.opts.Window.Milliseconds()).Err()
+}
+
+func (q *Quota) parseResult(identity string, res interface{}) (bool, QuotaUsage) {
+	usage := QuotaUsage{Identity: identity, Limit: q.Limit(identity), Window: q.opts.Window}
+	values, _ := res.([]interface{})
+	if len(values) < 3 {
+		return true, usage
+	}
+	allowed, _ := values[0].(int64)
+	usage.Requests, _ = values[1].(int64)
+	usage.Bytes, _ = values[2].(int64)
+	return allowed == 1, usage
+}
+
+// identity 从元数据中读取调用方身份
+func (q *Quota) identity(md metadata.MD) string {
+	for _, key := range q.opts.IdentityKeys {
+		if values := md.Get(key); len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+			return strings.TrimSpace(values[0])
+		}
+	}
+	return ""
+}
+
+func (q *Quota) key(identity string) string {
+	return q.rdb.DbPre + quotaKeyPrefix + identity
+}
+
+// slot 当前时间片编号
+func (q *Quota) slot() int64 {
+	slotMs := q.opts.Window.Milliseconds() / int64(q.opts.Slots)
+	if slotMs <= 0 {
+		slotMs = 1
+	}
+	return time.Now().UnixMilli() / slotMs
+}
+
+// messageSize 消息的protobuf编码大小（非protobuf消息按JSON长度估算）
+func messageSize(msg interface{}) int64 {
+	if msg == nil {
+		return 0
+	}
+	if m, ok := msg.(proto.Message); ok {
+		return int64(proto.Size(m))
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"strconv"
+)
+
+// RateLimitInterceptor 限流拦截器：超限时返回ResourceExhausted，并通过响应头retry-after告知建议等待秒数
+func RateLimitInterceptor(policy *ratelimit.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if policy == nil {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		peerInfo, _ := peer.FromContext(ctx)
+		c := NewContext(md, peerInfo, info.FullMethod, nil)
+		c.SetContext(ctx)
+		result := policy.Check(c)
+		if !result.Allowed {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(result.RetryAfterSeconds())))
+			return nil, status.Error(codes.ResourceExhausted, i18n.T(metadataLocale(md), i18n.MsgRateLimited))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor 流限流拦截器：在建立流时按同一策略计数（流内的消息不计数）
+func RateLimitStreamInterceptor(policy *ratelimit.Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if policy == nil {
+			return handler(srv, ss)
+		}
+		ctx := ss.Context()
+		md, _ := metadata.FromIncomingContext(ctx)
+		peerInfo, _ := peer.FromContext(ctx)
+		c := NewContext(md, peerInfo, info.FullMethod, nil)
+		c.SetContext(ctx)
+		result := policy.Check(c)
+		if !result.Allowed {
+			_ = ss.SetHeader(metadata.Pairs("retry-after", strconv.Itoa(result.RetryAfterSeconds())))
+			return status.Error(codes.ResourceExhausted, i18n.T(metadataLocale(md), i18n.MsgRateLimited))
+		}
+		return handler(srv, ss)
+	}
+}
+
+// metadataLocale 按元数据accept-language协商语言
+func metadataLocale(md metadata.MD) string {
+	if values := md.Get("accept-language"); len(values) > 0 {
+		return i18n.Negotiate(values[0])
+	}
+	return i18n.DefaultLocale()
+}
@@ -0,0 +1,55 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dfpopp/go-dai/config"
+	daiGrpc "github.com/dfpopp/go-dai/grpc"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// userInterceptor 模拟认证拦截器：按元数据x-user写入限流用户
+func userInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if users := md.Get("x-user"); len(users) > 0 {
+		ctx = ratelimit.WithUser(ctx, users[0])
+	}
+	return handler(ctx, req)
+}
+
+func TestRateLimitRunsAfterAuthInterceptor(t *testing.T) {
+	policy, err := ratelimit.NewPolicy(config.RateLimitConfig{Enable: true, Rate: 1, Period: 60, Key: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, conn := startServer(t, &daiGrpc.ServerConfig{
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{userInterceptor},
+		RateLimit:         policy,
+	}, map[string]structMethod{
+		"Ping": func(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+			return structpb.NewStruct(nil)
+		},
+	}, nil)
+
+	call := func(user string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user", user)
+		_, err := invoke(ctx, conn, fullMethod("Ping"), nil)
+		return err
+	}
+	// 同一连接（同一对端IP）的不同用户分别计数
+	if err := call("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("bob"); err != nil {
+		t.Fatalf("限流未读取到认证拦截器写入的用户：%v", err)
+	}
+	if err := call("alice"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("同一用户超限应返回ResourceExhausted，got %v", err)
+	}
+}
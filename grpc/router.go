@@ -23,9 +23,21 @@ func (r *Router) Use(middlewares ...MiddlewareFunc) {
 	r.middlewares = append(r.middlewares, middlewares...)
 }
 
-// Register 注册gRPC路由
+// Register 注册gRPC路由：处理器执行后（未以错误码响应时）继续执行该方法的gRPC处理器，
+// 中间件的后置逻辑在gRPC处理器完成后执行
 func (r *Router) Register(method string, handler HandlerFunc, chain []MiddlewareFunc) {
-	r.handlers[method] = buildChain(chain, handler)
+	r.handlers[method] = buildChain(chain, func(c *Context) {
+		handler(c)
+		if c.rpc != nil && !responseFailed(c.respData) {
+			c.rpc()
+		}
+	})
+}
+
+// route 获取方法对应的路由（已包含中间件链）
+func (r *Router) route(method string) (HandlerFunc, bool) {
+	handler, ok := r.handlers[method]
+	return handler, ok
 }
 
 // Dispatch 路由分发
@@ -53,3 +65,9 @@ func buildChain(middlewares []MiddlewareFunc, final HandlerFunc) HandlerFunc {
 	}
 	return final
 }
+
+// responseFailed 框架响应是否为错误码（>=400）
+func responseFailed(resp map[string]interface{}) bool {
+	code, _ := resp["code"].(int)
+	return code >= 400
+}
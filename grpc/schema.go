@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/schema"
+	"net/http"
+)
+
+// RequestSubject 强类型方法请求的schema subject（如/user.UserService/GetUser#request）
+func RequestSubject(method string) string {
+	return method + "#request"
+}
+
+// ResponseSubject 强类型方法响应的schema subject
+func ResponseSubject(method string) string {
+	return method + "#response"
+}
+
+// RegisterMethodSchemas 将强类型方法的请求/响应类型登记到注册中心（服务启动时调用，结构变更不兼容时返回*schema.IncompatibleError）。
+// reg为nil时使用schema.Default()，均未启用时不做任何事
+//
+//	if err := grpc.RegisterMethodSchemas[GetUserReq, GetUserResp](ctx, nil, "/user.UserService/GetUser"); err != nil {
+//		panic(err)
+//	}
+func RegisterMethodSchemas[TReq, TResp any](ctx context.Context, reg *schema.Registry, method string) error {
+	if reg == nil {
+		if reg = schema.Default(); reg == nil {
+			return nil
+		}
+	}
+	if _, err := reg.RegisterType(ctx, RequestSubject(method), new(TReq)); err != nil {
+		return err
+	}
+	_, err := reg.RegisterType(ctx, ResponseSubject(method), new(TResp))
+	return err
+}
+
+// ValidateSchema 框架中间件：按注册中心中subject的最新版本校验请求（subject为空时使用RequestSubject(方法名)），
+// 不符合时返回InvalidArgument；subject未登记或注册中心不可用时放行并记录日志。reg为nil时使用schema.Default()
+func ValidateSchema(reg *schema.Registry, subject string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			r := reg
+			if r == nil {
+				r = schema.Default()
+			}
+			if r == nil {
+				next(c)
+				return
+			}
+			name := subject
+			if name == "" {
+				name = RequestSubject(c.GetMethod())
+			}
+			body, _ := c.GetBody()
+			if len(body) == 0 {
+				body = []byte("{}")
+			}
+			if _, err := r.Validate(c.GetContext(), name, body); err != nil {
+				var invalid *schema.ValidationError
+				if errors.As(err, &invalid) {
+					c.JSON(http.StatusBadRequest, map[string]interface{}{"code": http.StatusBadRequest, "msg": err.Error()})
+					return
+				}
+				if !errors.Is(err, schema.ErrNotFound) {
+					logger.FromContext(c.GetContext()).Warn("gRPC请求结构校验失败，已放行：", err)
+				}
+			}
+			next(c)
+		}
+	}
+}
@@ -2,17 +2,33 @@ package grpc
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"github.com/dfpopp/go-dai/auth"
 	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
 	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"net"
+	"runtime/debug"
+	"sync"
 	"time"
 )
 
@@ -27,6 +43,33 @@ type ServerConfig struct {
 	SSL            bool          // 是否启用SSL
 	SSLCertFile    string        // SSL证书路径
 	SSLKeyFile     string        // SSL密钥路径
+	// UnaryInterceptors 附加的一元拦截器（在框架拦截器之后按顺序执行，如JWTAuthInterceptor）
+	UnaryInterceptors []grpc.UnaryServerInterceptor
+	// StreamInterceptors 附加的流拦截器（在框架流拦截器之后按顺序执行，如JWTAuthStreamInterceptor）
+	StreamInterceptors []grpc.StreamServerInterceptor
+	// RateLimit 限流策略（在附加的一元/流拦截器之后执行，可读取认证拦截器写入的用户；为nil时不限流）
+	RateLimit *ratelimit.Policy
+	// Quota 调用配额（为nil时不计量）
+	Quota *Quota
+	// DisableReflection 不注册反射服务（配置grpc.reflection，生产环境默认关闭）
+	DisableReflection bool
+
+	MaxConcurrentStreams uint32 // 每个连接的最大并发流数（0使用gRPC默认值，不限制）
+	MaxConnections       int    // 最大同时连接数（超出时新连接在accept处排队等待，0不限制）
+
+	// 服务端保活与连接寿命（0使用gRPC默认值）
+	KeepaliveTime         time.Duration // 连接空闲多久后服务端发送ping（默认2小时）
+	KeepaliveTimeout      time.Duration // 等待ping响应的时长，超时关闭连接（默认20秒）
+	MaxConnectionIdle     time.Duration // 无活跃调用的连接空闲多久后以GOAWAY关闭（默认不限制）
+	MaxConnectionAge      time.Duration // 连接最长存活时间，到期以GOAWAY关闭，使客户端重连后重新均衡（默认不限制）
+	MaxConnectionAgeGrace time.Duration // 到达MaxConnectionAge后等待进行中调用完成的时长（默认不限制）
+
+	// 客户端保活策略（超出限制的客户端ping会收到GOAWAY too_many_pings）
+	KeepaliveMinTime             time.Duration // 允许的客户端ping最小间隔（默认5分钟）
+	KeepalivePermitWithoutStream bool          // 是否允许客户端在没有活跃调用时发送ping
+
+	// MTLS 客户端证书校验（SSL启用时生效，为nil时不校验）
+	MTLS *auth.MTLSOptions
 }
 
 // Server gRPC服务器（门面角色，对齐HTTP/WS Server）
@@ -35,29 +78,47 @@ type Server struct {
 	router     *Router
 	GrpcServer *grpc.Server
 	services   map[string]interface{} // 存储注册的gRPC服务
+	listener   net.Listener           // 外部指定的监听器（为nil时按配置地址监听）
+	health     *health.Server         // 标准健康检查服务
+	startErr   error                  // 构建服务器时的错误（如SSL证书加载失败），Run时返回
+
+	typedMu       sync.Mutex
+	typedServices map[string]*grpc.ServiceDesc // RegisterTyped注册的服务（Run时注册到GrpcServer）
 }
 
-// NewServer 创建gRPC服务器实例
-func NewServer(appName string) *Server {
+// NewServer 创建gRPC服务器实例（interceptors为附加的一元拦截器，如JWTAuthInterceptor）
+func NewServer(appName string, interceptors ...grpc.UnaryServerInterceptor) *Server {
 	cfg := loadServerConfig(appName)
+	cfg.UnaryInterceptors = append(cfg.UnaryInterceptors, interceptors...)
+	return NewServerWithConfig(cfg)
+}
+
+// NewServerWithConfig 使用指定配置创建gRPC服务器实例（不依赖应用配置文件，便于嵌入与测试）
+func NewServerWithConfig(cfg *ServerConfig) *Server {
 	setDefaultConfig(cfg)
-	router := NewRouter()
+	s := &Server{
+		config:   cfg,
+		router:   NewRouter(),
+		services: make(map[string]interface{}),
+	}
 
-	// 构建gRPC服务器选项
-	opts := buildServerOptions(cfg)
+	// 构建gRPC服务器选项（框架拦截器绑定当前Server，按路由执行中间件与处理器）
+	opts, err := buildServerOptions(cfg, s.unaryInterceptor)
+	s.startErr = err
 
 	// 创建原生gRPC服务器
-	grpcServer := grpc.NewServer(opts...)
+	s.GrpcServer = grpc.NewServer(opts...)
 
-	// 新增：注册反射服务（核心！启用后测试工具可自动获取接口定义）
-	reflection.Register(grpcServer)
+	// 注册标准健康检查服务（整体状态默认SERVING）
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(s.GrpcServer, s.health)
 
-	return &Server{
-		config:     cfg,
-		router:     router,
-		GrpcServer: grpcServer,
-		services:   make(map[string]interface{}),
+	// 注册反射服务（启用后grpcurl等测试工具可自动获取接口定义，生产环境默认关闭）
+	if !cfg.DisableReflection {
+		reflection.Register(s.GrpcServer)
 	}
+
+	return s
 }
 
 // Config 暴露配置
@@ -65,6 +126,11 @@ func (s *Server) Config() *ServerConfig {
 	return s.config
 }
 
+// Quota 获取调用配额管理器（未启用时返回nil，可用于挂载用量管理接口）
+func (s *Server) Quota() *Quota {
+	return s.config.Quota
+}
+
 // Use 注册全局中间件
 func (s *Server) Use(middlewares ...MiddlewareFunc) {
 	s.router.Use(middlewares...)
@@ -76,58 +142,70 @@ func (s *Server) Register(method string, handler HandlerFunc, middlewares ...Mid
 	s.router.Register(method, handler, chain)
 }
 
+// UseStreamInterceptors 追加流拦截器（需在Run之前调用；NewServer的附加拦截器仅作用于一元调用，流式方法的认证等需通过此方法注册）
+func (s *Server) UseStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) {
+	s.config.StreamInterceptors = append(s.config.StreamInterceptors, interceptors...)
+}
+
 // RegisterService 注册gRPC服务（兼容标准gRPC注册逻辑，保证应用层正常使用）
 func (s *Server) RegisterService(sd *grpc.ServiceDesc, ss interface{}) {
 	// 1. 标准gRPC服务注册
 	s.GrpcServer.RegisterService(sd, ss)
 	// 2. 存储服务实例，供框架内部使用
 	s.services[sd.ServiceName] = ss
+	s.SetServingStatus(sd.ServiceName, true)
 	logger.Info("gRPC服务注册成功：", sd.ServiceName)
 }
 
+// SetListener 指定监听器（需在Run之前调用，SSL仍由服务端credentials处理）
+func (s *Server) SetListener(lis net.Listener) {
+	s.listener = lis
+}
+
+// Listener 获取外部指定的监听器
+func (s *Server) Listener() net.Listener {
+	return s.listener
+}
+
 // Run 启动gRPC服务器
 func (s *Server) Run() error {
-	lis, err := s.createListener()
-	if err != nil {
-		return fmt.Errorf("create gRPC listener failed: %w", err)
+	if s.startErr != nil {
+		return fmt.Errorf("gRPC server config invalid: %w", s.startErr)
+	}
+	lis := s.listener
+	if lis == nil {
+		var err error
+		lis, err = s.createListener()
+		if err != nil {
+			return fmt.Errorf("create gRPC listener failed: %w", err)
+		}
+	}
+	if s.config.MaxConnections > 0 {
+		lis = newLimitListener(lis, s.config.MaxConnections)
 	}
 	defer lis.Close()
+	s.registerTypedServices()
 
 	logger.Info("gRPC服务器启动成功，监听地址：", s.config.Addr)
 	return s.GrpcServer.Serve(lis)
 }
 
-// Stop 停止gRPC服务器
+// Stop 停止gRPC服务器（先将健康状态置为NOT_SERVING，再等待进行中的调用完成）
 func (s *Server) Stop() {
 	logger.Info("gRPC服务器正在停止...")
+	s.health.Shutdown()
 	s.GrpcServer.GracefulStop()
 	logger.Info("gRPC服务器已停止")
 }
 
 // 内部方法：创建监听器
 func (s *Server) createListener() (net.Listener, error) {
-	if s.config.SSL {
-		if s.config.SSLCertFile == "" || s.config.SSLKeyFile == "" {
-			return nil, fmt.Errorf("SSL enabled but cert/key file is empty")
-		}
-		// 加载证书
-		cert, err := tls.LoadX509KeyPair(s.config.SSLCertFile, s.config.SSLKeyFile)
-		if err != nil {
-			return nil, fmt.Errorf("load SSL cert failed: %w", err)
-		}
-		// 配置TLS
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
-		}
-		return tls.Listen("tcp", s.config.Addr, tlsConfig)
-	}
-	// 非SSL模式：普通TCP监听
+	// 普通TCP监听，SSL由服务端credentials处理（与SetListener一致）
 	return net.Listen("tcp", s.config.Addr)
 }
 
-// 内部方法：构建gRPC服务器选项
-func buildServerOptions(cfg *ServerConfig) []grpc.ServerOption {
+// 内部方法：构建gRPC服务器选项（unary为框架一元拦截器，位于拦截器链首位）
+func buildServerOptions(cfg *ServerConfig, unary grpc.UnaryServerInterceptor) ([]grpc.ServerOption, error) {
 	var opts []grpc.ServerOption
 
 	// 设置消息大小限制
@@ -137,79 +215,216 @@ func buildServerOptions(cfg *ServerConfig) []grpc.ServerOption {
 	if cfg.MaxSendMsgSize > 0 {
 		opts = append(opts, grpc.MaxSendMsgSize(cfg.MaxSendMsgSize))
 	}
+	if cfg.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(cfg.MaxConcurrentStreams))
+	}
+	opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+		Time:                  cfg.KeepaliveTime,
+		Timeout:               cfg.KeepaliveTimeout,
+		MaxConnectionIdle:     cfg.MaxConnectionIdle,
+		MaxConnectionAge:      cfg.MaxConnectionAge,
+		MaxConnectionAgeGrace: cfg.MaxConnectionAgeGrace,
+	}))
+	if cfg.KeepaliveMinTime > 0 || cfg.KeepalivePermitWithoutStream {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.KeepaliveMinTime,
+			PermitWithoutStream: cfg.KeepalivePermitWithoutStream,
+		}))
+	}
 
-	// SSL配置：创建credentials并传入gRPC选项（修复creds未使用问题）
+	// SSL配置：创建credentials并传入gRPC选项（配置mtls时要求并校验客户端证书）；
+	// 证书加载失败时不降级为明文，错误在Run时返回
+	var tlsErr error
 	if cfg.SSL {
-		if cfg.SSLCertFile == "" || cfg.SSLKeyFile == "" {
-			logger.Warn("SSL enabled but cert/key file is empty, skip SSL config")
-			return opts
-		}
-		// 加载证书并创建gRPC credentials
-		cert, err := tls.LoadX509KeyPair(cfg.SSLCertFile, cfg.SSLKeyFile)
+		tlsConfig, err := auth.ServerTLSConfig(cfg.SSLCertFile, cfg.SSLKeyFile, cfg.MTLS)
 		if err != nil {
+			tlsErr = err
 			logger.Error("load SSL cert failed when build server options: ", err)
-			return opts
+		} else {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
 		}
-		creds := credentials.NewServerTLSFromCert(&cert)
-		opts = append(opts, grpc.Creds(creds)) // 正确使用creds，传入gRPC服务器选项
 	}
 
-	// 注册通用拦截器（适配框架上下文）
-	opts = append(opts, grpc.UnaryInterceptor(unaryInterceptor))
+	// 注册通用拦截器（适配框架上下文），健康检查请求跳过附加拦截器（认证、限流、配额）
+	// 限流在附加拦截器（含认证）之后执行，按用户限流时可读取认证写入的用户
+	interceptors := append([]grpc.UnaryServerInterceptor{}, cfg.UnaryInterceptors...)
+	if cfg.RateLimit != nil {
+		interceptors = append(interceptors, RateLimitInterceptor(cfg.RateLimit))
+	}
+	if cfg.Quota != nil {
+		interceptors = append(interceptors, cfg.Quota.Interceptor())
+	}
+	extra := chainUnaryInterceptors(interceptors)
+	opts = append(opts, grpc.ChainUnaryInterceptor(unary, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isHealthMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		return extra(ctx, req, info, handler)
+	}))
+	// 流拦截器：框架拦截器之后执行配置中的流拦截器（运行时读取，UseStreamInterceptors追加的拦截器同样生效）
+	opts = append(opts, grpc.ChainStreamInterceptor(streamInterceptor, func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isHealthMethod(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		streams := cfg.StreamInterceptors
+		if cfg.RateLimit != nil {
+			streams = append(streams[:len(streams):len(streams)], RateLimitStreamInterceptor(cfg.RateLimit))
+		}
+		return chainStreamInterceptors(streams, handler)(srv, ss, info)
+	}))
 
-	return opts
+	return opts, tlsErr
+}
+
+// chainStreamInterceptors 按顺序组合流拦截器
+func chainStreamInterceptors(interceptors []grpc.StreamServerInterceptor, final grpc.StreamHandler) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo) error {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo) error {
+		handler := final
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, next)
+			}
+		}
+		return handler(srv, ss)
+	}
 }
 
-// 通用gRPC拦截器（转换为框架上下文）
-func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+// unaryInterceptor 通用gRPC一元拦截器：链路追踪与请求ID，方法通过Register注册了框架路由时，
+// 按洋葱模型执行 中间件 -> 路由处理器 -> gRPC处理器，并将框架响应合并到gRPC响应中。
+// 中间件未调用next、或路由处理器以错误码（>=400）响应时不再执行gRPC处理器，按响应code映射状态码返回
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 	// 1. 获取元数据和客户端信息
 	md, _ := metadata.FromIncomingContext(ctx)
 	peerInfo, _ := peer.FromContext(ctx)
 
-	// 2. 序列化请求数据（作为原始数据）
-	rawData, _ := json.Marshal(req)
+	// 链路追踪：从元数据中提取上游traceparent并开启服务端span
+	if tracing.Enabled() {
+		ctx = tracing.Extract(ctx, metadataCarrier(md))
+		var span trace.Span
+		ctx, span = tracing.StartServerSpan(ctx, info.FullMethod, attribute.String("rpc.method", info.FullMethod))
+		defer func() {
+			tracing.End(span, err)
+		}()
+	}
 
-	// 3. 创建框架gRPC上下文
-	grpcCtx := NewContext(md, peerInfo, info.FullMethod, rawData)
+	// 请求ID：沿用上游元数据x-request-id或生成新ID，写入响应头并绑定请求级日志
+	requestID := ""
+	if values := md.Get(logger.RequestIDHeader); len(values) > 0 {
+		requestID = values[0]
+	}
+	if requestID == "" {
+		requestID = logger.NewRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(logger.RequestIDHeader, requestID))
+	ctx, buf := logger.StartBuffer(logger.WithRequestID(ctx, requestID))
+	if buf != nil {
+		defer finishLogBuffer(buf, &err)
+	}
+	ctx = netContext.WithRequestCache(ctx)
+	ctx = auth.WithClientIdentity(ctx, peerIdentity(peerInfo))
 
-	// 4. 路由分发（执行中间件和处理器）
-	server := extractServerFromContext(ctx) // 实际项目中可通过上下文传递Server实例
-	if server != nil {
-		_ = server.router.Dispatch(grpcCtx)
+	// 2. 未注册框架路由的方法（标准proto服务、RegisterTyped方法等）直接执行gRPC处理器
+	route, ok := s.router.route(info.FullMethod)
+	if !ok {
+		resp, err = handler(ctx, req)
+		if err != nil {
+			logger.FromContext(ctx).Error("gRPC handler error: ", err)
+		}
+		return resp, err
 	}
 
-	// 5. 执行原始gRPC处理器
-	resp, err := handler(ctx, req)
+	// 3. 创建框架gRPC上下文（请求数据序列化为原始数据）
+	rawData, _ := json.Marshal(req)
+	grpcCtx := NewContext(md, peerInfo, info.FullMethod, rawData)
+	grpcCtx.SetContext(ctx)
+	called := false
+	grpcCtx.rpc = func() {
+		called = true
+		// 中间件可能通过SetContext替换了context（注入认证信息等）
+		resp, err = handler(grpcCtx.GetContext(), req)
+	}
+
+	// 4. 执行中间件、路由处理器与gRPC处理器（panic时返回Internal）
+	if panicErr := dispatchRoute(route, grpcCtx); panicErr != nil {
+		return nil, panicErr
+	}
+	if !called {
+		return nil, responseStatus(grpcCtx.GetResponse())
+	}
 	if err != nil {
-		logger.Error("gRPC handler error: ", err)
+		logger.FromContext(ctx).Error("gRPC handler error: ", err)
 		return resp, err
 	}
 
-	// 6. 合并框架响应数据
+	// 5. 合并框架响应数据
 	return mergeResponse(resp, grpcCtx.GetResponse()), nil
 }
 
-// 内部方法：提取Server实例（简化实现，实际可通过自定义上下文传递）
-func extractServerFromContext(ctx context.Context) *Server {
-	// 此处为简化实现，实际项目中可通过grpc.NewContextWithValue传递Server实例
+// dispatchRoute 执行路由（捕获中间件与处理器的panic）
+func dispatchRoute(route HandlerFunc, c *Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(c.GetContext()).Error("gRPC路由处理器panic：", c.Method, " ", r, "\n", string(debug.Stack()))
+			err = status.Error(codes.Internal, "服务器内部错误")
+		}
+	}()
+	route(c)
 	return nil
 }
 
-// 内部方法：合并响应数据
+// structFieldsName google.protobuf.Struct的fields字段（其key即JSON名称，合并时按key处理）
+const structFieldsName protoreflect.FullName = "google.protobuf.Struct.fields"
+
+// mergeResponse 合并框架响应：框架处理器通过c.JSON写出的data（无data时为除code/msg外的字段）按JSON名称
+// 填充到gRPC响应消息中，gRPC处理器已设置的字段整体优先（repeated、map与消息字段不与框架数据合并）；
+// google.protobuf.Struct响应的key即JSON名称，按key合并。响应不是protobuf消息或合并失败时原样返回
 func mergeResponse(originResp interface{}, frameResp map[string]interface{}) interface{} {
-	// 兼容原有响应和框架响应，保持一致性
-	if frameResp == nil || len(frameResp) == 0 {
+	if len(frameResp) == 0 {
 		return originResp
 	}
-	// 实际项目中可根据业务需求合并响应字段
-	return originResp
+	msg, ok := originResp.(proto.Message)
+	if !ok || msg == nil {
+		return originResp
+	}
+	payload, ok := frameResp["data"].(map[string]interface{})
+	if !ok {
+		payload = make(map[string]interface{}, len(frameResp))
+		for k, v := range frameResp {
+			if k != "code" && k != "msg" && k != "data" {
+				payload[k] = v
+			}
+		}
+	}
+	if len(payload) == 0 {
+		return originResp
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return originResp
+	}
+	merged := msg.ProtoReflect().New().Interface()
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, merged); err != nil {
+		logger.Warn("gRPC框架响应合并失败：", err)
+		return originResp
+	}
+	// proto.Merge对repeated字段追加、对map字段按key合并、对消息字段递归合并，先清除处理器已设置的字段使其整体替换框架数据
+	dst := merged.ProtoReflect()
+	msg.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.FullName() != structFieldsName {
+			dst.Clear(fd)
+		}
+		return true
+	})
+	proto.Merge(merged, msg)
+	return merged
 }
 
 // 内部方法：加载配置
 func loadServerConfig(appName string) *ServerConfig {
 	appCfg := config.GetAppConfig(appName)
 	grpcCfg := appCfg.GRPC
-	return &ServerConfig{
+	cfg := &ServerConfig{
 		Addr:           grpcCfg.Addr,
 		Timeout:        time.Duration(grpcCfg.Timeout) * time.Second,
 		MaxRecvMsgSize: grpcCfg.MaxRecvMsgSize,
@@ -217,7 +432,47 @@ func loadServerConfig(appName string) *ServerConfig {
 		SSL:            grpcCfg.SSL,
 		SSLCertFile:    grpcCfg.SSLCertFile,
 		SSLKeyFile:     grpcCfg.SSLKeyFile,
+
+		MaxConcurrentStreams:         grpcCfg.MaxConcurrentStreams,
+		MaxConnections:               grpcCfg.MaxConnections,
+		KeepaliveTime:                time.Duration(grpcCfg.KeepaliveTime) * time.Second,
+		KeepaliveTimeout:             time.Duration(grpcCfg.KeepaliveTimeout) * time.Second,
+		MaxConnectionIdle:            time.Duration(grpcCfg.MaxConnectionIdle) * time.Second,
+		MaxConnectionAge:             time.Duration(grpcCfg.MaxConnectionAge) * time.Second,
+		MaxConnectionAgeGrace:        time.Duration(grpcCfg.MaxConnectionAgeGrace) * time.Second,
+		KeepaliveMinTime:             time.Duration(grpcCfg.KeepaliveMinTime) * time.Second,
+		KeepalivePermitWithoutStream: grpcCfg.KeepalivePermitWithoutStream,
+		MTLS:                         auth.MTLSOptionsFromConfig(grpcCfg.MTLS),
+	}
+	// 反射服务：未配置时仅在非生产环境开启
+	if grpcCfg.Reflection != nil {
+		cfg.DisableReflection = !*grpcCfg.Reflection
+	} else {
+		cfg.DisableReflection = appCfg.Env == "prod"
+	}
+	if policy, err := ratelimit.FromAppConfig(appName); err != nil {
+		logger.Error("gRPC限流配置无效：", err)
+	} else {
+		cfg.RateLimit = policy
+	}
+	if quotaCfg := grpcCfg.Quota; quotaCfg.Enable {
+		if rdb, err := redisDb.GetRedisDB(quotaCfg.RedisDb); err != nil {
+			logger.Error("gRPC配额初始化失败：", err)
+		} else {
+			overrides := make(map[string]QuotaLimit, len(quotaCfg.Overrides))
+			for identity, limit := range quotaCfg.Overrides {
+				overrides[identity] = QuotaLimit{MaxRequests: limit.MaxRequests, MaxBytes: limit.MaxBytes}
+			}
+			cfg.Quota = NewQuota(rdb, QuotaOptions{
+				Window:          time.Duration(quotaCfg.Window) * time.Second,
+				Default:         QuotaLimit{MaxRequests: quotaCfg.MaxRequests, MaxBytes: quotaCfg.MaxBytes},
+				Overrides:       overrides,
+				IdentityKeys:    quotaCfg.IdentityKeys,
+				RequireIdentity: quotaCfg.RequireIdentity,
+			})
+		}
 	}
+	return cfg
 }
 
 // 内部方法：设置默认配置
@@ -0,0 +1,156 @@
+package grpc_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	daiGrpc "github.com/dfpopp/go-dai/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// tracer 记录中间件、拦截器与处理器的执行轨迹
+type tracer struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (tr *tracer) record(step string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.steps = append(tr.steps, step)
+}
+
+// take 取出并清空轨迹
+func (tr *tracer) take() string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	got := strings.Join(tr.steps, " ")
+	tr.steps = nil
+	return got
+}
+
+func (tr *tracer) middleware(name string) daiGrpc.MiddlewareFunc {
+	return func(next daiGrpc.HandlerFunc) daiGrpc.HandlerFunc {
+		return func(c *daiGrpc.Context) {
+			tr.record(name + ":before")
+			next(c)
+			tr.record(name + ":after")
+		}
+	}
+}
+
+// interceptor 附加拦截器：元数据x-deny-<name>存在时拒绝请求
+func (tr *tracer) interceptor(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if len(md.Get("x-deny-"+name)) > 0 {
+			tr.record(name + ":deny")
+			return nil, status.Error(codes.PermissionDenied, "denied")
+		}
+		tr.record(name + ":before")
+		resp, err := handler(ctx, req)
+		tr.record(name + ":after")
+		return resp, err
+	}
+}
+
+// startTraceServer 启动带两个附加拦截器、全局/路由中间件与框架路由的服务；
+// 元数据x-block存在时全局中间件以403拦截，x-reject存在时路由处理器以404响应，x-frame存在时路由处理器写出框架数据
+func startTraceServer(t *testing.T) (*tracer, *grpc.ClientConn) {
+	t.Helper()
+	tr := &tracer{}
+	block := func(next daiGrpc.HandlerFunc) daiGrpc.HandlerFunc {
+		return func(c *daiGrpc.Context) {
+			if c.GetHeader("x-block") != "" {
+				c.JSON(403, map[string]interface{}{"code": 403, "msg": "blocked", "data": nil})
+				return
+			}
+			next(c)
+		}
+	}
+	_, conn := startServer(t, &daiGrpc.ServerConfig{
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{tr.interceptor("auth"), tr.interceptor("audit")},
+	}, map[string]structMethod{
+		"Echo": func(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+			tr.record("rpc")
+			return structpb.NewStruct(map[string]interface{}{"source": "rpc", "tags": []interface{}{"rpc"}})
+		},
+	}, func(s *daiGrpc.Server) {
+		s.Use(tr.middleware("global"), block)
+		s.Register(fullMethod("Echo"), func(c *daiGrpc.Context) {
+			tr.record("handler")
+			switch {
+			case c.GetHeader("x-reject") != "":
+				c.JSON(404, map[string]interface{}{"code": 404, "msg": "not found", "data": nil})
+			case c.GetHeader("x-frame") != "":
+				c.JSON(200, map[string]interface{}{"code": 200, "msg": "success", "data": map[string]interface{}{
+					"frame": "merged", "source": "frame", "tags": []interface{}{"frame"},
+				}})
+			}
+		}, tr.middleware("route"))
+	})
+	return tr, conn
+}
+
+func TestUnaryInterceptorOrder(t *testing.T) {
+	tr, conn := startTraceServer(t)
+	if _, err := invoke(context.Background(), conn, fullMethod("Echo"), nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "global:before route:before handler auth:before audit:before rpc audit:after auth:after route:after global:after"
+	if got := tr.take(); got != want {
+		t.Fatalf("执行顺序为[%s]，期望[%s]", got, want)
+	}
+}
+
+func TestUnaryShortCircuit(t *testing.T) {
+	tr, conn := startTraceServer(t)
+	cases := []struct {
+		name string
+		md   []string
+		code codes.Code
+		want string
+	}{
+		{"中间件未调用next", []string{"x-block", "1"}, codes.PermissionDenied, "global:before global:after"},
+		{"路由处理器以错误码响应", []string{"x-reject", "1"}, codes.NotFound, "global:before route:before handler route:after global:after"},
+		{"附加拦截器拒绝", []string{"x-deny-audit", "1"}, codes.PermissionDenied, "global:before route:before handler auth:before audit:deny auth:after route:after global:after"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), tc.md...)
+			_, err := invoke(ctx, conn, fullMethod("Echo"), nil)
+			if status.Code(err) != tc.code {
+				t.Fatalf("期望%v，实际%v", tc.code, err)
+			}
+			if got := tr.take(); got != tc.want {
+				t.Fatalf("执行顺序为[%s]，期望[%s]（gRPC处理器不应执行）", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnaryResponseMerge(t *testing.T) {
+	_, conn := startTraceServer(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-frame", "1")
+	resp, err := invoke(ctx, conn, fullMethod("Echo"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := resp.GetFields()
+	if got := fields["frame"].GetStringValue(); got != "merged" {
+		t.Fatalf("框架字段frame=%q，期望merged", got)
+	}
+	if got := fields["source"].GetStringValue(); got != "rpc" {
+		t.Fatalf("source=%q，期望以gRPC处理器的值为准", got)
+	}
+	tags := fields["tags"].GetListValue().AsSlice()
+	if len(tags) != 1 || tags[0] != "rpc" {
+		t.Fatalf("tags=%v，期望gRPC处理器的列表整体替换框架数据", tags)
+	}
+}
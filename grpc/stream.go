@@ -0,0 +1,275 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+	"runtime/debug"
+)
+
+// StreamKind 流式方法类型
+type StreamKind int
+
+const (
+	ServerStreaming StreamKind = iota + 1 // 服务端流：客户端发送一条请求，服务端返回多条消息
+	ClientStreaming                       // 客户端流：客户端发送多条消息，服务端返回一条响应
+	BidiStreaming                         // 双向流：双方独立收发
+)
+
+// StreamContext 流式调用上下文：内嵌一元调用的Context（元数据、客户端IP、参数等与一元调用一致，可复用同一套中间件），
+// 并提供消息收发与响应头/尾元数据的封装
+type StreamContext struct {
+	*Context
+	Kind   StreamKind
+	stream grpc.ServerStream
+}
+
+// StreamHandler 流式处理器（返回的错误按ToStatus映射为gRPC状态码）
+type StreamHandler func(c *StreamContext) error
+
+// Stream 底层gRPC流（需要直接操作时使用）
+func (c *StreamContext) Stream() grpc.ServerStream {
+	return c.stream
+}
+
+// Send 发送一条消息：protobuf消息原样发送，其他类型按JSON转换为google.protobuf.Struct
+func (c *StreamContext) Send(msg interface{}) error {
+	out, err := encodeTyped(msg)
+	if err != nil {
+		return err
+	}
+	return c.stream.SendMsg(out)
+}
+
+// Recv 接收一条消息到msg（须为指针）：protobuf消息直接解码，其他类型经google.protobuf.Struct按json标签转换。
+// 客户端结束发送时返回io.EOF
+func (c *StreamContext) Recv(msg interface{}) error {
+	err := decodeTyped(c.stream.RecvMsg, msg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		if _, ok := status.FromError(err); !ok {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	return err
+}
+
+// SetHeader 设置响应头元数据（在首条消息发送前合并发送）
+func (c *StreamContext) SetHeader(md metadata.MD) error {
+	return c.stream.SetHeader(md)
+}
+
+// SendHeader 立即发送响应头元数据
+func (c *StreamContext) SendHeader(md metadata.MD) error {
+	return c.stream.SendHeader(md)
+}
+
+// SetTrailer 设置响应尾元数据（流结束时发送）
+func (c *StreamContext) SetTrailer(md metadata.MD) {
+	c.stream.SetTrailer(md)
+}
+
+// RegisterStream 注册流式方法（method为完整方法名，如/chat.ChatService/Talk），无需proto生成代码：
+//   - 请求依次经过gRPC流拦截器（认证、限流等）与框架中间件（全局中间件+middlewares），中间件收到的*Context即StreamContext内嵌的上下文
+//   - 中间件未调用next而直接响应时，按响应code映射状态码；处理器panic时返回Internal
+//
+// 须在Run之前调用（服务启动时统一注册到gRPC服务器）
+func (s *Server) RegisterStream(method string, kind StreamKind, handler StreamHandler, middlewares ...MiddlewareFunc) {
+	serviceName, methodName, ok := splitFullMethod(method)
+	if !ok {
+		logger.Error("gRPC流式方法名格式错误（应为/包名.服务名/方法名）：", method)
+		return
+	}
+	if kind < ServerStreaming || kind > BidiStreaming {
+		logger.Error("gRPC流式方法类型无效：", method)
+		return
+	}
+	chain := append(append([]MiddlewareFunc{}, s.router.middlewares...), middlewares...)
+	desc := grpc.StreamDesc{
+		StreamName:    methodName,
+		Handler:       streamMethodHandler(method, kind, handler, chain),
+		ServerStreams: kind != ClientStreaming,
+		ClientStreams: kind != ServerStreaming,
+	}
+	s.typedMu.Lock()
+	defer s.typedMu.Unlock()
+	sd := s.typedService(serviceName)
+	for i := range sd.Streams {
+		if sd.Streams[i].StreamName == methodName {
+			sd.Streams[i] = desc
+			return
+		}
+	}
+	sd.Streams = append(sd.Streams, desc)
+}
+
+// streamMethodHandler 构建grpc.StreamDesc处理器：创建StreamContext -> 框架中间件链 -> 业务处理器
+func streamMethodHandler(fullMethod string, kind StreamKind, handler StreamHandler, chain []MiddlewareFunc) grpc.StreamHandler {
+	return func(srv interface{}, stream grpc.ServerStream) (err error) {
+		ctx := stream.Context()
+		md, _ := metadata.FromIncomingContext(ctx)
+		peerInfo, _ := peer.FromContext(ctx)
+		c := &StreamContext{Context: NewContext(md, peerInfo, fullMethod, nil), Kind: kind, stream: stream}
+		c.SetContext(ctx)
+		called := false
+		final := func(*Context) {
+			called = true
+			defer func() {
+				if r := recover(); r != nil {
+					logger.FromContext(c.GetContext()).Error("gRPC流处理器panic：", fullMethod, " ", r, "\n", string(debug.Stack()))
+					err = status.Error(codes.Internal, "服务器内部错误")
+				}
+			}()
+			err = handler(c)
+		}
+		buildChain(chain, final)(c.Context)
+		if !called {
+			return responseStatus(c.GetResponse())
+		}
+		if err != nil {
+			logHandlerError(c.GetContext(), fullMethod, err)
+		}
+		return ToStatus(err)
+	}
+}
+
+// TypedStream 强类型流：按TReq接收、按TResp发送
+type TypedStream[TReq, TResp any] struct {
+	c *StreamContext
+}
+
+// Recv 接收一条请求消息（客户端结束发送时返回io.EOF）
+func (s *TypedStream[TReq, TResp]) Recv() (*TReq, error) {
+	req := new(TReq)
+	if err := s.c.Recv(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Send 发送一条响应消息
+func (s *TypedStream[TReq, TResp]) Send(resp *TResp) error {
+	return s.c.Send(resp)
+}
+
+// Context 流式调用上下文（读取元数据、设置响应头等）
+func (s *TypedStream[TReq, TResp]) Context() *StreamContext {
+	return s.c
+}
+
+// RegisterServerStream 注册强类型服务端流方法：fn收到首条请求，通过stream.Send逐条返回
+//
+//	grpc.RegisterServerStream(server, "/order.OrderService/Watch", func(ctx context.Context, req *WatchReq, stream *grpc.TypedStream[WatchReq, OrderEvent]) error {
+//		for event := range orderService.Watch(ctx, req.OrderID) {
+//			if err := stream.Send(event); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	})
+func RegisterServerStream[TReq, TResp any](s *Server, method string, fn func(ctx context.Context, req *TReq, stream *TypedStream[TReq, TResp]) error, middlewares ...MiddlewareFunc) {
+	s.RegisterStream(method, ServerStreaming, func(c *StreamContext) error {
+		stream := &TypedStream[TReq, TResp]{c: c}
+		req, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return status.Error(codes.InvalidArgument, "缺少请求消息")
+			}
+			return err
+		}
+		return fn(c.GetContext(), req, stream)
+	}, middlewares...)
+}
+
+// RegisterClientStream 注册强类型客户端流方法：fn通过stream.Recv读取请求直至io.EOF，返回的响应作为唯一一条响应消息
+func RegisterClientStream[TReq, TResp any](s *Server, method string, fn func(ctx context.Context, stream *TypedStream[TReq, TResp]) (*TResp, error), middlewares ...MiddlewareFunc) {
+	s.RegisterStream(method, ClientStreaming, func(c *StreamContext) error {
+		resp, err := fn(c.GetContext(), &TypedStream[TReq, TResp]{c: c})
+		if err != nil {
+			return err
+		}
+		if resp == nil {
+			resp = new(TResp)
+		}
+		return c.Send(resp)
+	}, middlewares...)
+}
+
+// RegisterBidiStream 注册强类型双向流方法
+func RegisterBidiStream[TReq, TResp any](s *Server, method string, fn func(ctx context.Context, stream *TypedStream[TReq, TResp]) error, middlewares ...MiddlewareFunc) {
+	s.RegisterStream(method, BidiStreaming, func(c *StreamContext) error {
+		return fn(c.GetContext(), &TypedStream[TReq, TResp]{c: c})
+	}, middlewares...)
+}
+
+// serverStream 替换context的gRPC流（拦截器注入请求ID、链路信息等）
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// WrapServerStream 返回使用ctx替换原context的流，供自定义流拦截器向后续处理传递context
+func WrapServerStream(stream grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &serverStream{ServerStream: stream, ctx: ctx}
+}
+
+// streamInterceptor 通用gRPC流拦截器：链路追踪与请求ID（与一元拦截器一致）
+func streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx := ss.Context()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if tracing.Enabled() {
+		ctx = tracing.Extract(ctx, metadataCarrier(md))
+		var span trace.Span
+		ctx, span = tracing.StartServerSpan(ctx, info.FullMethod, attribute.String("rpc.method", info.FullMethod),
+			attribute.Bool("rpc.client_streaming", info.IsClientStream), attribute.Bool("rpc.server_streaming", info.IsServerStream))
+		defer func() {
+			tracing.End(span, err)
+		}()
+	}
+
+	requestID := ""
+	if values := md.Get(logger.RequestIDHeader); len(values) > 0 {
+		requestID = values[0]
+	}
+	if requestID == "" {
+		requestID = logger.NewRequestID()
+	}
+	_ = ss.SetHeader(metadata.Pairs(logger.RequestIDHeader, requestID))
+	ctx, buf := logger.StartBuffer(logger.WithRequestID(ctx, requestID))
+	if buf != nil {
+		defer finishLogBuffer(buf, &err)
+	}
+	ctx = netContext.WithRequestCache(ctx)
+	peerInfo, _ := peer.FromContext(ctx)
+	ctx = auth.WithClientIdentity(ctx, peerIdentity(peerInfo))
+
+	err = handler(srv, WrapServerStream(ss, ctx))
+	if err != nil && status.Code(err) != codes.Canceled {
+		logger.FromContext(ctx).Error("gRPC stream handler error: ", err)
+	}
+	return err
+}
+
+// finishLogBuffer 请求结束时处理日志缓冲：返回错误或panic（继续向上抛出）时输出缓冲的debug/info日志
+func finishLogBuffer(buf *logger.Buffer, err *error) {
+	if p := recover(); p != nil {
+		buf.Finish(true)
+		panic(p)
+	}
+	buf.Finish(*err != nil)
+}
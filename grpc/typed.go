@@ -0,0 +1,206 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/dfpopp/go-dai/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"runtime/debug"
+	"strings"
+)
+
+// TypedHandler 强类型一元处理器
+type TypedHandler[TReq, TResp any] func(ctx context.Context, req *TReq) (*TResp, error)
+
+// RegisterTyped 注册强类型一元方法（method为完整方法名，如/user.UserService/GetUser），无需proto生成代码：
+//   - TReq/TResp为protobuf消息时按原类型收发；否则线上格式为google.protobuf.Struct，与结构体之间按json标签转换
+//   - 请求依次经过gRPC拦截器（认证、限流、配额等）与框架中间件（全局中间件+middlewares），处理器收到的ctx携带请求ID、认证声明与链路信息
+//   - 处理器返回的错误按ToStatus映射为gRPC状态码；中间件未调用next而直接响应时，按响应code映射状态码
+//
+// 须在Run之前调用（服务启动时统一注册到gRPC服务器）
+//
+//	grpc.RegisterTyped(server, "/user.UserService/GetUser", func(ctx context.Context, req *GetUserReq) (*GetUserResp, error) {
+//		return userService.Get(ctx, req.ID)
+//	})
+func RegisterTyped[TReq, TResp any](s *Server, method string, fn TypedHandler[TReq, TResp], middlewares ...MiddlewareFunc) {
+	serviceName, methodName, ok := splitFullMethod(method)
+	if !ok {
+		logger.Error("gRPC强类型方法名格式错误（应为/包名.服务名/方法名）：", method)
+		return
+	}
+	chain := append(append([]MiddlewareFunc{}, s.router.middlewares...), middlewares...)
+	s.typedMu.Lock()
+	defer s.typedMu.Unlock()
+	sd := s.typedService(serviceName)
+	desc := grpc.MethodDesc{MethodName: methodName, Handler: typedMethodHandler(method, fn, chain)}
+	for i := range sd.Methods {
+		if sd.Methods[i].MethodName == methodName {
+			sd.Methods[i] = desc
+			return
+		}
+	}
+	sd.Methods = append(sd.Methods, desc)
+}
+
+// typedService 获取或创建强类型方法所属的服务描述（调用方持有typedMu）
+func (s *Server) typedService(serviceName string) *grpc.ServiceDesc {
+	if s.typedServices == nil {
+		s.typedServices = make(map[string]*grpc.ServiceDesc)
+	}
+	sd := s.typedServices[serviceName]
+	if sd == nil {
+		sd = &grpc.ServiceDesc{ServiceName: serviceName, HandlerType: (*interface{})(nil)}
+		s.typedServices[serviceName] = sd
+	}
+	return sd
+}
+
+// registerTypedServices 将强类型方法与流式方法注册到gRPC服务器（Run时调用一次）
+func (s *Server) registerTypedServices() {
+	s.typedMu.Lock()
+	defer s.typedMu.Unlock()
+	for _, sd := range s.typedServices {
+		s.RegisterService(sd, struct{}{})
+	}
+	s.typedServices = nil
+}
+
+// typedMethodHandler 构建grpc.MethodDesc处理器：解码请求 -> 拦截器链 -> 框架中间件链 -> 业务处理器 -> 编码响应
+func typedMethodHandler[TReq, TResp any](fullMethod string, fn TypedHandler[TReq, TResp], chain []MiddlewareFunc) func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(TReq)
+		if err := decodeTyped(dec, req); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		handler := func(ctx context.Context, in interface{}) (interface{}, error) {
+			resp, err := invokeTyped(ctx, fullMethod, in.(*TReq), fn, chain)
+			if err != nil {
+				logHandlerError(ctx, fullMethod, err)
+				return nil, ToStatus(err)
+			}
+			return encodeTyped(resp)
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}, handler)
+	}
+}
+
+// invokeTyped 在框架中间件链中执行业务处理器（处理器panic时返回Internal）
+func invokeTyped[TReq, TResp any](ctx context.Context, fullMethod string, req *TReq, fn TypedHandler[TReq, TResp], chain []MiddlewareFunc) (resp *TResp, err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	peerInfo, _ := peer.FromContext(ctx)
+	rawData, _ := json.Marshal(req)
+	c := NewContext(md, peerInfo, fullMethod, rawData)
+	c.SetContext(ctx)
+	called := false
+	final := func(c *Context) {
+		called = true
+		defer func() {
+			if r := recover(); r != nil {
+				logger.FromContext(c.GetContext()).Error("gRPC处理器panic：", fullMethod, " ", r, "\n", string(debug.Stack()))
+				err = status.Error(codes.Internal, "服务器内部错误")
+			}
+		}()
+		resp, err = fn(c.GetContext(), req)
+	}
+	buildChain(chain, final)(c)
+	if !called {
+		return nil, responseStatus(c.GetResponse())
+	}
+	if err == nil && resp == nil {
+		resp = new(TResp)
+	}
+	return resp, err
+}
+
+// decodeTyped 解码请求：protobuf消息直接解码，其他类型经google.protobuf.Struct按JSON转换
+func decodeTyped(dec func(interface{}) error, req interface{}) error {
+	if m, ok := req.(proto.Message); ok {
+		return dec(m)
+	}
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return err
+	}
+	data, err := json.Marshal(in.AsMap())
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, req); err != nil {
+		return fmt.Errorf("请求参数格式错误：%w", err)
+	}
+	return nil
+}
+
+// encodeTyped 编码响应：protobuf消息原样返回，其他类型按JSON转换为google.protobuf.Struct
+func encodeTyped(resp interface{}) (interface{}, error) {
+	if m, ok := resp.(proto.Message); ok {
+		return m, nil
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "响应序列化失败")
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, status.Error(codes.Internal, "响应类型须为结构体或map")
+	}
+	out, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "响应序列化失败")
+	}
+	return out, nil
+}
+
+// ToStatus 将业务错误映射为gRPC状态错误：*errs.Error（含登记的业务错误码、认证失败、记录不存在等）按登记的gRPC状态码转换，
+// 其他status错误原样返回，context超时/取消映射为DeadlineExceeded/Canceled，其余映射为Internal（对外隐藏原因）
+func ToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+	}
+	return errs.From(err).GRPCStatus().Err()
+}
+
+// responseStatus 将中间件直接写出的响应（{"code","msg"}）转换为gRPC状态错误（code按错误码登记表映射）
+func responseStatus(resp map[string]interface{}) error {
+	code, _ := resp["code"].(int)
+	msg, _ := resp["msg"].(string)
+	if msg == "" {
+		msg = "请求被中间件拦截"
+	}
+	return errs.New(code, msg).GRPCStatus().Err()
+}
+
+// logHandlerError 记录服务端错误的原因（转换为gRPC状态后客户端只收到对外消息）
+func logHandlerError(ctx context.Context, fullMethod string, err error) {
+	if e := errs.From(err); e.IsServerError() && e.Cause != nil {
+		logger.FromContext(ctx).Error("gRPC处理器错误：", fullMethod, " code=", e.Code, " ", err)
+	}
+}
+
+// splitFullMethod 拆分完整方法名/包名.服务名/方法名
+func splitFullMethod(fullMethod string) (service, method string, ok bool) {
+	if !strings.HasPrefix(fullMethod, "/") {
+		return "", "", false
+	}
+	i := strings.LastIndex(fullMethod, "/")
+	service, method = fullMethod[1:i], fullMethod[i+1:]
+	return service, method, service != "" && method != ""
+}
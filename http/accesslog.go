@@ -0,0 +1,121 @@
+package http
+
+import (
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessLogOptions 访问日志配置
+type AccessLogOptions struct {
+	Format        string             // combined（Apache/Nginx组合日志格式，默认）/json（结构化字段）
+	SampleRate    float64            // 采样率（0~1，<=0或>1按1处理，即全部记录）
+	Routes        map[string]float64 // 按路径单独配置采样率（精确匹配，以*结尾时按前缀匹配；0表示不记录，如健康检查）
+	SlowThreshold time.Duration      // 慢请求阈值：超过该耗时的请求不受采样限制（<=0表示不启用）
+	SampleErrors  bool               // 状态码>=400的请求也参与采样（默认错误请求总是记录）
+}
+
+// AccessLog 访问日志中间件：记录方法、路径、状态码、响应字节数、耗时、客户端IP与User-Agent，
+// 通过logger输出（携带请求ID等上下文字段）；应注册为第一个中间件以统计完整耗时
+func AccessLog(opts ...AccessLogOptions) MiddlewareFunc {
+	var opt AccessLogOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.SampleRate <= 0 || opt.SampleRate > 1 {
+		opt.SampleRate = 1
+	}
+	jsonFormat := strings.EqualFold(opt.Format, "json")
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			start := time.Now()
+			rec := newResponseRecorder(c.Writer)
+			c.Writer = rec
+			next(c)
+			latency := time.Since(start)
+			if !opt.sampled(c.Req.URL.Path, rec.status, latency) {
+				return
+			}
+			entry := logger.FromContext(c.GetContext())
+			if jsonFormat {
+				entry.WithFields(logger.Fields{
+					"method":     c.Req.Method,
+					"path":       c.Req.URL.RequestURI(),
+					"proto":      c.Req.Proto,
+					"status":     rec.status,
+					"bytes":      rec.size,
+					"latency_ms": float64(latency.Microseconds()) / 1000,
+					"client_ip":  c.GetClientIP(),
+					"user":       ratelimit.UserFromContext(c.GetContext()),
+					"referer":    c.Req.Referer(),
+					"user_agent": c.Req.UserAgent(),
+				}).Info("access")
+				return
+			}
+			entry.Info(combinedLogLine(c, rec, start, latency))
+		}
+	}
+}
+
+// sampled 判断本次请求是否记录
+func (opt *AccessLogOptions) sampled(path string, status int, latency time.Duration) bool {
+	if status >= http.StatusBadRequest && !opt.SampleErrors {
+		return true
+	}
+	if opt.SlowThreshold > 0 && latency >= opt.SlowThreshold {
+		return true
+	}
+	rate := opt.SampleRate
+	if v, ok := matchRoute(opt.Routes, path); ok {
+		rate = v
+	}
+	if rate >= 1 {
+		return true
+	}
+	return rate > 0 && rand.Float64() < rate
+}
+
+// combinedLogLine 组合日志格式：ip - user [time] "method uri proto" status bytes "referer" "user-agent" latency
+func combinedLogLine(c *Context, rec *responseRecorder, start time.Time, latency time.Duration) string {
+	user := ratelimit.UserFromContext(c.GetContext())
+	if user == "" {
+		user = "-"
+	}
+	var b strings.Builder
+	b.Grow(256)
+	b.WriteString(c.GetClientIP())
+	b.WriteString(" - ")
+	b.WriteString(user)
+	b.WriteString(" [")
+	b.WriteString(start.Format("02/Jan/2006:15:04:05 -0700"))
+	b.WriteString(`] "`)
+	b.WriteString(c.Req.Method)
+	b.WriteByte(' ')
+	b.WriteString(c.Req.URL.RequestURI())
+	b.WriteByte(' ')
+	b.WriteString(c.Req.Proto)
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(rec.status))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(rec.size))
+	b.WriteString(` "`)
+	b.WriteString(orDash(c.Req.Referer()))
+	b.WriteString(`" "`)
+	b.WriteString(orDash(c.Req.UserAgent()))
+	b.WriteString(`" `)
+	b.WriteString(strconv.FormatFloat(float64(latency.Microseconds())/1000, 'f', 3, 64))
+	b.WriteString("ms")
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	quoted := strconv.Quote(s) // 转义引号与控制字符，避免日志注入
+	return quoted[1 : len(quoted)-1]
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"errors"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"net/http"
+	"strings"
+)
+
+// JWTAuth JWT认证中间件：校验Authorization: Bearer <token>，通过后将用户信息写入上下文参数
+// （auth.ParamUserID等）与请求级context（auth.FromContext），失败返回401
+func JWTAuth(j *auth.JWT) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			claims, err := j.Parse(auth.BearerToken(c.Req.Header.Get("Authorization")))
+			if err != nil {
+				msg := i18n.MsgAuthFailed
+				if errors.Is(err, auth.ErrTokenExpired) {
+					msg = i18n.MsgTokenExpired
+				}
+				c.Writer.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				c.JSON(http.StatusUnauthorized, map[string]interface{}{
+					"code": http.StatusUnauthorized,
+					"msg":  c.T(msg),
+					"data": nil,
+				})
+				return
+			}
+			auth.Bind(c, claims)
+			next(c)
+		}
+	}
+}
+
+// SessionAuth 会话认证中间件：要求会话中存在登录用户（由OAuthCallback或业务登录接口写入auth.ParamUserID/auth.ParamRoles），
+// 通过后与JWTAuth一样写入上下文，未登录返回401；须注册在Sessions中间件之后
+func SessionAuth() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			var userID, roles string
+			if sess := c.Session(); sess != nil {
+				userID, roles = sess.GetString(auth.ParamUserID), sess.GetString(auth.ParamRoles)
+			}
+			if userID == "" {
+				c.JSON(http.StatusUnauthorized, map[string]interface{}{
+					"code": http.StatusUnauthorized,
+					"msg":  c.T(i18n.MsgAuthFailed),
+					"data": nil,
+				})
+				return
+			}
+			claims := &auth.Claims{UserID: userID, SessionID: c.Session().ID()}
+			if roles != "" {
+				claims.Roles = strings.Split(roles, ",")
+			}
+			auth.Bind(c, claims)
+			next(c)
+		}
+	}
+}
+
+// RBAC 权限中间件：依次按"METHOD /path"与"/path"匹配规则，校验已认证用户的角色/权限（规则见auth.RBAC），
+// 须注册在JWTAuth/SessionAuth之后；未匹配到规则的请求直接放行，未认证返回401，权限不足返回403
+func RBAC(r *auth.RBAC) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			path := c.Req.URL.Path
+			err := r.AuthorizeAny(c.GetContext(), []string{c.Req.Method + " " + path, path}, auth.FromContext(c.GetContext()))
+			if err == nil {
+				next(c)
+				return
+			}
+			code, msg := http.StatusForbidden, i18n.MsgForbidden
+			switch {
+			case errors.Is(err, auth.ErrUnauthenticated):
+				code, msg = http.StatusUnauthorized, i18n.MsgAuthFailed
+			case !errors.Is(err, auth.ErrPermissionDenied):
+				logger.Error("HTTP权限策略加载失败：", err)
+				code, msg = http.StatusInternalServerError, i18n.MsgInternalError
+			}
+			c.JSON(code, map[string]interface{}{
+				"code": code,
+				"msg":  c.T(msg),
+				"data": nil,
+			})
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/dfpopp/go-dai/logger"
+	"golang.org/x/sync/singleflight"
+	"net"
+	"net/http"
+)
+
+// coalesceSkipHeaders 与单个请求绑定、不能共享给其他请求的响应头
+var coalesceSkipHeaders = []string{
+	"Set-Cookie",
+	logger.RequestIDHeader,
+	"Traceparent",
+	"Tracestate",
+}
+
+// coalescedResponse 合并请求共享的响应结果
+type coalescedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// bufferedWriter 缓存处理器输出的ResponseWriter（不支持Flush/Hijack，SSE/WS路由请勿使用请求合并）
+type bufferedWriter struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newBufferedWriter() *bufferedWriter {
+	return &bufferedWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(b)
+}
+
+// CoalesceKeyFunc 请求合并键中的身份标识函数（返回值相同的并发请求才会合并）
+type CoalesceKeyFunc func(c *Context) string
+
+// DefaultCoalesceIdentity 默认身份标识：Authorization与Cookie请求头的摘要（不同用户的请求不会合并）；
+// 匿名请求按连接的对端IP区分（不信任可伪造的X-Forwarded-For），不同客户端的匿名请求不会合并
+func DefaultCoalesceIdentity(c *Context) string {
+	auth := c.Req.Header.Get("Authorization")
+	cookie := c.Req.Header.Get("Cookie")
+	if auth == "" && cookie == "" {
+		return "anon:" + remoteAddrIP(c.Req)
+	}
+	sum := sha1.Sum([]byte(auth + "\n" + cookie))
+	return hex.EncodeToString(sum[:])
+}
+
+// remoteAddrIP 连接的对端IP（RemoteAddr去掉端口）
+func remoteAddrIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// Coalesce 请求合并中间件：并发到达的相同GET请求（规范化路径+查询参数+身份标识）只执行一次处理器，
+// 其余请求等待并共享同一响应，用于保护搜索、报表等高开销接口。identity为空时使用DefaultCoalesceIdentity。
+// 共享响应不包含Set-Cookie、X-Request-Id等与单个请求绑定的响应头；首个请求的响应设置了Cookie时，
+// 等待的请求不共享该响应而是各自执行处理器（避免会话、CSRF令牌串给其他客户端）。
+// 注意：仅合并同时在途的请求，不做结果缓存；流式响应（SSE）路由请勿使用。
+func Coalesce(identity ...CoalesceKeyFunc) MiddlewareFunc {
+	identityFunc := CoalesceKeyFunc(DefaultCoalesceIdentity)
+	if len(identity) > 0 && identity[0] != nil {
+		identityFunc = identity[0]
+	}
+	var group singleflight.Group
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if c.Req.Method != http.MethodGet {
+				next(c)
+				return
+			}
+			// url.Values.Encode按键排序，参数顺序不同的请求视为相同
+			key := c.Req.URL.Path + "?" + c.Req.URL.Query().Encode() + "#" + identityFunc(c)
+			leader := false
+			val, _, _ := group.Do(key, func() (interface{}, error) {
+				leader = true
+				writer := c.Writer
+				buffered := newBufferedWriter()
+				c.Writer = buffered
+				defer func() {
+					c.Writer = writer
+				}()
+				next(c)
+				return &coalescedResponse{
+					status: buffered.status,
+					header: buffered.header,
+					body:   buffered.body.Bytes(),
+				}, nil
+			})
+			resp := val.(*coalescedResponse)
+			if !leader && len(resp.header.Values("Set-Cookie")) > 0 {
+				next(c)
+				return
+			}
+			header := c.Writer.Header()
+			for name, values := range resp.header {
+				if !leader && isCoalesceSkipHeader(name) {
+					continue
+				}
+				header[name] = append([]string(nil), values...)
+			}
+			c.Writer.WriteHeader(resp.status)
+			_, _ = c.Writer.Write(resp.body)
+		}
+	}
+}
+
+func isCoalesceSkipHeader(name string) bool {
+	for _, skip := range coalesceSkipHeaders {
+		if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(skip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runConcurrent 并发发起n个请求（首个请求进入处理器后再发起其余请求），返回各自的响应
+func runConcurrent(r *Router, entered <-chan struct{}, release chan<- struct{}, reqs []*http.Request) []*httptest.ResponseRecorder {
+	recs := make([]*httptest.ResponseRecorder, len(reqs))
+	var wg sync.WaitGroup
+	for i := range reqs {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.ServeHTTP(recs[i], reqs[i])
+		}(i)
+		if i == 0 {
+			<-entered
+		}
+	}
+	time.Sleep(50 * time.Millisecond) // 等待其余请求进入合并等待
+	close(release)
+	wg.Wait()
+	return recs
+}
+
+func newCoalesceRouter(calls *int32, setCookie bool) (*Router, chan struct{}, chan struct{}) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	r := NewRouter()
+	r.Use(Coalesce())
+	r.GET("/report", func(c *Context) {
+		n := atomic.AddInt32(calls, 1)
+		once.Do(func() { close(entered) })
+		<-release
+		if setCookie {
+			http.SetCookie(c.Writer, &http.Cookie{Name: "sid", Value: "s" + strconv.Itoa(int(n))})
+		}
+		c.Writer.Header().Set("X-Request-Id", "req-"+strconv.Itoa(int(n)))
+		c.Writer.Header().Set("X-Report", "ok")
+		_, _ = c.Writer.Write([]byte("report"))
+	})
+	return r, entered, release
+}
+
+func anonRequest(remote string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/report?b=2&a=1", nil)
+	req.RemoteAddr = remote
+	return req
+}
+
+func TestCoalesceSharesResponseWithoutPerRequestHeaders(t *testing.T) {
+	var calls int32
+	r, entered, release := newCoalesceRouter(&calls, false)
+	reqs := []*http.Request{anonRequest("10.0.0.1:1000"), anonRequest("10.0.0.1:1001"), anonRequest("10.0.0.1:1002")}
+	recs := runConcurrent(r, entered, release, reqs)
+	if calls != 1 {
+		t.Fatalf("处理器执行%d次，期望1次", calls)
+	}
+	for i, rec := range recs {
+		if rec.Body.String() != "report" || rec.Header().Get("X-Report") != "ok" {
+			t.Fatalf("#%d body=%q header=%v", i, rec.Body.String(), rec.Header())
+		}
+	}
+	shared := 0
+	for _, rec := range recs {
+		if rec.Header().Get("X-Request-Id") == "" {
+			shared++
+		}
+	}
+	if shared != 2 {
+		t.Fatalf("等待的请求不应收到首个请求的X-Request-Id（%d个未携带）", shared)
+	}
+}
+
+func TestCoalesceDoesNotShareSetCookie(t *testing.T) {
+	var calls int32
+	r, entered, release := newCoalesceRouter(&calls, true)
+	reqs := []*http.Request{anonRequest("10.0.0.1:1000"), anonRequest("10.0.0.1:1001")}
+	recs := runConcurrent(r, entered, release, reqs)
+	if calls != 2 {
+		t.Fatalf("设置Cookie的响应不应共享，处理器执行%d次", calls)
+	}
+	if recs[0].Header().Get("Set-Cookie") == recs[1].Header().Get("Set-Cookie") {
+		t.Fatalf("两个请求收到相同的Cookie：%q", recs[0].Header().Get("Set-Cookie"))
+	}
+}
+
+func TestCoalesceAnonymousKeyedPerClient(t *testing.T) {
+	var calls int32
+	r, entered, release := newCoalesceRouter(&calls, false)
+	a, b := anonRequest("10.0.0.1:1000"), anonRequest("10.0.0.2:1000")
+	b.Header.Set("X-Forwarded-For", "10.0.0.1")
+	runConcurrent(r, entered, release, []*http.Request{a, b})
+	if calls != 2 {
+		t.Fatalf("不同客户端的匿名请求不应合并，处理器执行%d次", calls)
+	}
+}
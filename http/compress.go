@@ -0,0 +1,292 @@
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"github.com/andybalholm/brotli"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressOptions 响应压缩参数
+type CompressOptions struct {
+	Level        int      // 压缩级别（gzip为1-9，br为0-11，0使用各自默认级别）
+	MinSize      int      // 响应体达到该字节数才压缩（默认1024）
+	Encodings    []string // 支持的编码及服务端偏好顺序（默认 br、gzip）
+	ContentTypes []string // 可压缩的Content-Type前缀（默认JSON/文本/JS/XML/SVG）
+}
+
+// 压缩编码
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+)
+
+var defaultCompressTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+	"image/svg+xml",
+	"text/",
+}
+
+// Compress 响应压缩中间件：按Accept-Encoding协商br/gzip，仅压缩达到阈值的可压缩类型响应；
+// SSE（text/event-stream）、已设置Content-Encoding、Range请求、HEAD请求及204/304响应不压缩。
+// 可作为全局中间件，也可作为路由局部中间件；全局开启时可在路由上使用NoCompress()排除。
+func Compress(opts ...CompressOptions) MiddlewareFunc {
+	var opt CompressOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.MinSize <= 0 {
+		opt.MinSize = 1024
+	}
+	if len(opt.Encodings) == 0 {
+		opt.Encodings = []string{EncodingBrotli, EncodingGzip}
+	}
+	if len(opt.ContentTypes) == 0 {
+		opt.ContentTypes = defaultCompressTypes
+	}
+	pools := make(map[string]*sync.Pool, len(opt.Encodings))
+	for _, encoding := range opt.Encodings {
+		if pool := newEncoderPool(encoding, opt.Level); pool != nil {
+			pools[encoding] = pool
+		}
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if c.Req.Method == http.MethodHead || c.Req.Header.Get("Range") != "" {
+				next(c)
+				return
+			}
+			encoding := negotiateEncoding(c.Req.Header.Get("Accept-Encoding"), opt.Encodings, pools)
+			if encoding == "" {
+				next(c)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: c.Writer, opts: &opt, encoding: encoding, pool: pools[encoding], status: http.StatusOK}
+			c.Writer = cw
+			defer func() {
+				cw.close()
+				c.Writer = cw.ResponseWriter
+			}()
+			next(c)
+		}
+	}
+}
+
+// NoCompress 路由级关闭压缩（配合全局Compress使用）
+func NoCompress() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if cw, ok := c.Writer.(*compressWriter); ok {
+				cw.disabled = true
+			}
+			next(c)
+		}
+	}
+}
+
+// encoder 压缩器统一接口
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// newEncoderPool 创建压缩器对象池（不支持的编码返回nil）
+func newEncoderPool(encoding string, level int) *sync.Pool {
+	switch encoding {
+	case EncodingGzip:
+		if level == 0 || level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			level = gzip.DefaultCompression
+		}
+		return &sync.Pool{New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}}
+	case EncodingBrotli:
+		if level <= 0 || level > brotli.BestCompression {
+			level = 5 // 兼顾压缩率与CPU开销
+		}
+		return &sync.Pool{New: func() interface{} {
+			return brotli.NewWriterLevel(io.Discard, level)
+		}}
+	}
+	return nil
+}
+
+// negotiateEncoding 按Accept-Encoding（含q值）与服务端偏好选择编码，均不可接受时返回空
+func negotiateEncoding(accept string, preferred []string, pools map[string]*sync.Pool) string {
+	if accept == "" {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	for _, item := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range preferred {
+		if pools[encoding] == nil {
+			continue
+		}
+		q, ok := qualities[encoding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter 延迟决策的压缩ResponseWriter：缓存首段输出，达到阈值或Flush时再决定是否压缩
+type compressWriter struct {
+	http.ResponseWriter
+	opts     *CompressOptions
+	encoding string
+	pool     *sync.Pool
+	enc      encoder
+	buf      []byte
+	status   int
+	decided  bool // 是否已决定压缩与否（决定后响应头已写出）
+	disabled bool // 路由级关闭
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		return
+	}
+	w.status = code
+	// 无响应体的状态码直接放行
+	if code == http.StatusNoContent || code == http.StatusNotModified || code < 200 {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.opts.MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(w.shouldCompress()); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// shouldCompress 判断当前响应是否需要压缩
+func (w *compressWriter) shouldCompress() bool {
+	if w.disabled || len(w.buf) < w.opts.MinSize {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf)
+	}
+	contentType = strings.ToLower(contentType)
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return false
+	}
+	for _, prefix := range w.opts.ContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// decide 写出响应头与已缓存的数据
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	if compress {
+		header := w.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		w.enc = w.pool.Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// Flush 透传http.Flusher（未决策时按当前内容决策，SSE因类型判断不会被压缩）
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(w.shouldCompress())
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 透传http.Hijacker（WS升级依赖）
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijack")
+	}
+	w.decided = true
+	return hijacker.Hijack()
+}
+
+// Unwrap 供http.ResponseController获取原始ResponseWriter
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close 处理器返回后收尾：未达阈值的响应原样输出，压缩器归还对象池
+func (w *compressWriter) close() {
+	if !w.decided {
+		// 处理器未写任何内容时保持默认行为（由net/http写出200）
+		if len(w.buf) == 0 && w.status == http.StatusOK {
+			w.decided = true
+			return
+		}
+		_ = w.decide(false)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.enc.Reset(io.Discard)
+		w.pool.Put(w.enc)
+		w.enc = nil
+	}
+}
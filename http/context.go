@@ -2,11 +2,16 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/i18n"
 	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/session"
 	"io"
+	"mime"
 	"net"
 	"net/http"
 	"strings"
@@ -17,13 +22,22 @@ type Context struct {
 	Writer http.ResponseWriter
 	Req    *http.Request
 	Params map[string]string // 路径参数
+
+	sess *session.Session // 当前请求的会话（启用Sessions中间件后可用）
+	csrf *csrfState       // 当前请求的CSRF令牌（启用CSRF中间件后可用）
+
+	bodyLimit int64 // BodyLimit中间件设置的请求体上限（0表示使用默认上限）
+
+	templates *TemplateRenderer // HTML模板渲染器（启用Templates中间件后可用）
 }
 
 // NewContext 创建上下文实例
 func NewContext(w http.ResponseWriter, r *http.Request) *Context {
+	// 请求级缓存与已校验的客户端证书身份（mTLS）
+	ctx := auth.WithClientIdentity(netContext.WithRequestCache(r.Context()), auth.IdentityFromTLS(r.TLS))
 	return &Context{
 		Writer: w,
-		Req:    r,
+		Req:    r.WithContext(ctx),
 		Params: make(map[string]string),
 	}
 }
@@ -49,8 +63,9 @@ func ToHTTPHandler(fn HTTPHandlerFunc) HandlerFunc {
 
 // -------------------------- 编译期校验（移到http包中，验证http.Context实现通用接口） --------------------------
 var (
-	_ netContext.Context     = (*Context)(nil) // 验证上下文接口实现
-	_ netContext.RequestInfo = (*Context)(nil) // 验证请求信息接口实现
+	_ netContext.Context      = (*Context)(nil) // 验证上下文接口实现
+	_ netContext.RequestInfo  = (*Context)(nil) // 验证请求信息接口实现
+	_ netContext.RemoteIPInfo = (*Context)(nil) // 验证对端IP接口实现
 )
 
 // -------------------------- 实现通用context.RequestInfo接口 --------------------------
@@ -63,6 +78,12 @@ func (c *Context) GetPath() string {
 	return c.Req.URL.Path // HTTP请求路径（如/merchant/login）
 }
 
+// GetRemoteIP 连接的对端IP（不读取代理请求头，经反向代理时为代理地址）
+func (c *Context) GetRemoteIP() string {
+	return remoteAddrIP(c.Req)
+}
+
+// GetClientIP 客户端IP（优先读取X-Real-IP/X-Forwarded-For，可被客户端伪造，仅用于日志展示等非安全场景）
 func (c *Context) GetClientIP() string {
 	// 通用客户端IP获取逻辑（兼容反向代理）
 	ip := c.Req.Header.Get("X-Real-IP")
@@ -103,6 +124,16 @@ func (c *Context) GetRequestInfo() netContext.RequestInfo {
 	return c // HTTP上下文自身实现了RequestInfo，直接返回
 }
 
+// GetContext 获取请求级context（即Req.Context()）
+func (c *Context) GetContext() context.Context {
+	return c.Req.Context()
+}
+
+// SetContext 替换请求级context（中间件注入链路信息等）
+func (c *Context) SetContext(ctx context.Context) {
+	c.Req = c.Req.WithContext(ctx)
+}
+
 // JSON 返回JSON格式响应
 func (c *Context) JSON(code int, data map[string]interface{}) {
 	c.Writer.Header().Set("Content-Type", "application/json;charset=utf-8")
@@ -119,6 +150,16 @@ func (c *Context) String(code int, s string) {
 	_, _ = c.Writer.Write([]byte(s))
 }
 
+// Locale 协商当前请求的语言（查询参数lang优先，其次Accept-Language，与WS握手协商规则一致）
+func (c *Context) Locale() string {
+	return i18n.FromRequest(c.Req)
+}
+
+// T 按当前请求语言获取文案
+func (c *Context) T(key string, args ...interface{}) string {
+	return i18n.T(c.Locale(), key, args...)
+}
+
 // Query 获取URL查询参数
 func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
@@ -153,15 +194,19 @@ func (c *Context) GetBody() ([]byte, error) {
 		return nil, errors.New("request对象未初始化")
 	}
 
-	// 限制请求体大小，防止OOM
-	c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, maxBodySize)
+	// 限制请求体大小，防止OOM（BodyLimit中间件已限制时以其为准）
+	limit := int64(maxBodySize)
+	if c.bodyLimit > 0 {
+		limit = c.bodyLimit
+	}
+	c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, limit)
 	// 读取原始Body
 	bodyBytes, err := io.ReadAll(c.Req.Body)
 	if err != nil {
 		// 区分“超出大小限制”和普通读取错误
 		var maxBytesErr *http.MaxBytesError
 		if errors.As(err, &maxBytesErr) {
-			return nil, fmt.Errorf("请求体超出最大限制（%dMB）：%w", maxBodySize/1024/1024, err)
+			return nil, fmt.Errorf("%w（%d字节）", ErrBodyTooLarge, limit)
 		}
 		return nil, fmt.Errorf("读取请求体失败：%w", err)
 	}
@@ -178,6 +223,37 @@ func (c *Context) BindJSON(v interface{}) error {
 	return decoder.Decode(v)
 }
 
+// Bind 合并绑定查询参数、表单字段（urlencoded/multipart）与JSON请求体到结构体（按query/form/json标签，后者覆盖前者）
+func (c *Context) Bind(v interface{}) error {
+	src := netContext.BindSources{Query: c.Req.URL.Query()}
+	contentType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	switch contentType {
+	case "application/x-www-form-urlencoded":
+		if err := c.Req.ParseForm(); err != nil {
+			return &netContext.BindError{Source: "form", Err: err}
+		}
+		src.Form = c.Req.PostForm
+	case "multipart/form-data":
+		form, err := c.MultipartForm()
+		if err != nil {
+			return err
+		}
+		src.Form = form.Value
+	default:
+		// 其他类型（含未声明Content-Type）的请求体以{开头时按JSON解析
+		if c.Req.Body != nil && c.Req.Body != http.NoBody {
+			body, err := c.GetBody()
+			if err != nil {
+				return err
+			}
+			if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+				src.JSON = trimmed
+			}
+		}
+	}
+	return netContext.Bind(v, src)
+}
+
 // SetParam 设置路径参数
 func (c *Context) SetParam(key, value string) {
 	c.Params[key] = value
@@ -187,3 +263,8 @@ func (c *Context) SetParam(key, value string) {
 func (c *Context) GetParam(key string) string {
 	return c.Params[key]
 }
+
+// Param 获取路由路径参数（如 /users/:id 中的id），等同GetParam
+func (c *Context) Param(key string) string {
+	return c.Params[key]
+}
@@ -0,0 +1,409 @@
+package http
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 路由契约：注册路由时声明请求/响应结构体，契约模式下（测试/预发环境）框架按声明校验请求体与处理器响应，
+// 发现字段缺失、多余字段或类型不符时记录错误并按模式拒绝响应；声明信息同时通过Router.Routes()对外提供（供文档生成使用）。
+//
+//	s.POST("/users", createUser).Schema(CreateUserReq{}, UserResp{}).Describe("创建用户")
+//
+// 响应结构默认描述标准响应体 {"code","msg","data"} 中的data字段（仅校验code为200的成功响应），
+// 处理器直接输出业务结构时调用AsRawResponse()。
+
+// ContractMode 契约校验模式
+type ContractMode int32
+
+const (
+	ContractOff    ContractMode = iota // 关闭（默认，生产环境无额外开销）
+	ContractWarn                       // 仅记录日志
+	ContractStrict                     // 记录日志并拒绝：请求不符返回400，响应不符返回500
+)
+
+// 契约违规类型
+const (
+	ContractRequest  = "request"
+	ContractResponse = "response"
+)
+
+// Route 路由元数据
+type Route struct {
+	Method      string
+	Path        string
+	Summary     string       // 接口说明
+	Request     reflect.Type // 请求体结构（JSON）
+	Response    reflect.Type // 响应结构
+	RawResponse bool         // 响应结构描述整个响应体（默认仅描述标准响应体中的data字段）
+}
+
+// ContractViolation 契约违规详情
+type ContractViolation struct {
+	Route    *Route
+	Kind     string   // request/response
+	Problems []string // 违规项（字段路径: 原因）
+}
+
+func (v *ContractViolation) Error() string {
+	return fmt.Sprintf("契约校验失败 %s %s（%s）：%s", v.Route.Method, v.Route.Path, v.Kind, strings.Join(v.Problems, "; "))
+}
+
+var (
+	contractMode     atomic.Int32
+	contractHookMu   sync.RWMutex
+	contractHookFunc func(v *ContractViolation)
+)
+
+// SetContractMode 设置契约校验模式
+func SetContractMode(mode ContractMode) {
+	contractMode.Store(int32(mode))
+}
+
+// GetContractMode 获取契约校验模式
+func GetContractMode() ContractMode {
+	return ContractMode(contractMode.Load())
+}
+
+// OnContractViolation 注册契约违规回调（如测试中调用t.Error使用例失败）
+func OnContractViolation(fn func(v *ContractViolation)) {
+	contractHookMu.Lock()
+	defer contractHookMu.Unlock()
+	contractHookFunc = fn
+}
+
+// Schema 声明请求/响应结构（传入结构体零值或指针，nil表示不声明）
+func (rt *Route) Schema(request, response interface{}) *Route {
+	rt.Request = schemaType(request)
+	rt.Response = schemaType(response)
+	return rt
+}
+
+// Describe 设置接口说明
+func (rt *Route) Describe(summary string) *Route {
+	rt.Summary = summary
+	return rt
+}
+
+// AsRawResponse 声明响应结构描述整个响应体（而非标准响应体中的data字段）
+func (rt *Route) AsRawResponse() *Route {
+	rt.RawResponse = true
+	return rt
+}
+
+// Routes 获取已注册路由的元数据（按路径、方法排序）
+func (r *Router) Routes() []*Route {
+	routes := make([]*Route, len(r.routes))
+	copy(routes, r.routes)
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func schemaType(v interface{}) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	if t, ok := v.(reflect.Type); ok {
+		return t
+	}
+	return reflect.TypeOf(v)
+}
+
+// contractHandler 包装处理器：契约模式开启且路由声明了结构时执行校验
+func contractHandler(route *Route, handler HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		mode := GetContractMode()
+		if mode == ContractOff || (route.Request == nil && route.Response == nil) {
+			handler(c)
+			return
+		}
+		if route.Request != nil && c.Req.Body != nil && isJSONRequest(c.Req) {
+			body, err := io.ReadAll(c.Req.Body)
+			_ = c.Req.Body.Close()
+			c.Req.Body = io.NopCloser(bytes.NewReader(body))
+			if err == nil && len(bytes.TrimSpace(body)) > 0 {
+				if problems := checkJSON(body, route.Request, false); len(problems) > 0 {
+					reportViolation(c, &ContractViolation{Route: route, Kind: ContractRequest, Problems: problems})
+					if mode == ContractStrict {
+						c.JSON(http.StatusBadRequest, map[string]interface{}{"code": 400, "msg": "请求体不符合接口契约", "data": problems})
+						return
+					}
+				}
+			}
+		}
+		if route.Response == nil {
+			handler(c)
+			return
+		}
+		// 缓存处理器输出，校验通过后再写回
+		origin := c.Writer
+		buf := newBufferedWriter()
+		c.Writer = buf
+		handler(c)
+		c.Writer = origin
+		if problems := checkResponse(buf, route); len(problems) > 0 {
+			reportViolation(c, &ContractViolation{Route: route, Kind: ContractResponse, Problems: problems})
+			if mode == ContractStrict {
+				c.Writer.Header().Set("X-Contract-Violation", "response")
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{"code": 500, "msg": "响应不符合接口契约", "data": problems})
+				return
+			}
+		}
+		for key, values := range buf.header {
+			origin.Header()[key] = values
+		}
+		origin.WriteHeader(buf.status)
+		_, _ = origin.Write(buf.body.Bytes())
+	}
+}
+
+// reportViolation 记录违规并触发回调
+func reportViolation(c *Context, v *ContractViolation) {
+	logger.FromContext(c.GetContext()).Error(v.Error())
+	contractHookMu.RLock()
+	hook := contractHookFunc
+	contractHookMu.RUnlock()
+	if hook != nil {
+		hook(v)
+	}
+}
+
+// checkResponse 校验2xx的JSON响应
+func checkResponse(buf *bufferedWriter, route *Route) []string {
+	if buf.status < 200 || buf.status >= 300 || !strings.Contains(buf.header.Get("Content-Type"), "json") {
+		return nil
+	}
+	body := buf.body.Bytes()
+	if route.RawResponse {
+		return checkJSON(body, route.Response, true)
+	}
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return []string{"$: 响应体不是JSON对象"}
+	}
+	if code, ok := envelope["code"]; ok && string(code) != "200" {
+		return nil
+	}
+	data, ok := envelope["data"]
+	if !ok {
+		return []string{"data: 缺少字段"}
+	}
+	return prefixProblems("data", checkJSON(data, route.Response, true))
+}
+
+func prefixProblems(prefix string, problems []string) []string {
+	for i, p := range problems {
+		if strings.HasPrefix(p, "$") {
+			problems[i] = prefix + p[1:]
+		}
+	}
+	return problems
+}
+
+func isJSONRequest(req *http.Request) bool {
+	ct := req.Header.Get("Content-Type")
+	return ct == "" || strings.Contains(ct, "json")
+}
+
+// checkJSON 按结构体类型校验JSON：多余字段、类型不符；requireFields为true时未标记omitempty的字段必须存在
+func checkJSON(data []byte, typ reflect.Type, requireFields bool) []string {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []string{"$: JSON格式错误：" + err.Error()}
+	}
+	var problems []string
+	checkValue(value, typ, "$", requireFields, &problems)
+	return problems
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// checkValue 递归校验值与类型是否匹配
+func checkValue(value interface{}, typ reflect.Type, path string, requireFields bool, problems *[]string) {
+	for typ.Kind() == reflect.Ptr {
+		if value == nil {
+			return
+		}
+		typ = typ.Elem()
+	}
+	if typ == timeType || typ == rawMessageType || typ.Kind() == reflect.Interface {
+		return
+	}
+	// 自定义反序列化的类型无法静态判断结构
+	if reflect.PointerTo(typ).Implements(unmarshalerType) || reflect.PointerTo(typ).Implements(textUnmarshaler) {
+		return
+	}
+	if value == nil {
+		if typ.Kind() == reflect.Map || typ.Kind() == reflect.Slice {
+			return
+		}
+		*problems = append(*problems, path+": 不能为null")
+		return
+	}
+	mismatch := func(expected string) {
+		*problems = append(*problems, fmt.Sprintf("%s: 类型不符，期望%s，实际%s", path, expected, jsonKind(value)))
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch("object")
+			return
+		}
+		fields := jsonFields(typ)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field, ok := fields[key]
+			if !ok {
+				*problems = append(*problems, path+"."+key+": 未声明的字段")
+				continue
+			}
+			checkValue(obj[key], field.typ, path+"."+key, requireFields, problems)
+		}
+		if requireFields {
+			names := make([]string, 0, len(fields))
+			for name, field := range fields {
+				if _, ok := obj[name]; !ok && !field.omitEmpty {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				*problems = append(*problems, path+"."+name+": 缺少字段")
+			}
+		}
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch("object")
+			return
+		}
+		for key, item := range obj {
+			checkValue(item, typ.Elem(), path+"."+key, requireFields, problems)
+		}
+	case reflect.Slice, reflect.Array:
+		// []byte按base64字符串编码
+		if typ.Elem().Kind() == reflect.Uint8 {
+			if _, ok := value.(string); !ok {
+				mismatch("string")
+			}
+			return
+		}
+		arr, ok := value.([]interface{})
+		if !ok {
+			mismatch("array")
+			return
+		}
+		for i, item := range arr {
+			checkValue(item, typ.Elem(), fmt.Sprintf("%s[%d]", path, i), requireFields, problems)
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num, ok := value.(json.Number)
+		if !ok || strings.ContainsAny(num.String(), ".eE") {
+			mismatch("integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(json.Number); !ok {
+			mismatch("number")
+		}
+	}
+}
+
+// jsonField 结构体字段的JSON描述
+type jsonField struct {
+	typ       reflect.Type
+	omitEmpty bool
+}
+
+// jsonFields 按encoding/json规则收集结构体的JSON字段（含匿名嵌入字段）
+func jsonFields(typ reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for key, val := range jsonFields(embedded) {
+					if _, exists := fields[key]; !exists {
+						// 指针嵌入时字段可能整体缺失
+						val.omitEmpty = val.omitEmpty || field.Type.Kind() == reflect.Ptr
+						fields[key] = val
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = jsonField{
+			typ:       field.Type,
+			omitEmpty: strings.Contains(opts, "omitempty") || strings.Contains(opts, "omitzero"),
+		}
+	}
+	return fields
+}
+
+// jsonKind 返回JSON值的类型名
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	}
+	return "null"
+}
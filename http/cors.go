@@ -0,0 +1,133 @@
+package http
+
+import (
+	"errors"
+	"github.com/dfpopp/go-dai/function"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions 跨域配置
+type CORSOptions struct {
+	AllowOrigins     []string                 // 允许的来源（默认["*"]，支持子域通配如 https://*.example.com）
+	AllowOriginFunc  func(origin string) bool // 自定义来源校验（设置后优先于AllowOrigins）
+	AllowMethods     []string                 // 允许的方法（默认GET、POST、PUT、PATCH、DELETE、HEAD、OPTIONS）
+	AllowHeaders     []string                 // 允许的请求头（为空时回显预检请求的Access-Control-Request-Headers）
+	ExposeHeaders    []string                 // 允许前端读取的响应头
+	AllowCredentials bool                     // 是否允许携带Cookie等凭证（开启后须显式列出来源或设置AllowOriginFunc，回显具体来源）
+	MaxAge           time.Duration            // 预检结果缓存时长（0表示不设置）
+}
+
+var defaultCORSMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
+// ErrCORSCredentialsWildcard 允许携带凭证时未显式列出来源（任意网站都将获得带Cookie的跨域访问权限）
+var ErrCORSCredentialsWildcard = errors.New("跨域配置无效：allow_credentials为true时allow_origins须显式列出来源，不能为空或包含*")
+
+// Validate 校验跨域配置
+func (o CORSOptions) Validate() error {
+	if o.AllowCredentials && o.AllowOriginFunc == nil && function.IsWildcardOrigins(o.AllowOrigins) {
+		return ErrCORSCredentialsWildcard
+	}
+	return nil
+}
+
+// AllowOrigin 判断来源是否被允许（可直接作为WS握手的来源校验函数；配置无效时拒绝所有来源）
+func (o CORSOptions) AllowOrigin(origin string) bool {
+	if o.AllowOriginFunc != nil {
+		return o.AllowOriginFunc(origin)
+	}
+	if o.Validate() != nil {
+		return false
+	}
+	if len(o.AllowOrigins) == 0 {
+		return true
+	}
+	return function.MatchOrigin(origin, o.AllowOrigins)
+}
+
+// allowAll 是否允许任意来源（且可以直接返回"*"）
+func (o CORSOptions) allowAll() bool {
+	if o.AllowOriginFunc != nil || o.AllowCredentials {
+		return false
+	}
+	return function.IsWildcardOrigins(o.AllowOrigins)
+}
+
+// CORS 跨域中间件（不传参数时允许所有来源）
+// 预检请求（OPTIONS + Access-Control-Request-Method）在中间件内直接返回204，不进入路由处理器；
+// 来源不被允许时预检返回403，普通请求照常处理但不附加跨域响应头（由浏览器拦截）。
+// 配置未通过Validate（允许凭证但来源为空或*）时panic，配置文件中的该组合在加载时即被拒绝。
+func CORS(opts ...CORSOptions) MiddlewareFunc {
+	var opt CORSOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if err := opt.Validate(); err != nil {
+		panic(err)
+	}
+	allowAll := opt.allowAll()
+	methods := opt.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := strings.ToUpper(strings.Join(methods, ", "))
+	allowHeaders := strings.Join(opt.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(opt.ExposeHeaders, ", ")
+	maxAge := ""
+	if opt.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(opt.MaxAge / time.Second))
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			origin := c.Req.Header.Get("Origin")
+			header := c.Writer.Header()
+			if !allowAll {
+				header.Add("Vary", "Origin")
+			}
+			if origin == "" {
+				next(c)
+				return
+			}
+			preflight := c.Req.Method == http.MethodOptions && c.Req.Header.Get("Access-Control-Request-Method") != ""
+			if !allowAll && !opt.AllowOrigin(origin) {
+				if preflight {
+					c.Writer.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next(c)
+				return
+			}
+			if allowAll {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opt.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if exposeHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				next(c)
+				return
+			}
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", allowHeaders)
+			} else if requested := c.Req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+			if maxAge != "" {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.Writer.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func corsRouter(opt CORSOptions) *Router {
+	r := NewRouter()
+	r.Use(CORS(opt))
+	r.GET("/ping", func(c *Context) { c.Writer.WriteHeader(http.StatusOK) })
+	return r
+}
+
+func TestCORSCredentialsRequireExplicitOrigins(t *testing.T) {
+	for _, origins := range [][]string{nil, {"*"}, {"https://a.com", "*"}} {
+		opt := CORSOptions{AllowOrigins: origins, AllowCredentials: true}
+		if err := opt.Validate(); !errors.Is(err, ErrCORSCredentialsWildcard) {
+			t.Fatalf("origins=%v: err=%v", origins, err)
+		}
+		if opt.AllowOrigin("https://evil.com") {
+			t.Fatalf("origins=%v: 无效配置不应放行任意来源", origins)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("origins=%v: CORS应拒绝该配置", origins)
+				}
+			}()
+			CORS(opt)
+		}()
+	}
+	if err := (CORSOptions{AllowCredentials: true, AllowOriginFunc: func(string) bool { return true }}).Validate(); err != nil {
+		t.Fatalf("自定义来源校验时应允许：%v", err)
+	}
+}
+
+func TestCORSCredentialsEchoListedOrigin(t *testing.T) {
+	r := corsRouter(CORSOptions{AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Fatalf("Allow-Origin=%q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatal("缺少Allow-Credentials")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("未列出的来源不应返回跨域头：%v", w.Header())
+	}
+}
+
+func TestCORSWildcardWithoutCredentials(t *testing.T) {
+	r := corsRouter(CORSOptions{})
+	req := httptest.NewRequest(http.MethodOptions, "/ping", nil)
+	req.Header.Set("Origin", "https://any.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("code=%d header=%v", w.Code, w.Header())
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatal("未开启凭证时不应返回Allow-Credentials")
+	}
+}
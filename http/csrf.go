@@ -0,0 +1,179 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"github.com/dfpopp/go-dai/i18n"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CSRF防护：支持两种令牌存储方式
+//   - 双重提交Cookie（默认）：令牌写入非HttpOnly的Cookie，前端从Cookie读取后通过请求头/表单字段回传，两者一致即通过；
+//   - 同步令牌（UseSession）：令牌保存在服务端会话中（需先注册Sessions中间件），Cookie中不出现令牌。
+// GET/HEAD/OPTIONS/TRACE等安全方法不校验，仅签发令牌；模板中通过 c.CSRFField() 输出隐藏域。
+
+const csrfSessionKey = "_csrf_token"
+
+// CSRFOptions CSRF中间件配置
+type CSRFOptions struct {
+	CookieName string                // 双重提交模式的Cookie名（默认csrf_token）
+	HeaderName string                // 回传令牌的请求头（默认X-CSRF-Token，JSON/AJAX接口使用）
+	FormField  string                // 回传令牌的表单字段（默认_csrf，表单提交使用）
+	Path       string                // Cookie路径（默认/）
+	Domain     string                // Cookie域
+	MaxAge     time.Duration         // Cookie有效期（默认12小时）
+	Secure     bool                  // 仅HTTPS传输
+	SameSite   http.SameSite         // Cookie的SameSite策略（默认Lax）
+	UseSession bool                  // 使用会话保存令牌（同步令牌模式）
+	Exempt     []string              // 免校验的路径（精确匹配，以*结尾时按前缀匹配，如 /api/*）
+	ExemptFunc func(c *Context) bool // 自定义免校验规则（如携带Bearer令牌的API请求）
+}
+
+// csrfState 当前请求的CSRF令牌
+type csrfState struct {
+	token string
+	field string
+}
+
+// CSRF CSRF防护中间件（不传参数时使用双重提交Cookie模式）
+func CSRF(opts ...CSRFOptions) MiddlewareFunc {
+	var opt CSRFOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.CookieName == "" {
+		opt.CookieName = "csrf_token"
+	}
+	if opt.HeaderName == "" {
+		opt.HeaderName = "X-CSRF-Token"
+	}
+	if opt.FormField == "" {
+		opt.FormField = "_csrf"
+	}
+	if opt.Path == "" {
+		opt.Path = "/"
+	}
+	if opt.MaxAge <= 0 {
+		opt.MaxAge = 12 * time.Hour
+	}
+	if opt.SameSite == 0 {
+		opt.SameSite = http.SameSiteLaxMode
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			token := opt.token(c)
+			c.csrf = &csrfState{token: token, field: opt.FormField}
+			if csrfSafeMethod(c.Req.Method) || opt.exempt(c) {
+				next(c)
+				return
+			}
+			submitted := c.Req.Header.Get(opt.HeaderName)
+			if submitted == "" && csrfFormRequest(c.Req) {
+				submitted = c.Req.PostFormValue(opt.FormField)
+			}
+			if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				c.JSON(http.StatusForbidden, map[string]interface{}{
+					"code": http.StatusForbidden,
+					"msg":  c.T(i18n.MsgCSRFFailed),
+					"data": nil,
+				})
+				return
+			}
+			next(c)
+		}
+	}
+}
+
+// token 获取当前令牌，不存在时签发新令牌
+func (opt *CSRFOptions) token(c *Context) string {
+	if opt.UseSession && c.sess != nil {
+		if token := c.sess.GetString(csrfSessionKey); token != "" {
+			return token
+		}
+		token := newCSRFToken()
+		c.sess.Set(csrfSessionKey, token)
+		return token
+	}
+	if cookie, err := c.Req.Cookie(opt.CookieName); err == nil && len(cookie.Value) == 43 {
+		return cookie.Value
+	}
+	token := newCSRFToken()
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     opt.CookieName,
+		Value:    token,
+		Path:     opt.Path,
+		Domain:   opt.Domain,
+		MaxAge:   int(opt.MaxAge / time.Second),
+		Secure:   opt.Secure,
+		HttpOnly: false, // 双重提交模式需要前端脚本读取
+		SameSite: opt.SameSite,
+	})
+	return token
+}
+
+// exempt 判断当前请求是否免校验
+func (opt *CSRFOptions) exempt(c *Context) bool {
+	if pathExempt(c.Req.URL.Path, opt.Exempt) {
+		return true
+	}
+	return opt.ExemptFunc != nil && opt.ExemptFunc(c)
+}
+
+// pathExempt 路径是否命中免校验规则（精确匹配，以*结尾时按前缀匹配）
+func pathExempt(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// CSRFToken 当前请求的CSRF令牌（未启用CSRF中间件时返回空字符串）
+func (c *Context) CSRFToken() string {
+	if c.csrf == nil {
+		return ""
+	}
+	return c.csrf.token
+}
+
+// CSRFField 输出携带CSRF令牌的表单隐藏域（供html/template使用，如 {{.csrfField}}）
+func (c *Context) CSRFField() template.HTML {
+	if c.csrf == nil {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(c.csrf.field) +
+		`" value="` + template.HTMLEscapeString(c.csrf.token) + `">`)
+}
+
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// csrfFormRequest 是否为表单提交（JSON请求只从请求头读取令牌，避免解析请求体）
+func csrfFormRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "application/x-www-form-urlencoded") ||
+		strings.HasPrefix(contentType, "multipart/form-data")
+}
+
+// newCSRFToken 生成CSRF令牌（256位随机数）
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
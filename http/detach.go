@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/logger"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDetachTimeout 脱离请求的context默认最长存活时间
+const DefaultDetachTimeout = 30 * time.Second
+
+var (
+	detachTimeout   atomic.Int64   // Detach的最长存活时间（由服务器配置设置）
+	backgroundTasks sync.WaitGroup // SafeGo启动的后台任务（停机时等待完成）
+	backgroundCount atomic.Int64
+)
+
+func init() {
+	detachTimeout.Store(int64(DefaultDetachTimeout))
+}
+
+// Detach 返回脱离请求生命周期的context及其释放函数：保留请求级的值（request_id日志、链路追踪span等），
+// 不随响应写出、客户端断开或处理器超时而取消，最长存活http.detach_timeout（默认30秒）。
+// 用于响应后的工作（发送通知、刷新缓存等），通常直接使用c.Go
+func (c *Context) Detach() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(c.GetContext()), time.Duration(detachTimeout.Load()))
+}
+
+// Go 在后台协程中执行响应之后的工作：fn收到Detach生成的context（任务结束时自动释放），
+// panic被捕获并记录到请求级日志，服务器停机时等待任务完成（受shutdown_timeout约束）
+func (c *Context) Go(fn func(ctx context.Context)) {
+	ctx, cancel := c.Detach()
+	goSafe(ctx, func() {
+		defer cancel()
+		fn(ctx)
+	})
+}
+
+// SafeGo 启动后台协程执行fn：捕获panic并记录日志，服务器停机时等待任务完成。
+// 处理器中需要请求上下文时请使用c.Go，而不是在协程中直接引用请求context（响应写出后即被取消）
+func SafeGo(fn func()) {
+	goSafe(context.Background(), fn)
+}
+
+// goSafe 启动受追踪的后台协程（ctx仅用于日志关联）
+func goSafe(ctx context.Context, fn func()) {
+	backgroundTasks.Add(1)
+	backgroundCount.Add(1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.FromContext(ctx).Error("后台任务panic：", r, "\n", string(debug.Stack()))
+			}
+			backgroundCount.Add(-1)
+			backgroundTasks.Done()
+		}()
+		fn()
+	}()
+}
+
+// BackgroundTasks 运行中的后台任务数
+func BackgroundTasks() int64 {
+	return backgroundCount.Load()
+}
+
+// waitBackground 等待后台任务完成，ctx到期时返回ctx.Err()
+func waitBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		backgroundTasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"errors"
+	"github.com/dfpopp/go-dai/logger"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 文件下载与流式响应：File/Attachment基于http.ServeContent，支持按扩展名（或内容嗅探）识别MIME、
+// Range断点续传/分段下载、If-Modified-Since/If-Range协商与HEAD请求；Stream以分块传输（chunked）边读边发，
+// 适合导出报表、代理下游文件等无法预知长度的内容。
+// 注意：path为服务器本地路径，由请求参数拼接时须自行校验，防止目录穿越；大文件下载需在route_limits中放宽超时。
+
+// streamChunkSize Stream每次读取并刷新的块大小
+const streamChunkSize = 32 * 1024
+
+// File 发送本地文件（浏览器内联显示，如图片、PDF）
+func (c *Context) File(path string) {
+	c.serveFile(path, "")
+}
+
+// Attachment 以附件形式发送本地文件（浏览器下载），name为下载文件名（为空时取path的文件名，支持中文）
+func (c *Context) Attachment(path, name string) {
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	c.serveFile(path, contentDisposition("attachment", name))
+}
+
+// serveFile 打开文件并交给http.ServeContent（不存在返回404，目录或无权限返回403）
+func (c *Context) serveFile(path, disposition string) {
+	f, err := os.Open(path)
+	if err != nil {
+		c.fileError(path, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		c.fileError(path, err)
+		return
+	}
+	if info.IsDir() {
+		c.fileError(path, fs.ErrPermission)
+		return
+	}
+	if disposition != "" {
+		c.Writer.Header().Set("Content-Disposition", disposition)
+	}
+	http.ServeContent(c.Writer, c.Req, info.Name(), info.ModTime(), f)
+}
+
+func (c *Context) fileError(path string, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(c.Writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(c.Writer, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		logger.Error("发送文件失败：", path, err)
+		http.Error(c.Writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// Stream 以分块传输发送r中的内容（每读取一块立即刷新到客户端；r实现io.Closer时发送完毕后关闭）。
+// contentType为空时按首块内容嗅探；返回读取或写入错误（客户端断开时为写入错误，响应头已发送，仅可记录日志）
+func (c *Context) Stream(contentType string, r io.Reader) error {
+	if closer, ok := r.(io.Closer); ok {
+		defer closer.Close()
+	}
+	header := c.Writer.Header()
+	header.Del("Content-Length")
+	header.Set("X-Accel-Buffering", "no") // 关闭Nginx代理缓冲
+	rc := http.NewResponseController(c.Writer)
+	ctx := c.Req.Context()
+	buf := make([]byte, streamChunkSize)
+	started := false
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if !started {
+				if contentType == "" {
+					contentType = http.DetectContentType(buf[:n])
+				}
+				header.Set("Content-Type", contentType)
+				c.Writer.WriteHeader(http.StatusOK)
+				started = true
+			}
+			if _, err := c.Writer.Write(buf[:n]); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			if !started {
+				logger.Error("流式响应读取失败：", readErr)
+				http.Error(c.Writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return readErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if !started {
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+		c.Writer.WriteHeader(http.StatusOK)
+	}
+	return nil
+}
+
+// contentDisposition 生成Content-Disposition（filename为ASCII兜底名，filename*按RFC 5987携带UTF-8原名）
+func contentDisposition(disposition, name string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+	if fallback == name {
+		return disposition + `; filename="` + name + `"`
+	}
+	return disposition + `; filename="` + fallback + `"; filename*=UTF-8''` + encodeExtValue(name)
+}
+
+// encodeExtValue 按RFC 5987对扩展参数值进行百分号编码（仅保留attr-char）
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || strings.IndexByte("!#$&+-.^_`|~", ch) >= 0 {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&0x0f])
+	}
+	return b.String()
+}
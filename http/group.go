@@ -0,0 +1,98 @@
+package http
+
+import "strings"
+
+// RouterGroup 路由分组（共享路径前缀与中间件，支持嵌套）
+// 中间件执行顺序：全局中间件 -> 外层分组中间件 -> 内层分组中间件 -> 路由局部中间件
+type RouterGroup struct {
+	router      *Router
+	prefix      string           // 完整路径前缀（已包含父分组前缀）
+	middlewares []MiddlewareFunc // 分组中间件（已包含父分组中间件）
+}
+
+// Group 创建路由分组
+func (r *Router) Group(prefix string, middlewares ...MiddlewareFunc) *RouterGroup {
+	return &RouterGroup{
+		router:      r,
+		prefix:      normalizePrefix(prefix),
+		middlewares: append([]MiddlewareFunc(nil), middlewares...),
+	}
+}
+
+// Group 创建嵌套分组（继承当前分组的前缀与中间件）
+func (g *RouterGroup) Group(prefix string, middlewares ...MiddlewareFunc) *RouterGroup {
+	merged := make([]MiddlewareFunc, 0, len(g.middlewares)+len(middlewares))
+	merged = append(merged, g.middlewares...)
+	merged = append(merged, middlewares...)
+	return &RouterGroup{
+		router:      g.router,
+		prefix:      g.prefix + normalizePrefix(prefix),
+		middlewares: merged,
+	}
+}
+
+// Use 追加分组中间件（仅对之后注册的路由生效）
+func (g *RouterGroup) Use(middlewares ...MiddlewareFunc) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// Prefix 获取分组完整路径前缀
+func (g *RouterGroup) Prefix() string {
+	return g.prefix
+}
+
+// Handle 在分组下注册路由（path为相对路径，""或"/"表示分组根路径）
+func (g *RouterGroup) Handle(method, path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	merged := make([]MiddlewareFunc, 0, len(g.middlewares)+len(localMiddlewares))
+	merged = append(merged, g.middlewares...)
+	merged = append(merged, localMiddlewares...)
+	return g.router.Handle(method, g.fullPath(path), handler, merged...)
+}
+
+// GET 在分组下注册GET路由
+func (g *RouterGroup) GET(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return g.Handle("GET", path, handler, localMiddlewares...)
+}
+
+// POST 在分组下注册POST路由
+func (g *RouterGroup) POST(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return g.Handle("POST", path, handler, localMiddlewares...)
+}
+
+// PUT 在分组下注册PUT路由
+func (g *RouterGroup) PUT(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return g.Handle("PUT", path, handler, localMiddlewares...)
+}
+
+// DELETE 在分组下注册DELETE路由
+func (g *RouterGroup) DELETE(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return g.Handle("DELETE", path, handler, localMiddlewares...)
+}
+
+// PATCH 在分组下注册PATCH路由
+func (g *RouterGroup) PATCH(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return g.Handle("PATCH", path, handler, localMiddlewares...)
+}
+
+// fullPath 拼接分组前缀与相对路径
+func (g *RouterGroup) fullPath(path string) string {
+	if path == "" {
+		if g.prefix == "" {
+			return "/"
+		}
+		return g.prefix
+	}
+	if path[0] != '/' {
+		path = "/" + path
+	}
+	return g.prefix + path
+}
+
+// normalizePrefix 规范化分组前缀（补齐开头的"/"，去掉末尾的"/"）
+func normalizePrefix(prefix string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix != "" && prefix[0] != '/' {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
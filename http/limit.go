@@ -0,0 +1,193 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBodyTooLarge 请求体超出BodyLimit（或默认10MB）上限
+var ErrBodyTooLarge = errs.RequestTooLarge.WithMessage("http: 请求体超出大小限制")
+
+// BodyLimitOptions 请求体大小限制
+type BodyLimitOptions struct {
+	MaxBytes int64            // 默认上限（字节，<=0表示不限制）
+	Routes   map[string]int64 // 按路径单独配置（精确匹配，以*结尾时按前缀匹配；<0表示该路径不限制）
+}
+
+// TimeoutOptions 处理器超时
+type TimeoutOptions struct {
+	Timeout time.Duration            // 默认超时（<=0表示不限制）
+	Routes  map[string]time.Duration // 按路径单独配置（匹配规则同BodyLimitOptions；<0表示该路径不限制，如SSE、大文件下载）
+}
+
+// BodyLimit 请求体大小限制中间件：Content-Length超限时直接返回413，
+// 未声明长度（分块传输）时在读取超限后由GetBody/BindJSON等返回错误（GetBody返回ErrBodyTooLarge）
+func BodyLimit(opts BodyLimitOptions) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			limit := opts.MaxBytes
+			if v, ok := matchRoute(opts.Routes, c.Req.URL.Path); ok {
+				limit = v
+			}
+			if limit <= 0 || c.Req.Body == nil || c.Req.Body == http.NoBody {
+				next(c)
+				return
+			}
+			if c.Req.ContentLength > limit {
+				c.Writer.Header().Set("Connection", "close")
+				c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+					"code": http.StatusRequestEntityTooLarge,
+					"msg":  c.T(i18n.MsgRequestTooLarge),
+					"data": nil,
+				})
+				return
+			}
+			c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, limit)
+			c.bodyLimit = limit
+			next(c)
+		}
+	}
+}
+
+// Timeout 处理器超时中间件：超时后取消请求context并返回504，处理器此后的写入被丢弃（返回http.ErrHandlerTimeout）。
+// 处理器在独立goroutine中执行且响应先写入缓冲区，因此不支持流式输出（Flush返回http.ErrNotSupported，c.Stream的内容在处理器完成后一次写出；
+// SSE、WebSocket、大文件下载路径应配置为-1跳过）；处理器应通过c.GetContext()感知取消并尽快返回，超时后仍在运行的处理器不会被强制终止。
+// 处理器按时完成后，其对Context的修改（路径参数、会话等）同步回外层中间件，c.Writer保持为外层的写入器。
+func Timeout(opts TimeoutOptions) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			timeout := opts.Timeout
+			if v, ok := matchRoute(opts.Routes, c.Req.URL.Path); ok {
+				timeout = v
+			}
+			if timeout <= 0 || strings.EqualFold(c.Req.Header.Get("Upgrade"), "websocket") {
+				next(c)
+				return
+			}
+			ctx, cancel := context.WithTimeout(c.GetContext(), timeout)
+			defer cancel()
+			c.SetContext(ctx)
+
+			tw := &timeoutWriter{w: c.Writer, h: c.Writer.Header().Clone()}
+			// 处理器使用上下文副本，超时返回后外层中间件与仍在运行的处理器不会争用同一个Context
+			hc := *c
+			hc.Writer = tw
+			done := make(chan interface{}, 1)
+			go func() {
+				defer func() { done <- recover() }()
+				next(&hc)
+			}()
+			select {
+			case p := <-done:
+				if p != nil {
+					panic(p) // 交由外层Recovery处理
+				}
+				tw.flush()
+				w := c.Writer
+				*c = hc
+				c.Writer = w
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				tw.mu.Unlock()
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return // 客户端已断开，无需响应
+				}
+				logger.FromContext(ctx).Warn("HTTP处理器超时：", c.Req.Method, " ", c.Req.URL.Path, " ", timeout)
+				c.JSON(http.StatusGatewayTimeout, map[string]interface{}{
+					"code": http.StatusGatewayTimeout,
+					"msg":  c.T(i18n.MsgRequestTimeout),
+					"data": nil,
+				})
+			}
+		}
+	}
+}
+
+// timeoutWriter 缓冲处理器输出，处理器按时完成后再统一写出
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	h           http.Header
+	buf         bytes.Buffer
+	mu          sync.Mutex
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.h
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+// flush 将缓冲的响应头与响应体写入原始ResponseWriter
+func (w *timeoutWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dst := w.w.Header()
+	for k := range dst {
+		if _, ok := w.h[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range w.h {
+		dst[k] = v
+	}
+	if !w.wroteHeader {
+		return // 处理器未写任何内容时保持默认行为（由net/http写出200）
+	}
+	w.w.WriteHeader(w.status)
+	_, _ = w.w.Write(w.buf.Bytes())
+}
+
+// FlushError 响应在处理器完成前只写入缓冲区，不支持提前写出（http.ResponseController.Flush返回http.ErrNotSupported）
+func (w *timeoutWriter) FlushError() error {
+	return http.ErrNotSupported
+}
+
+// matchRoute 按路径匹配单独配置（精确匹配优先，其次最长前缀匹配，前缀规则以*结尾）
+func matchRoute[T any](routes map[string]T, path string) (T, bool) {
+	if v, ok := routes[path]; ok {
+		return v, true
+	}
+	var (
+		best    T
+		bestLen = -1
+	)
+	for pattern, v := range routes {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(path, prefix) && len(prefix) > bestLen {
+			best, bestLen = v, len(prefix)
+		}
+	}
+	return best, bestLen >= 0
+}
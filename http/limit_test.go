@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveTimeout 以Timeout中间件执行处理器，返回外层看到的Context与响应
+func serveTimeout(timeout time.Duration, handler HandlerFunc) (*Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest(http.MethodGet, "/download", nil))
+	Timeout(TimeoutOptions{Timeout: timeout})(handler)(c)
+	return c, w
+}
+
+// 超时中间件下c.Stream不提前写出：缓冲的响应头、状态码与内容在处理器完成后一次写出
+func TestTimeoutStreamBuffered(t *testing.T) {
+	var flushErr error
+	_, w := serveTimeout(time.Second, func(c *Context) {
+		c.Writer.Header().Set("X-Trace", "t1")
+		flushErr = http.NewResponseController(c.Writer).Flush()
+		if err := c.Stream("text/plain", strings.NewReader("hello")); err != nil {
+			t.Errorf("Stream: %v", err)
+		}
+	})
+	if !errors.Is(flushErr, http.ErrNotSupported) {
+		t.Fatalf("Flush应返回ErrNotSupported，实际%v", flushErr)
+	}
+	if w.Code != http.StatusOK || w.Header().Get("X-Trace") != "t1" || w.Body.String() != "hello" {
+		t.Fatalf("响应：%d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+	if w.Flushed {
+		t.Fatal("处理器完成前不应写出")
+	}
+}
+
+type ctxKey struct{}
+
+// 处理器按时完成后对Context的修改同步回外层，c.Writer保持外层的写入器
+func TestTimeoutCopiesContextBack(t *testing.T) {
+	c, w := serveTimeout(time.Second, func(c *Context) {
+		c.SetContext(context.WithValue(c.GetContext(), ctxKey{}, "v"))
+		c.Params = map[string]string{"id": "1"}
+		c.Writer.WriteHeader(http.StatusCreated)
+	})
+	if c.GetContext().Value(ctxKey{}) != "v" || c.Params["id"] != "1" {
+		t.Fatalf("外层未看到处理器的修改：%v %v", c.GetContext().Value(ctxKey{}), c.Params)
+	}
+	if c.Writer != http.ResponseWriter(w) || w.Code != http.StatusCreated {
+		t.Fatalf("外层写入器%T，状态码%d", c.Writer, w.Code)
+	}
+}
+
+// 超时返回504，处理器此后的写入被丢弃
+func TestTimeoutDiscardsLateWrites(t *testing.T) {
+	late := make(chan error, 1)
+	c, w := serveTimeout(20*time.Millisecond, func(c *Context) {
+		<-c.GetContext().Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := c.Writer.Write([]byte("late"))
+		late <- err
+	})
+	if w.Code != http.StatusGatewayTimeout || strings.Contains(w.Body.String(), "late") {
+		t.Fatalf("响应：%d %q", w.Code, w.Body.String())
+	}
+	if err := <-late; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Fatalf("超时后写入应返回ErrHandlerTimeout，实际%v", err)
+	}
+	if c.Writer != http.ResponseWriter(w) {
+		t.Fatal("超时后外层写入器不应改变")
+	}
+}
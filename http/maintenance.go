@@ -0,0 +1,36 @@
+package http
+
+import (
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/i18n"
+	"net/http"
+)
+
+// Maintenance 维护/只读模式中间件（开关由管理接口在线切换，见config.InMaintenance/IsReadOnly）：
+// 维护模式下所有请求返回503，只读模式下仅放行GET/HEAD/OPTIONS请求；exempt为免拦截的路径（规则同CSRF Exempt）
+func Maintenance(exempt ...string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			msg := ""
+			if config.InMaintenance() {
+				msg = i18n.MsgMaintenance
+			} else if config.IsReadOnly() {
+				switch c.Req.Method {
+				case http.MethodGet, http.MethodHead, http.MethodOptions:
+				default:
+					msg = i18n.MsgReadOnly
+				}
+			}
+			if msg == "" || pathExempt(c.Req.URL.Path, exempt) {
+				next(c)
+				return
+			}
+			c.Writer.Header().Set("Retry-After", "60")
+			c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+				"code": http.StatusServiceUnavailable,
+				"msg":  c.T(msg),
+				"data": nil,
+			})
+		}
+	}
+}
@@ -2,7 +2,12 @@ package http
 
 import (
 	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"net/http"
+	"strconv"
 )
 
 // HandlerFunc 自定义HTTP处理器
@@ -29,18 +34,57 @@ func Recovery() MiddlewareFunc {
 	}
 }
 
-// CORS 跨域中间件（默认实现）
-func CORS() MiddlewareFunc {
+// RequestID 请求ID中间件（沿用上游X-Request-Id或生成新ID，写入响应头并绑定请求级日志，通过logger.FromContext获取）；
+// 开启日志缓冲时，请求panic或响应5xx才输出缓冲的debug/info日志（见logger.StartBuffer）
+func RequestID() MiddlewareFunc {
 	return func(next HandlerFunc) HandlerFunc {
 		return func(c *Context) {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "*") // 允许所有域
-			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "*")
-			if c.Req.Method == "OPTIONS" {
-				c.Writer.WriteHeader(http.StatusOK)
+			requestID := c.Req.Header.Get(logger.RequestIDHeader)
+			if requestID == "" {
+				requestID = logger.NewRequestID()
+			}
+			c.Writer.Header().Set(logger.RequestIDHeader, requestID)
+			ctx, buf := logger.StartBuffer(logger.WithRequestID(c.GetContext(), requestID))
+			c.SetContext(ctx)
+			if buf == nil {
+				next(c)
 				return
 			}
+			rec := newResponseRecorder(c.Writer)
+			c.Writer = rec
+			finished := false
+			defer func() {
+				buf.Finish(!finished || rec.status >= http.StatusInternalServerError)
+			}()
 			next(c)
+			finished = true
+		}
+	}
+}
+
+// Tracing 链路追踪中间件（解析W3C traceparent，为每个请求开启服务端span，未启用追踪时直接放行）
+func Tracing() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if !tracing.Enabled() {
+				next(c)
+				return
+			}
+			ctx := tracing.Extract(c.Req.Context(), propagation.HeaderCarrier(c.Req.Header))
+			ctx, span := tracing.StartServerSpan(ctx, c.Req.Method+" "+c.Req.URL.Path,
+				attribute.String("http.method", c.Req.Method),
+				attribute.String("http.target", c.Req.URL.Path),
+				attribute.String("net.peer.ip", c.GetClientIP()),
+			)
+			defer span.End()
+			c.SetContext(ctx)
+			rec := newResponseRecorder(c.Writer)
+			c.Writer = rec
+			next(c)
+			span.SetAttributes(attribute.Int("http.status_code", rec.status))
+			if rec.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, "HTTP "+strconv.Itoa(rec.status))
+			}
 		}
 	}
 }
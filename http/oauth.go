@@ -0,0 +1,132 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"net/http"
+	"strings"
+)
+
+// 第三方登录路由示例（:provider为配置中的提供方名称）：
+//
+//	o, _ := auth.OAuthFromAppConfig(appName)
+//	server.GET("/auth/:provider/login", http.OAuthLogin(o))
+//	server.GET("/auth/:provider/callback", http.OAuthCallback(o, http.OAuthCallbackOptions{Login: userService.LoginByOAuth}))
+
+// OAuthCallbackOptions 第三方登录回调参数
+type OAuthCallbackOptions struct {
+	// Login 将第三方身份关联或注册为本地用户，返回本地用户的声明（必填）
+	Login func(c *Context, result *auth.OAuthResult) (*auth.Claims, error)
+	// JWT 设置时签发令牌对并以JSON返回（适用于前后端分离），否则重定向到登录前的页面
+	JWT *auth.JWT
+	// DefaultReturnTo 未指定回跳地址时的默认地址（默认/）
+	DefaultReturnTo string
+	// OnError 登录失败时的响应（默认返回401 JSON）
+	OnError func(c *Context, err error)
+}
+
+// OAuthLogin 发起第三方登录：跳转到提供方授权页，查询参数return_to为登录成功后的回跳地址（仅允许站内相对路径）
+func OAuthLogin(o *auth.OAuth) HandlerFunc {
+	return func(c *Context) {
+		authURL, err := o.Begin(c.GetContext(), c.GetParam("provider"), safeReturnTo(c.Query("return_to")))
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, auth.ErrOAuthProvider) {
+				code = http.StatusNotFound
+			}
+			logger.FromContext(c.GetContext()).Warn("发起第三方登录失败：", err)
+			c.JSON(code, map[string]interface{}{
+				"code": code,
+				"msg":  c.T(i18n.MsgOAuthFailed),
+				"data": nil,
+			})
+			return
+		}
+		http.Redirect(c.Writer, c.Req, authURL, http.StatusFound)
+	}
+}
+
+// OAuthCallback 第三方登录回调：校验state、换取令牌与用户信息后调用Login关联本地用户，
+// 启用Sessions中间件时重新生成会话ID（防止会话固定）并写入登录态，设置JWT时签发令牌对
+func OAuthCallback(o *auth.OAuth, opts OAuthCallbackOptions) HandlerFunc {
+	if opts.DefaultReturnTo == "" {
+		opts.DefaultReturnTo = "/"
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(c *Context, _ error) {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"code": http.StatusUnauthorized,
+				"msg":  c.T(i18n.MsgOAuthFailed),
+				"data": nil,
+			})
+		}
+	}
+	return func(c *Context) {
+		ctx := c.GetContext()
+		fail := func(err error) {
+			logger.FromContext(ctx).Warn("第三方登录失败：", err)
+			opts.OnError(c, err)
+		}
+		if e := c.Query("error"); e != "" {
+			fail(fmt.Errorf("%w：%s %s", auth.ErrOAuthDenied, e, c.Query("error_description")))
+			return
+		}
+		result, err := o.Complete(ctx, c.GetParam("provider"), c.Query("state"), c.Query("code"))
+		if err != nil {
+			fail(err)
+			return
+		}
+		if opts.Login == nil {
+			fail(errors.New("http: OAuthCallback未设置Login"))
+			return
+		}
+		claims, err := opts.Login(c, result)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if sess := c.Session(); sess != nil {
+			sess.Regenerate()
+			sess.Set(auth.ParamUserID, claims.UserID)
+			sess.Set(auth.ParamRoles, strings.Join(claims.Roles, ","))
+			sess.Set(sessionKeyProvider, result.Provider)
+			if err := sess.Err(); err != nil {
+				fail(err) // 会话存储不可用时登录状态无法保存
+				return
+			}
+		}
+		auth.Bind(c, claims)
+		returnTo := safeReturnTo(result.ReturnTo)
+		if returnTo == "" {
+			returnTo = opts.DefaultReturnTo
+		}
+		if opts.JWT == nil {
+			http.Redirect(c.Writer, c.Req, returnTo, http.StatusFound)
+			return
+		}
+		pair, err := opts.JWT.IssuePair(ctx, *claims)
+		if err != nil {
+			fail(err)
+			return
+		}
+		c.JSON(http.StatusOK, map[string]interface{}{
+			"code": 200,
+			"msg":  "success",
+			"data": map[string]interface{}{"token": pair, "return_to": returnTo},
+		})
+	}
+}
+
+// sessionKeyProvider 会话中记录登录来源的key
+const sessionKeyProvider = "auth_provider"
+
+// safeReturnTo 仅允许站内相对路径，防止开放重定向
+func safeReturnTo(s string) string {
+	if !strings.HasPrefix(s, "/") || strings.HasPrefix(s, "//") || strings.HasPrefix(s, "/\\") || strings.ContainsAny(s, "\r\n") {
+		return ""
+	}
+	return s
+}
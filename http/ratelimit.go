@@ -0,0 +1,34 @@
+package http
+
+import (
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"net/http"
+	"strconv"
+)
+
+// RateLimit 限流中间件：超限时返回429与Retry-After，并通过X-RateLimit-Limit/X-RateLimit-Remaining告知配额
+func RateLimit(policy *ratelimit.Policy) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		if policy == nil {
+			return next
+		}
+		return func(c *Context) {
+			result := policy.Check(c)
+			if result.Limit > 0 {
+				c.Writer.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+				c.Writer.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			}
+			if !result.Allowed {
+				c.Writer.Header().Set("Retry-After", strconv.Itoa(result.RetryAfterSeconds()))
+				c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+					"code": http.StatusTooManyRequests,
+					"msg":  c.T(i18n.MsgRateLimited),
+					"data": nil,
+				})
+				return
+			}
+			next(c)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/ratelimit"
+)
+
+func TestRateLimitRunsAfterAuthMiddleware(t *testing.T) {
+	policy, err := ratelimit.NewPolicy(config.RateLimitConfig{Enable: true, Rate: 1, Period: 60, Key: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter()
+	r.useInner(RateLimit(policy))
+	// 模拟认证中间件：在路由上写入用户（注册在限流之后，但应先于限流执行）
+	authenticate := func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			c.SetContext(ratelimit.WithUser(c.GetContext(), c.Req.Header.Get("X-User")))
+			next(c)
+		}
+	}
+	r.GET("/me", func(c *Context) { c.Writer.WriteHeader(http.StatusOK) }, authenticate)
+
+	do := func(user string) int {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.RemoteAddr = "203.0.113.7:1000" // 同一IP的不同用户
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if do("alice") != http.StatusOK || do("bob") != http.StatusOK {
+		t.Fatal("不同用户应分别计数（限流未读取到认证写入的用户）")
+	}
+	if code := do("alice"); code != http.StatusTooManyRequests {
+		t.Fatalf("同一用户超限应返回429，got %d", code)
+	}
+}
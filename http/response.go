@@ -0,0 +1,63 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// responseRecorder 记录响应状态码与字节数的ResponseWriter包装（保留Flusher/Hijacker能力，SSE/WS不受影响）
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int  // 响应状态码
+	size        int  // 已写入的响应体字节数
+	wroteHeader bool // 是否已写入响应头
+}
+
+// newResponseRecorder 包装ResponseWriter（已包装过的直接复用，避免多层中间件重复包装）
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	if rec, ok := w.(*responseRecorder); ok {
+		return rec
+	}
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = code
+	r.wroteHeader = true
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+// Flush 透传http.Flusher（SSE依赖）
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 透传http.Hijacker（WS升级依赖）
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijack")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap 供http.ResponseController获取原始ResponseWriter
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
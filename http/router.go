@@ -2,27 +2,104 @@ package http
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 )
 
 // Router HTTP路由器（框架内置，负责路由注册、映射存储与中间件链构建）
+// 路由规则（按路径分段匹配，优先级：静态段 > 参数段 > 通配段）：
+//
+//	/users/:id        路径参数，通过 c.Param("id") 获取
+//	/static/*filepath 通配参数，匹配剩余全部路径（可为空），通过 c.Param("filepath") 获取
+//	/api/             以"/"结尾的路径兼容ServeMux的子树匹配语义，匹配 /api/ 下所有未注册的路径
+//
+// 按请求方法匹配：静态段未注册该方法时回溯到参数/通配段（GET /users/:id 与 POST /users/new 并存时，GET /users/new 由前者处理）；
+// 路径存在但方法均未注册时返回405并携带Allow头（汇总匹配该路径的全部路由）；未注册HEAD时由GET处理。
 type Router struct {
-	mux               *http.ServeMux         // 系统ServeMux，负责HTTP请求分发
+	root              *routeNode             // 路由树根节点
 	handlers          map[string]HandlerFunc // 存储「method+path」与处理器的映射
 	globalMiddlewares []MiddlewareFunc       // 全局中间件
+	innerMiddlewares  []MiddlewareFunc       // 内层中间件（在全局、分组与路由中间件之后执行，如依赖认证结果的限流）
+	routes            []*Route               // 路由元数据（注册顺序）
+}
+
+// routeNode 路由树节点（按路径段组织）
+type routeNode struct {
+	segment   string                 // 当前路径段（参数段为":name"，通配段为"*name"）
+	static    map[string]*routeNode  // 静态子节点
+	param     *routeNode             // 参数子节点
+	wildcard  *routeNode             // 通配子节点（仅能位于末尾）
+	paramName string                 // 参数/通配名
+	handlers  map[string]HandlerFunc // method -> 处理器
+	pattern   string                 // 注册时的完整路径
 }
 
 // NewRouter 创建HTTP路由器实例
 func NewRouter() *Router {
 	return &Router{
-		mux:               http.NewServeMux(),
+		root:              newRouteNode(""),
 		handlers:          make(map[string]HandlerFunc),
 		globalMiddlewares: make([]MiddlewareFunc, 0),
 	}
 }
 
+func newRouteNode(segment string) *routeNode {
+	return &routeNode{segment: segment, static: make(map[string]*routeNode)}
+}
+
 // ServeHTTP 实现http.Handler接口，兼容系统HTTP服务
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	r.mux.ServeHTTP(w, req)
+	segments := splitPath(req.URL.Path)
+	params := make(map[string]string)
+	node := r.root.match(segments, params, func(n *routeNode) bool {
+		_, ok := n.handler(req.Method)
+		return ok
+	})
+	if node != nil {
+		handler, _ := node.handler(req.Method)
+		ctx := NewContext(w, req)
+		ctx.Params = params
+		handler(ctx)
+		return
+	}
+	// 汇总匹配该路径的全部路由已注册的方法（判定函数始终返回false，遍历所有候选节点）
+	methods := make(map[string]struct{})
+	r.root.match(segments, params, func(n *routeNode) bool {
+		for method := range n.handlers {
+			methods[method] = struct{}{}
+		}
+		return false
+	})
+	if len(methods) == 0 {
+		r.serveFallback(w, req, nil, notFoundHandler)
+		return
+	}
+	allow := allowHeader(methods)
+	if req.Method == http.MethodOptions {
+		// 未显式注册OPTIONS时交由全局中间件处理（如CORS预检），否则返回204与Allow头
+		r.serveFallback(w, req, nil, func(c *Context) {
+			c.Writer.Header().Set("Allow", allow)
+			c.Writer.WriteHeader(http.StatusNoContent)
+		})
+		return
+	}
+	r.serveFallback(w, req, nil, func(c *Context) {
+		c.Writer.Header().Set("Allow", allow)
+		http.Error(c.Writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	})
+}
+
+// serveFallback 经过全局中间件执行404/405等兜底处理（保证请求ID、访问日志等中间件对其同样生效）
+func (r *Router) serveFallback(w http.ResponseWriter, req *http.Request, params map[string]string, handler HandlerFunc) {
+	ctx := NewContext(w, req)
+	if params != nil {
+		ctx.Params = params
+	}
+	r.buildChain(handler, nil)(ctx)
+}
+
+func notFoundHandler(c *Context) {
+	http.NotFound(c.Writer, c.Req)
 }
 
 // Use 注册全局中间件
@@ -30,10 +107,19 @@ func (r *Router) Use(middlewares ...MiddlewareFunc) {
 	r.globalMiddlewares = append(r.globalMiddlewares, middlewares...)
 }
 
+// useInner 注册内层中间件（须在注册路由前调用）：在全局与局部中间件之后执行，
+// 使其能读取认证中间件写入的用户信息
+func (r *Router) useInner(middlewares ...MiddlewareFunc) {
+	r.innerMiddlewares = append(r.innerMiddlewares, middlewares...)
+}
+
 // buildChain 构建中间件链（内部方法）
 func (r *Router) buildChain(handler HandlerFunc, localMiddlewares []MiddlewareFunc) HandlerFunc {
-	// 合并全局中间件与局部中间件
-	allMiddlewares := append(r.globalMiddlewares, localMiddlewares...)
+	// 合并全局、局部与内层中间件（拷贝，避免append共享底层数组）
+	allMiddlewares := make([]MiddlewareFunc, 0, len(r.globalMiddlewares)+len(localMiddlewares)+len(r.innerMiddlewares))
+	allMiddlewares = append(allMiddlewares, r.globalMiddlewares...)
+	allMiddlewares = append(allMiddlewares, localMiddlewares...)
+	allMiddlewares = append(allMiddlewares, r.innerMiddlewares...)
 	finalHandler := handler
 
 	// 倒序构建中间件链
@@ -45,42 +131,179 @@ func (r *Router) buildChain(handler HandlerFunc, localMiddlewares []MiddlewareFu
 	return finalHandler
 }
 
-// wrapHandler 包装处理器为http.HandlerFunc（内部方法）
-func (r *Router) wrapHandler(handler HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, req *http.Request) {
-		ctx := NewContext(w, req)
-		handler(ctx)
-	}
-}
-
 // Handle 注册通用路由（核心方法，接收HTTP方法、路径、处理器与局部中间件）
-func (r *Router) Handle(method, path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) {
-	// 1. 构建完整中间件链
-	chainHandler := r.buildChain(handler, localMiddlewares)
-	// 2. 生成唯一路由键（method + path）
+func (r *Router) Handle(method, path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	if path == "" || path[0] != '/' {
+		panic("http: 路由路径必须以/开头：" + path)
+	}
+	method = strings.ToUpper(method)
+	// 1. 生成唯一路由键（method + path）
 	routeKey := method + " " + path
+	if _, exists := r.handlers[routeKey]; exists {
+		panic("http: 路由重复注册：" + routeKey)
+	}
+	// 2. 构建完整中间件链（最内层为契约校验，声明结构后生效）
+	route := &Route{Method: method, Path: path}
+	r.routes = append(r.routes, route)
+	chainHandler := r.buildChain(contractHandler(route, handler), localMiddlewares)
 	// 3. 存储路由映射
 	r.handlers[routeKey] = chainHandler
-	// 4. 注册到系统ServeMux
-	r.mux.HandleFunc(path, r.wrapHandler(chainHandler))
+	// 4. 插入路由树（以"/"结尾的路径按子树匹配处理）
+	segments := splitPath(path)
+	if strings.HasSuffix(path, "/") && path != "/" {
+		segments = append(segments, "*")
+	}
+	if path == "/" {
+		segments = []string{"*"}
+	}
+	node := r.root.insert(segments, path)
+	if node.handlers == nil {
+		node.handlers = make(map[string]HandlerFunc)
+	}
+	node.handlers[method] = chainHandler
+	node.pattern = path
+	return route
+}
+
+// WrapHandler 将标准库http.Handler包装为框架处理器（如挂载WS握手处理器：router.GET("/ws", http.WrapHandler(wsServer.Handler()))），
+// 包装前中间件设置的请求级context（request_id、追踪等）随c.Req传递给h
+func WrapHandler(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Req)
+	}
 }
 
 // GET 快捷注册GET请求路由
-func (r *Router) GET(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) {
-	r.Handle("GET", path, handler, localMiddlewares...)
+func (r *Router) GET(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return r.Handle("GET", path, handler, localMiddlewares...)
 }
 
 // POST 快捷注册POST请求路由
-func (r *Router) POST(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) {
-	r.Handle("POST", path, handler, localMiddlewares...)
+func (r *Router) POST(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return r.Handle("POST", path, handler, localMiddlewares...)
 }
 
 // PUT 快捷注册PUT请求路由（可选扩展，保持风格一致）
-func (r *Router) PUT(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) {
-	r.Handle("PUT", path, handler, localMiddlewares...)
+func (r *Router) PUT(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return r.Handle("PUT", path, handler, localMiddlewares...)
 }
 
 // DELETE 快捷注册DELETE请求路由（可选扩展，保持风格一致）
-func (r *Router) DELETE(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) {
-	r.Handle("DELETE", path, handler, localMiddlewares...)
+func (r *Router) DELETE(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return r.Handle("DELETE", path, handler, localMiddlewares...)
+}
+
+// PATCH 快捷注册PATCH请求路由
+func (r *Router) PATCH(path string, handler HandlerFunc, localMiddlewares ...MiddlewareFunc) *Route {
+	return r.Handle("PATCH", path, handler, localMiddlewares...)
+}
+
+// splitPath 按"/"拆分路径（忽略首尾及连续的"/"）
+func splitPath(path string) []string {
+	parts := strings.Split(path, "/")
+	segments := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			segments = append(segments, part)
+		}
+	}
+	return segments
+}
+
+// insert 插入路由段，返回末端节点
+func (n *routeNode) insert(segments []string, pattern string) *routeNode {
+	if len(segments) == 0 {
+		return n
+	}
+	segment := segments[0]
+	switch segment[0] {
+	case ':':
+		name := segment[1:]
+		if name == "" {
+			panic("http: 路径参数缺少名称：" + pattern)
+		}
+		if n.param == nil {
+			n.param = newRouteNode(segment)
+			n.param.paramName = name
+		} else if n.param.paramName != name {
+			panic("http: 路径参数名冲突（:" + n.param.paramName + " 与 " + segment + "）：" + pattern)
+		}
+		return n.param.insert(segments[1:], pattern)
+	case '*':
+		if len(segments) > 1 {
+			panic("http: 通配参数只能位于路径末尾：" + pattern)
+		}
+		name := segment[1:]
+		if n.wildcard == nil {
+			n.wildcard = newRouteNode(segment)
+			n.wildcard.paramName = name
+		} else if n.wildcard.paramName != name {
+			panic("http: 通配参数名冲突（" + n.wildcard.segment + " 与 " + segment + "）：" + pattern)
+		}
+		return n.wildcard
+	}
+	child, ok := n.static[segment]
+	if !ok {
+		child = newRouteNode(segment)
+		n.static[segment] = child
+	}
+	return child.insert(segments[1:], pattern)
+}
+
+// match 匹配路径段（静态优先，失败时回溯尝试参数与通配），返回首个满足accept的末端节点，命中时写入params
+func (n *routeNode) match(segments []string, params map[string]string, accept func(*routeNode) bool) *routeNode {
+	if len(segments) == 0 {
+		if n.handlers != nil && accept(n) {
+			return n
+		}
+		// 末尾通配可匹配空路径
+		if n.wildcard != nil && n.wildcard.handlers != nil && accept(n.wildcard) {
+			if n.wildcard.paramName != "" {
+				params[n.wildcard.paramName] = ""
+			}
+			return n.wildcard
+		}
+		return nil
+	}
+	if child, ok := n.static[segments[0]]; ok {
+		if found := child.match(segments[1:], params, accept); found != nil {
+			return found
+		}
+	}
+	if n.param != nil {
+		if found := n.param.match(segments[1:], params, accept); found != nil {
+			params[n.param.paramName] = segments[0]
+			return found
+		}
+	}
+	if n.wildcard != nil && n.wildcard.handlers != nil && accept(n.wildcard) {
+		if n.wildcard.paramName != "" {
+			params[n.wildcard.paramName] = strings.Join(segments, "/")
+		}
+		return n.wildcard
+	}
+	return nil
+}
+
+// handler 获取方法对应的处理器（未注册HEAD时由GET处理）
+func (n *routeNode) handler(method string) (HandlerFunc, bool) {
+	handler, ok := n.handlers[method]
+	if !ok && method == http.MethodHead {
+		handler, ok = n.handlers[http.MethodGet]
+	}
+	return handler, ok
+}
+
+// allowHeader 生成Allow响应头（已注册GET时隐含HEAD，OPTIONS始终可用）
+func allowHeader(methods map[string]struct{}) string {
+	if _, ok := methods[http.MethodGet]; ok {
+		methods[http.MethodHead] = struct{}{}
+	}
+	methods[http.MethodOptions] = struct{}{}
+	list := make([]string, 0, len(methods))
+	for method := range methods {
+		list = append(list, method)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
 }
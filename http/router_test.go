@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// echoRoute 返回路由名与指定路径参数
+func echoRoute(name, param string) HandlerFunc {
+	return func(c *Context) {
+		body := name
+		if param != "" {
+			body += ":" + c.Params[param]
+		}
+		c.Writer.WriteHeader(http.StatusOK)
+		_, _ = c.Writer.Write([]byte(body))
+	}
+}
+
+func TestRouterMatch(t *testing.T) {
+	r := NewRouter()
+	r.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			c.Writer.Header().Set("X-Global", "1")
+			next(c)
+		}
+	})
+	r.GET("/users/:id", echoRoute("user", "id"))
+	r.GET("/users/me", echoRoute("me", ""))
+	r.GET("/users/me/settings", echoRoute("settings", ""))
+	r.GET("/users/:id/posts", echoRoute("posts", "id"))
+	r.POST("/users/new", echoRoute("create", ""))
+	r.GET("/files/*path", echoRoute("files", "path"))
+	r.PUT("/items/:id", echoRoute("put", "id"))
+	r.DELETE("/items/:id", echoRoute("delete", "id"))
+	r.Handle(http.MethodOptions, "/items/:id", echoRoute("options", "id"))
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+		allow  string
+	}{
+		{"静态段优先", http.MethodGet, "/users/me", 200, "me", ""},
+		{"参数段", http.MethodGet, "/users/42", 200, "user:42", ""},
+		{"静态段无后续时回溯到参数段", http.MethodGet, "/users/me/posts", 200, "posts:me", ""},
+		{"静态段的深层路由", http.MethodGet, "/users/me/settings", 200, "settings", ""},
+		{"静态段缺少方法时回溯到参数段", http.MethodGet, "/users/new", 200, "user:new", ""},
+		{"静态段的方法", http.MethodPost, "/users/new", 200, "create", ""},
+		{"通配剩余为空", http.MethodGet, "/files", 200, "files:", ""},
+		{"通配剩余为空（末尾斜杠）", http.MethodGet, "/files/", 200, "files:", ""},
+		{"通配多段", http.MethodGet, "/files/a/b.txt", 200, "files:a/b.txt", ""},
+		{"HEAD由GET处理", http.MethodHead, "/users/42", 200, "user:42", ""},
+		{"405", http.MethodPost, "/users/42", 405, "", "GET, HEAD, OPTIONS"},
+		{"405汇总静态与参数路由", http.MethodDelete, "/users/new", 405, "", "GET, HEAD, OPTIONS, POST"},
+		{"405无GET时不含HEAD", http.MethodPatch, "/items/1", 405, "", "DELETE, OPTIONS, PUT"},
+		{"OPTIONS兜底", http.MethodOptions, "/users/42", 204, "", "GET, HEAD, OPTIONS"},
+		{"显式注册OPTIONS", http.MethodOptions, "/items/1", 200, "options:1", ""},
+		{"404", http.MethodGet, "/missing", 404, "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.code {
+				t.Fatalf("状态码%d，期望%d（%q）", w.Code, tc.code, w.Body.String())
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Fatalf("响应%q，期望%q", w.Body.String(), tc.body)
+			}
+			if got := w.Header().Get("Allow"); got != tc.allow {
+				t.Fatalf("Allow=%q，期望%q", got, tc.allow)
+			}
+			// 兜底响应（404/405/OPTIONS）同样经过全局中间件
+			if w.Header().Get("X-Global") != "1" {
+				t.Fatal("未经过全局中间件")
+			}
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package http
 
 import (
+	"context"
+	"github.com/dfpopp/go-dai/auth"
 	"github.com/dfpopp/go-dai/config"
 	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/ratelimit"
+	"github.com/dfpopp/go-dai/session"
+	"net"
 	"net/http"
 	"time"
 )
@@ -11,20 +16,33 @@ var ErrServerClosed = http.ErrServerClosed
 
 // ServerConfig HTTP服务器配置（原有逻辑不变）
 type ServerConfig struct {
-	Addr           string        // 监听地址（ip:port）
-	ReadTimeout    time.Duration // 读超时
-	WriteTimeout   time.Duration // 写超时
-	MaxHeaderBytes int           // 最大请求头大小
-	SSL            bool          // 是否启用SSL
-	SSLCertFile    string        // SSL证书路径
-	SSLKeyFile     string        // SSL密钥路径
+	Addr              string            // 监听地址（ip:port）
+	ReadTimeout       time.Duration     // 读超时
+	ReadHeaderTimeout time.Duration     // 读取请求头超时
+	WriteTimeout      time.Duration     // 写超时
+	IdleTimeout       time.Duration     // Keep-Alive空闲连接超时
+	ShutdownTimeout   time.Duration     // 停机等待超时（默认30秒）
+	DetachTimeout     time.Duration     // 脱离请求的后台任务context最长存活时间（默认30秒）
+	MaxHeaderBytes    int               // 最大请求头大小
+	SSL               bool              // 是否启用SSL
+	SSLCertFile       string            // SSL证书路径
+	SSLKeyFile        string            // SSL密钥路径
+	MTLS              *auth.MTLSOptions // 客户端证书校验（SSL启用时生效，为nil时不校验）
+	CORS              CORSOptions       // 默认跨域中间件配置
+	BodyLimit         BodyLimitOptions  // 请求体大小限制（未配置时不启用）
+	Timeout           TimeoutOptions    // 处理器超时（未配置时不启用）
+	AccessLog         *AccessLogOptions // 访问日志（未启用时为nil）
+	Template          *TemplateOptions  // HTML模板（未配置模板目录时为nil）
 }
 
 // Server HTTP服务器（门面角色，负责服务生命周期管理）
 type Server struct {
-	config *ServerConfig
-	router *Router      // 注入的HTTP路由器
-	server *http.Server // 系统HTTP服务实例
+	config   *ServerConfig
+	router   *Router      // 注入的HTTP路由器
+	server   *http.Server // 系统HTTP服务实例
+	listener net.Listener // 监听器（平滑重启时由父进程继承而来）
+
+	templates *TemplateRenderer // HTML模板渲染器（SetTemplates设置）
 }
 
 // NewServer 创建HTTP服务器实例
@@ -35,14 +53,40 @@ func NewServer(appName string) *Server {
 		config: cfg,
 		router: router, // 默认初始化路由器，也可通过SetRouter替换
 		server: &http.Server{
-			Addr:           cfg.Addr,
-			ReadTimeout:    cfg.ReadTimeout,
-			WriteTimeout:   cfg.WriteTimeout,
-			MaxHeaderBytes: cfg.MaxHeaderBytes,
-			Handler:        router, // 临时占位，SetRouter会覆盖
+			Addr:              cfg.Addr,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+			Handler:           router, // 临时占位，SetRouter会覆盖
 		},
 	}
-	serv.Use(CORS())
+	detachTimeout.Store(int64(cfg.DetachTimeout))
+	if cfg.AccessLog != nil {
+		serv.Use(AccessLog(*cfg.AccessLog))
+	}
+	serv.Use(CORS(cfg.CORS))
+	if cfg.BodyLimit.MaxBytes > 0 || len(cfg.BodyLimit.Routes) > 0 {
+		serv.Use(BodyLimit(cfg.BodyLimit))
+	}
+	if cfg.Timeout.Timeout > 0 || len(cfg.Timeout.Routes) > 0 {
+		serv.Use(Timeout(cfg.Timeout))
+	}
+	if policy, err := ratelimit.FromAppConfig(appName); err != nil {
+		logger.Error("HTTP限流配置无效：", err)
+	} else if policy != nil {
+		// 限流在全局与路由中间件（含认证）之后执行，按用户限流时可读取认证写入的用户
+		serv.router.useInner(RateLimit(policy))
+	}
+	if manager, err := session.FromAppConfig(appName); err != nil {
+		logger.Error("HTTP会话配置无效：", err)
+	} else if manager != nil {
+		serv.Use(Sessions(manager))
+	}
+	if cfg.Template != nil {
+		serv.SetTemplates(NewTemplateRenderer(*cfg.Template))
+	}
 	return serv
 }
 
@@ -51,62 +95,193 @@ func (s *Server) Config() *ServerConfig {
 	return s.config
 }
 
+// SetTemplates 设置HTML模板渲染器（c.HTML使用，须在注册路由前调用；配置了http.template时NewServer已自动设置）
+func (s *Server) SetTemplates(r *TemplateRenderer) {
+	s.templates = r
+	s.Use(Templates(r))
+}
+
+// Templates 获取HTML模板渲染器（未启用时为nil），可用于追加模板函数：srv.Templates().Funcs(template.FuncMap{...})
+func (s *Server) Templates() *TemplateRenderer {
+	return s.templates
+}
+
 // Use 注册全局中间件（门面方法，委托给Router）
 func (s *Server) Use(middlewares ...MiddlewareFunc) {
 	s.router.Use(middlewares...)
 }
 
 // Handle 注册通用路由（门面方法，委托给Router）
-func (s *Server) Handle(method, path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	s.router.Handle(method, path, handler, middlewares...)
+func (s *Server) Handle(method, path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return s.router.Handle(method, path, handler, middlewares...)
 }
 
 // GET 快捷注册GET路由（门面方法，委托给Router）
-func (s *Server) GET(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	s.router.GET(path, handler, middlewares...)
+func (s *Server) GET(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return s.router.GET(path, handler, middlewares...)
 }
 
 // POST 快捷注册POST路由（门面方法，委托给Router）
-func (s *Server) POST(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	s.router.POST(path, handler, middlewares...)
+func (s *Server) POST(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return s.router.POST(path, handler, middlewares...)
 }
 
 // PUT 快捷注册PUT路由（门面方法，委托给Router）
-func (s *Server) PUT(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	s.router.PUT(path, handler, middlewares...)
+func (s *Server) PUT(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return s.router.PUT(path, handler, middlewares...)
 }
 
 // DELETE 快捷注册DELETE路由（门面方法，委托给Router）
-func (s *Server) DELETE(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	s.router.DELETE(path, handler, middlewares...)
+func (s *Server) DELETE(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return s.router.DELETE(path, handler, middlewares...)
+}
+
+// PATCH 快捷注册PATCH路由（门面方法，委托给Router）
+func (s *Server) PATCH(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return s.router.PATCH(path, handler, middlewares...)
+}
+
+// Group 创建路由分组（门面方法，委托给Router），如 s.Group("/api/v1", auth).GET("/users", list)
+func (s *Server) Group(prefix string, middlewares ...MiddlewareFunc) *RouterGroup {
+	return s.router.Group(prefix, middlewares...)
+}
+
+// Static 注册静态文件目录（门面方法，委托给Router），如 s.Static("/assets", "./public")
+func (s *Server) Static(prefix, dir string, opts ...StaticOptions) {
+	s.router.Static(prefix, dir, opts...)
+}
+
+// SPA 注册单页应用（门面方法，委托给Router），如 s.SPA("/", "./dist")
+func (s *Server) SPA(prefix, distDir string, opts ...StaticOptions) {
+	s.router.SPA(prefix, distDir, opts...)
 }
 
-// Run 启动HTTP服务器（原有逻辑不变）
+// Routes 获取已注册路由的元数据（门面方法，委托给Router）
+func (s *Server) Routes() []*Route {
+	return s.router.Routes()
+}
+
+// SetListener 指定监听器（平滑重启时传入继承的监听器，需在Run之前调用）
+func (s *Server) SetListener(lis net.Listener) {
+	s.listener = lis
+}
+
+// Listener 获取当前监听器（Run之前为nil）
+func (s *Server) Listener() net.Listener {
+	return s.listener
+}
+
+// Run 启动HTTP服务器
 func (s *Server) Run() error {
-	logger.Info("HTTP服务器启动成功，监听地址：", s.config.Addr)
+	if s.listener == nil {
+		lis, err := net.Listen("tcp", s.config.Addr)
+		if err != nil {
+			return err
+		}
+		s.listener = lis
+	}
 	if s.config.SSL {
-		return s.server.ListenAndServeTLS(s.config.SSLCertFile, s.config.SSLKeyFile)
+		tlsConfig, err := auth.ServerTLSConfig(s.config.SSLCertFile, s.config.SSLKeyFile, s.config.MTLS)
+		if err != nil {
+			return err
+		}
+		s.server.TLSConfig = tlsConfig
+		logger.Info("HTTPS服务器启动成功，监听地址：", s.config.Addr)
+		return s.server.ServeTLS(s.listener, "", "")
 	}
-	return s.server.ListenAndServe()
+	logger.Info("HTTP服务器启动成功，监听地址：", s.config.Addr)
+	return s.server.Serve(s.listener)
 }
 
-// Stop 停止HTTP服务器（原有逻辑不变）
+// Stop 停止HTTP服务器（等待处理中的请求完成，最长等待ShutdownTimeout）
 func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown 优雅停止HTTP服务器：停止接受新连接，等待处理中的请求及c.Go/SafeGo启动的后台任务完成；
+// ctx到期时强制关闭剩余连接并返回ctx.Err()
+func (s *Server) Shutdown(ctx context.Context) error {
 	logger.Info("HTTP服务器正在停止...")
-	return s.server.Shutdown(nil)
+	err := s.server.Shutdown(ctx)
+	if err != nil && ctx.Err() != nil {
+		logger.Warn("HTTP服务器停机超时，强制关闭剩余连接")
+		_ = s.server.Close()
+	}
+	if err == nil {
+		if err = waitBackground(ctx); err != nil {
+			logger.Warn("HTTP后台任务未在停机超时前完成，剩余任务数：", BackgroundTasks())
+		}
+	}
+	return err
 }
 
 // loadServerConfig 加载配置（原有逻辑不变）
 func loadServerConfig(appName string) *ServerConfig {
 	appCfg := config.GetAppConfig(appName)
 	httpCfg := appCfg.HTTP
-	return &ServerConfig{
-		Addr:           httpCfg.Addr,
-		ReadTimeout:    time.Duration(httpCfg.ReadTimeout) * time.Second,
-		WriteTimeout:   time.Duration(httpCfg.WriteTimeout) * time.Second,
-		MaxHeaderBytes: httpCfg.MaxHeaderBytes,
-		SSL:            httpCfg.SSL,
-		SSLCertFile:    httpCfg.SSLCertFile,
-		SSLKeyFile:     httpCfg.SSLKeyFile,
+	cfg := &ServerConfig{
+		Addr:              httpCfg.Addr,
+		ReadTimeout:       time.Duration(httpCfg.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(httpCfg.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(httpCfg.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(httpCfg.IdleTimeout) * time.Second,
+		ShutdownTimeout:   time.Duration(httpCfg.ShutdownTimeout) * time.Second,
+		DetachTimeout:     time.Duration(httpCfg.DetachTimeout) * time.Second,
+		MaxHeaderBytes:    httpCfg.MaxHeaderBytes,
+		SSL:               httpCfg.SSL,
+		SSLCertFile:       httpCfg.SSLCertFile,
+		SSLKeyFile:        httpCfg.SSLKeyFile,
+		MTLS:              auth.MTLSOptionsFromConfig(httpCfg.MTLS),
+		CORS: CORSOptions{
+			AllowOrigins:     httpCfg.CORS.AllowOrigins,
+			AllowMethods:     httpCfg.CORS.AllowMethods,
+			AllowHeaders:     httpCfg.CORS.AllowHeaders,
+			ExposeHeaders:    httpCfg.CORS.ExposeHeaders,
+			AllowCredentials: httpCfg.CORS.AllowCredentials,
+			MaxAge:           time.Duration(httpCfg.CORS.MaxAge) * time.Second,
+		},
+	}
+	if httpCfg.AccessLog.Enable {
+		cfg.AccessLog = &AccessLogOptions{
+			Format:        httpCfg.AccessLog.Format,
+			SampleRate:    httpCfg.AccessLog.SampleRate,
+			Routes:        httpCfg.AccessLog.Routes,
+			SlowThreshold: time.Duration(httpCfg.AccessLog.SlowThreshold) * time.Millisecond,
+			SampleErrors:  httpCfg.AccessLog.SampleErrors,
+		}
+	}
+	if httpCfg.Template.Dir != "" {
+		cfg.Template = &TemplateOptions{
+			Dir:      httpCfg.Template.Dir,
+			Ext:      httpCfg.Template.Ext,
+			Layout:   httpCfg.Template.Layout,
+			Partials: httpCfg.Template.Partials,
+			Reload:   httpCfg.Template.Reload || appCfg.Env == "dev",
+		}
+	}
+	cfg.BodyLimit.MaxBytes = httpCfg.MaxBodySize
+	cfg.Timeout.Timeout = time.Duration(httpCfg.HandlerTimeout) * time.Second
+	for path, limit := range httpCfg.RouteLimits {
+		if limit.MaxBodySize != 0 {
+			if cfg.BodyLimit.Routes == nil {
+				cfg.BodyLimit.Routes = make(map[string]int64)
+			}
+			cfg.BodyLimit.Routes[path] = limit.MaxBodySize
+		}
+		if limit.Timeout != 0 {
+			if cfg.Timeout.Routes == nil {
+				cfg.Timeout.Routes = make(map[string]time.Duration)
+			}
+			cfg.Timeout.Routes[path] = time.Duration(limit.Timeout) * time.Second
+		}
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 30 * time.Second
+	}
+	if cfg.DetachTimeout <= 0 {
+		cfg.DetachTimeout = DefaultDetachTimeout
 	}
+	return cfg
 }
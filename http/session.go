@@ -0,0 +1,122 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/session"
+	"net"
+	"net/http"
+)
+
+// Sessions 会话中间件：为请求加载Cookie对应的会话，并在写出响应头前保存会话、下发Cookie
+func Sessions(m *session.Manager) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		if m == nil {
+			return next
+		}
+		return func(c *Context) {
+			sw := &sessionWriter{ResponseWriter: c.Writer, sess: m.Start(c.Req), ctx: c}
+			c.sess = sw.sess
+			c.Writer = sw
+			defer func() {
+				sw.commit()
+				c.Writer = sw.ResponseWriter
+			}()
+			next(c)
+		}
+	}
+}
+
+// Session 当前请求的会话（未启用Sessions中间件时返回nil）
+func (c *Context) Session() *session.Session {
+	return c.sess
+}
+
+// SessionGet 读取会话值
+func (c *Context) SessionGet(key string) (interface{}, bool) {
+	if c.sess == nil {
+		return nil, false
+	}
+	return c.sess.Get(key)
+}
+
+// SessionSet 写入会话值（会话加载失败时不生效并返回错误）
+func (c *Context) SessionSet(key string, value interface{}) error {
+	if c.sess == nil {
+		return errSessionDisabled
+	}
+	c.sess.Set(key, value)
+	return c.sess.Err()
+}
+
+// SessionDelete 删除会话值（会话加载失败时不生效并返回错误）
+func (c *Context) SessionDelete(key string) error {
+	if c.sess == nil {
+		return errSessionDisabled
+	}
+	c.sess.Delete(key)
+	return c.sess.Err()
+}
+
+// SessionDestroy 销毁会话（如退出登录；会话加载失败时不生效并返回错误）
+func (c *Context) SessionDestroy() error {
+	if c.sess == nil {
+		return errSessionDisabled
+	}
+	c.sess.Destroy()
+	return c.sess.Err()
+}
+
+var errSessionDisabled = errors.New("未启用会话中间件")
+
+// sessionWriter 在首次写出响应头前提交会话（Set-Cookie必须先于响应头发送）
+type sessionWriter struct {
+	http.ResponseWriter
+	sess      *session.Session
+	ctx       *Context
+	committed bool
+}
+
+func (w *sessionWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	if err := w.sess.Commit(w.ResponseWriter); err != nil {
+		logger.FromContext(w.ctx.Req.Context()).Error("保存会话失败：", err)
+	}
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush 透传http.Flusher
+func (w *sessionWriter) Flush() {
+	w.commit()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 透传http.Hijacker（WS升级依赖）
+func (w *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijack")
+	}
+	w.committed = true
+	return hijacker.Hijack()
+}
+
+// Unwrap 供http.ResponseController获取原始ResponseWriter
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,111 +1,335 @@
 package http
 
 import (
-	"github.com/dfpopp/go-dai/logger"
+	"context"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// defaultSSEHeartbeat 默认心跳间隔（防止代理/负载均衡因空闲断开长连接）
+const defaultSSEHeartbeat = 30 * time.Second
+
 // SSEvent SSE事件结构
 type SSEvent struct {
 	Event string // 事件类型
-	Data  string // 事件数据
-	ID    string // 事件ID
+	Data  string // 事件数据（包含换行时按SSE规范拆分为多行data）
+	ID    string // 事件ID（客户端重连时通过Last-Event-ID回传）
 	Retry int    // 重连时间（毫秒）
 }
 
-// SSEContext SSE上下文
+// SSEOptions SSE连接参数
+type SSEOptions struct {
+	Heartbeat time.Duration // 心跳间隔（默认30秒，<0表示不发送心跳）
+}
+
+// SSEContext SSE上下文（Send/Comment可在多个goroutine中并发调用）
 type SSEContext struct {
 	Writer  http.ResponseWriter
 	Flusher http.Flusher
-	Closed  bool
-}
+	Request *http.Request
 
-// NewSSEContext 创建SSE上下文
-func NewSSEContext(w http.ResponseWriter) (*SSEContext, error) {
-	// 设置SSE响应头
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	closed bool
+}
 
-	// 获取flusher
+// NewSSEContext 创建SSE上下文（客户端断开、请求context取消或调用Close后，Done()关闭且Send返回net.ErrClosed）
+func NewSSEContext(w http.ResponseWriter, r *http.Request) (*SSEContext, error) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		return nil, http.ErrNotSupported
 	}
+	// 设置SSE响应头（跨域由CORS中间件处理）
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // 关闭Nginx代理缓冲
+	ctx, cancel := context.WithCancel(r.Context())
+	s := &SSEContext{Writer: w, Flusher: flusher, Request: r, ctx: ctx, cancel: cancel}
+	// 立即下发响应头，客户端据此触发onopen
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return s, nil
+}
 
-	return &SSEContext{
-		Writer:  w,
-		Flusher: flusher,
-		Closed:  false,
-	}, nil
+// Done 连接关闭时关闭的通道（供处理器退出循环）
+func (s *SSEContext) Done() <-chan struct{} {
+	return s.ctx.Done()
 }
 
-// Send 发送SSE事件
-func (s *SSEContext) Send(event SSEvent) error {
-	if s.Closed {
-		return http.ErrClosed
+// Context 连接级context（连接关闭时取消）
+func (s *SSEContext) Context() context.Context {
+	return s.ctx
+}
+
+// LastEventID 客户端重连时携带的最后事件ID（用于断点续传）
+func (s *SSEContext) LastEventID() string {
+	return s.Request.Header.Get("Last-Event-ID")
+}
+
+// IsClosed 连接是否已关闭
+func (s *SSEContext) IsClosed() bool {
+	select {
+	case <-s.ctx.Done():
+		return true
+	default:
+		return false
 	}
+}
 
-	// 写入事件数据
+// Send 发送SSE事件
+func (s *SSEContext) Send(event SSEvent) error {
+	var b strings.Builder
 	if event.ID != "" {
-		_, err := s.Writer.Write([]byte("id: " + event.ID + "\n"))
-		if err != nil {
-			return err
-		}
+		b.WriteString("id: ")
+		b.WriteString(sseLine(event.ID))
+		b.WriteByte('\n')
 	}
 	if event.Event != "" {
-		_, err := s.Writer.Write([]byte("event: " + event.Event + "\n"))
-		if err != nil {
-			return err
-		}
+		b.WriteString("event: ")
+		b.WriteString(sseLine(event.Event))
+		b.WriteByte('\n')
 	}
 	if event.Retry > 0 {
-		_, err := s.Writer.Write([]byte("retry: " + string(rune(event.Retry)) + "\n"))
-		if err != nil {
-			return err
-		}
+		b.WriteString("retry: ")
+		b.WriteString(strconv.Itoa(event.Retry))
+		b.WriteByte('\n')
 	}
-	_, err := s.Writer.Write([]byte("data: " + event.Data + "\n\n"))
-	if err != nil {
-		return err
+	for _, line := range strings.Split(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: ")
+		b.WriteString(line)
+		b.WriteByte('\n')
 	}
+	b.WriteByte('\n')
+	return s.write(b.String())
+}
+
+// Comment 发送注释行（客户端忽略，用于心跳保活）
+func (s *SSEContext) Comment(text string) error {
+	return s.write(": " + sseLine(text) + "\n\n")
+}
 
-	// 刷新缓冲区
+// write 写入并刷新（写入失败视为客户端已断开）
+func (s *SSEContext) write(payload string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.ctx.Err() != nil {
+		return net.ErrClosed
+	}
+	if _, err := s.Writer.Write([]byte(payload)); err != nil {
+		s.closeLocked()
+		return err
+	}
 	s.Flusher.Flush()
 	return nil
 }
 
-// Close 关闭SSE连接
+// Close 关闭SSE连接（可重复调用）
 func (s *SSEContext) Close() {
-	s.Closed = true
-	logger.Info("SSE连接已关闭")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *SSEContext) closeLocked() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.cancel()
+}
+
+// heartbeat 定时发送心跳，连接关闭时退出
+func (s *SSEContext) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if s.Comment("ping") != nil {
+				return
+			}
+		}
+	}
 }
 
-// SSEHandler SSE处理器包装
-func SSEHandler(handler func(*SSEContext)) HandlerFunc {
+// sseLine 去除字段值中的换行（id/event/注释不允许跨行）
+func sseLine(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// SSEHandler SSE处理器包装：处理器返回或客户端断开后连接关闭，心跳随之停止。
+// 处理器中应监听s.Done()以便客户端断开时及时退出。
+func SSEHandler(handler func(*SSEContext), opts ...SSEOptions) HandlerFunc {
+	var opt SSEOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Heartbeat == 0 {
+		opt.Heartbeat = defaultSSEHeartbeat
+	}
 	return func(c *Context) {
-		sseCtx, err := NewSSEContext(c.Writer)
+		sseCtx, err := NewSSEContext(c.Writer, c.Req)
 		if err != nil {
-			c.Error(400, "不支持SSE协议")
+			c.String(http.StatusBadRequest, "不支持SSE协议")
 			return
 		}
 		defer sseCtx.Close()
+		if opt.Heartbeat > 0 {
+			go sseCtx.heartbeat(opt.Heartbeat)
+		}
+		handler(sseCtx)
+	}
+}
+
+// SSEHub SSE广播中心：订阅者按主题订阅，Publish向主题下所有订阅者投递事件。
+// 投递不阻塞发布方：订阅者缓冲区已满（客户端消费过慢）时丢弃该事件并计入Dropped。
+type SSEHub struct {
+	mu         sync.RWMutex
+	topics     map[string]map[*SSESubscription]struct{}
+	bufferSize int
+	closed     bool
+}
 
-		// 心跳检测
-		go func() {
-			ticker := time.NewTicker(30 * time.Second)
-			defer ticker.Stop()
-			for {
-				select {
-				case <-ticker.C:
-					_ = sseCtx.Send(SSEvent{Data: "ping"})
+// SSESubscription 订阅句柄
+type SSESubscription struct {
+	C       <-chan SSEvent // 事件通道（取消订阅或Hub关闭后关闭）
+	ch      chan SSEvent
+	hub     *SSEHub
+	topics  []string
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// NewSSEHub 创建广播中心（bufferSize为每个订阅者的缓冲事件数，默认64）
+func NewSSEHub(bufferSize int) *SSEHub {
+	if bufferSize <= 0 {
+		bufferSize = 64
+	}
+	return &SSEHub{topics: make(map[string]map[*SSESubscription]struct{}), bufferSize: bufferSize}
+}
+
+// Subscribe 订阅一个或多个主题
+func (h *SSEHub) Subscribe(topics ...string) *SSESubscription {
+	ch := make(chan SSEvent, h.bufferSize)
+	sub := &SSESubscription{C: ch, ch: ch, hub: h, topics: topics}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return sub
+	}
+	for _, topic := range topics {
+		subs, ok := h.topics[topic]
+		if !ok {
+			subs = make(map[*SSESubscription]struct{})
+			h.topics[topic] = subs
+		}
+		subs[sub] = struct{}{}
+	}
+	return sub
+}
+
+// Publish 向主题广播事件，返回成功投递的订阅者数
+func (h *SSEHub) Publish(topic string, event SSEvent) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	delivered := 0
+	for sub := range h.topics[topic] {
+		select {
+		case sub.ch <- event:
+			delivered++
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+	return delivered
+}
+
+// Subscribers 主题当前的订阅者数
+func (h *SSEHub) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Topics 当前有订阅者的主题
+func (h *SSEHub) Topics() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	topics := make([]string, 0, len(h.topics))
+	for topic := range h.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Close 关闭广播中心（关闭所有订阅者的事件通道）
+func (h *SSEHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	seen := make(map[*SSESubscription]struct{})
+	for _, subs := range h.topics {
+		for sub := range subs {
+			if _, ok := seen[sub]; !ok {
+				seen[sub] = struct{}{}
+				sub.once.Do(func() { close(sub.ch) })
+			}
+		}
+	}
+	h.topics = make(map[string]map[*SSESubscription]struct{})
+}
+
+// Handler 订阅主题并将事件推送给客户端的SSE处理器（topics按请求决定订阅的主题，如取查询参数或登录用户）
+func (h *SSEHub) Handler(topics func(r *http.Request) []string, opts ...SSEOptions) HandlerFunc {
+	return SSEHandler(func(s *SSEContext) {
+		sub := h.Subscribe(topics(s.Request)...)
+		defer sub.Close()
+		for {
+			select {
+			case <-s.Done():
+				return
+			case event, ok := <-sub.C:
+				if !ok || s.Send(event) != nil {
+					return
 				}
 			}
-		}()
+		}
+	}, opts...)
+}
 
-		// 执行业务处理器
-		handler(sseCtx)
+// Dropped 因缓冲区已满被丢弃的事件数
+func (s *SSESubscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 取消订阅（可重复调用）
+func (s *SSESubscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range s.topics {
+		if subs, ok := h.topics[topic]; ok {
+			delete(subs, s)
+			if len(subs) == 0 {
+				delete(h.topics, topic)
+			}
+		}
 	}
+	s.once.Do(func() { close(s.ch) })
 }
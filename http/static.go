@@ -0,0 +1,141 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StaticOptions 静态文件服务参数
+type StaticOptions struct {
+	MaxAge        time.Duration // Cache-Control的max-age（0表示no-cache，每次需通过ETag/Last-Modified协商）
+	Index         string        // 目录默认文件（默认index.html）
+	AllowDotFiles bool          // 是否允许访问以"."开头的文件或目录（默认拒绝，避免泄露.env/.git等）
+}
+
+// staticFS 静态文件目录（负责路径安全校验与文件响应）
+type staticFS struct {
+	root string
+	opts StaticOptions
+}
+
+func newStaticFS(dir string, opts []StaticOptions) *staticFS {
+	fs := &staticFS{}
+	if len(opts) > 0 {
+		fs.opts = opts[0]
+	}
+	if fs.opts.Index == "" {
+		fs.opts.Index = "index.html"
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		panic("http: 静态目录无效：" + dir)
+	}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	fs.root = root
+	return fs
+}
+
+// Static 注册静态文件目录，如 Static("/assets", "./public")：
+// 支持Range请求、ETag/Last-Modified协商缓存，拒绝目录穿越，不提供目录列表
+func (r *Router) Static(prefix, dir string, opts ...StaticOptions) {
+	fs := newStaticFS(dir, opts)
+	r.GET(staticPattern(prefix), func(c *Context) {
+		if !fs.serve(c.Writer, c.Req, c.Param("filepath"), fs.opts.MaxAge) {
+			http.NotFound(c.Writer, c.Req)
+		}
+	})
+}
+
+// SPA 注册单页应用，如 SPA("/", "./dist")：
+// 存在的文件按静态文件返回；不存在的路径（前端路由）返回index.html，且index.html始终不缓存；
+// 带扩展名的资源路径不存在时仍返回404，避免缺失的js/css被index.html替代
+func (r *Router) SPA(prefix, distDir string, opts ...StaticOptions) {
+	fs := newStaticFS(distDir, opts)
+	r.GET(staticPattern(prefix), func(c *Context) {
+		name := c.Param("filepath")
+		if name != "" && !strings.HasSuffix(name, "/") && path.Base(name) != fs.opts.Index {
+			if fs.serve(c.Writer, c.Req, name, fs.opts.MaxAge) {
+				return
+			}
+			if path.Ext(name) != "" {
+				http.NotFound(c.Writer, c.Req)
+				return
+			}
+		}
+		// 入口文件需及时更新，不使用强缓存
+		if !fs.serve(c.Writer, c.Req, fs.opts.Index, 0) {
+			http.NotFound(c.Writer, c.Req)
+		}
+	})
+}
+
+// staticPattern 生成静态路由规则（前缀 + 通配参数filepath）
+func staticPattern(prefix string) string {
+	return strings.TrimRight(prefix, "/") + "/*filepath"
+}
+
+// serve 响应文件（目录时尝试默认文件），文件不存在或路径不合法时返回false
+func (fs *staticFS) serve(w http.ResponseWriter, req *http.Request, name string, maxAge time.Duration) bool {
+	fullPath, ok := fs.resolve(name)
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		fullPath = filepath.Join(fullPath, fs.opts.Index)
+		if info, err = os.Stat(fullPath); err != nil || info.IsDir() {
+			return false
+		}
+	}
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := w.Header()
+	header.Set("ETag", fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano()))
+	if maxAge > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(maxAge/time.Second)))
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+	// ServeContent负责Range、If-None-Match、If-Modified-Since处理及Content-Type推断
+	http.ServeContent(w, req, info.Name(), info.ModTime(), file)
+	return true
+}
+
+// resolve 将请求路径安全地映射到根目录内的文件路径（拒绝目录穿越、符号链接逃逸及隐藏文件）
+func (fs *staticFS) resolve(name string) (string, bool) {
+	if strings.Contains(name, "\x00") || strings.Contains(name, "\\") {
+		return "", false
+	}
+	clean := path.Clean("/" + name)
+	if !fs.opts.AllowDotFiles {
+		for _, part := range strings.Split(clean, "/") {
+			if strings.HasPrefix(part, ".") {
+				return "", false
+			}
+		}
+	}
+	fullPath := filepath.Join(fs.root, filepath.FromSlash(clean))
+	// 解析符号链接后再次确认仍位于根目录内
+	if real, err := filepath.EvalSymlinks(fullPath); err == nil {
+		fullPath = real
+	}
+	rel, err := filepath.Rel(fs.root, fullPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return fullPath, true
+}
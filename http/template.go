@@ -0,0 +1,233 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// HTML模板渲染（服务端渲染的管理后台页面等）：加载模板目录下的全部页面，页面可复用布局与公共片段。
+//
+//	templates/
+//	  layouts/base.html   布局：<html>...{{block "content" .}}{{end}}...</html>
+//	  partials/nav.html   片段：任意页面中通过{{template "partials/nav" .}}引用
+//	  user/list.html      页面：{{define "content"}}...{{end}}，通过c.HTML(200, "user/list", data)渲染
+//
+// 模板名为相对模板目录的路径（不含扩展名）。页面中定义了子模板（{{define}}）时套用布局渲染，
+// 否则作为独立页面直接渲染（如登录页）。Reload开启时每次渲染重新解析（开发环境），否则首次渲染时解析并缓存。
+
+// ErrTemplateNotFound 模板不存在
+var ErrTemplateNotFound = errors.New("http: 模板不存在")
+
+// TemplateOptions HTML模板配置
+type TemplateOptions struct {
+	Dir      string           // 模板根目录
+	Ext      string           // 模板扩展名（默认.html）
+	Layout   string           // 默认布局文件（相对Dir，如layouts/base.html，为空时不使用布局）
+	Partials string           // 公共片段目录（相对Dir，默认partials）
+	Funcs    template.FuncMap // 自定义模板函数
+	Delims   [2]string        // 自定义分隔符（默认{{ }}）
+	Reload   bool             // 每次渲染重新解析模板（开发环境使用，修改模板后无需重启）
+}
+
+// TemplateRenderer HTML模板渲染器
+type TemplateRenderer struct {
+	mu     sync.RWMutex
+	opts   TemplateOptions
+	pages  map[string]*templatePage // 已解析的页面（Reload模式下不缓存）
+	loaded bool
+}
+
+// templatePage 解析后的页面（entry为执行的入口模板：布局或页面自身）
+type templatePage struct {
+	tpl   *template.Template
+	entry string
+}
+
+// NewTemplateRenderer 创建模板渲染器（首次渲染时解析模板，可调用Load提前解析以便启动时发现语法错误）
+func NewTemplateRenderer(opts TemplateOptions) *TemplateRenderer {
+	if opts.Ext == "" {
+		opts.Ext = ".html"
+	}
+	if opts.Partials == "" {
+		opts.Partials = "partials"
+	}
+	funcs := make(template.FuncMap, len(opts.Funcs))
+	for name, fn := range opts.Funcs {
+		funcs[name] = fn
+	}
+	opts.Funcs = funcs
+	return &TemplateRenderer{opts: opts}
+}
+
+// Funcs 追加模板函数（已解析的模板缓存失效，下次渲染时重新解析）
+func (r *TemplateRenderer) Funcs(funcs template.FuncMap) *TemplateRenderer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, fn := range funcs {
+		r.opts.Funcs[name] = fn
+	}
+	r.pages, r.loaded = nil, false
+	return r
+}
+
+// Load 解析全部模板并缓存
+func (r *TemplateRenderer) Load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pages, err := r.parse()
+	if err != nil {
+		return err
+	}
+	r.pages, r.loaded = pages, true
+	return nil
+}
+
+// Render 渲染模板到w（出错时w中可能已写入部分内容，需完整输出时先渲染到缓冲区）
+func (r *TemplateRenderer) Render(w io.Writer, name string, data interface{}) error {
+	pages, err := r.currentPages()
+	if err != nil {
+		return err
+	}
+	page, ok := pages[strings.TrimSuffix(name, r.opts.Ext)]
+	if !ok {
+		return fmt.Errorf("%w：%s", ErrTemplateNotFound, name)
+	}
+	return page.tpl.ExecuteTemplate(w, page.entry, data)
+}
+
+// currentPages 当前可用的页面（Reload模式下每次重新解析）
+func (r *TemplateRenderer) currentPages() (map[string]*templatePage, error) {
+	r.mu.RLock()
+	pages, loaded, reload := r.pages, r.loaded, r.opts.Reload
+	r.mu.RUnlock()
+	if loaded && !reload {
+		return pages, nil
+	}
+	if reload {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.parse()
+	}
+	if err := r.Load(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pages, nil
+}
+
+// parse 解析模板目录（布局与公共片段共享，每个页面克隆一份后解析，各页面的content等子模板互不覆盖）
+func (r *TemplateRenderer) parse() (map[string]*templatePage, error) {
+	opts := r.opts
+	if opts.Dir == "" {
+		return nil, errors.New("http: 未配置模板目录")
+	}
+	files := make(map[string]string) // 模板名 -> 文件内容
+	err := filepath.WalkDir(opts.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != opts.Ext {
+			return err
+		}
+		rel, err := filepath.Rel(opts.Dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[strings.TrimSuffix(filepath.ToSlash(rel), opts.Ext)] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("http: 读取模板目录失败：%w", err)
+	}
+
+	layout := strings.TrimSuffix(filepath.ToSlash(opts.Layout), opts.Ext)
+	partialPrefix := strings.Trim(filepath.ToSlash(opts.Partials), "/") + "/"
+	if layout != "" {
+		if _, ok := files[layout]; !ok {
+			return nil, fmt.Errorf("%w：布局%s", ErrTemplateNotFound, opts.Layout)
+		}
+	}
+	newTemplate := func(name string) *template.Template {
+		t := template.New(name).Funcs(opts.Funcs)
+		if opts.Delims[0] != "" && opts.Delims[1] != "" {
+			t = t.Delims(opts.Delims[0], opts.Delims[1])
+		}
+		return t
+	}
+	shared := newTemplate("")
+	for name, content := range files {
+		if name == layout || strings.HasPrefix(name, partialPrefix) {
+			if _, err := shared.New(name).Parse(content); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	pages := make(map[string]*templatePage)
+	for name, content := range files {
+		if name == layout || strings.HasPrefix(name, partialPrefix) {
+			continue
+		}
+		// 单独解析一次判断页面是否定义了子模板（定义了则套用布局）
+		standalone, err := newTemplate(name).Parse(content)
+		if err != nil {
+			return nil, err
+		}
+		tpl, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tpl.New(name).Parse(content); err != nil {
+			return nil, err
+		}
+		entry := name
+		if layout != "" && len(standalone.Templates()) > 1 {
+			entry = layout
+		}
+		pages[name] = &templatePage{tpl: tpl, entry: entry}
+	}
+	return pages, nil
+}
+
+// Templates 模板中间件：为请求上下文设置模板渲染器（c.HTML使用），NewServer按配置http.template自动注册
+func Templates(r *TemplateRenderer) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		if r == nil {
+			return next
+		}
+		return func(c *Context) {
+			c.templates = r
+			next(c)
+		}
+	}
+}
+
+// HTML 渲染HTML模板并响应（需启用模板：配置http.template或注册Templates中间件）
+func (c *Context) HTML(code int, name string, data interface{}) {
+	if c.templates == nil {
+		logger.Error("渲染模板失败：未启用HTML模板，", name)
+		http.Error(c.Writer, "模板渲染失败", http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := c.templates.Render(&buf, name, data); err != nil {
+		logger.Error("渲染模板失败：", name, err)
+		http.Error(c.Writer, "模板渲染失败", http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Writer.WriteHeader(code)
+	_, _ = buf.WriteTo(c.Writer)
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// UploadConfig 文件上传限制
+type UploadConfig struct {
+	MaxMemory      int64 // 解析multipart时保存在内存中的最大字节数，超出部分写入临时文件（默认32MB）
+	MaxRequestSize int64 // 上传请求体的最大字节数（默认100MB，<0表示不限制）
+	MaxFileSize    int64 // 单个文件的最大字节数（0表示不单独限制）
+}
+
+var (
+	// ErrMissingFile 请求中不存在指定的文件字段
+	ErrMissingFile = http.ErrMissingFile
+	// ErrFileTooLarge 单个文件超出MaxFileSize
+	ErrFileTooLarge = errors.New("http: 上传文件超出大小限制")
+	// ErrRequestTooLarge 请求体超出MaxRequestSize
+	ErrRequestTooLarge = errors.New("http: 上传请求体超出大小限制")
+)
+
+var (
+	uploadMu  sync.RWMutex
+	uploadCfg = UploadConfig{MaxMemory: 32 << 20, MaxRequestSize: 100 << 20}
+)
+
+// SetUploadConfig 设置全局上传限制（字段为0时保留默认值）
+func SetUploadConfig(cfg UploadConfig) {
+	uploadMu.Lock()
+	defer uploadMu.Unlock()
+	if cfg.MaxMemory > 0 {
+		uploadCfg.MaxMemory = cfg.MaxMemory
+	}
+	if cfg.MaxRequestSize != 0 {
+		uploadCfg.MaxRequestSize = cfg.MaxRequestSize
+	}
+	uploadCfg.MaxFileSize = cfg.MaxFileSize
+}
+
+// GetUploadConfig 获取当前上传限制
+func GetUploadConfig() UploadConfig {
+	uploadMu.RLock()
+	defer uploadMu.RUnlock()
+	return uploadCfg
+}
+
+// limitUploadBody 按MaxRequestSize限制请求体
+func (c *Context) limitUploadBody(cfg UploadConfig) {
+	if cfg.MaxRequestSize > 0 {
+		c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, cfg.MaxRequestSize)
+	}
+}
+
+// MultipartForm 解析multipart表单（结果缓存在Req.MultipartForm，重复调用不会重复解析）
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.Req.MultipartForm != nil {
+		return c.Req.MultipartForm, nil
+	}
+	cfg := GetUploadConfig()
+	c.limitUploadBody(cfg)
+	if err := c.Req.ParseMultipartForm(cfg.MaxMemory); err != nil {
+		if err = wrapUploadErr(err, cfg); errors.Is(err, ErrRequestTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("解析上传表单失败：%w", err)
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// FormFile 获取单个上传文件
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	files, err := c.FormFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// FormFiles 获取同一字段的全部上传文件（多文件上传）
+func (c *Context) FormFiles(name string) ([]*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, ErrMissingFile
+	}
+	maxFileSize := GetUploadConfig().MaxFileSize
+	for _, file := range files {
+		if maxFileSize > 0 && file.Size > maxFileSize {
+			return nil, fmt.Errorf("%w：%s（%d字节，上限%d字节）", ErrFileTooLarge, file.Filename, file.Size, maxFileSize)
+		}
+	}
+	return files, nil
+}
+
+// SaveUploadedFile 保存上传文件到指定路径（自动创建目录，dst需由调用方生成，勿直接使用客户端文件名）
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return saveToFile(src, dst)
+}
+
+// StreamFile 流式读取指定字段的文件并写入w（不缓存到内存/临时文件，适合大文件），返回客户端文件名与写入字节数。
+// 仅处理第一个匹配的文件，字段之前的普通表单字段会被跳过；调用后不可再使用MultipartForm/FormFile
+func (c *Context) StreamFile(name string, w io.Writer) (string, int64, error) {
+	cfg := GetUploadConfig()
+	c.limitUploadBody(cfg)
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		return "", 0, fmt.Errorf("读取上传表单失败：%w", err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return "", 0, ErrMissingFile
+		}
+		if err != nil {
+			return "", 0, wrapUploadErr(err, cfg)
+		}
+		if part.FormName() != name || part.FileName() == "" {
+			_ = part.Close()
+			continue
+		}
+		n, err := copyLimited(w, part, cfg.MaxFileSize)
+		_ = part.Close()
+		if err != nil {
+			return part.FileName(), n, wrapUploadErr(err, cfg)
+		}
+		return part.FileName(), n, nil
+	}
+}
+
+// StreamFileToDisk 流式保存指定字段的文件到dst（超出限制或写入失败时删除不完整文件）
+func (c *Context) StreamFileToDisk(name, dst string) (string, int64, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", 0, err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", 0, err
+	}
+	filename, n, err := c.StreamFile(name, out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+	return filename, n, err
+}
+
+// saveToFile 将src写入dst（写入失败时删除不完整文件）
+func saveToFile(src io.Reader, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, src)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+	return err
+}
+
+// copyLimited 复制数据，maxSize>0时超出即返回ErrFileTooLarge
+func copyLimited(dst io.Writer, src io.Reader, maxSize int64) (int64, error) {
+	if maxSize <= 0 {
+		return io.Copy(dst, src)
+	}
+	n, err := io.Copy(dst, io.LimitReader(src, maxSize))
+	if err != nil {
+		return n, err
+	}
+	// 已达上限时探测是否还有剩余数据
+	if n == maxSize {
+		var probe [1]byte
+		if k, _ := io.ReadFull(src, probe[:]); k > 0 {
+			return n, fmt.Errorf("%w（上限%d字节）", ErrFileTooLarge, maxSize)
+		}
+	}
+	return n, nil
+}
+
+// wrapUploadErr 将请求体超限错误转换为ErrRequestTooLarge
+func wrapUploadErr(err error, cfg UploadConfig) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return fmt.Errorf("%w（%d字节）", ErrRequestTooLarge, cfg.MaxRequestSize)
+	}
+	return err
+}
@@ -0,0 +1,422 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/function"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/netContext"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 第三方HTTP接口客户端：按配置http_clients创建命名客户端并复用连接池（与ES客户端的Transport配置一致），调用时统一处理
+// 超时（调用方context无更早截止时间时使用配置值，含重试）、幂等请求按状态码/网络错误的退避重试、
+// 请求ID（X-Request-Id）与链路信息（traceparent）向下游透传，各服务调用外部接口遵循同一套模式。
+//
+//	client, _ := httpclient.GetClient("payment")
+//	var resp PayResult
+//	err := client.PostJSON(ctx, "/v1/orders", req, &resp, httpclient.Idempotent()) // 携带幂等键的POST可声明为幂等以启用重试
+//
+// 非2xx响应在JSON辅助方法中返回*StatusError（可用errors.As取出状态码与响应体）。
+
+const (
+	defaultTimeout         = 10 * time.Second
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultMaxBodySize     = 10 << 20
+	defaultMaxIdleConns    = 100
+	defaultMaxIdlePerHost  = 10
+	defaultIdleConnTimeout = 90 * time.Second
+	defaultDialTimeout     = 5 * time.Second
+)
+
+var (
+	// ErrClientNotFound 客户端未配置
+	ErrClientNotFound = errors.New("HTTP客户端未配置")
+	// ErrBodyTooLarge 响应体超出上限
+	ErrBodyTooLarge = errors.New("httpclient: 响应体超出上限")
+)
+
+var (
+	clientsMu sync.RWMutex
+	clients   = map[string]*Client{}
+
+	defaultOnce   sync.Once
+	defaultClient *Client
+)
+
+// Client 命名HTTP客户端（并发安全）
+type Client struct {
+	name        string
+	baseURL     *url.URL
+	headers     http.Header
+	http        *http.Client
+	transport   *http.Transport
+	timeout     time.Duration
+	maxRetries  int
+	backoff     function.Backoff
+	retryStatus map[int]bool
+	maxBodySize int64
+}
+
+// Response 已读取完整响应体的响应
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// OK 状态码是否为2xx
+func (r *Response) OK() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// StatusError 非2xx响应（JSON辅助方法返回）
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	body := string(e.Body)
+	if len(body) > 256 {
+		body = body[:256] + "..."
+	}
+	return fmt.Sprintf("httpclient: %s %s 返回%d：%s", e.Method, e.URL, e.StatusCode, body)
+}
+
+// InitClients 按应用配置http_clients初始化全部命名客户端
+func InitClients(appName string) error {
+	appCfg := config.GetAppConfig(appName)
+	if appCfg == nil {
+		return fmt.Errorf("应用配置不存在：%s", appName)
+	}
+	for name, cfg := range appCfg.HTTPClients {
+		client, err := NewClient(name, cfg)
+		if err != nil {
+			return fmt.Errorf("HTTP客户端[%s]初始化失败：%w", name, err)
+		}
+		clientsMu.Lock()
+		old := clients[name]
+		clients[name] = client
+		clientsMu.Unlock()
+		if old != nil {
+			old.Close()
+		}
+	}
+	return nil
+}
+
+// GetClient 获取命名客户端（需先调用InitClients，bootstrap.Boot已自动调用）
+func GetClient(name string) (*Client, error) {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	client, ok := clients[name]
+	if !ok {
+		return nil, fmt.Errorf("%w：%s", ErrClientNotFound, name)
+	}
+	return client, nil
+}
+
+// CloseClients 关闭全部命名客户端的空闲连接
+func CloseClients() {
+	clientsMu.Lock()
+	all := clients
+	clients = map[string]*Client{}
+	clientsMu.Unlock()
+	for _, client := range all {
+		client.Close()
+	}
+}
+
+// Default 默认客户端（无基础地址，使用默认超时与连接池，不重试），用于临时调用完整URL
+func Default() *Client {
+	defaultOnce.Do(func() {
+		defaultClient, _ = NewClient("default", config.HTTPClientConfig{})
+	})
+	return defaultClient
+}
+
+// NewClient 按配置创建客户端（不注册到命名客户端表，适用于动态目标）
+func NewClient(name string, cfg config.HTTPClientConfig) (*Client, error) {
+	c := &Client{
+		name:        name,
+		headers:     make(http.Header, len(cfg.Headers)),
+		timeout:     time.Duration(cfg.Timeout) * time.Millisecond,
+		maxRetries:  cfg.MaxRetries,
+		retryStatus: map[int]bool{},
+		maxBodySize: cfg.MaxBodySize,
+	}
+	if cfg.BaseURL != "" {
+		base, err := url.Parse(cfg.BaseURL)
+		if err != nil || base.Scheme == "" || base.Host == "" {
+			return nil, fmt.Errorf("基础地址无效：%s", cfg.BaseURL)
+		}
+		c.baseURL = base
+	}
+	for key, val := range cfg.Headers {
+		c.headers.Set(key, val)
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+	if c.maxBodySize <= 0 {
+		c.maxBodySize = defaultMaxBodySize
+	}
+	initial := time.Duration(cfg.RetryBackoff) * time.Millisecond
+	if initial <= 0 {
+		initial = defaultRetryBackoff
+	}
+	c.backoff = function.ExponentialBackoff{Initial: initial, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2}
+	retryStatus := cfg.RetryStatus
+	if len(retryStatus) == 0 {
+		retryStatus = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	for _, code := range retryStatus {
+		c.retryStatus[code] = true
+	}
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.transport = transport
+	c.http = &http.Client{Transport: transport}
+	return c, nil
+}
+
+// newTransport 连接池与连接级超时
+func newTransport(cfg config.HTTPClientConfig) (*http.Transport, error) {
+	maxIdle, maxIdlePerHost := cfg.MaxIdleConnNum, cfg.MaxIdleConnNumPerHost
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = defaultMaxIdlePerHost
+	}
+	idleTimeout := time.Duration(cfg.IdleConnTimeout) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleConnTimeout
+	}
+	dialTimeout := time.Duration(cfg.DialTimeout) * time.Second
+	if dialTimeout <= 0 {
+		dialTimeout = defaultDialTimeout
+	}
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.SSLCAFile != "" {
+		pem, err := os.ReadFile(cfg.SSLCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败：%w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("CA证书格式错误：" + cfg.SSLCAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.SSLCertFile != "" || cfg.SSLKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.SSLCertFile, cfg.SSLKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败：%w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        maxIdle,
+		MaxIdleConnsPerHost: maxIdlePerHost,
+		MaxConnsPerHost:     cfg.MaxConnNumPerHost,
+		IdleConnTimeout:     idleTimeout,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSClientConfig:       tlsCfg,
+		TLSHandshakeTimeout:   dialTimeout,
+		ResponseHeaderTimeout: time.Duration(cfg.ResponseHeaderTimeout) * time.Second,
+	}, nil
+}
+
+// FromNetContext 取框架请求上下文（HTTP/WS/gRPC）的context用于下游调用：携带请求ID与链路信息，
+// 并随上游请求取消
+func FromNetContext(c netContext.Context) context.Context {
+	if c == nil || c.GetContext() == nil {
+		return context.Background()
+	}
+	return c.GetContext()
+}
+
+// Close 关闭空闲连接
+func (c *Client) Close() {
+	c.transport.CloseIdleConnections()
+}
+
+// Name 客户端名称
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Do 发起请求并读取完整响应体（非2xx不视为错误，由调用方判断StatusCode）。
+// 幂等方法（GET/HEAD/OPTIONS/PUT/DELETE）或声明Idempotent的请求在网络错误与可重试状态码时按退避重试
+func (c *Client) Do(ctx context.Context, method, rawURL string, body []byte, opts ...RequestOption) (resp *Response, err error) {
+	ro := requestOptions{timeout: c.timeout, retries: c.maxRetries, header: http.Header{}}
+	for _, opt := range opts {
+		opt(&ro)
+	}
+	method = strings.ToUpper(method)
+	target, err := c.resolve(rawURL, ro.query)
+	if err != nil {
+		return nil, err
+	}
+	if ro.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ro.timeout)
+		defer cancel()
+	}
+	if tracing.Enabled() {
+		var span trace.Span
+		ctx, span = tracing.StartClientSpan(ctx, "HTTP "+method+" "+target.Host,
+			attribute.String("http.method", method), attribute.String("http.url", redactURL(target)),
+			attribute.String("http.client", c.name))
+		defer func() {
+			if resp != nil {
+				span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+			}
+			tracing.End(span, err)
+		}()
+	}
+	retries := 0
+	if ro.idempotent || isIdempotent(method) || ro.header.Get("Idempotency-Key") != "" {
+		retries = ro.retries
+	}
+
+	var last *Response
+	retryErr := function.Retry(ctx, retries+1, c.backoff, func(ctx context.Context) error {
+		r, err := c.send(ctx, method, target, body, ro.header)
+		if err != nil {
+			return err
+		}
+		last = r
+		if c.retryStatus[r.StatusCode] {
+			return &StatusError{Method: method, URL: redactURL(target), StatusCode: r.StatusCode, Body: r.Body}
+		}
+		return nil
+	}, function.RetryIf(func(err error) bool {
+		return !errors.Is(err, ErrBodyTooLarge) && ctx.Err() == nil
+	}), function.OnRetry(func(attempt int, err error, wait time.Duration) {
+		logger.FromContext(ctx).Warn("HTTP调用失败，", wait, "后重试 [", c.name, " ", method, " ", redactURL(target), " 第", attempt, "次]：", err)
+	}))
+	if retryErr == nil {
+		return last, nil
+	}
+	// 重试用尽后仍为可重试状态码时返回最后一次响应，由调用方按状态码处理
+	var statusErr *StatusError
+	if last != nil && errors.As(retryErr, &statusErr) {
+		return last, nil
+	}
+	var re *function.RetryError
+	if errors.As(retryErr, &re) {
+		return nil, re.Last()
+	}
+	return nil, retryErr
+}
+
+// send 发送一次请求（请求体每次重新构建，以便重试）
+func (c *Client) send(ctx context.Context, method string, target *url.URL, body []byte, header http.Header) (*Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reader)
+	if err != nil {
+		return nil, function.Permanent(err)
+	}
+	for key, vals := range c.headers {
+		req.Header[key] = vals
+	}
+	for key, vals := range header {
+		req.Header[key] = vals
+	}
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" && req.Header.Get(logger.RequestIDHeader) == "" {
+		req.Header.Set(logger.RequestIDHeader, requestID)
+	}
+	if tracing.Enabled() {
+		tracing.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+	httpResp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, c.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > c.maxBodySize {
+		return nil, fmt.Errorf("%w（%d字节）", ErrBodyTooLarge, c.maxBodySize)
+	}
+	return &Response{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: data}, nil
+}
+
+// resolve 相对路径拼接基础地址，并追加查询参数
+func (c *Client) resolve(rawURL string, query url.Values) (*url.URL, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("httpclient: 请求地址无效：%w", err)
+	}
+	if !target.IsAbs() {
+		if c.baseURL == nil {
+			return nil, fmt.Errorf("httpclient: 客户端[%s]未配置base_url，请求地址须为完整URL：%s", c.name, rawURL)
+		}
+		base := *c.baseURL
+		if !strings.HasSuffix(base.Path, "/") {
+			base.Path += "/"
+		}
+		target = base.ResolveReference(&url.URL{Path: strings.TrimPrefix(target.Path, "/"), RawQuery: target.RawQuery})
+	}
+	if len(query) > 0 {
+		values := target.Query()
+		for key, vals := range query {
+			for _, val := range vals {
+				values.Add(key, val)
+			}
+		}
+		target.RawQuery = values.Encode()
+	}
+	return target, nil
+}
+
+// isIdempotent 按HTTP语义可安全重试的方法
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// redactURL 去掉查询参数与用户信息的地址（用于日志与span，避免泄露签名、令牌等）
+func redactURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = ""
+	clean.Fragment = ""
+	return clean.String()
+}
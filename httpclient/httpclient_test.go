@@ -0,0 +1,186 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/httpclient"
+)
+
+// startServer 启动测试服务并创建指向它的客户端（重试间隔1ms）
+func startServer(t *testing.T, cfg config.HTTPClientConfig, handler http.HandlerFunc) *httpclient.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	cfg.BaseURL = srv.URL
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = 1
+	}
+	client, err := httpclient.NewClient("test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// 可重试状态码仅对幂等请求重试：幂等方法、声明Idempotent或携带Idempotency-Key的请求
+func TestRetryOnlyIdempotentRequests(t *testing.T) {
+	var hits atomic.Int32
+	client := startServer(t, config.HTTPClientConfig{MaxRetries: 2}, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	cases := []struct {
+		name   string
+		method string
+		opts   []httpclient.RequestOption
+		want   int32
+	}{
+		{"GET", http.MethodGet, nil, 3},
+		{"PUT", http.MethodPut, nil, 3},
+		{"POST", http.MethodPost, nil, 1},
+		{"POST+Idempotent", http.MethodPost, []httpclient.RequestOption{httpclient.Idempotent()}, 3},
+		{"POST+Idempotency-Key", http.MethodPost, []httpclient.RequestOption{httpclient.WithHeader("Idempotency-Key", "order-1")}, 3},
+		{"GET+WithRetries(0)", http.MethodGet, []httpclient.RequestOption{httpclient.WithRetries(0)}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hits.Store(0)
+			resp, err := client.Do(context.Background(), tc.method, "/pay", []byte(`{}`), tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Fatalf("状态码%d", resp.StatusCode)
+			}
+			if got := hits.Load(); got != tc.want {
+				t.Fatalf("请求%d次，期望%d次", got, tc.want)
+			}
+		})
+	}
+}
+
+// 每次重试都携带同一个幂等键
+func TestIdempotencyKeySentOnEveryAttempt(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	client := startServer(t, config.HTTPClientConfig{MaxRetries: 2}, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	if _, err := client.Do(context.Background(), http.MethodPost, "/pay", nil, httpclient.WithHeader("Idempotency-Key", "order-1")); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(keys, ",") != "order-1,order-1,order-1" {
+		t.Fatalf("各次请求的幂等键：%v", keys)
+	}
+}
+
+// 重试用尽后返回最后一次响应；中途成功时返回成功的响应；不在重试列表中的状态码不重试
+func TestRetryStatusReturnsLastResponse(t *testing.T) {
+	var hits atomic.Int32
+	var okAfter atomic.Int32
+	client := startServer(t, config.HTTPClientConfig{MaxRetries: 2}, func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		switch {
+		case r.URL.Path == "/internal":
+			w.WriteHeader(http.StatusInternalServerError)
+		case okAfter.Load() > 0 && n >= okAfter.Load():
+			_, _ = fmt.Fprint(w, `{"ok":true}`)
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = fmt.Fprintf(w, "busy-%d", n)
+		}
+	})
+	ctx := context.Background()
+
+	resp, err := client.Get(ctx, "/busy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests || resp.String() != "busy-3" {
+		t.Fatalf("期望最后一次响应，实际%d %q", resp.StatusCode, resp.String())
+	}
+
+	// JSON辅助方法将最终的非2xx响应转为*StatusError
+	hits.Store(0)
+	var statusErr *httpclient.StatusError
+	if err := client.GetJSON(ctx, "/busy", nil); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests || string(statusErr.Body) != "busy-3" {
+		t.Fatalf("期望*StatusError(429, busy-3)，实际%v", err)
+	}
+
+	hits.Store(0)
+	okAfter.Store(2)
+	var out struct{ OK bool }
+	if err := client.GetJSON(ctx, "/busy", &out); err != nil || !out.OK {
+		t.Fatalf("第二次成功时应返回成功响应：%v %+v", err, out)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("请求%d次，期望2次", got)
+	}
+
+	hits.Store(0)
+	resp, err = client.Get(ctx, "/internal")
+	if err != nil || resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("500应直接返回：%v %v", resp, err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("500不在重试列表中，请求了%d次", got)
+	}
+}
+
+// 网络错误对幂等请求重试
+func TestRetryOnNetworkError(t *testing.T) {
+	var hits atomic.Int32
+	client := startServer(t, config.HTTPClientConfig{MaxRetries: 1}, func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		_, _ = fmt.Fprint(w, "ok")
+	})
+	resp, err := client.Get(context.Background(), "/flaky")
+	if err != nil || resp.String() != "ok" {
+		t.Fatalf("期望重试后成功：%v %v", resp, err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("请求%d次，期望2次", got)
+	}
+}
+
+// 响应体超出上限返回ErrBodyTooLarge且不重试，恰好等于上限时正常返回
+func TestBodyTooLarge(t *testing.T) {
+	var hits atomic.Int32
+	client := startServer(t, config.HTTPClientConfig{MaxRetries: 2, MaxBodySize: 16}, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = fmt.Fprint(w, strings.Repeat("x", len(r.URL.Query().Get("n"))))
+	})
+	ctx := context.Background()
+	_, err := client.Get(ctx, "/data?n="+strings.Repeat("1", 17))
+	if !errors.Is(err, httpclient.ErrBodyTooLarge) {
+		t.Fatalf("期望ErrBodyTooLarge，实际%v", err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("响应体超限不应重试，请求了%d次", got)
+	}
+	resp, err := client.Get(ctx, "/data?n="+strings.Repeat("1", 16))
+	if err != nil || len(resp.Body) != 16 {
+		t.Fatalf("响应体等于上限时应正常返回：%v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RequestOption 单次请求选项
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	timeout    time.Duration
+	retries    int
+	idempotent bool
+	header     http.Header
+	query      url.Values
+}
+
+// WithTimeout 覆盖本次请求的超时（含重试，<=0表示仅受调用方context控制）
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) { o.timeout = timeout }
+}
+
+// WithRetries 覆盖本次请求的最大重试次数（不含首次，仅对幂等请求生效）
+func WithRetries(retries int) RequestOption {
+	return func(o *requestOptions) { o.retries = retries }
+}
+
+// Idempotent 声明非幂等方法（如POST）的本次请求可安全重试（携带Idempotency-Key请求头时自动视为幂等）
+func Idempotent() RequestOption {
+	return func(o *requestOptions) { o.idempotent = true }
+}
+
+// WithHeader 设置本次请求的请求头（覆盖客户端默认请求头）
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) { o.header.Set(key, value) }
+}
+
+// WithQuery 追加查询参数
+func WithQuery(query url.Values) RequestOption {
+	return func(o *requestOptions) {
+		if o.query == nil {
+			o.query = url.Values{}
+		}
+		for key, vals := range query {
+			o.query[key] = append(o.query[key], vals...)
+		}
+	}
+}
+
+// JSON 将响应体解析到out
+func (r *Response) JSON(out interface{}) error {
+	return json.Unmarshal(r.Body, out)
+}
+
+// String 响应体文本
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
+// Get 发起GET请求
+func (c *Client) Get(ctx context.Context, rawURL string, opts ...RequestOption) (*Response, error) {
+	return c.Do(ctx, http.MethodGet, rawURL, nil, opts...)
+}
+
+// GetJSON 发起GET请求并将JSON响应解析到out（非2xx返回*StatusError）
+func (c *Client) GetJSON(ctx context.Context, rawURL string, out interface{}, opts ...RequestOption) error {
+	return c.DoJSON(ctx, http.MethodGet, rawURL, nil, out, opts...)
+}
+
+// PostJSON 以JSON提交in并将响应解析到out（out为nil时忽略响应体）
+func (c *Client) PostJSON(ctx context.Context, rawURL string, in, out interface{}, opts ...RequestOption) error {
+	return c.DoJSON(ctx, http.MethodPost, rawURL, in, out, opts...)
+}
+
+// PutJSON 以JSON提交in并将响应解析到out
+func (c *Client) PutJSON(ctx context.Context, rawURL string, in, out interface{}, opts ...RequestOption) error {
+	return c.DoJSON(ctx, http.MethodPut, rawURL, in, out, opts...)
+}
+
+// DeleteJSON 发起DELETE请求并将响应解析到out
+func (c *Client) DeleteJSON(ctx context.Context, rawURL string, out interface{}, opts ...RequestOption) error {
+	return c.DoJSON(ctx, http.MethodDelete, rawURL, nil, out, opts...)
+}
+
+// DoJSON 以JSON编码in（为nil时无请求体）发起请求，2xx时将响应解析到out（为nil或响应体为空时忽略），非2xx返回*StatusError
+func (c *Client) DoJSON(ctx context.Context, method, rawURL string, in, out interface{}, opts ...RequestOption) error {
+	var body []byte
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("httpclient: 请求体JSON编码失败：%w", err)
+		}
+		body = data
+		opts = append([]RequestOption{WithHeader("Content-Type", "application/json")}, opts...)
+	}
+	opts = append([]RequestOption{WithHeader("Accept", "application/json")}, opts...)
+	resp, err := c.Do(ctx, method, rawURL, body, opts...)
+	if err != nil {
+		return err
+	}
+	if !resp.OK() {
+		statusURL := rawURL
+		if u, err := url.Parse(rawURL); err == nil {
+			statusURL = redactURL(u)
+		}
+		return &StatusError{Method: method, URL: statusURL, StatusCode: resp.StatusCode, Body: resp.Body}
+	}
+	if out == nil || len(resp.Body) == 0 {
+		return nil
+	}
+	if err := resp.JSON(out); err != nil {
+		return fmt.Errorf("httpclient: 响应JSON解析失败：%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 多语言文案模块：按语言注册文案，按 query参数 > Accept-Language 协商语言，
+// 查找顺序为 请求语言 -> 基础语言（如zh-TW -> zh）-> 默认语言 -> 文案key本身。
+// HTTP、WS、gRPC的框架内置错误文案统一从这里获取，业务也可注册自己的文案。
+
+// LocaleQueryParam 指定语言的查询参数名（如 ?lang=en）
+const LocaleQueryParam = "lang"
+
+var (
+	mu            sync.RWMutex
+	catalogs      = make(map[string]map[string]string) // 规范化语言标签 -> key -> 文案
+	defaultLocale = "zh-CN"
+)
+
+type localeKey struct{}
+
+// Register 注册（合并）指定语言的文案，已存在的key会被覆盖
+func Register(locale string, messages map[string]string) {
+	locale = Canonical(locale)
+	if locale == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	catalog, ok := catalogs[locale]
+	if !ok {
+		catalog = make(map[string]string, len(messages))
+		catalogs[locale] = catalog
+	}
+	for key, msg := range messages {
+		catalog[key] = msg
+	}
+}
+
+// SetDefaultLocale 设置默认语言（协商失败或文案缺失时使用，默认zh-CN）
+func SetDefaultLocale(locale string) {
+	if locale = Canonical(locale); locale != "" {
+		mu.Lock()
+		defaultLocale = locale
+		mu.Unlock()
+	}
+}
+
+// DefaultLocale 获取默认语言
+func DefaultLocale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLocale
+}
+
+// Locales 已注册的语言列表（按名称排序）
+func Locales() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// T 获取指定语言的文案，携带args时按fmt.Sprintf格式化
+func T(locale, key string, args ...interface{}) string {
+	msg := lookup(Canonical(locale), key)
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Has 指定语言（或其基础语言）是否注册了该文案（不回退到默认语言）
+func Has(locale, key string) bool {
+	locale = Canonical(locale)
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, candidate := range []string{locale, baseLanguage(locale)} {
+		if candidate == "" {
+			continue
+		}
+		if _, ok := catalogs[candidate][key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup 按 语言 -> 基础语言 -> 默认语言 的顺序查找文案
+func lookup(locale, key string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, candidate := range []string{locale, baseLanguage(locale), defaultLocale, baseLanguage(defaultLocale)} {
+		if candidate == "" {
+			continue
+		}
+		if msg, ok := catalogs[candidate][key]; ok {
+			return msg
+		}
+	}
+	return key
+}
+
+// Negotiate 从Accept-Language中按q值选择已注册的语言，均不匹配时返回默认语言
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		if tag = strings.TrimSpace(tag); tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if locale := Match(c.tag); locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale()
+}
+
+// Match 将语言标签匹配为已注册的语言（精确匹配优先，其次基础语言、同基础语言的其他地区），无匹配返回空
+func Match(tag string) string {
+	tag = Canonical(tag)
+	if tag == "" {
+		return ""
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	base := baseLanguage(tag)
+	if _, ok := catalogs[base]; ok {
+		return base
+	}
+	// 如请求zh-HK，已注册zh-CN
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	for _, locale := range locales {
+		if baseLanguage(locale) == base {
+			return locale
+		}
+	}
+	return ""
+}
+
+// FromRequest 协商HTTP请求（含WS握手请求）的语言：查询参数lang优先，其次Accept-Language
+func FromRequest(r *http.Request) string {
+	if r == nil {
+		return DefaultLocale()
+	}
+	if lang := r.URL.Query().Get(LocaleQueryParam); lang != "" {
+		if locale := Match(lang); locale != "" {
+			return locale
+		}
+	}
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// WithLocale 将语言写入context
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext 从context获取语言（未设置时返回默认语言）
+func FromContext(ctx context.Context) string {
+	if ctx != nil {
+		if locale, ok := ctx.Value(localeKey{}).(string); ok && locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale()
+}
+
+// Canonical 规范化语言标签（zh_cn -> zh-CN，EN -> en）
+func Canonical(tag string) string {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return ""
+	}
+	parts := strings.Split(tag, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i]) // 地区
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:]) // 文字（如Hans）
+		default:
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// baseLanguage 基础语言（zh-CN -> zh）
+func baseLanguage(locale string) string {
+	base, _, _ := strings.Cut(locale, "-")
+	return base
+}
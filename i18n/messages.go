@@ -0,0 +1,182 @@
+package i18n
+
+// 框架内置文案key
+const (
+	MsgInvalidAction      = "ws.invalid_action"       // 无效的action
+	MsgInvalidPayload     = "ws.invalid_payload"      // 消息格式错误
+	MsgRateLimited        = "rate_limited"            // 请求过于频繁（HTTP/WS/gRPC限流共用）
+	MsgAuthFailed         = "auth_failed"             // 身份验证失败（HTTP/WS/gRPC共用）
+	MsgTokenExpired       = "token_expired"           // 登录已过期
+	MsgMessageTooLarge    = "ws.message_too_large"    // 消息超出大小限制
+	MsgServerDraining     = "ws.server_draining"      // 服务排空中（拒绝新连接）
+	MsgServerRestart      = "ws.server_restart"       // 服务即将重启（通知重连）
+	MsgTooManyConnections = "ws.too_many_connections" // 连接数已满
+	MsgHandshakeFailed    = "ws.handshake_failed"     // 握手失败
+	MsgHandshakeTimeout   = "ws.handshake_timeout"    // 握手超时
+	MsgUnsupportedVersion = "ws.unsupported_version"  // 不支持的协议版本
+	MsgCSRFFailed         = "csrf_failed"             // CSRF令牌校验失败
+	MsgRequestTooLarge    = "request_too_large"       // 请求体超出大小限制
+	MsgRequestTimeout     = "request_timeout"         // 请求处理超时
+	MsgOAuthFailed        = "oauth_failed"            // 第三方登录失败
+	MsgMaintenance        = "maintenance"             // 系统维护中
+	MsgReadOnly           = "read_only"               // 只读模式（暂停写操作）
+	MsgInternalError      = "internal_error"          // 服务器内部错误
+	MsgForbidden          = "forbidden"               // 无权访问（已认证但权限不足）
+)
+
+// 时长与相对时间文案key（function.HumanizeDuration/RelativeTime使用；单位文案的参数为数量，
+// 可另注册"key.one"作为数量为1时的单数形式）
+const (
+	MsgTimeJustNow         = "time.just_now"        // 刚刚
+	MsgTimeAgo             = "time.ago"             // 过去（参数为时长文案）
+	MsgTimeLater           = "time.later"           // 将来（参数为时长文案）
+	MsgDurationSeparator   = "duration.separator"   // 多个时长单位之间的分隔符
+	MsgDurationMillisecond = "duration.millisecond" // 毫秒
+	MsgDurationSecond      = "duration.second"      // 秒
+	MsgDurationMinute      = "duration.minute"      // 分钟
+	MsgDurationHour        = "duration.hour"        // 小时
+	MsgDurationDay         = "duration.day"         // 天
+	MsgDurationMonth       = "duration.month"       // 月
+	MsgDurationYear        = "duration.year"        // 年
+)
+
+// 参数校验文案key（validate包使用；参数依次为字段显示名与规则参数，字段显示名取自"field.<参数名>"文案，
+// 可另注册"validate.<规则>.<参数名>"覆盖单个字段的提示）
+const (
+	MsgFieldPrefix       = "field."              // 字段显示名前缀（如"field.mobile" -> 手机号）
+	MsgValidateRequired  = "validate.required"   // 必填
+	MsgValidateMin       = "validate.min"        // 数值不小于
+	MsgValidateMax       = "validate.max"        // 数值不大于
+	MsgValidateMinLen    = "validate.min_len"    // 长度/元素个数不少于
+	MsgValidateMaxLen    = "validate.max_len"    // 长度/元素个数不超过
+	MsgValidateLen       = "validate.len"        // 长度/元素个数等于
+	MsgValidateGt        = "validate.gt"         // 大于
+	MsgValidateGte       = "validate.gte"        // 大于等于
+	MsgValidateLt        = "validate.lt"         // 小于
+	MsgValidateLte       = "validate.lte"        // 小于等于
+	MsgValidateOneOf     = "validate.oneof"      // 取值范围
+	MsgValidateEmail     = "validate.email"      // 邮箱格式
+	MsgValidateMobile    = "validate.mobile"     // 手机号格式
+	MsgValidateURL       = "validate.url"        // URL格式
+	MsgValidateAlphaNum  = "validate.alphanum"   // 仅字母与数字
+	MsgValidateInvalid   = "validate.invalid"    // 通用格式错误（自定义规则未注册文案时使用）
+	MsgValidateBindError = "validate.bind_error" // 参数类型错误（Bind转换失败）
+)
+
+func init() {
+	Register("zh-CN", map[string]string{
+		MsgInvalidAction:      "无效的接口",
+		MsgInvalidPayload:     "消息格式错误",
+		MsgRateLimited:        "请求过于频繁，请稍后再试",
+		MsgAuthFailed:         "身份验证失败",
+		MsgTokenExpired:       "登录已过期，请重新登录",
+		MsgMessageTooLarge:    "消息大小超出限制",
+		MsgServerDraining:     "服务正在重启，请稍后重新连接",
+		MsgServerRestart:      "服务即将重启，请重新连接",
+		MsgTooManyConnections: "连接数已达上限",
+		MsgHandshakeFailed:    "握手失败：%v",
+		MsgHandshakeTimeout:   "握手超时",
+		MsgUnsupportedVersion: "不支持的协议版本：%s（支持的版本：%s）",
+		MsgCSRFFailed:         "页面已过期，请刷新后重试",
+		MsgRequestTooLarge:    "请求内容过大",
+		MsgRequestTimeout:     "请求处理超时，请稍后再试",
+		MsgOAuthFailed:        "第三方登录失败，请重试",
+		MsgMaintenance:        "系统维护中，请稍后再试",
+		MsgReadOnly:           "系统维护中，暂不支持修改操作",
+		MsgInternalError:      "服务器内部错误",
+		MsgForbidden:          "无权访问",
+	})
+	Register("zh-CN", map[string]string{
+		MsgTimeJustNow:         "刚刚",
+		MsgTimeAgo:             "%s前",
+		MsgTimeLater:           "%s后",
+		MsgDurationSeparator:   "",
+		MsgDurationMillisecond: "%d毫秒",
+		MsgDurationSecond:      "%d秒",
+		MsgDurationMinute:      "%d分钟",
+		MsgDurationHour:        "%d小时",
+		MsgDurationDay:         "%d天",
+		MsgDurationMonth:       "%d个月",
+		MsgDurationYear:        "%d年",
+	})
+	Register("zh-CN", map[string]string{
+		MsgValidateRequired:  "%s不能为空",
+		MsgValidateMin:       "%s不能小于%s",
+		MsgValidateMax:       "%s不能大于%s",
+		MsgValidateMinLen:    "%s长度不能少于%s",
+		MsgValidateMaxLen:    "%s长度不能超过%s",
+		MsgValidateLen:       "%s长度必须为%s",
+		MsgValidateGt:        "%s必须大于%s",
+		MsgValidateGte:       "%s必须大于或等于%s",
+		MsgValidateLt:        "%s必须小于%s",
+		MsgValidateLte:       "%s必须小于或等于%s",
+		MsgValidateOneOf:     "%s必须是[%s]中的一个",
+		MsgValidateEmail:     "%s不是有效的邮箱地址",
+		MsgValidateMobile:    "%s不是有效的手机号",
+		MsgValidateURL:       "%s不是有效的URL",
+		MsgValidateAlphaNum:  "%s只能包含字母和数字",
+		MsgValidateInvalid:   "%s格式不正确",
+		MsgValidateBindError: "%s类型错误",
+	})
+	Register("en", map[string]string{
+		MsgInvalidAction:      "invalid action",
+		MsgInvalidPayload:     "invalid message format",
+		MsgRateLimited:        "too many requests, please retry later",
+		MsgAuthFailed:         "authentication failed",
+		MsgTokenExpired:       "token expired, please sign in again",
+		MsgMessageTooLarge:    "message size exceeds limit",
+		MsgServerDraining:     "server is draining, please reconnect later",
+		MsgServerRestart:      "server is restarting, please reconnect",
+		MsgTooManyConnections: "too many connections",
+		MsgHandshakeFailed:    "handshake failed: %v",
+		MsgHandshakeTimeout:   "handshake timeout",
+		MsgUnsupportedVersion: "unsupported protocol version: %s (supported: %s)",
+		MsgCSRFFailed:         "invalid or missing CSRF token, please refresh and retry",
+		MsgRequestTooLarge:    "request entity too large",
+		MsgRequestTimeout:     "request timed out, please retry later",
+		MsgOAuthFailed:        "third-party sign-in failed, please retry",
+		MsgMaintenance:        "service under maintenance, please retry later",
+		MsgReadOnly:           "service is read-only during maintenance, please retry later",
+		MsgInternalError:      "internal server error",
+		MsgForbidden:          "access denied",
+	})
+	Register("en", map[string]string{
+		MsgTimeJustNow:                  "just now",
+		MsgTimeAgo:                      "%s ago",
+		MsgTimeLater:                    "in %s",
+		MsgDurationSeparator:            " ",
+		MsgDurationMillisecond:          "%d milliseconds",
+		MsgDurationMillisecond + ".one": "%d millisecond",
+		MsgDurationSecond:               "%d seconds",
+		MsgDurationSecond + ".one":      "%d second",
+		MsgDurationMinute:               "%d minutes",
+		MsgDurationMinute + ".one":      "%d minute",
+		MsgDurationHour:                 "%d hours",
+		MsgDurationHour + ".one":        "%d hour",
+		MsgDurationDay:                  "%d days",
+		MsgDurationDay + ".one":         "%d day",
+		MsgDurationMonth:                "%d months",
+		MsgDurationMonth + ".one":       "%d month",
+		MsgDurationYear:                 "%d years",
+		MsgDurationYear + ".one":        "%d year",
+	})
+	Register("en", map[string]string{
+		MsgValidateRequired:  "%s is required",
+		MsgValidateMin:       "%s must be at least %s",
+		MsgValidateMax:       "%s must be at most %s",
+		MsgValidateMinLen:    "%s must be at least %s characters or items long",
+		MsgValidateMaxLen:    "%s must be at most %s characters or items long",
+		MsgValidateLen:       "%s must be exactly %s characters or items long",
+		MsgValidateGt:        "%s must be greater than %s",
+		MsgValidateGte:       "%s must be greater than or equal to %s",
+		MsgValidateLt:        "%s must be less than %s",
+		MsgValidateLte:       "%s must be less than or equal to %s",
+		MsgValidateOneOf:     "%s must be one of [%s]",
+		MsgValidateEmail:     "%s must be a valid email address",
+		MsgValidateMobile:    "%s must be a valid mobile number",
+		MsgValidateURL:       "%s must be a valid URL",
+		MsgValidateAlphaNum:  "%s may only contain letters and digits",
+		MsgValidateInvalid:   "%s is invalid",
+		MsgValidateBindError: "%s has an invalid type",
+	})
+}
@@ -0,0 +1,217 @@
+// Package etcd etcd v3 HTTP/JSON网关客户端（config.EtcdSource与discovery.EtcdRegistry共用），
+// 负责认证token、节点切换、key的base64编码与watch流解析，无需引入etcd客户端依赖
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Client 网关客户端
+type Client struct {
+	Endpoints []string     // 节点地址（如 http://127.0.0.1:2379），按顺序尝试
+	Username  string       // 启用认证时的用户名
+	Password  string       // 启用认证时的密码
+	HTTP      *http.Client // 自定义HTTP客户端（如需TLS），为nil时使用默认客户端
+
+	mu    sync.Mutex
+	token string
+}
+
+// KeyValue 键值（已解码）
+type KeyValue struct {
+	Key   string
+	Value []byte
+}
+
+// Encode key/value按网关要求做base64编码
+func Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// PrefixEnd 前缀查询的range_end（最后一个字节加1）
+func PrefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00"
+}
+
+// Range 读取key（rangeEnd非空时读取[key, rangeEnd)范围内的全部键）
+func (c *Client) Range(ctx context.Context, key, rangeEnd string) ([]KeyValue, error) {
+	body := map[string]string{"key": Encode(key)}
+	if rangeEnd != "" {
+		body["range_end"] = Encode(rangeEnd)
+	}
+	var resp struct {
+		Kvs []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := c.Call(ctx, "/v3/kv/range", body, &resp); err != nil {
+		return nil, err
+	}
+	list := make([]KeyValue, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		k, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("etcd键解码失败：%w", err)
+		}
+		v, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			return nil, fmt.Errorf("etcd值解码失败（%s）：%w", k, err)
+		}
+		list = append(list, KeyValue{Key: string(k), Value: v})
+	}
+	return list, nil
+}
+
+// Watch 通过/v3/watch流式接口监听key（rangeEnd非空时监听范围）变更，收到事件时调用onChange，
+// ctx取消时返回nil，连接断开或watch失败时返回错误（由调用方重试）
+func (c *Client) Watch(ctx context.Context, key, rangeEnd string, onChange func()) error {
+	create := map[string]string{"key": Encode(key)}
+	if rangeEnd != "" {
+		create["range_end"] = Encode(rangeEnd)
+	}
+	res, err := c.Do(ctx, "/v3/watch", map[string]interface{}{"create_request": create})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Events []json.RawMessage `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err = decoder.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return errors.New("etcd watch失败：" + msg.Error.Message)
+		}
+		if len(msg.Result.Events) > 0 {
+			onChange()
+		}
+	}
+}
+
+// Call 发起请求并解析JSON响应
+func (c *Client) Call(ctx context.Context, path string, body interface{}, out interface{}) error {
+	res, err := c.Do(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("etcd响应解析失败（%s）：%w", path, err)
+	}
+	return nil
+}
+
+// Do 依次尝试各节点发送POST请求（启用认证时自动获取token），调用方负责关闭响应体
+func (c *Client) Do(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	if len(c.Endpoints) == 0 {
+		return nil, errors.New("etcd节点地址不能为空")
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, endpoint := range c.Endpoints {
+		endpoint = strings.TrimRight(endpoint, "/")
+		token, tokenErr := c.authToken(ctx, endpoint)
+		if tokenErr != nil {
+			lastErr = tokenErr
+			continue
+		}
+		req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, bytes.NewReader(payload))
+		if reqErr != nil {
+			return nil, reqErr
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		res, doErr := c.httpClient().Do(req)
+		if doErr != nil {
+			lastErr = doErr
+			continue
+		}
+		if res.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+			_ = res.Body.Close()
+			if res.StatusCode == http.StatusUnauthorized {
+				c.mu.Lock()
+				c.token = ""
+				c.mu.Unlock()
+			}
+			lastErr = fmt.Errorf("etcd请求失败（%s）：%d %s", endpoint+path, res.StatusCode, strings.TrimSpace(string(msg)))
+			continue
+		}
+		return res, nil
+	}
+	return nil, lastErr
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+// authToken 获取认证token（未配置用户名时返回空）
+func (c *Client) authToken(ctx context.Context, endpoint string) (string, error) {
+	if c.Username == "" {
+		return "", nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" {
+		return c.token, nil
+	}
+	payload, _ := json.Marshal(map[string]string{"name": c.Username, "password": c.Password})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v3/auth/authenticate", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return "", err
+	}
+	if resp.Token == "" {
+		return "", errors.New("etcd认证失败")
+	}
+	c.token = resp.Token
+	return c.token, nil
+}
@@ -0,0 +1,96 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 认证后携带token访问，节点不可用时切换到下一个节点，range结果解码
+func TestClientRangeWithAuthAndFailover(t *testing.T) {
+	var auths atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			auths.Add(1)
+			_, _ = fmt.Fprint(w, `{"token":"tk"}`)
+		case "/v3/kv/range":
+			if r.Header.Get("Authorization") != "tk" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["key"] != Encode("/svc/") || body["range_end"] != Encode("/svc0") {
+				t.Errorf("请求体：%v", body)
+			}
+			_, _ = fmt.Fprintf(w, `{"kvs":[{"key":%q,"value":%q}]}`, Encode("/svc/a"), Encode(`{"id":"a"}`))
+		}
+	}))
+	defer srv.Close()
+	c := &Client{Endpoints: []string{"http://127.0.0.1:1", srv.URL + "/"}, Username: "root", Password: "pwd"}
+	for i := 0; i < 2; i++ {
+		kvs, err := c.Range(context.Background(), "/svc/", PrefixEnd("/svc/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kvs) != 1 || kvs[0].Key != "/svc/a" || string(kvs[0].Value) != `{"id":"a"}` {
+			t.Fatalf("range结果：%+v", kvs)
+		}
+	}
+	if auths.Load() != 1 {
+		t.Fatalf("token应缓存，认证了%d次", auths.Load())
+	}
+}
+
+// watch流收到事件时回调，ctx取消后返回nil，watch错误时返回错误
+func TestClientWatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Create map[string]string `json:"create_request"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Create["key"] == Encode("bad") {
+			_, _ = fmt.Fprint(w, `{"error":{"message":"permission denied"}}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"result":{"created":true}}`+"\n")
+		_, _ = fmt.Fprint(w, `{"result":{"events":[{"type":"PUT"}]}}`+"\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c := &Client{Endpoints: []string{srv.URL}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Watch(ctx, "app.json", "", func() { changed <- struct{}{} })
+	}()
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到变更回调")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("ctx取消后应返回nil，实际%v", err)
+	}
+	if err := c.Watch(context.Background(), "bad", "", func() {}); err == nil {
+		t.Fatal("watch失败应返回错误")
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	for prefix, want := range map[string]string{"/svc/": "/svc0", "a\xff": "b", "\xff": "\x00"} {
+		if got := PrefixEnd(prefix); got != want {
+			t.Fatalf("PrefixEnd(%q)=%q，期望%q", prefix, got, want)
+		}
+	}
+}
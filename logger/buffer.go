@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 请求级日志缓冲：请求内通过FromContext记录的debug/info日志先缓冲在内存中，
+// 请求出错（记录Error日志、返回错误或panic）或耗时超过阈值时按原顺序与原时间输出，
+// 否则丢弃（可选输出一行摘要）。warn及以上级别始终直接输出。
+// 缓冲由HTTP/WS/gRPC的请求ID中间件在配置logger.buffer.enable后自动开启，
+// 包级函数logger.Info等不经过context，不受缓冲影响。
+
+// BufferOptions 请求级日志缓冲参数
+type BufferOptions struct {
+	Level         Level         // 缓冲的最低级别（低于该级别的日志按全局级别直接判断）
+	MaxLines      int           // 最多缓冲的行数（超出时丢弃最早的）
+	SlowThreshold time.Duration // 慢请求阈值（0表示不按耗时输出）
+	Summary       bool          // 丢弃缓冲时输出一行摘要
+}
+
+type bufferedLine struct {
+	level  Level
+	fields Fields
+	time   time.Time
+	msg    string
+}
+
+// Buffer 一个请求的日志缓冲
+type Buffer struct {
+	logger *DefaultLogger
+	opts   BufferOptions
+	start  time.Time
+	fields Fields // 绑定时日志实例的字段（摘要行使用）
+
+	mu      sync.Mutex
+	lines   []bufferedLine
+	dropped int  // 超出MaxLines被丢弃的行数
+	flushed bool // 已输出（请求出错），后续日志直接输出
+	done    bool // 请求已结束
+}
+
+// applyBuffer 应用请求级缓冲配置
+func (l *DefaultLogger) applyBuffer(cfg config.LogBufferConfig) {
+	if !cfg.Enable {
+		l.buffer.Store(nil)
+		return
+	}
+	opts := &BufferOptions{
+		Level:         DebugLevel,
+		MaxLines:      cfg.MaxLines,
+		SlowThreshold: time.Duration(cfg.SlowThreshold) * time.Millisecond,
+		Summary:       cfg.Summary,
+	}
+	if cfg.Level != "" {
+		opts.Level = min(ParseLevel(cfg.Level), InfoLevel)
+	}
+	if opts.MaxLines <= 0 {
+		opts.MaxLines = 200
+	}
+	l.buffer.Store(opts)
+}
+
+// NewBuffer 创建日志缓冲（输出到全局日志实例）
+func NewBuffer(opts BufferOptions) *Buffer {
+	if opts.MaxLines <= 0 {
+		opts.MaxLines = 200
+	}
+	return &Buffer{logger: defaultLogger, opts: opts, start: time.Now()}
+}
+
+// StartBuffer 按日志配置为请求开启缓冲，返回绑定了缓冲的context（未启用时返回原context与nil），
+// 请求结束时须调用Buffer.Finish
+func StartBuffer(ctx context.Context) (context.Context, *Buffer) {
+	if defaultLogger == nil {
+		return ctx, nil
+	}
+	opts := defaultLogger.buffer.Load()
+	if opts == nil {
+		return ctx, nil
+	}
+	b := NewBuffer(*opts)
+	return WithBuffer(ctx, b), b
+}
+
+// WithBuffer 将缓冲绑定到context中的日志实例（之后通过FromContext取得的实例均写入该缓冲）
+func WithBuffer(ctx context.Context, b *Buffer) context.Context {
+	entry, _ := ctxValue(ctx).(*Entry)
+	if entry == nil {
+		entry = &Entry{logger: defaultLogger}
+	}
+	if b.fields == nil {
+		b.fields = entry.fields
+	}
+	return WithContext(ctx, &Entry{logger: entry.logger, fields: entry.fields, buffer: b})
+}
+
+// BufferFromContext 获取context绑定的日志缓冲（未开启时返回nil）
+func BufferFromContext(ctx context.Context) *Buffer {
+	entry, _ := ctxValue(ctx).(*Entry)
+	if entry == nil {
+		return nil
+	}
+	return entry.buffer
+}
+
+// hold 缓冲一条日志，返回false时由调用方直接输出（级别不在缓冲范围、已出错或请求已结束）
+func (b *Buffer) hold(level Level, fields Fields, v []interface{}) bool {
+	if b == nil || b.logger == nil || level < b.opts.Level || level >= WarnLevel {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.flushed || b.done {
+		return false
+	}
+	if len(b.lines) >= b.opts.MaxLines {
+		b.lines = b.lines[1:]
+		b.dropped++
+	}
+	b.lines = append(b.lines, bufferedLine{level: level, fields: fields, time: time.Now(), msg: strings.TrimSuffix(fmt.Sprintln(v...), "\n")})
+	return true
+}
+
+// Flush 立即按原顺序输出已缓冲的日志，之后的日志直接输出（记录Error日志时自动调用）
+func (b *Buffer) Flush() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.flushed {
+		return
+	}
+	b.flushed = true
+	if b.dropped > 0 && len(b.lines) > 0 {
+		first := b.lines[0]
+		b.logger.write(InfoLevel, first.fields, first.time, "", fmt.Sprintf("日志缓冲已满，此前%d行已丢弃", b.dropped))
+	}
+	for _, line := range b.lines {
+		b.logger.write(line.level, line.fields, line.time, "", line.msg)
+	}
+	b.lines = nil
+}
+
+// Finish 请求结束：failed为true或耗时超过慢请求阈值时输出缓冲，否则丢弃
+func (b *Buffer) Finish(failed bool) {
+	if b == nil {
+		return
+	}
+	elapsed := time.Since(b.start)
+	if failed || (b.opts.SlowThreshold > 0 && elapsed >= b.opts.SlowThreshold) {
+		b.Flush()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return
+	}
+	b.done = true
+	if !b.flushed && b.opts.Summary && len(b.lines) > 0 {
+		b.logger.write(InfoLevel, b.fields, time.Now(), "", fmt.Sprintf("请求完成，耗时：%v，已丢弃缓冲日志%d行", elapsed, len(b.lines)+b.dropped))
+	}
+	b.lines = nil
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id" // HTTP请求/响应头中的请求ID（gRPC元数据键为其小写形式）
+	RequestIDField  = "request_id"   // 日志中的请求ID字段
+	TraceIDField    = "trace_id"     // 日志中的链路追踪ID字段
+	SpanIDField     = "span_id"      // 日志中的span ID字段
+)
+
+type loggerCtxKey struct{}
+type requestIDCtxKey struct{}
+
+// NewRequestID 生成请求ID
+func NewRequestID() string {
+	return uuid.NewString()
+}
+
+// WithRequestID 将请求ID及携带该ID的日志实例注入context（由HTTP/WS/gRPC入口中间件调用）
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDCtxKey{}, requestID)
+	return WithContext(ctx, WithFields(Fields{RequestIDField: requestID}))
+}
+
+// RequestIDFromContext 获取context中的请求ID（不存在时返回空字符串）
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return requestID
+}
+
+// WithContext 将日志实例绑定到context
+func WithContext(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, entry)
+}
+
+// FromContext 获取context绑定的请求级日志实例（未绑定时返回全局日志），
+// context中存在有效的链路追踪span时自动附带trace_id、span_id字段，
+// 且通过该实例记录Error日志时在span上记录错误并触发升级采样（见tracing.RecordLogError）
+func FromContext(ctx context.Context) *Entry {
+	entry, _ := ctxValue(ctx).(*Entry)
+	if entry == nil {
+		entry = &Entry{logger: defaultLogger}
+	}
+	if ctx != nil {
+		if span := trace.SpanFromContext(ctx); span.SpanContext().HasTraceID() {
+			spanCtx := span.SpanContext()
+			fields := Fields{TraceIDField: spanCtx.TraceID().String()}
+			if spanCtx.HasSpanID() {
+				fields[SpanIDField] = spanCtx.SpanID().String()
+			}
+			entry = entry.WithFields(fields)
+			entry.span = span
+		}
+	}
+	return entry
+}
+
+func ctxValue(ctx context.Context) interface{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Value(loggerCtxKey{})
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"fmt"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+	"strings"
+)
+
+// Entry 携带结构化字段的日志条目（JSON格式下字段输出为顶层键，文本格式下追加为key=value）
+type Entry struct {
+	logger *DefaultLogger
+	fields Fields
+	span   trace.Span // 请求所在的span（由FromContext绑定）
+	buffer *Buffer    // 请求级日志缓冲（见StartBuffer）
+}
+
+var _ Logger = (*Entry)(nil)
+
+// WithFields 追加字段，返回新的日志条目（不修改原条目）
+func (e *Entry) WithFields(fields Fields) *Entry {
+	merged := make(Fields, len(e.fields)+len(fields))
+	for key, val := range e.fields {
+		merged[key] = val
+	}
+	for key, val := range fields {
+		merged[key] = val
+	}
+	return &Entry{logger: e.logger, fields: merged, span: e.span, buffer: e.buffer}
+}
+
+// WithField 追加单个字段
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+func (e *Entry) Debug(v ...interface{}) {
+	if e.logger != nil && !e.buffer.hold(DebugLevel, e.fields, v) {
+		e.logger.output(DebugLevel, e.fields, v...)
+	}
+}
+
+func (e *Entry) Info(v ...interface{}) {
+	if e.logger != nil && !e.buffer.hold(InfoLevel, e.fields, v) {
+		e.logger.output(InfoLevel, e.fields, v...)
+	}
+}
+
+func (e *Entry) Warn(v ...interface{}) {
+	if e.logger != nil {
+		e.logger.output(WarnLevel, e.fields, v...)
+	}
+}
+
+func (e *Entry) Error(v ...interface{}) {
+	e.recordSpanError(v...)
+	e.buffer.Flush()
+	if e.logger != nil {
+		e.logger.output(ErrorLevel, e.fields, v...)
+	}
+}
+
+func (e *Entry) Fatal(v ...interface{}) {
+	e.recordSpanError(v...)
+	e.buffer.Flush()
+	if e.logger != nil {
+		e.logger.output(FatalLevel, e.fields, v...)
+	}
+	os.Exit(1)
+}
+
+func (e *Entry) Debugf(format string, args ...interface{}) {
+	e.Debug(fmt.Sprintf(format, args...))
+}
+
+func (e *Entry) Infof(format string, args ...interface{}) {
+	e.Info(fmt.Sprintf(format, args...))
+}
+
+func (e *Entry) Warnf(format string, args ...interface{}) {
+	e.Warn(fmt.Sprintf(format, args...))
+}
+
+func (e *Entry) Errorf(format string, args ...interface{}) {
+	e.Error(fmt.Sprintf(format, args...))
+}
+
+// recordSpanError 在所在span上记录错误日志并升级采样
+func (e *Entry) recordSpanError(v ...interface{}) {
+	if e.span != nil {
+		tracing.RecordLogError(e.span, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	}
+}
+
+func (e *Entry) GetEnv() string {
+	if e.logger == nil {
+		return ""
+	}
+	return e.logger.GetEnv()
+}
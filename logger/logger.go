@@ -1,33 +1,88 @@
 package logger
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/dfpopp/go-dai/config"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Logger 日志接口
 type Logger interface {
+	Debug(v ...interface{}) //调试日志
 	Info(v ...interface{})  //正常日志
 	Warn(v ...interface{})  //警告日志
 	Error(v ...interface{}) //错误日志
+	Fatal(v ...interface{}) //致命错误日志（记录后退出进程）
 	GetEnv() string         //获取当前运行环境
 }
 
+// Level 日志级别
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	FatalLevel
+)
+
+// String 级别名称（小写，用于JSON输出）
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case FatalLevel:
+		return "fatal"
+	}
+	return "unknown"
+}
+
+// ParseLevel 解析配置中的级别名称（无法识别时默认info）
+func ParseLevel(level string) Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return DebugLevel
+	case "warn", "warning":
+		return WarnLevel
+	case "error":
+		return ErrorLevel
+	case "fatal":
+		return FatalLevel
+	}
+	return InfoLevel
+}
+
+// Fields 结构化日志字段
+type Fields map[string]interface{}
+
 // DefaultLogger 默认日志实现
 type DefaultLogger struct {
-	infoLogger  *log.Logger
-	warnLogger  *log.Logger
-	errorLogger *log.Logger
-	cfg         *config.AppConfig
-	appPath     string
+	writers  map[Level]io.Writer           // 各级别输出（prod写入轮转文件，其他环境输出到控制台）
+	level    atomic.Int32                  // 最低输出级别（支持配置热更新时在线调整）
+	cfgLevel atomic.Int32                  // 配置文件中的级别（运行时覆盖撤销后恢复为该级别）
+	modules  atomic.Pointer[[]moduleLevel] // 按模块的日志级别（按模块名长度降序）
+	json     bool                          // 是否以JSON格式输出
+	mu       sync.Mutex                    // 控制台输出锁，避免多协程日志交错
+	cfg      *config.AppConfig
+	appPath  string
+	buffer   atomic.Pointer[BufferOptions] // 请求级日志缓冲（为nil时未启用，见StartBuffer）
 }
 
 var (
@@ -39,6 +94,9 @@ var (
 func getCallerPrefix() string {
 	fileList := make([]string, 0)
 	goRoot := os.Getenv("GOROOT")
+	if goRoot == "" {
+		goRoot = runtime.GOROOT()
+	}
 	// 遍历调用栈，跳过logger包内的调用，找到业务代码位置
 	for i := 0; i < 10; i++ {
 		pc, filePath, lineNum, ok := runtime.Caller(i)
@@ -52,7 +110,7 @@ func getCallerPrefix() string {
 		}
 		funcName := funcInfo.Name()
 		// 跳过logger包内的所有调用（匹配包路径关键词）
-		if strings.Contains(funcName, "github.com/dfpopp/go-dai/logger") || strings.Contains(filePath, goRoot) {
+		if strings.Contains(funcName, "github.com/dfpopp/go-dai/logger") || (goRoot != "" && strings.Contains(filePath, goRoot)) {
 			continue
 		}
 		// 只保留文件名+行号（如 login.go:25），也可保留完整路径
@@ -69,7 +127,7 @@ func getCallerPrefix() string {
 	return "[unknown:0] "
 }
 
-// InitLogger 初始化日志（移除 log.Lshortfile，改用自定义前缀）
+// InitLogger 初始化日志（prod环境按级别写入轮转文件，其他环境输出到控制台）
 func InitLogger(appName string, appPath string) error {
 	var err error
 	once.Do(func() {
@@ -78,47 +136,54 @@ func InitLogger(appName string, appPath string) error {
 			err = errors.New("应用配置未加载")
 			return
 		}
-		logPath := cfg.Logger.Path
-		today := time.Now().Format("20060102")
-		infoLogPath := filepath.Join(logPath, "info", today+".log")
-		warnLogPath := filepath.Join(logPath, "warn", today+".log")
-		errLogPath := filepath.Join(logPath, "error", today+".log")
-		affairLogPath := filepath.Join(logPath, "affair.log")
-
-		// 创建日志目录
-		dirs := []string{filepath.Dir(infoLogPath), filepath.Dir(warnLogPath), filepath.Dir(errLogPath), filepath.Dir(affairLogPath)}
-		for _, dir := range dirs {
-			if mkdirErr := os.MkdirAll(dir, 0755); mkdirErr != nil {
-				err = fmt.Errorf("创建日志目录失败: %s, err=%v", dir, mkdirErr)
-				return
-			}
-		}
-
-		// 打开日志文件
-		infoFile, infoErr := os.OpenFile(infoLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if infoErr != nil {
-			err = fmt.Errorf("打开信息日志文件失败: %v", infoErr)
-			return
+		logCfg := cfg.Logger
+		l := &DefaultLogger{
+			writers: make(map[Level]io.Writer),
+			json:    strings.ToLower(logCfg.Format) == "json",
+			cfg:     cfg,
+			appPath: appPath,
 		}
-		warnFile, warnErr := os.OpenFile(warnLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if warnErr != nil {
-			err = fmt.Errorf("打开警告日志文件失败: %v", warnErr)
+		l.cfgLevel.Store(int32(ParseLevel(logCfg.Level)))
+		l.applyRuntime(config.GetRuntime())
+		l.applyBuffer(logCfg.Buffer)
+		// 配置热更新时同步调整日志级别与请求级缓冲（管理接口覆盖的级别优先）
+		config.OnAppConfigChange(func(name string, oldCfg, newCfg *config.AppConfig) {
+			if name == appName && newCfg != nil {
+				l.cfgLevel.Store(int32(ParseLevel(newCfg.Logger.Level)))
+				l.applyRuntime(config.GetRuntime())
+				l.applyBuffer(newCfg.Logger.Buffer)
+			}
+		})
+		config.OnRuntimeChange(func(_, newVal config.RuntimeOverrides) {
+			l.applyRuntime(newVal)
+		})
+		if cfg.Env != "prod" {
+			for _, level := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, FatalLevel} {
+				l.writers[level] = os.Stdout
+			}
+			defaultLogger = l
 			return
 		}
-		errFile, errErr := os.OpenFile(errLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if errErr != nil {
-			err = fmt.Errorf("打开错误日志文件失败: %v", errErr)
+
+		// 创建事务日志目录
+		affairLogPath := filepath.Join(logCfg.Path, "affair.log")
+		if mkdirErr := os.MkdirAll(filepath.Dir(affairLogPath), 0755); mkdirErr != nil {
+			err = fmt.Errorf("创建日志目录失败: %s, err=%v", filepath.Dir(affairLogPath), mkdirErr)
 			return
 		}
-
-		// 关键修改：移除 log.Lshortfile，只保留时间和日期
-		defaultLogger = &DefaultLogger{
-			infoLogger:  log.New(infoFile, "INFO: ", log.Ldate|log.Ltime),
-			warnLogger:  log.New(warnFile, "WARN: ", log.Ldate|log.Ltime),
-			errorLogger: log.New(errFile, "ERROR: ", log.Ldate|log.Ltime),
-			cfg:         cfg,
-			appPath:     appPath,
+		// 按级别创建轮转写入器（debug写入info目录，fatal写入error目录）
+		dirs := map[string]Level{"info": InfoLevel, "warn": WarnLevel, "error": ErrorLevel}
+		for dir, level := range dirs {
+			writer, writerErr := newRotateWriter(filepath.Join(logCfg.Path, dir), logCfg.MaxSize, logCfg.MaxAge, logCfg.Compress)
+			if writerErr != nil {
+				err = writerErr
+				return
+			}
+			l.writers[level] = writer
 		}
+		l.writers[DebugLevel] = l.writers[InfoLevel]
+		l.writers[FatalLevel] = l.writers[ErrorLevel]
+		defaultLogger = l
 	})
 	return err
 }
@@ -128,46 +193,162 @@ func GetLogger() Logger {
 	return defaultLogger
 }
 
-// Info 打印信息日志（添加业务代码位置前缀）
-func (l *DefaultLogger) Info(v ...interface{}) {
-	// 拼接业务代码位置前缀
+// SetLevel 设置最低输出级别
+func (l *DefaultLogger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+// GetLevel 获取最低输出级别
+func (l *DefaultLogger) GetLevel() Level {
+	return Level(l.level.Load())
+}
 
-	if l.cfg.Env == "prod" {
-		l.infoLogger.Println(v...)
+// output 格式化并输出一条日志（warn及以上级别附带业务代码位置）
+func (l *DefaultLogger) output(level Level, fields Fields, v ...interface{}) {
+	if !l.enabled(level) {
+		return
+	}
+	caller := ""
+	if level >= WarnLevel {
+		caller = strings.TrimSpace(getCallerPrefix())
+	}
+	l.write(level, fields, time.Now(), caller, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+// write 按格式输出一条已格式化消息的日志（不再判断级别，缓冲日志输出时使用记录时的时间）
+func (l *DefaultLogger) write(level Level, fields Fields, now time.Time, caller, msg string) {
+	var line []byte
+	if l.json {
+		entry := make(map[string]interface{}, len(fields)+4)
+		for key, val := range fields {
+			if errVal, ok := val.(error); ok {
+				val = errVal.Error()
+			}
+			entry[key] = val
+		}
+		entry["time"] = now.Format("2006-01-02 15:04:05.000")
+		entry["level"] = level.String()
+		entry["msg"] = msg
+		if caller != "" {
+			entry["caller"] = caller
+		}
+		data, jsonErr := json.Marshal(entry)
+		if jsonErr != nil {
+			data, _ = json.Marshal(map[string]interface{}{"time": entry["time"], "level": entry["level"], "msg": msg, "fields_error": jsonErr.Error()})
+		}
+		line = append(data, '\n')
 	} else {
-		fmt.Println(append([]interface{}{"INFO: "}, v...)...)
+		var builder strings.Builder
+		builder.WriteString(strings.ToUpper(level.String()))
+		builder.WriteString(": ")
+		builder.WriteString(now.Format("2006/01/02 15:04:05"))
+		builder.WriteString(" ")
+		if caller != "" {
+			builder.WriteString(caller)
+			builder.WriteString(" ")
+		}
+		// 请求ID、链路ID作为前缀输出，便于按请求检索日志
+		for _, key := range []string{RequestIDField, TraceIDField} {
+			if val, ok := fields[key]; ok {
+				builder.WriteString(fmt.Sprintf("[%s=%v] ", key, val))
+			}
+		}
+		builder.WriteString(msg)
+		if len(fields) > 0 {
+			keys := make([]string, 0, len(fields))
+			for key := range fields {
+				if key == RequestIDField || key == TraceIDField {
+					continue
+				}
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				builder.WriteString(fmt.Sprintf(" %s=%v", key, fields[key]))
+			}
+		}
+		builder.WriteString("\n")
+		line = []byte(builder.String())
 	}
+	writer := l.writers[level]
+	if writer == nil {
+		return
+	}
+	l.mu.Lock()
+	_, _ = writer.Write(line)
+	l.mu.Unlock()
+}
+
+// Debug 打印调试日志
+func (l *DefaultLogger) Debug(v ...interface{}) {
+	l.output(DebugLevel, nil, v...)
+}
+
+// Info 打印信息日志
+func (l *DefaultLogger) Info(v ...interface{}) {
+	l.output(InfoLevel, nil, v...)
 }
 
 // Warn 打印警告日志（添加业务代码位置前缀）
 func (l *DefaultLogger) Warn(v ...interface{}) {
-	prefix := getCallerPrefix()
-	newV := append([]interface{}{prefix}, v...)
-
-	if l.cfg.Env == "prod" {
-		l.warnLogger.Println(newV...)
-	} else {
-		fmt.Println(append([]interface{}{"WARN: "}, newV...)...)
-	}
+	l.output(WarnLevel, nil, v...)
 }
 
 // Error 打印错误日志（添加业务代码位置前缀）
 func (l *DefaultLogger) Error(v ...interface{}) {
-	prefix := getCallerPrefix()
-	newV := append([]interface{}{prefix}, v...)
+	l.output(ErrorLevel, nil, v...)
+}
 
-	if l.cfg.Env == "prod" {
-		l.errorLogger.Println(newV...)
-	} else {
-		fmt.Println(append([]interface{}{"ERROR: "}, newV...)...)
+// Fatal 打印致命错误日志后退出进程
+func (l *DefaultLogger) Fatal(v ...interface{}) {
+	l.output(FatalLevel, nil, v...)
+	os.Exit(1)
+}
+
+// Debugf 按格式打印调试日志
+func (l *DefaultLogger) Debugf(format string, args ...interface{}) {
+	l.outputf(DebugLevel, nil, format, args...)
+}
+
+// Infof 按格式打印信息日志
+func (l *DefaultLogger) Infof(format string, args ...interface{}) {
+	l.outputf(InfoLevel, nil, format, args...)
+}
+
+// Warnf 按格式打印警告日志
+func (l *DefaultLogger) Warnf(format string, args ...interface{}) {
+	l.outputf(WarnLevel, nil, format, args...)
+}
+
+// Errorf 按格式打印错误日志
+func (l *DefaultLogger) Errorf(format string, args ...interface{}) {
+	l.outputf(ErrorLevel, nil, format, args...)
+}
+
+// outputf 按格式化字符串输出（级别未启用时不格式化）
+func (l *DefaultLogger) outputf(level Level, fields Fields, format string, args ...interface{}) {
+	if !l.enabled(level) {
+		return
 	}
+	l.output(level, fields, fmt.Sprintf(format, args...))
 }
 
 func (l *DefaultLogger) GetEnv() string {
 	return l.cfg.Env
 }
 
+// WithFields 创建携带结构化字段的日志条目
+func (l *DefaultLogger) WithFields(fields Fields) *Entry {
+	return &Entry{logger: l, fields: fields}
+}
+
 // 全局快捷方法（无需修改，会自动调用带前缀的方法）
+func Debug(v ...interface{}) {
+	if defaultLogger != nil {
+		defaultLogger.Debug(v...)
+	}
+}
+
 func Info(v ...interface{}) {
 	if defaultLogger != nil {
 		defaultLogger.Info(v...)
@@ -185,6 +366,43 @@ func Error(v ...interface{}) {
 		defaultLogger.Error(v...)
 	}
 }
+
+// Debugf 按格式打印调试日志（如 logger.Errorf("关闭结果集失败：%v", err)，下同）
+func Debugf(format string, args ...interface{}) {
+	if defaultLogger != nil {
+		defaultLogger.Debugf(format, args...)
+	}
+}
+
+func Infof(format string, args ...interface{}) {
+	if defaultLogger != nil {
+		defaultLogger.Infof(format, args...)
+	}
+}
+
+func Warnf(format string, args ...interface{}) {
+	if defaultLogger != nil {
+		defaultLogger.Warnf(format, args...)
+	}
+}
+
+func Errorf(format string, args ...interface{}) {
+	if defaultLogger != nil {
+		defaultLogger.Errorf(format, args...)
+	}
+}
+
+func Fatal(v ...interface{}) {
+	if defaultLogger != nil {
+		defaultLogger.Fatal(v...)
+	}
+	os.Exit(1)
+}
+
+// WithFields 创建携带结构化字段的日志条目（如 logger.WithFields(logger.Fields{"uid": 1}).Info("登录成功")）
+func WithFields(fields Fields) *Entry {
+	return &Entry{logger: defaultLogger, fields: fields}
+}
 func getFilePath(filePath string) string {
 	if strings.Contains(filePath, "/go-Dai/") {
 		pathList := strings.Split(filePath, "/go-Dai/")
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestLogger(json bool) (*DefaultLogger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l := &DefaultLogger{writers: map[Level]io.Writer{}, json: json}
+	for _, level := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel} {
+		l.writers[level] = buf
+	}
+	l.SetLevel(DebugLevel)
+	return l, buf
+}
+
+func TestErrorfFormatsMessage(t *testing.T) {
+	l, buf := newTestLogger(true)
+	l.Errorf("关闭结果集失败: %v [%d]", io.EOF, 3)
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("输出不是JSON：%q", buf.String())
+	}
+	if entry["msg"] != "关闭结果集失败: EOF [3]" || entry["level"] != "error" {
+		t.Fatalf("msg=%v level=%v", entry["msg"], entry["level"])
+	}
+	if entry["caller"] == nil {
+		t.Fatal("错误日志应携带调用位置")
+	}
+}
+
+func TestFormattedLevelFiltered(t *testing.T) {
+	l, buf := newTestLogger(false)
+	l.SetLevel(WarnLevel)
+	l.Infof("不输出 %d", 1)
+	l.Debugf("不输出 %d", 2)
+	if buf.Len() != 0 {
+		t.Fatalf("低于级别的日志不应输出：%q", buf.String())
+	}
+	l.Warnf("磁盘使用率%d%%", 91)
+	if !strings.Contains(buf.String(), "磁盘使用率91%") {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
+func TestEntryErrorfKeepsFields(t *testing.T) {
+	l, buf := newTestLogger(false)
+	l.WithFields(Fields{"uid": 7}).Errorf("扣款失败：%s", "余额不足")
+	if out := buf.String(); !strings.Contains(out, "扣款失败：余额不足") || !strings.Contains(out, "uid=7") {
+		t.Fatalf("got %q", out)
+	}
+}
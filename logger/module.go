@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"github.com/dfpopp/go-dai/config"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// 按模块的日志级别：模块按调用方的包路径匹配，可写完整导入路径或其末尾若干段（如 websocket、db/mysql），
+// 子包继承父包的设置，多个模块同时命中时取最长的一项；未命中时使用全局级别。
+// 仅在设置了模块级别时才解析调用栈，未设置时与原有的全局级别判断开销一致。
+
+const loggerPackage = "github.com/dfpopp/go-dai/logger"
+
+// moduleLevel 单个模块的最低输出级别
+type moduleLevel struct {
+	module string
+	level  Level
+}
+
+// SetModuleLevels 整体替换按模块的日志级别（传入空表清除全部）
+func (l *DefaultLogger) SetModuleLevels(levels map[string]Level) {
+	mods := make([]moduleLevel, 0, len(levels))
+	for module, level := range levels {
+		module = strings.Trim(strings.TrimSpace(module), "/")
+		if module == "" || module == config.GlobalLogModule {
+			continue
+		}
+		mods = append(mods, moduleLevel{module: module, level: level})
+	}
+	sort.Slice(mods, func(i, j int) bool {
+		if len(mods[i].module) != len(mods[j].module) {
+			return len(mods[i].module) > len(mods[j].module)
+		}
+		return mods[i].module < mods[j].module
+	})
+	l.modules.Store(&mods)
+}
+
+// ModuleLevels 当前按模块的日志级别
+func (l *DefaultLogger) ModuleLevels() map[string]Level {
+	out := make(map[string]Level)
+	if mods := l.modules.Load(); mods != nil {
+		for _, m := range *mods {
+			out[m.module] = m.level
+		}
+	}
+	return out
+}
+
+// enabled 判断该级别的日志是否需要输出
+func (l *DefaultLogger) enabled(level Level) bool {
+	mods := l.modules.Load()
+	if mods == nil || len(*mods) == 0 {
+		return level >= l.GetLevel()
+	}
+	if pkg := callerPackage(); pkg != "" {
+		for _, m := range *mods {
+			if matchModule(pkg, m.module) {
+				return level >= m.level
+			}
+		}
+	}
+	return level >= l.GetLevel()
+}
+
+// applyRuntime 应用运行时覆盖的日志级别（未覆盖全局级别时恢复为配置文件中的级别）
+func (l *DefaultLogger) applyRuntime(val config.RuntimeOverrides) {
+	if level, ok := val.LogLevels[config.GlobalLogModule]; ok {
+		l.SetLevel(ParseLevel(level))
+	} else {
+		l.SetLevel(Level(l.cfgLevel.Load()))
+	}
+	levels := make(map[string]Level, len(val.LogLevels))
+	for module, level := range val.LogLevels {
+		levels[module] = ParseLevel(level)
+	}
+	l.SetModuleLevels(levels)
+}
+
+// callerPackage 调用方（logger包之外第一帧）所在的包路径
+func callerPackage() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if pkg := funcPackage(frame.Function); pkg != "" && pkg != loggerPackage {
+			return pkg
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// funcPackage 从完整函数名（如 github.com/a/b.(*T).M）中取出包路径
+func funcPackage(funcName string) string {
+	slash := strings.LastIndex(funcName, "/")
+	dot := strings.Index(funcName[slash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	return funcName[:slash+1+dot]
+}
+
+// matchModule 包路径是否属于该模块（完整路径、路径前缀或末尾若干段）
+func matchModule(pkg, module string) bool {
+	if pkg == module || strings.HasPrefix(pkg, module+"/") {
+		return true
+	}
+	return strings.HasSuffix(pkg, "/"+module) || strings.Contains(pkg, "/"+module+"/")
+}
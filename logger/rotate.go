@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotateWriter 按天+按大小轮转的日志文件写入器
+// 当天日志写入 {dir}/20060102.log，超过MaxSize后重命名为 20060102.1.log、20060102.2.log...，可选gzip压缩及过期清理
+type rotateWriter struct {
+	mu       sync.Mutex
+	dir      string   // 日志目录
+	maxSize  int64    // 单文件最大字节数（0表示不限制）
+	maxAge   int      // 保留天数（0表示不清理）
+	compress bool     // 是否压缩已轮转的文件
+	file     *os.File // 当前文件
+	date     string   // 当前文件日期
+	size     int64    // 当前文件大小
+}
+
+// newRotateWriter 创建轮转写入器（maxSizeMB单位为MB）
+func newRotateWriter(dir string, maxSizeMB int, maxAge int, compress bool) (*rotateWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %s, err=%v", dir, err)
+	}
+	w := &rotateWriter{
+		dir:      dir,
+		maxSize:  int64(maxSizeMB) * 1024 * 1024,
+		maxAge:   maxAge,
+		compress: compress,
+	}
+	if err := w.openFile(time.Now().Format("20060102")); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write 实现io.Writer（跨天或超出大小时自动轮转）
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	today := time.Now().Format("20060102")
+	if w.file == nil || today != w.date {
+		if err := w.rotateByDate(today); err != nil {
+			return 0, err
+		}
+	} else if w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotateBySize(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close 关闭当前文件
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// openFile 打开指定日期的日志文件（追加写入）
+func (w *rotateWriter) openFile(date string) error {
+	path := filepath.Join(w.dir, date+".log")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("读取日志文件信息失败: %v", err)
+	}
+	w.file = file
+	w.date = date
+	w.size = info.Size()
+	return nil
+}
+
+// rotateByDate 跨天轮转：关闭昨日文件并打开当天文件
+func (w *rotateWriter) rotateByDate(today string) error {
+	if w.file != nil {
+		oldPath := w.file.Name()
+		_ = w.file.Close()
+		w.file = nil
+		if w.compress {
+			go compressFile(oldPath)
+		}
+	}
+	if err := w.openFile(today); err != nil {
+		return err
+	}
+	go w.cleanup()
+	return nil
+}
+
+// rotateBySize 大小轮转：当前文件重命名为带序号的备份文件后重新打开
+func (w *rotateWriter) rotateBySize() error {
+	oldPath := w.file.Name()
+	_ = w.file.Close()
+	w.file = nil
+	backupPath := ""
+	for i := 1; ; i++ {
+		backupPath = filepath.Join(w.dir, fmt.Sprintf("%s.%d.log", w.date, i))
+		if !fileExists(backupPath) && !fileExists(backupPath+".gz") {
+			break
+		}
+	}
+	if err := os.Rename(oldPath, backupPath); err != nil {
+		return fmt.Errorf("轮转日志文件失败: %v", err)
+	}
+	if w.compress {
+		go compressFile(backupPath)
+	}
+	return w.openFile(w.date)
+}
+
+// cleanup 删除超出保留天数的日志文件
+func (w *rotateWriter) cleanup() {
+	if w.maxAge <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return
+	}
+	deadline := time.Now().AddDate(0, 0, -w.maxAge)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log.gz")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(deadline) {
+			_ = os.Remove(filepath.Join(w.dir, name))
+		}
+	}
+}
+
+// compressFile gzip压缩日志文件，成功后删除原文件
+func compressFile(path string) {
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	gz := gzip.NewWriter(dst)
+	_, copyErr := io.Copy(gz, src)
+	closeErr := gz.Close()
+	_ = dst.Close()
+	if copyErr != nil || closeErr != nil {
+		_ = os.Remove(path + ".gz")
+		return
+	}
+	_ = os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
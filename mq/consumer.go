@@ -0,0 +1,251 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/logger"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 死信消息附带的消息头
+const (
+	HeaderError       = "x-mq-error"        // 最后一次处理的错误
+	HeaderSourceTopic = "x-mq-source-topic" // 原主题
+	HeaderAttempts    = "x-mq-attempts"     // 处理次数
+)
+
+// ErrConsumerStarted 消费者已启动（启动后不能再注册处理函数）
+var ErrConsumerStarted = errors.New("mq: 消费者已启动")
+
+// HandleOption 处理函数的可选配置
+type HandleOption func(s *subscription)
+
+// WithGroup 消费组（默认为连接配置的group，未配置时为应用名）
+func WithGroup(group string) HandleOption {
+	return func(s *subscription) {
+		s.group = group
+	}
+}
+
+// WithConcurrency 同时处理的消息数（默认1；Kafka为同时处理的分区数）
+func WithConcurrency(n int) HandleOption {
+	return func(s *subscription) {
+		s.concurrency = n
+	}
+}
+
+// WithRetry 处理失败（返回错误或panic）时在本进程内最多重试attempts次，第i次重试前等待backoff*2^(i-1)
+func WithRetry(attempts int, backoff time.Duration) HandleOption {
+	return func(s *subscription) {
+		s.retries = attempts
+		s.backoff = backoff
+	}
+}
+
+// WithTimeout 单次处理超时
+func WithTimeout(timeout time.Duration) HandleOption {
+	return func(s *subscription) {
+		s.timeout = timeout
+	}
+}
+
+// WithDeadLetter 重试用尽后将消息发布到同一连接的死信主题（附带x-mq-error等消息头），发布成功后确认原消息
+func WithDeadLetter(topic string) HandleOption {
+	return func(s *subscription) {
+		s.deadLetter = topic
+	}
+}
+
+type subscription struct {
+	broker      string
+	topic       string
+	fn          Handler
+	group       string
+	concurrency int
+	retries     int
+	backoff     time.Duration
+	timeout     time.Duration
+	deadLetter  string
+}
+
+// Consumer 消费者：登记各连接上主题的处理函数，Start后统一开始消费，Stop时等待处理中的消息完成
+type Consumer struct {
+	group string // 默认消费组
+
+	mu      sync.Mutex
+	subs    []*subscription
+	running []Subscriber
+	started bool
+	runCtx  context.Context // 处理函数的ctx（停机等待超时时取消）
+	cancel  context.CancelFunc
+}
+
+// NewConsumer 创建消费者（group为未指定消费组时的默认值）
+func NewConsumer(group string) *Consumer {
+	c := &Consumer{group: group}
+	c.runCtx, c.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+// FromAppConfig 按应用创建消费者（默认消费组为应用名）
+func FromAppConfig(appName string) *Consumer {
+	return NewConsumer(appName)
+}
+
+// Handle 登记处理函数（broker为数据库配置mq节点中的连接标识）
+func (c *Consumer) Handle(broker, topic string, fn Handler, opts ...HandleOption) error {
+	if topic == "" || fn == nil {
+		return errors.New("mq: 主题与处理函数不能为空")
+	}
+	cfg, ok := config.GetMQConfig()[broker]
+	if !ok {
+		return fmt.Errorf("消息中间件[%s]未配置", broker)
+	}
+	s := &subscription{broker: broker, topic: topic, fn: fn, group: cfg.Group, concurrency: 1, backoff: time.Second}
+	if s.group == "" {
+		s.group = c.group
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = 1
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return ErrConsumerStarted
+	}
+	c.subs = append(c.subs, s)
+	return nil
+}
+
+// Start 开始消费（任一订阅失败时停止已启动的订阅并返回错误）
+func (c *Consumer) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return nil
+	}
+	for _, s := range c.subs {
+		broker, err := GetMQ(s.broker)
+		if err != nil {
+			c.stopRunning(context.Background())
+			return err
+		}
+		sub, err := broker.Subscribe(Subscription{Topic: s.topic, Group: s.group, Concurrency: s.concurrency, Handler: c.wrap(broker, s)})
+		if err != nil {
+			c.stopRunning(context.Background())
+			return fmt.Errorf("订阅[%s/%s]失败：%w", s.broker, s.topic, err)
+		}
+		c.running = append(c.running, sub)
+	}
+	c.started = true
+	logger.Info("消息中间件消费已启动，订阅数：", len(c.subs))
+	return nil
+}
+
+// Stop 停止全部订阅并等待处理中的消息完成；ctx到期时取消处理函数的ctx并返回ctx错误
+func (c *Consumer) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.stopRunning(ctx)
+	c.cancel()
+	return err
+}
+
+// stopRunning 并行停止运行中的订阅（调用方持有c.mu）
+func (c *Consumer) stopRunning(ctx context.Context) error {
+	stopped := make(chan struct{})
+	var errList []error
+	var mu sync.Mutex
+	go func() {
+		defer close(stopped)
+		var wg sync.WaitGroup
+		for _, sub := range c.running {
+			wg.Add(1)
+			go func(sub Subscriber) {
+				defer wg.Done()
+				if err := sub.Stop(ctx); err != nil {
+					mu.Lock()
+					errList = append(errList, err)
+					mu.Unlock()
+				}
+			}(sub)
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		// 取消处理函数的ctx，促使处理中的消息尽快结束
+		c.cancel()
+		<-stopped
+	}
+	c.running = nil
+	return errors.Join(errList...)
+}
+
+// wrap 包装处理函数：超时控制、panic恢复、本进程内重试，重试用尽后转入死信主题
+func (c *Consumer) wrap(broker Broker, s *subscription) Handler {
+	return func(_ context.Context, msg *Message) error {
+		var err error
+		for attempt := 1; ; attempt++ {
+			msg.Attempt = attempt
+			if err = c.call(s, msg); err == nil {
+				return nil
+			}
+			if attempt > s.retries || c.runCtx.Err() != nil {
+				break
+			}
+			wait := s.backoff << (attempt - 1)
+			logger.Warn("消息[", s.topic, "]", msg.ID, "第", attempt, "次处理失败，", wait, "后重试：", err)
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-c.runCtx.Done():
+				timer.Stop()
+			}
+		}
+		if s.deadLetter == "" {
+			logger.Error("消息[", s.topic, "]", msg.ID, "处理失败", msg.Attempt, "次：", err)
+			return err
+		}
+		dead := &Message{Topic: s.deadLetter, Key: msg.Key, Value: msg.Value, Time: msg.Time, Headers: make(map[string]string, len(msg.Headers)+3)}
+		for key, val := range msg.Headers {
+			dead.Headers[key] = val
+		}
+		dead.Headers[HeaderError] = err.Error()
+		dead.Headers[HeaderSourceTopic] = s.topic
+		dead.Headers[HeaderAttempts] = strconv.Itoa(msg.Attempt)
+		if pubErr := broker.Publish(context.Background(), dead); pubErr != nil {
+			logger.Error("消息[", s.topic, "]", msg.ID, "处理失败", msg.Attempt, "次，写入死信失败：", pubErr, " 处理错误：", err)
+			return err
+		}
+		logger.Error("消息[", s.topic, "]", msg.ID, "处理失败", msg.Attempt, "次，已转入死信[", s.deadLetter, "]：", err)
+		return nil
+	}
+}
+
+// call 执行一次处理函数（超时控制，panic转换为错误并记录堆栈）
+func (c *Consumer) call(s *subscription, msg *Message) (err error) {
+	ctx := c.runCtx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	ctx = logger.WithContext(ctx, logger.FromContext(ctx).WithFields(logger.Fields{"mq": s.broker, "topic": s.topic, "msg_id": msg.ID, "attempt": msg.Attempt}))
+	defer func() {
+		if p := recover(); p != nil {
+			logger.Error("消息[", s.topic, "]处理函数panic：", p, "\n", string(debug.Stack()))
+			err = fmt.Errorf("mq: 处理函数panic：%v", p)
+		}
+	}()
+	return s.fn(ctx, msg)
+}
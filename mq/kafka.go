@@ -0,0 +1,339 @@
+package mq
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	kafkaBatchTimeout   = 5 * time.Millisecond   // Publish同步等待写入结果，批次不等待凑满
+	kafkaFetchMaxWait   = 500 * time.Millisecond // 拉取请求在无新消息时的最长等待
+	kafkaPartitionQueue = 64                     // 每个分区已拉取、待处理的消息数上限
+)
+
+// kafkaWriter、kafkaReader 驱动使用的kafka-go方法（*kafka.Writer、*kafka.Reader）
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type kafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// kafkaBroker Kafka驱动（基于segmentio/kafka-go）：发布共用一个Writer（按分区批量写入，leader变更时自动重试），
+// 每个订阅使用独立的消费组Reader
+type kafkaBroker struct {
+	name      string
+	cfg       config.MQConfig
+	writer    kafkaWriter
+	reader    kafka.ReaderConfig                   // 订阅的Reader配置模板（不含消费组与主题）
+	newReader func(kafka.ReaderConfig) kafkaReader // 默认kafka.NewReader
+
+	mu     sync.Mutex
+	subs   map[*kafkaSub]struct{}
+	closed bool
+}
+
+func newKafkaBroker(name string, cfg config.MQConfig) (Broker, error) {
+	writer, reader, err := kafkaConfigs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// 启动时检查集群可用
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutOf(cfg))
+	defer cancel()
+	client := &kafka.Client{Addr: writer.Addr, Timeout: timeoutOf(cfg), Transport: writer.Transport}
+	if _, err := client.Metadata(ctx, &kafka.MetadataRequest{}); err != nil {
+		writer.Transport.(*kafka.Transport).CloseIdleConnections()
+		return nil, fmt.Errorf("kafka: 连接集群失败：%w", err)
+	}
+	return newKafkaAdapter(name, cfg, writer, reader, func(rc kafka.ReaderConfig) kafkaReader {
+		return kafka.NewReader(rc)
+	}), nil
+}
+
+func newKafkaAdapter(name string, cfg config.MQConfig, writer kafkaWriter, reader kafka.ReaderConfig, newReader func(kafka.ReaderConfig) kafkaReader) *kafkaBroker {
+	return &kafkaBroker{
+		name:      name,
+		cfg:       cfg,
+		writer:    writer,
+		reader:    reader,
+		newReader: newReader,
+		subs:      make(map[*kafkaSub]struct{}),
+	}
+}
+
+// kafkaConfigs 按连接配置生成发布用的Writer与订阅用的Reader配置模板
+func kafkaConfigs(cfg config.MQConfig) (*kafka.Writer, kafka.ReaderConfig, error) {
+	if cfg.ClientID == "" {
+		cfg.ClientID = "go-dai"
+	}
+	timeout := timeoutOf(cfg)
+	var tlsCfg *tls.Config
+	if cfg.TLS {
+		tlsCfg = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport := &kafka.Transport{ClientID: cfg.ClientID, DialTimeout: timeout, TLS: tlsCfg}
+	dialer := &kafka.Dialer{ClientID: cfg.ClientID, Timeout: timeout, DualStack: true, TLS: tlsCfg}
+	if cfg.User != "" {
+		mechanism := plain.Mechanism{Username: cfg.User, Password: cfg.Pwd}
+		transport.SASL = mechanism
+		dialer.SASLMechanism = mechanism
+	}
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Addrs...),
+		Balancer:               &kafka.Murmur2Balancer{}, // 与Java客户端一致：有分区键时按murmur2哈希
+		RequiredAcks:           kafka.RequireAll,
+		BatchTimeout:           kafkaBatchTimeout,
+		ReadTimeout:            timeout,
+		WriteTimeout:           timeout,
+		AllowAutoTopicCreation: true, // 由broker的auto.create.topics.enable决定
+		Transport:              transport,
+	}
+	if cfg.Acks == 1 {
+		writer.RequiredAcks = kafka.RequireOne
+	}
+	switch strings.ToLower(cfg.Compression) {
+	case "", "none":
+	case "gzip":
+		writer.Compression = kafka.Gzip
+	case "snappy":
+		writer.Compression = kafka.Snappy
+	case "lz4":
+		writer.Compression = kafka.Lz4
+	case "zstd":
+		writer.Compression = kafka.Zstd
+	default:
+		return nil, kafka.ReaderConfig{}, fmt.Errorf("kafka: 不支持的压缩类型[%s]", cfg.Compression)
+	}
+	reader := kafka.ReaderConfig{
+		Brokers:           cfg.Addrs,
+		Dialer:            dialer,
+		MaxWait:           kafkaFetchMaxWait,
+		StartOffset:       kafka.LastOffset,
+		GroupBalancers:    []kafka.GroupBalancer{kafka.RangeGroupBalancer{}},
+		SessionTimeout:    30 * time.Second,
+		HeartbeatInterval: 3 * time.Second,
+		CommitInterval:    time.Second,
+	}
+	if cfg.Offset == "earliest" {
+		reader.StartOffset = kafka.FirstOffset
+	}
+	if cfg.Session > 0 {
+		reader.SessionTimeout = time.Duration(cfg.Session) * time.Second
+	}
+	if cfg.Heartbeat > 0 {
+		reader.HeartbeatInterval = time.Duration(cfg.Heartbeat) * time.Second
+	}
+	if cfg.Commit > 0 {
+		reader.CommitInterval = time.Duration(cfg.Commit) * time.Millisecond
+	}
+	return writer, reader, nil
+}
+
+// Publish 发布消息并等待写入确认（leader变更等可重试错误由kafka-go重试）
+func (b *kafkaBroker) Publish(ctx context.Context, msgs ...*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	list := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		km := kafka.Message{Topic: b.cfg.Pre + msg.Topic, Key: msg.Key, Value: msg.Value, Time: msg.Time}
+		if km.Time.IsZero() {
+			km.Time = time.Now()
+		}
+		for k, v := range msg.Headers {
+			km.Headers = append(km.Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		list[i] = km
+	}
+	if err := b.writer.WriteMessages(ctx, list...); err != nil {
+		return fmt.Errorf("kafka: 发布失败：%w", err)
+	}
+	return nil
+}
+
+// Subscribe 以消费组方式订阅主题
+func (b *kafkaBroker) Subscribe(sub Subscription) (Subscriber, error) {
+	if sub.Group == "" {
+		return nil, errors.New("kafka: 消费组不能为空")
+	}
+	rc := b.reader
+	rc.GroupID = sub.Group
+	rc.Topic = b.cfg.Pre + sub.Topic
+	prefix := "Kafka消费组[" + sub.Group + "]主题[" + rc.Topic + "]："
+	rc.ErrorLogger = kafka.LoggerFunc(func(format string, args ...interface{}) {
+		logger.Warnf(prefix+format, args...)
+	})
+	s := &kafkaSub{b: b, sub: sub, topic: rc.Topic, sem: make(chan struct{}, max(sub.Concurrency, 1)), done: make(chan struct{})}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrClosed
+	}
+	s.reader = b.newReader(rc)
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	go s.run()
+	return s, nil
+}
+
+// removeSub 订阅停止后移除
+func (b *kafkaBroker) removeSub(s *kafkaSub) {
+	b.mu.Lock()
+	delete(b.subs, s)
+	b.mu.Unlock()
+}
+
+// Close 停止全部订阅（等待处理中的消息完成）并关闭发布连接
+func (b *kafkaBroker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subs := make([]*kafkaSub, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+	var errList []error
+	for _, s := range subs {
+		if err := s.Stop(context.Background()); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	if err := b.writer.Close(); err != nil {
+		errList = append(errList, err)
+	}
+	return errors.Join(errList...)
+}
+
+// kafkaSub 运行中的订阅：拉取的消息按分区分发，分区内按顺序处理，处理完成（含重试与死信）后提交位点
+// （kafka-go按CommitInterval定期提交，重平衡与关闭前提交已处理的位点）
+type kafkaSub struct {
+	b      *kafkaBroker
+	sub    Subscription
+	topic  string // 含前缀的主题名
+	reader kafkaReader
+	sem    chan struct{} // 同时处理的分区数
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop 停止拉取：等待处理中的消息完成，提交位点后离开消费组
+func (s *kafkaSub) Stop(ctx context.Context) error {
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *kafkaSub) logPrefix() string {
+	return "Kafka消费组[" + s.sub.Group + "]主题[" + s.topic + "]"
+}
+
+// run 拉取消息并分发到各分区的处理协程，直到Stop
+func (s *kafkaSub) run() {
+	defer close(s.done)
+	defer s.b.removeSub(s)
+	var wg sync.WaitGroup
+	queues := make(map[int]chan kafka.Message)
+	for s.ctx.Err() == nil {
+		m, err := s.reader.FetchMessage(s.ctx)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				break
+			}
+			// 连接与重平衡由kafka-go处理，这里只在Reader异常时退避
+			logger.Warn(s.logPrefix(), "拉取失败：", err)
+			select {
+			case <-s.ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		queue, ok := queues[m.Partition]
+		if !ok {
+			queue = make(chan kafka.Message, kafkaPartitionQueue)
+			queues[m.Partition] = queue
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.consume(queue)
+			}()
+		}
+		select {
+		case queue <- m:
+		case <-s.ctx.Done():
+		}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+	// 关闭时提交已处理的位点并离开消费组
+	if err := s.reader.Close(); err != nil {
+		logger.Warn(s.logPrefix(), "关闭失败：", err)
+	}
+}
+
+// consume 按顺序处理一个分区的消息（停止后不再处理已拉取未开始的消息，其位点未提交，重新加入后再次投递）
+func (s *kafkaSub) consume(queue <-chan kafka.Message) {
+	for m := range queue {
+		if s.ctx.Err() != nil {
+			continue
+		}
+		s.sem <- struct{}{}
+		_ = s.sub.Handler(s.ctx, s.message(m))
+		<-s.sem
+		if err := s.reader.CommitMessages(context.Background(), m); err != nil {
+			logger.Warn(s.logPrefix(), "提交位点失败：", err)
+		}
+	}
+}
+
+// message 转换为驱动无关的消息
+func (s *kafkaSub) message(m kafka.Message) *Message {
+	msg := &Message{
+		Topic:     s.sub.Topic,
+		Key:       m.Key,
+		Value:     m.Value,
+		Time:      m.Time,
+		ID:        s.sub.Topic + "/" + strconv.Itoa(m.Partition) + "/" + strconv.FormatInt(m.Offset, 10),
+		Partition: int32(m.Partition),
+		Offset:    m.Offset,
+	}
+	if len(m.Headers) > 0 {
+		msg.Headers = make(map[string]string, len(m.Headers))
+		for _, h := range m.Headers {
+			msg.Headers[h.Key] = string(h.Value)
+		}
+	}
+	return msg
+}
@@ -0,0 +1,332 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dfpopp/go-dai/config"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+// fakeKafkaWriter 记录写入的消息
+type fakeKafkaWriter struct {
+	mu     sync.Mutex
+	msgs   []kafka.Message
+	err    error
+	closed bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	return nil
+}
+
+// fakeKafkaReader 按顺序返回预置的消息，取完后阻塞到ctx取消，并记录提交的位点
+type fakeKafkaReader struct {
+	cfg  kafka.ReaderConfig
+	msgs chan kafka.Message
+
+	mu        sync.Mutex
+	committed map[int][]int64 // 分区 -> 按提交顺序的位点
+	closed    bool
+}
+
+func newFakeKafkaReader(cfg kafka.ReaderConfig, msgs ...kafka.Message) *fakeKafkaReader {
+	r := &fakeKafkaReader{cfg: cfg, msgs: make(chan kafka.Message, len(msgs)), committed: make(map[int][]int64)}
+	for _, m := range msgs {
+		r.msgs <- m
+	}
+	return r
+}
+
+func (r *fakeKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case m := <-r.msgs:
+		return m, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeKafkaReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		r.committed[m.Partition] = append(r.committed[m.Partition], m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeKafkaReader) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *fakeKafkaReader) commits(partition int) []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.committed[partition]...)
+}
+
+func (r *fakeKafkaReader) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// newTestKafkaBroker 使用fake读写端创建驱动，reader为订阅时返回的Reader
+func newTestKafkaBroker(t *testing.T, cfg config.MQConfig, reader func(kafka.ReaderConfig) *fakeKafkaReader) (*kafkaBroker, *fakeKafkaWriter) {
+	t.Helper()
+	_, rc, err := kafkaConfigs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &fakeKafkaWriter{}
+	b := newKafkaAdapter("test", cfg, w, rc, func(c kafka.ReaderConfig) kafkaReader { return reader(c) })
+	t.Cleanup(func() { _ = b.Close() })
+	return b, w
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时：%s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 连接配置映射到kafka-go的Writer与Reader配置
+func TestKafkaConfigs(t *testing.T) {
+	w, rc, err := kafkaConfigs(config.MQConfig{Addrs: []string{"k1:9092", "k2:9092"}, User: "app", Pwd: "secret", TLS: true,
+		Acks: 1, Compression: "zstd", Offset: "earliest", Session: 10, Heartbeat: 2, Commit: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Addr.String() != "k1:9092,k2:9092" || w.RequiredAcks != kafka.RequireOne || w.Compression != kafka.Zstd {
+		t.Fatalf("Writer配置：addr=%s acks=%v compression=%v", w.Addr, w.RequiredAcks, w.Compression)
+	}
+	if _, ok := w.Balancer.(*kafka.Murmur2Balancer); !ok {
+		t.Fatalf("分区器应为murmur2，实际%T", w.Balancer)
+	}
+	tr := w.Transport.(*kafka.Transport)
+	if tr.ClientID != "go-dai" || tr.TLS == nil || tr.SASL != (plain.Mechanism{Username: "app", Password: "secret"}) {
+		t.Fatalf("Transport配置：%+v", tr)
+	}
+	if rc.StartOffset != kafka.FirstOffset || rc.SessionTimeout != 10*time.Second || rc.HeartbeatInterval != 2*time.Second ||
+		rc.CommitInterval != 200*time.Millisecond {
+		t.Fatalf("Reader配置：%+v", rc)
+	}
+	if rc.Dialer.TLS == nil || rc.Dialer.SASLMechanism == nil || len(rc.GroupBalancers) != 1 {
+		t.Fatalf("Reader连接配置：%+v", rc.Dialer)
+	}
+	if _, ok := rc.GroupBalancers[0].(kafka.RangeGroupBalancer); !ok {
+		t.Fatalf("分区分配应为range，实际%T", rc.GroupBalancers[0])
+	}
+
+	w, rc, err = kafkaConfigs(config.MQConfig{Addrs: []string{"k1:9092"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.RequiredAcks != kafka.RequireAll || w.Compression != 0 || w.Transport.(*kafka.Transport).SASL != nil || rc.StartOffset != kafka.LastOffset {
+		t.Fatalf("默认配置：acks=%v compression=%v offset=%d", w.RequiredAcks, w.Compression, rc.StartOffset)
+	}
+	if _, _, err := kafkaConfigs(config.MQConfig{Compression: "brotli"}); err == nil {
+		t.Fatal("不支持的压缩类型应返回错误")
+	}
+}
+
+// 发布时加主题前缀、转换消息头，未指定时间时取当前时间
+func TestKafkaPublish(t *testing.T) {
+	b, w := newTestKafkaBroker(t, config.MQConfig{Pre: "dev_"}, nil)
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := b.Publish(context.Background(),
+		&Message{Topic: "orders", Key: []byte("u1"), Value: []byte("a"), Headers: map[string]string{"trace": "t1"}, Time: at},
+		&Message{Topic: "orders", Value: []byte("b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.msgs) != 2 {
+		t.Fatalf("写入%d条", len(w.msgs))
+	}
+	m := w.msgs[0]
+	if m.Topic != "dev_orders" || string(m.Key) != "u1" || string(m.Value) != "a" || !m.Time.Equal(at) ||
+		len(m.Headers) != 1 || m.Headers[0].Key != "trace" || string(m.Headers[0].Value) != "t1" {
+		t.Fatalf("消息转换：%+v", m)
+	}
+	if w.msgs[1].Time.IsZero() || w.msgs[1].Key != nil {
+		t.Fatalf("默认字段：%+v", w.msgs[1])
+	}
+
+	w.err = errors.New("leader not available")
+	if err := b.Publish(context.Background(), &Message{Topic: "orders"}); err == nil {
+		t.Fatal("写入失败应返回错误")
+	}
+	_ = b.Close()
+	if err := b.Publish(context.Background(), &Message{Topic: "orders"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("关闭后发布应返回ErrClosed，实际%v", err)
+	}
+	if !w.closed {
+		t.Fatal("关闭驱动应关闭Writer")
+	}
+}
+
+// 消费：分区内按顺序处理并逐条提交位点，消息字段按驱动无关格式填充
+func TestKafkaConsumeInPartitionOrder(t *testing.T) {
+	var msgs []kafka.Message
+	for i := 0; i < 5; i++ {
+		for p := 0; p < 2; p++ {
+			msgs = append(msgs, kafka.Message{Topic: "dev_orders", Partition: p, Offset: int64(i), Value: []byte{byte(i)},
+				Headers: []kafka.Header{{Key: "k", Value: []byte("v")}}})
+		}
+	}
+	var reader *fakeKafkaReader
+	b, _ := newTestKafkaBroker(t, config.MQConfig{Pre: "dev_"}, func(c kafka.ReaderConfig) *fakeKafkaReader {
+		reader = newFakeKafkaReader(c, msgs...)
+		return reader
+	})
+	var mu sync.Mutex
+	seen := make(map[int32][]int64)
+	var first *Message
+	s, err := b.Subscribe(Subscription{Topic: "orders", Group: "billing", Concurrency: 2, Handler: func(ctx context.Context, m *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if first == nil {
+			first = m
+		}
+		seen[m.Partition] = append(seen[m.Partition], m.Offset)
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.cfg.GroupID != "billing" || reader.cfg.Topic != "dev_orders" || reader.cfg.ErrorLogger == nil {
+		t.Fatalf("订阅的Reader配置：%+v", reader.cfg)
+	}
+	waitFor(t, "提交全部位点", func() bool { return len(reader.commits(0)) == 5 && len(reader.commits(1)) == 5 })
+	mu.Lock()
+	for p := int32(0); p < 2; p++ {
+		for i, off := range seen[p] {
+			if off != int64(i) {
+				t.Fatalf("分区%d处理顺序：%v", p, seen[p])
+			}
+		}
+	}
+	if first.Topic != "orders" || first.ID != "orders/"+strconv.Itoa(int(first.Partition))+"/0" || first.Headers["k"] != "v" {
+		t.Fatalf("消息转换：%+v", first)
+	}
+	mu.Unlock()
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reader.isClosed() {
+		t.Fatal("停止订阅应关闭Reader")
+	}
+}
+
+// 停止时等待处理中的消息完成并提交，已拉取未开始的消息不处理、不提交
+func TestKafkaStopWaitsInFlight(t *testing.T) {
+	msgs := []kafka.Message{{Partition: 0, Offset: 0}, {Partition: 0, Offset: 1}, {Partition: 0, Offset: 2}}
+	var reader *fakeKafkaReader
+	b, _ := newTestKafkaBroker(t, config.MQConfig{}, func(c kafka.ReaderConfig) *fakeKafkaReader {
+		reader = newFakeKafkaReader(c, msgs...)
+		return reader
+	})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []int64
+	s, err := b.Subscribe(Subscription{Topic: "orders", Group: "billing", Handler: func(ctx context.Context, m *Message) error {
+		if m.Offset == 0 {
+			close(started)
+			<-release
+		}
+		mu.Lock()
+		handled = append(handled, m.Offset)
+		mu.Unlock()
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	waitFor(t, "拉取全部消息", func() bool { return len(reader.msgs) == 0 })
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+	select {
+	case <-stopped:
+		t.Fatal("处理中的消息未完成时Stop不应返回")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("等待超时应返回ctx错误，实际%v", err)
+	}
+	cancel()
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 1 || handled[0] != 0 {
+		t.Fatalf("停止后处理了：%v", handled)
+	}
+	if got := reader.commits(0); len(got) != 1 || got[0] != 0 {
+		t.Fatalf("提交的位点：%v", got)
+	}
+	if !reader.isClosed() {
+		t.Fatal("停止订阅应关闭Reader")
+	}
+}
+
+// 关闭驱动时停止全部订阅，关闭后不能再订阅
+func TestKafkaCloseStopsSubscriptions(t *testing.T) {
+	var readers []*fakeKafkaReader
+	b, _ := newTestKafkaBroker(t, config.MQConfig{}, func(c kafka.ReaderConfig) *fakeKafkaReader {
+		r := newFakeKafkaReader(c)
+		readers = append(readers, r)
+		return r
+	})
+	noop := func(context.Context, *Message) error { return nil }
+	if _, err := b.Subscribe(Subscription{Topic: "a", Handler: noop}); err == nil {
+		t.Fatal("消费组为空应返回错误")
+	}
+	for _, topic := range []string{"a", "b"} {
+		if _, err := b.Subscribe(Subscription{Topic: topic, Group: "g", Handler: noop}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range readers {
+		if !r.isClosed() {
+			t.Fatalf("订阅[%s]的Reader未关闭", r.cfg.Topic)
+		}
+	}
+	if _, err := b.Subscribe(Subscription{Topic: "c", Group: "g", Handler: noop}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("关闭后订阅应返回ErrClosed，实际%v", err)
+	}
+}
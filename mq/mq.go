@@ -0,0 +1,155 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 消息中间件：Kafka与RabbitMQ的统一发布/消费接口，连接按数据库配置（mq节点）在启动时初始化：
+//
+//	broker, err := mq.GetMQ("default")
+//	err = broker.Publish(ctx, &mq.Message{Topic: "order.created", Key: []byte(orderID), Value: body})
+//
+// 消费者通过bootstrap.BootConfig.Consumers注册处理函数，停机时停止拉取、等待处理中的消息完成后确认/提交位点：
+//
+//	Consumers: func(c *mq.Consumer) error {
+//		return c.Handle("default", "order.created", statService.OnOrderCreated, mq.WithGroup("stat"), mq.WithRetry(3, time.Second))
+//	},
+//
+// 两种驱动的语义（Kafka基于segmentio/kafka-go，RabbitMQ基于rabbitmq/amqp091-go）：
+//   - Kafka：Topic为主题，Key相同的消息写入同一分区；同一消费组内各实例按分区分摊（range分配），分区内按顺序处理，
+//     处理完成（含重试与死信）后位点定期提交，停机与重平衡前提交已处理的位点
+//   - RabbitMQ：配置exchange时Topic为路由键，每个消费组声明独立的持久化队列（{组名}.{Topic}）绑定到交换机，
+//     同一消费组内各实例竞争消费；未配置exchange时Topic即队列名。处理成功后ack，失败时nack且不重新入队
+//     （队列配置了死信交换机时由RabbitMQ转入死信），发布时等待broker的发布确认
+
+// ErrClosed 连接已关闭
+var ErrClosed = errors.New("mq: 连接已关闭")
+
+// Message 消息
+type Message struct {
+	Topic   string            // Kafka主题 / RabbitMQ路由键（不含配置的前缀）
+	Key     []byte            // Kafka分区键（相同键写入同一分区，保证顺序），RabbitMQ忽略
+	Value   []byte            // 消息体
+	Headers map[string]string // 消息头
+	Time    time.Time         // 消息时间（发布时为零值则取当前时间）
+
+	// 以下字段在消费时填充
+	ID          string // 消息ID（Kafka为 主题/分区/位点，RabbitMQ为message-id或投递标签）
+	Partition   int32  // Kafka分区
+	Offset      int64  // Kafka位点
+	Redelivered bool   // RabbitMQ重新投递标记
+	Attempt     int    // 本进程内第几次处理（从1开始）
+}
+
+// Handler 消息处理函数（返回错误时按订阅的重试配置重试）
+type Handler func(ctx context.Context, msg *Message) error
+
+// Broker 消息中间件驱动
+type Broker interface {
+	Publish(ctx context.Context, msgs ...*Message) error // 发布消息（Kafka等待acks，RabbitMQ等待发布确认）
+	Subscribe(sub Subscription) (Subscriber, error)      // 开始消费（断线自动重连，Kafka自动重平衡）
+	Close() error                                        // 关闭连接（运行中的订阅随之停止）
+}
+
+// Subscription 订阅参数
+type Subscription struct {
+	Topic       string  // 主题/路由键（不含前缀）
+	Group       string  // 消费组
+	Concurrency int     // 同时处理的消息数（Kafka为同时处理的分区数，分区内按顺序处理）
+	Handler     Handler // 返回nil时确认；返回错误时RabbitMQ拒绝且不重新入队，Kafka仍提交位点
+}
+
+// Subscriber 运行中的订阅
+type Subscriber interface {
+	Stop(ctx context.Context) error // 停止拉取，等待处理中的消息完成并确认/提交；ctx到期时返回ctx错误
+}
+
+// DriverFunc 按配置创建驱动实例（name为配置中的连接标识）
+type DriverFunc func(name string, cfg config.MQConfig) (Broker, error)
+
+var (
+	driverMu sync.RWMutex
+	drivers  = map[string]DriverFunc{
+		"kafka":    newKafkaBroker,
+		"rabbitmq": newRabbitBroker,
+	}
+	pool sync.Map // 连接标识 -> Broker
+)
+
+// RegisterDriver 注册自定义驱动（同名时覆盖内置驱动）
+func RegisterDriver(name string, fn DriverFunc) {
+	driverMu.Lock()
+	defer driverMu.Unlock()
+	drivers[strings.ToLower(name)] = fn
+}
+
+// InitMQ 按数据库配置初始化全部消息中间件连接
+func InitMQ() error {
+	cfgMap := config.GetMQConfig()
+	names := make([]string, 0, len(cfgMap))
+	for name := range cfgMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		broker, err := Open(name, cfgMap[name])
+		if err != nil {
+			return fmt.Errorf("消息中间件[%s]初始化失败：%w", name, err)
+		}
+		if old, loaded := pool.Swap(name, broker); loaded {
+			_ = old.(Broker).Close()
+		}
+	}
+	return nil
+}
+
+// Open 按配置创建驱动实例（不加入连接池）
+func Open(name string, cfg config.MQConfig) (Broker, error) {
+	driverMu.RLock()
+	fn, ok := drivers[strings.ToLower(cfg.Driver)]
+	driverMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("mq: 未知驱动[%s]", cfg.Driver)
+	}
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("mq: 未配置服务地址")
+	}
+	return fn(name, cfg)
+}
+
+// GetMQ 按连接标识获取驱动实例
+func GetMQ(name string) (Broker, error) {
+	val, ok := pool.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("消息中间件[%s]未初始化", name)
+	}
+	return val.(Broker), nil
+}
+
+// CloseMQ 关闭全部连接
+func CloseMQ() error {
+	var errList []error
+	pool.Range(func(key, val interface{}) bool {
+		pool.Delete(key)
+		if err := val.(Broker).Close(); err != nil {
+			errList = append(errList, fmt.Errorf("%v: %w", key, err))
+		}
+		return true
+	})
+	return errors.Join(errList...)
+}
+
+// timeoutOf 配置中的超时（默认10秒）
+func timeoutOf(cfg config.MQConfig) time.Duration {
+	if cfg.Timeout > 0 {
+		return time.Duration(cfg.Timeout) * time.Second
+	}
+	return 10 * time.Second
+}
@@ -0,0 +1,485 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"math"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rabbitBroker RabbitMQ驱动（基于amqp091-go）：发布使用一条共享连接上的确认模式通道池，每个订阅使用独立连接
+type rabbitBroker struct {
+	name    string
+	cfg     config.MQConfig
+	dial    amqp.Config
+	timeout time.Duration
+	slots   chan struct{} // 发布通道数上限
+
+	mu       sync.Mutex
+	conn     *amqp.Connection
+	idle     []*rabbitChan
+	declared map[string]bool // 当前连接上已声明的队列（默认交换机发布时）
+	subs     map[*rabbitSub]struct{}
+	closed   bool
+}
+
+func newRabbitBroker(name string, cfg config.MQConfig) (Broker, error) {
+	if cfg.Vhost == "" {
+		cfg.Vhost = "/"
+	}
+	if cfg.ExchangeType == "" {
+		cfg.ExchangeType = "topic"
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "go-dai"
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = runtime.NumCPU()
+	}
+	heartbeat := 10 * time.Second
+	if cfg.Heartbeat > 0 {
+		heartbeat = time.Duration(cfg.Heartbeat) * time.Second
+	}
+	user, pwd := cfg.User, cfg.Pwd
+	if user == "" {
+		user, pwd = "guest", "guest"
+	}
+	b := &rabbitBroker{
+		name: name,
+		cfg:  cfg,
+		dial: amqp.Config{
+			SASL:       []amqp.Authentication{&amqp.PlainAuth{Username: user, Password: pwd}},
+			Vhost:      cfg.Vhost,
+			Heartbeat:  heartbeat,
+			Properties: amqp.NewConnectionProperties(),
+			Dial:       amqp.DefaultDial(timeoutOf(cfg)),
+		},
+		timeout:  timeoutOf(cfg),
+		slots:    make(chan struct{}, cfg.PoolSize),
+		declared: make(map[string]bool),
+		subs:     make(map[*rabbitSub]struct{}),
+	}
+	b.dial.Properties.SetClientConnectionName(cfg.ClientID)
+	// 启动时检查服务可用并声明交换机
+	b.mu.Lock()
+	_, err := b.connectLocked()
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// dialAny 依次尝试配置的地址建立连接，配置了交换机时声明交换机
+func (b *rabbitBroker) dialAny() (*amqp.Connection, error) {
+	if len(b.cfg.Addrs) == 0 {
+		return nil, errors.New("rabbitmq: 未配置服务地址")
+	}
+	scheme := "amqp"
+	if b.cfg.TLS {
+		scheme = "amqps"
+	}
+	var errList []error
+	for _, addr := range b.cfg.Addrs {
+		conn, err := amqp.DialConfig((&url.URL{Scheme: scheme, Host: addr, Path: "/"}).String(), b.dial)
+		if err != nil {
+			switch {
+			case errors.Is(err, amqp.ErrCredentials):
+				err = fmt.Errorf("rabbitmq: %s认证失败：%w", addr, err)
+			case errors.Is(err, amqp.ErrVhost):
+				err = fmt.Errorf("rabbitmq: %s无权访问虚拟主机[%s]：%w", addr, b.cfg.Vhost, err)
+			}
+			errList = append(errList, err)
+			continue
+		}
+		if b.cfg.Exchange != "" {
+			ch, err := conn.Channel()
+			if err == nil {
+				err = ch.ExchangeDeclare(b.cfg.Exchange, b.cfg.ExchangeType, true, false, false, false, nil)
+				_ = ch.Close()
+			}
+			if err != nil {
+				_ = conn.Close()
+				return nil, fmt.Errorf("rabbitmq: 声明交换机[%s]失败：%w", b.cfg.Exchange, err)
+			}
+		}
+		return conn, nil
+	}
+	return nil, errors.Join(errList...)
+}
+
+// rabbitChan 确认模式的发布通道
+type rabbitChan struct {
+	ch   *amqp.Channel
+	conn *amqp.Connection // 所属连接（重连后旧连接上的通道不再归还）
+}
+
+// connectLocked 返回发布连接，断开时重新连接（调用方持有b.mu）
+func (b *rabbitBroker) connectLocked() (*amqp.Connection, error) {
+	if b.closed {
+		return nil, ErrClosed
+	}
+	if b.conn != nil && !b.conn.IsClosed() {
+		return b.conn, nil
+	}
+	conn, err := b.dialAny()
+	if err != nil {
+		return nil, err
+	}
+	b.conn = conn
+	b.idle = nil
+	b.declared = make(map[string]bool)
+	return conn, nil
+}
+
+// channel 取一个确认模式的发布通道（超过池大小时等待归还）
+func (b *rabbitBroker) channel(ctx context.Context) (*rabbitChan, error) {
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.idle) > 0 {
+		pc := b.idle[len(b.idle)-1]
+		b.idle = b.idle[:len(b.idle)-1]
+		if !pc.ch.IsClosed() {
+			return pc, nil
+		}
+	}
+	conn, err := b.connectLocked()
+	if err == nil {
+		var ch *amqp.Channel
+		if ch, err = conn.Channel(); err == nil {
+			if err = ch.Confirm(false); err == nil {
+				return &rabbitChan{ch: ch, conn: conn}, nil
+			}
+			_ = ch.Close()
+		}
+	}
+	<-b.slots
+	return nil, err
+}
+
+// release 归还发布通道（出错的通道直接关闭）
+func (b *rabbitBroker) release(pc *rabbitChan, broken bool) {
+	if broken {
+		_ = pc.ch.Close()
+	} else {
+		b.mu.Lock()
+		if !b.closed && pc.conn == b.conn && !pc.ch.IsClosed() {
+			b.idle = append(b.idle, pc)
+		} else {
+			_ = pc.ch.Close()
+		}
+		b.mu.Unlock()
+	}
+	<-b.slots
+}
+
+// Publish 发布持久化消息并等待发布确认（连接断开时重连后重发一次，可能产生重复消息）
+func (b *rabbitBroker) Publish(ctx context.Context, msgs ...*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+	}
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var retry bool
+		if retry, err = b.publish(ctx, msgs); err == nil || !retry || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// publish 在一个通道上发布一批消息，返回是否可以重试（连接断开）
+func (b *rabbitBroker) publish(ctx context.Context, msgs []*Message) (bool, error) {
+	pc, err := b.channel(ctx)
+	if err != nil {
+		return false, err
+	}
+	broken := true
+	defer func() {
+		b.release(pc, broken)
+	}()
+	confirms := make([]*amqp.DeferredConfirmation, 0, len(msgs))
+	for _, msg := range msgs {
+		key := b.cfg.Pre + msg.Topic
+		if b.cfg.Exchange == "" {
+			// 默认交换机按队列名路由，队列不存在时消息会被丢弃，发布前先声明
+			if err := b.declareQueue(pc, key); err != nil {
+				return pc.ch.IsClosed(), err
+			}
+		}
+		pub := amqp.Publishing{DeliveryMode: amqp.Persistent, MessageId: uuid.NewString(), Timestamp: msg.Time, Body: msg.Value}
+		if pub.Timestamp.IsZero() {
+			pub.Timestamp = time.Now()
+		}
+		if len(msg.Headers) > 0 {
+			pub.Headers = make(amqp.Table, len(msg.Headers))
+			for k, v := range msg.Headers {
+				pub.Headers[k] = v
+			}
+		}
+		confirm, err := pc.ch.PublishWithDeferredConfirmWithContext(ctx, b.cfg.Exchange, key, false, false, pub)
+		if err != nil {
+			return true, err
+		}
+		confirms = append(confirms, confirm)
+	}
+	for _, confirm := range confirms {
+		ok, err := confirm.WaitContext(ctx)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			if pc.ch.IsClosed() {
+				return true, errors.New("rabbitmq: 等待发布确认时通道关闭")
+			}
+			broken = false
+			return false, errors.New("rabbitmq: 消息被broker拒绝（nack）")
+		}
+	}
+	broken = false
+	return false, nil
+}
+
+// declareQueue 在当前连接上声明一次队列
+func (b *rabbitBroker) declareQueue(pc *rabbitChan, queue string) error {
+	b.mu.Lock()
+	done := b.declared[queue] && pc.conn == b.conn
+	b.mu.Unlock()
+	if done {
+		return nil
+	}
+	if _, err := pc.ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("rabbitmq: 声明队列[%s]失败：%w", queue, err)
+	}
+	b.mu.Lock()
+	if pc.conn == b.conn {
+		b.declared[queue] = true
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// Subscribe 声明队列并开始消费（配置exchange时队列为{前缀}{组名}.{Topic}）
+func (b *rabbitBroker) Subscribe(sub Subscription) (Subscriber, error) {
+	if sub.Concurrency <= 0 {
+		sub.Concurrency = 1
+	}
+	queue := b.cfg.Pre + sub.Topic
+	if b.cfg.Exchange != "" {
+		if sub.Group == "" {
+			return nil, errors.New("rabbitmq: 消费组不能为空")
+		}
+		queue = b.cfg.Pre + sub.Group + "." + sub.Topic
+	}
+	s := &rabbitSub{b: b, sub: sub, queue: queue, done: make(chan struct{})}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrClosed
+	}
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	go s.run()
+	return s, nil
+}
+
+func (b *rabbitBroker) removeSub(s *rabbitSub) {
+	b.mu.Lock()
+	delete(b.subs, s)
+	b.mu.Unlock()
+}
+
+// Close 停止全部订阅（等待处理中的消息完成）并关闭发布连接
+func (b *rabbitBroker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	subs := make([]*rabbitSub, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+	var errList []error
+	for _, s := range subs {
+		if err := s.Stop(context.Background()); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	b.mu.Lock()
+	b.closed = true
+	conn := b.conn
+	b.conn, b.idle = nil, nil
+	b.mu.Unlock()
+	if conn != nil {
+		_ = conn.Close()
+	}
+	return errors.Join(errList...)
+}
+
+// rabbitSub 运行中的订阅
+type rabbitSub struct {
+	b      *rabbitBroker
+	sub    Subscription
+	queue  string
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop 取消消费，等待处理中的消息完成并确认
+func (s *rabbitSub) Stop(ctx context.Context) error {
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *rabbitSub) logPrefix() string {
+	return "RabbitMQ队列[" + s.queue + "]"
+}
+
+// run 消费直到Stop，连接断开时退避后重连
+func (s *rabbitSub) run() {
+	defer close(s.done)
+	defer s.b.removeSub(s)
+	backoff := time.Second
+	for s.ctx.Err() == nil {
+		consumed, err := s.session()
+		if consumed {
+			backoff = time.Second
+		}
+		if err == nil || s.ctx.Err() != nil {
+			continue
+		}
+		logger.Warn(s.logPrefix(), "：", err, "，", backoff, "后重连")
+		select {
+		case <-s.ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// session 一次连接上的消费：声明并绑定队列后消费，直到停止或连接断开
+func (s *rabbitSub) session() (bool, error) {
+	conn, err := s.b.dialAny()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	ch, err := conn.Channel()
+	if err != nil {
+		return false, err
+	}
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	if _, err := ch.QueueDeclare(s.queue, true, false, false, false, nil); err != nil {
+		return false, fmt.Errorf("声明队列失败：%w", err)
+	}
+	if s.b.cfg.Exchange != "" {
+		if err := ch.QueueBind(s.queue, s.b.cfg.Pre+s.sub.Topic, s.b.cfg.Exchange, false, nil); err != nil {
+			return false, fmt.Errorf("绑定交换机失败：%w", err)
+		}
+	}
+	// 预取数即同时处理的消息数，broker不会推送超过该数量的未确认消息
+	if err := ch.Qos(min(s.sub.Concurrency, math.MaxUint16), 0, false); err != nil {
+		return false, err
+	}
+	tag := s.b.cfg.ClientID + "-" + uuid.NewString()
+	deliveries, err := ch.Consume(s.queue, tag, false, false, false, false, nil)
+	if err != nil {
+		return false, err
+	}
+	logger.Info(s.logPrefix(), "开始消费，并发数：", s.sub.Concurrency)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				if !ch.IsClosed() {
+					return true, errors.New("rabbitmq: 消费被服务端取消")
+				}
+				if amqpErr := <-closed; amqpErr != nil {
+					return true, amqpErr
+				}
+				return true, amqp.ErrClosed
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.handle(d)
+			}()
+		case <-s.ctx.Done():
+			if err := ch.Cancel(tag, false); err != nil {
+				logger.Warn(s.logPrefix(), "取消消费失败：", err)
+				return true, nil
+			}
+			// 已推送但未开始处理的消息退回队列（取消后投递通道在缓冲的消息取完后关闭）
+			for d := range deliveries {
+				_ = d.Nack(false, true)
+			}
+			return true, nil
+		}
+	}
+}
+
+// handle 处理一条投递：成功ack，失败nack且不重新入队
+func (s *rabbitSub) handle(d amqp.Delivery) {
+	msg := &Message{
+		Topic:       strings.TrimPrefix(d.RoutingKey, s.b.cfg.Pre),
+		Value:       d.Body,
+		Time:        d.Timestamp,
+		ID:          d.MessageId,
+		Redelivered: d.Redelivered,
+	}
+	if msg.ID == "" {
+		msg.ID = s.queue + "/" + strconv.FormatUint(d.DeliveryTag, 10)
+	}
+	if len(d.Headers) > 0 {
+		msg.Headers = make(map[string]string, len(d.Headers))
+		for k, v := range d.Headers {
+			if b, ok := v.([]byte); ok {
+				msg.Headers[k] = string(b)
+			} else {
+				msg.Headers[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	var err error
+	if s.sub.Handler(s.ctx, msg) == nil {
+		err = d.Ack(false)
+	} else {
+		err = d.Nack(false, false)
+	}
+	if err != nil {
+		// 连接断开时未确认的消息由broker重新投递
+		logger.Warn(s.logPrefix(), "确认消息", msg.ID, "失败：", err)
+	}
+}
@@ -0,0 +1,660 @@
+package mq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dfpopp/go-dai/config"
+)
+
+// connectionStartFixture RabbitMQ发送的Connection.Start：版本0-9，server-properties含嵌套的capabilities表，
+// 认证机制"PLAIN AMQPLAIN"，语言"en_US"
+const connectionStartFixture = "0100000000008d000a000a0009000000680770726f6475637453000000085261626269744d510776657273696f6e5300000006332e31332e30" +
+	"0c6361706162696c6974696573460000002e127075626c69736865725f636f6e6669726d73740116636f6e73756d65725f63616e63656c5f6e6f74696679740100" +
+	"00000e504c41494e20414d51504c41494e00000005656e5f5553ce"
+
+// fakeRabbit 单节点RabbitMQ模拟：握手（发送录制的Connection.Start样本）、通道与确认模式、交换机/队列声明与绑定、
+// 发布确认和消费投递，用于验证驱动适配层在amqp091-go客户端上的行为
+type fakeRabbit struct {
+	t   *testing.T
+	lis net.Listener
+
+	mu          sync.Mutex
+	user, pwd   string
+	vhost       string
+	frameMax    uint32
+	tune        [3]uint32 // 客户端Tune-Ok：channel_max、frame_max、heartbeat
+	methods     []string  // 收到的通道方法（按顺序，如"queue.declare"）
+	exchanges   map[string]string
+	queues      map[string]bool
+	bindings    map[string][]string // 路由键 -> 队列
+	prefetch    int
+	consumers   map[string]*fakeConsumer // 队列 -> 消费者
+	ready       map[string][]*fakeMessage
+	bodyFrames  int // 客户端发布的消息体帧数
+	nackNext    bool
+	deliveryTag uint64
+	delivered   map[uint64]*fakeMessage
+	acked       []uint64
+	nacked      map[uint64]bool // 投递标签 -> requeue
+}
+
+type fakeMessage struct {
+	exchange, key string
+	header        []byte // 内容头原样转发给消费者
+	body          []byte
+}
+
+type fakeConsumer struct {
+	conn    *fakeRabbitConn
+	channel uint16
+	tag     string
+}
+
+// fakeRabbitConn 服务端连接：投递可能来自其他连接的发布，写入加锁
+type fakeRabbitConn struct {
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+func (c *fakeRabbitConn) send(frames ...[]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, frame := range frames {
+		_, _ = c.conn.Write(frame)
+	}
+}
+
+func (c *fakeRabbitConn) method(channel, class, method uint16, args []byte) {
+	c.send(amqpFrame(amqpFrameMethod, channel, methodPayload(class, method, args)))
+}
+
+func newFakeRabbit(t *testing.T) *fakeRabbit {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRabbit{
+		t: t, lis: lis, user: "app", pwd: "secret", vhost: "/test", frameMax: 64,
+		exchanges: make(map[string]string), queues: make(map[string]bool), bindings: make(map[string][]string),
+		consumers: make(map[string]*fakeConsumer), ready: make(map[string][]*fakeMessage),
+		delivered: make(map[uint64]*fakeMessage), nacked: make(map[uint64]bool),
+	}
+	go f.serve()
+	t.Cleanup(func() { _ = lis.Close() })
+	return f
+}
+
+func (f *fakeRabbit) serve() {
+	for {
+		conn, err := f.lis.Accept()
+		if err != nil {
+			return
+		}
+		go f.handleConn(conn)
+	}
+}
+
+func (f *fakeRabbit) count(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, m := range f.methods {
+		if m == method {
+			n++
+		}
+	}
+	return n
+}
+
+// nextMethod 读取下一个方法帧（跳过心跳）
+func nextMethod(r *bufio.Reader) (uint16, amqpMethod, error) {
+	for {
+		typ, channel, payload, err := readAMQPFrame(r)
+		if err != nil {
+			return 0, amqpMethod{}, err
+		}
+		if typ == amqpFrameMethod {
+			return channel, parseMethod(payload), nil
+		}
+	}
+}
+
+func (f *fakeRabbit) handleConn(nc net.Conn) {
+	defer nc.Close()
+	c := &fakeRabbitConn{conn: nc}
+	r := bufio.NewReader(nc)
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(r, head); err != nil || string(head) != "AMQP\x00\x00\x09\x01" {
+		f.t.Errorf("协议头%q", head)
+		return
+	}
+	start, _ := hex.DecodeString(connectionStartFixture)
+	c.send(start)
+
+	_, m, err := nextMethod(r)
+	if err != nil || !m.is(amqpConnection, 11) {
+		f.t.Errorf("期望Start-Ok，实际%d.%d %v", m.class, m.method, err)
+		return
+	}
+	args := &amqpReader{buf: m.args}
+	args.skipTable() // client-properties
+	mechanism, response := args.shortstr(), string(args.longstr())
+	f.mu.Lock()
+	credentials, frameMax, vhost := "\x00"+f.user+"\x00"+f.pwd, f.frameMax, f.vhost
+	f.mu.Unlock()
+	if mechanism != "PLAIN" || response != credentials {
+		return // 认证失败时RabbitMQ直接关闭连接
+	}
+	var w amqpWriter
+	w.short(2047)
+	w.long(frameMax)
+	w.short(0)
+	c.method(0, amqpConnection, 30, w.buf)
+	if _, m, err = nextMethod(r); err != nil || !m.is(amqpConnection, 31) {
+		f.t.Errorf("期望Tune-Ok，实际%d.%d %v", m.class, m.method, err)
+		return
+	}
+	args = &amqpReader{buf: m.args}
+	f.mu.Lock()
+	f.tune = [3]uint32{uint32(args.short()), args.long(), uint32(args.short())}
+	f.mu.Unlock()
+	if _, m, err = nextMethod(r); err != nil || !m.is(amqpConnection, 40) {
+		f.t.Errorf("期望Connection.Open，实际%d.%d %v", m.class, m.method, err)
+		return
+	}
+	if got := (&amqpReader{buf: m.args}).shortstr(); got != vhost {
+		w = amqpWriter{}
+		w.short(530)
+		w.shortstr("NOT_ALLOWED - vhost " + got + " not found")
+		w.short(10)
+		w.short(40)
+		c.method(0, amqpConnection, 50, w.buf)
+		return
+	}
+	c.method(0, amqpConnection, 41, []byte{0})
+	f.serveChannels(c, r)
+}
+
+// publishing 通道上正在接收的消息
+type publishing struct {
+	msg  *fakeMessage
+	size uint64
+}
+
+func (f *fakeRabbit) serveChannels(c *fakeRabbitConn, r *bufio.Reader) {
+	confirming := make(map[uint16]uint64) // 确认模式的通道 -> 已发布序号
+	pending := make(map[uint16]*publishing)
+	defer f.dropConsumers(c)
+	for {
+		typ, channel, payload, err := readAMQPFrame(r)
+		if err != nil {
+			return
+		}
+		switch typ {
+		case amqpFrameHeader:
+			p := pending[channel]
+			if p == nil {
+				f.t.Errorf("通道%d收到意外的内容头", channel)
+				return
+			}
+			p.msg.header = payload
+			p.size = (&amqpReader{buf: payload[4:]}).longlong()
+			if p.size == 0 {
+				delete(pending, channel)
+				f.published(c, channel, p.msg, confirming)
+			}
+			continue
+		case amqpFrameBody:
+			p := pending[channel]
+			if p == nil {
+				f.t.Errorf("通道%d收到意外的消息体", channel)
+				return
+			}
+			f.mu.Lock()
+			f.bodyFrames++
+			f.mu.Unlock()
+			if len(payload) > int(f.frameMax)-8 {
+				f.t.Errorf("消息体帧%d字节，超出frameMax", len(payload))
+			}
+			p.msg.body = append(p.msg.body, payload...)
+			if uint64(len(p.msg.body)) >= p.size {
+				delete(pending, channel)
+				f.published(c, channel, p.msg, confirming)
+			}
+			continue
+		case amqpFrameMethod:
+		default:
+			continue
+		}
+		m := parseMethod(payload)
+		args := &amqpReader{buf: m.args}
+		if channel == 0 {
+			if m.is(amqpConnection, 50) {
+				c.method(0, amqpConnection, 51, nil)
+				return
+			}
+			continue
+		}
+		switch {
+		case m.is(amqpChannel, 10):
+			c.method(channel, amqpChannel, 11, []byte{0, 0, 0, 0})
+		case m.is(amqpChannel, 40):
+			c.method(channel, amqpChannel, 41, nil)
+		case m.is(amqpConfirm, 10):
+			f.record("confirm.select")
+			confirming[channel] = 0
+			c.method(channel, amqpConfirm, 11, nil)
+		case m.is(amqpExchange, 10):
+			args.short()
+			name, kind := args.shortstr(), args.shortstr()
+			f.mu.Lock()
+			f.methods = append(f.methods, "exchange.declare")
+			f.exchanges[name] = kind
+			f.mu.Unlock()
+			c.method(channel, amqpExchange, 11, nil)
+		case m.is(amqpQueue, 10):
+			args.short()
+			name := args.shortstr()
+			f.mu.Lock()
+			f.methods = append(f.methods, "queue.declare")
+			f.queues[name] = true
+			f.mu.Unlock()
+			var w amqpWriter
+			w.shortstr(name)
+			w.long(0)
+			w.long(0)
+			c.method(channel, amqpQueue, 11, w.buf)
+		case m.is(amqpQueue, 20):
+			args.short()
+			queue, exchange, key := args.shortstr(), args.shortstr(), args.shortstr()
+			f.mu.Lock()
+			f.methods = append(f.methods, "queue.bind")
+			if f.exchanges[exchange] == "" {
+				f.t.Errorf("绑定到未声明的交换机%s", exchange)
+			}
+			f.bindings[key] = append(f.bindings[key], queue)
+			f.mu.Unlock()
+			c.method(channel, amqpQueue, 21, nil)
+		case m.is(amqpBasic, 10):
+			args.long()
+			f.mu.Lock()
+			f.methods = append(f.methods, "basic.qos")
+			f.prefetch = int(args.short())
+			f.mu.Unlock()
+			c.method(channel, amqpBasic, 11, nil)
+		case m.is(amqpBasic, 20):
+			args.short()
+			queue, tag := args.shortstr(), args.shortstr()
+			var w amqpWriter
+			w.shortstr(tag)
+			c.method(channel, amqpBasic, 21, w.buf)
+			f.mu.Lock()
+			f.methods = append(f.methods, "basic.consume")
+			consumer := &fakeConsumer{conn: c, channel: channel, tag: tag}
+			f.consumers[queue] = consumer
+			ready := f.ready[queue]
+			delete(f.ready, queue)
+			for _, msg := range ready {
+				f.deliverLocked(consumer, msg)
+			}
+			f.mu.Unlock()
+		case m.is(amqpBasic, 30):
+			tag := args.shortstr()
+			f.mu.Lock()
+			f.methods = append(f.methods, "basic.cancel")
+			for queue, consumer := range f.consumers {
+				if consumer.tag == tag {
+					delete(f.consumers, queue)
+				}
+			}
+			f.mu.Unlock()
+			var w amqpWriter
+			w.shortstr(tag)
+			c.method(channel, amqpBasic, 31, w.buf)
+		case m.is(amqpBasic, 40):
+			args.short()
+			pending[channel] = &publishing{msg: &fakeMessage{exchange: args.shortstr(), key: args.shortstr()}}
+			f.record("basic.publish")
+		case m.is(amqpBasic, 80):
+			tag := args.longlong()
+			f.mu.Lock()
+			f.acked = append(f.acked, tag)
+			f.mu.Unlock()
+		case m.is(amqpBasic, 120):
+			tag, bits := args.longlong(), args.octet()
+			f.mu.Lock()
+			f.nacked[tag] = bits&0x02 != 0
+			f.mu.Unlock()
+		default:
+			f.t.Errorf("未处理的方法%d.%d", m.class, m.method)
+		}
+	}
+}
+
+func (f *fakeRabbit) record(method string) {
+	f.mu.Lock()
+	f.methods = append(f.methods, method)
+	f.mu.Unlock()
+}
+
+// published 消息接收完整：确认模式下发送ack/nack，并按交换机路由到队列
+func (f *fakeRabbit) published(c *fakeRabbitConn, channel uint16, msg *fakeMessage, confirming map[uint16]uint64) {
+	f.mu.Lock()
+	nack := f.nackNext
+	f.nackNext = false
+	queues := []string{msg.key} // 默认交换机按队列名路由
+	if msg.exchange != "" {
+		queues = f.bindings[msg.key]
+	}
+	if !nack {
+		for _, queue := range queues {
+			if !f.queues[queue] {
+				continue
+			}
+			if consumer := f.consumers[queue]; consumer != nil {
+				f.deliverLocked(consumer, msg)
+			} else {
+				f.ready[queue] = append(f.ready[queue], msg)
+			}
+		}
+	}
+	f.mu.Unlock()
+	seq, ok := confirming[channel]
+	if !ok {
+		return
+	}
+	seq++
+	confirming[channel] = seq
+	var w amqpWriter
+	w.longlong(seq)
+	w.octet(0)
+	if nack {
+		c.method(channel, amqpBasic, 120, w.buf)
+	} else {
+		c.method(channel, amqpBasic, 80, w.buf)
+	}
+}
+
+// deliverLocked 投递消息，消息体按8字节拆分以覆盖客户端的多帧组装（调用方持有f.mu）
+func (f *fakeRabbit) deliverLocked(consumer *fakeConsumer, msg *fakeMessage) {
+	f.deliveryTag++
+	f.delivered[f.deliveryTag] = msg
+	var w amqpWriter
+	w.shortstr(consumer.tag)
+	w.longlong(f.deliveryTag)
+	w.octet(0)
+	w.shortstr(msg.exchange)
+	w.shortstr(msg.key)
+	frames := [][]byte{
+		amqpFrame(amqpFrameMethod, consumer.channel, methodPayload(amqpBasic, 60, w.buf)),
+		amqpFrame(amqpFrameHeader, consumer.channel, msg.header),
+	}
+	for body := msg.body; len(body) > 0; {
+		n := min(len(body), 8)
+		frames = append(frames, amqpFrame(amqpFrameBody, consumer.channel, body[:n]))
+		body = body[n:]
+	}
+	go consumer.conn.send(frames...)
+}
+
+func (f *fakeRabbit) dropConsumers(c *fakeRabbitConn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for queue, consumer := range f.consumers {
+		if consumer.conn == c {
+			delete(f.consumers, queue)
+		}
+	}
+}
+
+func rabbitTestConfig(f *fakeRabbit) config.MQConfig {
+	return config.MQConfig{Driver: "rabbitmq", Addrs: []string{f.lis.Addr().String()}, User: "app", Pwd: "secret", Vhost: "/test", Timeout: 5, PoolSize: 1}
+}
+
+func TestRabbitPublishAndConsume(t *testing.T) {
+	f := newFakeRabbit(t)
+	cfg := rabbitTestConfig(f)
+	cfg.Exchange = "events"
+	cfg.Pre = "app."
+	broker, err := Open("test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+	f.mu.Lock()
+	if f.exchanges["events"] != "topic" {
+		t.Errorf("交换机声明：%v", f.exchanges)
+	}
+	// frame_max取服务端值，服务端不要求心跳时使用默认10秒
+	if f.tune != [3]uint32{2047, 64, 10} {
+		t.Errorf("Tune-Ok参数%v", f.tune)
+	}
+	f.mu.Unlock()
+
+	var mu sync.Mutex
+	var got []*Message
+	sub, err := broker.Subscribe(Subscription{Topic: "order.created", Group: "stat", Concurrency: 2, Handler: func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		got = append(got, msg)
+		mu.Unlock()
+		if msg.Headers["fail"] == "1" {
+			return errors.New("处理失败")
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const queue = "app.stat.order.created"
+	waitFor(t, "开始消费", func() bool { return f.count("basic.consume") == 1 })
+	f.mu.Lock()
+	if !f.queues[queue] || len(f.bindings["app.order.created"]) != 1 || f.bindings["app.order.created"][0] != queue || f.prefetch != 2 {
+		t.Errorf("队列%v 绑定%v 预取数%d", f.queues, f.bindings, f.prefetch)
+	}
+	f.mu.Unlock()
+
+	ts := time.Unix(1700000000, 0)
+	large := bytes.Repeat([]byte("0123456789"), 15)
+	if err := broker.Publish(context.Background(),
+		&Message{Topic: "order.created", Value: large, Headers: map[string]string{"trace": "abc"}, Time: ts},
+		&Message{Topic: "order.created", Value: []byte("bad"), Headers: map[string]string{"fail": "1"}},
+	); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "确认全部投递", func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return len(f.acked)+len(f.nacked) == 2
+	})
+
+	f.mu.Lock()
+	// 150字节的消息体按frame_max 64拆为3帧
+	if f.bodyFrames != 4 {
+		t.Errorf("发布消息体帧数%d，期望4", f.bodyFrames)
+	}
+	if len(f.acked) != 1 || !bytes.Equal(f.delivered[f.acked[0]].body, large) {
+		t.Errorf("ack标签%v", f.acked)
+	}
+	for tag, requeue := range f.nacked {
+		if string(f.delivered[tag].body) != "bad" || requeue {
+			t.Errorf("处理失败的消息应nack且不重新入队：标签%d requeue=%v", tag, requeue)
+		}
+	}
+	f.mu.Unlock()
+
+	mu.Lock()
+	for _, msg := range got {
+		if msg.Topic != "order.created" || msg.ID == "" {
+			t.Errorf("消息Topic=%q ID=%q", msg.Topic, msg.ID)
+		}
+		if bytes.Equal(msg.Value, large) && (msg.Headers["trace"] != "abc" || !msg.Time.Equal(ts)) {
+			t.Errorf("消息属性：headers=%v time=%v", msg.Headers, msg.Time)
+		}
+	}
+	if len(got) != 2 {
+		t.Errorf("处理了%d条消息", len(got))
+	}
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sub.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.count("basic.cancel"); n != 1 {
+		t.Fatalf("停止订阅发送basic.cancel %d次", n)
+	}
+}
+
+// 默认交换机发布前声明队列（同一连接只声明一次），nack时返回错误且通道可继续使用
+func TestRabbitPublishNack(t *testing.T) {
+	f := newFakeRabbit(t)
+	broker, err := Open("test", rabbitTestConfig(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+	f.mu.Lock()
+	f.nackNext = true
+	f.mu.Unlock()
+	if err := broker.Publish(context.Background(), &Message{Topic: "jobs", Value: []byte("a")}); err == nil || !strings.Contains(err.Error(), "nack") {
+		t.Fatalf("nack时应返回错误，实际%v", err)
+	}
+	if err := broker.Publish(context.Background(), &Message{Topic: "jobs", Value: []byte("b")}); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	want := []string{"confirm.select", "queue.declare", "basic.publish", "basic.publish"}
+	if strings.Join(f.methods, ",") != strings.Join(want, ",") {
+		t.Fatalf("通道方法顺序%v，期望%v", f.methods, want)
+	}
+	if len(f.ready["jobs"]) != 1 || string(f.ready["jobs"][0].body) != "b" {
+		t.Fatalf("队列中的消息：%d条", len(f.ready["jobs"]))
+	}
+}
+
+func TestRabbitHandshakeFailures(t *testing.T) {
+	f := newFakeRabbit(t)
+	cfg := rabbitTestConfig(f)
+	cfg.Pwd = "wrong"
+	if _, err := Open("test", cfg); err == nil || !strings.Contains(err.Error(), "认证失败") {
+		t.Fatalf("密码错误应返回认证失败，实际%v", err)
+	}
+	cfg = rabbitTestConfig(f)
+	cfg.Vhost = "/missing"
+	if _, err := Open("test", cfg); err == nil || !strings.Contains(err.Error(), "虚拟主机[/missing]") {
+		t.Fatalf("虚拟主机不存在应返回错误，实际%v", err)
+	}
+}
+
+// 以下为模拟服务端使用的AMQP 0-9-1帧编解码，帧结构：type(1) channel(2) size(4) payload 0xCE
+const (
+	amqpFrameMethod = 1
+	amqpFrameHeader = 2
+	amqpFrameBody   = 3
+	amqpFrameEnd    = 0xCE
+
+	amqpConnection = 10
+	amqpChannel    = 20
+	amqpExchange   = 40
+	amqpQueue      = 50
+	amqpBasic      = 60
+	amqpConfirm    = 85
+)
+
+type amqpMethod struct {
+	class  uint16
+	method uint16
+	args   []byte
+}
+
+func (m amqpMethod) is(class, method uint16) bool {
+	return m.class == class && m.method == method
+}
+
+func readAMQPFrame(r *bufio.Reader) (uint8, uint16, []byte, error) {
+	var head [7]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, 0, nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(head[3:])+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, err
+	}
+	if payload[len(payload)-1] != amqpFrameEnd {
+		return 0, 0, nil, errors.New("帧结束标记错误")
+	}
+	return head[0], binary.BigEndian.Uint16(head[1:]), payload[:len(payload)-1], nil
+}
+
+func parseMethod(payload []byte) amqpMethod {
+	if len(payload) < 4 {
+		return amqpMethod{}
+	}
+	return amqpMethod{class: binary.BigEndian.Uint16(payload), method: binary.BigEndian.Uint16(payload[2:]), args: payload[4:]}
+}
+
+func methodPayload(class, method uint16, args []byte) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, class)
+	payload = binary.BigEndian.AppendUint16(payload, method)
+	return append(payload, args...)
+}
+
+func amqpFrame(typ uint8, channel uint16, payload []byte) []byte {
+	frame := []byte{typ}
+	frame = binary.BigEndian.AppendUint16(frame, channel)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	return append(frame, amqpFrameEnd)
+}
+
+// amqpReader 按AMQP基本类型读取方法参数（越界时返回零值并记录错误）
+type amqpReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *amqpReader) take(n int) []byte {
+	if r.err != nil || n < 0 || r.off+n > len(r.buf) {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, max(n, 0))
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *amqpReader) octet() uint8     { return r.take(1)[0] }
+func (r *amqpReader) short() uint16    { return binary.BigEndian.Uint16(r.take(2)) }
+func (r *amqpReader) long() uint32     { return binary.BigEndian.Uint32(r.take(4)) }
+func (r *amqpReader) longlong() uint64 { return binary.BigEndian.Uint64(r.take(8)) }
+func (r *amqpReader) shortstr() string { return string(r.take(int(r.octet()))) }
+func (r *amqpReader) longstr() []byte  { return r.take(int(r.long())) }
+func (r *amqpReader) skipTable()       { r.take(int(r.long())) }
+
+type amqpWriter struct {
+	buf []byte
+}
+
+func (w *amqpWriter) octet(v uint8)     { w.buf = append(w.buf, v) }
+func (w *amqpWriter) short(v uint16)    { w.buf = binary.BigEndian.AppendUint16(w.buf, v) }
+func (w *amqpWriter) long(v uint32)     { w.buf = binary.BigEndian.AppendUint32(w.buf, v) }
+func (w *amqpWriter) longlong(v uint64) { w.buf = binary.BigEndian.AppendUint64(w.buf, v) }
+func (w *amqpWriter) shortstr(s string) {
+	w.octet(uint8(len(s)))
+	w.buf = append(w.buf, s...)
+}
@@ -0,0 +1,500 @@
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/google/uuid"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrClientClosed 客户端已断开
+	ErrClientClosed = errors.New("mqtt: 客户端已断开")
+	// errSlowClient 发送队列已满或未确认消息过多
+	errSlowClient = errors.New("mqtt: 客户端消费过慢")
+)
+
+// Client 已连接的MQTT客户端
+type Client struct {
+	ID          string    // 客户端标识（CONNECT中为空时自动生成）
+	Username    string    // CONNECT中的用户名
+	IP          string    // 客户端IP
+	ConnectedAt time.Time // 连接时间
+
+	server    *Server
+	conn      net.Conn
+	identity  *auth.ClientIdentity
+	claims    *auth.Claims
+	keepAlive time.Duration
+	will      *Message
+
+	sendCh   chan []byte
+	closing  chan struct{}
+	closed   atomic.Bool
+	reason   atomic.Pointer[string]
+	graceful atomic.Bool // 客户端发送了DISCONNECT（不发布遗嘱）
+	draining atomic.Bool // 服务器停机：处理完当前报文后停止读取
+
+	mu          sync.Mutex
+	subs        map[string]byte     // 订阅的过滤器 -> 授予的QoS
+	nextID      uint16              // 下一个下行报文标识
+	inflight    map[uint16]struct{} // 等待PUBACK的下行报文标识
+	awaitingRel map[uint16]struct{} // 等待PUBREL的上行QoS2报文标识
+	attrs       sync.Map
+}
+
+// Claims CONNECT时认证的用户声明（未认证时为nil）
+func (c *Client) Claims() *auth.Claims {
+	return c.claims
+}
+
+// Identity mTLS客户端证书身份（未启用时为nil）
+func (c *Client) Identity() *auth.ClientIdentity {
+	return c.identity
+}
+
+// SetAttr 设置客户端自定义属性
+func (c *Client) SetAttr(key string, value interface{}) {
+	c.attrs.Store(key, value)
+}
+
+// GetAttr 获取客户端自定义属性
+func (c *Client) GetAttr(key string) (interface{}, bool) {
+	return c.attrs.Load(key)
+}
+
+// Subscriptions 当前订阅的过滤器
+func (c *Client) Subscriptions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]string, 0, len(c.subs))
+	for filter := range c.subs {
+		list = append(list, filter)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Publish 直接向该客户端下发消息（不经过订阅匹配，QoS最高为1）
+func (c *Client) Publish(topic string, payload []byte, qos byte) error {
+	if err := validTopicName(topic); err != nil {
+		return err
+	}
+	return c.send(&Message{Topic: topic, Payload: payload}, min(qos, 1), false)
+}
+
+// Close 断开客户端（会发布遗嘱）
+func (c *Client) Close() {
+	c.close("closed by server")
+}
+
+// close 标记关闭并唤醒读写协程（写协程发送完队列中的报文后关闭连接）
+func (c *Client) close(reason string) {
+	if !c.closed.CompareAndSwap(false, true) {
+		return
+	}
+	c.reason.Store(&reason)
+	close(c.closing)
+	_ = c.conn.SetReadDeadline(time.Now())
+}
+
+// drain 停止读取新报文（当前报文处理完成并应答后断开）
+func (c *Client) drain() {
+	c.draining.Store(true)
+	_ = c.conn.SetReadDeadline(time.Now())
+}
+
+// send 发送PUBLISH报文（QoS1时分配报文标识并等待PUBACK）
+func (c *Client) send(msg *Message, qos byte, retain bool) error {
+	var id uint16
+	if qos > 0 {
+		qos = 1
+		c.mu.Lock()
+		if len(c.inflight) >= c.server.config.MaxInflight {
+			c.mu.Unlock()
+			c.close(errSlowClient.Error())
+			return errSlowClient
+		}
+		for {
+			c.nextID++
+			if c.nextID == 0 {
+				c.nextID = 1
+			}
+			if _, used := c.inflight[c.nextID]; !used {
+				break
+			}
+		}
+		id = c.nextID
+		c.inflight[id] = struct{}{}
+		c.mu.Unlock()
+	}
+	return c.write(encodePublish(msg, qos, id, retain))
+}
+
+// write 报文加入发送队列（队列已满时断开客户端）
+func (c *Client) write(frame []byte) error {
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+	select {
+	case c.sendCh <- frame:
+		return nil
+	case <-c.closing:
+		return ErrClientClosed
+	default:
+		c.close(errSlowClient.Error())
+		return errSlowClient
+	}
+}
+
+// writeLoop 写协程：依次写出发送队列中的报文，关闭时写完剩余报文后关闭连接
+func (c *Client) writeLoop(done chan<- struct{}) {
+	defer close(done)
+	defer c.conn.Close()
+	writeFrame := func(frame []byte) bool {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.server.config.WriteTimeout))
+		if _, err := c.conn.Write(frame); err != nil {
+			c.close(err.Error())
+			return false
+		}
+		return true
+	}
+	for {
+		select {
+		case frame := <-c.sendCh:
+			if !writeFrame(frame) {
+				return
+			}
+		case <-c.closing:
+			for {
+				select {
+				case frame := <-c.sendCh:
+					if !writeFrame(frame) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// serve 处理一个连接：读取CONNECT并认证，随后循环处理报文直到断开
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	count := s.connCount.Add(1)
+	defer s.connCount.Add(-1)
+
+	_ = conn.SetDeadline(time.Now().Add(s.config.ConnectTimeout))
+	var identity *auth.ClientIdentity
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			logger.Warn("MQTT TLS握手失败：", err, "客户端：", conn.RemoteAddr())
+			_ = conn.Close()
+			return
+		}
+		state := tlsConn.ConnectionState()
+		identity = auth.IdentityFromTLS(&state)
+	}
+	r := bufio.NewReader(conn)
+	p, err := readPacket(r, s.config.MaxMessageSize)
+	if err != nil || p.typ != packetConnect {
+		_ = conn.Close()
+		return
+	}
+	connack := func(code byte) {
+		_, _ = conn.Write(encodePacket(packetConnack, 0, []byte{0, code}))
+	}
+	cp, code, err := decodeConnect(p)
+	if err != nil {
+		if code != connAccepted {
+			connack(code)
+		}
+		logger.Warn("MQTT连接被拒绝：", err, "客户端：", conn.RemoteAddr())
+		_ = conn.Close()
+		return
+	}
+	if count > s.config.MaxConnections || s.closed.Load() {
+		connack(connRefusedServer)
+		_ = conn.Close()
+		return
+	}
+	if cp.clientID == "" {
+		if !cp.cleanSession {
+			connack(connRefusedIdentifier)
+			_ = conn.Close()
+			return
+		}
+		cp.clientID = "auto-" + uuid.NewString()
+	}
+	ip := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	c := &Client{
+		ID:          cp.clientID,
+		Username:    cp.username,
+		IP:          ip,
+		ConnectedAt: time.Now(),
+		server:      s,
+		conn:        conn,
+		identity:    identity,
+		keepAlive:   time.Duration(cp.keepAlive) * time.Second,
+		will:        cp.will,
+		sendCh:      make(chan []byte, s.config.SendQueueSize),
+		closing:     make(chan struct{}),
+		subs:        make(map[string]byte),
+		inflight:    make(map[uint16]struct{}),
+		awaitingRel: make(map[uint16]struct{}),
+	}
+	if s.authenticate != nil {
+		claims, err := s.authenticate(&ConnectInfo{ClientID: c.ID, Username: cp.username, Password: cp.password, IP: ip, Identity: identity})
+		if err != nil {
+			if cp.hasUsername || cp.hasPassword {
+				connack(connRefusedBadAuth)
+			} else {
+				connack(connRefusedNotAuth)
+			}
+			logger.Warn("MQTT认证失败：", err, "客户端标识：", c.ID, "IP：", ip)
+			_ = conn.Close()
+			return
+		}
+		c.claims = claims
+	}
+	if c.will != nil && (validTopicName(c.will.Topic) != nil || !s.allowed(c, c.will.Topic, AccessPublish)) {
+		connack(connRefusedNotAuth)
+		_ = conn.Close()
+		return
+	}
+	if !s.register(c) {
+		connack(connRefusedServer)
+		_ = conn.Close()
+		return
+	}
+	// 不保存会话，CONNACK的session present始终为0
+	connack(connAccepted)
+	_ = conn.SetDeadline(time.Time{})
+
+	writerDone := make(chan struct{})
+	go c.writeLoop(writerDone)
+	logger.Info("MQTT客户端上线，客户端标识：", c.ID, "IP：", ip, "在线数：", s.connCount.Load())
+	for _, fn := range s.onConnect {
+		fn(c)
+	}
+
+	s.readLoop(c, r)
+
+	if c.draining.Load() {
+		c.close("server shutdown")
+	} else {
+		c.close("connection closed")
+	}
+	<-writerDone
+	c.mu.Lock()
+	filters := make([]string, 0, len(c.subs))
+	for filter := range c.subs {
+		filters = append(filters, filter)
+	}
+	c.mu.Unlock()
+	for _, filter := range filters {
+		s.subs.remove(filter, c)
+	}
+	s.unregister(c)
+	reason := *c.reason.Load()
+	// 非正常断开（未发送DISCONNECT）时发布遗嘱，服务器停机时不发布
+	if c.will != nil && !c.graceful.Load() && !s.closed.Load() {
+		if c.will.Retain {
+			s.retain(c.will)
+		}
+		s.deliver(c.will)
+	}
+	logger.Info("MQTT客户端下线，客户端标识：", c.ID, "原因：", reason)
+	for _, fn := range s.onDisconnect {
+		fn(c, reason)
+	}
+}
+
+// readLoop 读取并处理报文（同一客户端的消息按顺序处理，处理器返回后才应答，保证QoS1/2的至少一次处理）
+func (s *Server) readLoop(c *Client, r *bufio.Reader) {
+	for {
+		if c.keepAlive > 0 {
+			// 1.5倍保活时间内未收到任何报文视为断线（MQTT 3.1.1 3.1.2.10）
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		}
+		// 设置读超时之后再检查，避免覆盖drain设置的超时
+		if c.closed.Load() || c.draining.Load() {
+			return
+		}
+		p, err := readPacket(r, s.config.MaxMessageSize)
+		if err != nil {
+			if !c.closed.Load() && !c.draining.Load() {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					c.close("keepalive timeout")
+				} else {
+					c.close(err.Error())
+				}
+			}
+			return
+		}
+		if err := s.handlePacket(c, p); err != nil {
+			if errors.Is(err, ErrClientClosed) {
+				return
+			}
+			logger.Warn("MQTT报文处理失败：", err, "客户端标识：", c.ID)
+			c.close(err.Error())
+			return
+		}
+	}
+}
+
+// handlePacket 按报文类型处理
+func (s *Server) handlePacket(c *Client, p packet) error {
+	switch p.typ {
+	case packetPublish:
+		msg, id, err := decodePublish(p)
+		if err != nil {
+			return err
+		}
+		return s.handlePublish(c, msg, id)
+	case packetPuback:
+		id, err := decodePacketID(p)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		delete(c.inflight, id)
+		c.mu.Unlock()
+	case packetPubrec:
+		// 下行消息最高QoS1，不应收到PUBREC；按协议回复PUBREL
+		id, err := decodePacketID(p)
+		if err != nil {
+			return err
+		}
+		return c.write(encodeAck(packetPubrel, id))
+	case packetPubrel:
+		id, err := decodePacketID(p)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		delete(c.awaitingRel, id)
+		c.mu.Unlock()
+		return c.write(encodeAck(packetPubcomp, id))
+	case packetPubcomp:
+	case packetSubscribe:
+		id, list, err := decodeSubscribe(p)
+		if err != nil {
+			return err
+		}
+		return s.handleSubscribe(c, id, list)
+	case packetUnsubscribe:
+		id, list, err := decodeUnsubscribe(p)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		for _, filter := range list {
+			delete(c.subs, filter)
+		}
+		c.mu.Unlock()
+		for _, filter := range list {
+			s.subs.remove(filter, c)
+		}
+		return c.write(encodeAck(packetUnsuback, id))
+	case packetPingreq:
+		return c.write(encodePacket(packetPingresp, 0, nil))
+	case packetDisconnect:
+		c.graceful.Store(true)
+		c.close("client disconnect")
+	default:
+		return errProtocol
+	}
+	return nil
+}
+
+// handlePublish 处理客户端发布：授权、保留、转发给订阅者、路由到处理器，最后按QoS应答
+func (s *Server) handlePublish(c *Client, msg *Message, id uint16) error {
+	if msg.QoS == 2 {
+		c.mu.Lock()
+		_, dup := c.awaitingRel[id]
+		c.mu.Unlock()
+		if dup {
+			// 已处理过的重发，只需再次应答
+			return c.write(encodeAck(packetPubrec, id))
+		}
+	}
+	if s.allowed(c, msg.Topic, AccessPublish) {
+		if msg.Retain {
+			s.retain(msg)
+		}
+		s.deliver(msg)
+		ctx := NewContext(s, c, msg)
+		if err := s.router.Dispatch(ctx); err != nil && !errors.Is(err, errNoRoute) {
+			if errors.Is(err, ErrHandlerPanic) {
+				c.close(err.Error())
+				return nil
+			}
+			logger.Error("MQTT路由分发失败：", err, "主题：", msg.Topic, "客户端标识：", c.ID)
+		}
+	} else {
+		logger.Warn("MQTT客户端无权发布，已丢弃：", msg.Topic, "客户端标识：", c.ID)
+	}
+	switch msg.QoS {
+	case 1:
+		return c.write(encodeAck(packetPuback, id))
+	case 2:
+		c.mu.Lock()
+		c.awaitingRel[id] = struct{}{}
+		c.mu.Unlock()
+		return c.write(encodeAck(packetPubrec, id))
+	}
+	return nil
+}
+
+// handleSubscribe 处理订阅：授予的QoS最高为1，订阅成功后下发匹配的保留消息
+func (s *Server) handleSubscribe(c *Client, id uint16, list []subscribeRequest) error {
+	codes := make([]byte, 0, len(list)+2)
+	codes = append(codes, byte(id>>8), byte(id))
+	var granted []subscribeRequest
+	for _, req := range list {
+		if req.qos > 2 {
+			return errProtocol
+		}
+		if validTopicFilter(req.filter) != nil || !s.allowed(c, req.filter, AccessSubscribe) {
+			codes = append(codes, subackFailure)
+			continue
+		}
+		qos := min(req.qos, 1)
+		c.mu.Lock()
+		c.subs[req.filter] = qos
+		c.mu.Unlock()
+		s.subs.add(req.filter, c, qos)
+		codes = append(codes, qos)
+		granted = append(granted, subscribeRequest{filter: req.filter, qos: qos})
+	}
+	if err := c.write(encodePacket(packetSuback, 0, codes)); err != nil {
+		return err
+	}
+	for _, req := range granted {
+		for _, msg := range s.retainedFor(req.filter) {
+			if err := c.send(msg, min(msg.QoS, req.qos), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// allowed 主题授权（未设置Authorizer时全部允许）
+func (s *Server) allowed(c *Client, topic string, access Access) bool {
+	return s.authorize == nil || s.authorize(c, topic, access)
+}
@@ -0,0 +1,242 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/netContext"
+	"net/url"
+	"strings"
+)
+
+// Context MQTT上下文（客户端发布的一条消息，与http.Context/websocket.Context方法签名一致）
+type Context struct {
+	Client    *Client           // 发布消息的客户端
+	Topic     string            // 消息主题（对应WS的action）
+	Route     string            // 匹配的路由模式
+	QoS       byte              // 消息QoS
+	Retain    bool              // 保留消息标记
+	RequestId string            // 请求唯一标识（RequestID中间件生成，用于日志关联）
+	params    map[string]string // 路由参数与自定义参数
+	rawData   []byte            // 消息载荷（对应HTTP请求体）
+	ctx       context.Context   // 单条消息的请求级context
+	server    *Server
+	panicked  bool // 处理器已panic（客户端随后被断开）
+}
+
+// NewContext 创建MQTT上下文
+func NewContext(server *Server, client *Client, msg *Message) *Context {
+	c := &Context{
+		Client:  client,
+		Topic:   msg.Topic,
+		QoS:     msg.QoS,
+		Retain:  msg.Retain,
+		params:  make(map[string]string),
+		rawData: msg.Payload,
+		server:  server,
+	}
+	c.ctx = netContext.WithRequestCache(context.Background()) // 每条消息独立的请求级缓存
+	if client != nil {
+		c.ctx = auth.WithClientIdentity(c.ctx, client.identity) // 连接上已校验的客户端证书身份（mTLS）
+		if client.claims != nil {
+			auth.Bind(c, client.claims) // CONNECT时认证的用户
+		}
+	}
+	return c
+}
+
+// -------------------------- 通用控制器签名 --------------------------
+
+// MQTTHandlerFunc 通用控制器方法签名（入参为通用Context接口）
+type MQTTHandlerFunc func(netContext.Context)
+
+// ToMQTTHandler 将通用控制器方法转换为mqtt.HandlerFunc
+func ToMQTTHandler(fn MQTTHandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		fn(ctx)
+	}
+}
+
+// -------------------------- 编译期校验 --------------------------
+var (
+	_ netContext.Context     = (*Context)(nil)
+	_ netContext.RequestInfo = (*Context)(nil)
+)
+
+// GetClientID 获取发布消息的客户端标识
+func (c *Context) GetClientID() string {
+	if c.Client == nil {
+		return ""
+	}
+	return c.Client.ID
+}
+
+// -------------------------- 实现通用context.RequestInfo接口 --------------------------
+
+func (c *Context) GetMethod() string {
+	return c.Topic // MQTT场景：用主题作为请求方法标识
+}
+
+func (c *Context) GetPath() string {
+	return c.Topic // MQTT场景：用主题作为请求唯一标识
+}
+
+func (c *Context) GetClientIP() string {
+	if c.Client == nil {
+		return ""
+	}
+	return c.Client.IP
+}
+
+// GetHeader MQTT 3.1.1没有消息头，返回CONNECT中的连接信息（client_id、username）
+func (c *Context) GetHeader(key string) string {
+	if c.Client == nil {
+		return ""
+	}
+	switch strings.ToLower(strings.ReplaceAll(key, "-", "_")) {
+	case "client_id":
+		return c.Client.ID
+	case "username":
+		return c.Client.Username
+	}
+	return ""
+}
+
+// GetQuery 获取路由参数（主题模式中的{name}）
+func (c *Context) GetQuery(key string) string {
+	return c.params[key]
+}
+
+// -------------------------- 实现通用context.Context接口 --------------------------
+
+func (c *Context) GetRequestInfo() netContext.RequestInfo {
+	return c
+}
+
+// GetContext 获取单条消息的请求级context
+func (c *Context) GetContext() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// SetContext 替换请求级context（中间件注入链路信息等）
+func (c *Context) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// -------------------------- 与http.Context一致的方法实现 --------------------------
+
+// JSON 回复JSON（发布到请求主题对应的回复主题，见ServerConfig.ReplyTopic；自动回写request_id）
+func (c *Context) JSON(code int, data map[string]interface{}) {
+	if c.RequestId != "" && data != nil {
+		if _, ok := data["request_id"]; !ok {
+			data["request_id"] = c.RequestId
+		}
+	}
+	respBytes, err := json.Marshal(data)
+	if err != nil {
+		logger.Error("MQTT上下文JSON序列化失败：", err)
+		return
+	}
+	c.reply(respBytes)
+}
+
+// String 回复文本（发布到回复主题）
+func (c *Context) String(code int, s string) {
+	c.reply([]byte(s))
+}
+
+// Error 回复本地化错误（key为i18n文案key，如i18n.MsgAuthFailed）
+func (c *Context) Error(code int, key string, args ...interface{}) {
+	c.JSON(200, map[string]interface{}{
+		"code": code,
+		"msg":  i18n.T(i18n.FromContext(c.GetContext()), key, args...),
+		"data": nil,
+	})
+}
+
+// ReplyTopic 当前消息的回复主题（未配置回复主题时为空）
+func (c *Context) ReplyTopic() string {
+	if c.server == nil {
+		return ""
+	}
+	return c.server.replyTopic(c.Topic, c.GetClientID())
+}
+
+// reply 以请求的QoS（最高1）发布回复
+func (c *Context) reply(payload []byte) {
+	topic := c.ReplyTopic()
+	if topic == "" {
+		return
+	}
+	if err := c.server.Publish(topic, payload, min(c.QoS, 1), false); err != nil {
+		logger.FromContext(c.GetContext()).Warn("MQTT回复失败：", err, "主题：", topic)
+	}
+}
+
+// Query 获取路由参数
+func (c *Context) Query(key string) string {
+	return c.params[key]
+}
+
+// PostForm 获取表单参数（载荷为key=value&...格式时解析）
+func (c *Context) PostForm(key string) string {
+	if c.params[key] != "" {
+		return c.params[key]
+	}
+	return c.PostFormAll()[key]
+}
+
+// PostFormAll 获取所有表单参数（载荷为key=value&...格式时解析，与路由参数合并）
+func (c *Context) PostFormAll() map[string]string {
+	if len(c.rawData) > 0 {
+		values, err := url.ParseQuery(string(c.rawData))
+		if err == nil {
+			for key, val := range values {
+				if _, ok := c.params[key]; !ok && len(val) > 0 {
+					c.params[key] = val[0]
+				}
+			}
+		}
+	}
+	return c.params
+}
+
+// GetBody 获取消息载荷
+func (c *Context) GetBody() ([]byte, error) {
+	if len(c.rawData) > 0 {
+		return c.rawData, nil
+	}
+	return []byte{}, nil
+}
+
+// BindJSON 绑定JSON载荷到结构体
+func (c *Context) BindJSON(v interface{}) error {
+	if len(c.rawData) == 0 {
+		return json.Unmarshal([]byte("{}"), v)
+	}
+	return json.Unmarshal(c.rawData, v)
+}
+
+// Bind 合并绑定路由参数（按query标签）与消息载荷（JSON对象按json标签，key=value&...按form标签）到结构体
+func (c *Context) Bind(v interface{}) error {
+	query := make(url.Values, len(c.params))
+	for key, val := range c.params {
+		query.Set(key, val)
+	}
+	return netContext.Bind(v, netContext.PayloadSources(query, c.rawData))
+}
+
+// SetParam 手动设置参数（供中间件使用）
+func (c *Context) SetParam(key, value string) {
+	c.params[key] = value
+}
+
+// GetParam 获取路由参数或自定义参数
+func (c *Context) GetParam(key string) string {
+	return c.params[key]
+}
@@ -0,0 +1,83 @@
+package mqtt
+
+import (
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"runtime/debug"
+)
+
+// HandlerFunc MQTT处理器函数（与http.HandlerFunc对齐）
+type HandlerFunc func(*Context)
+
+// MiddlewareFunc MQTT中间件函数（与http.MiddlewareFunc对齐）
+type MiddlewareFunc func(HandlerFunc) HandlerFunc
+
+// Recovery 异常恢复中间件（NewServer默认安装）：处理器panic时记录堆栈并回复500错误，随后断开当前客户端
+func Recovery() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			defer func() {
+				if p := recover(); p != nil {
+					c.recoverPanic(p)
+				}
+			}()
+			next(c)
+		}
+	}
+}
+
+// recoverPanic 记录panic堆栈并回复500错误（同一消息只处理一次）
+func (c *Context) recoverPanic(p interface{}) {
+	if c.panicked {
+		return
+	}
+	c.panicked = true
+	logger.FromContext(c.GetContext()).Error("MQTT请求异常：", p, "主题：", c.Topic, "客户端：", c.GetClientID(), "\n", string(debug.Stack()))
+	c.Error(500, i18n.MsgInternalError)
+}
+
+// RequestID 请求ID中间件（为每条消息生成ID，回复时回写request_id并绑定请求级日志，通过logger.FromContext获取）；
+// 开启日志缓冲时，处理器panic才输出缓冲的debug/info日志（见logger.StartBuffer）
+func RequestID() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if c.RequestId == "" {
+				c.RequestId = logger.NewRequestID()
+			}
+			ctx, buf := logger.StartBuffer(logger.WithRequestID(c.GetContext(), c.RequestId))
+			c.SetContext(ctx)
+			if buf == nil {
+				next(c)
+				return
+			}
+			finished := false
+			defer func() {
+				buf.Finish(!finished || c.panicked)
+			}()
+			next(c)
+			finished = true
+		}
+	}
+}
+
+// Tracing 链路追踪中间件（MQTT 3.1.1没有消息头，每条消息开启新的服务端span）
+func Tracing() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if !tracing.Enabled() {
+				next(c)
+				return
+			}
+			ctx, span := tracing.StartServerSpan(c.GetContext(), "MQTT "+c.Route,
+				attribute.String("mqtt.topic", c.Topic),
+				attribute.String("mqtt.client_id", c.GetClientID()),
+				attribute.Int("mqtt.qos", int(c.QoS)),
+			)
+			defer span.End()
+			c.SetContext(ctx)
+			next(c)
+		}
+	}
+}
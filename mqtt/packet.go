@@ -0,0 +1,322 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// MQTT 3.1.1 控制报文类型
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+// CONNACK返回码
+const (
+	connAccepted          = 0x00
+	connRefusedVersion    = 0x01 // 不支持的协议级别
+	connRefusedIdentifier = 0x02 // 客户端标识不合法
+	connRefusedBadAuth    = 0x04 // 用户名或密码错误
+	connRefusedNotAuth    = 0x05 // 未授权
+	connRefusedServer     = 0x03 // 服务不可用
+)
+
+// subackFailure SUBACK中表示订阅失败的返回码
+const subackFailure = 0x80
+
+var (
+	errMalformed  = errors.New("mqtt: 报文格式错误")
+	errTooLarge   = errors.New("mqtt: 报文超出大小限制")
+	errProtocol   = errors.New("mqtt: 违反协议")
+	errInvalidUTF = errors.New("mqtt: 字符串不是合法的UTF-8")
+)
+
+// packet 原始控制报文（固定报头 + 剩余部分）
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+// readPacket 读取一个控制报文（maxSize为剩余长度上限）
+func readPacket(r *bufio.Reader, maxSize int) (packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	var size, shift int
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errMalformed
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		size |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	if maxSize > 0 && size > maxSize {
+		return packet{}, errTooLarge
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{typ: first >> 4, flags: first & 0x0F, body: body}, nil
+}
+
+// encodePacket 编码控制报文
+func encodePacket(typ, flags byte, body []byte) []byte {
+	buf := make([]byte, 0, len(body)+5)
+	buf = append(buf, typ<<4|flags)
+	size := len(body)
+	for {
+		b := byte(size & 0x7F)
+		size >>= 7
+		if size > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if size == 0 {
+			break
+		}
+	}
+	return append(buf, body...)
+}
+
+// packetReader 报文内容解码（出错后后续读取均返回零值，最后检查err）
+type packetReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *packetReader) remaining() int {
+	return len(r.buf) - r.off
+}
+
+func (r *packetReader) byte() byte {
+	if r.err != nil || r.off >= len(r.buf) {
+		r.err = errMalformed
+		return 0
+	}
+	b := r.buf[r.off]
+	r.off++
+	return b
+}
+
+func (r *packetReader) uint16() uint16 {
+	if r.err != nil || r.off+2 > len(r.buf) {
+		r.err = errMalformed
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.buf[r.off:])
+	r.off += 2
+	return v
+}
+
+func (r *packetReader) bytes() []byte {
+	n := int(r.uint16())
+	if r.err != nil || r.off+n > len(r.buf) {
+		r.err = errMalformed
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *packetReader) string() string {
+	b := r.bytes()
+	if r.err == nil && !utf8.Valid(b) {
+		r.err = errInvalidUTF
+	}
+	return string(b)
+}
+
+func (r *packetReader) rest() []byte {
+	if r.err != nil {
+		return nil
+	}
+	b := r.buf[r.off:]
+	r.off = len(r.buf)
+	return b
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// connectPacket CONNECT报文
+type connectPacket struct {
+	protocol     string
+	level        byte
+	cleanSession bool
+	keepAlive    uint16
+	clientID     string
+	will         *Message
+	username     string
+	password     []byte
+	hasUsername  bool
+	hasPassword  bool
+}
+
+// decodeConnect 解析CONNECT报文（支持3.1（MQIsdp/3）与3.1.1（MQTT/4））
+func decodeConnect(p packet) (*connectPacket, byte, error) {
+	r := &packetReader{buf: p.body}
+	c := &connectPacket{protocol: r.string(), level: r.byte()}
+	flags := r.byte()
+	c.keepAlive = r.uint16()
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	if !(c.protocol == "MQTT" && c.level == 4) && !(c.protocol == "MQIsdp" && c.level == 3) {
+		return nil, connRefusedVersion, fmt.Errorf("mqtt: 不支持的协议%s/%d", c.protocol, c.level)
+	}
+	if flags&0x01 != 0 {
+		return nil, 0, errProtocol
+	}
+	c.cleanSession = flags&0x02 != 0
+	c.clientID = r.string()
+	if flags&0x04 != 0 {
+		c.will = &Message{Topic: r.string(), QoS: (flags >> 3) & 0x03, Retain: flags&0x20 != 0}
+		c.will.Payload = append([]byte(nil), r.bytes()...)
+		if c.will.QoS > 2 {
+			return nil, 0, errProtocol
+		}
+	} else if flags&0x38 != 0 {
+		return nil, 0, errProtocol
+	}
+	if flags&0x80 != 0 {
+		c.hasUsername = true
+		c.username = r.string()
+	}
+	if flags&0x40 != 0 {
+		c.hasPassword = true
+		c.password = append([]byte(nil), r.bytes()...)
+	}
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	return c, connAccepted, nil
+}
+
+// decodePublish 解析PUBLISH报文，返回消息与报文标识（QoS 0时为0）
+func decodePublish(p packet) (*Message, uint16, error) {
+	msg := &Message{QoS: (p.flags >> 1) & 0x03, Retain: p.flags&0x01 != 0, Dup: p.flags&0x08 != 0}
+	if msg.QoS > 2 {
+		return nil, 0, errProtocol
+	}
+	r := &packetReader{buf: p.body}
+	msg.Topic = r.string()
+	var id uint16
+	if msg.QoS > 0 {
+		id = r.uint16()
+	}
+	msg.Payload = r.rest()
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	if err := validTopicName(msg.Topic); err != nil {
+		return nil, 0, err
+	}
+	return msg, id, nil
+}
+
+// encodePublish 编码PUBLISH报文
+func encodePublish(msg *Message, qos byte, id uint16, retain bool) []byte {
+	body := make([]byte, 0, len(msg.Topic)+len(msg.Payload)+4)
+	body = appendString(body, msg.Topic)
+	if qos > 0 {
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, msg.Payload...)
+	flags := qos << 1
+	if retain {
+		flags |= 0x01
+	}
+	return encodePacket(packetPublish, flags, body)
+}
+
+// encodeAck 编码仅含报文标识的应答（PUBACK/PUBREC/PUBREL/PUBCOMP/UNSUBACK）
+func encodeAck(typ byte, id uint16) []byte {
+	var flags byte
+	if typ == packetPubrel {
+		flags = 0x02
+	}
+	return encodePacket(typ, flags, binary.BigEndian.AppendUint16(nil, id))
+}
+
+// subscribeRequest SUBSCRIBE报文中的一项订阅
+type subscribeRequest struct {
+	filter string
+	qos    byte
+}
+
+// decodeSubscribe 解析SUBSCRIBE报文
+func decodeSubscribe(p packet) (uint16, []subscribeRequest, error) {
+	if p.flags != 0x02 {
+		return 0, nil, errProtocol
+	}
+	r := &packetReader{buf: p.body}
+	id := r.uint16()
+	var list []subscribeRequest
+	for r.err == nil && r.remaining() > 0 {
+		list = append(list, subscribeRequest{filter: r.string(), qos: r.byte()})
+	}
+	if r.err != nil {
+		return 0, nil, r.err
+	}
+	if len(list) == 0 {
+		return 0, nil, errProtocol
+	}
+	return id, list, nil
+}
+
+// decodeUnsubscribe 解析UNSUBSCRIBE报文
+func decodeUnsubscribe(p packet) (uint16, []string, error) {
+	if p.flags != 0x02 {
+		return 0, nil, errProtocol
+	}
+	r := &packetReader{buf: p.body}
+	id := r.uint16()
+	var list []string
+	for r.err == nil && r.remaining() > 0 {
+		list = append(list, r.string())
+	}
+	if r.err != nil {
+		return 0, nil, r.err
+	}
+	if len(list) == 0 {
+		return 0, nil, errProtocol
+	}
+	return id, list, nil
+}
+
+// decodePacketID 解析仅含报文标识的报文
+func decodePacketID(p packet) (uint16, error) {
+	r := &packetReader{buf: p.body}
+	id := r.uint16()
+	return id, r.err
+}
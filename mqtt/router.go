@@ -0,0 +1,168 @@
+package mqtt
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Router MQTT路由器：按主题模式分发客户端发布的消息（对应WS的action路由）。
+// 主题模式沿用MQTT过滤器语法，另支持命名层级：
+//   - devices/{device_id}/telemetry：{device_id}匹配单层，值通过ctx.GetParam("device_id")获取
+//   - devices/+/status：+匹配单层（不命名）
+//   - logs/#：#匹配末尾任意层级，匹配部分通过ctx.GetParam("#")获取
+//
+// 多个模式匹配同一主题时，字面层级多的优先，其次按注册顺序
+type Router struct {
+	routes      []*route
+	middlewares []MiddlewareFunc // 全局中间件（由Server注入）
+}
+
+type route struct {
+	pattern string
+	levels  []string
+	literal int // 字面层级数（匹配优先级）
+	order   int
+	handler HandlerFunc
+}
+
+// NewRouter 创建MQTT路由器实例
+func NewRouter() *Router {
+	return &Router{middlewares: make([]MiddlewareFunc, 0)}
+}
+
+// Use 注入全局中间件（由Server调用）
+func (r *Router) Use(middlewares ...MiddlewareFunc) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Register 注册主题路由（由Server调用，统一处理中间件链）；模式不合法时panic
+func (r *Router) Register(pattern string, handler HandlerFunc, chain []MiddlewareFunc) {
+	if err := validTopicFilter(PatternFilter(pattern)); err != nil {
+		panic("mqtt: 路由模式不合法：" + pattern)
+	}
+	rt := &route{pattern: pattern, levels: strings.Split(pattern, "/"), order: len(r.routes), handler: buildChain(chain, handler)}
+	for _, level := range rt.levels {
+		if level != "+" && level != "#" && !isParam(level) {
+			rt.literal++
+		}
+	}
+	for i, old := range r.routes {
+		if old.pattern == pattern {
+			rt.order = old.order
+			r.routes[i] = rt
+			return
+		}
+	}
+	r.routes = append(r.routes, rt)
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		if r.routes[i].literal != r.routes[j].literal {
+			return r.routes[i].literal > r.routes[j].literal
+		}
+		return r.routes[i].order < r.routes[j].order
+	})
+}
+
+// Topics 已注册的主题模式（按匹配优先级排序）
+func (r *Router) Topics() []string {
+	topics := make([]string, 0, len(r.routes))
+	for _, rt := range r.routes {
+		topics = append(topics, rt.pattern)
+	}
+	return topics
+}
+
+// PatternFilter 将主题模式转换为MQTT过滤器（{name}替换为+）
+func PatternFilter(pattern string) string {
+	levels := strings.Split(pattern, "/")
+	for i, level := range levels {
+		if isParam(level) {
+			levels[i] = "+"
+		}
+	}
+	return strings.Join(levels, "/")
+}
+
+func isParam(level string) bool {
+	return len(level) > 2 && level[0] == '{' && level[len(level)-1] == '}'
+}
+
+// match 查找匹配主题的路由并提取参数
+func (r *Router) match(topic string) (*route, map[string]string) {
+	levels := strings.Split(topic, "/")
+	for _, rt := range r.routes {
+		if params, ok := rt.match(levels, strings.HasPrefix(topic, "$")); ok {
+			return rt, params
+		}
+	}
+	return nil, nil
+}
+
+func (rt *route) match(levels []string, system bool) (map[string]string, bool) {
+	var params map[string]string
+	for i, level := range rt.levels {
+		wildcard := level == "+" || level == "#" || isParam(level)
+		if i == 0 && system && wildcard {
+			return nil, false
+		}
+		if level == "#" {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params["#"] = strings.Join(levels[i:], "/")
+			return params, true
+		}
+		if i >= len(levels) {
+			return nil, false
+		}
+		switch {
+		case level == "+":
+		case isParam(level):
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[level[1:len(level)-1]] = levels[i]
+		case level != levels[i]:
+			return nil, false
+		}
+	}
+	return params, len(rt.levels) == len(levels)
+}
+
+// errNoRoute 没有匹配的路由（消息仅转发给订阅者）
+var errNoRoute = errors.New("mqtt: no route")
+
+// ErrHandlerPanic 处理器panic（Dispatch返回该错误时，Server断开当前客户端）
+var ErrHandlerPanic = errors.New("mqtt handler panic")
+
+// Dispatch 路由分发（内部方法，供Server调用）：处理器panic（含Recovery中间件之外的中间件）时记录堆栈并返回ErrHandlerPanic
+func (r *Router) Dispatch(ctx *Context) (err error) {
+	rt, params := r.match(ctx.Topic)
+	if rt == nil {
+		return errNoRoute
+	}
+	ctx.Route = rt.pattern
+	for key, val := range params {
+		ctx.params[key] = val
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			ctx.recoverPanic(p)
+		}
+		if ctx.panicked {
+			err = ErrHandlerPanic
+		}
+	}()
+	rt.handler(ctx)
+	return nil
+}
+
+// buildChain 构建中间件链（与HTTP/WS服务逻辑一致）
+func buildChain(middlewares []MiddlewareFunc, final HandlerFunc) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		currentMid := middlewares[i]
+		currentNext := final
+		final = currentMid(currentNext)
+	}
+	return final
+}
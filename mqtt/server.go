@@ -0,0 +1,399 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/logger"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrServerClosed 服务器已停止
+var ErrServerClosed = errors.New("mqtt: Server closed")
+
+// Message MQTT应用消息
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte // 0/1/2（下行消息最高为1）
+	Retain  bool
+	Dup     bool
+}
+
+// ServerConfig MQTT服务器配置
+type ServerConfig struct {
+	Addr            string        // 监听地址（ip:port）
+	SSL             bool          // 是否启用TLS
+	SSLCertFile     string        // TLS证书路径
+	SSLKeyFile      string        // TLS密钥路径
+	MaxConnections  int32         // 最大连接数（默认10000）
+	MaxMessageSize  int           // 最大报文大小（默认256KB）
+	ConnectTimeout  time.Duration // 等待CONNECT报文的超时（默认10秒）
+	WriteTimeout    time.Duration // 写超时（默认10秒）
+	SendQueueSize   int           // 每个客户端的发送队列长度（默认256）
+	MaxInflight     int           // 每个客户端未确认的QoS1下行消息上限（默认1000）
+	ReplyTopic      string        // 回复主题模板（默认{topic}/reply，-表示不回复）
+	ShutdownTimeout time.Duration // Stop时等待处理中消息完成的时长（默认10秒）
+	// MTLS 客户端证书校验（SSL启用时生效，为nil时不校验）
+	MTLS *auth.MTLSOptions
+}
+
+// ConnectInfo CONNECT报文中的连接信息（供认证函数使用）
+type ConnectInfo struct {
+	ClientID string
+	Username string
+	Password []byte
+	IP       string
+	Identity *auth.ClientIdentity // mTLS客户端证书身份（未启用时为nil）
+}
+
+// Authenticator 认证函数：返回错误时拒绝连接（CONNACK返回码4）；返回的声明绑定到该客户端后续消息的上下文（可为nil）
+type Authenticator func(info *ConnectInfo) (*auth.Claims, error)
+
+// Access 主题访问类型
+type Access int
+
+const (
+	AccessPublish   Access = iota + 1 // 客户端发布
+	AccessSubscribe                   // 客户端订阅（topic为订阅的过滤器）
+)
+
+// Authorizer 主题授权函数：拒绝发布时丢弃消息（仍按QoS应答），拒绝订阅时SUBACK返回失败
+type Authorizer func(c *Client, topic string, access Access) bool
+
+// Server MQTT服务器（内置broker：转发客户端之间的消息、保留消息与遗嘱；客户端发布的消息同时按主题路由到处理器）
+type Server struct {
+	config       *ServerConfig
+	router       *Router
+	middlewares  []MiddlewareFunc
+	listener     net.Listener
+	authenticate Authenticator
+	authorize    Authorizer
+	onConnect    []func(c *Client)
+	onDisconnect []func(c *Client, reason string)
+
+	subs      *topicTree
+	retainMu  sync.RWMutex
+	retained  map[string]*Message // 主题 -> 保留消息
+	mu        sync.Mutex
+	clients   map[string]*Client // 客户端标识 -> 客户端
+	connCount atomic.Int32
+	closed    atomic.Bool
+	wg        sync.WaitGroup // 连接处理协程
+}
+
+// NewServer 创建MQTT服务器实例（按应用配置mqtt节点）
+func NewServer(appName string) *Server {
+	cfg := loadServerConfig(appName)
+	setDefaultConfig(cfg)
+	serv := &Server{
+		config:      cfg,
+		router:      NewRouter(),
+		middlewares: make([]MiddlewareFunc, 0),
+		subs:        newTopicTree(),
+		retained:    make(map[string]*Message),
+		clients:     make(map[string]*Client),
+	}
+	serv.Use(Recovery())
+	return serv
+}
+
+func loadServerConfig(appName string) *ServerConfig {
+	mqttCfg := config.GetAppConfig(appName).MQTT
+	return &ServerConfig{
+		Addr:            mqttCfg.Addr,
+		SSL:             mqttCfg.SSL,
+		SSLCertFile:     mqttCfg.SSLCertFile,
+		SSLKeyFile:      mqttCfg.SSLKeyFile,
+		MTLS:            auth.MTLSOptionsFromConfig(mqttCfg.MTLS),
+		MaxConnections:  mqttCfg.MaxConnections,
+		MaxMessageSize:  mqttCfg.MaxMessageSize,
+		ConnectTimeout:  time.Duration(mqttCfg.ConnectTimeout) * time.Second,
+		WriteTimeout:    time.Duration(mqttCfg.WriteTimeout) * time.Second,
+		SendQueueSize:   mqttCfg.SendQueueSize,
+		MaxInflight:     mqttCfg.MaxInflight,
+		ReplyTopic:      mqttCfg.ReplyTopic,
+		ShutdownTimeout: time.Duration(mqttCfg.ShutdownTimeout) * time.Second,
+	}
+}
+
+func setDefaultConfig(cfg *ServerConfig) {
+	if cfg.Addr == "" {
+		cfg.Addr = ":1883"
+	}
+	if cfg.MaxConnections == 0 {
+		cfg.MaxConnections = 10000
+	}
+	if cfg.MaxMessageSize == 0 {
+		cfg.MaxMessageSize = 256 * 1024
+	}
+	if cfg.ConnectTimeout == 0 {
+		cfg.ConnectTimeout = 10 * time.Second
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.SendQueueSize == 0 {
+		cfg.SendQueueSize = 256
+	}
+	if cfg.MaxInflight == 0 {
+		cfg.MaxInflight = 1000
+	}
+	if cfg.ReplyTopic == "" {
+		cfg.ReplyTopic = "{topic}/reply"
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = 10 * time.Second
+	}
+}
+
+// Config 暴露配置
+func (s *Server) Config() *ServerConfig {
+	return s.config
+}
+
+// Use 注册全局中间件（对齐HTTP/WS Server.Use，需在Register之前调用）
+func (s *Server) Use(middlewares ...MiddlewareFunc) {
+	s.middlewares = append(s.middlewares, middlewares...)
+	s.router.Use(middlewares...)
+}
+
+// Register 注册主题路由（模式语法见Router），客户端发布到匹配主题的消息交给handler处理
+func (s *Server) Register(pattern string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
+	chain := append(append([]MiddlewareFunc(nil), s.middlewares...), middlewares...)
+	s.router.Register(pattern, handler, chain)
+}
+
+// Topics 已注册的主题模式
+func (s *Server) Topics() []string {
+	return s.router.Topics()
+}
+
+// SetAuthenticator 设置CONNECT认证函数（未设置时允许匿名连接）
+func (s *Server) SetAuthenticator(fn Authenticator) {
+	s.authenticate = fn
+}
+
+// SetAuthorizer 设置主题授权函数（未设置时允许发布与订阅任意主题）
+func (s *Server) SetAuthorizer(fn Authorizer) {
+	s.authorize = fn
+}
+
+// OnConnect 订阅客户端上线（CONNACK发送后同步调用）
+func (s *Server) OnConnect(fn func(c *Client)) {
+	s.onConnect = append(s.onConnect, fn)
+}
+
+// OnDisconnect 订阅客户端下线（reason为下线原因）
+func (s *Server) OnDisconnect(fn func(c *Client, reason string)) {
+	s.onDisconnect = append(s.onDisconnect, fn)
+}
+
+// SetListener 指定监听器（平滑重启时传入继承的监听器，需在Run之前调用）
+func (s *Server) SetListener(lis net.Listener) {
+	s.listener = lis
+}
+
+// Listener 获取当前TCP监听器（Run之前为nil）
+func (s *Server) Listener() net.Listener {
+	return s.listener
+}
+
+// ConnCount 获取当前连接数
+func (s *Server) ConnCount() int32 {
+	return s.connCount.Load()
+}
+
+// Client 按客户端标识获取在线客户端
+func (s *Server) Client(clientID string) (*Client, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.clients[clientID]
+	return c, ok
+}
+
+// Run 启动MQTT服务器（阻塞直到Stop）
+func (s *Server) Run() error {
+	if s.listener == nil {
+		lis, err := net.Listen("tcp", s.config.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to create MQTT listener: %w", err)
+		}
+		s.listener = lis
+	}
+	lis := s.listener
+	if s.config.SSL {
+		tlsConfig, err := auth.ServerTLSConfig(s.config.SSLCertFile, s.config.SSLKeyFile, s.config.MTLS)
+		if err != nil {
+			return err
+		}
+		lis = tls.NewListener(lis, tlsConfig)
+		logger.Info("MQTTS服务器启动成功，监听地址：", s.config.Addr)
+	} else {
+		logger.Info("MQTT服务器启动成功，监听地址：", s.config.Addr)
+	}
+	var delay time.Duration
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if s.closed.Load() {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				delay = min(max(delay*2, 5*time.Millisecond), time.Second)
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+// Stop 优雅停止MQTT服务器（等待时长为ShutdownTimeout，详见Shutdown）
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown 停止接收新连接，断开全部客户端（处理中的消息完成并应答后断开，不发布遗嘱），ctx到期时强制关闭连接
+func (s *Server) Shutdown(ctx context.Context) error {
+	if !s.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	s.mu.Lock()
+	clients := make([]*Client, 0, len(s.clients))
+	for _, c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+	for _, c := range clients {
+		c.drain()
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, c := range clients {
+			_ = c.conn.Close()
+		}
+		return ctx.Err()
+	}
+}
+
+// Publish 发布消息给订阅者（服务端下发指令等；retain为true时同时作为该主题的保留消息）
+func (s *Server) Publish(topic string, payload []byte, qos byte, retain bool) error {
+	if err := validTopicName(topic); err != nil {
+		return err
+	}
+	if qos > 2 {
+		return errProtocol
+	}
+	msg := &Message{Topic: topic, Payload: payload, QoS: qos, Retain: retain}
+	if retain {
+		s.retain(msg)
+	}
+	s.deliver(msg)
+	return nil
+}
+
+// PublishJSON 序列化为JSON后发布
+func (s *Server) PublishJSON(topic string, v interface{}, qos byte, retain bool) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Publish(topic, payload, qos, retain)
+}
+
+// deliver 投递给匹配的订阅者（QoS取发布与订阅中的较小值，转发给已有订阅者时不带保留标记）
+func (s *Server) deliver(msg *Message) {
+	for c, qos := range s.subs.match(msg.Topic) {
+		_ = c.send(msg, min(msg.QoS, qos), false)
+	}
+}
+
+// retain 保存或清除（载荷为空时）保留消息
+func (s *Server) retain(msg *Message) {
+	s.retainMu.Lock()
+	defer s.retainMu.Unlock()
+	if len(msg.Payload) == 0 {
+		delete(s.retained, msg.Topic)
+		return
+	}
+	s.retained[msg.Topic] = &Message{Topic: msg.Topic, Payload: msg.Payload, QoS: msg.QoS, Retain: true}
+}
+
+// retainedFor 匹配过滤器的保留消息
+func (s *Server) retainedFor(filter string) []*Message {
+	s.retainMu.RLock()
+	defer s.retainMu.RUnlock()
+	var list []*Message
+	for topic, msg := range s.retained {
+		if MatchTopic(filter, topic) {
+			list = append(list, msg)
+		}
+	}
+	return list
+}
+
+// replyTopic 按模板生成回复主题
+func (s *Server) replyTopic(topic, clientID string) string {
+	tpl := s.config.ReplyTopic
+	if tpl == "-" {
+		return ""
+	}
+	return strings.NewReplacer("{topic}", topic, "{client_id}", clientID).Replace(tpl)
+}
+
+// register 登记上线客户端，同一标识已在线时断开旧连接（会话接管）
+func (s *Server) register(c *Client) bool {
+	s.mu.Lock()
+	if s.closed.Load() {
+		s.mu.Unlock()
+		return false
+	}
+	old := s.clients[c.ID]
+	s.clients[c.ID] = c
+	s.mu.Unlock()
+	if old != nil {
+		old.close("session taken over")
+	}
+	return true
+}
+
+// unregister 客户端下线时移除（已被新连接接管时不移除）
+func (s *Server) unregister(c *Client) {
+	s.mu.Lock()
+	if s.clients[c.ID] == c {
+		delete(s.clients, c.ID)
+	}
+	s.mu.Unlock()
+}
+
+// JWTAuthenticator 以CONNECT的password作为JWT令牌认证（username可为设备标识等任意值）
+func JWTAuthenticator(j *auth.JWT) Authenticator {
+	return func(info *ConnectInfo) (*auth.Claims, error) {
+		return j.Parse(string(info.Password))
+	}
+}
@@ -0,0 +1,145 @@
+package mqtt
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+var (
+	errInvalidTopic  = errors.New("mqtt: 主题名不合法")
+	errInvalidFilter = errors.New("mqtt: 主题过滤器不合法")
+)
+
+// validTopicName 校验发布的主题名（非空，不含通配符与空字符）
+func validTopicName(topic string) error {
+	if topic == "" || len(topic) > 65535 || strings.ContainsAny(topic, "+#\x00") {
+		return errInvalidTopic
+	}
+	return nil
+}
+
+// validTopicFilter 校验订阅的主题过滤器（+匹配单层且独占一层，#只能在末尾且独占一层）
+func validTopicFilter(filter string) error {
+	if filter == "" || len(filter) > 65535 || strings.Contains(filter, "\x00") {
+		return errInvalidFilter
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return errInvalidFilter
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return errInvalidFilter
+		}
+	}
+	return nil
+}
+
+// MatchTopic 主题名是否匹配过滤器（以$开头的主题不被首层通配符匹配）
+func MatchTopic(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, level := range fl {
+		if level == "#" {
+			return true
+		}
+		if i >= len(tl) {
+			return false
+		}
+		if level != "+" && level != tl[i] {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
+
+// topicNode 订阅树节点（按主题层级）
+type topicNode struct {
+	children map[string]*topicNode
+	subs     map[*Client]byte // 订阅该过滤器的客户端 -> 授予的QoS
+}
+
+func newTopicNode() *topicNode {
+	return &topicNode{children: make(map[string]*topicNode), subs: make(map[*Client]byte)}
+}
+
+// topicTree 订阅树：发布时按层级匹配，避免遍历全部订阅
+type topicTree struct {
+	mu   sync.RWMutex
+	root *topicNode
+}
+
+func newTopicTree() *topicTree {
+	return &topicTree{root: newTopicNode()}
+}
+
+// add 添加订阅（同一客户端重复订阅同一过滤器时替换QoS）
+func (t *topicTree) add(filter string, c *Client, qos byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node := t.root
+	for _, level := range strings.Split(filter, "/") {
+		child, ok := node.children[level]
+		if !ok {
+			child = newTopicNode()
+			node.children[level] = child
+		}
+		node = child
+	}
+	node.subs[c] = qos
+}
+
+// remove 移除订阅并清理空节点
+func (t *topicTree) remove(filter string, c *Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeLevel(t.root, strings.Split(filter, "/"), c)
+}
+
+func (t *topicTree) removeLevel(node *topicNode, levels []string, c *Client) bool {
+	if len(levels) == 0 {
+		delete(node.subs, c)
+	} else if child, ok := node.children[levels[0]]; ok && t.removeLevel(child, levels[1:], c) {
+		delete(node.children, levels[0])
+	}
+	return len(node.subs) == 0 && len(node.children) == 0
+}
+
+// match 匹配主题的订阅者（客户端有多个重叠订阅时取最大QoS）
+func (t *topicTree) match(topic string) map[*Client]byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	result := make(map[*Client]byte)
+	levels := strings.Split(topic, "/")
+	t.matchLevel(t.root, levels, strings.HasPrefix(topic, "$"), result)
+	return result
+}
+
+func (t *topicTree) matchLevel(node *topicNode, levels []string, system bool, result map[*Client]byte) {
+	// #匹配当前层及以下全部层级（含父级本身，如 a/# 匹配 a）
+	if multi, ok := node.children["#"]; ok && !system {
+		collect(multi, result)
+	}
+	if len(levels) == 0 {
+		collect(node, result)
+		return
+	}
+	if child, ok := node.children[levels[0]]; ok {
+		t.matchLevel(child, levels[1:], false, result)
+	}
+	if single, ok := node.children["+"]; ok && !system {
+		t.matchLevel(single, levels[1:], false, result)
+	}
+}
+
+func collect(node *topicNode, result map[*Client]byte) {
+	for c, qos := range node.subs {
+		if old, ok := result[c]; !ok || qos > old {
+			result[c] = qos
+		}
+	}
+}
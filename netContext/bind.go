@@ -0,0 +1,262 @@
+package netContext
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 请求参数绑定：将查询参数、表单字段与JSON请求体按结构体标签合并写入同一个DTO，替代逐个GetQuery/PostForm再strconv转换。
+//
+//	type ListReq struct {
+//		Page    int       `query:"page"`
+//		Size    int       `query:"size"`
+//		Status  []int     `query:"status"`                          // ?status=1&status=2 或 ?status=1,2
+//		Since   time.Time `query:"since" time_format:"2006-01-02"` // 未指定time_format时支持RFC3339、2006-01-02 15:04:05、2006-01-02与Unix时间戳
+//		Keyword string    `form:"keyword" json:"keyword"`
+//	}
+//
+//	var req ListReq
+//	if err := ctx.Bind(&req); err != nil { ... }
+//
+// 按query→form→json的顺序写入，同一字段后者覆盖前者；请求中不存在的参数保留结构体原值（可预先填充默认值）。
+// query/form标签支持string、bool、整数、浮点数、time.Time、time.Duration、实现encoding.TextUnmarshaler的类型，
+// 以及它们的指针与切片；JSON请求体按json标签由encoding/json解码。
+
+// BindSources 参数来源
+type BindSources struct {
+	Query url.Values // 查询参数（query标签）
+	Form  url.Values // 表单字段（form标签）
+	JSON  []byte     // JSON请求体（json标签）
+}
+
+// BindError 参数格式错误（可用errors.As取出参数名）
+type BindError struct {
+	Source string // 参数来源：query/form/json
+	Field  string // 参数名
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s参数格式错误：%v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("参数%s格式错误：%v", e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	unmarshaler  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeLayouts  = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+)
+
+// Bind 按标签将各来源的参数绑定到v（须为非nil的结构体指针）
+func Bind(v interface{}, src BindSources) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("netContext: Bind的目标须为非nil的结构体指针，当前为%T", v)
+	}
+	if len(src.Query) > 0 {
+		if err := bindValues(rv.Elem(), "query", src.Query); err != nil {
+			return err
+		}
+	}
+	if len(src.Form) > 0 {
+		if err := bindValues(rv.Elem(), "form", src.Form); err != nil {
+			return err
+		}
+	}
+	if len(bytes.TrimSpace(src.JSON)) > 0 {
+		if err := json.Unmarshal(src.JSON, v); err != nil {
+			bindErr := &BindError{Source: "json", Err: err}
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				bindErr.Field = typeErr.Field
+			}
+			return bindErr
+		}
+	}
+	return nil
+}
+
+// PayloadSources 按消息载荷格式生成参数来源（WS/gRPC/MQTT等无Content-Type的协议使用）：
+// 以{开头的载荷作为JSON，否则按key=value&...表单解析
+func PayloadSources(query url.Values, payload []byte) BindSources {
+	src := BindSources{Query: query}
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 {
+		return src
+	}
+	if trimmed[0] == '{' {
+		src.JSON = trimmed
+	} else if form, err := url.ParseQuery(string(trimmed)); err == nil {
+		src.Form = form
+	}
+	return src
+}
+
+// bindValues 将values按标签tag写入结构体字段（递归处理嵌入的结构体）
+func bindValues(rv reflect.Value, tag string, values url.Values) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if field.Anonymous && name == "" {
+			if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				if err := bindValues(fv, tag, values); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if err := setField(fv, vals, field.Tag.Get("time_format")); err != nil {
+			return &BindError{Source: tag, Field: name, Err: err}
+		}
+	}
+	return nil
+}
+
+// setField 写入字段值（切片字段接收多值，单个值中含逗号时按逗号拆分）
+func setField(fv reflect.Value, vals []string, layout string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 && !reflect.PointerTo(fv.Type()).Implements(unmarshaler) {
+		if len(vals) == 1 {
+			if vals[0] == "" {
+				return nil
+			}
+			vals = strings.Split(vals[0], ",")
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setScalar(slice.Index(i), strings.TrimSpace(val), layout); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setScalar(fv, vals[0], layout)
+}
+
+// setScalar 将字符串转换为字段类型（非字符串类型的空值视为未传，保留原值）
+func setScalar(fv reflect.Value, val, layout string) error {
+	if fv.Kind() != reflect.String && strings.TrimSpace(val) == "" {
+		return nil
+	}
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setScalar(elem.Elem(), val, layout); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+	if fv.CanAddr() && fv.Addr().Type().Implements(unmarshaler) && fv.Type() != timeType {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+	val = strings.TrimSpace(val)
+	switch fv.Type() {
+	case timeType:
+		t, err := parseTime(val, layout)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			// 纯数字按秒计
+			seconds, numErr := strconv.ParseInt(val, 10, 64)
+			if numErr != nil {
+				return err
+			}
+			d = time.Duration(seconds) * time.Second
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := parseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("不支持的字段类型%s", fv.Type())
+	}
+	return nil
+}
+
+// parseBool 解析布尔值（兼容表单复选框的on/off与yes/no）
+func parseBool(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+	return strconv.ParseBool(val)
+}
+
+// parseTime 按指定格式解析时间；未指定时依次尝试常用格式与Unix时间戳（13位及以上按毫秒）
+func parseTime(val, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.ParseInLocation(layout, val, time.Local)
+	}
+	if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+		if len(val) >= 13 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, l := range timeLayouts {
+		if t, err := time.ParseInLocation(l, val, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析的时间%q", val)
+}
@@ -0,0 +1,135 @@
+package netContext
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 请求级缓存：HTTP请求、WS单条消息、gRPC调用开始时由框架挂到请求context上，请求结束后随context一起释放。
+// 同一请求内重复的查询（用户资料、权限、配置行等）只执行一次，适合在服务层/模型层通过ctx使用：
+//
+//	user, err := netContext.GetOrCompute(ctx, "user:"+uid, func() (*User, error) {
+//		return userModel.Find(ctx, uid)
+//	})
+
+// RequestCache 请求级缓存（并发安全：同一请求内并发计算同一key时只执行一次）
+type RequestCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type requestCacheKey struct{}
+
+// errComputePanicked 计算函数panic（panic继续向上传递，等待中的协程重新计算）
+var errComputePanicked = errors.New("netContext: 请求级缓存计算函数panic")
+
+// WithRequestCache 在ctx上挂载新的请求级缓存（覆盖上层已挂载的缓存，如WS连接握手请求上的缓存）
+func WithRequestCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestCacheKey{}, &RequestCache{entries: make(map[string]*cacheEntry)})
+}
+
+// RequestCacheFrom 获取ctx上的请求级缓存（未挂载时返回nil）
+func RequestCacheFrom(ctx context.Context) *RequestCache {
+	if ctx == nil {
+		return nil
+	}
+	cache, _ := ctx.Value(requestCacheKey{}).(*RequestCache)
+	return cache
+}
+
+// GetOrCompute 读取请求级缓存，未命中时调用fn计算并缓存结果（fn返回错误时不缓存，下次调用重新计算）。
+// ctx上未挂载缓存（如后台任务）时直接调用fn
+func GetOrCompute[T any](ctx context.Context, key string, fn func() (T, error)) (T, error) {
+	cache := RequestCacheFrom(ctx)
+	if cache == nil {
+		return fn()
+	}
+	v, err := cache.getOrCompute(key, func() (interface{}, error) {
+		return fn()
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	value, _ := v.(T) // T为接口类型且结果为nil时返回零值
+	return value, nil
+}
+
+func (c *RequestCache) getOrCompute(key string, fn func() (interface{}, error)) (interface{}, error) {
+	for {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		if !ok {
+			entry = &cacheEntry{done: make(chan struct{})}
+			c.entries[key] = entry
+			c.mu.Unlock()
+			c.compute(key, entry, fn)
+			return entry.value, entry.err
+		}
+		c.mu.Unlock()
+		<-entry.done
+		if entry.err == nil {
+			return entry.value, nil
+		}
+		// 其他协程计算失败（结果未缓存），重新竞争计算
+	}
+}
+
+// compute 执行计算（fn panic时同样移除占位，避免等待方永久阻塞）
+func (c *RequestCache) compute(key string, entry *cacheEntry, fn func() (interface{}, error)) {
+	completed := false
+	defer func() {
+		if !completed {
+			entry.err = errComputePanicked
+		}
+		if entry.err != nil {
+			c.mu.Lock()
+			if c.entries[key] == entry {
+				delete(c.entries, key)
+			}
+			c.mu.Unlock()
+		}
+		close(entry.done)
+	}()
+	entry.value, entry.err = fn()
+	completed = true
+}
+
+// Get 读取已缓存的值
+func (c *RequestCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	select {
+	case <-entry.done:
+		return entry.value, entry.err == nil
+	default:
+		return nil, false
+	}
+}
+
+// Set 写入缓存（覆盖已有值）
+func (c *RequestCache) Set(key string, value interface{}) {
+	entry := &cacheEntry{done: make(chan struct{}), value: value}
+	close(entry.done)
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+// Delete 删除缓存（请求内数据被修改后调用，使后续读取重新查询）
+func (c *RequestCache) Delete(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
@@ -1,5 +1,7 @@
 package netContext
 
+import "context"
+
 // -------------------------- 通用请求信息接口（解耦具体协议） --------------------------
 
 // RequestInfo 通用请求信息接口，抽象所有协议的公共请求属性
@@ -12,6 +14,13 @@ type RequestInfo interface {
 	GetQuery(key string) string  // 获取查询参数/附加参数（HTTP：URL.Query；WS：握手Query；gRPC：Metadata）
 }
 
+// RemoteIPInfo 可获取连接对端IP的请求信息（HTTP/WS实现）。
+// HTTP/WS的GetClientIP优先读取客户端可伪造的X-Real-IP/X-Forwarded-For，限流等安全相关场景应使用对端IP，
+// 仅在对端为受信任的反向代理时才采信这些请求头（见ratelimit.TrustedProxies）
+type RemoteIPInfo interface {
+	GetRemoteIP() string
+}
+
 // Context 通用上下文接口（包含HTTP和WS上下文的公共方法）
 type Context interface {
 	JSON(code int, data map[string]interface{})
@@ -21,9 +30,12 @@ type Context interface {
 	PostFormAll() map[string]string
 	GetBody() ([]byte, error)
 	BindJSON(v interface{}) error
+	Bind(v interface{}) error // 按query/form/json标签合并绑定查询参数、表单与JSON请求体（见netContext.Bind）
 	SetParam(key, value string)
 	GetParam(key string) string
 	GetRequestInfo() RequestInfo // 返回通用请求信息，替代直接返回*http.Request
+	GetContext() context.Context // 获取请求级context（携带链路追踪等信息，供DB操作透传）
+	SetContext(ctx context.Context)
 }
 
 // GRPCHandlerFunc 通用gRPC处理器签名（框架层定义，应用层复用）
@@ -0,0 +1,482 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/schema"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 任务队列：基于Redis Stream与消费组，发送邮件、生成报表等耗时任务投递到队列后由工作协程池异步处理，
+// 同一消费组的多个实例分摊消息。每个队列使用以下键（均拼接Redis表前缀与Prefix）：
+//   - {queue}：待处理消息（Stream，消费组读取，处理完成后确认并删除）
+//   - {queue}:delayed：延迟投递与等待重试的消息（有序集合，到期后移入Stream）
+//   - {queue}:dead：重试用尽或载荷不符合结构定义的死信（Stream，可查看并重新投递）
+//
+// 处理失败（返回错误、panic或超时）时按backoff*2^(attempt-1)延迟重试；消费者崩溃导致未确认的消息在ClaimIdle后由其他消费者接管，
+// 计为一次失败。投递语义为至少一次，处理函数应按Message.ID保证幂等。
+// 启用schema注册中心且队列名已登记为subject时，投递前按最新版本校验载荷，消费时按消息携带的版本校验。
+
+// HandlerFunc 消息处理函数（返回错误时按重试配置重试）
+type HandlerFunc func(ctx context.Context, msg *Message) error
+
+// 队列错误
+var (
+	ErrHandlerExists = errors.New("queue: 队列已注册处理函数")
+	ErrStopped       = errors.New("queue: 队列已停止")
+)
+
+// Message 队列消息
+type Message struct {
+	ID         string    // 消息ID（投递时生成，重试时不变）
+	Queue      string    // 队列名
+	Payload    []byte    // 载荷（JSON）
+	Version    int       // 载荷的schema版本（队列名未登记为subject时为0）
+	Attempt    int       // 第几次处理（从1开始）
+	EnqueuedAt time.Time // 投递时间
+	Err        string    // 最后一次处理的错误（死信）
+	streamID   string
+}
+
+// Decode 反序列化载荷（protobuf消息按protojson，其他类型按encoding/json）
+func (m *Message) Decode(v interface{}) error {
+	if pm, ok := v.(proto.Message); ok {
+		return protojson.Unmarshal(m.Payload, pm)
+	}
+	return json.Unmarshal(m.Payload, v)
+}
+
+// Options 队列参数
+type Options struct {
+	Prefix      string        // 键前缀（默认queue:）
+	Group       string        // 消费组（默认default）
+	Consumer    string        // 消费者名（默认主机名-进程号）
+	Concurrency int           // 每个队列的并发处理数（默认10）
+	MaxRetries  int           // 失败重试次数（默认3，<0不重试）
+	Backoff     time.Duration // 首次重试的等待时长（默认1秒，之后每次翻倍）
+	Timeout     time.Duration // 单次处理超时（默认60秒）
+	ClaimIdle   time.Duration // 未确认消息的接管时长（默认5分钟，应大于Timeout）
+	MaxLen      int64         // 队列最大长度（近似裁剪，0不限制）
+	Schema      *schema.Registry
+}
+
+// HandlerOption 处理函数的可选配置
+type HandlerOption func(h *handler)
+
+// WithConcurrency 该队列的并发处理数
+func WithConcurrency(n int) HandlerOption {
+	return func(h *handler) {
+		h.concurrency = n
+	}
+}
+
+// WithRetry 该队列的失败重试次数与首次重试等待时长
+func WithRetry(attempts int, backoff time.Duration) HandlerOption {
+	return func(h *handler) {
+		h.retries = attempts
+		h.backoff = backoff
+	}
+}
+
+// WithTimeout 该队列的单次处理超时
+func WithTimeout(timeout time.Duration) HandlerOption {
+	return func(h *handler) {
+		h.timeout = timeout
+	}
+}
+
+// EnqueueOption 投递的可选配置
+type EnqueueOption func(o *enqueueOptions)
+
+type enqueueOptions struct {
+	at time.Time
+}
+
+// Delay 延迟投递
+func Delay(d time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.at = time.Now().Add(d)
+	}
+}
+
+// At 在指定时间投递
+func At(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.at = t
+	}
+}
+
+// Stats 队列状态
+type Stats struct {
+	Ready   int64 // 等待处理（含已投递未确认）
+	Pending int64 // 已投递给消费者但未确认
+	Delayed int64 // 延迟投递与等待重试
+	Dead    int64 // 死信
+}
+
+type handler struct {
+	queue       string
+	fn          HandlerFunc
+	concurrency int
+	retries     int
+	backoff     time.Duration
+	timeout     time.Duration
+	slots       chan struct{}
+}
+
+// Queue 任务队列
+type Queue struct {
+	rdb       *redisDb.RedisDb
+	opts      Options
+	overrides map[string]config.QueueItemConfig
+
+	mu       sync.Mutex
+	handlers map[string]*handler
+	started  bool
+	stop     chan struct{}   // 关闭后不再读取新消息
+	runCtx   context.Context // 处理中消息的ctx（停机等待超时时取消）
+	cancel   context.CancelFunc
+	loops    sync.WaitGroup // 各队列的读取与维护协程
+	running  sync.WaitGroup // 处理中的消息
+}
+
+// New 创建任务队列
+func New(rdb *redisDb.RedisDb, opts Options) *Queue {
+	if opts.Prefix == "" {
+		opts.Prefix = "queue:"
+	}
+	if opts.Group == "" {
+		opts.Group = "default"
+	}
+	if opts.Consumer == "" {
+		host, _ := os.Hostname()
+		opts.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 10
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+	if opts.ClaimIdle <= 0 {
+		opts.ClaimIdle = 5 * time.Minute
+	}
+	q := &Queue{rdb: rdb, opts: opts, handlers: make(map[string]*handler), stop: make(chan struct{})}
+	q.runCtx, q.cancel = context.WithCancel(context.Background())
+	return q
+}
+
+var (
+	queueMu    sync.Mutex
+	queueCache sync.Map
+)
+
+// FromAppConfig 按应用配置（queue节点）获取任务队列（同一应用共用一个实例，生产者与消费者均可调用）
+func FromAppConfig(appName string) (*Queue, error) {
+	if v, ok := queueCache.Load(appName); ok {
+		return v.(*Queue), nil
+	}
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	if v, ok := queueCache.Load(appName); ok {
+		return v.(*Queue), nil
+	}
+	appCfg := config.GetAppConfig(appName)
+	cfg := appCfg.Queue
+	if cfg.RedisDb == "" {
+		return nil, errors.New("queue: 未配置queue.redis_db")
+	}
+	rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+	if err != nil {
+		return nil, err
+	}
+	group := cfg.Group
+	if group == "" {
+		group = appCfg.Name
+	}
+	q := New(rdb, Options{
+		Prefix:      cfg.Prefix,
+		Group:       group,
+		Concurrency: cfg.Concurrency,
+		MaxRetries:  cfg.MaxRetries,
+		Backoff:     time.Duration(cfg.Backoff) * time.Millisecond,
+		Timeout:     time.Duration(cfg.Timeout) * time.Second,
+		ClaimIdle:   time.Duration(cfg.ClaimIdle) * time.Second,
+		MaxLen:      cfg.MaxLen,
+	})
+	q.overrides = cfg.Queues
+	queueCache.Store(appName, q)
+	return q, nil
+}
+
+// Enqueue 投递消息，返回消息ID。payload为[]byte/json.RawMessage时原样投递，protobuf消息按protojson序列化，其他类型按encoding/json序列化
+func (q *Queue) Enqueue(ctx context.Context, queue string, payload interface{}, opts ...EnqueueOption) (string, error) {
+	if queue == "" {
+		return "", errors.New("queue: 队列名不能为空")
+	}
+	var o enqueueOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	data, version, err := q.marshal(ctx, queue, payload)
+	if err != nil {
+		return "", err
+	}
+	msg := &Message{ID: uuid.NewString(), Queue: queue, Payload: data, Version: version, Attempt: 1, EnqueuedAt: time.Now()}
+	client := q.rdb.WithContext(ctx).Db
+	if !o.at.IsZero() && o.at.After(time.Now()) {
+		err = client.ZAdd(q.key(queue, "delayed"), redis.Z{Score: float64(o.at.UnixMilli()), Member: msg.encode()}).Err()
+	} else {
+		err = client.XAdd(q.addArgs(q.key(queue, ""), msg.fields())).Err()
+	}
+	if err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// marshal 序列化并按schema注册中心校验（队列名未登记为subject或注册中心不可用时不校验）
+func (q *Queue) marshal(ctx context.Context, queue string, payload interface{}) ([]byte, int, error) {
+	var data []byte
+	var err error
+	switch v := payload.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	case proto.Message:
+		data, err = protojson.Marshal(v)
+	default:
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	reg := q.registry()
+	if reg == nil {
+		return data, 0, nil
+	}
+	version, err := reg.Validate(ctx, queue, data)
+	if err != nil {
+		var invalid *schema.ValidationError
+		if errors.As(err, &invalid) {
+			return nil, 0, err
+		}
+		if !errors.Is(err, schema.ErrNotFound) {
+			logger.FromContext(ctx).Warn("队列[", queue, "]载荷结构校验失败，已放行：", err)
+		}
+		return data, 0, nil
+	}
+	return data, version, nil
+}
+
+func (q *Queue) registry() *schema.Registry {
+	if q.opts.Schema != nil {
+		return q.opts.Schema
+	}
+	return schema.Default()
+}
+
+// Handle 注册队列的处理函数（配置queue.queues中的同名项覆盖代码中的并发、重试与超时）
+func (q *Queue) Handle(queue string, fn HandlerFunc, opts ...HandlerOption) error {
+	if queue == "" || fn == nil {
+		return errors.New("queue: 队列名与处理函数不能为空")
+	}
+	h := &handler{queue: queue, fn: fn, concurrency: q.opts.Concurrency, retries: q.opts.MaxRetries, backoff: q.opts.Backoff, timeout: q.opts.Timeout}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if o, ok := q.overrides[queue]; ok {
+		if o.Concurrency > 0 {
+			h.concurrency = o.Concurrency
+		}
+		if o.MaxRetries != 0 {
+			h.retries = o.MaxRetries
+		}
+		if o.Timeout > 0 {
+			h.timeout = time.Duration(o.Timeout) * time.Second
+		}
+	}
+	if h.concurrency <= 0 {
+		h.concurrency = 1
+	}
+	h.slots = make(chan struct{}, h.concurrency)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.handlers[queue]; ok {
+		return fmt.Errorf("%w：%s", ErrHandlerExists, queue)
+	}
+	q.handlers[queue] = h
+	if q.started {
+		return q.startHandler(h)
+	}
+	return nil
+}
+
+// Start 创建消费组并开始消费已注册的队列（重复调用无效；启动后注册的队列立即开始消费）
+func (q *Queue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return nil
+	}
+	select {
+	case <-q.stop:
+		return ErrStopped
+	default:
+	}
+	for _, h := range q.handlers {
+		if err := q.startHandler(h); err != nil {
+			return err
+		}
+	}
+	q.started = true
+	logger.Info("任务队列已启动，消费组：", q.opts.Group, " 消费者：", q.opts.Consumer, " 队列数：", len(q.handlers))
+	return nil
+}
+
+// Stop 停止读取新消息并等待处理中的消息完成；ctx到期时取消处理中消息的ctx并返回ctx错误（未确认的消息在ClaimIdle后被重新处理）
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	select {
+	case <-q.stop:
+	default:
+		close(q.stop)
+	}
+	q.mu.Unlock()
+	q.loops.Wait()
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
+
+// Stats 队列状态
+func (q *Queue) Stats(ctx context.Context, queue string) (Stats, error) {
+	var stats Stats
+	client := q.rdb.WithContext(ctx).Db
+	pipe := client.Pipeline()
+	ready := pipe.XLen(q.key(queue, ""))
+	delayed := pipe.ZCard(q.key(queue, "delayed"))
+	dead := pipe.XLen(q.key(queue, "dead"))
+	if _, err := pipe.Exec(); err != nil && !errors.Is(err, redis.Nil) {
+		return stats, err
+	}
+	stats.Ready, stats.Delayed, stats.Dead = ready.Val(), delayed.Val(), dead.Val()
+	if pending, err := client.XPending(q.key(queue, ""), q.opts.Group).Result(); err == nil {
+		stats.Pending = pending.Count
+	}
+	return stats, nil
+}
+
+// DeadLetters 查看死信（按进入死信队列的先后，最多count条）
+func (q *Queue) DeadLetters(ctx context.Context, queue string, count int64) ([]*Message, error) {
+	list, err := q.rdb.WithContext(ctx).Db.XRangeN(q.key(queue, "dead"), "-", "+", count).Result()
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*Message, 0, len(list))
+	for _, x := range list {
+		messages = append(messages, decodeFields(queue, x))
+	}
+	return messages, nil
+}
+
+// RetryDead 将死信重新投递到队列（重置处理次数）
+func (q *Queue) RetryDead(ctx context.Context, msg *Message) error {
+	if msg == nil || msg.streamID == "" {
+		return errors.New("queue: 只能重新投递DeadLetters返回的消息")
+	}
+	retry := *msg
+	retry.Attempt, retry.Err = 1, ""
+	_, err := q.rdb.WithContext(ctx).Db.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.XAdd(q.addArgs(q.key(msg.Queue, ""), retry.fields()))
+		pipe.XDel(q.key(msg.Queue, "dead"), msg.streamID)
+		return nil
+	})
+	return err
+}
+
+// key 队列的Redis键（suffix为空时为待处理Stream）
+func (q *Queue) key(queue, suffix string) string {
+	key := q.rdb.DbPre + q.opts.Prefix + queue
+	if suffix != "" {
+		key += ":" + suffix
+	}
+	return key
+}
+
+func (q *Queue) addArgs(stream string, fields map[string]interface{}) *redis.XAddArgs {
+	return &redis.XAddArgs{Stream: stream, MaxLenApprox: q.opts.MaxLen, Values: fields}
+}
+
+// fields 消息写入Stream的字段
+func (m *Message) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"id":          m.ID,
+		"payload":     string(m.Payload),
+		"version":     strconv.Itoa(m.Version),
+		"attempt":     strconv.Itoa(m.Attempt),
+		"enqueued_at": strconv.FormatInt(m.EnqueuedAt.UnixMilli(), 10),
+	}
+	if m.Err != "" {
+		fields["error"] = m.Err
+	}
+	return fields
+}
+
+// encode 延迟消息在有序集合中的成员（字段均为字符串，到期后由脚本原样写入Stream）
+func (m *Message) encode() string {
+	fields := m.fields()
+	values := make(map[string]string, len(fields))
+	for k, v := range fields {
+		values[k] = v.(string)
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// decodeFields 由Stream条目还原消息
+func decodeFields(queue string, x redis.XMessage) *Message {
+	field := func(name string) string {
+		s, _ := x.Values[name].(string)
+		return s
+	}
+	msg := &Message{ID: field("id"), Queue: queue, Payload: []byte(field("payload")), Err: field("error"), streamID: x.ID}
+	msg.Version, _ = strconv.Atoi(field("version"))
+	msg.Attempt, _ = strconv.Atoi(field("attempt"))
+	if msg.Attempt <= 0 {
+		msg.Attempt = 1
+	}
+	if ms, err := strconv.ParseInt(field("enqueued_at"), 10, 64); err == nil {
+		msg.EnqueuedAt = time.UnixMilli(ms)
+	}
+	return msg
+}
@@ -0,0 +1,254 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/schema"
+	"github.com/go-redis/redis"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const (
+	readBlock        = 2 * time.Second // 读取新消息的阻塞时长（也是停机时等待读取协程退出的最长时间）
+	maintainInterval = time.Second     // 延迟消息到期检查间隔
+	claimBatch       = 100
+)
+
+// moveDueScript 将到期的延迟消息移入Stream：KEYS[1]为有序集合，KEYS[2]为Stream，ARGV为当前时间（毫秒）、条数与MaxLen
+var moveDueScript = redis.NewScript(`local items = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, item in ipairs(items) do
+  redis.call('ZREM', KEYS[1], item)
+  local args = {KEYS[2]}
+  if tonumber(ARGV[3]) > 0 then
+    table.insert(args, 'MAXLEN')
+    table.insert(args, '~')
+    table.insert(args, ARGV[3])
+  end
+  table.insert(args, '*')
+  for k, v in pairs(cjson.decode(item)) do
+    table.insert(args, k)
+    table.insert(args, v)
+  end
+  redis.call('XADD', unpack(args))
+end
+return #items`)
+
+// startHandler 创建消费组并启动读取与维护协程（调用方持有q.mu）
+func (q *Queue) startHandler(h *handler) error {
+	stream := q.key(h.queue, "")
+	// 从头读取，消费组创建前投递的消息也会被处理
+	if err := q.rdb.Db.XGroupCreateMkStream(stream, q.opts.Group, "0").Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("queue: 创建消费组失败[%s]：%w", h.queue, err)
+	}
+	q.loops.Add(2)
+	go q.readLoop(h)
+	go q.maintainLoop(h)
+	return nil
+}
+
+// readLoop 有空闲处理槽时读取新消息
+func (q *Queue) readLoop(h *handler) {
+	defer q.loops.Done()
+	stream := q.key(h.queue, "")
+	for {
+		select {
+		case <-q.stop:
+			return
+		case h.slots <- struct{}{}:
+		}
+		// 一次读取与空闲槽数相同的消息
+		free := int64(1)
+	fill:
+		for free < int64(h.concurrency) {
+			select {
+			case h.slots <- struct{}{}:
+				free++
+			default:
+				break fill
+			}
+		}
+		streams, err := q.rdb.Db.XReadGroup(&redis.XReadGroupArgs{
+			Group:    q.opts.Group,
+			Consumer: q.opts.Consumer,
+			Streams:  []string{stream, ">"},
+			Count:    free,
+			Block:    readBlock,
+		}).Result()
+		var received []redis.XMessage
+		if len(streams) > 0 {
+			received = streams[0].Messages
+		}
+		for i := int64(len(received)); i < free; i++ {
+			<-h.slots
+		}
+		if err != nil && !errors.Is(err, redis.Nil) {
+			logger.Warn("队列[", h.queue, "]读取消息失败：", err)
+			select {
+			case <-q.stop:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, x := range received {
+			q.running.Add(1)
+			go func(msg *Message) {
+				defer q.running.Done()
+				defer func() { <-h.slots }()
+				q.process(h, msg)
+			}(decodeFields(h.queue, x))
+		}
+	}
+}
+
+// maintainLoop 定期将到期的延迟消息移入Stream，并接管其他消费者长时间未确认的消息
+func (q *Queue) maintainLoop(h *handler) {
+	defer q.loops.Done()
+	ticker := time.NewTicker(maintainInterval)
+	defer ticker.Stop()
+	claimEvery := min(q.opts.ClaimIdle/2, 30*time.Second)
+	lastClaim := time.Now()
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+		if err := q.moveDue(h.queue); err != nil {
+			logger.Warn("队列[", h.queue, "]延迟消息移入失败：", err)
+		}
+		if time.Since(lastClaim) >= claimEvery {
+			lastClaim = time.Now()
+			q.claimStale(h)
+		}
+	}
+}
+
+// moveDue 移入全部到期的延迟消息
+func (q *Queue) moveDue(queue string) error {
+	keys := []string{q.key(queue, "delayed"), q.key(queue, "")}
+	for {
+		n, err := moveDueScript.Run(q.rdb.Db, keys, time.Now().UnixMilli(), claimBatch, q.opts.MaxLen).Int()
+		if err != nil || n < claimBatch {
+			return err
+		}
+	}
+}
+
+// claimStale 接管超过ClaimIdle未确认的消息（原消费者崩溃或失联），计为一次处理失败
+func (q *Queue) claimStale(h *handler) {
+	stream := q.key(h.queue, "")
+	pending, err := q.rdb.Db.XPendingExt(&redis.XPendingExtArgs{Stream: stream, Group: q.opts.Group, Start: "-", End: "+", Count: claimBatch}).Result()
+	if err != nil {
+		logger.Warn("队列[", h.queue, "]查询未确认消息失败：", err)
+		return
+	}
+	var ids []string
+	for _, p := range pending {
+		if p.Idle >= q.opts.ClaimIdle {
+			ids = append(ids, p.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	claimed, err := q.rdb.Db.XClaim(&redis.XClaimArgs{Stream: stream, Group: q.opts.Group, Consumer: q.opts.Consumer, MinIdle: q.opts.ClaimIdle, Messages: ids}).Result()
+	if err != nil {
+		logger.Warn("队列[", h.queue, "]接管未确认消息失败：", err)
+		return
+	}
+	for _, x := range claimed {
+		msg := decodeFields(h.queue, x)
+		logger.Warn("队列[", h.queue, "]消息", msg.ID, "超过", q.opts.ClaimIdle, "未确认，已接管")
+		q.fail(h, msg, errors.New("消费者未确认（处理超时或进程退出）"))
+	}
+}
+
+// process 校验载荷结构后调用处理函数，按结果确认、重试或转入死信
+func (q *Queue) process(h *handler, msg *Message) {
+	if msg.Version > 0 {
+		if reg := q.registry(); reg != nil {
+			if err := reg.ValidateVersion(q.runCtx, msg.Queue, msg.Version, msg.Payload); err != nil {
+				var invalid *schema.ValidationError
+				if errors.As(err, &invalid) {
+					// 结构错误重试无意义，直接转入死信
+					q.dead(h, msg, err)
+					return
+				}
+				logger.Warn("队列[", msg.Queue, "]载荷结构校验失败，已放行：", err)
+			}
+		}
+	}
+	start := time.Now()
+	err := q.call(h, msg)
+	if err != nil {
+		q.fail(h, msg, err)
+		return
+	}
+	if err := q.ack(h.queue, msg, nil); err != nil {
+		logger.Warn("队列[", h.queue, "]确认消息失败：", msg.ID, " Err：", err)
+	}
+	logger.Debug("队列[", h.queue, "]消息", msg.ID, "处理完成，耗时：", time.Since(start))
+}
+
+// call 执行一次处理函数（超时控制，panic转换为错误并记录堆栈）
+func (q *Queue) call(h *handler, msg *Message) (err error) {
+	ctx, cancel := context.WithTimeout(q.runCtx, h.timeout)
+	defer cancel()
+	ctx = logger.WithContext(ctx, logger.FromContext(ctx).WithFields(logger.Fields{"queue": msg.Queue, "msg_id": msg.ID, "attempt": msg.Attempt}))
+	defer func() {
+		if p := recover(); p != nil {
+			logger.Error("队列[", msg.Queue, "]处理函数panic：", p, "\n", string(debug.Stack()))
+			err = fmt.Errorf("queue: 处理函数panic：%v", p)
+		}
+	}()
+	return h.fn(ctx, msg)
+}
+
+// fail 处理失败：未用尽重试次数时延迟重试，否则转入死信
+func (q *Queue) fail(h *handler, msg *Message, cause error) {
+	if msg.Attempt > h.retries {
+		q.dead(h, msg, cause)
+		return
+	}
+	wait := h.backoff << (msg.Attempt - 1)
+	logger.Warn("队列[", h.queue, "]消息", msg.ID, "第", msg.Attempt, "次处理失败，", wait, "后重试：", cause)
+	retry := *msg
+	retry.Attempt++
+	retry.Err = cause.Error()
+	if err := q.ack(h.queue, msg, func(pipe redis.Pipeliner) {
+		pipe.ZAdd(q.key(h.queue, "delayed"), redis.Z{Score: float64(time.Now().Add(wait).UnixMilli()), Member: retry.encode()})
+	}); err != nil {
+		logger.Error("队列[", h.queue, "]消息", msg.ID, "写入重试失败：", err)
+	}
+}
+
+// dead 转入死信队列
+func (q *Queue) dead(h *handler, msg *Message, cause error) {
+	logger.Error("队列[", h.queue, "]消息", msg.ID, "处理失败", msg.Attempt, "次，转入死信：", cause)
+	dead := *msg
+	dead.Err = cause.Error()
+	if err := q.ack(h.queue, msg, func(pipe redis.Pipeliner) {
+		pipe.XAdd(q.addArgs(q.key(h.queue, "dead"), dead.fields()))
+	}); err != nil {
+		logger.Error("队列[", h.queue, "]消息", msg.ID, "写入死信失败：", err)
+	}
+}
+
+// ack 确认并删除消息（then不为nil时在同一事务中写入重试或死信）
+func (q *Queue) ack(queue string, msg *Message, then func(pipe redis.Pipeliner)) error {
+	stream := q.key(queue, "")
+	_, err := q.rdb.Db.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.XAck(stream, q.opts.Group, msg.streamID)
+		pipe.XDel(stream, msg.streamID)
+		if then != nil {
+			then(pipe)
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"fmt"
+	"github.com/dfpopp/go-dai/netContext"
+	"net"
+	"strings"
+)
+
+// TrustedProxies 受信任的反向代理地址（如Nginx、负载均衡）。
+// 仅当连接的对端IP属于受信任代理时才采信X-Forwarded-For/X-Real-IP，否则任何客户端都可通过伪造请求头绕过按IP限流
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies 解析受信任代理列表（IP或CIDR，如 10.0.0.0/8、127.0.0.1）
+func ParseTrustedProxies(list []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(list))
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("受信任代理地址无效：%s", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("受信任代理地址无效：%s", item)
+		}
+		proxies = append(proxies, ipNet)
+	}
+	return proxies, nil
+}
+
+// Contains 判断IP是否属于受信任代理
+func (t TrustedProxies) Contains(ip string) bool {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range t {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP 限流使用的客户端IP：默认取连接的对端IP；对端为受信任代理时，从X-Forwarded-For末尾向前
+// 跳过受信任代理取第一个地址（全部为受信任代理时取最左侧），无X-Forwarded-For时取X-Real-IP。
+// 未实现netContext.RemoteIPInfo的协议（gRPC、MQTT）的GetClientIP本身即为对端地址
+func (t TrustedProxies) ClientIP(info netContext.RequestInfo) string {
+	remote, ok := info.(netContext.RemoteIPInfo)
+	if !ok {
+		return info.GetClientIP()
+	}
+	ip := remote.GetRemoteIP()
+	if len(t) == 0 || !t.Contains(ip) {
+		return ip
+	}
+	if forwarded := info.GetHeader("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !t.Contains(hop) || i == 0 {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(info.GetHeader("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return ip
+}
+
+// KeyByTrustedIP 按客户端IP限流（对端为proxies中的代理时采信转发请求头）
+func KeyByTrustedIP(proxies TrustedProxies) KeyFunc {
+	return func(c netContext.Context) string {
+		return "ip:" + proxies.ClientIP(c.GetRequestInfo())
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// sweepInterval 清理空闲令牌桶的间隔
+	sweepInterval = time.Minute
+	// defaultMaxKeys 默认最多保留的计数键
+	defaultMaxKeys = 100000
+)
+
+// MemoryLimiter 内存令牌桶限流器（仅对单实例生效）。
+// 计数键数量超过上限时淘汰最久未访问的令牌桶（被淘汰的键下次访问时按新桶计数），内存占用有上界
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*list.Element // key -> lru中的元素（Value为*bucket）
+	lru       *list.List               // 按最近访问排序，队首最新
+	maxKeys   int
+	lastSweep time.Time
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+	rule   Rule
+}
+
+// NewMemoryLimiter 创建内存令牌桶限流器（maxKeys为最多保留的计数键，不传或<=0时为100000）
+func NewMemoryLimiter(maxKeys ...int) *MemoryLimiter {
+	limit := defaultMaxKeys
+	if len(maxKeys) > 0 && maxKeys[0] > 0 {
+		limit = maxKeys[0]
+	}
+	return &MemoryLimiter{buckets: make(map[string]*list.Element), lru: list.New(), maxKeys: limit, lastSweep: time.Now()}
+}
+
+// Allow 消耗一个令牌（令牌按 Rate/Period 的速度恢复，最多累积Burst个）
+func (l *MemoryLimiter) Allow(_ context.Context, key string, rule Rule) (Result, error) {
+	rule = rule.normalize()
+	now := time.Now()
+	perSecond := float64(rule.Rate) / rule.Period.Seconds()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	var b *bucket
+	if elem, ok := l.buckets[key]; ok {
+		b = elem.Value.(*bucket)
+		l.lru.MoveToFront(elem)
+	}
+	if b == nil || b.rule != rule {
+		if b == nil {
+			l.evict()
+			b = &bucket{key: key}
+			l.buckets[key] = l.lru.PushFront(b)
+		}
+		b.tokens, b.last, b.rule = float64(rule.Burst), now, rule
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * perSecond
+		if b.tokens > float64(rule.Burst) {
+			b.tokens = float64(rule.Burst)
+		}
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return Result{Allowed: true, Limit: rule.Rate, Remaining: int(b.tokens)}, nil
+	}
+	wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	return Result{Allowed: false, Limit: rule.Rate, RetryAfter: wait}, nil
+}
+
+// Len 当前保留的计数键数量
+func (l *MemoryLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// evict 键数量达到上限时淘汰最久未访问的令牌桶
+func (l *MemoryLimiter) evict() {
+	for len(l.buckets) >= l.maxKeys {
+		oldest := l.lru.Back()
+		if oldest == nil {
+			return
+		}
+		l.lru.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*bucket).key)
+	}
+}
+
+// sweep 定期删除已回满的令牌桶（回满后与新建桶等价），避免按IP等维度计数时内存持续增长
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, elem := range l.buckets {
+		b := elem.Value.(*bucket)
+		refill := time.Duration(float64(b.rule.Period) * float64(b.rule.Burst) / float64(b.rule.Rate))
+		if now.Sub(b.last) >= refill {
+			l.lru.Remove(elem)
+			delete(l.buckets, key)
+		}
+	}
+}
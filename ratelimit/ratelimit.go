@@ -0,0 +1,237 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/netContext"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 限流模块：HTTP/WS/gRPC共用同一套规则与计数，
+// 单实例使用内存令牌桶（MemoryLimiter），集群部署使用Redis滑动窗口（RedisLimiter）。
+// 各协议的中间件见 http.RateLimit、websocket.RateLimit、grpc.RateLimitInterceptor。
+
+// Rule 限流规则：每Period最多Rate次请求，Burst为令牌桶容量（仅内存令牌桶使用，默认等于Rate）
+type Rule struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// Result 限流判定结果
+type Result struct {
+	Allowed    bool          // 是否放行
+	Limit      int           // 周期内允许的请求数
+	Remaining  int           // 剩余可用次数
+	RetryAfter time.Duration // 被拒绝时建议的重试等待时长
+}
+
+// RetryAfterSeconds Retry-After响应头取值（向上取整，至少1秒）
+func (r Result) RetryAfterSeconds() int {
+	seconds := int(math.Ceil(r.RetryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// Limiter 限流器
+type Limiter interface {
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// KeyFunc 从请求上下文中提取限流维度（返回空字符串表示该请求不限流）
+type KeyFunc func(c netContext.Context) string
+
+// KeyByIP 按连接的对端IP限流（不采信可伪造的X-Forwarded-For等请求头；部署在反向代理之后时使用KeyByTrustedIP）
+func KeyByIP(c netContext.Context) string {
+	return "ip:" + TrustedProxies(nil).ClientIP(c.GetRequestInfo())
+}
+
+// KeyByRoute 按路由限流（HTTP路径/WS action/gRPC方法）
+func KeyByRoute(c netContext.Context) string {
+	return "route:" + c.GetRequestInfo().GetPath()
+}
+
+// KeyByUser 按用户限流（用户ID由认证中间件通过WithUser写入，未登录时退化为按IP）
+func KeyByUser(c netContext.Context) string {
+	return keyByUser(KeyByIP)(c)
+}
+
+// keyByUser 按用户限流，未登录时使用ipKey
+func keyByUser(ipKey KeyFunc) KeyFunc {
+	return func(c netContext.Context) string {
+		if user := UserFromContext(c.GetContext()); user != "" {
+			return "user:" + user
+		}
+		return ipKey(c)
+	}
+}
+
+// Keys 组合多个维度（如 Keys(KeyByUser, KeyByRoute) 表示每个用户在每个路由上独立计数）
+func Keys(fns ...KeyFunc) KeyFunc {
+	return func(c netContext.Context) string {
+		parts := make([]string, 0, len(fns))
+		for _, fn := range fns {
+			part := fn(c)
+			if part == "" {
+				return ""
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+type userKey struct{}
+
+// WithUser 写入当前请求的用户标识（供KeyByUser使用）
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext 获取当前请求的用户标识
+func UserFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// Policy 限流策略（限流器 + 默认规则 + 路由规则 + 限流维度）
+type Policy struct {
+	Limiter Limiter
+	Default Rule            // 默认规则（Rate<=0表示未单独配置的路由不限流）
+	Routes  map[string]Rule // 按路由单独配置的规则（键为HTTP路径/WS action/gRPC方法）
+	Key     KeyFunc         // 限流维度（默认KeyByIP）
+}
+
+// Check 判定请求是否放行（限流器出错时放行并记录日志，避免Redis故障导致服务不可用）
+func (p *Policy) Check(c netContext.Context) Result {
+	path := c.GetRequestInfo().GetPath()
+	rule, routeRule := p.Routes[path]
+	if !routeRule {
+		rule = p.Default
+	}
+	if rule.Rate <= 0 {
+		return Result{Allowed: true}
+	}
+	keyFn := p.Key
+	if keyFn == nil {
+		keyFn = KeyByIP
+	}
+	key := keyFn(c)
+	if key == "" {
+		return Result{Allowed: true}
+	}
+	// 路由单独配置的规则按路由独立计数
+	if routeRule {
+		key += "|route:" + path
+	}
+	result, err := p.Limiter.Allow(c.GetContext(), key, rule)
+	if err != nil {
+		logger.FromContext(c.GetContext()).Warn("限流判定失败，已放行：", err, "key：", key)
+		return Result{Allowed: true, Limit: rule.Rate}
+	}
+	return result
+}
+
+var (
+	appPolicies sync.Map // appName -> *Policy（同一应用的HTTP/WS/gRPC共用计数）
+	policyMu    sync.Mutex
+)
+
+// FromAppConfig 按应用配置创建限流策略（未启用时返回nil，同一应用多次调用返回同一实例）
+func FromAppConfig(appName string) (*Policy, error) {
+	if p, ok := appPolicies.Load(appName); ok {
+		return p.(*Policy), nil
+	}
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	if p, ok := appPolicies.Load(appName); ok {
+		return p.(*Policy), nil
+	}
+	cfg := config.GetAppConfig(appName).RateLimit
+	if !cfg.Enable {
+		return nil, nil
+	}
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	appPolicies.Store(appName, p)
+	return p, nil
+}
+
+// NewPolicy 按配置创建限流策略
+func NewPolicy(cfg config.RateLimitConfig) (*Policy, error) {
+	p := &Policy{
+		Default: ruleFromConfig(config.RateLimitRule{Rate: cfg.Rate, Period: cfg.Period, Burst: cfg.Burst}),
+		Routes:  make(map[string]Rule, len(cfg.Routes)),
+	}
+	for route, rule := range cfg.Routes {
+		p.Routes[route] = ruleFromConfig(rule)
+	}
+	proxies, err := ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	ipKey := KeyFunc(KeyByIP)
+	if len(proxies) > 0 {
+		ipKey = KeyByTrustedIP(proxies)
+	}
+	switch strings.ToLower(cfg.Store) {
+	case "", "memory":
+		p.Limiter = NewMemoryLimiter(cfg.MaxKeys)
+	case "redis":
+		if cfg.RedisDb == "" {
+			return nil, errors.New("限流使用redis存储时必须配置redis_db")
+		}
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return nil, err
+		}
+		p.Limiter = NewRedisLimiter(rdb)
+	default:
+		return nil, fmt.Errorf("不支持的限流存储：%s", cfg.Store)
+	}
+	keyFns := make([]KeyFunc, 0, 2)
+	for _, name := range strings.Split(cfg.Key, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "", "ip":
+			keyFns = append(keyFns, ipKey)
+		case "route":
+			keyFns = append(keyFns, KeyByRoute)
+		case "user":
+			keyFns = append(keyFns, keyByUser(ipKey))
+		default:
+			return nil, fmt.Errorf("不支持的限流维度：%s", name)
+		}
+	}
+	p.Key = Keys(keyFns...)
+	return p, nil
+}
+
+func ruleFromConfig(cfg config.RateLimitRule) Rule {
+	rule := Rule{Rate: cfg.Rate, Period: time.Duration(cfg.Period) * time.Second, Burst: cfg.Burst}
+	return rule.normalize()
+}
+
+// normalize 补齐默认值（Period默认1秒，Burst默认等于Rate）
+func (r Rule) normalize() Rule {
+	if r.Period <= 0 {
+		r.Period = time.Second
+	}
+	if r.Burst <= 0 {
+		r.Burst = r.Rate
+	}
+	return r
+}
@@ -0,0 +1,110 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dfpopp/go-dai/config"
+	daiHttp "github.com/dfpopp/go-dai/http"
+	"github.com/dfpopp/go-dai/ratelimit"
+)
+
+func httpContext(remote, forwarded string) *daiHttp.Context {
+	req := httptest.NewRequest("GET", "/api/list", nil)
+	req.RemoteAddr = remote
+	if forwarded != "" {
+		req.Header.Set("X-Forwarded-For", forwarded)
+	}
+	return daiHttp.NewContext(httptest.NewRecorder(), req)
+}
+
+func TestKeyByIPIgnoresForwardedHeaders(t *testing.T) {
+	c := httpContext("203.0.113.7:5123", "1.2.3.4")
+	c.Req.Header.Set("X-Real-IP", "5.6.7.8")
+	if got := ratelimit.KeyByIP(c); got != "ip:203.0.113.7" {
+		t.Fatalf("KeyByIP=%q，期望使用对端IP", got)
+	}
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	proxies, err := ratelimit.ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		remote, forwarded, want string
+	}{
+		{"10.0.0.5:80", "198.51.100.9", "198.51.100.9"},                    // 受信任代理转发
+		{"10.0.0.5:80", "6.6.6.6, 198.51.100.9, 10.0.0.2", "198.51.100.9"}, // 跳过受信任代理，不采信客户端伪造的最左侧地址
+		{"10.0.0.5:80", "10.0.0.3, 10.0.0.2", "10.0.0.3"},                  // 全部为代理时取最左侧
+		{"192.168.1.1:80", "198.51.100.9", "198.51.100.9"},                 // 单个IP
+		{"203.0.113.7:80", "198.51.100.9", "203.0.113.7"},                  // 非受信任对端不采信请求头
+		{"10.0.0.5:80", "", "10.0.0.5"},                                    // 无转发头
+	}
+	for _, tc := range cases {
+		if got := proxies.ClientIP(httpContext(tc.remote, tc.forwarded)); got != tc.want {
+			t.Errorf("remote=%s xff=%q: got %s, want %s", tc.remote, tc.forwarded, got, tc.want)
+		}
+	}
+	if _, err := ratelimit.ParseTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Fatal("无效地址应返回错误")
+	}
+}
+
+func TestPolicyRotatingForwardedForCannotBypass(t *testing.T) {
+	policy, err := ratelimit.NewPolicy(config.RateLimitConfig{Enable: true, Rate: 2, Period: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if policy.Check(httpContext("203.0.113.7:5000", "1.1.1."+strconv.Itoa(i))).Allowed {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Fatalf("伪造X-Forwarded-For放行了%d次，期望2次", allowed)
+	}
+}
+
+func TestPolicyUserKeyFallsBackToTrustedIP(t *testing.T) {
+	policy, err := ratelimit.NewPolicy(config.RateLimitConfig{Enable: true, Rate: 1, Period: 60, Key: "user", TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.Check(httpContext("10.0.0.1:80", "198.51.100.1")).Allowed || !policy.Check(httpContext("10.0.0.1:80", "198.51.100.2")).Allowed {
+		t.Fatal("经受信任代理的不同客户端应分别计数")
+	}
+	c := httpContext("10.0.0.1:80", "198.51.100.1")
+	c.SetContext(ratelimit.WithUser(c.GetContext(), "u1"))
+	if !policy.Check(c).Allowed {
+		t.Fatal("已登录用户按用户计数")
+	}
+	if policy.Check(c).Allowed {
+		t.Fatal("同一用户第二次应被限流")
+	}
+}
+
+func TestMemoryLimiterBoundedKeys(t *testing.T) {
+	l := ratelimit.NewMemoryLimiter(100)
+	rule := ratelimit.Rule{Rate: 1, Period: time.Hour}
+	ctx := context.Background()
+	if res, _ := l.Allow(ctx, "hot", rule); !res.Allowed {
+		t.Fatal("首次应放行")
+	}
+	for i := 0; i < 1000; i++ {
+		_, _ = l.Allow(ctx, "ip:"+strconv.Itoa(i), rule)
+		if i%50 == 0 {
+			// 持续访问的键不会被淘汰
+			if res, _ := l.Allow(ctx, "hot", rule); res.Allowed {
+				t.Fatal("活跃键被淘汰后重新计数")
+			}
+		}
+	}
+	if n := l.Len(); n > 100 {
+		t.Fatalf("保留%d个键，超过上限100", n)
+	}
+}
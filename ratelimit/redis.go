@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/go-redis/redis"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// redisKeyPrefix Redis键前缀（会再拼接Redis表前缀）
+const redisKeyPrefix = "ratelimit:"
+
+// slidingWindowScript 滑动窗口：清理窗口外的记录，未达上限时记录本次请求；
+// 返回 {是否放行, 剩余次数, 需等待毫秒数}
+var slidingWindowScript = redis.NewScript(`local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[4])
+  redis.call('PEXPIRE', KEYS[1], window)
+  return {1, limit - count - 1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+local wait = window
+if oldest[2] then wait = tonumber(oldest[2]) + window - now end
+return {0, 0, wait}`)
+
+// RedisLimiter Redis滑动窗口限流器（多实例共享计数，时间以各实例本地时钟为准，需保证时钟同步）
+type RedisLimiter struct {
+	rdb *redisDb.RedisDb
+}
+
+// NewRedisLimiter 创建Redis滑动窗口限流器
+func NewRedisLimiter(rdb *redisDb.RedisDb) *RedisLimiter {
+	return &RedisLimiter{rdb: rdb}
+}
+
+// Allow 判定请求是否放行（窗口长度为Period，窗口内最多Rate次；Burst不生效）
+func (l *RedisLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	rule = rule.normalize()
+	now := time.Now().UnixMilli()
+	member := strconv.FormatInt(now, 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+	res, err := slidingWindowScript.Run(l.rdb.WithContext(ctx).Db, []string{l.rdb.DbPre + redisKeyPrefix + key},
+		now, rule.Period.Milliseconds(), rule.Rate, member).Result()
+	if err != nil {
+		return Result{}, err
+	}
+	values, _ := res.([]interface{})
+	if len(values) < 3 {
+		return Result{Allowed: true, Limit: rule.Rate}, nil
+	}
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	wait, _ := values[2].(int64)
+	return Result{
+		Allowed:    allowed == 1,
+		Limit:      rule.Rate,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+	}, nil
+}
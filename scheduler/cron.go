@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 任务的执行时间表
+type Schedule interface {
+	// Next 严格晚于t的下一次执行时间（零值表示不再执行）
+	Next(t time.Time) time.Time
+}
+
+// ParseCron 解析cron表达式：
+//   - 5段：分 时 日 月 周（如"*/5 * * * *"每5分钟，"0 3 * * *"每天3点）
+//   - 6段：秒 分 时 日 月 周（如"30 0 * * * *"每小时的0分30秒）
+//   - 每段支持 *、?、a-b、*/n、a-b/n 及逗号列表，月与周可用英文缩写（JAN、MON），周日为0或7
+//   - 预定义：@yearly/@annually、@monthly、@weekly、@daily/@midnight、@hourly、@every <间隔>（如@every 30s）
+//
+// 日与周同时指定（均不为*或?）时满足其一即执行，与标准cron一致；loc为nil时使用本地时区
+func ParseCron(spec string, loc *time.Location) (Schedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("cron表达式[%s]间隔无效：%w", spec, err)
+		}
+		return Every(d), nil
+	}
+	switch spec {
+	case "@yearly", "@annually":
+		spec = "0 0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 0 * * *"
+	case "@hourly":
+		spec = "0 0 * * * *"
+	}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron表达式[%s]应为5段或6段", spec)
+	}
+	s := &cronSchedule{loc: loc}
+	var err error
+	targets := []*uint64{&s.second, &s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, f := range cronFields {
+		if *targets[i], err = parseField(fields[i], f); err != nil {
+			return nil, fmt.Errorf("cron表达式[%s]%s段无效：%w", spec, f.name, err)
+		}
+	}
+	s.domStar = fields[3] == "*" || fields[3] == "?"
+	s.dowStar = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+// Every 固定间隔的时间表（按间隔对齐到整点，如5分钟间隔在每个整5分钟执行，多实例的执行时间一致；间隔最小1秒）
+func Every(interval time.Duration) Schedule {
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return everySchedule(interval.Round(time.Second))
+}
+
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	return t.Truncate(d).Add(d)
+}
+
+// cronField 单段的取值范围
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "秒", min: 0, max: 59},
+	{name: "分", min: 0, max: 59},
+	{name: "时", min: 0, max: 23},
+	{name: "日", min: 1, max: 31},
+	{name: "月", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "周", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// parseField 解析单段为位图
+func parseField(expr string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepExpr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("步长[%s]无效", stepExpr)
+			}
+			step = n
+		}
+		var lo, hi int
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangeExpr, "-"):
+			a, b, _ := strings.Cut(rangeExpr, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := f.value(rangeExpr)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			if hasStep {
+				hi = f.max
+			}
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("范围[%s]起始值大于结束值", rangeExpr)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	// 周日可写作7
+	if f.max == 7 && bits&(1<<7) != 0 {
+		bits = bits&^(1<<7) | 1
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("取值[%s]超出范围%d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// cronSchedule cron表达式时间表（各段为取值位图）
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	domStar, dowStar                      bool
+	loc                                   *time.Location
+}
+
+// Next 逐级查找下一个匹配的时间（月 -> 日 -> 时 -> 分 -> 秒），进位时从上一级重新匹配；5年内无匹配时返回零值
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	added := false
+	yearLimit := t.Year() + 5
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for s.month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	for s.second&(1<<uint(t.Second())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+	return t
+}
+
+// dayMatches 日与周的匹配：任一为*时两者都须匹配，否则满足其一即可
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
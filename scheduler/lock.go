@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+// lockKeyPrefix Redis键前缀（会再拼接Redis表前缀）
+const lockKeyPrefix = "scheduler:"
+
+// lastFireTTL 最近执行时间记录的保留时长（只需长于各实例间的时钟偏差）
+const lastFireTTL = 24 * time.Hour
+
+// acquireScript 获取一次执行的锁：其他实例正在执行，或同一执行时间已被其他实例执行过时返回0
+var acquireScript = redis.NewScript(`if redis.call('EXISTS', KEYS[1]) == 1 then return 0 end
+local last = tonumber(redis.call('GET', KEYS[2]) or '0')
+if last >= tonumber(ARGV[3]) then return 0 end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('SET', KEYS[2], ARGV[3], 'PX', ARGV[4])
+return 1`)
+
+// renewScript 锁仍由当前实例持有时续期
+var renewScript = redis.NewScript(`if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`)
+
+// redisLock 任务的分布式锁：running为执行中锁（执行期间续期，结束后释放），last记录最近一次执行时间，
+// 同一执行时间在多个实例上只执行一次（各实例按相同的时间表计算执行时间）
+type redisLock struct {
+	rdb *redisDb.RedisDb
+	ttl time.Duration
+}
+
+// acquire 获取锁，成功时返回释放函数（执行期间按ttl/3续期）
+func (l *redisLock) acquire(ctx context.Context, job string, fire time.Time) (func(), bool, error) {
+	token, err := lockToken()
+	if err != nil {
+		return nil, false, err
+	}
+	running := lockKeyPrefix + job + ":running"
+	keys := []string{l.rdb.DbPre + running, l.rdb.DbPre + lockKeyPrefix + job + ":last"}
+	res, err := acquireScript.Run(l.rdb.WithContext(ctx).Db, keys,
+		token, l.ttl.Milliseconds(), fire.UnixMilli(), lastFireTTL.Milliseconds()).Int64()
+	if err != nil || res != 1 {
+		return nil, false, err
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_ = renewScript.Run(l.rdb.WithContext(context.Background()).Db, keys[:1], token, l.ttl.Milliseconds()).Err()
+			}
+		}
+	}()
+	release := func() {
+		close(stop)
+		<-done
+		_, _ = l.rdb.CompareAndDelete(context.Background(), running, token)
+	}
+	return release, true, nil
+}
+
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
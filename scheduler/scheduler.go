@@ -0,0 +1,370 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 定时任务：cron表达式或固定间隔触发，单次执行可设置超时与失败重试，任务panic只影响本次执行。
+// 配置了Redis时多个实例通过分布式锁保证同一次执行只在一个实例上进行（WithoutLock的任务在每个实例上执行）。
+// 调度器由bootstrap.Boot/BootCron按BootConfig.Jobs创建并启动，停机时等待执行中的任务完成：
+//
+//	Jobs: func(s *scheduler.Scheduler) error {
+//		if err := s.Cron("report.daily", "0 3 * * *", reportService.Daily, scheduler.WithTimeout(30*time.Minute)); err != nil {
+//			return err
+//		}
+//		return s.Every("counter.flush", 10*time.Second, func(ctx context.Context) error {
+//			_, err := viewCounter.Flush(ctx)
+//			return err
+//		})
+//	},
+
+// JobFunc 任务函数（ctx在超时或停机等待超时时取消，返回错误时按重试配置重试）
+type JobFunc func(ctx context.Context) error
+
+// ErrJobExists 任务名重复
+var ErrJobExists = errors.New("scheduler: 任务名重复")
+
+// JobOption 任务的可选配置
+type JobOption func(j *job)
+
+// WithTimeout 单次执行的超时时长（每次重试单独计时，默认不限制）
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(j *job) {
+		j.timeout = timeout
+	}
+}
+
+// WithRetry 执行失败（返回错误或panic）时最多重试attempts次，第i次重试前等待backoff*2^(i-1)
+func WithRetry(attempts int, backoff time.Duration) JobOption {
+	return func(j *job) {
+		j.retries = attempts
+		j.backoff = backoff
+	}
+}
+
+// WithoutLock 不使用分布式锁，每个实例都执行（如刷新进程内缓存）
+func WithoutLock() JobOption {
+	return func(j *job) {
+		j.noLock = true
+	}
+}
+
+// Options 调度器参数
+type Options struct {
+	Redis    *redisDb.RedisDb // 分布式锁使用的Redis（nil时不加锁，每个实例都执行）
+	LockTTL  time.Duration    // 锁过期时长（默认60秒，执行期间自动续期；实例崩溃后锁在该时长后释放）
+	Location *time.Location   // cron表达式的时区（默认本地时区）
+}
+
+// JobStatus 任务运行状态
+type JobStatus struct {
+	Name         string
+	Spec         string
+	Next         time.Time     // 下一次执行时间
+	LastRun      time.Time     // 最近一次在本实例开始执行的时间
+	LastDuration time.Duration // 最近一次执行耗时（含重试）
+	LastErr      error         // 最近一次执行的错误（成功时为nil）
+	Running      bool
+	Runs         int64 // 本实例执行次数
+	Failures     int64 // 本实例执行失败次数（重试全部失败计一次）
+	Skipped      int64 // 因上一次仍在执行或其他实例已执行而跳过的次数
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       JobFunc
+	timeout  time.Duration
+	retries  int
+	backoff  time.Duration
+	noLock   bool
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// Scheduler 定时任务调度器
+type Scheduler struct {
+	opts      Options
+	lock      *redisLock
+	overrides map[string]config.SchedulerJobConfig
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	started bool
+	stop    chan struct{}   // 关闭后不再触发新的执行
+	runCtx  context.Context // 执行中任务的ctx（停机等待超时时取消）
+	cancel  context.CancelFunc
+	loops   sync.WaitGroup // 各任务的调度协程
+	running sync.WaitGroup // 执行中的任务
+}
+
+// New 创建调度器
+func New(opts Options) *Scheduler {
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = time.Minute
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	s := &Scheduler{opts: opts, jobs: make(map[string]*job), stop: make(chan struct{})}
+	if opts.Redis != nil {
+		s.lock = &redisLock{rdb: opts.Redis, ttl: opts.LockTTL}
+	}
+	s.runCtx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// FromAppConfig 按应用配置（scheduler节点）创建调度器
+func FromAppConfig(appName string) (*Scheduler, error) {
+	cfg := config.GetAppConfig(appName).Scheduler
+	opts := Options{LockTTL: time.Duration(cfg.LockTTL) * time.Second}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("定时任务时区[%s]无效：%w", cfg.Timezone, err)
+		}
+		opts.Location = loc
+	}
+	if cfg.RedisDb != "" {
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return nil, err
+		}
+		opts.Redis = rdb
+	}
+	s := New(opts)
+	s.overrides = cfg.Jobs
+	return s, nil
+}
+
+// Cron 按cron表达式注册任务（表达式格式见ParseCron）
+func (s *Scheduler) Cron(name, spec string, fn JobFunc, opts ...JobOption) error {
+	if o, ok := s.overrides[name]; ok && o.Spec != "" {
+		spec = o.Spec
+	}
+	schedule, err := ParseCron(spec, s.opts.Location)
+	if err != nil {
+		return err
+	}
+	return s.add(name, spec, schedule, fn, opts)
+}
+
+// Every 按固定间隔注册任务（执行时间对齐到间隔的整数倍，见scheduler.Every）
+func (s *Scheduler) Every(name string, interval time.Duration, fn JobFunc, opts ...JobOption) error {
+	if o, ok := s.overrides[name]; ok && o.Spec != "" {
+		return s.Cron(name, o.Spec, fn, opts...)
+	}
+	return s.add(name, "@every "+interval.String(), Every(interval), fn, opts)
+}
+
+// Add 按自定义时间表注册任务
+func (s *Scheduler) Add(name string, schedule Schedule, fn JobFunc, opts ...JobOption) error {
+	return s.add(name, "custom", schedule, fn, opts)
+}
+
+func (s *Scheduler) add(name, spec string, schedule Schedule, fn JobFunc, opts []JobOption) error {
+	if name == "" || fn == nil {
+		return errors.New("scheduler: 任务名与任务函数不能为空")
+	}
+	if o, ok := s.overrides[name]; ok && o.Disable {
+		logger.Info("定时任务[", name, "]已在配置中停用")
+		return nil
+	}
+	j := &job{name: name, spec: spec, schedule: schedule, fn: fn}
+	for _, opt := range opts {
+		opt(j)
+	}
+	j.status = JobStatus{Name: name, Spec: spec}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w：%s", ErrJobExists, name)
+	}
+	s.jobs[name] = j
+	if s.started {
+		s.startLoop(j)
+	}
+	return nil
+}
+
+// Start 启动调度（重复调用无效；启动后注册的任务立即开始调度）
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.startLoop(j)
+	}
+	logger.Info("定时任务调度已启动，任务数：", len(s.jobs))
+}
+
+// Stop 停止调度并等待执行中的任务完成；ctx到期时取消执行中任务的ctx并返回ctx错误
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	s.mu.Unlock()
+	s.loops.Wait()
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
+
+// Jobs 全部任务的运行状态（按任务名排序）
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	list := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		list = append(list, j.status)
+		j.mu.Unlock()
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, k int) bool {
+		return list[i].Name < list[k].Name
+	})
+	return list
+}
+
+// startLoop 启动任务的调度协程（调用方持有s.mu）
+func (s *Scheduler) startLoop(j *job) {
+	s.loops.Add(1)
+	go func() {
+		defer s.loops.Done()
+		for {
+			next := j.schedule.Next(time.Now())
+			j.mu.Lock()
+			j.status.Next = next
+			j.mu.Unlock()
+			if next.IsZero() {
+				return
+			}
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			s.fire(j, next)
+		}
+	}()
+}
+
+// fire 到达执行时间：上一次仍在执行时跳过，否则异步执行（不阻塞下一次调度）
+func (s *Scheduler) fire(j *job, at time.Time) {
+	j.mu.Lock()
+	if j.status.Running {
+		j.status.Skipped++
+		j.mu.Unlock()
+		logger.Warn("定时任务[", j.name, "]上一次执行尚未结束，跳过本次：", at.Format(time.DateTime))
+		return
+	}
+	j.status.Running = true
+	j.mu.Unlock()
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer func() {
+			j.mu.Lock()
+			j.status.Running = false
+			j.mu.Unlock()
+		}()
+		s.run(j, at)
+	}()
+}
+
+// run 获取分布式锁后执行任务（含重试）
+func (s *Scheduler) run(j *job, at time.Time) {
+	if s.lock != nil && !j.noLock {
+		release, ok, err := s.lock.acquire(s.runCtx, j.name, at)
+		if err != nil {
+			logger.Error("定时任务[", j.name, "]获取分布式锁失败，跳过本次：", err)
+			return
+		}
+		if !ok {
+			j.mu.Lock()
+			j.status.Skipped++
+			j.mu.Unlock()
+			logger.Debug("定时任务[", j.name, "]已由其他实例执行：", at.Format(time.DateTime))
+			return
+		}
+		defer release()
+	}
+	start := time.Now()
+	err := s.attempt(j)
+	for i := 0; err != nil && i < j.retries; i++ {
+		wait := j.backoff << i
+		logger.Warn("定时任务[", j.name, "]执行失败，", wait, "后第", i+1, "次重试：", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.runCtx.Done():
+			timer.Stop()
+		}
+		if s.runCtx.Err() != nil {
+			break
+		}
+		err = s.attempt(j)
+	}
+	elapsed := time.Since(start)
+	j.mu.Lock()
+	j.status.LastRun = start
+	j.status.LastDuration = elapsed
+	j.status.LastErr = err
+	j.status.Runs++
+	if err != nil {
+		j.status.Failures++
+	}
+	j.mu.Unlock()
+	if err != nil {
+		logger.Error("定时任务[", j.name, "]执行失败：", err, "，耗时：", elapsed)
+		return
+	}
+	logger.Debug("定时任务[", j.name, "]执行完成，耗时：", elapsed)
+}
+
+// attempt 执行一次任务（超时控制，panic转换为错误并记录堆栈）
+func (s *Scheduler) attempt(j *job) (err error) {
+	ctx := s.runCtx
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+	ctx = logger.WithContext(ctx, logger.FromContext(ctx).WithField("job", j.name))
+	defer func() {
+		if p := recover(); p != nil {
+			logger.Error("定时任务[", j.name, "]panic：", p, "\n", string(debug.Stack()))
+			err = fmt.Errorf("scheduler: 任务panic：%v", p)
+		}
+	}()
+	return j.fn(ctx)
+}
@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRegistry 按应用配置初始化的全局注册中心
+var defaultRegistry atomic.Pointer[Registry]
+
+// InitRegistry 按应用配置（schema节点）初始化全局注册中心（未启用时不做任何事），由bootstrap在数据库连接就绪后调用
+func InitRegistry(appName string) error {
+	appCfg := config.GetAppConfig(appName)
+	if appCfg == nil || !appCfg.Schema.Enable {
+		return nil
+	}
+	cfg := appCfg.Schema
+	var store Store
+	switch cfg.Store {
+	case "", "redis":
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return fmt.Errorf("schema: 注册中心初始化失败：%w", err)
+		}
+		store = NewRedisStore(rdb, cfg.Prefix)
+	case "memory":
+		store = NewMemoryStore()
+	default:
+		return fmt.Errorf("schema: 不支持的存储类型 %q", cfg.Store)
+	}
+	opts := Options{
+		Compatibility: Compatibility(strings.ToUpper(cfg.Compatibility)),
+		Transitive:    cfg.Transitive,
+		CacheTTL:      time.Duration(cfg.CacheTTL) * time.Second,
+		Subjects:      make(map[string]Compatibility, len(cfg.Subjects)),
+	}
+	for subject, mode := range cfg.Subjects {
+		opts.Subjects[subject] = Compatibility(strings.ToUpper(mode))
+	}
+	for _, mode := range append([]Compatibility{opts.Compatibility}, mapValues(opts.Subjects)...) {
+		switch mode {
+		case "", CompatNone, CompatBackward, CompatForward, CompatFull:
+		default:
+			return fmt.Errorf("schema: 不支持的兼容性策略 %q", mode)
+		}
+	}
+	defaultRegistry.Store(NewRegistry(store, opts))
+	return nil
+}
+
+// Default 全局注册中心（未启用时为nil）
+func Default() *Registry {
+	return defaultRegistry.Load()
+}
+
+// SetDefault 替换全局注册中心（如测试或自定义存储）
+func SetDefault(r *Registry) {
+	defaultRegistry.Store(r)
+}
+
+func mapValues(m map[string]Compatibility) []Compatibility {
+	values := make([]Compatibility, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,288 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchema 支持的JSON Schema子集
+type jsonSchema struct {
+	Type                 typeList               `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// typeList type关键字（单个类型或类型数组）
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = typeList{single}
+		return nil
+	}
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return fmt.Errorf("type须为字符串或字符串数组")
+	}
+	*t = multi
+	return nil
+}
+
+func (t typeList) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// allows 是否允许该类型（未声明type时允许任意类型；number包含integer）
+func (t typeList) allows(name string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, v := range t {
+		if v == name || (v == "number" && name == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseJSONSchema(definition []byte) (*jsonSchema, error) {
+	s := new(jsonSchema)
+	if err := json.Unmarshal(definition, s); err != nil {
+		return nil, fmt.Errorf("schema: JSON Schema格式错误：%w", err)
+	}
+	if err := s.compile("$"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compile 检查类型名并预编译pattern
+func (s *jsonSchema) compile(path string) error {
+	for _, t := range s.Type {
+		switch t {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return fmt.Errorf("schema: %s 不支持的类型 %q", path, t)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("schema: %s pattern无效：%w", path, err)
+		}
+		s.pattern = re
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("schema: %s.%s 定义为空", path, name)
+		}
+		if err := prop.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// validate 校验JSON值（v为encoding/json解码的通用值）
+func (s *jsonSchema) validate(path string, v interface{}, errs []string) []string {
+	name := jsonTypeOf(v)
+	if !s.Type.allows(name) {
+		return append(errs, fmt.Sprintf("%s 类型应为%s，实际为%s", path, strings.Join(s.Type, "/"), name))
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, v) {
+		errs = append(errs, fmt.Sprintf("%s 取值不在枚举范围内", path))
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, field := range s.Required {
+			if _, ok := val[field]; !ok {
+				errs = append(errs, fmt.Sprintf("%s.%s 为必填字段", path, field))
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				errs = prop.validate(path+"."+k, val[k], errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs = append(errs, fmt.Sprintf("%s.%s 为未定义的字段", path, k))
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range val {
+				errs = s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(val)
+		if s.MinLength != nil && length < *s.MinLength {
+			errs = append(errs, fmt.Sprintf("%s 长度不能小于%d", path, *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			errs = append(errs, fmt.Sprintf("%s 长度不能大于%d", path, *s.MaxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			errs = append(errs, fmt.Sprintf("%s 格式不匹配%s", path, s.Pattern))
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			errs = append(errs, fmt.Sprintf("%s 不能小于%v", path, *s.Minimum))
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			errs = append(errs, fmt.Sprintf("%s 不能大于%v", path, *s.Maximum))
+		}
+	}
+	return errs
+}
+
+// canRead 读取方（s）能否接受写入方（w）产生的所有数据
+func (s *jsonSchema) canRead(w *jsonSchema, path string, reasons []string) []string {
+	if len(s.Type) > 0 {
+		if len(w.Type) == 0 {
+			reasons = append(reasons, fmt.Sprintf("%s 类型由任意类型收窄为%s", path, strings.Join(s.Type, "/")))
+		}
+		for _, t := range w.Type {
+			if !s.Type.allows(t) {
+				reasons = append(reasons, fmt.Sprintf("%s 不再接受%s类型", path, t))
+			}
+		}
+	}
+	for _, field := range s.Required {
+		if !contains(w.Required, field) {
+			reasons = append(reasons, fmt.Sprintf("%s.%s 为必填字段，但对方版本未保证提供", path, field))
+		}
+	}
+	if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+		for name := range w.Properties {
+			if _, ok := s.Properties[name]; !ok {
+				reasons = append(reasons, fmt.Sprintf("%s.%s 字段不被接受（additionalProperties为false）", path, name))
+			}
+		}
+	}
+	if len(s.Enum) > 0 {
+		if len(w.Enum) == 0 {
+			reasons = append(reasons, fmt.Sprintf("%s 新增了枚举限制", path))
+		}
+		for _, v := range w.Enum {
+			if !containsValue(s.Enum, v) {
+				reasons = append(reasons, fmt.Sprintf("%s 不接受枚举值%v", path, v))
+			}
+		}
+	}
+	if tighter(s.Minimum, w.Minimum, false) {
+		reasons = append(reasons, fmt.Sprintf("%s 最小值限制收紧", path))
+	}
+	if tighter(s.Maximum, w.Maximum, true) {
+		reasons = append(reasons, fmt.Sprintf("%s 最大值限制收紧", path))
+	}
+	if tighter(intPtrToFloat(s.MinLength), intPtrToFloat(w.MinLength), false) {
+		reasons = append(reasons, fmt.Sprintf("%s 最小长度限制收紧", path))
+	}
+	if tighter(intPtrToFloat(s.MaxLength), intPtrToFloat(w.MaxLength), true) {
+		reasons = append(reasons, fmt.Sprintf("%s 最大长度限制收紧", path))
+	}
+	if s.Pattern != "" && s.Pattern != w.Pattern {
+		reasons = append(reasons, fmt.Sprintf("%s 格式限制变更", path))
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if wp, ok := w.Properties[name]; ok {
+			reasons = s.Properties[name].canRead(wp, path+"."+name, reasons)
+		}
+	}
+	if s.Items != nil && w.Items != nil {
+		reasons = s.Items.canRead(w.Items, path+"[]", reasons)
+	}
+	return reasons
+}
+
+// tighter 读取方的范围限制是否比写入方更严（upper为上限）
+func tighter(reader, writer *float64, upper bool) bool {
+	if reader == nil {
+		return false
+	}
+	if writer == nil {
+		return true
+	}
+	if upper {
+		return *reader < *writer
+	}
+	return *reader > *writer
+}
+
+func intPtrToFloat(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"strings"
+)
+
+// protoDefinition proto结构定义的存储格式
+type protoDefinition struct {
+	Message string `json:"message"` // 消息全名
+	Files   []byte `json:"files"`   // 消息所在文件及其依赖的FileDescriptorSet
+}
+
+// protoSchema 解析后的proto结构定义
+type protoSchema struct {
+	desc protoreflect.MessageDescriptor
+}
+
+// ProtoSchema 由protobuf消息生成结构定义（描述符随定义保存，校验与比对时无需依赖生成代码）
+func ProtoSchema(subject string, msg proto.Message) (*Schema, error) {
+	desc := msg.ProtoReflect().Descriptor()
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var collect func(fd protoreflect.FileDescriptor)
+	collect = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			collect(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	collect(desc.ParentFile())
+	files, err := proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := json.Marshal(protoDefinition{Message: string(desc.FullName()), Files: files})
+	if err != nil {
+		return nil, err
+	}
+	return newSchema(subject, KindProto, definition)
+}
+
+func parseProtoSchema(definition []byte) (*protoSchema, error) {
+	var def protoDefinition
+	if err := json.Unmarshal(definition, &def); err != nil {
+		return nil, fmt.Errorf("schema: proto结构定义格式错误：%w", err)
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(def.Files, set); err != nil {
+		return nil, fmt.Errorf("schema: proto描述符解析失败：%w", err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("schema: proto描述符解析失败：%w", err)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(def.Message))
+	if err != nil {
+		return nil, fmt.Errorf("schema: 描述符中不存在消息%s", def.Message)
+	}
+	desc, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("schema: %s 不是消息类型", def.Message)
+	}
+	return &protoSchema{desc: desc}, nil
+}
+
+// validate 以JSON（protojson，不接受未定义字段）或protobuf二进制解码载荷（载荷为合法JSON时按JSON解码）
+func (s *protoSchema) validate(payload []byte) []string {
+	msg := dynamicpb.NewMessage(s.desc)
+	var err error
+	if json.Valid(payload) {
+		err = protojson.UnmarshalOptions{Resolver: dynamicResolver{s.desc.ParentFile()}}.Unmarshal(payload, msg)
+	} else {
+		err = proto.Unmarshal(payload, msg)
+	}
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// canRead 读取方（s）能否解析写入方（w）的消息：同一字段编号的名称、类型与重复性须一致，
+// 枚举须包含写入方的全部取值；仅一方存在的字段按未知字段/默认值处理，视为兼容
+func (s *protoSchema) canRead(w *protoSchema) []string {
+	if s.desc.FullName() != w.desc.FullName() {
+		return []string{fmt.Sprintf("消息由%s变为%s", w.desc.FullName(), s.desc.FullName())}
+	}
+	return compareMessages(s.desc, w.desc, string(s.desc.Name()), make(map[protoreflect.FullName]bool), nil)
+}
+
+func compareMessages(reader, writer protoreflect.MessageDescriptor, path string, visited map[protoreflect.FullName]bool, reasons []string) []string {
+	if visited[reader.FullName()] {
+		return reasons
+	}
+	visited[reader.FullName()] = true
+	fields := reader.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		rf := fields.Get(i)
+		wf := writer.Fields().ByNumber(rf.Number())
+		if wf == nil {
+			continue
+		}
+		fieldPath := fmt.Sprintf("%s.%s(%d)", path, rf.Name(), rf.Number())
+		switch {
+		case rf.Name() != wf.Name():
+			reasons = append(reasons, fmt.Sprintf("%s 字段名与对方版本的%s不一致（JSON格式不兼容）", fieldPath, wf.Name()))
+		case rf.Kind() != wf.Kind():
+			reasons = append(reasons, fmt.Sprintf("%s 类型由%s变为%s", fieldPath, wf.Kind(), rf.Kind()))
+		case rf.Cardinality() != wf.Cardinality() || rf.IsMap() != wf.IsMap():
+			reasons = append(reasons, fmt.Sprintf("%s 重复性（repeated/map）不一致", fieldPath))
+		case rf.Message() != nil:
+			if rf.Message().FullName() != wf.Message().FullName() {
+				reasons = append(reasons, fmt.Sprintf("%s 消息类型由%s变为%s", fieldPath, wf.Message().FullName(), rf.Message().FullName()))
+			} else {
+				reasons = compareMessages(rf.Message(), wf.Message(), fieldPath, visited, reasons)
+			}
+		case rf.Enum() != nil:
+			values := wf.Enum().Values()
+			for j := 0; j < values.Len(); j++ {
+				if rf.Enum().Values().ByNumber(values.Get(j).Number()) == nil {
+					reasons = append(reasons, fmt.Sprintf("%s 不接受枚举值%s", fieldPath, values.Get(j).Name()))
+				}
+			}
+		}
+	}
+	return reasons
+}
+
+// dynamicResolver 在结构定义自带的描述符中解析Any等类型引用，找不到时回退到全局注册表
+type dynamicResolver struct {
+	file protoreflect.FileDescriptor
+}
+
+func (r dynamicResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if d := findMessage(r.file, name, make(map[string]bool)); d != nil {
+		return dynamicpb.NewMessageType(d), nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (r dynamicResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	if i := strings.LastIndexByte(url, '/'); i >= 0 {
+		name = url[i+1:]
+	}
+	return r.FindMessageByName(protoreflect.FullName(name))
+}
+
+func (r dynamicResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r dynamicResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
+
+func findMessage(fd protoreflect.FileDescriptor, name protoreflect.FullName, seen map[string]bool) protoreflect.MessageDescriptor {
+	if seen[fd.Path()] {
+		return nil
+	}
+	seen[fd.Path()] = true
+	if d := fd.Messages().ByName(name.Name()); d != nil && d.FullName() == name {
+		return d
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if d := findMessage(imports.Get(i).FileDescriptor, name, seen); d != nil {
+			return d
+		}
+	}
+	return nil
+}
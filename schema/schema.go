@@ -0,0 +1,444 @@
+package schema
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema注册中心：gRPC强类型方法与消息队列生产者/消费者共用的载荷结构定义（JSON Schema或protobuf描述符），
+// 按subject（如 order.created、/order.OrderService/Create#request）分版本登记。
+// 注册新版本时按兼容性策略与已有版本比对，不兼容的变更在注册（服务启动/CI）时即被拒绝；
+// 生产者发布前按最新版本校验载荷，结构错误在发布端暴露，而不是在消费端解析失败。
+
+// Kind 结构定义类型
+type Kind string
+
+const (
+	KindJSON  Kind = "json"  // JSON Schema（支持type/properties/required/additionalProperties/items/enum/长度与数值范围/pattern）
+	KindProto Kind = "proto" // protobuf消息描述符
+)
+
+// Compatibility 兼容性策略
+type Compatibility string
+
+const (
+	CompatNone     Compatibility = "NONE"     // 不检查
+	CompatBackward Compatibility = "BACKWARD" // 新版本可读取旧版本写入的数据（先升级消费者，默认）
+	CompatForward  Compatibility = "FORWARD"  // 旧版本可读取新版本写入的数据（先升级生产者）
+	CompatFull     Compatibility = "FULL"     // 同时满足BACKWARD与FORWARD
+)
+
+// 注册中心错误
+var (
+	ErrNotFound = errors.New("schema: 结构定义不存在")
+	ErrConflict = errors.New("schema: 版本已被并发注册，请重试")
+)
+
+// Schema 某个subject的一个版本
+type Schema struct {
+	Subject     string          `json:"subject"`
+	Version     int             `json:"version"`
+	Kind        Kind            `json:"kind"`
+	Definition  json.RawMessage `json:"definition"` // JSON Schema文档；proto为{"message": 消息全名, "files": FileDescriptorSet}
+	Fingerprint string          `json:"fingerprint"`
+	CreatedAt   int64           `json:"created_at"`
+}
+
+// IncompatibleError 新版本与已有版本不兼容
+type IncompatibleError struct {
+	Subject string
+	Version int // 与之不兼容的已有版本
+	Mode    Compatibility
+	Reasons []string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("schema: %s 与版本%d不兼容（%s）：%s", e.Subject, e.Version, e.Mode, strings.Join(e.Reasons, "；"))
+}
+
+// ValidationError 载荷不符合结构定义
+type ValidationError struct {
+	Subject string
+	Version int
+	Errors  []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("schema: 载荷不符合 %s 版本%d：%s", e.Subject, e.Version, strings.Join(e.Errors, "；"))
+}
+
+// Store 结构定义存储（版本一经写入不可修改）
+type Store interface {
+	// Latest 最新版本（不存在时返回ErrNotFound）
+	Latest(ctx context.Context, subject string) (*Schema, error)
+	// Get 指定版本（不存在时返回ErrNotFound）
+	Get(ctx context.Context, subject string, version int) (*Schema, error)
+	// Lookup 按指纹查找已注册的版本（不存在时返回ErrNotFound）
+	Lookup(ctx context.Context, subject, fingerprint string) (*Schema, error)
+	// Versions 全部版本号（升序）
+	Versions(ctx context.Context, subject string) ([]int, error)
+	// Create 以expectedLatest+1为版本号写入（最新版本已不是expectedLatest时返回ErrConflict）
+	Create(ctx context.Context, s *Schema, expectedLatest int) error
+}
+
+// Options 注册中心配置
+type Options struct {
+	Compatibility Compatibility            // 默认兼容性策略（默认BACKWARD）
+	Subjects      map[string]Compatibility // 按subject覆盖兼容性策略
+	Transitive    bool                     // 与全部历史版本比对（默认仅比对最新版本）
+	CacheTTL      time.Duration            // 最新版本的本地缓存时长（默认30秒，<0不缓存）；历史版本不可变，始终缓存
+}
+
+// Registry Schema注册中心
+type Registry struct {
+	store Store
+	opts  Options
+
+	mu       sync.RWMutex
+	subjects map[string]Compatibility
+	latest   map[string]cachedSchema
+	versions map[string]*compiled // key：subject@version
+}
+
+type cachedSchema struct {
+	c       *compiled
+	expires time.Time
+}
+
+// compiled 解析后的结构定义（校验与兼容性比对使用）
+type compiled struct {
+	schema *Schema
+	json   *jsonSchema
+	proto  *protoSchema
+}
+
+// NewRegistry 创建注册中心
+func NewRegistry(store Store, opts Options) *Registry {
+	if opts.Compatibility == "" {
+		opts.Compatibility = CompatBackward
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = 30 * time.Second
+	}
+	subjects := make(map[string]Compatibility, len(opts.Subjects))
+	for subject, mode := range opts.Subjects {
+		subjects[subject] = mode
+	}
+	return &Registry{
+		store:    store,
+		opts:     opts,
+		subjects: subjects,
+		latest:   make(map[string]cachedSchema),
+		versions: make(map[string]*compiled),
+	}
+}
+
+// Store 底层存储
+func (r *Registry) Store() Store {
+	return r.store
+}
+
+// SetCompatibility 设置subject的兼容性策略
+func (r *Registry) SetCompatibility(subject string, mode Compatibility) {
+	r.mu.Lock()
+	r.subjects[subject] = mode
+	r.mu.Unlock()
+}
+
+// Compatibility subject生效的兼容性策略
+func (r *Registry) Compatibility(subject string) Compatibility {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if mode, ok := r.subjects[subject]; ok {
+		return mode
+	}
+	return r.opts.Compatibility
+}
+
+// JSONSchema 由JSON Schema文档创建结构定义
+func JSONSchema(subject string, definition []byte) (*Schema, error) {
+	return newSchema(subject, KindJSON, definition)
+}
+
+// Register 注册结构定义：与已注册版本完全相同时返回已有版本号；否则按兼容性策略检查后写入新版本
+func (r *Registry) Register(ctx context.Context, s *Schema) (int, error) {
+	c, err := compile(s)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if existing, err := r.store.Lookup(ctx, s.Subject, s.Fingerprint); err == nil {
+			return existing.Version, nil
+		} else if !errors.Is(err, ErrNotFound) {
+			return 0, err
+		}
+		latest, err := r.store.Latest(ctx, s.Subject)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return 0, err
+		}
+		expected := 0
+		if latest != nil {
+			expected = latest.Version
+			if err := r.check(ctx, c, latest); err != nil {
+				return 0, err
+			}
+		}
+		next := *s
+		next.Version = expected + 1
+		next.CreatedAt = time.Now().Unix()
+		if err := r.store.Create(ctx, &next, expected); err != nil {
+			if errors.Is(err, ErrConflict) {
+				continue
+			}
+			return 0, err
+		}
+		r.mu.Lock()
+		delete(r.latest, s.Subject)
+		r.mu.Unlock()
+		return next.Version, nil
+	}
+}
+
+// RegisterType 由Go类型或protobuf消息生成结构定义并注册（服务启动时调用，结构变更不兼容时启动失败）
+func (r *Registry) RegisterType(ctx context.Context, subject string, v interface{}) (int, error) {
+	s, err := SchemaOf(subject, v)
+	if err != nil {
+		return 0, err
+	}
+	return r.Register(ctx, s)
+}
+
+// Check 仅检查结构定义能否注册（不写入，用于CI检查）
+func (r *Registry) Check(ctx context.Context, s *Schema) error {
+	c, err := compile(s)
+	if err != nil {
+		return err
+	}
+	latest, err := r.store.Latest(ctx, s.Subject)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if latest.Fingerprint == s.Fingerprint {
+		return nil
+	}
+	return r.check(ctx, c, latest)
+}
+
+// check 按兼容性策略比对新定义与已有版本
+func (r *Registry) check(ctx context.Context, c *compiled, latest *Schema) error {
+	mode := r.Compatibility(c.schema.Subject)
+	if mode == CompatNone {
+		return nil
+	}
+	targets := []int{latest.Version}
+	if r.opts.Transitive {
+		versions, err := r.store.Versions(ctx, c.schema.Subject)
+		if err != nil {
+			return err
+		}
+		targets = versions
+	}
+	for i := len(targets) - 1; i >= 0; i-- {
+		old, err := r.version(ctx, c.schema.Subject, targets[i])
+		if err != nil {
+			return err
+		}
+		if reasons := compatible(old, c, mode); len(reasons) > 0 {
+			return &IncompatibleError{Subject: c.schema.Subject, Version: targets[i], Mode: mode, Reasons: reasons}
+		}
+	}
+	return nil
+}
+
+// Latest subject的最新版本（按CacheTTL缓存）
+func (r *Registry) Latest(ctx context.Context, subject string) (*Schema, error) {
+	c, err := r.latestCompiled(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	return c.schema, nil
+}
+
+// Get subject的指定版本
+func (r *Registry) Get(ctx context.Context, subject string, version int) (*Schema, error) {
+	c, err := r.version(ctx, subject, version)
+	if err != nil {
+		return nil, err
+	}
+	return c.schema, nil
+}
+
+// Validate 按最新版本校验载荷（JSON；proto定义同时接受protobuf二进制），返回校验所用的版本号
+func (r *Registry) Validate(ctx context.Context, subject string, payload []byte) (int, error) {
+	c, err := r.latestCompiled(ctx, subject)
+	if err != nil {
+		return 0, err
+	}
+	return c.schema.Version, c.validate(payload)
+}
+
+// ValidateVersion 按指定版本校验载荷（消费者按消息携带的版本号校验）
+func (r *Registry) ValidateVersion(ctx context.Context, subject string, version int, payload []byte) error {
+	c, err := r.version(ctx, subject, version)
+	if err != nil {
+		return err
+	}
+	return c.validate(payload)
+}
+
+// Marshal 序列化并按最新版本校验（生产者发布前调用，版本号随消息发送供消费者使用）：
+// protobuf消息按protojson序列化，其他类型按encoding/json序列化
+func (r *Registry) Marshal(ctx context.Context, subject string, v interface{}) ([]byte, int, error) {
+	var payload []byte
+	var err error
+	if m, ok := v.(proto.Message); ok {
+		payload, err = protojson.Marshal(m)
+	} else {
+		payload, err = json.Marshal(v)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	version, err := r.Validate(ctx, subject, payload)
+	if err != nil {
+		return nil, version, err
+	}
+	return payload, version, nil
+}
+
+func (r *Registry) latestCompiled(ctx context.Context, subject string) (*compiled, error) {
+	now := time.Now()
+	r.mu.RLock()
+	cached, ok := r.latest[subject]
+	r.mu.RUnlock()
+	if ok && now.Before(cached.expires) {
+		return cached.c, nil
+	}
+	s, err := r.store.Latest(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.version(ctx, subject, s.Version)
+	if err != nil {
+		return nil, err
+	}
+	if r.opts.CacheTTL > 0 {
+		r.mu.Lock()
+		r.latest[subject] = cachedSchema{c: c, expires: now.Add(r.opts.CacheTTL)}
+		r.mu.Unlock()
+	}
+	return c, nil
+}
+
+func (r *Registry) version(ctx context.Context, subject string, version int) (*compiled, error) {
+	key := fmt.Sprintf("%s@%d", subject, version)
+	r.mu.RLock()
+	c, ok := r.versions[key]
+	r.mu.RUnlock()
+	if ok {
+		return c, nil
+	}
+	s, err := r.store.Get(ctx, subject, version)
+	if err != nil {
+		return nil, err
+	}
+	if c, err = compile(s); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.versions[key] = c
+	r.mu.Unlock()
+	return c, nil
+}
+
+// newSchema 规范化定义并计算指纹（键排序后的JSON，格式与字段顺序不影响指纹）
+func newSchema(subject string, kind Kind, definition []byte) (*Schema, error) {
+	if subject == "" {
+		return nil, errors.New("schema: subject不能为空")
+	}
+	var v interface{}
+	if err := json.Unmarshal(definition, &v); err != nil {
+		return nil, fmt.Errorf("schema: 结构定义不是有效的JSON：%w", err)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte(kind+":"), canonical...))
+	return &Schema{Subject: subject, Kind: kind, Definition: canonical, Fingerprint: hex.EncodeToString(sum[:])}, nil
+}
+
+// compile 解析结构定义
+func compile(s *Schema) (*compiled, error) {
+	c := &compiled{schema: s}
+	var err error
+	switch s.Kind {
+	case KindJSON:
+		c.json, err = parseJSONSchema(s.Definition)
+	case KindProto:
+		c.proto, err = parseProtoSchema(s.Definition)
+	default:
+		err = fmt.Errorf("schema: 不支持的结构定义类型 %q", s.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *compiled) validate(payload []byte) error {
+	var errs []string
+	if c.proto != nil {
+		errs = c.proto.validate(payload)
+	} else {
+		var v interface{}
+		if err := json.Unmarshal(payload, &v); err != nil {
+			errs = []string{"不是有效的JSON：" + err.Error()}
+		} else {
+			errs = c.json.validate("$", v, nil)
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Subject: c.schema.Subject, Version: c.schema.Version, Errors: errs}
+	}
+	return nil
+}
+
+// compatible 按策略比对新旧版本，返回不兼容原因
+func compatible(old, next *compiled, mode Compatibility) []string {
+	if old.schema.Kind != next.schema.Kind {
+		return []string{fmt.Sprintf("结构定义类型由%s变为%s", old.schema.Kind, next.schema.Kind)}
+	}
+	var reasons []string
+	if mode == CompatBackward || mode == CompatFull {
+		for _, reason := range canRead(next, old) {
+			reasons = append(reasons, "新版本读取旧数据："+reason)
+		}
+	}
+	if mode == CompatForward || mode == CompatFull {
+		for _, reason := range canRead(old, next) {
+			reasons = append(reasons, "旧版本读取新数据："+reason)
+		}
+	}
+	return reasons
+}
+
+// canRead reader版本能否读取writer版本写入的数据
+func canRead(reader, writer *compiled) []string {
+	if reader.proto != nil {
+		return reader.proto.canRead(writer.proto)
+	}
+	return reader.json.canRead(writer.json, "$", nil)
+}
@@ -0,0 +1,185 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/go-redis/redis"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// MemoryStore 内存存储（单进程使用，或测试与CI检查）
+type MemoryStore struct {
+	mu       sync.RWMutex
+	subjects map[string][]*Schema
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{subjects: make(map[string][]*Schema)}
+}
+
+func (m *MemoryStore) Latest(_ context.Context, subject string) (*Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	versions := m.subjects[subject]
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return versions[len(versions)-1], nil
+}
+
+func (m *MemoryStore) Get(_ context.Context, subject string, version int) (*Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	versions := m.subjects[subject]
+	if version < 1 || version > len(versions) {
+		return nil, ErrNotFound
+	}
+	return versions[version-1], nil
+}
+
+func (m *MemoryStore) Lookup(_ context.Context, subject, fingerprint string) (*Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, s := range m.subjects[subject] {
+		if s.Fingerprint == fingerprint {
+			return s, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) Versions(_ context.Context, subject string) ([]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	versions := make([]int, len(m.subjects[subject]))
+	for i := range versions {
+		versions[i] = i + 1
+	}
+	return versions, nil
+}
+
+func (m *MemoryStore) Create(_ context.Context, s *Schema, expectedLatest int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.subjects[s.Subject]) != expectedLatest {
+		return ErrConflict
+	}
+	stored := *s
+	m.subjects[s.Subject] = append(m.subjects[s.Subject], &stored)
+	return nil
+}
+
+// redisKeyPrefix Redis键默认前缀（会再拼接Redis表前缀）
+const redisKeyPrefix = "schema:"
+
+// createScript 最新版本仍为预期值时写入新版本；返回0表示版本冲突
+var createScript = redis.NewScript(`local latest = tonumber(redis.call('GET', KEYS[1]) or '0')
+if latest ~= tonumber(ARGV[1]) then return 0 end
+local version = latest + 1
+redis.call('HSET', KEYS[2], version, ARGV[2])
+redis.call('HSET', KEYS[3], ARGV[3], version)
+redis.call('SET', KEYS[1], version)
+redis.call('SADD', KEYS[4], ARGV[4])
+return version`)
+
+// RedisStore Redis存储（多个服务共享同一注册中心）：
+// <prefix><subject>:latest 最新版本号，<prefix><subject>:versions 版本号->定义，<prefix><subject>:fingerprints 指纹->版本号，
+// <prefix>subjects 全部subject
+type RedisStore struct {
+	rdb    *redisDb.RedisDb
+	prefix string
+}
+
+// NewRedisStore 创建Redis存储（prefix为空时使用schema:）
+func NewRedisStore(rdb *redisDb.RedisDb, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = redisKeyPrefix
+	}
+	return &RedisStore{rdb: rdb, prefix: prefix}
+}
+
+func (r *RedisStore) key(subject, suffix string) string {
+	return r.rdb.DbPre + r.prefix + subject + ":" + suffix
+}
+
+func (r *RedisStore) Latest(ctx context.Context, subject string) (*Schema, error) {
+	version, err := r.rdb.WithContext(ctx).Db.Get(r.key(subject, "latest")).Int()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, subject, version)
+}
+
+func (r *RedisStore) Get(ctx context.Context, subject string, version int) (*Schema, error) {
+	data, err := r.rdb.WithContext(ctx).Db.HGet(r.key(subject, "versions"), strconv.Itoa(version)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := new(Schema)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *RedisStore) Lookup(ctx context.Context, subject, fingerprint string) (*Schema, error) {
+	version, err := r.rdb.WithContext(ctx).Db.HGet(r.key(subject, "fingerprints"), fingerprint).Int()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, subject, version)
+}
+
+func (r *RedisStore) Versions(ctx context.Context, subject string) ([]int, error) {
+	fields, err := r.rdb.WithContext(ctx).Db.HKeys(r.key(subject, "versions")).Result()
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int, 0, len(fields))
+	for _, field := range fields {
+		if v, err := strconv.Atoi(field); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+func (r *RedisStore) Create(ctx context.Context, s *Schema, expectedLatest int) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	keys := []string{r.key(s.Subject, "latest"), r.key(s.Subject, "versions"), r.key(s.Subject, "fingerprints"), r.rdb.DbPre + r.prefix + "subjects"}
+	version, err := createScript.Run(r.rdb.WithContext(ctx).Db, keys, expectedLatest, data, s.Fingerprint, s.Subject).Int()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Subjects 已注册的全部subject
+func (r *RedisStore) Subjects(ctx context.Context) ([]string, error) {
+	subjects, err := r.rdb.WithContext(ctx).Db.SMembers(r.rdb.DbPre + r.prefix + "subjects").Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(subjects)
+	return subjects, nil
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"google.golang.org/protobuf/proto"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaOf 由值的类型生成结构定义：protobuf消息生成proto定义；其他类型按encoding/json的序列化规则生成JSON Schema
+// （未标记omitempty的字段为必填，指针/切片/map字段允许null，不限制未定义字段以便演进）
+func SchemaOf(subject string, v interface{}) (*Schema, error) {
+	if m, ok := v.(proto.Message); ok {
+		return ProtoSchema(subject, m)
+	}
+	definition, err := json.Marshal(typeSchema(reflect.TypeOf(v), make(map[reflect.Type]bool)))
+	if err != nil {
+		return nil, err
+	}
+	return JSONSchema(subject, definition)
+}
+
+// typeSchema 生成Go类型对应的JSON Schema（递归类型在第二次出现时不再展开）
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) *jsonSchema {
+	if t == nil {
+		return &jsonSchema{}
+	}
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+	s := &jsonSchema{}
+	switch {
+	case t == timeType:
+		s.Type = typeList{"string"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return s
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		s.Type = typeList{"string"}
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			s.Type = typeList{"boolean"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s.Type = typeList{"integer"}
+		case reflect.Float32, reflect.Float64:
+			s.Type = typeList{"number"}
+		case reflect.String:
+			s.Type = typeList{"string"}
+		case reflect.Slice, reflect.Array:
+			if t.Elem().Kind() == reflect.Uint8 {
+				s.Type = typeList{"string"} // []byte按base64编码
+				break
+			}
+			s.Type = typeList{"array"}
+			s.Items = typeSchema(t.Elem(), visiting)
+			nullable = nullable || t.Kind() == reflect.Slice
+		case reflect.Map:
+			s.Type = typeList{"object"}
+			nullable = true
+		case reflect.Struct:
+			if visiting[t] {
+				return &jsonSchema{}
+			}
+			visiting[t] = true
+			s.Type = typeList{"object"}
+			s.Properties = make(map[string]*jsonSchema)
+			structFields(t, s, visiting)
+			delete(visiting, t)
+		default:
+			return s
+		}
+	}
+	if nullable && len(s.Type) > 0 {
+		s.Type = append(s.Type, "null")
+	}
+	return s
+}
+
+// structFields 按json标签收集结构体字段（匿名结构体字段展开到上层）
+func structFields(t reflect.Type, s *jsonSchema, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := field.Type
+		if field.Anonymous && name == "" {
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				structFields(ft, s, visiting)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		prop := typeSchema(ft, visiting)
+		if strings.Contains(opts, "string") && len(prop.Type) > 0 {
+			prop.Type = typeList{"string"}
+		}
+		s.Properties[name] = prop
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
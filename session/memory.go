@@ -0,0 +1,119 @@
+package session
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"sync"
+	"time"
+)
+
+// MemoryStore 进程内会话存储（单实例部署使用，重启后会话丢失；多实例请使用Redis）
+type MemoryStore struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	lastSweep time.Time
+}
+
+type memoryItem struct {
+	value    []byte
+	expireAt time.Time // 零值表示不过期
+}
+
+func (it memoryItem) expired(now time.Time) bool {
+	return !it.expireAt.IsZero() && now.After(it.expireAt)
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore 创建内存会话存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem), lastSweep: time.Now()}
+}
+
+// Get 读取会话数据（不存在或已过期返回redisDb.ErrNotFound）
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.items[key]
+	if !ok || it.expired(time.Now()) {
+		return nil, redisDb.ErrNotFound
+	}
+	return append([]byte(nil), it.value...), nil
+}
+
+// Set 写入会话数据（ttl<=0表示不过期）
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	it := memoryItem{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		it.expireAt = now.Add(ttl)
+	}
+	s.items[key] = it
+	return nil
+}
+
+// Delete 删除会话数据
+func (s *MemoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.items, key)
+	}
+	return nil
+}
+
+// TTL 剩余有效期（不过期返回-1）
+func (s *MemoryStore) TTL(_ context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	it, ok := s.items[key]
+	if !ok || it.expired(now) {
+		return 0, redisDb.ErrNotFound
+	}
+	if it.expireAt.IsZero() {
+		return -1, nil
+	}
+	return it.expireAt.Sub(now), nil
+}
+
+// Expire 刷新有效期，键不存在时返回false
+func (s *MemoryStore) Expire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	it, ok := s.items[key]
+	if !ok || it.expired(now) {
+		return false, nil
+	}
+	if ttl > 0 {
+		it.expireAt = now.Add(ttl)
+	} else {
+		it.expireAt = time.Time{}
+	}
+	s.items[key] = it
+	return true, nil
+}
+
+// Len 当前会话数（含尚未清理的过期会话）
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// sweep 写入时顺带清理过期会话（每分钟最多一次，需持有锁）
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, it := range s.items {
+		if it.expired(now) {
+			delete(s.items, key)
+		}
+	}
+}
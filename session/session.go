@@ -0,0 +1,311 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 服务端会话：会话ID保存在Cookie中，会话数据保存在可替换的存储中（内存/Redis）。
+// HTTP中间件与上下文方法见 http.Sessions、Context.Session。
+
+// Store 会话存储（与redisDb.Store一致，redisDb.KVStore可直接作为会话存储）
+type Store = redisDb.Store
+
+// Options 会话参数
+type Options struct {
+	CookieName string        // Cookie名（默认go_dai_session）
+	Path       string        // Cookie路径（默认/）
+	Domain     string        // Cookie域
+	MaxAge     time.Duration // 会话有效期（默认24小时）
+	Secure     bool          // 仅HTTPS传输
+	SameSite   http.SameSite // SameSite策略（默认Lax）
+	Rolling    bool          // 每次访问都刷新有效期（默认仅在会话数据变更时刷新）
+
+	DisableHttpOnly bool // 允许脚本读取会话Cookie（默认HttpOnly）
+}
+
+// Manager 会话管理器
+type Manager struct {
+	store Store
+	opts  Options
+}
+
+// NewManager 创建会话管理器
+func NewManager(store Store, opts Options) *Manager {
+	if opts.CookieName == "" {
+		opts.CookieName = "go_dai_session"
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 24 * time.Hour
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	return &Manager{store: store, opts: opts}
+}
+
+// Options 获取会话参数
+func (m *Manager) Options() Options {
+	return m.opts
+}
+
+// Start 根据请求Cookie创建会话句柄（会话数据在首次读写时才从存储加载）
+func (m *Manager) Start(r *http.Request) *Session {
+	s := &Session{manager: m, ctx: r.Context()}
+	if cookie, err := r.Cookie(m.opts.CookieName); err == nil && validID(cookie.Value) {
+		s.id = cookie.Value
+	}
+	return s
+}
+
+// Session 单个请求的会话句柄（非并发安全，仅在当前请求内使用）。
+// 从存储加载失败时（存储不可用等）会话只读为空，Set/Delete/Destroy/Regenerate不生效，Commit返回加载错误且不写入存储，
+// 避免用空会话覆盖用户已保存的数据；调用方通过Err判断
+type Session struct {
+	manager   *Manager
+	ctx       context.Context
+	id        string
+	values    map[string]interface{}
+	loaded    bool
+	dirty     bool
+	destroyed bool
+	oldID     string // Regenerate前的会话ID（提交时删除）
+	err       error  // 加载失败的错误
+}
+
+// ID 会话ID（新会话在首次写入前为空）
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Get 读取会话值（加载失败时返回false，原因见Err）
+func (s *Session) Get(key string) (interface{}, bool) {
+	if !s.load() {
+		return nil, false
+	}
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// GetString 读取字符串会话值
+func (s *Session) GetString(key string) string {
+	value, _ := s.Get(key)
+	str, _ := value.(string)
+	return str
+}
+
+// Set 写入会话值（值需可JSON序列化，从存储加载后数字类型为float64）
+func (s *Session) Set(key string, value interface{}) {
+	if !s.load() {
+		return
+	}
+	s.values[key] = value
+	s.dirty = true
+	s.destroyed = false
+}
+
+// Delete 删除会话值
+func (s *Session) Delete(key string) {
+	if !s.load() {
+		return
+	}
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+}
+
+// Destroy 销毁会话（删除存储中的数据并清除Cookie，如退出登录）
+func (s *Session) Destroy() {
+	if !s.load() {
+		return
+	}
+	s.values = make(map[string]interface{})
+	s.destroyed = true
+	s.dirty = false
+}
+
+// Regenerate 更换会话ID并保留数据（登录成功后调用，防止会话固定攻击）
+func (s *Session) Regenerate() {
+	if !s.load() {
+		return
+	}
+	if s.id != "" && s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = ""
+	s.dirty = true
+}
+
+// Err 加载会话数据时的错误（存储不可用等）
+func (s *Session) Err() error {
+	return s.err
+}
+
+// load 从存储加载会话数据（会话不存在或已过期时视为新会话），返回是否加载成功
+func (s *Session) load() bool {
+	if s.loaded {
+		return s.err == nil
+	}
+	s.loaded = true
+	s.values = make(map[string]interface{})
+	if s.id == "" {
+		return true
+	}
+	data, err := s.manager.store.Get(s.ctx, s.id)
+	if errors.Is(err, redisDb.ErrNotFound) {
+		s.id = "" // 过期或伪造的会话ID，重新生成
+		return true
+	}
+	if err != nil {
+		s.err = fmt.Errorf("会话加载失败：%w", err)
+		return false
+	}
+	if err := json.Unmarshal(data, &s.values); err != nil {
+		// 数据已损坏，按新会话处理并在提交时删除旧数据
+		s.values = make(map[string]interface{})
+		s.oldID, s.id = s.id, ""
+	}
+	return true
+}
+
+// Commit 保存会话并写入Cookie（需在写出响应头之前调用，http.Sessions中间件会自动调用）；加载失败时返回加载错误，不写入
+func (s *Session) Commit(w http.ResponseWriter) error {
+	if s.err != nil {
+		return s.err
+	}
+	m := s.manager
+	if s.oldID != "" {
+		_ = m.store.Delete(s.ctx, s.oldID)
+		s.oldID = ""
+	}
+	if s.destroyed {
+		if s.id != "" {
+			if err := m.store.Delete(s.ctx, s.id); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, m.cookie("", -1))
+		s.id, s.destroyed = "", false
+		return nil
+	}
+	if s.dirty {
+		if s.id == "" {
+			s.id = newID()
+		}
+		data, err := json.Marshal(s.values)
+		if err != nil {
+			return err
+		}
+		if err := m.store.Set(s.ctx, s.id, data, m.opts.MaxAge); err != nil {
+			return err
+		}
+		s.dirty = false
+	} else if m.opts.Rolling && s.id != "" {
+		if ok, err := m.store.Expire(s.ctx, s.id, m.opts.MaxAge); err != nil || !ok {
+			return err
+		}
+	} else {
+		return nil
+	}
+	http.SetCookie(w, m.cookie(s.id, int(m.opts.MaxAge/time.Second)))
+	return nil
+}
+
+// cookie 构造会话Cookie（maxAge<0表示删除）
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     m.opts.CookieName,
+		Value:    value,
+		Path:     m.opts.Path,
+		Domain:   m.opts.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.opts.Secure,
+		HttpOnly: !m.opts.DisableHttpOnly,
+		SameSite: m.opts.SameSite,
+	}
+}
+
+// newID 生成会话ID（256位随机数）
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// validID 校验Cookie中的会话ID格式（避免将任意输入作为存储键）
+func validID(id string) bool {
+	if len(id) != 43 {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(id)
+	return err == nil
+}
+
+var (
+	appManagers sync.Map // appName -> *Manager
+	managerMu   sync.Mutex
+)
+
+// FromAppConfig 按应用配置创建会话管理器（未启用时返回nil，同一应用多次调用返回同一实例）
+func FromAppConfig(appName string) (*Manager, error) {
+	if m, ok := appManagers.Load(appName); ok {
+		return m.(*Manager), nil
+	}
+	managerMu.Lock()
+	defer managerMu.Unlock()
+	if m, ok := appManagers.Load(appName); ok {
+		return m.(*Manager), nil
+	}
+	cfg := config.GetAppConfig(appName).Session
+	if !cfg.Enable {
+		return nil, nil
+	}
+	var store Store
+	switch strings.ToLower(cfg.Store) {
+	case "", "memory":
+		store = NewMemoryStore()
+	case "redis":
+		rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+		if err != nil {
+			return nil, err
+		}
+		store = rdb.NewStore("session:")
+	default:
+		return nil, fmt.Errorf("不支持的会话存储：%s", cfg.Store)
+	}
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(cfg.SameSite) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+	m := NewManager(store, Options{
+		CookieName:      cfg.CookieName,
+		Path:            cfg.Path,
+		Domain:          cfg.Domain,
+		MaxAge:          time.Duration(cfg.MaxAge) * time.Second,
+		Secure:          cfg.Secure,
+		SameSite:        sameSite,
+		Rolling:         cfg.Rolling,
+		DisableHttpOnly: cfg.DisableHttpOnly,
+	})
+	appManagers.Store(appName, m)
+	return m, nil
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// flakyStore 可模拟读取失败的内存存储
+type flakyStore struct {
+	*MemoryStore
+	getErr error
+}
+
+func (s *flakyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	return s.MemoryStore.Get(ctx, key)
+}
+
+// startWithCookie 以携带会话Cookie的请求创建会话句柄
+func startWithCookie(m *Manager, id string) *Session {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: m.Options().CookieName, Value: id})
+	return m.Start(req)
+}
+
+// 存储读取失败时写入不生效，Commit返回错误且不覆盖已保存的会话
+func TestSessionLoadFailureKeepsStoredData(t *testing.T) {
+	store := &flakyStore{MemoryStore: NewMemoryStore()}
+	m := NewManager(store, Options{})
+	id := newID()
+	if err := store.Set(context.Background(), id, []byte(`{"uid":"42"}`), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	store.getErr = errors.New("redis: connection refused")
+
+	s := startWithCookie(m, id)
+	if _, ok := s.Get("uid"); ok {
+		t.Fatal("加载失败时不应读到值")
+	}
+	s.Set("csrf", "t1")
+	s.Regenerate()
+	s.Destroy()
+	if s.Err() == nil {
+		t.Fatal("Err应返回加载错误")
+	}
+	w := httptest.NewRecorder()
+	if err := s.Commit(w); !errors.Is(err, store.getErr) {
+		t.Fatalf("Commit应返回加载错误，实际%v", err)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Fatal("加载失败时不应写Cookie")
+	}
+	data, err := store.MemoryStore.Get(context.Background(), id)
+	if err != nil || string(data) != `{"uid":"42"}` {
+		t.Fatalf("已保存的会话被修改：%s %v", data, err)
+	}
+}
+
+// 数据损坏时按新会话处理，提交时删除旧数据
+func TestSessionCorruptDataStartsNew(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewManager(store, Options{})
+	id := newID()
+	if err := store.Set(context.Background(), id, []byte(`not json`), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	s := startWithCookie(m, id)
+	s.Set("uid", "42")
+	if err := s.Commit(httptest.NewRecorder()); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID() == "" || s.ID() == id {
+		t.Fatalf("应生成新的会话ID：%q", s.ID())
+	}
+	if _, err := store.Get(context.Background(), id); err == nil {
+		t.Fatal("损坏的旧会话应被删除")
+	}
+	if data, err := store.Get(context.Background(), s.ID()); err != nil || string(data) != `{"uid":"42"}` {
+		t.Fatalf("新会话数据：%s %v", data, err)
+	}
+}
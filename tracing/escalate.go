@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"sync/atomic"
+)
+
+// 错误升级采样：请求内记录Error级别日志时，将该链路标记为强制采样，
+// 由tracing/sampling中的处理器把原本未采样（仅记录）的span一并导出，保证每条错误日志都能在后端查到完整链路。
+// 未安装sampling处理器时只在span上记录错误事件，不保留标记。
+
+var (
+	escalation atomic.Bool
+	forced     sync.Map // trace.TraceID -> struct{}
+)
+
+// EnableErrorEscalation 启用错误升级采样（由sampling.NewProcessor调用）
+func EnableErrorEscalation() {
+	escalation.Store(true)
+}
+
+// RecordLogError 在span上记录错误日志事件并设置错误状态，启用升级采样时将所在链路标记为强制采样
+func RecordLogError(span trace.Span, msg string) {
+	if span == nil {
+		return
+	}
+	spanCtx := span.SpanContext()
+	if !spanCtx.HasTraceID() {
+		return
+	}
+	if span.IsRecording() {
+		span.AddEvent("log.error", trace.WithAttributes(attribute.String("log.message", Summary(msg))))
+		span.SetStatus(codes.Error, Summary(msg))
+	}
+	if escalation.Load() && !spanCtx.IsSampled() {
+		forced.Store(spanCtx.TraceID(), struct{}{})
+	}
+}
+
+// IsForced 链路是否已被标记为强制采样
+func IsForced(traceID trace.TraceID) bool {
+	_, ok := forced.Load(traceID)
+	return ok
+}
+
+// ClearForced 清除强制采样标记（链路在本服务内的根span结束时调用）
+func ClearForced(traceID trace.TraceID) {
+	forced.Delete(traceID)
+}
@@ -0,0 +1,162 @@
+// Package sampling 提供与框架错误升级采样配合的OpenTelemetry SDK组件（仅在应用层注册SDK时使用）：
+//
+//	exporter, _ := otlptracegrpc.New(ctx)
+//	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+//		sdktrace.WithSampler(sampling.Sampler(0.05)),
+//		sdktrace.WithSpanProcessor(sampling.NewProcessor(sdktrace.NewBatchSpanProcessor(exporter), 0)),
+//	))
+//
+// 未命中采样比例的链路仍会在本进程内记录（RecordOnly），其span暂存在处理器中：
+// 请求内出现Error日志时整条链路补发给下游处理器导出，否则在本服务的根span结束时丢弃。
+// 升级仅作用于本服务，下游服务按各自收到的traceparent采样标志决定。
+package sampling
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"time"
+)
+
+// defaultMaxSpans 单条链路默认最多暂存的span数
+const defaultMaxSpans = 512
+
+// pendingTTL 暂存链路的最长保留时间（根span迟迟未结束时兜底清理）
+const pendingTTL = 5 * time.Minute
+
+// Sampler 按比例采样，未命中的链路降级为仅记录（而非丢弃），以便出现错误时补发；
+// 上游已采样的链路沿用上游决定
+func Sampler(ratio float64) sdktrace.Sampler {
+	return recordOnlySampler{base: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))}
+}
+
+type recordOnlySampler struct {
+	base sdktrace.Sampler
+}
+
+func (s recordOnlySampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.base.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (s recordOnlySampler) Description() string {
+	return "ErrorEscalation{" + s.base.Description() + "}"
+}
+
+// pendingTrace 暂存的未采样链路
+type pendingTrace struct {
+	spans   []sdktrace.ReadOnlySpan
+	created time.Time
+}
+
+// Processor 错误升级采样处理器：已采样的span直接交给下游，未采样的span按链路暂存，
+// 链路被标记为强制采样后（见tracing.RecordLogError）以采样状态补发
+type Processor struct {
+	next     sdktrace.SpanProcessor
+	maxSpans int
+
+	mu        sync.Mutex
+	pending   map[trace.TraceID]*pendingTrace
+	lastSweep time.Time
+}
+
+var _ sdktrace.SpanProcessor = (*Processor)(nil)
+
+// NewProcessor 包装下游处理器（通常为BatchSpanProcessor），maxSpans为单条链路最多暂存的span数（<=0时默认512）
+func NewProcessor(next sdktrace.SpanProcessor, maxSpans int) *Processor {
+	if maxSpans <= 0 {
+		maxSpans = defaultMaxSpans
+	}
+	tracing.EnableErrorEscalation()
+	return &Processor{next: next, maxSpans: maxSpans, pending: make(map[trace.TraceID]*pendingTrace)}
+}
+
+// OnStart 实现SpanProcessor
+func (p *Processor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+// OnEnd 实现SpanProcessor
+func (p *Processor) OnEnd(s sdktrace.ReadOnlySpan) {
+	spanCtx := s.SpanContext()
+	if spanCtx.IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+	traceID := spanCtx.TraceID()
+	localRoot := !s.Parent().IsValid() || s.Parent().IsRemote()
+	forced := tracing.IsForced(traceID)
+
+	p.mu.Lock()
+	pt := p.pending[traceID]
+	var flush []sdktrace.ReadOnlySpan
+	switch {
+	case forced:
+		if pt != nil {
+			flush = pt.spans
+			delete(p.pending, traceID)
+		}
+		flush = append(flush, s)
+	case localRoot:
+		delete(p.pending, traceID)
+	default:
+		if pt == nil {
+			p.sweep()
+			pt = &pendingTrace{created: time.Now()}
+			p.pending[traceID] = pt
+		}
+		if len(pt.spans) < p.maxSpans {
+			pt.spans = append(pt.spans, s)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, span := range flush {
+		p.next.OnEnd(sampledSpan{span})
+	}
+	if localRoot {
+		tracing.ClearForced(traceID)
+	}
+}
+
+// sweep 清理超时未结束的暂存链路（每分钟最多一次，调用方持有锁）
+func (p *Processor) sweep() {
+	now := time.Now()
+	if now.Sub(p.lastSweep) < time.Minute {
+		return
+	}
+	p.lastSweep = now
+	for traceID, pt := range p.pending {
+		if now.Sub(pt.created) > pendingTTL {
+			delete(p.pending, traceID)
+			tracing.ClearForced(traceID)
+		}
+	}
+}
+
+// Shutdown 实现SpanProcessor
+func (p *Processor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.pending = make(map[trace.TraceID]*pendingTrace)
+	p.mu.Unlock()
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush 实现SpanProcessor
+func (p *Processor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// sampledSpan 以采样状态呈现的span（下游处理器只导出已采样的span）
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	return s.ReadOnlySpan.SpanContext().WithTraceFlags(s.ReadOnlySpan.SpanContext().TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 该文件为OpenTelemetry链路追踪的统一入口，框架只依赖otel API：
+// 应用层需自行通过 otel.SetTracerProvider 注册SDK及导出器（Jaeger/OTLP等），未注册时所有span均为空操作
+
+// instrumentationName 框架埋点名称
+const instrumentationName = "github.com/dfpopp/go-dai"
+
+// maxStatementLen 数据库语句摘要最大长度
+const maxStatementLen = 256
+
+var (
+	enabled          bool   // 是否启用链路追踪
+	captureStatement bool   // 是否在数据库span中记录语句摘要
+	serviceName      string // 服务名
+	// W3C traceparent + baggage 传播
+	propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+)
+
+// Init 根据应用配置初始化链路追踪（需在数据库初始化之前调用）
+func Init(appName string) {
+	cfg := config.GetAppConfig(appName)
+	if cfg == nil {
+		return
+	}
+	enabled = cfg.Tracing.Enable
+	captureStatement = cfg.Tracing.CaptureStatement
+	serviceName = cfg.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = appName
+	}
+	if enabled {
+		otel.SetTextMapPropagator(propagator)
+	}
+}
+
+// Enabled 是否启用链路追踪
+func Enabled() bool {
+	return enabled
+}
+
+// CaptureStatement 是否记录数据库语句摘要
+func CaptureStatement() bool {
+	return captureStatement
+}
+
+// Tracer 获取框架Tracer（来自全局TracerProvider）
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Extract 从载体（HTTP Header/gRPC Metadata）中提取上游链路信息
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return propagator.Extract(ctx, carrier)
+}
+
+// Inject 将当前链路信息注入载体（供下游调用透传）
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	propagator.Inject(ctx, carrier)
+}
+
+// StartServerSpan 开启服务端span（HTTP/WS/gRPC入口）
+func StartServerSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("service.name", serviceName))
+	return Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// StartClientSpan 开启客户端span（服务间调用出口）
+func StartClientSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("service.name", serviceName))
+	return Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// StartDbSpan 开启数据库客户端span（system：mysql/mongodb/redis/elasticsearch，statement为语句摘要）
+func StartDbSpan(ctx context.Context, system string, operation string, statement string) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", system),
+		attribute.String("db.operation", operation),
+	}
+	if captureStatement && statement != "" {
+		attrs = append(attrs, attribute.String("db.statement", Summary(statement)))
+	}
+	return Tracer().Start(ctx, system+" "+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// End 结束span并记录错误
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Summary 截断语句，避免span体积过大
+func Summary(statement string) string {
+	runes := []rune(statement)
+	if len(runes) > maxStatementLen {
+		return string(runes[:maxStatementLen]) + "..."
+	}
+	return statement
+}
@@ -0,0 +1,26 @@
+package validate
+
+import (
+	"net/url"
+	"regexp"
+)
+
+var (
+	emailPattern    = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+	mobilePattern   = regexp.MustCompile(`^1[3-9]\d{9}$`) // 中国大陆手机号
+	alphaNumPattern = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+)
+
+// formatRules 字符串格式规则
+var formatRules = map[string]func(string) bool{
+	"email":    emailPattern.MatchString,
+	"mobile":   mobilePattern.MatchString,
+	"alphanum": alphaNumPattern.MatchString,
+	"url":      isURL,
+}
+
+// isURL 是否为带协议与主机的绝对URL
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
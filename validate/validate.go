@@ -0,0 +1,402 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/errs"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/netContext"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 请求参数校验：按结构体validate标签校验（通常在Bind之后），校验失败返回Errors，
+// 每个字段的提示按请求语言从i18n文案生成，字段名映射为显示名（如mobile -> 手机号 / Mobile number）。
+//
+//	type CreateUserReq struct {
+//		Name   string `json:"name" validate:"required,max=20" label:"姓名"`
+//		Mobile string `json:"mobile" validate:"required,mobile"`
+//		Age    int    `json:"age" validate:"gte=18,lte=120"`
+//		Role   string `json:"role" validate:"oneof=admin user"`
+//	}
+//
+//	validate.SetFieldNames("zh-CN", map[string]string{"mobile": "手机号", "age": "年龄"})
+//	validate.SetFieldNames("en", map[string]string{"mobile": "Mobile number", "age": "Age"})
+//
+//	if err := c.Bind(&req); err != nil { c.Fail(err); return }
+//	if err := c.Validate(&req); err != nil { c.Fail(err); return } // {"code":400,"msg":"手机号不能为空","data":{"errors":[...]}}
+//
+// 内置规则：required、min、max、len（数值比较大小，字符串按字符数、切片/map按元素个数）、gt、gte、lt、lte、
+// oneof（空格分隔）、email、mobile、url、alphanum；可通过RegisterRule注册自定义规则。
+// 非required字段为空值时跳过其余规则；每个字段只报告第一个不满足的规则；嵌套结构体与结构体切片递归校验（参数名如items[0].name）。
+//
+// 文案查找：字段提示优先"validate.<规则>.<参数名>"，其次"validate.<规则>"；
+// 字段显示名优先"field.<参数名>"、"field.<末级参数名>"，其次label标签，最后为参数名本身。
+
+// RuleFunc 自定义校验规则（v为解引用后的字段值，param为=后的参数），返回false表示校验失败
+type RuleFunc func(v reflect.Value, param string) bool
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`           // 参数名（按json/query/form标签，嵌套字段以.连接）
+	Rule    string `json:"rule"`            // 未通过的规则
+	Param   string `json:"param,omitempty"` // 规则参数
+	Message string `json:"message"`         // 本地化提示
+
+	label string // label标签
+	key   string // 文案key（min/max按字段类型区分数值与长度）
+}
+
+// Errors 校验错误（可通过errors.As取出；errs.From转换为InvalidArgument，消息为第一条提示）
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		if fe.Message == "" {
+			fe.localize(i18n.DefaultLocale())
+		}
+		msgs = append(msgs, fe.Message)
+	}
+	return strings.Join(msgs, "；")
+}
+
+// Unwrap 转换为框架统一错误（请求参数错误）
+func (e Errors) Unwrap() error {
+	if len(e) == 0 {
+		return errs.InvalidArgument
+	}
+	if e[0].Message == "" {
+		e[0].localize(i18n.DefaultLocale())
+	}
+	return errs.InvalidArgument.WithMessage(e[0].Message)
+}
+
+// Localize 按语言重新生成全部提示
+func (e Errors) Localize(locale string) Errors {
+	for _, fe := range e {
+		fe.localize(locale)
+	}
+	return e
+}
+
+// Body 统一JSON响应体：{"code":400,"msg":第一条提示,"data":{"errors":[...]}}
+func (e Errors) Body() map[string]interface{} {
+	body := errs.From(e).Body()
+	body["data"] = map[string]interface{}{"errors": e}
+	return body
+}
+
+func (fe *FieldError) localize(locale string) {
+	name := displayName(locale, fe.Field, fe.label)
+	args := []interface{}{name}
+	if fe.Param != "" {
+		args = append(args, fe.Param)
+	}
+	for _, key := range []string{"validate." + fe.Rule + "." + fe.Field, fe.key} {
+		if i18n.T(locale, key) != key {
+			fe.Message = i18n.T(locale, key, args...)
+			return
+		}
+	}
+	fe.Message = i18n.T(locale, i18n.MsgValidateInvalid, name)
+}
+
+// displayName 字段显示名（仅取当前语言注册的映射，避免英文提示中出现中文字段名）
+func displayName(locale, field, label string) string {
+	leaf := field
+	if i := strings.LastIndexAny(leaf, ".]"); i >= 0 {
+		leaf = strings.TrimPrefix(leaf[i+1:], ".")
+	}
+	for _, name := range []string{field, leaf} {
+		if name != "" && i18n.Has(locale, i18n.MsgFieldPrefix+name) {
+			return i18n.T(locale, i18n.MsgFieldPrefix+name)
+		}
+	}
+	if label != "" {
+		return label
+	}
+	return field
+}
+
+// SetFieldNames 注册指定语言的字段显示名（参数名 -> 显示名，嵌套字段可用完整路径如address.city或末级名city）
+func SetFieldNames(locale string, names map[string]string) {
+	messages := make(map[string]string, len(names))
+	for field, name := range names {
+		messages[i18n.MsgFieldPrefix+field] = name
+	}
+	i18n.Register(locale, messages)
+}
+
+var (
+	rulesMu     sync.RWMutex
+	customRules = make(map[string]RuleFunc)
+)
+
+// RegisterRule 注册自定义规则（提示文案key为"validate.<name>"，参数为字段显示名与规则参数，未注册文案时使用通用提示）
+//
+//	validate.RegisterRule("id_card", func(v reflect.Value, _ string) bool { return isIDCard(v.String()) })
+//	i18n.Register("zh-CN", map[string]string{"validate.id_card": "%s不是有效的身份证号"})
+func RegisterRule(name string, fn RuleFunc) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	customRules[name] = fn
+}
+
+// Struct 校验结构体（v为结构体或结构体指针），提示使用默认语言；通过时返回nil，未通过返回Errors，标签错误返回普通错误
+func Struct(v interface{}) error {
+	return StructLocale(i18n.DefaultLocale(), v)
+}
+
+// StructLocale 按指定语言校验结构体
+func StructLocale(locale string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("validate: 校验目标为nil（%T）", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: 校验目标须为结构体，当前为%T", v)
+	}
+	var result Errors
+	if err := walk(rv, "", &result); err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result.Localize(locale)
+}
+
+// FromBindError 将参数绑定失败（netContext.BindError）转换为校验错误，以便按同样的格式响应；其他错误返回nil
+func FromBindError(err error) Errors {
+	var bindErr *netContext.BindError
+	if !errors.As(err, &bindErr) {
+		return nil
+	}
+	field := bindErr.Field
+	if field == "" {
+		field = bindErr.Source
+	}
+	return Errors{{Field: field, Rule: "bind_error", key: i18n.MsgValidateBindError}}
+}
+
+// walk 递归校验结构体字段
+func walk(rv reflect.Value, prefix string, result *Errors) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		name := paramName(field)
+		path := prefix
+		if !field.Anonymous || name != field.Name {
+			path = joinPath(prefix, name)
+		}
+		if tag != "" {
+			fe, err := checkField(fv, tag)
+			if err != nil {
+				return fmt.Errorf("validate: 字段%s.%s：%w", rt.Name(), field.Name, err)
+			}
+			if fe != nil {
+				fe.Field, fe.label = path, field.Tag.Get("label")
+				*result = append(*result, fe)
+				continue
+			}
+		}
+		if err := walkNested(fv, path, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkNested 校验嵌套结构体与结构体切片
+func walkNested(fv reflect.Value, path string, result *Errors) error {
+	fv = indirect(fv)
+	switch fv.Kind() {
+	case reflect.Struct:
+		if fv.Type() != timeType {
+			return walk(fv, path, result)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			elem := indirect(fv.Index(i))
+			if elem.Kind() == reflect.Struct && elem.Type() != timeType {
+				if err := walk(elem, fmt.Sprintf("%s[%d]", path, i), result); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkField 按标签依次校验字段，返回第一个不满足的规则
+func checkField(fv reflect.Value, tag string) (*FieldError, error) {
+	rules := strings.Split(tag, ",")
+	required := false
+	for _, rule := range rules {
+		if strings.TrimSpace(rule) == "required" {
+			required = true
+		}
+	}
+	if isEmpty(fv) {
+		if required {
+			return &FieldError{Rule: "required", key: i18n.MsgValidateRequired}, nil
+		}
+		return nil, nil
+	}
+	value := indirect(fv)
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "" || name == "required" {
+			continue
+		}
+		ok, err := checkRule(value, name, param)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return &FieldError{Rule: name, Param: param, key: messageKey(name, value)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// messageKey 规则对应的文案key（min/max对字符串、切片、map使用长度文案）
+func messageKey(rule string, v reflect.Value) string {
+	if _, ok := length(v); ok {
+		switch rule {
+		case "min":
+			return i18n.MsgValidateMinLen
+		case "max":
+			return i18n.MsgValidateMaxLen
+		}
+	}
+	return "validate." + rule
+}
+
+func checkRule(v reflect.Value, rule, param string) (bool, error) {
+	switch rule {
+	case "min", "gte":
+		return compare(v, param, func(a, b float64) bool { return a >= b })
+	case "max", "lte":
+		return compare(v, param, func(a, b float64) bool { return a <= b })
+	case "gt":
+		return compare(v, param, func(a, b float64) bool { return a > b })
+	case "lt":
+		return compare(v, param, func(a, b float64) bool { return a < b })
+	case "len":
+		n, ok := length(v)
+		if !ok {
+			return false, fmt.Errorf("规则len不支持类型%s", v.Type())
+		}
+		expected, err := strconv.Atoi(param)
+		if err != nil {
+			return false, fmt.Errorf("规则len的参数%q不是整数", param)
+		}
+		return n == expected, nil
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if s == option {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "email", "mobile", "url", "alphanum":
+		if v.Kind() != reflect.String {
+			return false, fmt.Errorf("规则%s仅支持字符串", rule)
+		}
+		return formatRules[rule](v.String()), nil
+	}
+	rulesMu.RLock()
+	fn, ok := customRules[rule]
+	rulesMu.RUnlock()
+	if !ok {
+		return false, fmt.Errorf("未知的校验规则%s", rule)
+	}
+	return fn(v, param), nil
+}
+
+// compare 数值字段比较数值，字符串/切片/map比较长度
+func compare(v reflect.Value, param string, op func(a, b float64) bool) (bool, error) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false, fmt.Errorf("规则参数%q不是数字", param)
+	}
+	if n, ok := length(v); ok {
+		return op(float64(n), limit), nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return op(float64(v.Int()), limit), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return op(float64(v.Uint()), limit), nil
+	case reflect.Float32, reflect.Float64:
+		return op(v.Float(), limit), nil
+	}
+	return false, fmt.Errorf("比较规则不支持类型%s", v.Type())
+}
+
+// length 字符串的字符数、切片/数组/map的元素个数
+func length(v reflect.Value) (int, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return len([]rune(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	}
+	return 0, false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// isEmpty 是否为空值（nil指针、空字符串（忽略空白）、空切片/map、零值）
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// paramName 字段的参数名（依次取json、query、form标签，均未设置时为字段名）
+func paramName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "query", "form"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,202 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// 端到端确认（QoS1）：关键推送（支付结果、安全告警等）以需确认模式发送，消息携带 "ack": true，
+// request_id 即消息ID；客户端处理后回复 {"action": "_ack", "request_id": "<消息ID>"}。
+// 超时未确认时以同一消息ID重发（客户端应按消息ID去重），直至确认或重试次数用尽，并通过回调/Delivery报告投递结果。
+// 启用集群时，其他节点收到的确认经Redis中继回发送节点。
+
+// AckAction 客户端确认帧的action（框架保留，不进入路由）
+const AckAction = "_ack"
+
+const (
+	defaultAckTimeout = 5 * time.Second
+	defaultAckRetries = 2
+)
+
+// DeliveryStatus 投递状态
+type DeliveryStatus string
+
+const (
+	DeliveryAcked   DeliveryStatus = "acked"   // 客户端已确认
+	DeliveryTimeout DeliveryStatus = "timeout" // 已发送但重试用尽仍未确认
+	DeliveryOffline DeliveryStatus = "offline" // 每次尝试时目标均无在线连接
+	DeliveryFailed  DeliveryStatus = "failed"  // 消息构建或目标查询失败
+)
+
+// AckOptions 需确认发送的参数
+type AckOptions struct {
+	Timeout  time.Duration        // 每次发送后等待确认的时长（默认5秒）
+	Retries  int                  // 未确认时的重发次数（默认2，<0表示不重发）
+	OnResult func(DeliveryResult) // 投递结束时回调（在后台协程中执行）
+}
+
+// DeliveryResult 投递结果
+type DeliveryResult struct {
+	MsgID    string         // 消息ID
+	Status   DeliveryStatus // 投递状态
+	ConnID   string         // 确认消息的连接
+	Attempts int            // 实际发送次数
+	Err      error          // 失败原因（DeliveryFailed时）
+}
+
+// Delivery 需确认发送的投递句柄（future）
+type Delivery struct {
+	MsgID  string
+	ackCh  chan string
+	done   chan struct{}
+	result DeliveryResult
+}
+
+// Done 投递结束时关闭的通道
+func (d *Delivery) Done() <-chan struct{} {
+	return d.done
+}
+
+// Wait 等待投递结束（ctx先结束时返回ctx.Err()，投递仍在后台继续）
+func (d *Delivery) Wait(ctx context.Context) (DeliveryResult, error) {
+	select {
+	case <-d.done:
+		return d.result, nil
+	case <-ctx.Done():
+		return DeliveryResult{MsgID: d.MsgID}, ctx.Err()
+	}
+}
+
+// Result 投递结果（未结束时ok为false）
+func (d *Delivery) Result() (result DeliveryResult, ok bool) {
+	select {
+	case <-d.done:
+		return d.result, true
+	default:
+		return DeliveryResult{MsgID: d.MsgID}, false
+	}
+}
+
+// pendingAcks 等待确认的投递（key：消息ID）
+var pendingAcks sync.Map
+
+// SendWithAck 以需确认模式发送消息：targets在每次发送前调用以获取目标连接（用户重连后可投递到新连接），
+// 任一连接确认即视为送达
+func (cm *ConnManager) SendWithAck(targets func() ([]string, error), action string, data interface{}, opts AckOptions) *Delivery {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultAckTimeout
+	}
+	if opts.Retries == 0 {
+		opts.Retries = defaultAckRetries
+	}
+	d := &Delivery{MsgID: uuid.NewString(), ackCh: make(chan string, 1), done: make(chan struct{})}
+	message, err := json.Marshal(map[string]interface{}{"action": action, "request_id": d.MsgID, "data": data, "ack": true})
+	if err != nil || action == "" {
+		if err == nil {
+			err = errors.New("消息动作不能为空")
+		}
+		d.finish(DeliveryResult{MsgID: d.MsgID, Status: DeliveryFailed, Err: err}, opts.OnResult)
+		return d
+	}
+	pendingAcks.Store(d.MsgID, d)
+	go cm.deliver(d, targets, string(message), opts)
+	return d
+}
+
+// SendToConnIDWithAck 以需确认模式给单个连接发送消息
+func (cm *ConnManager) SendToConnIDWithAck(connID string, action string, data interface{}, opts AckOptions) *Delivery {
+	return cm.SendWithAck(func() ([]string, error) {
+		if cm.cluster.Load() == nil {
+			if _, ok := cm.GetConnInfoByConnID(connID); !ok {
+				return nil, nil
+			}
+		}
+		return []string{connID}, nil
+	}, action, data, opts)
+}
+
+// deliver 发送并等待确认，超时后重发
+func (cm *ConnManager) deliver(d *Delivery, targets func() ([]string, error), message string, opts AckOptions) {
+	defer pendingAcks.Delete(d.MsgID)
+	result := DeliveryResult{MsgID: d.MsgID, Status: DeliveryOffline}
+	for attempt := 0; attempt <= max(opts.Retries, 0); attempt++ {
+		connIDs, err := targets()
+		if err != nil {
+			result.Status, result.Err = DeliveryFailed, err
+			break
+		}
+		if len(connIDs) > 0 {
+			cm.Multicast(connIDs, message)
+			result.Attempts++
+			result.Status = DeliveryTimeout
+		}
+		timer := time.NewTimer(opts.Timeout)
+		select {
+		case connID := <-d.ackCh:
+			timer.Stop()
+			result.Status, result.ConnID = DeliveryAcked, connID
+			d.finish(result, opts.OnResult)
+			return
+		case <-timer.C:
+		}
+	}
+	// 等待期间的最后一次确认（与超时同时到达）
+	select {
+	case connID := <-d.ackCh:
+		result.Status, result.ConnID, result.Err = DeliveryAcked, connID, nil
+	default:
+	}
+	if result.Status != DeliveryAcked {
+		logger.Warn("WS需确认消息未送达：", d.MsgID, " 状态：", result.Status, " 发送次数：", result.Attempts)
+	}
+	d.finish(result, opts.OnResult)
+}
+
+// finish 记录结果并通知等待方
+func (d *Delivery) finish(result DeliveryResult, onResult func(DeliveryResult)) {
+	d.result = result
+	close(d.done)
+	if onResult != nil {
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("WS投递结果回调panic：", r)
+				}
+			}()
+			onResult(result)
+		}()
+	}
+}
+
+// handleAck 处理客户端确认帧：本节点的投递直接完成，启用集群时中继到其他节点
+func (cm *ConnManager) handleAck(msgID, connID string) {
+	if msgID == "" {
+		return
+	}
+	if resolveAck(msgID, connID) {
+		return
+	}
+	if cl := cm.cluster.Load(); cl != nil {
+		if err := cl.publish(context.Background(), cl.key("broadcast"), clusterEnvelope{AckMsgID: msgID, ConnIDs: []string{connID}}); err != nil {
+			logger.Warn("WS集群中继确认失败：", err)
+		}
+	}
+}
+
+// resolveAck 完成本节点等待中的投递（重复确认忽略）
+func resolveAck(msgID, connID string) bool {
+	v, ok := pendingAcks.Load(msgID)
+	if !ok {
+		return false
+	}
+	select {
+	case v.(*Delivery).ackCh <- connID:
+	default:
+	}
+	return true
+}
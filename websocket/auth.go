@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"time"
+)
+
+// ActionAuth 连接建立后通过首条消息认证时使用的action（data为{"token":"..."}）
+const ActionAuth = "auth"
+
+// connAttrClaims 连接属性中保存认证结果的key
+const connAttrClaims = "auth_claims"
+
+// JWTAuth JWT认证中间件：令牌可在握手时通过查询参数token或Authorization头携带，
+// 也可在连接后发送action为ActionAuth的消息认证；认证结果保存在连接上，后续消息无需重复携带。
+// publicActions为无需认证的action；认证失败返回code为401的错误帧，连接保持不断开。
+// 首条消息认证须将其注册为全局中间件（Server.Use），ActionAuth消息由中间件直接应答，无需注册路由。
+func JWTAuth(j *auth.JWT, publicActions ...string) MiddlewareFunc {
+	public := make(map[string]bool, len(publicActions))
+	for _, action := range publicActions {
+		public[action] = true
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			cm := GetGlobalConnManager()
+			if c.Action == ActionAuth {
+				var req struct {
+					Token string `json:"token"`
+				}
+				_ = json.Unmarshal(c.rawData, &req)
+				claims, err := j.Parse(req.Token)
+				if err != nil {
+					c.Error(401, authErrorKey(err))
+					return
+				}
+				cm.SetConnAttr(c.ConnID, connAttrClaims, claims)
+				c.JSON(200, map[string]interface{}{
+					"code": 200,
+					"msg":  "success",
+					"data": map[string]interface{}{"user_id": claims.UserID, "expires_at": claims.ExpiresAt.Unix()},
+				})
+				return
+			}
+			claims, err := connClaims(cm, c, j)
+			if err == nil {
+				auth.Bind(c, claims)
+				next(c)
+				return
+			}
+			if public[c.Action] {
+				next(c)
+				return
+			}
+			c.Error(401, authErrorKey(err))
+		}
+	}
+}
+
+// connClaims 获取连接上已认证的声明（首次调用时尝试握手请求中的令牌）
+func connClaims(cm *ConnManager, c *Context, j *auth.JWT) (*auth.Claims, error) {
+	if value, ok := cm.GetConnAttr(c.ConnID, connAttrClaims); ok {
+		claims := value.(*auth.Claims)
+		if claims.ExpiresAt != nil && time.Now().After(claims.ExpiresAt.Time) {
+			return nil, auth.ErrTokenExpired
+		}
+		return claims, nil
+	}
+	if c.Req == nil {
+		return nil, auth.ErrInvalidToken
+	}
+	token := c.Req.URL.Query().Get("token")
+	if token == "" {
+		token = auth.BearerToken(c.Req.Header.Get("Authorization"))
+	}
+	claims, err := j.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	cm.SetConnAttr(c.ConnID, connAttrClaims, claims)
+	return claims, nil
+}
+
+func authErrorKey(err error) string {
+	if errors.Is(err, auth.ErrTokenExpired) {
+		return i18n.MsgTokenExpired
+	}
+	return i18n.MsgAuthFailed
+}
+
+// RBAC 权限中间件：按action校验已认证用户的角色/权限（规则见auth.RBAC），须注册在JWTAuth之后；
+// 未匹配到规则的action直接放行，未认证返回code为401、权限不足返回code为403的错误帧，连接保持不断开
+func RBAC(r *auth.RBAC) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if c.Action == ActionAuth {
+				next(c)
+				return
+			}
+			if err := r.Authorize(c.GetContext(), c.Action, auth.FromContext(c.GetContext())); err != nil {
+				c.Error(rbacErrorFrame(err))
+				return
+			}
+			next(c)
+		}
+	}
+}
+
+// rbacErrorFrame 权限校验错误对应的错误帧code与文案key
+func rbacErrorFrame(err error) (int, string) {
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return 401, i18n.MsgAuthFailed
+	case errors.Is(err, auth.ErrPermissionDenied):
+		return 403, i18n.MsgForbidden
+	}
+	logger.Error("WS权限策略加载失败：", err)
+	return 500, i18n.MsgInternalError
+}
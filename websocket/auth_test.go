@@ -0,0 +1,91 @@
+package websocket_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/websocket"
+)
+
+const testApp = "ws_test"
+
+// startServer 以临时配置启动WS服务，返回ws地址
+func startServer(t *testing.T, setup func(s *websocket.Server)) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(path, []byte(`{"`+testApp+`":{"websocket":{"path":"/ws"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadAppConfig(path, testApp); err != nil {
+		t.Fatal(err)
+	}
+	s := websocket.NewServer(testApp)
+	setup(s)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+}
+
+func TestJWTAuthFirstMessageWithoutAuthRoute(t *testing.T) {
+	j, err := auth.NewJWT(auth.JWTConfig{Secret: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startServer(t, func(s *websocket.Server) {
+		s.Use(websocket.JWTAuth(j))
+		// 仅注册业务action，不注册auth
+		s.Register("profile", func(c *websocket.Context) {
+			c.JSON(200, map[string]interface{}{"code": 200, "msg": "success", "data": auth.FromContext(c.GetContext()).UserID})
+		})
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := websocket.Dial(ctx, url, &websocket.DialOptions{MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	resp, err := client.Request(ctx, "profile", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != 401 {
+		t.Fatalf("未认证时应返回401，got %d", resp.Code)
+	}
+
+	token, err := j.Issue(auth.Claims{UserID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Request(ctx, websocket.ActionAuth, map[string]string{"token": token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != 200 {
+		t.Fatalf("未注册auth路由时首条消息认证应由JWTAuth处理，got %d %s", resp.Code, resp.Msg)
+	}
+
+	var uid string
+	if err := client.Call(ctx, "profile", nil, &uid); err != nil {
+		t.Fatal(err)
+	}
+	if uid != "u1" {
+		t.Fatalf("认证后应读取到用户u1，got %q", uid)
+	}
+
+	resp, err = client.Request(ctx, "missing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != 404 {
+		t.Fatalf("已认证时未注册的action应返回404，got %d", resp.Code)
+	}
+}
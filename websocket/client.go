@@ -0,0 +1,667 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/google/uuid"
+	"io"
+	mrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 客户端错误
+var (
+	ErrClientClosed  = errors.New("websocket: 客户端已关闭")
+	ErrConnLost      = errors.New("websocket: 连接已断开，请求未收到响应")
+	ErrRetryExceeded = errors.New("websocket: 重连次数已达上限")
+)
+
+// DialOptions 客户端配置（零值字段使用默认值）
+type DialOptions struct {
+	Header               http.Header                // 握手附加请求头（如Authorization、Accept-Language）
+	TLSConfig            *tls.Config                // wss连接的TLS配置（为nil时使用系统根证书）
+	HandshakeTimeout     time.Duration              // 建连与握手超时（默认10秒）
+	RequestTimeout       time.Duration              // Request未指定截止时间时的响应超时（默认10秒）
+	WriteTimeout         time.Duration              // 写超时（默认10秒）
+	ReconnectInterval    time.Duration              // 首次重连间隔（默认1秒，之后按指数退避）
+	MaxReconnectInterval time.Duration              // 重连间隔上限（默认30秒）
+	MaxRetries           int                        // 单次断线的最大重连次数（0表示不限制，<0表示断线后不重连）
+	PingInterval         time.Duration              // 客户端ping间隔（默认30秒，<0表示不发送）
+	PongTimeout          time.Duration              // 发送ping后等待服务端响应的时长（默认10秒）
+	MaxMessageSize       int64                      // 最大消息大小（默认1MB）
+	SendQueueSize        int                        // 发送队列长度（默认256）
+	Compression          bool                       // 是否提议permessage-deflate压缩
+	CompressionThreshold int                        // 不小于该字节数的消息才压缩（默认1024）
+	CompressionLevel     int                        // 压缩级别（默认1）
+	ProtocolVersion      string                     // 协议版本（为空时使用版本1；其他版本需同时指定Codec，且服务端须已注册该版本）
+	Codec                Codec                      // ProtocolVersion对应的编解码器
+	OnConnect            func(c *Client)            // 连接（含重连）建立后回调，可在此重新订阅房间等
+	OnDisconnect         func(c *Client, err error) // 连接断开回调（主动Close时不回调）
+}
+
+// Response 服务端消息：按request_id关联的响应，或服务端主动推送（含action）
+type Response struct {
+	Action    string                     `json:"action,omitempty"`
+	RequestId string                     `json:"request_id,omitempty"`
+	Code      int                        `json:"code"`
+	Msg       string                     `json:"msg"`
+	Data      json.RawMessage            `json:"data"`
+	Meta      map[string]json.RawMessage `json:"-"` // 信封扩展字段
+	Raw       []byte                     `json:"-"` // 原始消息
+}
+
+// Decode 将data解析到v
+func (r *Response) Decode(v interface{}) error {
+	if len(r.Data) == 0 || string(r.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(r.Data, v)
+}
+
+// ResponseError 业务失败响应（code不为200）
+type ResponseError struct {
+	Code int
+	Msg  string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("websocket: 请求失败（code=%d）：%s", e.Code, e.Msg)
+}
+
+// PushHandler 服务端推送处理函数（在读协程中执行，耗时逻辑请自行异步处理）
+type PushHandler func(msg *Response)
+
+// callResult 等待中的请求结果
+type callResult struct {
+	resp *Response
+	err  error
+}
+
+// Client WS客户端：与服务端使用相同的消息格式（{action, request_id, data}），支持按request_id关联响应、
+// 服务端推送订阅、心跳与断线自动重连，用于go-dai服务之间互相调用WS接口
+type Client struct {
+	url  string
+	opts DialOptions
+
+	mu       sync.Mutex
+	conn     *Conn
+	ready    chan struct{} // 连接可用时关闭，断线后替换为新的通道
+	gone     chan struct{} // 当前连接断开清理后关闭
+	pending  map[string]chan callResult
+	handlers map[string][]*pushEntry
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+type pushEntry struct {
+	handler PushHandler
+}
+
+// Dial 连接WS服务端（首次连接同步完成，失败时直接返回错误；之后断线按DialOptions自动重连）。
+// url支持ws://、wss://（也接受http://、https://）
+func Dial(ctx context.Context, rawURL string, opts *DialOptions) (*Client, error) {
+	c := &Client{
+		url:      rawURL,
+		ready:    make(chan struct{}),
+		pending:  make(map[string]chan callResult),
+		handlers: make(map[string][]*pushEntry),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	setDefaultDialOptions(&c.opts)
+	if c.opts.ProtocolVersion != DefaultProtocolVersion && c.opts.Codec == nil {
+		return nil, fmt.Errorf("websocket: 协议版本%s未指定编解码器", c.opts.ProtocolVersion)
+	}
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.attach(conn)
+	go c.run(conn)
+	return c, nil
+}
+
+func setDefaultDialOptions(opts *DialOptions) {
+	if opts.ProtocolVersion == "" || opts.ProtocolVersion == DefaultProtocolVersion {
+		opts.ProtocolVersion = DefaultProtocolVersion
+		opts.Codec = nil
+	}
+	if opts.HandshakeTimeout <= 0 {
+		opts.HandshakeTimeout = 10 * time.Second
+	}
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = 10 * time.Second
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	if opts.ReconnectInterval <= 0 {
+		opts.ReconnectInterval = time.Second
+	}
+	if opts.MaxReconnectInterval <= 0 {
+		opts.MaxReconnectInterval = 30 * time.Second
+	}
+	if opts.MaxReconnectInterval < opts.ReconnectInterval {
+		opts.MaxReconnectInterval = opts.ReconnectInterval
+	}
+	if opts.PingInterval == 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	if opts.PongTimeout <= 0 {
+		opts.PongTimeout = 10 * time.Second
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = 1024 * 1024
+	}
+	if opts.SendQueueSize <= 0 {
+		opts.SendQueueSize = 256
+	}
+	if opts.CompressionThreshold <= 0 {
+		opts.CompressionThreshold = 1024
+	}
+	if opts.CompressionLevel < 1 || opts.CompressionLevel > 9 {
+		opts.CompressionLevel = 1
+	}
+}
+
+// Request 发送请求并等待按request_id关联的响应（ctx无截止时间时使用RequestTimeout；
+// 断线重连期间发起的请求等待连接恢复，等待时间计入超时）。code不为200时仍返回响应，由调用方判断
+func (c *Client) Request(ctx context.Context, action string, data interface{}) (*Response, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.RequestTimeout)
+		defer cancel()
+	}
+	requestId := uuid.NewString()
+	msg, err := json.Marshal(map[string]interface{}{"action": action, "request_id": requestId, "data": data})
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan callResult, 1)
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, requestId)
+		c.mu.Unlock()
+	}()
+	var stale *Conn
+	for {
+		conn, err := c.waitConn(ctx, stale)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		if c.conn == conn {
+			c.pending[requestId] = ch
+		}
+		c.mu.Unlock()
+		if err = conn.WriteMessage(string(msg)); err == nil {
+			break
+		}
+		if !errors.Is(err, net.ErrClosed) {
+			return nil, err
+		}
+		// 连接已关闭但读协程尚未清理，消息未发出，等待重连后重发
+		c.mu.Lock()
+		delete(c.pending, requestId)
+		c.mu.Unlock()
+		stale = conn
+	}
+	select {
+	case res := <-ch:
+		return res.resp, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.closed:
+		return nil, ErrClientClosed
+	}
+}
+
+// Call 发送请求，code不为200时返回*ResponseError，成功时将data解析到out（out为nil时忽略data）
+func (c *Client) Call(ctx context.Context, action string, data interface{}, out interface{}) error {
+	resp, err := c.Request(ctx, action, data)
+	if err != nil {
+		return err
+	}
+	if resp.Code != http.StatusOK {
+		return &ResponseError{Code: resp.Code, Msg: resp.Msg}
+	}
+	if out == nil {
+		return nil
+	}
+	return resp.Decode(out)
+}
+
+// Send 发送消息但不等待响应（未连接时返回错误，不排队等待重连）
+func (c *Client) Send(action string, data interface{}) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		select {
+		case <-c.closed:
+			return ErrClientClosed
+		default:
+			return net.ErrClosed
+		}
+	}
+	msg, err := json.Marshal(map[string]interface{}{"action": action, "request_id": uuid.NewString(), "data": data})
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(string(msg))
+}
+
+// Ack 确认需确认的推送（msgID为推送的request_id）；已订阅的推送在处理函数返回后自动确认，无需手动调用
+func (c *Client) Ack(msgID string) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return net.ErrClosed
+	}
+	msg, err := json.Marshal(map[string]interface{}{"action": AckAction, "request_id": msgID})
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(string(msg))
+}
+
+// On 订阅服务端推送的action（同一action可注册多个处理函数），返回取消订阅函数
+func (c *Client) On(action string, handler PushHandler) func() {
+	entry := &pushEntry{handler: handler}
+	c.mu.Lock()
+	c.handlers[action] = append(c.handlers[action], entry)
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		entries := c.handlers[action]
+		for i, e := range entries {
+			if e == entry {
+				c.handlers[action] = append(entries[:i:i], entries[i+1:]...)
+				break
+			}
+		}
+		if len(c.handlers[action]) == 0 {
+			delete(c.handlers, action)
+		}
+	}
+}
+
+// Connected 当前是否已连接
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// RTT 最近一次心跳的往返时延（未连接或尚未收到pong时为0）
+func (c *Client) RTT() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return 0
+	}
+	return c.conn.RTT()
+}
+
+// Close 关闭客户端（发送关闭帧并停止重连，等待中的请求返回ErrClientClosed；可重复调用）
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.mu.Lock()
+		conn := c.conn
+		c.mu.Unlock()
+		if conn != nil {
+			_ = conn.Close()
+		}
+	})
+	<-c.done
+	return nil
+}
+
+// waitConn 等待连接可用（stale为已确认关闭的连接，等待其清理后的新连接）
+func (c *Client) waitConn(ctx context.Context, stale *Conn) (*Conn, error) {
+	for {
+		c.mu.Lock()
+		conn, wait := c.conn, c.ready
+		if conn != nil && conn == stale {
+			wait = c.gone
+		}
+		c.mu.Unlock()
+		if conn != nil && conn != stale {
+			return conn, nil
+		}
+		select {
+		case <-wait:
+		case <-c.done:
+			select {
+			case <-c.closed:
+				return nil, ErrClientClosed
+			default:
+				return nil, ErrRetryExceeded
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// run 读循环：连接断开后按指数退避重连，直至Close或重连次数用尽
+func (c *Client) run(conn *Conn) {
+	defer close(c.done)
+	for {
+		err := c.serve(conn)
+		c.detach(conn, err)
+		if conn = c.reconnect(); conn == nil {
+			return
+		}
+		c.attach(conn)
+	}
+}
+
+// serve 读取并分发消息，直至连接断开
+func (c *Client) serve(conn *Conn) error {
+	for {
+		_, raw, err := conn.ReadMessageType()
+		if err != nil {
+			return err
+		}
+		c.dispatch(conn, raw)
+	}
+}
+
+// dispatch 响应交给等待中的请求，其余消息按action交给推送处理函数
+func (c *Client) dispatch(conn *Conn, raw []byte) {
+	env, err := conn.decodeEnvelope(raw)
+	if err != nil {
+		logger.Warn("WS客户端消息解析失败：", err, "地址：", c.url)
+		return
+	}
+	resp := &Response{
+		Action:    env.Action,
+		RequestId: env.RequestId,
+		Code:      env.Code,
+		Msg:       env.Msg,
+		Data:      env.Data,
+		Meta:      env.Meta,
+		Raw:       raw,
+	}
+	c.mu.Lock()
+	ch, ok := c.pending[resp.RequestId]
+	if ok {
+		delete(c.pending, resp.RequestId)
+	}
+	entries := c.handlers[resp.Action]
+	c.mu.Unlock()
+	if ok {
+		ch <- callResult{resp: resp}
+		return
+	}
+	if len(entries) == 0 {
+		logger.Debug("WS客户端收到未订阅的消息：", resp.Action, "地址：", c.url)
+		return
+	}
+	for _, e := range entries {
+		c.callHandler(e.handler, resp)
+	}
+	// 需确认的推送：处理函数执行完成后自动回复确认帧
+	if string(resp.Meta["ack"]) == "true" && resp.RequestId != "" {
+		if err := c.Ack(resp.RequestId); err != nil {
+			logger.Warn("WS客户端确认消息失败：", err, "消息ID：", resp.RequestId)
+		}
+	}
+}
+
+// callHandler 执行推送处理函数（捕获panic，避免读协程退出）
+func (c *Client) callHandler(handler PushHandler, resp *Response) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("WS客户端推送处理函数panic：", r, "action：", resp.Action)
+		}
+	}()
+	handler(resp)
+}
+
+// attach 连接建立后启用，唤醒等待连接的请求
+func (c *Client) attach(conn *Conn) {
+	c.mu.Lock()
+	c.conn = conn
+	c.gone = make(chan struct{})
+	close(c.ready)
+	c.mu.Unlock()
+	if c.opts.OnConnect != nil {
+		c.opts.OnConnect(c)
+	}
+}
+
+// detach 连接断开后清理：等待中的请求返回ErrConnLost（已发出的请求无法确认服务端是否处理，由调用方决定是否重试）
+func (c *Client) detach(conn *Conn, err error) {
+	_ = conn.Close()
+	c.mu.Lock()
+	c.conn = nil
+	c.ready = make(chan struct{})
+	close(c.gone)
+	pending := c.pending
+	c.pending = make(map[string]chan callResult)
+	c.mu.Unlock()
+	for _, ch := range pending {
+		ch <- callResult{err: ErrConnLost}
+	}
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	logger.Warn("WS客户端连接断开：", err, "地址：", c.url)
+	if c.opts.OnDisconnect != nil {
+		c.opts.OnDisconnect(c, err)
+	}
+}
+
+// reconnect 按指数退避（附加±20%抖动，避免大量客户端同时重连）重连，Close或重连次数用尽时返回nil
+func (c *Client) reconnect() *Conn {
+	if c.opts.MaxRetries < 0 {
+		return nil
+	}
+	for attempt := 0; c.opts.MaxRetries == 0 || attempt < c.opts.MaxRetries; attempt++ {
+		delay := c.opts.ReconnectInterval << min(attempt, 16)
+		if delay <= 0 || delay > c.opts.MaxReconnectInterval {
+			delay = c.opts.MaxReconnectInterval
+		}
+		delay += time.Duration(float64(delay) * (mrand.Float64()*0.4 - 0.2))
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.closed:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		// 重连过程中调用Close时立即中止握手
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-c.closed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		conn, err := c.connect(ctx)
+		cancel()
+		if err == nil {
+			logger.Info("WS客户端重连成功：", c.url, "重试次数：", attempt+1)
+			return conn
+		}
+		logger.Warn("WS客户端重连失败：", err, "地址：", c.url, "重试次数：", attempt+1)
+	}
+	logger.Error("WS客户端重连次数已达上限，停止重连：", c.url)
+	return nil
+}
+
+// connect 建立TCP/TLS连接并完成WS握手
+func (c *Client) connect(ctx context.Context) (*Conn, error) {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return nil, err
+	}
+	secure := false
+	switch strings.ToLower(u.Scheme) {
+	case "ws", "http":
+	case "wss", "https":
+		secure = true
+	default:
+		return nil, fmt.Errorf("websocket: 不支持的协议：%s", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		if secure {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.HandshakeTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if secure {
+		cfg := c.opts.TLSConfig
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			cfg = cfg.Clone()
+			cfg.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(netConn, cfg)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+		netConn = tlsConn
+	}
+	conn, err := c.handshake(ctx, netConn, u)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// handshake 发送升级请求并校验响应
+func (c *Client) handshake(ctx context.Context, netConn net.Conn, u *url.URL) (*Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = netConn.SetDeadline(time.Now()) })
+	defer stop()
+
+	nonce := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	reqURL := *u
+	reqURL.Scheme = "http"
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &reqURL,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for name, values := range c.opts.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if c.opts.ProtocolVersion != DefaultProtocolVersion {
+		req.Header.Set(ProtocolVersionHeader, c.opts.ProtocolVersion)
+	}
+	if c.opts.Compression {
+		req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+	}
+	if err := req.Write(netConn); err != nil {
+		return nil, fmt.Errorf("websocket: 发送握手请求失败：%w", err)
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: 读取握手响应失败：%w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("websocket: 握手失败（状态码%d）：%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") || resp.Header.Get("Sec-WebSocket-Accept") != generateServerKey(key) {
+		return nil, errors.New("websocket: 握手响应校验失败")
+	}
+	// 未返回版本头的旧版服务端只支持版本1
+	if version := resp.Header.Get(ProtocolVersionHeader); version != c.opts.ProtocolVersion && (version != "" || c.opts.ProtocolVersion != DefaultProtocolVersion) {
+		return nil, fmt.Errorf("websocket: 服务端不支持协议版本%s", c.opts.ProtocolVersion)
+	}
+	compress := false
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		if !c.opts.Compression || !strings.HasPrefix(strings.TrimSpace(ext), "permessage-deflate") {
+			return nil, fmt.Errorf("websocket: 服务端返回了未提议的扩展：%s", ext)
+		}
+		compress = true
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	conn := &Conn{
+		conn:              &clientNetConn{Conn: netConn, r: br},
+		readBuf:           make([]byte, 4096),
+		writeBuf:          make([]byte, 4096),
+		maxMsgSize:        c.opts.MaxMessageSize,
+		writeTimeout:      c.opts.WriteTimeout,
+		compress:          compress,
+		compressThreshold: c.opts.CompressionThreshold,
+		compressLevel:     c.opts.CompressionLevel,
+		streamChunkSize:   defaultStreamChunkSize,
+		isClient:          true,
+		protocol:          c.opts.ProtocolVersion,
+		codec:             c.opts.Codec,
+	}
+	conn.lastActive.Store(time.Now().UnixNano())
+	conn.startWriter(c.opts.SendQueueSize, SlowPolicyDrop)
+	conn.startHeartbeat(max(c.opts.PingInterval, 0), c.opts.PongTimeout, 0)
+	return conn, nil
+}
+
+// clientNetConn 握手时bufio可能已预读服务端紧随响应发送的帧，读取时先消费缓冲区
+type clientNetConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *clientNetConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,392 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"os"
+	"sync"
+	"time"
+)
+
+// 多节点部署时，ConnManager只持有本节点的连接。Cluster基于Redis在节点间同步：
+//   - 连接注册表：{prefix}conn:{connID} -> 节点ID（带有效期，由各节点定期续期，节点宕机后自动过期）
+//   - 用户索引：{prefix}user:{userID} -> 连接ID集合（BindUser写入，连接断开时移除，失效条目在查询时清理）
+//   - 消息中继：Redis Pub/Sub，广播走{prefix}broadcast频道，定向消息按目标节点发布到{prefix}node:{节点ID}
+//
+// 启用后ConnManager的Broadcast/Multicast/SendToConnID自动跨节点投递，应用层代码无需改动。
+
+// ClusterOptions 集群参数
+type ClusterOptions struct {
+	NodeID   string        // 节点ID（默认主机名-进程号-随机串）
+	Prefix   string        // 键与频道前缀（默认ws:）
+	TTL      time.Duration // 连接注册有效期（默认60秒，每TTL/3续期一次）
+	UserAttr string        // 连接属性中用户ID的key（默认user_id，与BaseController.UserIDField一致）
+}
+
+// clusterEnvelope 节点间中继的消息
+type clusterEnvelope struct {
+	Origin  string   `json:"origin"`
+	ConnIDs []string `json:"conn_ids,omitempty"` // 为空表示广播
+	Message string   `json:"message"`
+	// AckMsgID 客户端确认的中继（经广播频道发给全部节点，由发送该消息的节点完成投递，ConnIDs为确认的连接）
+	AckMsgID string `json:"ack_msg_id,omitempty"`
+}
+
+// Cluster 基于Redis的WS多节点连接注册表与消息中继
+type Cluster struct {
+	rdb    *redisDb.RedisDb
+	cm     *ConnManager
+	opts   ClusterOptions
+	pubsub *redis.PubSub
+	stop   chan struct{}
+	done   sync.WaitGroup
+	once   sync.Once
+}
+
+// NewCluster 创建集群中继（调用Start后生效）
+func NewCluster(rdb *redisDb.RedisDb, cm *ConnManager, opts ClusterOptions) *Cluster {
+	if opts.NodeID == "" {
+		host, _ := os.Hostname()
+		opts.NodeID = fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "ws:"
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 60 * time.Second
+	}
+	if opts.UserAttr == "" {
+		opts.UserAttr = "user_id"
+	}
+	if cm == nil {
+		cm = GetGlobalConnManager()
+	}
+	return &Cluster{rdb: rdb, cm: cm, opts: opts, stop: make(chan struct{})}
+}
+
+var (
+	clusterMu    sync.Mutex
+	clusterCache sync.Map
+)
+
+// ClusterFromAppConfig 按应用配置ws.cluster创建并启动全局连接管理器的集群中继（未启用时返回nil, nil）
+func ClusterFromAppConfig(appName string) (*Cluster, error) {
+	if v, ok := clusterCache.Load(appName); ok {
+		return v.(*Cluster), nil
+	}
+	clusterMu.Lock()
+	defer clusterMu.Unlock()
+	if v, ok := clusterCache.Load(appName); ok {
+		return v.(*Cluster), nil
+	}
+	cfg := config.GetAppConfig(appName).WebSocket.Cluster
+	if !cfg.Enable {
+		return nil, nil
+	}
+	rdb, err := redisDb.GetRedisDB(cfg.RedisDb)
+	if err != nil {
+		return nil, err
+	}
+	cl := NewCluster(rdb, GetGlobalConnManager(), ClusterOptions{
+		NodeID: cfg.NodeID,
+		Prefix: cfg.Prefix,
+		TTL:    time.Duration(cfg.TTL) * time.Second,
+	})
+	if err := cl.Start(); err != nil {
+		return nil, err
+	}
+	clusterCache.Store(appName, cl)
+	return cl, nil
+}
+
+// NodeID 当前节点ID
+func (cl *Cluster) NodeID() string {
+	return cl.opts.NodeID
+}
+
+// Start 订阅中继频道、注册本节点现有连接并挂载到ConnManager
+func (cl *Cluster) Start() error {
+	cl.pubsub = cl.rdb.Db.Subscribe(cl.key("broadcast"), cl.key("node:"+cl.opts.NodeID))
+	if _, err := cl.pubsub.Receive(); err != nil {
+		_ = cl.pubsub.Close()
+		return fmt.Errorf("websocket: 集群频道订阅失败：%w", err)
+	}
+	cl.cm.cluster.Store(cl)
+	cl.cm.eventBus.Subscribe("websocket.cluster", cl)
+	cl.refresh()
+	cl.done.Add(2)
+	go cl.receiveLoop()
+	go cl.refreshLoop()
+	logger.Info("WS集群中继已启动，节点：", cl.opts.NodeID)
+	return nil
+}
+
+// Stop 停止中继并注销本节点的连接（可重复调用）
+func (cl *Cluster) Stop() {
+	cl.once.Do(func() {
+		cl.cm.cluster.CompareAndSwap(cl, nil)
+		cl.cm.eventBus.Unsubscribe("websocket.cluster")
+		close(cl.stop)
+		if cl.pubsub != nil {
+			_ = cl.pubsub.Close()
+		}
+		cl.done.Wait()
+		var keys []string
+		cl.cm.connMap.Range(func(key, _ interface{}) bool {
+			keys = append(keys, cl.key("conn:"+key.(string)))
+			return true
+		})
+		if len(keys) > 0 {
+			_ = cl.rdb.Db.Del(keys...).Err()
+		}
+	})
+}
+
+// OnConnEvent 实现ConnEventListener：连接上线时注册，下线时注销并移出用户索引
+func (cl *Cluster) OnConnEvent(event ConnEvent) {
+	info := event.ConnInfo
+	switch event.EventType {
+	case EventConnOnline:
+		if err := cl.rdb.Db.Set(cl.key("conn:"+info.ConnID), cl.opts.NodeID, cl.opts.TTL).Err(); err != nil {
+			logger.Warn("WS集群注册连接失败：", info.ConnID, " Err：", err)
+		}
+	case EventConnOffline:
+		pipe := cl.rdb.Db.Pipeline()
+		pipe.Del(cl.key("conn:" + info.ConnID))
+		if userID, ok := info.attrs.Load(cl.opts.UserAttr); ok {
+			if uid, _ := userID.(string); uid != "" {
+				pipe.SRem(cl.key("user:"+uid), info.ConnID)
+			}
+		}
+		if _, err := pipe.Exec(); err != nil {
+			logger.Warn("WS集群注销连接失败：", info.ConnID, " Err：", err)
+		}
+	}
+}
+
+// BindUser 将连接绑定到用户（写入连接属性与Redis用户索引）
+func (cl *Cluster) BindUser(ctx context.Context, connID, userID string) error {
+	if userID == "" {
+		return errors.New("用户ID不能为空")
+	}
+	cl.cm.SetConnAttr(connID, cl.opts.UserAttr, userID)
+	key := cl.key("user:" + userID)
+	pipe := cl.client(ctx).Pipeline()
+	pipe.SAdd(key, connID)
+	pipe.Expire(key, 24*time.Hour)
+	_, err := pipe.Exec()
+	return err
+}
+
+// UnbindUser 解除连接与用户的绑定
+func (cl *Cluster) UnbindUser(ctx context.Context, connID, userID string) error {
+	cl.cm.SetConnAttr(connID, cl.opts.UserAttr, "")
+	return cl.client(ctx).SRem(cl.key("user:"+userID), connID).Err()
+}
+
+// UserConnIDs 获取用户在全部节点上的在线连接ID（顺带清理已失效的条目）
+func (cl *Cluster) UserConnIDs(ctx context.Context, userID string) ([]string, error) {
+	client := cl.client(ctx)
+	connIDs, err := client.SMembers(cl.key("user:" + userID)).Result()
+	if err != nil || len(connIDs) == 0 {
+		return nil, err
+	}
+	nodes, err := cl.lookup(ctx, connIDs)
+	if err != nil {
+		return nil, err
+	}
+	online := make([]string, 0, len(connIDs))
+	var stale []interface{}
+	for _, connID := range connIDs {
+		if _, ok := nodes[connID]; ok {
+			online = append(online, connID)
+		} else {
+			stale = append(stale, connID)
+		}
+	}
+	if len(stale) > 0 {
+		_ = client.SRem(cl.key("user:"+userID), stale...).Err()
+	}
+	return online, nil
+}
+
+// SendToUser 给用户在全部节点上的连接发送消息
+func (cl *Cluster) SendToUser(ctx context.Context, userID, message string) error {
+	connIDs, err := cl.UserConnIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return cl.Multicast(ctx, connIDs, message)
+}
+
+// Broadcast 向全部节点的连接群发消息
+func (cl *Cluster) Broadcast(ctx context.Context, message string) error {
+	cl.cm.broadcastLocal(message)
+	return cl.publish(ctx, cl.key("broadcast"), clusterEnvelope{Message: message})
+}
+
+// Multicast 向指定连接群发消息（本节点连接直接写出，其他节点的连接按所在节点中继）
+func (cl *Cluster) Multicast(ctx context.Context, connIDs []string, message string) error {
+	var remote []string
+	for _, connID := range connIDs {
+		if info, ok := cl.cm.GetConnInfoByConnID(connID); ok {
+			_ = info.Conn.WriteMessage(message)
+		} else {
+			remote = append(remote, connID)
+		}
+	}
+	if len(remote) == 0 {
+		return nil
+	}
+	nodes, err := cl.lookup(ctx, remote)
+	if err != nil {
+		return err
+	}
+	byNode := make(map[string][]string)
+	for _, connID := range remote {
+		if node, ok := nodes[connID]; ok && node != cl.opts.NodeID {
+			byNode[node] = append(byNode[node], connID)
+		}
+	}
+	var errs []error
+	for node, ids := range byNode {
+		if err := cl.publish(ctx, cl.key("node:"+node), clusterEnvelope{ConnIDs: ids, Message: message}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendToConnID 给单个连接发送消息（连接不在任何节点时返回错误）
+func (cl *Cluster) SendToConnID(ctx context.Context, connID, message string) error {
+	if info, ok := cl.cm.GetConnInfoByConnID(connID); ok {
+		return info.Conn.WriteMessage(message)
+	}
+	nodes, err := cl.lookup(ctx, []string{connID})
+	if err != nil {
+		return err
+	}
+	node, ok := nodes[connID]
+	if !ok || node == cl.opts.NodeID {
+		return errors.New("connection not found: " + connID)
+	}
+	return cl.publish(ctx, cl.key("node:"+node), clusterEnvelope{ConnIDs: []string{connID}, Message: message})
+}
+
+// lookup 批量查询连接所在节点（不存在的连接不出现在结果中）
+func (cl *Cluster) lookup(ctx context.Context, connIDs []string) (map[string]string, error) {
+	keys := make([]string, len(connIDs))
+	for i, connID := range connIDs {
+		keys[i] = cl.key("conn:" + connID)
+	}
+	values, err := cl.client(ctx).MGet(keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]string, len(connIDs))
+	for i, v := range values {
+		if node, ok := v.(string); ok && node != "" {
+			nodes[connIDs[i]] = node
+		}
+	}
+	return nodes, nil
+}
+
+// publish 发布中继消息
+func (cl *Cluster) publish(ctx context.Context, channel string, env clusterEnvelope) error {
+	env.Origin = cl.opts.NodeID
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return cl.client(ctx).Publish(channel, data).Err()
+}
+
+// receiveLoop 处理其他节点中继过来的消息（订阅连接断开时go-redis自动重连并重新订阅）
+func (cl *Cluster) receiveLoop() {
+	defer cl.done.Done()
+	ch := cl.pubsub.Channel()
+	for {
+		select {
+		case <-cl.stop:
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var env clusterEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				logger.Warn("WS集群消息格式错误：", err)
+				continue
+			}
+			if env.Origin == cl.opts.NodeID {
+				continue
+			}
+			if env.AckMsgID != "" {
+				if len(env.ConnIDs) > 0 {
+					resolveAck(env.AckMsgID, env.ConnIDs[0])
+				}
+				continue
+			}
+			if len(env.ConnIDs) == 0 {
+				cl.cm.broadcastLocal(env.Message)
+				continue
+			}
+			for _, connID := range env.ConnIDs {
+				if info, ok := cl.cm.GetConnInfoByConnID(connID); ok {
+					_ = info.Conn.WriteMessage(env.Message)
+				}
+			}
+		}
+	}
+}
+
+// refreshLoop 定期续期本节点连接的注册
+func (cl *Cluster) refreshLoop() {
+	defer cl.done.Done()
+	ticker := time.NewTicker(cl.opts.TTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cl.stop:
+			return
+		case <-ticker.C:
+			cl.refresh()
+		}
+	}
+}
+
+// refresh 批量写入本节点全部连接的注册（同时覆盖启动前已存在的连接）
+func (cl *Cluster) refresh() {
+	pipe := cl.rdb.Db.Pipeline()
+	n := 0
+	cl.cm.connMap.Range(func(key, _ interface{}) bool {
+		pipe.Set(cl.key("conn:"+key.(string)), cl.opts.NodeID, cl.opts.TTL)
+		n++
+		return true
+	})
+	if n == 0 {
+		return
+	}
+	if _, err := pipe.Exec(); err != nil {
+		logger.Warn("WS集群续期连接注册失败：", err)
+	}
+}
+
+func (cl *Cluster) key(suffix string) string {
+	return cl.rdb.DbPre + cl.opts.Prefix + suffix
+}
+
+func (cl *Cluster) client(ctx context.Context) *redis.Client {
+	if ctx == nil {
+		return cl.rdb.Db
+	}
+	return cl.rdb.WithContext(ctx).Db
+}
@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 消息类型（RFC 6455 数据帧操作码）
+const (
+	TextMessage   = opCodeText
+	BinaryMessage = opCodeBinary
+)
+
+// rsv1Bit 帧首字节RSV1位（permessage-deflate下表示消息已压缩）
+const rsv1Bit = 0x40
+
+// deflateTail 压缩数据块结尾的空存储块（RFC 7692 7.2.1：发送方去除，接收方补回）
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// ErrDecompressTooLarge 解压后的消息超过大小上限（防止压缩炸弹）
+var ErrDecompressTooLarge = errors.New("websocket: 解压后的消息超过大小上限")
+
+// negotiateDeflate 解析客户端的permessage-deflate提议，返回响应头（不接受时返回空字符串）。
+// 服务端固定使用无上下文接管模式（每条消息独立压缩），不保留跨消息的滑动窗口，内存占用与连接数无关
+func negotiateDeflate(h http.Header) string {
+	for _, ext := range h.Values("Sec-WebSocket-Extensions") {
+		for _, offer := range strings.Split(ext, ",") {
+			params := strings.Split(offer, ";")
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				continue
+			}
+			acceptable := true
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				switch strings.TrimSpace(name) {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					// compress/flate固定使用32KB窗口，客户端要求更小的窗口时无法满足
+					bits, err := strconv.Atoi(strings.Trim(strings.TrimSpace(value), `"`))
+					if err != nil || bits != 15 {
+						acceptable = false
+					}
+				default:
+					acceptable = false
+				}
+			}
+			if acceptable {
+				return "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+			}
+		}
+	}
+	return ""
+}
+
+// flateWriterPools 按压缩级别复用flate.Writer
+var flateWriterPools sync.Map // level -> *sync.Pool
+
+func flateWriterPool(level int) *sync.Pool {
+	if pool, ok := flateWriterPools.Load(level); ok {
+		return pool.(*sync.Pool)
+	}
+	pool, _ := flateWriterPools.LoadOrStore(level, &sync.Pool{New: func() interface{} {
+		w, _ := flate.NewWriter(nil, level)
+		return w
+	}})
+	return pool.(*sync.Pool)
+}
+
+// compressMessage 压缩消息负载（去除结尾的空存储块）
+func compressMessage(payload []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	pool := flateWriterPool(level)
+	w := pool.Get().(*flate.Writer)
+	defer pool.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail), nil
+}
+
+// decompressMessage 解压消息负载（结果超过limit字节时返回ErrDecompressTooLarge）
+func decompressMessage(payload []byte, limit int64) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail)))
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrDecompressTooLarge
+	}
+	return data, nil
+}
+
+// prepareFrame 满足压缩条件时压缩数据帧并在操作码上附加RSV1位
+func (c *Conn) prepareFrame(opCode byte, payload []byte) (byte, []byte) {
+	if !c.compress || len(payload) < c.compressThreshold || (opCode != opCodeText && opCode != opCodeBinary) {
+		return opCode, payload
+	}
+	compressed, err := compressMessage(payload, c.compressLevel)
+	if err != nil || len(compressed) >= len(payload) {
+		return opCode, payload
+	}
+	return opCode | rsv1Bit, compressed
+}
+
+// Compressed 连接是否协商启用了permessage-deflate
+func (c *Conn) Compressed() bool {
+	return c.compress
+}
@@ -9,6 +9,8 @@ import (
 const (
 	EventConnOnline  = "websocket.conn.online"  // 连接上线事件
 	EventConnOffline = "websocket.conn.offline" // 连接下线事件
+	EventRoomJoin    = "websocket.room.join"    // 加入房间事件
+	EventRoomLeave   = "websocket.room.leave"   // 离开房间事件（含断开时自动离开）
 )
 
 // ConnEvent 连接事件结构体（携带完整事件信息）
@@ -16,7 +18,8 @@ type ConnEvent struct {
 	EventType   string    // 事件类型
 	ConnInfo    *ConnInfo // 连接详情
 	TriggerTime time.Time // 事件触发时间
-	CloseReason string    // 下线原因（仅离线事件有效）
+	CloseReason string    // 下线原因（仅离线事件及断开导致的离开房间事件有效）
+	Room        string    // 房间名（仅房间事件有效）
 }
 
 // ConnEventListener 应用层事件监听器接口（应用层需实现该接口）
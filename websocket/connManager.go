@@ -1,26 +1,41 @@
 package websocket
 
 import (
+	"context"
 	"errors"
 	"github.com/dfpopp/go-dai/logger"
 	"github.com/google/uuid"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // ConnInfo 连接信息结构体
 type ConnInfo struct {
-	Conn     *Conn     // WS连接实例
-	ConnID   string    // 唯一连接ID
-	ClientIP string    // 客户端IP
-	CreateAt time.Time // 连接创建时间
-	attrs    sync.Map  // 应用层自定义属性（如用户ID）
+	Conn     *Conn               // WS连接实例
+	ConnID   string              // 唯一连接ID
+	ClientIP string              // 客户端IP
+	CreateAt time.Time           // 连接创建时间
+	attrs    sync.Map            // 应用层自定义属性（如用户ID）
+	tags     map[string]struct{} // 连接标签（由ConnManager.tagMu保护）
+	rooms    map[string]struct{} // 已加入的房间（由ConnManager.roomMu保护）
 }
 
 // ConnManager 连接管理器（单例）
 type ConnManager struct {
 	connMap  sync.Map      // key: ConnID, value: *ConnInfo
 	eventBus *ConnEventBus // 事件总线
+	draining atomic.Bool   // 是否处于排空状态
+	retryAt  atomic.Int64  // 排空期间建议客户端重试的等待秒数
+	tagMu    sync.RWMutex
+	tagIndex connIndex // 标签倒排索引，key: 标签，value: ConnID集合
+	roomMu   sync.RWMutex
+	rooms    connIndex               // 房间成员，key: 房间名，value: ConnID集合
+	cluster  atomic.Pointer[Cluster] // 多节点中继（为nil时仅投递本节点连接）
+	// delay 定时/延迟消息队列（为nil时未启用SendToUserAt）
+	delay atomic.Pointer[DelayQueue]
+	// history 房间消息历史（为nil时不记录）
+	history atomic.Pointer[RoomHistory]
 }
 
 // 全局连接管理器实例
@@ -73,6 +88,10 @@ func (cm *ConnManager) RemoveConn(connID string, closeReason string) {
 		return
 	}
 	info := connInfo.(*ConnInfo)
+	cm.tagMu.Lock()
+	cm.clearTags(info)
+	cm.tagMu.Unlock()
+	cm.leaveAllRooms(info, closeReason)
 	logger.Info("WS连接下线", "connID", connID, "clientIP", info.ClientIP, "reason", closeReason, "totalConn", cm.GetConnCount())
 
 	// 发布下线事件
@@ -114,8 +133,24 @@ func (cm *ConnManager) GetConnCount() int {
 	return count
 }
 
-// Broadcast 群发消息（应用层调用）
+// Cluster 获取多节点中继（未启用时返回nil）
+func (cm *ConnManager) Cluster() *Cluster {
+	return cm.cluster.Load()
+}
+
+// Broadcast 群发消息（应用层调用，启用集群时投递到全部节点）
 func (cm *ConnManager) Broadcast(message string) {
+	if cl := cm.cluster.Load(); cl != nil {
+		if err := cl.Broadcast(context.Background(), message); err != nil {
+			logger.Warn("WS集群广播失败：", err)
+		}
+		return
+	}
+	cm.broadcastLocal(message)
+}
+
+// broadcastLocal 向本节点的全部连接群发消息
+func (cm *ConnManager) broadcastLocal(message string) {
 	cm.connMap.Range(func(_, value interface{}) bool {
 		connInfo := value.(*ConnInfo)
 		_ = connInfo.Conn.WriteMessage(message)
@@ -123,8 +158,14 @@ func (cm *ConnManager) Broadcast(message string) {
 	})
 }
 
-// Multicast 定向群发消息（应用层调用）
+// Multicast 定向群发消息（应用层调用，启用集群时不在本节点的连接经中继投递）
 func (cm *ConnManager) Multicast(connIDs []string, message string) {
+	if cl := cm.cluster.Load(); cl != nil {
+		if err := cl.Multicast(context.Background(), connIDs, message); err != nil {
+			logger.Warn("WS集群定向群发失败：", err)
+		}
+		return
+	}
 	for _, connID := range connIDs {
 		if connInfo, exists := cm.connMap.Load(connID); exists {
 			info := connInfo.(*ConnInfo)
@@ -135,8 +176,11 @@ func (cm *ConnManager) Multicast(connIDs []string, message string) {
 	}
 }
 
-// SendToConnID 给单个ConnID发送消息（应用层调用）
+// SendToConnID 给单个ConnID发送消息（应用层调用，启用集群时可发送到其他节点的连接）
 func (cm *ConnManager) SendToConnID(connID string, message string) error {
+	if cl := cm.cluster.Load(); cl != nil {
+		return cl.SendToConnID(context.Background(), connID, message)
+	}
 	connInfo, exists := cm.connMap.Load(connID)
 	if !exists {
 		return errors.New("connection not found: " + connID)
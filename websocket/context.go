@@ -1,7 +1,10 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/dfpopp/go-dai/auth"
+	"github.com/dfpopp/go-dai/i18n"
 	"github.com/dfpopp/go-dai/logger"
 	"github.com/dfpopp/go-dai/netContext"
 	"net"
@@ -19,11 +22,17 @@ type Context struct {
 	params    map[string]string // 存储查询参数/POST参数（模拟HTTP参数）
 	rawData   []byte            // 原始消息数据（对应HTTP请求体）
 	ConnID    string            // 新增：当前连接的唯一ID
+	ctx       context.Context   // 单条消息的请求级context
+	// MessageType 消息类型（TextMessage/BinaryMessage），二进制消息的data同样按JSON协议解析
+	MessageType int
+	// Meta 消息信封中的扩展字段（由连接协商的协议版本解析，v1中为action/request_id/data以外的顶层字段）
+	Meta     map[string]json.RawMessage
+	panicked bool // 处理器已panic（连接随后以1011关闭）
 }
 
 // NewContext 创建WS上下文（对应HTTP上下文初始化）
 func NewContext(conn *Conn, req *http.Request, action, requestId, connID string, rawData []byte) *Context {
-	return &Context{
+	c := &Context{
 		Conn:      conn,
 		Req:       req,
 		Action:    action,
@@ -32,6 +41,11 @@ func NewContext(conn *Conn, req *http.Request, action, requestId, connID string,
 		params:    make(map[string]string),
 		ConnID:    connID, // 赋值连接ID
 	}
+	c.ctx = netContext.WithRequestCache(c.GetContext()) // 每条消息独立的请求级缓存
+	if req != nil {
+		c.ctx = auth.WithClientIdentity(c.ctx, auth.IdentityFromTLS(req.TLS)) // 握手连接上已校验的客户端证书身份（mTLS）
+	}
+	return c
 }
 
 // -------------------------- 通用控制器签名 --------------------------
@@ -50,8 +64,9 @@ func ToWSHandler(fn WSHandlerFunc) HandlerFunc {
 
 // -------------------------- 编译期校验 --------------------------
 var (
-	_ netContext.Context     = (*Context)(nil) // 验证上下文接口实现
-	_ netContext.RequestInfo = (*Context)(nil) // 验证请求信息接口实现
+	_ netContext.Context      = (*Context)(nil) // 验证上下文接口实现
+	_ netContext.RequestInfo  = (*Context)(nil) // 验证请求信息接口实现
+	_ netContext.RemoteIPInfo = (*Context)(nil) // 验证对端IP接口实现
 )
 
 // -------------------------- 新增：ConnIDContext接口（扩展通用上下文） --------------------------
@@ -78,6 +93,19 @@ func (c *Context) GetPath() string {
 	return c.Action // WS场景：用action作为请求唯一标识
 }
 
+// GetRemoteIP 连接的对端IP（取自握手请求的RemoteAddr，不读取代理请求头）
+func (c *Context) GetRemoteIP() string {
+	if c.Req == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(c.Req.RemoteAddr)
+	if err != nil {
+		return c.Req.RemoteAddr
+	}
+	return host
+}
+
+// GetClientIP 客户端IP（优先读取X-Real-IP/X-Forwarded-For，可被客户端伪造，仅用于日志展示等非安全场景）
 func (c *Context) GetClientIP() string {
 	// 复用IP获取逻辑（从WS握手请求中提取）
 	ip := c.Req.Header.Get("X-Real-IP")
@@ -113,10 +141,32 @@ func (c *Context) GetRequestInfo() netContext.RequestInfo {
 	return c // WS上下文自身实现了RequestInfo，直接返回
 }
 
+// GetContext 获取单条消息的请求级context（未设置时沿用握手请求的context）
+func (c *Context) GetContext() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	if c.Req != nil {
+		return c.Req.Context()
+	}
+	return context.Background()
+}
+
+// SetContext 替换请求级context（中间件注入链路信息等）
+func (c *Context) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
 // -------------------------- 与http.Context一致的方法实现 --------------------------
 
 // JSON 统一JSON响应（与HTTP上下文JSON方法完全一致）
 func (c *Context) JSON(code int, data map[string]interface{}) {
+	// 回写请求ID，便于客户端关联请求与响应
+	if c.RequestId != "" && data != nil {
+		if _, ok := data["request_id"]; !ok {
+			data["request_id"] = c.RequestId
+		}
+	}
 	// 序列化响应并发送
 	respBytes, err := json.Marshal(data)
 	if err != nil {
@@ -125,6 +175,34 @@ func (c *Context) JSON(code int, data map[string]interface{}) {
 	}
 	_ = c.Conn.WriteMessage(string(respBytes))
 }
+
+// Error 发送本地化错误帧（key为i18n文案key，如i18n.MsgAuthFailed；自动回写request_id）
+func (c *Context) Error(code int, key string, args ...interface{}) {
+	c.JSON(200, map[string]interface{}{
+		"code": code,
+		"msg":  c.T(key, args...),
+		"data": nil,
+	})
+}
+
+// ProtocolVersion 当前连接握手时协商的协议版本
+func (c *Context) ProtocolVersion() string {
+	return c.Conn.ProtocolVersion()
+}
+
+// Locale 当前连接握手时协商的语言（查询参数lang优先，其次Accept-Language）
+func (c *Context) Locale() string {
+	if c.Conn != nil {
+		return c.Conn.Locale()
+	}
+	return i18n.FromRequest(c.Req)
+}
+
+// T 按当前连接语言获取文案
+func (c *Context) T(key string, args ...interface{}) string {
+	return i18n.T(c.Locale(), key, args...)
+}
+
 func (c *Context) String(code int, s string) {
 	_ = c.Conn.WriteMessage(s)
 }
@@ -206,6 +284,19 @@ func (c *Context) BindJSON(v interface{}) error {
 	return json.Unmarshal(c.rawData, v)
 }
 
+// Bind 合并绑定握手查询参数与消息数据到结构体（消息数据为JSON对象时按json标签，为key=value&...时按form标签；
+// SetParam设置的参数按query标签绑定）
+func (c *Context) Bind(v interface{}) error {
+	query := url.Values{}
+	if c.Req != nil {
+		query = c.Req.URL.Query()
+	}
+	for key, val := range c.params {
+		query.Set(key, val)
+	}
+	return netContext.Bind(v, netContext.PayloadSources(query, c.rawData))
+}
+
 // SetParam 手动设置参数（供中间件使用，兼容HTTP上下文参数传递）
 func (c *Context) SetParam(key, value string) {
 	c.params[key] = value
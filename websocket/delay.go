@@ -0,0 +1,509 @@
+package websocket
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// 定时/延迟消息：SendToUserAt/SendToUserAfter将消息写入延迟队列，到期时投递给用户当前的在线连接；
+// 用户不在线时转入该用户的离线队列，用户再次绑定连接（BaseController.BindUserID）时补发。
+//   - 使用Redis时多节点共享队列：{prefix}delay为到期时间有序集合，{prefix}delay:msg保存消息内容，
+//     到期消息由Lua脚本原子取出，多个节点同时检查时每条消息只投递一次；离线队列为列表{prefix}offline:{userID}
+//   - 未配置Redis时使用进程内队列（单节点，重启后未到期与离线的消息丢失）
+//
+// 投递为至多一次：消息取出后节点崩溃会丢失，需确认的关键消息应在到期回调中使用SendToUserWithAck。
+// 投递的消息比普通推送多一个deliver_at字段（原定投递时间，Unix秒），离线补发时客户端可据此判断消息的时效。
+
+// ErrDelayDisabled 未启用定时/延迟消息
+var ErrDelayDisabled = errors.New("websocket: 未启用定时消息（配置websocket.delay.enable）")
+
+const (
+	defaultDelayPollInterval = time.Second
+	defaultDelayBatch        = 100
+	defaultOfflineTTL        = 7 * 24 * time.Hour
+	defaultOfflineMax        = 100
+)
+
+// DelayOptions 延迟队列参数
+type DelayOptions struct {
+	Prefix       string        // 键前缀（默认ws:）
+	PollInterval time.Duration // 到期检查间隔（默认1秒，即投递精度）
+	Batch        int           // 每次最多取出的到期消息数（默认100）
+	OfflineTTL   time.Duration // 离线队列保留时长（默认7天，<0表示用户不在线时直接丢弃）
+	OfflineMax   int           // 每个用户离线队列的最大长度（默认100）
+	UserAttr     string        // 连接属性中用户ID的key（默认user_id，与BaseController.UserIDField一致）
+}
+
+// delayedMessage 队列中的消息
+type delayedMessage struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Message   string `json:"message"`
+	DeliverAt int64  `json:"deliver_at"` // 毫秒
+}
+
+// delayStore 延迟队列与离线队列的存储
+type delayStore interface {
+	push(ctx context.Context, msg delayedMessage) error
+	popDue(ctx context.Context, now time.Time, n int) ([]delayedMessage, error)
+	cancel(ctx context.Context, id string) (bool, error)
+	pushOffline(ctx context.Context, userID, message string, ttl time.Duration, limit int) error
+	popOffline(ctx context.Context, userID string) ([]string, error)
+}
+
+// DelayQueue WS定时/延迟消息队列
+type DelayQueue struct {
+	cm    *ConnManager
+	opts  DelayOptions
+	store delayStore
+	stop  chan struct{}
+	done  sync.WaitGroup
+	once  sync.Once
+}
+
+// NewDelayQueue 创建延迟队列（rdb为nil时使用进程内队列；调用Start后生效）
+func NewDelayQueue(rdb *redisDb.RedisDb, cm *ConnManager, opts DelayOptions) *DelayQueue {
+	if opts.Prefix == "" {
+		opts.Prefix = "ws:"
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultDelayPollInterval
+	}
+	if opts.Batch <= 0 {
+		opts.Batch = defaultDelayBatch
+	}
+	if opts.OfflineTTL == 0 {
+		opts.OfflineTTL = defaultOfflineTTL
+	}
+	if opts.OfflineMax <= 0 {
+		opts.OfflineMax = defaultOfflineMax
+	}
+	if opts.UserAttr == "" {
+		opts.UserAttr = "user_id"
+	}
+	if cm == nil {
+		cm = GetGlobalConnManager()
+	}
+	q := &DelayQueue{cm: cm, opts: opts, stop: make(chan struct{})}
+	if rdb != nil {
+		q.store = &redisDelayStore{rdb: rdb, prefix: rdb.DbPre + opts.Prefix}
+	} else {
+		q.store = newMemoryDelayStore()
+	}
+	return q
+}
+
+var (
+	delayMu    sync.Mutex
+	delayCache sync.Map
+)
+
+// DelayQueueFromAppConfig 按应用配置ws.delay创建并启动全局连接管理器的延迟队列（未启用时返回nil, nil）
+func DelayQueueFromAppConfig(appName string) (*DelayQueue, error) {
+	if v, ok := delayCache.Load(appName); ok {
+		return v.(*DelayQueue), nil
+	}
+	delayMu.Lock()
+	defer delayMu.Unlock()
+	if v, ok := delayCache.Load(appName); ok {
+		return v.(*DelayQueue), nil
+	}
+	cfg := config.GetAppConfig(appName).WebSocket.Delay
+	if !cfg.Enable {
+		return nil, nil
+	}
+	var rdb *redisDb.RedisDb
+	if cfg.RedisDb != "" {
+		var err error
+		if rdb, err = redisDb.GetRedisDB(cfg.RedisDb); err != nil {
+			return nil, err
+		}
+	}
+	q := NewDelayQueue(rdb, GetGlobalConnManager(), DelayOptions{
+		Prefix:       cfg.Prefix,
+		PollInterval: time.Duration(cfg.PollInterval) * time.Millisecond,
+		Batch:        cfg.Batch,
+		OfflineTTL:   time.Duration(cfg.OfflineTTL) * time.Second,
+		OfflineMax:   cfg.OfflineMax,
+	})
+	q.Start()
+	delayCache.Store(appName, q)
+	return q, nil
+}
+
+// Start 挂载到ConnManager并开始检查到期消息
+func (q *DelayQueue) Start() {
+	q.cm.delay.Store(q)
+	q.done.Add(1)
+	go q.pollLoop()
+	logger.Info("WS定时消息队列已启动")
+}
+
+// Stop 停止检查到期消息（未到期的消息保留在队列中，可重复调用）
+func (q *DelayQueue) Stop() {
+	q.once.Do(func() {
+		q.cm.delay.CompareAndSwap(q, nil)
+		close(q.stop)
+		q.done.Wait()
+	})
+}
+
+// Schedule 在deliverAt投递消息给用户，返回消息ID（即消息的request_id，可用于Cancel；deliverAt已过时在下次检查时投递）
+func (q *DelayQueue) Schedule(ctx context.Context, userID, action string, data interface{}, deliverAt time.Time) (string, error) {
+	if userID == "" || action == "" {
+		return "", errors.New("目标用户ID和消息动作不能为空")
+	}
+	id := uuid.NewString()
+	message, err := json.Marshal(map[string]interface{}{
+		"action":     action,
+		"request_id": id,
+		"data":       data,
+		"deliver_at": deliverAt.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	msg := delayedMessage{ID: id, UserID: userID, Message: string(message), DeliverAt: deliverAt.UnixMilli()}
+	if err := q.store.push(ctx, msg); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Cancel 取消尚未投递的消息（已投递或不存在时返回false）
+func (q *DelayQueue) Cancel(ctx context.Context, msgID string) (bool, error) {
+	return q.store.cancel(ctx, msgID)
+}
+
+// DeliverOffline 将用户离线队列中的消息补发到指定连接，返回补发条数
+func (q *DelayQueue) DeliverOffline(ctx context.Context, userID, connID string) (int, error) {
+	if q.opts.OfflineTTL < 0 {
+		return 0, nil
+	}
+	messages, err := q.store.popOffline(ctx, userID)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+	for i, message := range messages {
+		if err := q.cm.SendToConnID(connID, message); err != nil {
+			// 未补发的消息放回离线队列
+			for _, rest := range messages[i:] {
+				_ = q.store.pushOffline(ctx, userID, rest, q.opts.OfflineTTL, q.opts.OfflineMax)
+			}
+			return i, err
+		}
+	}
+	return len(messages), nil
+}
+
+// pollLoop 定期取出到期消息并投递
+func (q *DelayQueue) pollLoop() {
+	defer q.done.Done()
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+			q.poll()
+		}
+	}
+}
+
+// poll 取出全部到期消息（每批Batch条，取满时继续取下一批）
+func (q *DelayQueue) poll() {
+	ctx := context.Background()
+	for {
+		due, err := q.store.popDue(ctx, time.Now(), q.opts.Batch)
+		if err != nil {
+			logger.Warn("WS定时消息读取失败：", err)
+			return
+		}
+		for _, msg := range due {
+			q.deliver(ctx, msg)
+		}
+		if len(due) < q.opts.Batch {
+			return
+		}
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+	}
+}
+
+// deliver 投递到用户的在线连接，用户不在线时转入离线队列
+func (q *DelayQueue) deliver(ctx context.Context, msg delayedMessage) {
+	connIDs, err := q.userConnIDs(ctx, msg.UserID)
+	if err != nil {
+		logger.Warn("WS定时消息查询用户连接失败，转入离线队列：", msg.ID, " Err：", err)
+	}
+	if len(connIDs) > 0 {
+		q.cm.Multicast(connIDs, msg.Message)
+		return
+	}
+	if q.opts.OfflineTTL < 0 {
+		logger.Debug("WS定时消息到期时用户不在线，已丢弃：", msg.ID)
+		return
+	}
+	if err := q.store.pushOffline(ctx, msg.UserID, msg.Message, q.opts.OfflineTTL, q.opts.OfflineMax); err != nil {
+		logger.Warn("WS定时消息写入离线队列失败：", msg.ID, " Err：", err)
+	}
+}
+
+// userConnIDs 用户的在线连接（启用集群时查询全部节点，否则遍历本节点连接的用户属性）
+func (q *DelayQueue) userConnIDs(ctx context.Context, userID string) ([]string, error) {
+	if cl := q.cm.cluster.Load(); cl != nil {
+		return cl.UserConnIDs(ctx, userID)
+	}
+	var connIDs []string
+	q.cm.connMap.Range(func(key, value interface{}) bool {
+		if uid, ok := value.(*ConnInfo).attrs.Load(q.opts.UserAttr); ok && uid == userID {
+			connIDs = append(connIDs, key.(string))
+		}
+		return true
+	})
+	return connIDs, nil
+}
+
+// DelayQueue 获取定时消息队列（未启用时返回nil）
+func (cm *ConnManager) DelayQueue() *DelayQueue {
+	return cm.delay.Load()
+}
+
+// SendToUserAt 在deliverAt给用户发送消息（到期时用户不在线则进入离线队列，用户绑定连接后补发），返回消息ID
+func (cm *ConnManager) SendToUserAt(ctx context.Context, userID, action string, data interface{}, deliverAt time.Time) (string, error) {
+	q := cm.delay.Load()
+	if q == nil {
+		return "", ErrDelayDisabled
+	}
+	return q.Schedule(ctx, userID, action, data, deliverAt)
+}
+
+// SendToUserAfter 在delay之后给用户发送消息（见SendToUserAt）
+func (cm *ConnManager) SendToUserAfter(ctx context.Context, userID, action string, data interface{}, delay time.Duration) (string, error) {
+	return cm.SendToUserAt(ctx, userID, action, data, time.Now().Add(delay))
+}
+
+// CancelScheduled 取消尚未投递的定时消息
+func (cm *ConnManager) CancelScheduled(ctx context.Context, msgID string) (bool, error) {
+	q := cm.delay.Load()
+	if q == nil {
+		return false, ErrDelayDisabled
+	}
+	return q.Cancel(ctx, msgID)
+}
+
+// DeliverOffline 将用户离线队列中的定时消息补发到连接（BaseController.BindUserID自动调用，未启用时返回0）
+func (cm *ConnManager) DeliverOffline(ctx context.Context, userID, connID string) (int, error) {
+	q := cm.delay.Load()
+	if q == nil {
+		return 0, nil
+	}
+	return q.DeliverOffline(ctx, userID, connID)
+}
+
+// popDueScript 原子取出到期消息：KEYS[1]为有序集合，KEYS[2]为消息内容哈希，ARGV为当前时间（毫秒）与条数
+var popDueScript = redis.NewScript(`local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local out = {}
+for _, id in ipairs(ids) do
+  redis.call('ZREM', KEYS[1], id)
+  local v = redis.call('HGET', KEYS[2], id)
+  if v then
+    redis.call('HDEL', KEYS[2], id)
+    table.insert(out, v)
+  end
+end
+return out`)
+
+// cancelScript 取消未投递的消息
+var cancelScript = redis.NewScript(`if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then return 0 end
+redis.call('HDEL', KEYS[2], ARGV[1])
+return 1`)
+
+// redisDelayStore Redis延迟队列（多节点共享）
+type redisDelayStore struct {
+	rdb    *redisDb.RedisDb
+	prefix string
+}
+
+func (s *redisDelayStore) keys() []string {
+	return []string{s.prefix + "delay", s.prefix + "delay:msg"}
+}
+
+func (s *redisDelayStore) push(ctx context.Context, msg delayedMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	keys := s.keys()
+	_, err = s.rdb.WithContext(ctx).Db.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HSet(keys[1], msg.ID, data)
+		pipe.ZAdd(keys[0], redis.Z{Score: float64(msg.DeliverAt), Member: msg.ID})
+		return nil
+	})
+	return err
+}
+
+func (s *redisDelayStore) popDue(ctx context.Context, now time.Time, n int) ([]delayedMessage, error) {
+	values, err := popDueScript.Run(s.rdb.WithContext(ctx).Db, s.keys(), now.UnixMilli(), n).Result()
+	if err != nil {
+		return nil, err
+	}
+	list, _ := values.([]interface{})
+	due := make([]delayedMessage, 0, len(list))
+	for _, v := range list {
+		var msg delayedMessage
+		if str, ok := v.(string); ok && json.Unmarshal([]byte(str), &msg) == nil {
+			due = append(due, msg)
+		}
+	}
+	return due, nil
+}
+
+func (s *redisDelayStore) cancel(ctx context.Context, id string) (bool, error) {
+	n, err := cancelScript.Run(s.rdb.WithContext(ctx).Db, s.keys(), id).Int64()
+	return n == 1, err
+}
+
+func (s *redisDelayStore) pushOffline(ctx context.Context, userID, message string, ttl time.Duration, limit int) error {
+	key := s.prefix + "offline:" + userID
+	_, err := s.rdb.WithContext(ctx).Db.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.RPush(key, message)
+		pipe.LTrim(key, int64(-limit), -1)
+		pipe.Expire(key, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *redisDelayStore) popOffline(ctx context.Context, userID string) ([]string, error) {
+	key := s.prefix + "offline:" + userID
+	var values *redis.StringSliceCmd
+	_, err := s.rdb.WithContext(ctx).Db.TxPipelined(func(pipe redis.Pipeliner) error {
+		values = pipe.LRange(key, 0, -1)
+		pipe.Del(key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values.Val(), nil
+}
+
+// memoryDelayStore 进程内延迟队列（按到期时间的最小堆，取消的消息在出堆时跳过）
+type memoryDelayStore struct {
+	mu      sync.Mutex
+	queue   delayHeap
+	pending map[string]struct{}
+	offline map[string]*offlineList
+}
+
+// offlineList 进程内离线队列
+type offlineList struct {
+	messages []string
+	expireAt time.Time
+}
+
+func newMemoryDelayStore() *memoryDelayStore {
+	return &memoryDelayStore{pending: make(map[string]struct{}), offline: make(map[string]*offlineList)}
+}
+
+func (s *memoryDelayStore) push(_ context.Context, msg delayedMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	heap.Push(&s.queue, msg)
+	s.pending[msg.ID] = struct{}{}
+	return nil
+}
+
+func (s *memoryDelayStore) popDue(_ context.Context, now time.Time, n int) ([]delayedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []delayedMessage
+	for len(due) < n && s.queue.Len() > 0 && s.queue[0].DeliverAt <= now.UnixMilli() {
+		msg := heap.Pop(&s.queue).(delayedMessage)
+		if _, ok := s.pending[msg.ID]; !ok {
+			continue
+		}
+		delete(s.pending, msg.ID)
+		due = append(due, msg)
+	}
+	// 清理过期的离线队列
+	for userID, list := range s.offline {
+		if now.After(list.expireAt) {
+			delete(s.offline, userID)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryDelayStore) cancel(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[id]; !ok {
+		return false, nil
+	}
+	delete(s.pending, id)
+	return true, nil
+}
+
+func (s *memoryDelayStore) pushOffline(_ context.Context, userID, message string, ttl time.Duration, limit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, ok := s.offline[userID]
+	if !ok {
+		list = &offlineList{}
+		s.offline[userID] = list
+	}
+	list.messages = append(list.messages, message)
+	if len(list.messages) > limit {
+		list.messages = list.messages[len(list.messages)-limit:]
+	}
+	list.expireAt = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *memoryDelayStore) popOffline(_ context.Context, userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, ok := s.offline[userID]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.offline, userID)
+	if time.Now().After(list.expireAt) {
+		return nil, nil
+	}
+	return list.messages, nil
+}
+
+// delayHeap 按到期时间排序的最小堆
+type delayHeap []delayedMessage
+
+func (h delayHeap) Len() int           { return len(h) }
+func (h delayHeap) Less(i, j int) bool { return h[i].DeliverAt < h[j].DeliverAt }
+func (h delayHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *delayHeap) Push(x interface{}) {
+	*h = append(*h, x.(delayedMessage))
+}
+
+func (h *delayHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
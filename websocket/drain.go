@@ -0,0 +1,128 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dfpopp/go-dai/i18n"
+	"math/rand"
+	"time"
+)
+
+// ActionReconnect 排空时下发给客户端的控制消息action（客户端收到后应按backoff_ms延迟重连到其他实例）
+const ActionReconnect = "reconnect"
+
+// CloseCodeServiceRestart 排空超时后关闭剩余连接使用的关闭码（RFC 6455：Service Restart）
+const CloseCodeServiceRestart = 1012
+
+// DrainOptions 连接排空参数
+type DrainOptions struct {
+	Threshold      int           // 连接数降到该值及以下即视为排空完成（默认0）
+	Backoff        time.Duration // 建议客户端重连前等待的基础时长（默认1秒）
+	Jitter         time.Duration // 在Backoff基础上为每个连接叠加的随机抖动上限，避免同时重连（默认等于Backoff）
+	Message        string        // 控制消息中的提示文案（为空时按连接语言使用i18n.MsgServerRestart）
+	PollInterval   time.Duration // 连接数检查间隔（默认200毫秒）
+	CloseRemaining bool          // 到达截止时间仍未排空时，是否主动关闭剩余连接
+}
+
+// DrainResult 排空结果
+type DrainResult struct {
+	Notified  int  // 已通知的连接数
+	Remaining int  // 结束时剩余的连接数
+	Closed    int  // 超时后主动关闭的连接数
+	Completed bool // 是否在截止时间前降到阈值以下
+}
+
+// setDefaults 补齐排空参数默认值
+func (o *DrainOptions) setDefaults() {
+	if o.Backoff <= 0 {
+		o.Backoff = time.Second
+	}
+	if o.Jitter < 0 {
+		o.Jitter = 0
+	} else if o.Jitter == 0 {
+		o.Jitter = o.Backoff
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 200 * time.Millisecond
+	}
+	if o.Threshold < 0 {
+		o.Threshold = 0
+	}
+}
+
+// IsDraining 是否处于排空状态（排空期间服务器拒绝新的握手请求）
+func (cm *ConnManager) IsDraining() bool {
+	return cm.draining.Load()
+}
+
+// Resume 退出排空状态，重新接受新连接（如取消发布时由管理接口调用）
+func (cm *ConnManager) Resume() {
+	cm.draining.Store(false)
+}
+
+// Drain 排空连接：停止接受新连接，通知存量客户端重连到其他实例，
+// 并等待连接数降到阈值以下或ctx到期（到期时按CloseRemaining决定是否主动关闭剩余连接）。
+// 未在截止时间前完成排空时返回ctx.Err()。
+func (cm *ConnManager) Drain(ctx context.Context, opts DrainOptions) (DrainResult, error) {
+	opts.setDefaults()
+	cm.retryAt.Store(int64((opts.Backoff + opts.Jitter + time.Second - 1) / time.Second))
+	cm.draining.Store(true)
+
+	var result DrainResult
+	cm.connMap.Range(func(_, value interface{}) bool {
+		info := value.(*ConnInfo)
+		backoff := opts.Backoff
+		if opts.Jitter > 0 {
+			backoff += time.Duration(rand.Int63n(int64(opts.Jitter)))
+		}
+		text := opts.Message
+		if text == "" {
+			text = i18n.T(info.Conn.Locale(), i18n.MsgServerRestart)
+		}
+		msg, _ := json.Marshal(map[string]interface{}{
+			"action": ActionReconnect,
+			"code":   CloseCodeServiceRestart,
+			"msg":    text,
+			"data":   map[string]interface{}{"backoff_ms": backoff.Milliseconds()},
+		})
+		if err := info.Conn.WriteMessage(string(msg)); err == nil {
+			result.Notified++
+		}
+		return true
+	})
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		if result.Remaining = cm.GetConnCount(); result.Remaining <= opts.Threshold {
+			result.Completed = true
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			if opts.CloseRemaining {
+				result.Closed = cm.closeAll("drain timeout")
+			}
+			return result, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeAll 以1012关闭码断开全部连接（由各连接的读循环负责清理并发布下线事件），返回关闭的连接数
+func (cm *ConnManager) closeAll(reason string) int {
+	closed := 0
+	cm.connMap.Range(func(_, value interface{}) bool {
+		info := value.(*ConnInfo)
+		_ = info.Conn.WriteCloseMessage(CloseCodeServiceRestart, reason)
+		_ = info.Conn.conn.Close()
+		closed++
+		return true
+	})
+	return closed
+}
+
+// Drain 排空当前服务器的连接（排空期间握手请求返回503并携带Retry-After头）
+func (s *Server) Drain(ctx context.Context, opts DrainOptions) (DrainResult, error) {
+	return GetGlobalConnManager().Drain(ctx, opts)
+}
@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"github.com/dfpopp/go-dai/logger"
+	"time"
+)
+
+// CloseCodeGoingAway 空闲超时断开使用的关闭码（RFC 6455：Going Away）
+const CloseCodeGoingAway = 1001
+
+// startHeartbeat 启动服务端心跳：每隔pingInterval发送ping帧，读超时按pingInterval+pongTimeout设置并在收到任意帧（含pong）时顺延，
+// 对端失联时读循环因超时退出；idleTimeout>0时，超过该时长未收到业务消息（心跳帧不计）即主动断开
+func (c *Conn) startHeartbeat(pingInterval, pongTimeout, idleTimeout time.Duration) {
+	if pingInterval <= 0 && idleTimeout <= 0 {
+		return
+	}
+	if pingInterval > 0 {
+		c.readTimeout = pingInterval + pongTimeout
+	}
+	go func() {
+		var pingC, idleC <-chan time.Time
+		if pingInterval > 0 {
+			ticker := time.NewTicker(pingInterval)
+			defer ticker.Stop()
+			pingC = ticker.C
+		}
+		if idleTimeout > 0 {
+			// 按空闲时长的1/4检查，断开时间误差不超过25%
+			ticker := time.NewTicker(max(idleTimeout/4, 10*time.Millisecond))
+			defer ticker.Stop()
+			idleC = ticker.C
+		}
+		for {
+			select {
+			case <-c.closing:
+				return
+			case now := <-idleC:
+				if now.Sub(c.LastActive()) >= idleTimeout {
+					logger.Info("WS连接空闲超时，主动断开：", c.RemoteAddr())
+					_ = c.WriteCloseMessage(CloseCodeGoingAway, "idle timeout")
+					_ = c.conn.Close()
+					return
+				}
+			case now := <-pingC:
+				c.pingSentAt.Store(now.UnixNano())
+				if err := c.writeFrame(true, opCodePing, nil); err != nil {
+					_ = c.conn.Close()
+					return
+				}
+			}
+		}
+	}()
+}
+
+// onPong 记录往返时延
+func (c *Conn) onPong() {
+	if sent := c.pingSentAt.Load(); sent > 0 {
+		c.rtt.Store(time.Now().UnixNano() - sent)
+	}
+}
+
+// LastActive 最后一次收到业务消息的时间
+func (c *Conn) LastActive() time.Time {
+	return time.Unix(0, c.lastActive.Load())
+}
+
+// RTT 最近一次服务端ping到收到pong的往返时延（未启用心跳或尚未收到pong时为0）
+func (c *Conn) RTT() time.Duration {
+	return time.Duration(c.rtt.Load())
+}
@@ -0,0 +1,376 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/db/redisDb"
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/go-redis/redis"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 房间消息历史：BroadcastToRoom（含BaseController.SendToRoom）发出的消息按房间保留最近Size条，每条分配房间内递增的序号。
+//   - 新成员加入房间时自动补发最近Replay条（action为_history，data为{"room":房间名,"messages":[...]}）
+//   - 客户端可发送 {"action": "_history", "request_id": "...", "data": {"room": "...", "limit": 20, "before_seq": 序号}}
+//     拉取更早的消息（仅限已加入的房间，before_seq为0时返回最近的消息），响应格式同上
+//   - 使用Redis时存储为Stream {prefix}history:{房间名}（消息ID为"序号-0"，多节点共享），否则为进程内环形缓冲区（重启后丢失）
+//
+// 历史只记录经服务端群发的消息，不是完整的消息存储：需要全文检索或长期保留时应由业务自行持久化。
+
+// HistoryAction 历史消息帧的action（框架保留，不进入路由）
+const HistoryAction = "_history"
+
+// ErrHistoryDisabled 未启用房间消息历史
+var ErrHistoryDisabled = errors.New("websocket: 未启用房间消息历史（配置websocket.history.enable）")
+
+const (
+	defaultHistorySize  = 100
+	defaultHistoryLimit = 20
+	defaultHistoryTTL   = 7 * 24 * time.Hour
+)
+
+// HistoryOptions 房间消息历史参数
+type HistoryOptions struct {
+	Prefix string        // 键前缀（默认ws:）
+	Size   int           // 每个房间保留的消息条数（默认100）
+	Replay int           // 新成员加入时补发的条数（0表示不补发）
+	TTL    time.Duration // 房间无新消息后历史的保留时长（默认7天，仅Redis）
+}
+
+// HistoryMessage 历史消息
+type HistoryMessage struct {
+	Seq     int64           `json:"seq"`     // 房间内递增的序号
+	Time    int64           `json:"time"`    // 发送时间（Unix毫秒）
+	Message json.RawMessage `json:"message"` // 原始消息（与群发时收到的内容一致）
+}
+
+// historyStore 房间消息历史的存储
+type historyStore interface {
+	append(ctx context.Context, room, message string, at time.Time) (int64, error)
+	// list 按序号升序返回beforeSeq之前（不含）最近的limit条，beforeSeq<=0时为最近的limit条
+	list(ctx context.Context, room string, limit int, beforeSeq int64) ([]HistoryMessage, error)
+}
+
+// RoomHistory 房间消息历史
+type RoomHistory struct {
+	cm    *ConnManager
+	opts  HistoryOptions
+	store historyStore
+}
+
+// NewRoomHistory 创建房间消息历史（rdb为nil时使用进程内环形缓冲区；调用Start后生效）
+func NewRoomHistory(rdb *redisDb.RedisDb, cm *ConnManager, opts HistoryOptions) *RoomHistory {
+	if opts.Prefix == "" {
+		opts.Prefix = "ws:"
+	}
+	if opts.Size <= 0 {
+		opts.Size = defaultHistorySize
+	}
+	if opts.Replay > opts.Size {
+		opts.Replay = opts.Size
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultHistoryTTL
+	}
+	if cm == nil {
+		cm = GetGlobalConnManager()
+	}
+	h := &RoomHistory{cm: cm, opts: opts}
+	if rdb != nil {
+		h.store = &redisHistoryStore{rdb: rdb, prefix: rdb.DbPre + opts.Prefix, size: opts.Size, ttl: opts.TTL}
+	} else {
+		h.store = &memoryHistoryStore{size: opts.Size, rooms: make(map[string]*historyRing)}
+	}
+	return h
+}
+
+var (
+	historyMu    sync.Mutex
+	historyCache sync.Map
+)
+
+// HistoryFromAppConfig 按应用配置ws.history创建并启用全局连接管理器的房间消息历史（未启用时返回nil, nil）
+func HistoryFromAppConfig(appName string) (*RoomHistory, error) {
+	if v, ok := historyCache.Load(appName); ok {
+		return v.(*RoomHistory), nil
+	}
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if v, ok := historyCache.Load(appName); ok {
+		return v.(*RoomHistory), nil
+	}
+	cfg := config.GetAppConfig(appName).WebSocket.History
+	if !cfg.Enable {
+		return nil, nil
+	}
+	var rdb *redisDb.RedisDb
+	if cfg.RedisDb != "" {
+		var err error
+		if rdb, err = redisDb.GetRedisDB(cfg.RedisDb); err != nil {
+			return nil, err
+		}
+	}
+	h := NewRoomHistory(rdb, GetGlobalConnManager(), HistoryOptions{
+		Prefix: cfg.Prefix,
+		Size:   cfg.Size,
+		Replay: cfg.Replay,
+		TTL:    time.Duration(cfg.TTL) * time.Second,
+	})
+	h.Start()
+	historyCache.Store(appName, h)
+	return h, nil
+}
+
+// Start 挂载到ConnManager（此后BroadcastToRoom的消息写入历史）
+func (h *RoomHistory) Start() {
+	h.cm.history.Store(h)
+}
+
+// Stop 从ConnManager卸载（已保存的历史保留）
+func (h *RoomHistory) Stop() {
+	h.cm.history.CompareAndSwap(h, nil)
+}
+
+// Record 写入一条房间消息，返回分配的序号
+func (h *RoomHistory) Record(ctx context.Context, room, message string) (int64, error) {
+	if room == "" {
+		return 0, errors.New("room name is empty")
+	}
+	return h.store.append(ctx, room, message, time.Now())
+}
+
+// History 查询房间beforeSeq之前（不含）最近的limit条消息，按序号升序（beforeSeq<=0时为最近的消息；limit<=0时为20，最多Size条）
+func (h *RoomHistory) History(ctx context.Context, room string, limit int, beforeSeq int64) ([]HistoryMessage, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > h.opts.Size {
+		limit = h.opts.Size
+	}
+	return h.store.list(ctx, room, limit, beforeSeq)
+}
+
+// replay 向新加入房间的连接补发最近Replay条消息
+func (h *RoomHistory) replay(connID, room string) {
+	if h.opts.Replay <= 0 {
+		return
+	}
+	messages, err := h.store.list(context.Background(), room, h.opts.Replay, 0)
+	if err != nil {
+		logger.Warn("WS房间历史读取失败：", room, " Err：", err)
+		return
+	}
+	if len(messages) == 0 {
+		return
+	}
+	if err := h.cm.SendToConnID(connID, historyFrame("", room, messages)); err != nil {
+		logger.Warn("WS房间历史补发失败：", room, "连接ID：", connID, " Err：", err)
+	}
+}
+
+// historyFrame 组装历史消息帧
+func historyFrame(requestID, room string, messages []HistoryMessage) string {
+	if messages == nil {
+		messages = make([]HistoryMessage, 0)
+	}
+	frame := map[string]interface{}{
+		"action": HistoryAction,
+		"data":   map[string]interface{}{"room": room, "messages": messages},
+	}
+	if requestID != "" {
+		frame["request_id"] = requestID
+	}
+	data, _ := json.Marshal(frame)
+	return string(data)
+}
+
+// historyRequest 客户端拉取历史的请求
+type historyRequest struct {
+	Room      string `json:"room"`
+	Limit     int    `json:"limit"`
+	BeforeSeq int64  `json:"before_seq"`
+}
+
+// handleHistory 处理客户端的_history请求（仅限已加入的房间）
+func (cm *ConnManager) handleHistory(conn *Conn, connID, requestID string, data json.RawMessage) {
+	h := cm.history.Load()
+	if h == nil {
+		_ = conn.WriteError(400, i18n.MsgInvalidAction)
+		return
+	}
+	var req historyRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.Room == "" {
+		_ = conn.WriteError(400, i18n.MsgInvalidPayload)
+		return
+	}
+	if !cm.InRoom(connID, req.Room) {
+		_ = conn.WriteError(403, i18n.MsgForbidden)
+		return
+	}
+	messages, err := h.History(context.Background(), req.Room, req.Limit, req.BeforeSeq)
+	if err != nil {
+		logger.Warn("WS房间历史读取失败：", req.Room, " Err：", err)
+		_ = conn.WriteError(500, i18n.MsgInternalError)
+		return
+	}
+	_ = conn.WriteMessage(historyFrame(requestID, req.Room, messages))
+}
+
+// RoomHistory 获取房间消息历史（未启用时返回nil）
+func (cm *ConnManager) RoomHistory() *RoomHistory {
+	return cm.history.Load()
+}
+
+// History 查询房间的历史消息（见RoomHistory.History）
+func (cm *ConnManager) History(ctx context.Context, room string, limit int, beforeSeq int64) ([]HistoryMessage, error) {
+	h := cm.history.Load()
+	if h == nil {
+		return nil, ErrHistoryDisabled
+	}
+	return h.History(ctx, room, limit, beforeSeq)
+}
+
+// recordRoomMessage 群发前写入房间历史（未启用时忽略，写入失败只记录日志，不影响投递）
+func (cm *ConnManager) recordRoomMessage(room, message string) {
+	h := cm.history.Load()
+	if h == nil {
+		return
+	}
+	if _, err := h.Record(context.Background(), room, message); err != nil {
+		logger.Warn("WS房间历史写入失败：", room, " Err：", err)
+	}
+}
+
+// historyRing 单个房间的环形缓冲区
+type historyRing struct {
+	buf  []HistoryMessage
+	head int // 最早一条的下标
+	seq  int64
+}
+
+// memoryHistoryStore 进程内房间消息历史（单节点）
+type memoryHistoryStore struct {
+	mu    sync.Mutex
+	size  int
+	rooms map[string]*historyRing
+}
+
+func (s *memoryHistoryStore) append(_ context.Context, room, message string, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ring := s.rooms[room]
+	if ring == nil {
+		ring = &historyRing{buf: make([]HistoryMessage, 0, s.size)}
+		s.rooms[room] = ring
+	}
+	ring.seq++
+	msg := HistoryMessage{Seq: ring.seq, Time: at.UnixMilli(), Message: rawMessage(message)}
+	if len(ring.buf) < s.size {
+		ring.buf = append(ring.buf, msg)
+	} else {
+		ring.buf[ring.head] = msg
+		ring.head = (ring.head + 1) % s.size
+	}
+	return ring.seq, nil
+}
+
+func (s *memoryHistoryStore) list(_ context.Context, room string, limit int, beforeSeq int64) ([]HistoryMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ring := s.rooms[room]
+	if ring == nil {
+		return nil, nil
+	}
+	n := len(ring.buf)
+	// 从最新一条向前取，序号连续，可直接定位
+	end := n
+	if beforeSeq > 0 {
+		oldest := ring.seq - int64(n) + 1
+		if beforeSeq <= oldest {
+			return nil, nil
+		}
+		if beforeSeq <= ring.seq {
+			end = int(beforeSeq - oldest)
+		}
+	}
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+	out := make([]HistoryMessage, 0, end-start)
+	for i := start; i < end; i++ {
+		out = append(out, ring.buf[(ring.head+i)%n])
+	}
+	return out, nil
+}
+
+// historyAppendScript 分配序号并写入Stream：KEYS[1]为Stream，KEYS[2]为序号计数器，ARGV为保留条数、有效期（秒）、发送时间与消息
+var historyAppendScript = redis.NewScript(`local seq = redis.call('INCR', KEYS[2])
+redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[1], seq .. '-0', 't', ARGV[3], 'm', ARGV[4])
+redis.call('EXPIRE', KEYS[1], ARGV[2])
+redis.call('EXPIRE', KEYS[2], ARGV[2])
+return seq`)
+
+// redisHistoryStore Redis Stream房间消息历史（多节点共享）
+type redisHistoryStore struct {
+	rdb    *redisDb.RedisDb
+	prefix string
+	size   int
+	ttl    time.Duration
+}
+
+func (s *redisHistoryStore) keys(room string) []string {
+	key := s.prefix + "history:" + room
+	return []string{key, key + ":seq"}
+}
+
+func (s *redisHistoryStore) append(ctx context.Context, room, message string, at time.Time) (int64, error) {
+	return historyAppendScript.Run(s.rdb.WithContext(ctx).Db, s.keys(room), s.size, int64(s.ttl/time.Second), at.UnixMilli(), message).Int64()
+}
+
+func (s *redisHistoryStore) list(ctx context.Context, room string, limit int, beforeSeq int64) ([]HistoryMessage, error) {
+	end := "+"
+	if beforeSeq > 0 {
+		if beforeSeq == 1 {
+			return nil, nil
+		}
+		end = strconv.FormatInt(beforeSeq-1, 10) + "-0"
+	}
+	entries, err := s.rdb.WithContext(ctx).Db.XRevRangeN(s.keys(room)[0], end, "-", int64(limit)).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]HistoryMessage, len(entries))
+	// XREVRANGE按序号降序返回，倒序写入
+	for i, entry := range entries {
+		msg := HistoryMessage{}
+		if idx := len(entry.ID) - 2; idx > 0 {
+			msg.Seq, _ = strconv.ParseInt(entry.ID[:idx], 10, 64)
+		}
+		msg.Time, _ = strconv.ParseInt(toString(entry.Values["t"]), 10, 64)
+		msg.Message = rawMessage(toString(entry.Values["m"]))
+		out[len(entries)-1-i] = msg
+	}
+	return out, nil
+}
+
+// rawMessage 原样保留JSON消息，非JSON内容按字符串编码
+func rawMessage(message string) json.RawMessage {
+	if json.Valid([]byte(message)) {
+		return json.RawMessage(message)
+	}
+	data, _ := json.Marshal(message)
+	return data
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
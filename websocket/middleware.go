@@ -1,7 +1,89 @@
 package websocket
 
+import (
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"runtime/debug"
+)
+
 // HandlerFunc WS处理器函数（与http.HandlerFunc对齐）
 type HandlerFunc func(*Context)
 
 // MiddlewareFunc WS中间件函数（与http.MiddlewareFunc对齐）
 type MiddlewareFunc func(HandlerFunc) HandlerFunc
+
+// Recovery 异常恢复中间件（NewServer默认安装）：处理器panic时记录堆栈并回复500错误帧，
+// 随后当前连接以1011关闭，读循环与其他连接不受影响
+func Recovery() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			defer func() {
+				if p := recover(); p != nil {
+					c.recoverPanic(p)
+				}
+			}()
+			next(c)
+		}
+	}
+}
+
+// recoverPanic 记录panic堆栈并回复500错误帧（同一消息只处理一次）
+func (c *Context) recoverPanic(p interface{}) {
+	if c.panicked {
+		return
+	}
+	c.panicked = true
+	logger.FromContext(c.GetContext()).Error("WS请求异常：", p, "action：", c.Action, "连接ID：", c.ConnID, "\n", string(debug.Stack()))
+	c.Error(500, i18n.MsgInternalError)
+}
+
+// RequestID 请求ID中间件（沿用消息中的request_id或生成新ID，响应时回写request_id并绑定请求级日志，通过logger.FromContext获取）；
+// 开启日志缓冲时，处理器panic才输出缓冲的debug/info日志（见logger.StartBuffer）
+func RequestID() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if c.RequestId == "" {
+				c.RequestId = logger.NewRequestID()
+			}
+			ctx, buf := logger.StartBuffer(logger.WithRequestID(c.GetContext(), c.RequestId))
+			c.SetContext(ctx)
+			if buf == nil {
+				next(c)
+				return
+			}
+			finished := false
+			defer func() {
+				buf.Finish(!finished || c.panicked)
+			}()
+			next(c)
+			finished = true
+		}
+	}
+}
+
+// Tracing 链路追踪中间件（以握手请求头中的traceparent为上游，为每条消息开启服务端span）
+func Tracing() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if !tracing.Enabled() {
+				next(c)
+				return
+			}
+			ctx := c.GetContext()
+			if c.Req != nil {
+				ctx = tracing.Extract(ctx, propagation.HeaderCarrier(c.Req.Header))
+			}
+			ctx, span := tracing.StartServerSpan(ctx, "WS "+c.Action,
+				attribute.String("ws.action", c.Action),
+				attribute.String("ws.request_id", c.RequestId),
+				attribute.String("ws.conn_id", c.ConnID),
+			)
+			defer span.End()
+			c.SetContext(ctx)
+			next(c)
+		}
+	}
+}
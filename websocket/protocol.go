@@ -0,0 +1,159 @@
+package websocket
+
+import (
+	"encoding/json"
+	"github.com/dfpopp/go-dai/logger"
+	"sort"
+	"strings"
+)
+
+// DefaultProtocolVersion 客户端未指定版本时使用的协议版本，即内置的 {action, request_id, data} 消息格式
+const DefaultProtocolVersion = "1"
+
+// 握手时指定协议版本的查询参数与请求头（查询参数优先，浏览器WebSocket无法自定义请求头），
+// 可按偏好顺序列出多个版本（如 2,1），服务端选择第一个支持的版本，并通过同名响应头返回
+const (
+	ProtocolVersionQuery  = "protocol_version"
+	ProtocolVersionHeader = "X-Protocol-Version"
+)
+
+// Envelope 与协议版本无关的消息信封，各版本的编解码器负责与线上格式互相转换
+type Envelope struct {
+	Action    string                     // 消息动作（推送消息与请求消息携带）
+	RequestId string                     // 请求唯一标识（响应原样回写）
+	Code      int                        // 响应码（请求与推送消息为0）
+	Msg       string                     // 响应提示信息
+	Data      json.RawMessage            // 业务数据
+	Meta      map[string]json.RawMessage // 版本扩展字段（如压缩标记、链路追踪等关联元数据），v1中为信封外的其他顶层字段
+}
+
+// Codec 协议版本的消息编解码器（需可并发使用）
+type Codec interface {
+	Decode(raw []byte) (*Envelope, error)
+	Encode(env *Envelope) ([]byte, error)
+}
+
+// V1Codec 内置协议版本1：请求/推送为 {action, request_id, data}，响应为 {code, msg, data, request_id}
+type V1Codec struct{}
+
+// Decode 解析v1消息（code/msg类型不符时保留在Meta中，不视为错误）
+func (V1Codec) Decode(raw []byte) (*Envelope, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	env := &Envelope{Data: fields["data"]}
+	delete(fields, "data")
+	if v, ok := fields["action"]; ok {
+		if err := json.Unmarshal(v, &env.Action); err != nil {
+			return nil, err
+		}
+		delete(fields, "action")
+	}
+	if v, ok := fields["request_id"]; ok {
+		if err := json.Unmarshal(v, &env.RequestId); err != nil {
+			return nil, err
+		}
+		delete(fields, "request_id")
+	}
+	if v, ok := fields["code"]; ok && json.Unmarshal(v, &env.Code) == nil {
+		delete(fields, "code")
+	}
+	if v, ok := fields["msg"]; ok && json.Unmarshal(v, &env.Msg) == nil {
+		delete(fields, "msg")
+	}
+	if len(fields) > 0 {
+		env.Meta = fields
+	}
+	return env, nil
+}
+
+// Encode 生成v1消息（Meta作为顶层字段输出）
+func (V1Codec) Encode(env *Envelope) ([]byte, error) {
+	msg := make(map[string]interface{}, len(env.Meta)+5)
+	for k, v := range env.Meta {
+		msg[k] = v
+	}
+	if env.Action != "" {
+		msg["action"] = env.Action
+	}
+	if env.RequestId != "" {
+		msg["request_id"] = env.RequestId
+	}
+	if env.Code != 0 {
+		msg["code"] = env.Code
+		msg["msg"] = env.Msg
+	}
+	msg["data"] = env.Data
+	return json.Marshal(msg)
+}
+
+// RegisterProtocol 注册协议版本的编解码器（需在Run之前调用；版本1为内置格式，不可覆盖）。
+// 框架内部（响应、广播、集群中继等）统一按v1格式构建消息，发送给其他版本的连接时自动转换
+func (s *Server) RegisterProtocol(version string, codec Codec) {
+	if version == "" || version == DefaultProtocolVersion || codec == nil {
+		logger.Warn("WS协议版本注册被忽略（版本为空、为内置版本1或编解码器为nil）：", version)
+		return
+	}
+	s.protocols[version] = codec
+}
+
+// Protocols 支持的协议版本列表（按版本号排序）
+func (s *Server) Protocols() []string {
+	versions := []string{DefaultProtocolVersion}
+	for version := range s.protocols {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// negotiateProtocol 协商协议版本：按客户端偏好顺序选择第一个支持的版本（未指定时为版本1）
+func (s *Server) negotiateProtocol(offer string) (version string, codec Codec, ok bool) {
+	if strings.TrimSpace(offer) == "" {
+		return DefaultProtocolVersion, nil, true
+	}
+	for _, v := range strings.Split(offer, ",") {
+		v = strings.TrimSpace(v)
+		if v == DefaultProtocolVersion {
+			return v, nil, true
+		}
+		if codec, ok = s.protocols[v]; ok {
+			return v, codec, true
+		}
+	}
+	return "", nil, false
+}
+
+// ProtocolVersion 连接握手时协商的协议版本
+func (c *Conn) ProtocolVersion() string {
+	if c.protocol == "" {
+		return DefaultProtocolVersion
+	}
+	return c.protocol
+}
+
+// decodeEnvelope 按连接协商的版本解析收到的消息
+func (c *Conn) decodeEnvelope(raw []byte) (*Envelope, error) {
+	if c.codec == nil {
+		return V1Codec{}.Decode(raw)
+	}
+	return c.codec.Decode(raw)
+}
+
+// transcode 将v1格式的出站消息转换为连接协商的版本（广播时同一条消息按各连接的版本分别转换；无法按v1解析的消息原样发送）
+func (c *Conn) transcode(message []byte) []byte {
+	if c.codec == nil {
+		return message
+	}
+	env, err := V1Codec{}.Decode(message)
+	if err != nil {
+		return message
+	}
+	out, err := c.codec.Encode(env)
+	if err != nil {
+		logger.Warn("WS消息转换为协议版本", c.protocol, "失败：", err)
+		return message
+	}
+	return out
+}
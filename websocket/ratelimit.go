@@ -0,0 +1,27 @@
+package websocket
+
+import (
+	"github.com/dfpopp/go-dai/i18n"
+	"github.com/dfpopp/go-dai/ratelimit"
+)
+
+// RateLimit 限流中间件：超限时回复code为429的错误帧（data.retry_after_ms为建议等待时长），连接保持不断开
+func RateLimit(policy *ratelimit.Policy) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		if policy == nil {
+			return next
+		}
+		return func(c *Context) {
+			result := policy.Check(c)
+			if !result.Allowed {
+				c.JSON(200, map[string]interface{}{
+					"code": 429,
+					"msg":  c.T(i18n.MsgRateLimited),
+					"data": map[string]interface{}{"retry_after_ms": result.RetryAfter.Milliseconds()},
+				})
+				return
+			}
+			next(c)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// JoinRoom 连接加入房间（房间在首个成员加入时自动创建，最后一个成员离开或断开时自动删除；启用房间历史时向新成员补发最近的消息）
+func (cm *ConnManager) JoinRoom(connID, room string) error {
+	if room == "" {
+		return errors.New("room name is empty")
+	}
+	cm.roomMu.Lock()
+	info, ok := cm.GetConnInfoByConnID(connID)
+	if !ok {
+		cm.roomMu.Unlock()
+		return errors.New("connection not found: " + connID)
+	}
+	if info.rooms == nil {
+		info.rooms = make(map[string]struct{})
+	}
+	info.rooms[room] = struct{}{}
+	if cm.rooms == nil {
+		cm.rooms = make(connIndex)
+	}
+	joined := cm.rooms.add(room, connID)
+	cm.roomMu.Unlock()
+	if joined {
+		cm.publishRoomEvent(EventRoomJoin, info, room, "")
+		if h := cm.history.Load(); h != nil {
+			h.replay(connID, room)
+		}
+	}
+	return nil
+}
+
+// LeaveRoom 连接离开房间
+func (cm *ConnManager) LeaveRoom(connID, room string) {
+	cm.roomMu.Lock()
+	info, ok := cm.GetConnInfoByConnID(connID)
+	if !ok {
+		cm.roomMu.Unlock()
+		return
+	}
+	delete(info.rooms, room)
+	left := cm.rooms.remove(room, connID)
+	cm.roomMu.Unlock()
+	if left {
+		cm.publishRoomEvent(EventRoomLeave, info, room, "")
+	}
+}
+
+// leaveAllRooms 连接断开时退出全部房间
+func (cm *ConnManager) leaveAllRooms(info *ConnInfo, reason string) {
+	cm.roomMu.Lock()
+	rooms := make([]string, 0, len(info.rooms))
+	for room := range info.rooms {
+		cm.rooms.remove(room, info.ConnID)
+		rooms = append(rooms, room)
+	}
+	info.rooms = nil
+	cm.roomMu.Unlock()
+	for _, room := range rooms {
+		cm.publishRoomEvent(EventRoomLeave, info, room, reason)
+	}
+}
+
+// RoomMembers 获取房间内的全部连接ID
+func (cm *ConnManager) RoomMembers(room string) []string {
+	cm.roomMu.RLock()
+	defer cm.roomMu.RUnlock()
+	return cm.rooms.members(room)
+}
+
+// RoomCount 获取房间内的连接数
+func (cm *ConnManager) RoomCount(room string) int {
+	cm.roomMu.RLock()
+	defer cm.roomMu.RUnlock()
+	return len(cm.rooms[room])
+}
+
+// InRoom 连接是否在房间内
+func (cm *ConnManager) InRoom(connID, room string) bool {
+	cm.roomMu.RLock()
+	defer cm.roomMu.RUnlock()
+	_, ok := cm.rooms[room][connID]
+	return ok
+}
+
+// Rooms 获取当前全部房间名（按字典序）
+func (cm *ConnManager) Rooms() []string {
+	cm.roomMu.RLock()
+	defer cm.roomMu.RUnlock()
+	rooms := make([]string, 0, len(cm.rooms))
+	for room := range cm.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// ConnRooms 获取连接已加入的房间（按字典序）
+func (cm *ConnManager) ConnRooms(connID string) []string {
+	cm.roomMu.RLock()
+	defer cm.roomMu.RUnlock()
+	info, ok := cm.GetConnInfoByConnID(connID)
+	if !ok {
+		return nil
+	}
+	rooms := make([]string, 0, len(info.rooms))
+	for room := range info.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// BroadcastToRoom 向房间内的连接群发消息（excludeConnIDs通常为发送者自身；启用房间历史时同时写入历史），返回成功入队/写出的连接数
+func (cm *ConnManager) BroadcastToRoom(room string, message string, excludeConnIDs ...string) int {
+	cm.recordRoomMessage(room, message)
+	sent := 0
+next:
+	for _, connID := range cm.RoomMembers(room) {
+		for _, exclude := range excludeConnIDs {
+			if connID == exclude {
+				continue next
+			}
+		}
+		if conn, ok := cm.GetConnByConnID(connID); ok && conn.WriteMessage(message) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// publishRoomEvent 发布房间事件
+func (cm *ConnManager) publishRoomEvent(eventType string, info *ConnInfo, room, reason string) {
+	cm.eventBus.Publish(ConnEvent{
+		EventType:   eventType,
+		ConnInfo:    info,
+		TriggerTime: time.Now(),
+		CloseReason: reason,
+		Room:        room,
+	})
+}
@@ -3,12 +3,15 @@ package websocket
 import (
 	"encoding/json"
 	"errors"
+	"github.com/dfpopp/go-dai/i18n"
+	"sort"
 )
 
 // Router WS路由器（框架内置，非系统包，供Server内部使用）
 type Router struct {
 	handlers    map[string]HandlerFunc // action -> 包装后的处理器
 	middlewares []MiddlewareFunc       // 全局中间件（由Server注入）
+	types       map[string]ActionTypes // action -> 载荷/响应类型描述（供客户端SDK生成）
 }
 
 // NewRouter 创建WS路由器实例（框架内置）
@@ -16,6 +19,7 @@ func NewRouter() *Router {
 	return &Router{
 		handlers:    make(map[string]HandlerFunc),
 		middlewares: make([]MiddlewareFunc, 0),
+		types:       make(map[string]ActionTypes),
 	}
 }
 
@@ -29,20 +33,39 @@ func (r *Router) Register(action string, handler HandlerFunc, chain []Middleware
 	r.handlers[action] = buildChain(chain, handler)
 }
 
-// Dispatch WS路由分发（内部方法，供WS Server调用）
-func (r *Router) Dispatch(ctx *Context) error {
-	action := ctx.Action
-	handler, exists := r.handlers[action]
+// Actions 已注册的action列表（按名称排序）
+func (r *Router) Actions() []string {
+	actions := make([]string, 0, len(r.handlers))
+	for action := range r.handlers {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// ErrHandlerPanic 处理器panic（Dispatch返回该错误时，WS Server以1011关闭当前连接）
+var ErrHandlerPanic = errors.New("ws handler panic")
+
+// Dispatch WS路由分发（内部方法，供WS Server调用）：处理器panic（含Recovery中间件之外的中间件）时记录堆栈并返回ErrHandlerPanic。
+// 未注册的action仍经过全局中间件后再返回404，首条消息认证（ActionAuth）等由全局中间件处理的action无需注册路由
+func (r *Router) Dispatch(ctx *Context) (err error) {
+	handler, exists := r.handlers[ctx.Action]
 	if !exists {
-		ctx.JSON(200, map[string]interface{}{
-			"code": 404,
-			"msg":  "无效的接口",
-			"data": nil,
+		handler = buildChain(r.middlewares, func(c *Context) {
+			c.Error(404, i18n.MsgInvalidAction)
+			err = errors.New("invalid ws action: " + c.Action)
 		})
-		return errors.New("invalid ws action: " + action)
 	}
+	defer func() {
+		if p := recover(); p != nil {
+			ctx.recoverPanic(p)
+		}
+		if ctx.panicked {
+			err = ErrHandlerPanic
+		}
+	}()
 	handler(ctx)
-	return nil
+	return err
 }
 
 // ParseMessage 解析WS消息（内部方法，供WS Server调用）
@@ -0,0 +1,323 @@
+package websocket
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ActionTypes action的载荷与响应类型描述（传入对应Go类型的零值，如 UserReq{}、&UserResp{}）
+type ActionTypes struct {
+	Payload  interface{} // 客户端发送的data类型（nil表示任意）
+	Response interface{} // 服务端响应的类型（nil表示任意）
+}
+
+// Describe 描述action的载荷与响应类型，供GenerateTSClient生成带类型的前端SDK（未描述的action生成为unknown类型）
+func (s *Server) Describe(action string, types ActionTypes) {
+	s.router.types[action] = types
+}
+
+// Actions 已注册的action列表（按名称排序）
+func (s *Server) Actions() []string {
+	return s.router.Actions()
+}
+
+// TSClientOptions TypeScript客户端生成选项
+type TSClientOptions struct {
+	ClassName string   // 生成的客户端类名（默认DaiWsClient）
+	Events    []string // 服务端主动推送的action（如BaseController.SendToConnID发送的消息），数据类型通过Describe(event, ActionTypes{Response: ...})描述
+}
+
+// GenerateTSClient 根据已注册的action生成TypeScript客户端SDK（连接/自动重连、send(action, payload)请求响应、推送事件订阅）
+func (s *Server) GenerateTSClient(w io.Writer, opts TSClientOptions) error {
+	if opts.ClassName == "" {
+		opts.ClassName = "DaiWsClient"
+	}
+	gen := &tsGenerator{named: make(map[reflect.Type]string), defs: make(map[string]string)}
+	var actionMap, eventMap bytes.Buffer
+	for _, action := range s.router.Actions() {
+		types := s.router.types[action]
+		fmt.Fprintf(&actionMap, "  %q: { payload: %s; response: %s };\n", action, gen.typeOf(types.Payload), gen.typeOf(types.Response))
+	}
+	events := append([]string(nil), opts.Events...)
+	sort.Strings(events)
+	for _, event := range events {
+		fmt.Fprintf(&eventMap, "  %q: %s;\n", event, gen.typeOf(s.router.types[event].Response))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by go-dai websocket.GenerateTSClient. DO NOT EDIT.\n\n")
+	names := make([]string, 0, len(gen.defs))
+	for name := range gen.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.WriteString(gen.defs[name])
+		out.WriteString("\n")
+	}
+	fmt.Fprintf(&out, "export interface Actions {\n%s}\n\n", actionMap.String())
+	fmt.Fprintf(&out, "export interface Events {\n%s  [action: string]: unknown;\n}\n\n", eventMap.String())
+	out.WriteString(strings.ReplaceAll(tsClientTemplate, "{{ClassName}}", opts.ClassName))
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// tsGenerator Go类型到TypeScript类型的转换器（结构体按json标签生成interface）
+type tsGenerator struct {
+	named map[reflect.Type]string // 已生成的命名类型
+	defs  map[string]string       // 类型名 -> interface定义
+}
+
+func (g *tsGenerator) typeOf(v interface{}) string {
+	if v == nil {
+		return "unknown"
+	}
+	return g.tsType(reflect.TypeOf(v))
+}
+
+func (g *tsGenerator) tsType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.tsType(t.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string" // []byte以base64字符串序列化
+		}
+		return g.tsType(t.Elem()) + "[]"
+	case reflect.Map:
+		return "Record<string, " + g.tsType(t.Elem()) + ">"
+	case reflect.Struct:
+		return g.structType(t)
+	}
+	return "unknown"
+}
+
+func (g *tsGenerator) structType(t reflect.Type) string {
+	if name, ok := g.named[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if name == "" {
+		return "{ " + strings.Join(g.fields(t), " ") + " }"
+	}
+	// 不同包的同名类型追加序号避免冲突
+	for i := 2; ; i++ {
+		if _, exists := g.defs[name]; !exists {
+			break
+		}
+		name = fmt.Sprintf("%s%d", t.Name(), i)
+	}
+	g.named[t] = name
+	g.defs[name] = "" // 占位，支持自引用类型
+	var def bytes.Buffer
+	fmt.Fprintf(&def, "export interface %s {\n", name)
+	for _, field := range g.fields(t) {
+		fmt.Fprintf(&def, "  %s\n", field)
+	}
+	def.WriteString("}\n")
+	g.defs[name] = def.String()
+	return name
+}
+
+// fields 按encoding/json规则生成字段列表（忽略未导出字段与json:"-"，omitempty/指针字段为可选）
+func (g *tsGenerator) fields(t reflect.Type) []string {
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, g.fields(embedded)...)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		optional := strings.Contains(opts, "omitempty") || field.Type.Kind() == reflect.Ptr
+		if !isTSIdentifier(name) {
+			name = fmt.Sprintf("%q", name)
+		}
+		if optional {
+			name += "?"
+		}
+		fields = append(fields, fmt.Sprintf("%s: %s;", name, g.tsType(field.Type)))
+	}
+	return fields
+}
+
+func isTSIdentifier(name string) bool {
+	for i, r := range name {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return false
+	}
+	return name != ""
+}
+
+// tsClientTemplate 客户端运行时代码（消息格式与服务端一致：{action, request_id, data}，响应按request_id关联）
+const tsClientTemplate = `export interface ClientOptions {
+  /** 断线后是否自动重连（默认true） */
+  reconnect?: boolean;
+  /** 重连初始间隔毫秒（默认1000，指数退避） */
+  reconnectInterval?: number;
+  /** 重连最大间隔毫秒（默认30000） */
+  maxReconnectInterval?: number;
+  /** 请求超时毫秒（默认10000） */
+  timeout?: number;
+  protocols?: string | string[];
+}
+
+type Pending = { resolve: (value: any) => void; reject: (reason: unknown) => void; timer: ReturnType<typeof setTimeout> };
+
+export class {{ClassName}} {
+  private ws: WebSocket | null = null;
+  private pending = new Map<string, Pending>();
+  private handlers = new Map<string, Set<(data: any) => void>>();
+  private queue: string[] = [];
+  private attempts = 0;
+  private closedByUser = false;
+  private seq = 0;
+  private readonly opts: Required<Omit<ClientOptions, "protocols">> & Pick<ClientOptions, "protocols">;
+
+  onOpen?: () => void;
+  onClose?: (event: CloseEvent) => void;
+  onError?: (event: Event) => void;
+
+  constructor(private readonly url: string, options: ClientOptions = {}) {
+    this.opts = {
+      reconnect: options.reconnect ?? true,
+      reconnectInterval: options.reconnectInterval ?? 1000,
+      maxReconnectInterval: options.maxReconnectInterval ?? 30000,
+      timeout: options.timeout ?? 10000,
+      protocols: options.protocols,
+    };
+  }
+
+  connect(): void {
+    this.closedByUser = false;
+    const ws = new WebSocket(this.url, this.opts.protocols);
+    this.ws = ws;
+    ws.onopen = () => {
+      this.attempts = 0;
+      const queued = this.queue;
+      this.queue = [];
+      queued.forEach((msg) => ws.send(msg));
+      this.onOpen?.();
+    };
+    ws.onmessage = (event) => this.handleMessage(event.data);
+    ws.onerror = (event) => this.onError?.(event);
+    ws.onclose = (event) => {
+      this.ws = null;
+      this.onClose?.(event);
+      if (!this.closedByUser && this.opts.reconnect) {
+        const delay = Math.min(this.opts.reconnectInterval * 2 ** this.attempts, this.opts.maxReconnectInterval);
+        this.attempts++;
+        setTimeout(() => this.connect(), delay);
+      }
+    };
+  }
+
+  close(code?: number, reason?: string): void {
+    this.closedByUser = true;
+    this.ws?.close(code, reason);
+    this.pending.forEach((p) => {
+      clearTimeout(p.timer);
+      p.reject(new Error("connection closed"));
+    });
+    this.pending.clear();
+  }
+
+  /** 发送请求并等待按request_id关联的响应 */
+  send<A extends keyof Actions>(action: A, payload: Actions[A]["payload"]): Promise<Actions[A]["response"]> {
+    const requestId = this.nextRequestId();
+    return new Promise((resolve, reject) => {
+      const timer = setTimeout(() => {
+        this.pending.delete(requestId);
+        reject(new Error("request timeout: " + String(action)));
+      }, this.opts.timeout);
+      this.pending.set(requestId, { resolve, reject, timer });
+      this.write(JSON.stringify({ action, request_id: requestId, data: payload }));
+    });
+  }
+
+  /** 订阅服务端推送，返回取消订阅函数 */
+  on<E extends keyof Events & string>(action: E, handler: (data: Events[E]) => void): () => void {
+    let set = this.handlers.get(action);
+    if (!set) {
+      set = new Set();
+      this.handlers.set(action, set);
+    }
+    set.add(handler);
+    return () => set!.delete(handler);
+  }
+
+  private write(msg: string): void {
+    if (this.ws && this.ws.readyState === WebSocket.OPEN) {
+      this.ws.send(msg);
+    } else {
+      this.queue.push(msg);
+    }
+  }
+
+  private handleMessage(raw: unknown): void {
+    if (typeof raw !== "string") {
+      return;
+    }
+    let msg: any;
+    try {
+      msg = JSON.parse(raw);
+    } catch {
+      return;
+    }
+    const requestId: string | undefined = msg?.request_id;
+    if (requestId && this.pending.has(requestId)) {
+      const p = this.pending.get(requestId)!;
+      this.pending.delete(requestId);
+      clearTimeout(p.timer);
+      p.resolve(msg);
+      return;
+    }
+    if (msg?.action) {
+      this.handlers.get(msg.action)?.forEach((handler) => handler(msg.data));
+    }
+  }
+
+  private nextRequestId(): string {
+    this.seq = (this.seq + 1) % Number.MAX_SAFE_INTEGER;
+    return Date.now().toString(36) + "-" + this.seq.toString(36) + "-" + Math.random().toString(36).slice(2, 8);
+  }
+}
+`
@@ -1,19 +1,31 @@
 package websocket
 
 import (
+	"compress/flate"
+	"context"
 	"crypto/sha1"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/dfpopp/go-dai/auth"
 	"github.com/dfpopp/go-dai/config"
+	"github.com/dfpopp/go-dai/function"
+	"github.com/dfpopp/go-dai/i18n"
 	"github.com/dfpopp/go-dai/logger"
+	"github.com/dfpopp/go-dai/ratelimit"
 	"io"
+	"math/rand/v2"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 var ErrServerClosed = http.ErrServerClosed
@@ -34,13 +46,26 @@ type ServerConfig struct {
 	ReadTimeout      time.Duration // 读超时
 	WriteTimeout     time.Duration // 写超时
 	Path             string        // WebSocket监听路径（如：/ws）
-	Origin           string        // 允许的来源（* 表示允许所有）
+	Origin           string        // 允许的来源（* 表示允许所有，多个以逗号分隔，支持子域通配）
 	HandshakeTimeout time.Duration // 握手超时（默认3秒）
 	MaxMessageSize   int64         // 最大消息大小（默认1MB）
 	MaxConnections   int32         // 最大连接数（默认1000）
 	SSL              bool          // 是否启用SSL/TLS（启用后为WSS，禁用为WS）
 	SSLCertFile      string        // SSL证书路径（如：./cert/server.crt）
 	SSLKeyFile       string        // SSL密钥路径（如：./cert/server.key）
+	SendQueueSize    int           // 每个连接的发送队列长度（默认256）
+	SlowPolicy       string        // 发送队列已满时的策略：close（断开，默认）/drop（丢弃消息）
+	PingInterval     time.Duration // 服务端ping间隔（默认30秒，<0表示不发送；启用后读超时为PingInterval+PongTimeout，ReadTimeout不再生效）
+	PongTimeout      time.Duration // 发送ping后等待对端响应的时长（默认10秒）
+	IdleTimeout      time.Duration // 超过该时长未收到业务消息即断开（心跳帧不计，0表示不限制）
+	// Compression 是否接受客户端的permessage-deflate提议（RFC 7692，无上下文接管模式）
+	Compression          bool
+	CompressionThreshold int           // 不小于该字节数的消息才压缩（默认1024，小消息压缩收益低于CPU开销）
+	CompressionLevel     int           // 压缩级别（1-9，默认1即BestSpeed）
+	StreamChunkSize      int           // WriteMessageStream的分片大小（字节，默认32KB）
+	ShutdownTimeout      time.Duration // Stop时等待客户端确认关闭的时长（默认10秒）
+	// MTLS 客户端证书校验（SSL启用时生效，为nil时不校验）
+	MTLS *auth.MTLSOptions
 }
 
 // Conn WS连接封装（原有逻辑不变）
@@ -51,15 +76,49 @@ type Conn struct {
 	maxMsgSize   int64
 	readTimeout  time.Duration
 	writeTimeout time.Duration
+	locale       string // 握手时协商的语言（用于错误帧与关闭原因的本地化）
+
+	writeMu    sync.Mutex // 串行化帧写入（写协程与控制帧共用）
+	stopOnce   sync.Once
+	closeSent  atomic.Bool   // 已发送关闭帧（关闭握手中不再重复发送）
+	dataMu     sync.Mutex    // 数据帧通道（流式发送期间独占，保证分片之间不插入其他消息）
+	sendCh     chan outFrame // 发送队列（为nil时WriteMessage直接写出）
+	closing    chan struct{}
+	writerDone chan struct{}
+	slowPolicy string
+	dropped    atomic.Int64
+	slowClosed atomic.Bool
+	closeOnce  sync.Once
+	lastActive atomic.Int64 // 最后一次收到业务消息的时间（UnixNano）
+	pingSentAt atomic.Int64
+	rtt        atomic.Int64
+
+	compress          bool // 是否协商启用permessage-deflate
+	compressThreshold int  // 不小于该字节数的消息才压缩
+	compressLevel     int  // 压缩级别
+	streamChunkSize   int  // 流式发送的分片大小
+	isClient          bool // 客户端连接（发出的帧需加掩码，RFC 6455 5.3）
+
+	protocol string // 握手时协商的协议版本
+	codec    Codec  // 协议版本编解码器（版本1为nil）
 }
 
 // Server WS服务器（框架内置，对齐HTTP Server使用风格）
 type Server struct {
 	config          *ServerConfig
 	server          *http.Server
-	router          *Router          // 框架WS Router（内部持有）
-	connectionCount int32            // 连接计数器
-	middlewares     []MiddlewareFunc // 全局中间件
+	router          *Router                  // 框架WS Router（内部持有）
+	connectionCount int32                    // 连接计数器
+	middlewares     []MiddlewareFunc         // 全局中间件
+	inner           []MiddlewareFunc         // 内层中间件（在全局与路由中间件之后执行，如依赖认证结果的限流）
+	listener        net.Listener             // 监听器（平滑重启时由父进程继承而来）
+	checkOrigin     func(origin string) bool // 自定义握手来源校验（为nil时按配置Origin校验）
+	cluster         *Cluster                 // 多节点中继（未启用时为nil）
+	delay           *DelayQueue              // 定时/延迟消息队列（未启用时为nil）
+	protocols       map[string]Codec         // 协议版本 -> 编解码器（版本1为内置格式，不在其中）
+	mux             *http.ServeMux           // 当前服务器独立的路由（不使用http.DefaultServeMux，同进程多个WS服务器互不影响）
+	extraPaths      []string                 // 配置Path之外的其他监听路径
+	routesOnce      sync.Once
 }
 
 // NewServer 创建WS服务器实例（原有逻辑不变）
@@ -67,16 +126,41 @@ func NewServer(appName string) *Server {
 	cfg := loadServerConfig(appName)
 	setDefaultConfig(cfg)
 	router := NewRouter()
-	return &Server{
+	mux := http.NewServeMux()
+	serv := &Server{
 		config: cfg,
 		server: &http.Server{
 			Addr:         cfg.Addr,
 			ReadTimeout:  cfg.ReadTimeout,
 			WriteTimeout: cfg.WriteTimeout,
+			Handler:      mux,
 		},
+		mux:         mux,
 		router:      router, // 内部初始化Router
 		middlewares: make([]MiddlewareFunc, 0),
+		protocols:   make(map[string]Codec),
 	}
+	serv.Use(Recovery())
+	if policy, err := ratelimit.FromAppConfig(appName); err != nil {
+		logger.Error("WS限流配置无效：", err)
+	} else if policy != nil {
+		// 限流在全局与路由中间件（含认证）之后执行，按用户限流时可读取认证写入的用户
+		serv.inner = append(serv.inner, RateLimit(policy))
+	}
+	if cl, err := ClusterFromAppConfig(appName); err != nil {
+		logger.Error("WS集群中继启动失败，仅投递本节点连接：", err)
+	} else {
+		serv.cluster = cl
+	}
+	if q, err := DelayQueueFromAppConfig(appName); err != nil {
+		logger.Error("WS定时消息队列启动失败：", err)
+	} else {
+		serv.delay = q
+	}
+	if _, err := HistoryFromAppConfig(appName); err != nil {
+		logger.Error("WS房间消息历史启用失败：", err)
+	}
+	return serv
 }
 
 // Config 暴露配置（原有逻辑不变）
@@ -93,72 +177,147 @@ func (s *Server) Use(middlewares ...MiddlewareFunc) {
 
 // Register 注册WS路由（对齐HTTP Server.Handle/GET/POST，核心新增方法）
 func (s *Server) Register(action string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	// 构建完整中间件链：全局中间件 + 局部中间件
-	chain := append(s.middlewares, middlewares...)
+	// 构建完整中间件链：全局中间件 + 局部中间件 + 内层中间件
+	chain := make([]MiddlewareFunc, 0, len(s.middlewares)+len(middlewares)+len(s.inner))
+	chain = append(chain, s.middlewares...)
+	chain = append(chain, middlewares...)
+	chain = append(chain, s.inner...)
 	// 注册到Router
 	s.router.Register(action, handler, chain)
 }
 
+// SetOriginChecker 设置握手来源校验函数（可复用HTTP跨域配置：SetOriginChecker(corsOptions.AllowOrigin)）
+func (s *Server) SetOriginChecker(fn func(origin string) bool) {
+	s.checkOrigin = fn
+}
+
+// allowOrigin 校验握手来源（未携带Origin的非浏览器客户端直接放行）
+func (s *Server) allowOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	if s.checkOrigin != nil {
+		return s.checkOrigin(origin)
+	}
+	return s.config.Origin == "*" || function.MatchOrigin(origin, function.SplitOrigins(s.config.Origin))
+}
+
+// SetListener 指定监听器（平滑重启时传入继承的监听器，需在Run之前调用）
+func (s *Server) SetListener(lis net.Listener) {
+	s.listener = lis
+}
+
+// Listener 获取当前TCP监听器（Run之前为nil）
+func (s *Server) Listener() net.Listener {
+	return s.listener
+}
+
 // Run 启动WS/WSS服务器（核心改造：添加SSL判断，支持两种监听模式）
 func (s *Server) Run() error {
-	// 注册WS握手处理器
-	http.HandleFunc(s.config.Path, s.handleRequest)
-
-	// 根据SSL配置选择监听模式
-	if s.config.SSL {
-		// 启用WSS：加载证书并创建TLS监听器
-		// 验证证书文件路径
-		if s.config.SSLCertFile == "" || s.config.SSLKeyFile == "" {
-			return fmt.Errorf("SSL enabled but cert/key file path is empty")
+	// 在当前服务器独立的路由上注册WS握手处理器
+	s.routesOnce.Do(func() {
+		for _, path := range s.Paths() {
+			s.mux.HandleFunc(path, s.handleRequest)
 		}
-		// 加载X509证书和密钥
-		cert, err := tls.LoadX509KeyPair(s.config.SSLCertFile, s.config.SSLKeyFile)
+	})
+
+	// 创建TCP监听器（已通过SetListener继承时直接复用）
+	if s.listener == nil {
+		lis, err := net.Listen("tcp", s.config.Addr)
 		if err != nil {
-			return fmt.Errorf("failed to load SSL cert/key: %w", err)
-		}
-		// 配置TLS
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12, // 推荐的最小TLS版本
+			return fmt.Errorf("failed to create WS listener: %w", err)
 		}
-		// 创建TLS监听器
-		lis, err := tls.Listen("tcp", s.config.Addr, tlsConfig)
+		s.listener = lis
+	}
+
+	// 根据SSL配置选择监听模式
+	if s.config.SSL {
+		// 启用WSS：加载证书并创建TLS监听器（配置mtls时要求并校验客户端证书）
+		tlsConfig, err := auth.ServerTLSConfig(s.config.SSLCertFile, s.config.SSLKeyFile, s.config.MTLS)
 		if err != nil {
-			return fmt.Errorf("failed to create WSS listener: %w", err)
+			return err
 		}
+		// 在TCP监听器之上包装TLS
+		lis := tls.NewListener(s.listener, tlsConfig)
 		// 打印WSS启动日志
 		logger.Info("WSS服务器启动成功，监听地址：", s.config.Addr, "路径：", s.config.Path)
 		defer func(lis net.Listener) {
 			err := lis.Close()
-			if err != nil {
+			if err != nil && !errors.Is(err, net.ErrClosed) {
 				logger.Error("WSS服务器关闭后释放lis失败，监听地址：", s.config.Addr, "路径：", s.config.Path)
 			}
 		}(lis)
 		return s.server.Serve(lis)
 	} else {
-		// 启用WS：普通TCP监听（原有逻辑）
+		// 启用WS：普通TCP监听
 		logger.Info("WS服务器启动成功，监听地址：", s.config.Addr, "路径：", s.config.Path)
-		return s.server.ListenAndServe()
+		return s.server.Serve(s.listener)
 	}
 }
 
-// Stop 停止WS服务器（原有逻辑不变）
-func (s *Server) Stop() error {
-	logger.Info("WebSocket服务器正在停止...当前连接数：", atomic.LoadInt32(&s.connectionCount))
-	if s.server != nil {
-		return s.server.Shutdown(nil)
+// AddPath 增加监听路径（与配置的Path共用路由与连接管理，如兼容旧版客户端的 /socket；需在Run之前调用）
+func (s *Server) AddPath(paths ...string) {
+	s.extraPaths = append(s.extraPaths, paths...)
+}
+
+// Paths 全部监听路径（配置的Path在前，已去重）
+func (s *Server) Paths() []string {
+	paths := make([]string, 0, len(s.extraPaths)+1)
+	seen := make(map[string]struct{}, len(s.extraPaths)+1)
+	for _, path := range append([]string{s.config.Path}, s.extraPaths...) {
+		if _, ok := seen[path]; path == "" || ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
 	}
-	return nil
+	return paths
+}
+
+// Handler WS握手处理器，用于挂载到其他路由（如框架HTTP路由：router.GET("/ws", http.WrapHandler(wsServer.Handler()))），
+// 与HTTP服务共用端口时无需调用Run；挂载路由上的ResponseWriter包装须支持http.Hijacker
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.handleRequest)
+}
+
+// ConnCount 获取当前连接数
+func (s *Server) ConnCount() int32 {
+	return atomic.LoadInt32(&s.connectionCount)
+}
+
+// Stop 优雅停止WS服务器（等待时长为ShutdownTimeout，详见Shutdown）
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
 }
 
 // handleRequest 处理WS请求（使用框架Router分发，原有逻辑不变）
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	// 排空期间拒绝新连接，引导客户端连接其他实例
+	if cm := GetGlobalConnManager(); cm.IsDraining() {
+		w.Header().Set("Retry-After", strconv.FormatInt(cm.retryAt.Load(), 10))
+		http.Error(w, i18n.T(i18n.FromRequest(r), i18n.MsgServerDraining), http.StatusServiceUnavailable)
+		return
+	}
 	// 1. 连接限流
 	currentConn := atomic.AddInt32(&s.connectionCount, 1)
 	defer atomic.AddInt32(&s.connectionCount, -1)
 
 	if currentConn > s.config.MaxConnections {
-		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		http.Error(w, i18n.T(i18n.FromRequest(r), i18n.MsgTooManyConnections), http.StatusServiceUnavailable)
+		return
+	}
+
+	// 协商协议版本（不支持时拒绝握手，避免客户端按无法识别的格式收发消息）
+	offer := r.URL.Query().Get(ProtocolVersionQuery)
+	if offer == "" {
+		offer = r.Header.Get(ProtocolVersionHeader)
+	}
+	protocol, codec, ok := s.negotiateProtocol(offer)
+	if !ok {
+		w.Header().Set(ProtocolVersionHeader, strings.Join(s.Protocols(), ","))
+		http.Error(w, i18n.T(i18n.FromRequest(r), i18n.MsgUnsupportedVersion, offer, strings.Join(s.Protocols(), ",")), http.StatusBadRequest)
 		return
 	}
 
@@ -173,21 +332,33 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 		err    error
 	)
 	go func() {
-		wsConn, err = s.upgrade(w, r)
+		wsConn, err = s.upgrade(w, r, protocol)
 		handshakeDone <- struct{}{}
 	}()
 
 	select {
 	case <-handshakeDone:
 		if err != nil {
-			http.Error(w, fmt.Sprintf("handshake failed: %v", err), http.StatusBadRequest)
+			http.Error(w, i18n.T(i18n.FromRequest(r), i18n.MsgHandshakeFailed, err), http.StatusBadRequest)
 			return
 		}
 	case <-timeoutTimer:
-		http.Error(w, "handshake timeout", http.StatusGatewayTimeout)
+		http.Error(w, i18n.T(i18n.FromRequest(r), i18n.MsgHandshakeTimeout), http.StatusGatewayTimeout)
 		return
 	}
 
+	wsConn.locale = i18n.FromRequest(r)
+	wsConn.protocol = protocol
+	wsConn.codec = codec
+	wsConn.maxMsgSize = s.config.MaxMessageSize
+	wsConn.readTimeout = s.config.ReadTimeout
+	wsConn.writeTimeout = s.config.WriteTimeout
+	wsConn.compressThreshold = s.config.CompressionThreshold
+	wsConn.compressLevel = s.config.CompressionLevel
+	wsConn.streamChunkSize = s.config.StreamChunkSize
+	wsConn.lastActive.Store(time.Now().UnixNano())
+	wsConn.startWriter(s.config.SendQueueSize, s.config.SlowPolicy)
+	wsConn.startHeartbeat(s.config.PingInterval, s.config.PongTimeout, s.config.IdleTimeout)
 	// 新增：获取客户端IP
 	clientIP := getClientIPFromRequest(r)
 	// 新增：添加连接到全局管理器
@@ -212,40 +383,53 @@ func (s *Server) messageLoop(wsConn *Conn, r *http.Request, connID string, close
 		return
 	}
 
-	// 设置连接的消息大小和超时
-	wsConn.maxMsgSize = s.config.MaxMessageSize
-	wsConn.readTimeout = s.config.ReadTimeout
-	wsConn.writeTimeout = s.config.WriteTimeout
-
 	for {
 		// 读取原始消息
-		rawMsg, err := wsConn.ReadMessage()
+		messageType, rawMsg, err := wsConn.ReadMessageType()
 		if err != nil {
 			*closeReason = err.Error() // 更新下线原因
 			logger.Error("WS读取消息失败：", err, "连接ID：", connID, "客户端：", wsConn.RemoteAddr())
 			break
 		}
 
-		// 框架Router解析消息
-		action, requestId, data, err := s.router.ParseMessage(rawMsg)
+		// 按连接协商的协议版本解析消息
+		env, err := wsConn.decodeEnvelope(rawMsg)
 		if err != nil {
 			logger.Warn("WS解析消息失败：", err, "连接ID：", connID, "客户端：", wsConn.RemoteAddr())
-			_ = wsConn.WriteMessage(string(`{"code":400,"msg":"消息格式错误","data":null}`))
+			_ = wsConn.WriteError(400, i18n.MsgInvalidPayload)
+			continue
+		}
+
+		// 需确认消息的客户端确认帧由框架处理，不进入路由
+		if env.Action == AckAction {
+			GetGlobalConnManager().handleAck(env.RequestId, connID)
+			continue
+		}
+		// 房间历史拉取由框架处理
+		if env.Action == HistoryAction {
+			GetGlobalConnManager().handleHistory(wsConn, connID, env.RequestId, env.Data)
 			continue
 		}
 
 		// 创建WS上下文（传入connID）
-		ctx := NewContext(wsConn, r, action, requestId, connID, data)
+		ctx := NewContext(wsConn, r, env.Action, env.RequestId, connID, env.Data)
+		ctx.MessageType = messageType
+		ctx.Meta = env.Meta
 
-		// 框架Router分发消息
+		// 框架Router分发消息（处理器panic时仅以1011关闭当前连接）
 		if err := s.router.Dispatch(ctx); err != nil {
-			logger.Error("WS路由分发失败：", err, "action：", action, "连接ID：", connID)
+			if errors.Is(err, ErrHandlerPanic) {
+				*closeReason = err.Error()
+				_ = wsConn.CloseWithCode(CloseCodeInternalError, i18n.T(wsConn.Locale(), i18n.MsgInternalError))
+				break
+			}
+			logger.Error("WS路由分发失败：", err, "action：", env.Action, "连接ID：", connID)
 		}
 	}
 }
 
 // upgrade 升级为WS连接（原有逻辑不变）
-func (s *Server) upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+func (s *Server) upgrade(w http.ResponseWriter, r *http.Request, protocol string) (*Conn, error) {
 	if r.Header.Get("Upgrade") != "websocket" {
 		return nil, errors.New("invalid upgrade header (expected 'websocket')")
 	}
@@ -258,7 +442,7 @@ func (s *Server) upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error)
 
 	// 跨域校验
 	origin := r.Header.Get("Origin")
-	if s.config.Origin != "*" && origin != "" && origin != s.config.Origin {
+	if !s.allowOrigin(origin) {
 		return nil, fmt.Errorf("origin '%s' not allowed", origin)
 	}
 
@@ -279,14 +463,25 @@ func (s *Server) upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error)
 		return nil, fmt.Errorf("hijack failed: %v", err)
 	}
 
+	// 协商permessage-deflate扩展
+	extensions := ""
+	if s.config.Compression {
+		extensions = negotiateDeflate(r.Header)
+	}
+
 	// 写入握手响应
 	response := fmt.Sprintf(
 		"HTTP/1.1 101 Switching Protocols\r\n"+
 			"Upgrade: websocket\r\n"+
 			"Connection: Upgrade\r\n"+
-			"Sec-WebSocket-Accept: %s\r\n\r\n",
-		serverKey,
+			"Sec-WebSocket-Accept: %s\r\n"+
+			ProtocolVersionHeader+": %s\r\n",
+		serverKey, protocol,
 	)
+	if extensions != "" {
+		response += "Sec-WebSocket-Extensions: " + extensions + "\r\n"
+	}
+	response += "\r\n"
 	_, err = conn.Write([]byte(response))
 	if err != nil {
 		conn.Close()
@@ -297,6 +492,7 @@ func (s *Server) upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error)
 		conn:     conn,
 		readBuf:  make([]byte, 4096),
 		writeBuf: make([]byte, 4096),
+		compress: extensions != "",
 	}, nil
 }
 
@@ -307,57 +503,169 @@ func generateServerKey(clientKey string) string {
 	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
 }
 
+// ReadMessage 读取一条完整消息（自动应答ping；读超时在收到每一帧后顺延）
 func (c *Conn) ReadMessage() (message []byte, err error) {
-	if c.readTimeout > 0 {
-		if conn, ok := c.conn.(interface{ SetReadDeadline(time.Time) error }); ok {
-			_ = conn.SetReadDeadline(time.Now().Add(c.readTimeout))
-		}
-	}
+	_, message, err = c.ReadMessageType()
+	return message, err
+}
 
+// ReadMessageType 读取一条完整消息并返回消息类型（TextMessage/BinaryMessage），分片消息自动拼接、压缩消息自动解压；
+// 消息（含全部分片）超过读取上限时以1009关闭连接并返回ErrMessageTooLarge，分片顺序等不符合协议时以1002关闭连接
+func (c *Conn) ReadMessageType() (messageType int, message []byte, err error) {
+	compressed := false
 	for {
-		fin, opCode, payload, err := c.readFrame()
+		if c.readTimeout > 0 {
+			if conn, ok := c.conn.(interface{ SetReadDeadline(time.Time) error }); ok {
+				_ = conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+			}
+		}
+		fin, rsv1, opCode, payload, err := c.readFrame()
 		if err != nil {
-			return nil, err
+			if errors.Is(err, ErrMessageTooLarge) {
+				_ = c.WriteLocalizedClose(CloseCodeMessageTooBig, i18n.MsgMessageTooLarge)
+			}
+			return 0, nil, err
 		}
 
 		switch opCode {
-		case opCodeClose:
-			return nil, errors.New("client closed connection")
-		case opCodePing:
-			_ = c.writeFrame(true, opCodePong, payload)
-			continue
-		case opCodePong:
+		case opCodeClose, opCodePing, opCodePong:
+			// 控制帧可穿插在分片之间，但自身不能分片且负载不超过125字节
+			if !fin || len(payload) > 125 || rsv1 {
+				return 0, nil, c.protocolError("invalid control frame")
+			}
+			switch opCode {
+			case opCodeClose:
+				if c.isClient {
+					return 0, nil, errors.New("server closed connection")
+				}
+				return 0, nil, errors.New("client closed connection")
+			case opCodePing:
+				_ = c.writeFrame(true, opCodePong, payload)
+			default:
+				c.onPong()
+			}
 			continue
+		case opCodeText, opCodeBinary:
+			if messageType != 0 {
+				return 0, nil, c.protocolError("expected continuation frame")
+			}
+			if rsv1 && !c.compress {
+				return 0, nil, c.protocolError("unexpected rsv1")
+			}
+			messageType = int(opCode)
+			compressed = rsv1
+		case opCodeContinuation:
+			if messageType == 0 {
+				return 0, nil, c.protocolError("unexpected continuation frame")
+			}
+			if rsv1 {
+				return 0, nil, c.protocolError("unexpected rsv1")
+			}
+		default:
+			return 0, nil, c.protocolError("unknown opcode")
 		}
 
 		if int64(len(message)+len(payload)) > c.maxMsgSize {
-			_ = c.WriteCloseMessage(1009, "message size exceeds limit")
-			return nil, errors.New("message size exceeds limit")
+			_ = c.WriteLocalizedClose(CloseCodeMessageTooBig, i18n.MsgMessageTooLarge)
+			return 0, nil, ErrMessageTooLarge
 		}
 
 		message = append(message, payload...)
 
 		if fin {
-			return message, nil
+			if compressed {
+				if message, err = decompressMessage(message, c.maxMsgSize); err != nil {
+					if errors.Is(err, ErrDecompressTooLarge) {
+						_ = c.WriteLocalizedClose(CloseCodeMessageTooBig, i18n.MsgMessageTooLarge)
+					} else {
+						_ = c.WriteCloseMessage(CloseCodeInvalidPayload, "invalid compressed payload")
+					}
+					return 0, nil, err
+				}
+			}
+			c.lastActive.Store(time.Now().UnixNano())
+			return messageType, message, nil
 		}
 	}
 }
 
+// WriteMessage 发送文本消息（启用发送队列时仅入队，可在多个协程中并发调用；队列已满时返回ErrSendQueueFull）。
+// 消息按v1格式构建，连接协商了其他协议版本时自动转换
 func (c *Conn) WriteMessage(message string) error {
-	return c.writeFrame(true, opCodeText, []byte(message))
+	return c.enqueue(opCodeText, c.transcode([]byte(message)))
 }
 
+// WriteBinary 发送二进制消息（入队规则同WriteMessage）
+func (c *Conn) WriteBinary(data []byte) error {
+	return c.enqueue(opCodeBinary, data)
+}
+
+// WriteCloseMessage 发送关闭帧（reason超过协议上限123字节时按字符边界截断）
 func (c *Conn) WriteCloseMessage(code int, reason string) error {
+	reason = truncateCloseReason(reason)
 	payload := make([]byte, 2+len(reason))
 	payload[0] = byte(code >> 8)
 	payload[1] = byte(code & 0xff)
 	copy(payload[2:], []byte(reason))
+	c.closeSent.Store(true)
 	return c.writeFrame(true, opCodeClose, payload)
 }
 
+// Locale 连接握手时协商的语言
+func (c *Conn) Locale() string {
+	if c.locale == "" {
+		return i18n.DefaultLocale()
+	}
+	return c.locale
+}
+
+// WriteError 发送本地化错误帧（{"code":code,"msg":本地化文案,"data":null}）
+func (c *Conn) WriteError(code int, key string, args ...interface{}) error {
+	resp, err := json.Marshal(map[string]interface{}{
+		"code": code,
+		"msg":  i18n.T(c.Locale(), key, args...),
+		"data": nil,
+	})
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(string(resp))
+}
+
+// WriteLocalizedClose 发送本地化关闭原因的关闭帧
+func (c *Conn) WriteLocalizedClose(code int, key string, args ...interface{}) error {
+	return c.WriteCloseMessage(code, i18n.T(c.Locale(), key, args...))
+}
+
+// truncateCloseReason 关闭原因最多123字节（RFC 6455 5.5），截断时保持UTF-8完整
+func truncateCloseReason(reason string) string {
+	const maxCloseReason = 123
+	if len(reason) <= maxCloseReason {
+		return reason
+	}
+	reason = reason[:maxCloseReason]
+	for len(reason) > 0 && !utf8.ValidString(reason) {
+		reason = reason[:len(reason)-1]
+	}
+	return reason
+}
+
+// Close 写完发送队列中剩余的消息后发送关闭帧并断开连接（可重复调用）
 func (c *Conn) Close() error {
-	_ = c.WriteCloseMessage(1000, "normal closure")
-	return c.conn.Close()
+	return c.CloseWithCode(1000, "normal closure")
+}
+
+// CloseWithCode 写完发送队列中剩余的消息后以指定关闭码发送关闭帧并断开连接（可重复调用，已发送过关闭帧时不再发送）
+func (c *Conn) CloseWithCode(code int, reason string) error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		c.stopWriter()
+		if !c.closeSent.Load() {
+			_ = c.WriteCloseMessage(code, reason)
+		}
+		err = c.conn.Close()
+	})
+	return err
 }
 
 func (c *Conn) RemoteAddr() string {
@@ -367,17 +675,18 @@ func (c *Conn) RemoteAddr() string {
 	return "unknown"
 }
 
-func (c *Conn) readFrame() (fin bool, opCode byte, payload []byte, err error) {
+func (c *Conn) readFrame() (fin, rsv1 bool, opCode byte, payload []byte, err error) {
 	_, err = io.ReadFull(c.conn, c.readBuf[:1])
 	if err != nil {
-		return false, 0, nil, err
+		return false, false, 0, nil, err
 	}
 	fin = (c.readBuf[0] & 0x80) != 0
+	rsv1 = (c.readBuf[0] & rsv1Bit) != 0
 	opCode = c.readBuf[0] & 0x0f
 
 	_, err = io.ReadFull(c.conn, c.readBuf[:1])
 	if err != nil {
-		return false, 0, nil, err
+		return false, false, 0, nil, err
 	}
 	masked := (c.readBuf[0] & 0x80) != 0
 	payloadLen := uint64(c.readBuf[0] & 0x7f)
@@ -386,13 +695,13 @@ func (c *Conn) readFrame() (fin bool, opCode byte, payload []byte, err error) {
 	case 126:
 		_, err = io.ReadFull(c.conn, c.readBuf[:2])
 		if err != nil {
-			return false, 0, nil, err
+			return false, false, 0, nil, err
 		}
 		payloadLen = uint64(c.readBuf[0])<<8 | uint64(c.readBuf[1])
 	case 127:
 		_, err = io.ReadFull(c.conn, c.readBuf[:8])
 		if err != nil {
-			return false, 0, nil, err
+			return false, false, 0, nil, err
 		}
 		payloadLen = 0
 		for i := 0; i < 8; i++ {
@@ -401,14 +710,14 @@ func (c *Conn) readFrame() (fin bool, opCode byte, payload []byte, err error) {
 	}
 
 	if payloadLen > uint64(c.maxMsgSize) {
-		return false, 0, nil, errors.New("payload too large")
+		return false, false, 0, nil, ErrMessageTooLarge
 	}
 
 	mask := make([]byte, 4)
 	if masked {
 		_, err = io.ReadFull(c.conn, mask)
 		if err != nil {
-			return false, 0, nil, err
+			return false, false, 0, nil, err
 		}
 	}
 
@@ -416,7 +725,7 @@ func (c *Conn) readFrame() (fin bool, opCode byte, payload []byte, err error) {
 	if payloadLen > 0 {
 		_, err = io.ReadFull(c.conn, payload)
 		if err != nil {
-			return false, 0, nil, err
+			return false, false, 0, nil, err
 		}
 	}
 
@@ -426,17 +735,19 @@ func (c *Conn) readFrame() (fin bool, opCode byte, payload []byte, err error) {
 		}
 	}
 
-	return fin, opCode, payload, nil
+	return fin, rsv1, opCode, payload, nil
 }
 
 func (c *Conn) writeFrame(fin bool, opCode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	if c.writeTimeout > 0 {
 		if conn, ok := c.conn.(interface{ SetWriteDeadline(time.Time) error }); ok {
 			_ = conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
 		}
 	}
 
-	frameHeader := make([]byte, 0, 10)
+	frameHeader := make([]byte, 0, 14)
 	firstByte := byte(opCode)
 	if fin {
 		firstByte |= 0x80
@@ -445,6 +756,9 @@ func (c *Conn) writeFrame(fin bool, opCode byte, payload []byte) error {
 
 	payloadLen := len(payload)
 	secondByte := byte(0x00)
+	if c.isClient {
+		secondByte |= 0x80
+	}
 	switch {
 	case payloadLen < 126:
 		secondByte |= byte(payloadLen)
@@ -470,6 +784,17 @@ func (c *Conn) writeFrame(fin bool, opCode byte, payload []byte) error {
 	case payloadLen >= 126:
 		frameHeader = append(frameHeader, byte(payloadLen>>8), byte(payloadLen))
 	}
+	if c.isClient {
+		// 负载可能被多个连接共用（如广播），掩码作用在副本上
+		var mask [4]byte
+		binary.BigEndian.PutUint32(mask[:], rand.Uint32())
+		frameHeader = append(frameHeader, mask[:]...)
+		masked := make([]byte, payloadLen)
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
 
 	_, err := c.conn.Write(frameHeader)
 	if err != nil {
@@ -485,17 +810,28 @@ func loadServerConfig(appName string) *ServerConfig {
 	appCfg := config.GetAppConfig(appName)
 	wsCfg := appCfg.WebSocket
 	return &ServerConfig{
-		Addr:             wsCfg.Addr,
-		ReadTimeout:      time.Duration(wsCfg.ReadTimeout) * time.Second,
-		WriteTimeout:     time.Duration(wsCfg.WriteTimeout) * time.Second,
-		Path:             wsCfg.Path,
-		Origin:           wsCfg.Origin,
-		HandshakeTimeout: time.Duration(wsCfg.HandshakeTimeout) * time.Second,
-		MaxMessageSize:   wsCfg.MaxMessageSize,
-		MaxConnections:   wsCfg.MaxConnections,
-		SSL:              wsCfg.SSL,
-		SSLCertFile:      wsCfg.SSLCertFile,
-		SSLKeyFile:       wsCfg.SSLKeyFile,
+		Addr:                 wsCfg.Addr,
+		ReadTimeout:          time.Duration(wsCfg.ReadTimeout) * time.Second,
+		WriteTimeout:         time.Duration(wsCfg.WriteTimeout) * time.Second,
+		Path:                 wsCfg.Path,
+		Origin:               wsCfg.Origin,
+		HandshakeTimeout:     time.Duration(wsCfg.HandshakeTimeout) * time.Second,
+		MaxMessageSize:       wsCfg.MaxMessageSize,
+		MaxConnections:       wsCfg.MaxConnections,
+		SSL:                  wsCfg.SSL,
+		SSLCertFile:          wsCfg.SSLCertFile,
+		SSLKeyFile:           wsCfg.SSLKeyFile,
+		MTLS:                 auth.MTLSOptionsFromConfig(wsCfg.MTLS),
+		SendQueueSize:        wsCfg.SendQueueSize,
+		SlowPolicy:           wsCfg.SlowPolicy,
+		PingInterval:         time.Duration(wsCfg.PingInterval) * time.Second,
+		PongTimeout:          time.Duration(wsCfg.PongTimeout) * time.Second,
+		IdleTimeout:          time.Duration(wsCfg.IdleTimeout) * time.Second,
+		Compression:          wsCfg.Compression,
+		CompressionThreshold: wsCfg.CompressionThreshold,
+		CompressionLevel:     wsCfg.CompressionLevel,
+		StreamChunkSize:      wsCfg.StreamChunkSize,
+		ShutdownTimeout:      time.Duration(wsCfg.ShutdownTimeout) * time.Second,
 	}
 }
 
@@ -521,6 +857,30 @@ func setDefaultConfig(cfg *ServerConfig) {
 	if cfg.Origin == "" {
 		cfg.Origin = "*"
 	}
+	if cfg.SendQueueSize <= 0 {
+		cfg.SendQueueSize = 256
+	}
+	if cfg.SlowPolicy == "" {
+		cfg.SlowPolicy = SlowPolicyClose
+	}
+	if cfg.PingInterval == 0 {
+		cfg.PingInterval = 30 * time.Second
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = 10 * time.Second
+	}
+	if cfg.CompressionThreshold <= 0 {
+		cfg.CompressionThreshold = 1024
+	}
+	if cfg.CompressionLevel < flate.BestSpeed || cfg.CompressionLevel > flate.BestCompression {
+		cfg.CompressionLevel = flate.BestSpeed
+	}
+	if cfg.StreamChunkSize <= 0 {
+		cfg.StreamChunkSize = defaultStreamChunkSize
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 10 * time.Second
+	}
 }
 
 // getClientIPFromRequest 提取客户端IP（复用Context逻辑）
@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"context"
+	"github.com/dfpopp/go-dai/logger"
+	"sync/atomic"
+	"time"
+)
+
+// shutdownPollInterval 停止时检查连接是否全部关闭的间隔
+const shutdownPollInterval = 50 * time.Millisecond
+
+// Shutdown 优雅停止：拒绝新的握手请求，写完各连接发送队列中的消息后发送1001关闭帧，
+// 等待客户端确认关闭（连接全部断开）或ctx到期（到期时强制断开剩余连接），最后关闭监听器。
+// 未在ctx到期前完成时返回ctx.Err()
+func (s *Server) Shutdown(ctx context.Context) error {
+	logger.Info("WebSocket服务器正在停止...当前连接数：", atomic.LoadInt32(&s.connectionCount))
+	cm := GetGlobalConnManager()
+	if cm.retryAt.Load() <= 0 {
+		cm.retryAt.Store(1)
+	}
+	cm.draining.Store(true)
+
+	notified := cm.closeGracefully(CloseCodeGoingAway, "server shutting down")
+	logger.Info("WS已向连接发送关闭帧：", notified)
+	var err error
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for err == nil && atomic.LoadInt32(&s.connectionCount) > 0 {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			logger.Warn("WS连接未在停止超时前全部关闭，强制断开：", cm.forceCloseAll())
+		case <-ticker.C:
+		}
+	}
+
+	if s.delay != nil {
+		s.delay.Stop()
+	}
+	if s.cluster != nil {
+		s.cluster.Stop()
+	}
+	if s.server != nil {
+		if shutdownErr := s.server.Shutdown(ctx); shutdownErr != nil {
+			_ = s.server.Close()
+			if err == nil {
+				err = shutdownErr
+			}
+		}
+	}
+	return err
+}
+
+// CloseListener 停止接受新连接（已建立的连接不受影响），平滑重启时由新进程接管监听后调用
+func (s *Server) CloseListener() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Shutdown(context.Background())
+}
+
+// closeGracefully 并发向全部连接发起关闭握手：先写完发送队列，再发送关闭帧，
+// 客户端回应关闭帧后由读循环退出并清理（慢连接不阻塞其他连接），返回发起关闭的连接数
+func (cm *ConnManager) closeGracefully(code int, reason string) int {
+	count := 0
+	cm.connMap.Range(func(_, value interface{}) bool {
+		conn := value.(*ConnInfo).Conn
+		count++
+		go func() {
+			conn.stopWriter()
+			_ = conn.WriteCloseMessage(code, reason)
+		}()
+		return true
+	})
+	return count
+}
+
+// forceCloseAll 直接断开全部连接的底层连接，返回断开的连接数
+func (cm *ConnManager) forceCloseAll() int {
+	closed := 0
+	cm.connMap.Range(func(_, value interface{}) bool {
+		_ = value.(*ConnInfo).Conn.conn.Close()
+		closed++
+		return true
+	})
+	return closed
+}
@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"errors"
+	"io"
+	"net"
+)
+
+// 关闭码（RFC 6455）
+const (
+	CloseCodeProtocolError  = 1002 // 协议错误（分片顺序错误、控制帧分片等）
+	CloseCodeInvalidPayload = 1007 // 负载数据无效（如压缩数据无法解压）
+	CloseCodeMessageTooBig  = 1009 // 消息超出读取上限
+	CloseCodeInternalError  = 1011 // 服务端内部错误（如流式发送中途读取数据源失败）
+)
+
+// defaultStreamChunkSize 流式发送的默认分片大小
+const defaultStreamChunkSize = 32 * 1024
+
+// ErrMessageTooLarge 收到的消息（含全部分片）超出读取上限
+var ErrMessageTooLarge = errors.New("websocket: 消息大小超出限制")
+
+// SetReadLimit 调整当前连接的消息读取上限（如鉴权通过后为文件上传类连接放宽限制），
+// 仅可在消息处理器或连接建立回调中调用（与读循环同一协程）；limit<=0时不修改
+func (c *Conn) SetReadLimit(limit int64) {
+	if limit > 0 {
+		c.maxMsgSize = limit
+	}
+}
+
+// ReadLimit 当前连接的消息读取上限
+func (c *Conn) ReadLimit() int64 {
+	return c.maxMsgSize
+}
+
+// WriteMessageStream 将r中的数据作为一条二进制消息分片发送，适合文件等大负载（内存占用仅为一个分片，与消息大小无关）
+func (c *Conn) WriteMessageStream(r io.Reader) error {
+	return c.WriteStream(BinaryMessage, r)
+}
+
+// WriteStream 将r中的数据作为一条指定类型的消息分片发送（不压缩、不经过发送队列）：
+// 发送期间独占数据帧通道，其他消息在发送队列中等待（心跳等控制帧不受影响），队列较小时请注意慢连接策略；
+// 读取r失败时消息已无法补全，以1011关闭连接并返回该错误
+func (c *Conn) WriteStream(messageType int, r io.Reader) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return errors.New("websocket: 无效的消息类型")
+	}
+	if c.closing != nil {
+		select {
+		case <-c.closing:
+			return net.ErrClosed
+		default:
+		}
+	}
+	chunkSize := c.streamChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultStreamChunkSize
+	}
+	buf := make([]byte, chunkSize)
+	c.dataMu.Lock()
+	defer c.dataMu.Unlock()
+	opCode := byte(messageType)
+	for {
+		n, err := io.ReadFull(r, buf)
+		switch {
+		case err == nil:
+			// 分片已满，后续可能还有数据
+			if werr := c.writeFrame(false, opCode, buf[:n]); werr != nil {
+				return werr
+			}
+			opCode = opCodeContinuation
+			continue
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			// 数据读完：写出最后一片（数据恰好为分片整数倍时为空的结束帧）
+			return c.writeFrame(true, opCode, buf[:n])
+		default:
+			_ = c.WriteCloseMessage(CloseCodeInternalError, "stream aborted")
+			_ = c.conn.Close()
+			return err
+		}
+	}
+}
+
+// writeDataFrame 写出一条完整的数据帧（与流式发送互斥，避免插入到分片之间）
+func (c *Conn) writeDataFrame(opCode byte, payload []byte) error {
+	c.dataMu.Lock()
+	defer c.dataMu.Unlock()
+	return c.writeFrame(true, opCode, payload)
+}
+
+// protocolError 以1002关闭连接并返回对应错误
+func (c *Conn) protocolError(reason string) error {
+	_ = c.WriteCloseMessage(CloseCodeProtocolError, reason)
+	return errors.New("websocket protocol error: " + reason)
+}
@@ -0,0 +1,243 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TagSelector 标签选择器：外层为“或”，内层为“且”，
+// 如 TagSelector{{"region:eu", "plan:pro"}, {"vip"}} 表示 (region:eu 且 plan:pro) 或 vip
+type TagSelector [][]string
+
+// TagAll 匹配同时带有全部标签的连接
+func TagAll(tags ...string) TagSelector {
+	return TagSelector{tags}
+}
+
+// TagAny 匹配带有任一标签的连接
+func TagAny(tags ...string) TagSelector {
+	sel := make(TagSelector, 0, len(tags))
+	for _, tag := range tags {
+		sel = append(sel, []string{tag})
+	}
+	return sel
+}
+
+// Or 合并两个选择器（满足任一即匹配）
+func (s TagSelector) Or(other TagSelector) TagSelector {
+	return append(append(TagSelector{}, s...), other...)
+}
+
+// String 以ParseTagSelector可解析的格式输出
+func (s TagSelector) String() string {
+	parts := make([]string, 0, len(s))
+	for _, clause := range s {
+		parts = append(parts, strings.Join(clause, "&"))
+	}
+	return strings.Join(parts, "|")
+}
+
+// ParseTagSelector 解析标签表达式：&表示且，|表示或，&优先级高于|，如 "region:eu&plan:pro|vip"
+func ParseTagSelector(expr string) (TagSelector, error) {
+	var sel TagSelector
+	for _, part := range strings.Split(expr, "|") {
+		var clause []string
+		for _, tag := range strings.Split(part, "&") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				return nil, fmt.Errorf("websocket: 标签表达式格式错误：%q", expr)
+			}
+			clause = append(clause, tag)
+		}
+		sel = append(sel, clause)
+	}
+	return sel, nil
+}
+
+// AddTags 为连接添加标签（如region:eu、plan:pro），用于BroadcastWhere按标签定向推送
+func (cm *ConnManager) AddTags(connID string, tags ...string) error {
+	cm.tagMu.Lock()
+	defer cm.tagMu.Unlock()
+	info, ok := cm.GetConnInfoByConnID(connID)
+	if !ok {
+		return errors.New("connection not found: " + connID)
+	}
+	for _, tag := range tags {
+		cm.indexTag(info, tag)
+	}
+	return nil
+}
+
+// RemoveTags 移除连接的标签
+func (cm *ConnManager) RemoveTags(connID string, tags ...string) {
+	cm.tagMu.Lock()
+	defer cm.tagMu.Unlock()
+	info, ok := cm.GetConnInfoByConnID(connID)
+	if !ok {
+		return
+	}
+	for _, tag := range tags {
+		cm.unindexTag(info, tag)
+	}
+}
+
+// SetTags 以给定标签替换连接的全部标签
+func (cm *ConnManager) SetTags(connID string, tags ...string) error {
+	cm.tagMu.Lock()
+	defer cm.tagMu.Unlock()
+	info, ok := cm.GetConnInfoByConnID(connID)
+	if !ok {
+		return errors.New("connection not found: " + connID)
+	}
+	cm.clearTags(info)
+	for _, tag := range tags {
+		cm.indexTag(info, tag)
+	}
+	return nil
+}
+
+// GetTags 获取连接的全部标签（按字典序）
+func (cm *ConnManager) GetTags(connID string) []string {
+	cm.tagMu.RLock()
+	defer cm.tagMu.RUnlock()
+	info, ok := cm.GetConnInfoByConnID(connID)
+	if !ok {
+		return nil
+	}
+	tags := make([]string, 0, len(info.tags))
+	for tag := range info.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// HasTag 连接是否带有指定标签
+func (cm *ConnManager) HasTag(connID, tag string) bool {
+	cm.tagMu.RLock()
+	defer cm.tagMu.RUnlock()
+	_, ok := cm.tagIndex[tag][connID]
+	return ok
+}
+
+// CountTag 带有指定标签的连接数
+func (cm *ConnManager) CountTag(tag string) int {
+	cm.tagMu.RLock()
+	defer cm.tagMu.RUnlock()
+	return len(cm.tagIndex[tag])
+}
+
+// ConnIDsWhere 查询匹配选择器的连接ID（基于倒排索引，每个“且”子句从最小的标签集合开始求交集）
+func (cm *ConnManager) ConnIDsWhere(sel TagSelector) []string {
+	cm.tagMu.RLock()
+	defer cm.tagMu.RUnlock()
+	matched := make(map[string]struct{})
+	for _, clause := range sel {
+		if len(clause) == 0 {
+			continue
+		}
+		smallest := cm.tagIndex[clause[0]]
+		for _, tag := range clause[1:] {
+			if set := cm.tagIndex[tag]; len(set) < len(smallest) {
+				smallest = set
+			}
+		}
+	next:
+		for connID := range smallest {
+			if _, ok := matched[connID]; ok {
+				continue
+			}
+			for _, tag := range clause {
+				if _, ok := cm.tagIndex[tag][connID]; !ok {
+					continue next
+				}
+			}
+			matched[connID] = struct{}{}
+		}
+	}
+	connIDs := make([]string, 0, len(matched))
+	for connID := range matched {
+		connIDs = append(connIDs, connID)
+	}
+	return connIDs
+}
+
+// BroadcastWhere 向匹配选择器的连接群发消息，返回成功入队/写出的连接数
+func (cm *ConnManager) BroadcastWhere(sel TagSelector, message string) int {
+	sent := 0
+	for _, connID := range cm.ConnIDsWhere(sel) {
+		if conn, ok := cm.GetConnByConnID(connID); ok && conn.WriteMessage(message) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// indexTag 写入倒排索引（调用方持有tagMu写锁）
+func (cm *ConnManager) indexTag(info *ConnInfo, tag string) {
+	if tag == "" {
+		return
+	}
+	if info.tags == nil {
+		info.tags = make(map[string]struct{})
+	}
+	info.tags[tag] = struct{}{}
+	if cm.tagIndex == nil {
+		cm.tagIndex = make(connIndex)
+	}
+	cm.tagIndex.add(tag, info.ConnID)
+}
+
+// unindexTag 从倒排索引中移除
+func (cm *ConnManager) unindexTag(info *ConnInfo, tag string) {
+	delete(info.tags, tag)
+	cm.tagIndex.remove(tag, info.ConnID)
+}
+
+// clearTags 移除连接的全部标签（调用方持有tagMu写锁）
+func (cm *ConnManager) clearTags(info *ConnInfo) {
+	for tag := range info.tags {
+		cm.unindexTag(info, tag)
+	}
+}
+
+// connIndex 倒排索引：key（标签/房间名）-> ConnID集合
+type connIndex map[string]map[string]struct{}
+
+// add 加入集合，返回是否为新加入
+func (idx connIndex) add(key, connID string) bool {
+	set := idx[key]
+	if set == nil {
+		set = make(map[string]struct{})
+		idx[key] = set
+	}
+	if _, ok := set[connID]; ok {
+		return false
+	}
+	set[connID] = struct{}{}
+	return true
+}
+
+// remove 移出集合（集合为空时删除该key，避免索引随取值无限增长），返回是否存在
+func (idx connIndex) remove(key, connID string) bool {
+	set := idx[key]
+	if _, ok := set[connID]; !ok {
+		return false
+	}
+	delete(set, connID)
+	if len(set) == 0 {
+		delete(idx, key)
+	}
+	return true
+}
+
+// members 集合中的ConnID
+func (idx connIndex) members(key string) []string {
+	connIDs := make([]string, 0, len(idx[key]))
+	for connID := range idx[key] {
+		connIDs = append(connIDs, connID)
+	}
+	return connIDs
+}
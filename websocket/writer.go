@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"errors"
+	"github.com/dfpopp/go-dai/logger"
+	"net"
+	"strings"
+	"time"
+)
+
+// 慢连接策略（发送队列已满时）
+const (
+	SlowPolicyClose = "close" // 断开连接（默认，客户端重连后重新同步，避免静默丢消息）
+	SlowPolicyDrop  = "drop"  // 丢弃本条消息并返回ErrSendQueueFull
+)
+
+// ErrSendQueueFull 发送队列已满（对端消费过慢）
+var ErrSendQueueFull = errors.New("websocket: 发送队列已满")
+
+// outFrame 待发送的帧
+type outFrame struct {
+	opCode  byte
+	payload []byte
+}
+
+// startWriter 启用发送队列：WriteMessage只入队，由独立的写协程按顺序写出，
+// Broadcast/Multicast等不再被单个慢连接阻塞，多个协程同时发送也不会交错写帧
+func (c *Conn) startWriter(queueSize int, slowPolicy string) {
+	if queueSize <= 0 {
+		queueSize = 256
+	}
+	c.sendCh = make(chan outFrame, queueSize)
+	c.closing = make(chan struct{})
+	c.writerDone = make(chan struct{})
+	c.slowPolicy = strings.ToLower(slowPolicy)
+	go c.writeLoop()
+}
+
+// writeLoop 写协程：写出失败时关闭底层连接，由读循环负责清理；Close时先写完队列中剩余的消息
+func (c *Conn) writeLoop() {
+	defer close(c.writerDone)
+	for {
+		select {
+		case f := <-c.sendCh:
+			if err := c.writeDataFrame(f.opCode, f.payload); err != nil {
+				_ = c.conn.Close()
+				return
+			}
+		case <-c.closing:
+			for {
+				select {
+				case f := <-c.sendCh:
+					if err := c.writeDataFrame(f.opCode, f.payload); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// enqueue 消息入队（未启用发送队列时直接写出；满足条件时先压缩，在调用方协程中完成以分摊CPU）
+func (c *Conn) enqueue(opCode byte, payload []byte) error {
+	opCode, payload = c.prepareFrame(opCode, payload)
+	if c.sendCh == nil {
+		return c.writeDataFrame(opCode, payload)
+	}
+	select {
+	case <-c.closing:
+		return net.ErrClosed
+	default:
+	}
+	select {
+	case c.sendCh <- outFrame{opCode: opCode, payload: payload}:
+		return nil
+	default:
+	}
+	c.dropped.Add(1)
+	if c.slowPolicy != SlowPolicyDrop {
+		if c.slowClosed.CompareAndSwap(false, true) {
+			logger.Warn("WS发送队列已满，断开慢连接：", c.RemoteAddr())
+			// 对端已无法及时接收，不再写关闭帧，直接断开使读循环退出
+			_ = c.conn.Close()
+		}
+	}
+	return ErrSendQueueFull
+}
+
+// QueueLen 发送队列中待写出的消息数
+func (c *Conn) QueueLen() int {
+	return len(c.sendCh)
+}
+
+// Dropped 因发送队列已满未能发送的消息数
+func (c *Conn) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// stopWriter 停止写协程并等待队列写完（受写超时约束）
+func (c *Conn) stopWriter() {
+	if c.sendCh == nil {
+		return
+	}
+	c.stopOnce.Do(func() {
+		close(c.closing)
+		select {
+		case <-c.writerDone:
+		case <-time.After(c.flushTimeout()):
+		}
+	})
+}
+
+// flushTimeout 关闭时等待队列写完的上限
+func (c *Conn) flushTimeout() time.Duration {
+	if c.writeTimeout > 0 {
+		return c.writeTimeout
+	}
+	return 5 * time.Second
+}